@@ -0,0 +1,327 @@
+// Package amsl talks to the AMSL electronic resource management outbound
+// services, refs #14456, #14415. The responses about collections, ISIL and
+// holding files are merged into a discovery (now defunkt) like list, which can
+// be turned into a span-tag filter configuration.
+package amsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/sethgrid/pester"
+	log "github.com/sirupsen/logrus"
+)
+
+// Discovery API response (now defunkt).
+type Discovery struct {
+	ContentFileLabel               string `json:"contentFileLabel,omitempty"`
+	ContentFileURI                 string `json:"contentFileURI,omitempty"`
+	DokumentLabel                  string `json:"DokumentLabel,omitempty"`
+	DokumentURI                    string `json:"DokumentURI,omitempty"`
+	EvaluateHoldingsFileForLibrary string `json:"evaluateHoldingsFileForLibrary"`
+	ExternalLinkToContentFile      string `json:"externalLinkToContentFile,omitempty"`
+	HoldingsFileLabel              string `json:"holdingsFileLabel,omitempty"`
+	HoldingsFileURI                string `json:"holdingsFileURI,omitempty"`
+	ISIL                           string `json:"ISIL"`
+	LinkToContentFile              string `json:"linkToContentFile,omitempty"`
+	LinkToHoldingsFile             string `json:"linkToHoldingsFile,omitempty"`
+	MegaCollection                 string `json:"megaCollection"`
+	ProductISIL                    string `json:"productISIL"`
+	ShardLabel                     string `json:"shardLabel"`
+	SourceID                       string `json:"sourceID"`
+	TechnicalCollectionID          string `json:"technicalCollectionID"`
+}
+
+// MetadataUsage entry from metadata_usage and metadata_usage_concat endpoints.
+type MetadataUsage struct {
+	ISIL                  string `json:"ISIL"`
+	MegaCollection        string `json:"megaCollection"`
+	ProductISIL           string `json:"productISIL"`
+	ShardLabel            string `json:"shardLabel"`
+	SourceID              string `json:"sourceID"`
+	TechnicalCollectionID string `json:"technicalCollectionID"`
+}
+
+// ContentFiles entry from contentfiles endpoint.
+type ContentFiles struct {
+	ContentFileLabel      string `json:"contentFileLabel"`
+	ContentFileURI        string `json:"contentFileURI"`
+	LinkToContentFile     string `json:"linkToContentFile"`
+	MegaCollection        string `json:"megaCollection"`
+	TechnicalCollectionID string `json:"technicalCollectionID"`
+}
+
+// HoldingsFiles entry from holdingsfiles endpoint.
+type HoldingsFiles struct {
+	DokumentLabel string `json:"DokumentLabel"`
+	DokumentURI   string `json:"DokumentURI"`
+	ISIL          string `json:"ISIL"`
+	LinkToFile    string `json:"LinkToFile"`
+}
+
+// HoldingsFileConcat entry from holdings_file_concat endpoint.
+type HoldingsFileConcat struct {
+	ISIL                  string `json:"ISIL"`
+	MegaCollection        string `json:"megaCollection"`
+	ProductISIL           string `json:"productISIL"`
+	ShardLabel            string `json:"shardLabel"`
+	SourceID              string `json:"sourceID"`
+	TechnicalCollectionID string `json:"technicalCollectionID"`
+}
+
+// SeparatedFields splits s on given separator and trims whitespace.
+func SeparatedFields(s, sep string) (result []string) {
+	for _, v := range strings.Split(s, sep) {
+		result = append(result, strings.TrimSpace(v))
+	}
+	return
+}
+
+// Responses groups the responses of all endpoints needed for discovery.
+type Responses struct {
+	MetadataUsage      []MetadataUsage
+	HoldingsFileConcat []HoldingsFileConcat
+	HoldingsFiles      []HoldingsFiles
+	ContentFiles       []ContentFiles
+}
+
+// Client fetches responses from an AMSL instance.
+type Client struct {
+	Base string // e.g. https://amsl.example.com
+	// AllowEmpty allows empty responses, which usually point to a problem.
+	AllowEmpty bool
+}
+
+// fetch fetches the response for a given kind of query into v.
+func (c *Client) fetch(kind string, v interface{}) error {
+	link := fmt.Sprintf("%s/outboundservices/list?do=%s", strings.TrimSuffix(c.Base, "/"), kind)
+	log.Printf("fetching %s", link)
+	resp, err := pester.Get(link)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s: %s", link, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Fetch fetches all responses needed for discovery.
+func (c *Client) Fetch() (*Responses, error) {
+	var r Responses
+	fetchlist := []struct {
+		kind string
+		v    interface{}
+		size func() int
+	}{
+		{"metadata_usage", &r.MetadataUsage, func() int { return len(r.MetadataUsage) }},
+		{"holdings_file_concat", &r.HoldingsFileConcat, func() int { return len(r.HoldingsFileConcat) }},
+		{"holdingsfiles", &r.HoldingsFiles, func() int { return len(r.HoldingsFiles) }},
+		{"contentfiles", &r.ContentFiles, func() int { return len(r.ContentFiles) }},
+	}
+	for _, f := range fetchlist {
+		if err := c.fetch(f.kind, f.v); err != nil {
+			return nil, err
+		}
+		log.Printf("%s: %d", f.kind, f.size())
+		if f.size() == 0 && !c.AllowEmpty {
+			return nil, fmt.Errorf("empty response from %s", f.kind)
+		}
+	}
+	return &r, nil
+}
+
+// Discover merges responses into a discovery like list. Links to files stored
+// in AMSL are made absolute with a given base URL.
+func (r *Responses) Discover(base string) (updates []Discovery) {
+	base = strings.TrimSuffix(base, "/")
+	for i, mu := range r.MetadataUsage {
+		if i%10000 == 0 {
+			log.Printf("%d of %d done", i, len(r.MetadataUsage))
+		}
+		if mu.MegaCollection == "" {
+			log.Printf("skipping empty megaCollection in #L%d", i)
+			continue
+		}
+
+		// Defunkt update.
+		update := Discovery{
+			ISIL:                  mu.ISIL,
+			MegaCollection:        mu.MegaCollection,
+			ProductISIL:           mu.ProductISIL,
+			ShardLabel:            mu.ShardLabel,
+			SourceID:              mu.SourceID,
+			TechnicalCollectionID: mu.TechnicalCollectionID,
+		}
+
+		// Merge fields from content file.
+		for _, cf := range r.ContentFiles {
+			if cf.MegaCollection != mu.MegaCollection {
+				continue
+			}
+			update.ContentFileLabel = cf.ContentFileLabel
+			update.ContentFileURI = cf.ContentFileURI
+			update.LinkToContentFile = cf.LinkToContentFile
+			break
+		}
+
+		// Default is negative.
+		update.EvaluateHoldingsFileForLibrary = "no"
+
+		// Incorporate new holdings file concat response.
+		for _, hc := range r.HoldingsFileConcat {
+			if hc.MegaCollection != mu.MegaCollection {
+				continue
+			}
+			// ISIL is a list, separated by semicolons.
+			for _, isil := range SeparatedFields(hc.ISIL, ";") {
+				if isil != mu.ISIL {
+					continue
+				}
+				update.ProductISIL = hc.ProductISIL
+				update.ShardLabel = hc.ShardLabel
+				update.EvaluateHoldingsFileForLibrary = "yes"
+				break
+			}
+		}
+
+		// Add link to content file.
+		if update.ContentFileURI != "" {
+			if strings.HasPrefix(update.ContentFileURI, "http://amsl") {
+				update.LinkToContentFile = fmt.Sprintf(
+					"%s/OntoWiki/files/get?setResource=%s", base, update.ContentFileURI)
+			}
+		}
+
+		// No holding file required? Next item.
+		if update.EvaluateHoldingsFileForLibrary == "no" {
+			updates = append(updates, update)
+			continue
+		}
+
+		for _, hf := range r.HoldingsFiles {
+			if hf.ISIL != mu.ISIL {
+				continue
+			}
+			// Create a new item for each holding file.
+			ndoc := Discovery{
+				DokumentLabel:                  hf.DokumentLabel,
+				DokumentURI:                    hf.DokumentURI,
+				LinkToHoldingsFile:             hf.LinkToFile,
+				ContentFileLabel:               update.ContentFileLabel,
+				ContentFileURI:                 update.ContentFileURI,
+				EvaluateHoldingsFileForLibrary: update.EvaluateHoldingsFileForLibrary,
+				ISIL:                           update.ISIL,
+				LinkToContentFile:              update.LinkToContentFile,
+				MegaCollection:                 update.MegaCollection,
+				ProductISIL:                    update.ProductISIL,
+				ShardLabel:                     update.ShardLabel,
+				SourceID:                       update.SourceID,
+				TechnicalCollectionID:          update.TechnicalCollectionID,
+			}
+			if hf.DokumentURI != "" {
+				ndoc.LinkToHoldingsFile = fmt.Sprintf(
+					"%s/OntoWiki/files/get?setResource=%s", base, hf.DokumentURI)
+			}
+			updates = append(updates, ndoc)
+		}
+	}
+	return updates
+}
+
+// FilterConfig turns a discovery list into a span-tag filter configuration.
+// Per ISIL, a record needs to come from one of the source and collection
+// pairs; if holdings are to be evaluated, the record must be covered by one of
+// the holding files as well.
+//
+//	{"DE-15": {"or": [{"and": [{"source": ["49"]}, {"collection": ["A", "B"]}, {"holdings": {"urls": [...]}}]}, ...]}}
+func FilterConfig(updates []Discovery) map[string]interface{} {
+	// Holding file links per ISIL, source and collection.
+	type key struct{ isil, sid, collection string }
+	type holdings struct {
+		required bool
+		links    map[string]bool
+	}
+	entries := make(map[key]*holdings)
+	for _, u := range updates {
+		if u.ISIL == "" || u.SourceID == "" || u.MegaCollection == "" {
+			continue
+		}
+		k := key{u.ISIL, u.SourceID, u.MegaCollection}
+		if entries[k] == nil {
+			entries[k] = &holdings{links: make(map[string]bool)}
+		}
+		if u.EvaluateHoldingsFileForLibrary == "yes" {
+			entries[k].required = true
+			if u.LinkToHoldingsFile != "" {
+				entries[k].links[u.LinkToHoldingsFile] = true
+			}
+		}
+	}
+	// Group collections, that share source and holding files.
+	type group struct {
+		isil, sid string
+		required  bool
+		links     string
+	}
+	collections := make(map[group][]string)
+	for k, h := range entries {
+		var links []string
+		for link := range h.links {
+			links = append(links, link)
+		}
+		sort.Strings(links)
+		g := group{k.isil, k.sid, h.required, strings.Join(links, "\n")}
+		collections[g] = append(collections[g], k.collection)
+	}
+	var groups []group
+	for g := range collections {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].isil != groups[j].isil {
+			return groups[i].isil < groups[j].isil
+		}
+		if groups[i].sid != groups[j].sid {
+			return groups[i].sid < groups[j].sid
+		}
+		if groups[i].links != groups[j].links {
+			return groups[i].links < groups[j].links
+		}
+		return !groups[i].required && groups[j].required
+	})
+	alternatives := make(map[string][]interface{})
+	for _, g := range groups {
+		cs := collections[g]
+		sort.Strings(cs)
+		filters := []interface{}{
+			map[string]interface{}{"source": []string{g.sid}},
+			map[string]interface{}{"collection": cs},
+		}
+		if g.required {
+			if g.links == "" {
+				// Without holding file, no record can be checked.
+				log.Printf("%s: holdings required for source %s, but no holding file found", g.isil, g.sid)
+				continue
+			}
+			urls := strings.Split(g.links, "\n")
+			filters = append(filters, map[string]interface{}{"holdings": map[string]interface{}{"urls": urls}})
+		}
+		alternatives[g.isil] = append(alternatives[g.isil], map[string]interface{}{"and": filters})
+	}
+	config := make(map[string]interface{})
+	for isil, v := range alternatives {
+		config[isil] = map[string]interface{}{"or": v}
+	}
+	return config
+}
+
+// ReadDiscovery reads a discovery list, e.g. as written by span-amsl-discovery.
+func ReadDiscovery(r io.Reader) (updates []Discovery, err error) {
+	err = json.NewDecoder(r).Decode(&updates)
+	return updates, err
+}
@@ -0,0 +1,97 @@
+package amsl
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	responses := map[string]string{
+		"metadata_usage": `[
+			{"ISIL": "DE-15", "megaCollection": "A", "sourceID": "49"},
+			{"ISIL": "DE-15", "megaCollection": "B", "sourceID": "49"},
+			{"ISIL": "DE-14", "megaCollection": "A", "sourceID": "49"}
+		]`,
+		"holdings_file_concat": `[{"ISIL": "DE-14; DE-1", "megaCollection": "A", "sourceID": "49"}]`,
+		"holdingsfiles":        `[{"ISIL": "DE-14", "DokumentURI": "http://amsl/1", "LinkToFile": "x"}]`,
+		"contentfiles":         `[{"megaCollection": "A"}]`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(responses[r.URL.Query().Get("do")]))
+	}))
+	defer ts.Close()
+
+	client := Client{Base: ts.URL}
+	r, err := client.Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates := r.Discover(ts.URL)
+	if len(updates) != 3 {
+		t.Fatalf("got %d updates, want 3", len(updates))
+	}
+	want := ts.URL + "/OntoWiki/files/get?setResource=http://amsl/1"
+	if updates[2].ISIL != "DE-14" || updates[2].LinkToHoldingsFile != want {
+		t.Errorf("got %+v, want DE-14 with link %s", updates[2], want)
+	}
+}
+
+func TestFilterConfig(t *testing.T) {
+	var cases = []struct {
+		about   string
+		updates []Discovery
+		result  string
+	}{
+		{
+			about:   "empty",
+			updates: nil,
+			result:  `{}`,
+		},
+		{
+			about: "collections of a source are grouped",
+			updates: []Discovery{
+				{ISIL: "DE-15", SourceID: "49", MegaCollection: "B", EvaluateHoldingsFileForLibrary: "no"},
+				{ISIL: "DE-15", SourceID: "49", MegaCollection: "A", EvaluateHoldingsFileForLibrary: "no"},
+			},
+			result: `{"DE-15": {"or": [{"and": [{"source": ["49"]}, {"collection": ["A", "B"]}]}]}}`,
+		},
+		{
+			about: "holdings",
+			updates: []Discovery{
+				{ISIL: "DE-14", SourceID: "49", MegaCollection: "A", EvaluateHoldingsFileForLibrary: "yes", LinkToHoldingsFile: "http://x/2"},
+				{ISIL: "DE-14", SourceID: "49", MegaCollection: "A", EvaluateHoldingsFileForLibrary: "yes", LinkToHoldingsFile: "http://x/1"},
+				{ISIL: "DE-14", SourceID: "55", MegaCollection: "C", EvaluateHoldingsFileForLibrary: "no"},
+			},
+			result: `{"DE-14": {"or": [
+				{"and": [{"source": ["49"]}, {"collection": ["A"]}, {"holdings": {"urls": ["http://x/1", "http://x/2"]}}]},
+				{"and": [{"source": ["55"]}, {"collection": ["C"]}]}
+			]}}`,
+		},
+		{
+			about: "holdings required, but missing",
+			updates: []Discovery{
+				{ISIL: "DE-14", SourceID: "49", MegaCollection: "A", EvaluateHoldingsFileForLibrary: "yes"},
+			},
+			result: `{}`,
+		},
+	}
+	for _, c := range cases {
+		b, err := json.Marshal(FilterConfig(c.updates))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got, want interface{}
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(c.result), &want); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %s, want %s", c.about, b, c.result)
+		}
+	}
+}
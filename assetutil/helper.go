@@ -25,7 +25,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
-	"io"
+	"fmt"
 	"regexp"
 	"strings"
 
@@ -54,80 +54,191 @@ func (r RegexpMap) LookupDefault(s, def string) string {
 	return def
 }
 
-// MustLoadRegexpMap loads the content of a given asset path into a RegexpMap. It
-// will panic, if the asset path is not found and if the patterns found in the
-// file cannot be compiled.
-func MustLoadRegexpMap(ap string) RegexpMap {
-	b, err := Asset(ap)
+// LoadRegexpMap loads a JSON object from patterns to values from an asset
+// path into a RegexpMap.
+func LoadRegexpMap(ap string) (RegexpMap, error) {
+	b, err := Load(ap)
 	if err != nil {
-		panic(err)
+		return RegexpMap{}, err
 	}
-	d := make(map[string]string)
-	err = json.Unmarshal(b, &d)
+	entries, err := decodeRegexpMap(b)
 	if err != nil {
-		panic(err)
+		return RegexpMap{}, fmt.Errorf("asset %s: %v", ap, err)
+	}
+	return RegexpMap{Entries: entries}, nil
+}
+
+// decodeRegexpMap compiles the patterns of a JSON object.
+func decodeRegexpMap(b []byte) (entries []RegexpMapEntry, err error) {
+	d := make(map[string]string)
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
 	}
-	remap := RegexpMap{}
 	for k, v := range d {
-		remap.Entries = append(remap.Entries, RegexpMapEntry{Pattern: regexp.MustCompile(k), Value: v})
+		p, err := regexp.Compile(k)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, RegexpMapEntry{Pattern: p, Value: v})
 	}
-	return remap
+	return entries, nil
 }
 
-func MustLoadStringSet(paths ...string) *container.StringSet {
+// MustLoadRegexpMap loads the content of a given asset path into a RegexpMap. It
+// will panic, if the asset path is not found and if the patterns found in the
+// file cannot be compiled. The entries are updated by Override.
+func MustLoadRegexpMap(ap string) *RegexpMap {
+	remap, err := LoadRegexpMap(ap)
+	if err != nil && fallback(err, ap) {
+		remap, err = LoadRegexpMap(ap)
+	}
+	if err != nil {
+		panic(err)
+	}
+	m := &remap
+	register(ap, func(b []byte) (func(), error) {
+		entries, err := decodeRegexpMap(b)
+		if err != nil {
+			return nil, err
+		}
+		return func() { m.Entries = entries }, nil
+	})
+	return m
+}
+
+// LoadStringSet loads lines from one or more asset paths into a set, ignoring
+// empty lines.
+func LoadStringSet(paths ...string) (*container.StringSet, error) {
 	s := container.NewStringSet()
 	for _, path := range paths {
-		b, err := Asset(path)
+		b, err := Load(path)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
-		rdr := bufio.NewReader(bytes.NewReader(b))
-		for {
-			line, err := rdr.ReadString('\n')
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				panic(err)
-			}
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
+		if err := addLines(s, b); err != nil {
+			return nil, fmt.Errorf("asset %s: %v", path, err)
+		}
+	}
+	return s, nil
+}
+
+// addLines adds non-empty lines to a set.
+func addLines(s *container.StringSet, b []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
 			s.Add(line)
 		}
 	}
+	return scanner.Err()
+}
+
+// MustLoadStringSet is like LoadStringSet, but panics on error. Sets loaded
+// from a single path are updated by Override.
+func MustLoadStringSet(paths ...string) *container.StringSet {
+	s, err := LoadStringSet(paths...)
+	if err != nil && fallback(err, paths...) {
+		s, err = LoadStringSet(paths...)
+	}
+	if err != nil {
+		panic(err)
+	}
+	if len(paths) == 1 {
+		register(paths[0], func(b []byte) (func(), error) {
+			t := container.NewStringSet()
+			if err := addLines(t, b); err != nil {
+				return nil, err
+			}
+			return func() { s.Set = t.Set }, nil
+		})
+	}
 	return s
 }
 
+// LoadStringMap loads a JSON object from an asset path into a
+// container.StringMap.
+func LoadStringMap(path string) (container.StringMap, error) {
+	d := make(map[string]string)
+	if err := loadJSON(path, &d); err != nil {
+		return nil, err
+	}
+	return container.StringMap(d), nil
+}
+
 // MustLoadStringMap loads a JSON file from an asset path and parses it into a
 // container.StringMap. This function will panic, if the asset cannot be found
-// or the JSON is erroneous.
+// or the JSON is erroneous. The map is updated in place by Override.
 func MustLoadStringMap(path string) container.StringMap {
-	b, err := Asset(path)
-	if err != nil {
-		panic(err)
+	m, err := LoadStringMap(path)
+	if err != nil && fallback(err, path) {
+		m, err = LoadStringMap(path)
 	}
-	d := make(map[string]string)
-	err = json.Unmarshal(b, &d)
 	if err != nil {
 		panic(err)
 	}
-	return container.StringMap(d)
+	register(path, func(b []byte) (func(), error) {
+		d := make(map[string]string)
+		if err := json.Unmarshal(b, &d); err != nil {
+			return nil, err
+		}
+		return func() {
+			for k := range m {
+				delete(m, k)
+			}
+			for k, v := range d {
+				m[k] = v
+			}
+		}, nil
+	})
+	return m
+}
+
+// LoadStringSliceMap loads a JSON object from an asset path into a
+// container.StringSliceMap.
+func LoadStringSliceMap(path string) (container.StringSliceMap, error) {
+	d := make(map[string][]string)
+	if err := loadJSON(path, &d); err != nil {
+		return nil, err
+	}
+	return container.StringSliceMap(d), nil
 }
 
 // MustLoadStringSliceMap loads a JSON file from an asset path and parses it into
 // a container.StringSliceMap. This function will halt the world, if it is
-// called with an invalid argument.
+// called with an invalid argument. The map is updated in place by Override.
 func MustLoadStringSliceMap(path string) container.StringSliceMap {
-	b, err := Asset(path)
+	m, err := LoadStringSliceMap(path)
+	if err != nil && fallback(err, path) {
+		m, err = LoadStringSliceMap(path)
+	}
 	if err != nil {
 		panic(err)
 	}
-	d := make(map[string][]string)
-	err = json.Unmarshal(b, &d)
+	register(path, func(b []byte) (func(), error) {
+		d := make(map[string][]string)
+		if err := json.Unmarshal(b, &d); err != nil {
+			return nil, err
+		}
+		return func() {
+			for k := range m {
+				delete(m, k)
+			}
+			for k, v := range d {
+				m[k] = v
+			}
+		}, nil
+	})
+	return m
+}
+
+// loadJSON decodes an asset.
+func loadJSON(path string, v interface{}) error {
+	b, err := Load(path)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("asset %s: invalid JSON: %v", path, err)
 	}
-	return container.StringSliceMap(d)
+	return nil
 }
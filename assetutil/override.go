@@ -0,0 +1,217 @@
+package assetutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// EnvAssetDir names an environment variable with a directory, whose files
+// replace embedded assets with the same relative path, e.g.
+// $SPAN_ASSETS/crossref/formats.json replaces assets/crossref/formats.json.
+// Files, that cannot be loaded, are reported by Err.
+const EnvAssetDir = "SPAN_ASSETS"
+
+var (
+	mu sync.Mutex
+	// overrides maps asset names to files.
+	overrides = make(map[string]string)
+	// reloaders decode new content for values already loaded from an asset
+	// and return a function, that updates the values.
+	reloaders = make(map[string][]reloader)
+	// errs are errors of override files, that were replaced by embedded
+	// assets.
+	errs []error
+)
+
+func init() {
+	dir := os.Getenv(EnvAssetDir)
+	if dir == "" {
+		return
+	}
+	for _, name := range Names() {
+		filename := filepath.Join(dir, strings.TrimPrefix(name, "assets/"))
+		if _, err := os.Stat(filename); err == nil {
+			overrides[name] = filename
+		}
+	}
+}
+
+// Names returns the sorted names of the assets embedded in the binary.
+func Names() []string {
+	names := AssetNames()
+	sort.Strings(names)
+	return names
+}
+
+// Overrides returns a copy of the asset names and the files replacing them.
+func Overrides() map[string]string {
+	mu.Lock()
+	defer mu.Unlock()
+	result := make(map[string]string)
+	for k, v := range overrides {
+		result[k] = v
+	}
+	return result
+}
+
+// WriteNames writes the names of embedded assets, one per line, followed by a
+// tab and the replacing file for overridden assets.
+func WriteNames(w io.Writer) error {
+	o := Overrides()
+	for _, name := range Names() {
+		var err error
+		if filename, ok := o[name]; ok {
+			_, err = fmt.Fprintf(w, "%s\t%s\n", name, filename)
+		} else {
+			_, err = fmt.Fprintln(w, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load returns the content of an asset, read from a file, if the asset is
+// overridden.
+func Load(name string) ([]byte, error) {
+	mu.Lock()
+	filename, ok := overrides[name]
+	mu.Unlock()
+	if !ok {
+		return Asset(name)
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("asset %s: %v", name, err)
+	}
+	return b, nil
+}
+
+// reloader decodes new content of an asset and returns a function, that
+// updates a value loaded from the asset.
+type reloader func(b []byte) (apply func(), err error)
+
+// Override replaces an embedded asset with a file. Maps and sets already
+// loaded from the asset are updated in place, so this works after package
+// initialization, e.g. with values from flags. The file is validated first and
+// nothing changes on error. Not safe for concurrent use with readers of the
+// loaded values, call it at startup.
+func Override(name, filename string) error {
+	return OverrideFiles(map[string]string{name: filename})
+}
+
+// OverrideFiles replaces a number of embedded assets with files, see Override.
+// All files are read and decoded first, nothing changes, if any of them is
+// invalid.
+func OverrideFiles(files map[string]string) error {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	mu.Lock()
+	defer mu.Unlock()
+	var updates []func()
+	for _, name := range names {
+		filename := files[name]
+		if _, err := Asset(name); err != nil {
+			return fmt.Errorf("unknown asset %s, see list of embedded assets", name)
+		}
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(name, ".json") && !json.Valid(b) {
+			return fmt.Errorf("asset %s: invalid JSON in %s", name, filename)
+		}
+		for _, f := range reloaders[name] {
+			apply, err := f(b)
+			if err != nil {
+				return fmt.Errorf("asset %s: %s: %v", name, filename, err)
+			}
+			updates = append(updates, apply)
+		}
+	}
+	for _, apply := range updates {
+		apply()
+	}
+	for _, name := range names {
+		overrides[name] = files[name]
+	}
+	return nil
+}
+
+// OverrideFlag parses a name=file value and overrides the asset.
+func OverrideFlag(value string) error {
+	return OverrideFlags([]string{value})
+}
+
+// OverrideFlags parses name=file values and overrides all assets or none, see
+// OverrideFiles.
+func OverrideFlags(values []string) error {
+	files := make(map[string]string)
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("asset override must be name=file, got %q", value)
+		}
+		files[parts[0]] = parts[1]
+	}
+	return OverrideFiles(files)
+}
+
+// register adds a function, that decodes new content for a loaded value.
+func register(name string, f reloader) {
+	mu.Lock()
+	defer mu.Unlock()
+	reloaders[name] = append(reloaders[name], f)
+}
+
+// fallback handles override files, that cannot be loaded during
+// initialization. The overrides of the given assets are dropped, so the
+// embedded assets are used instead, and the error is kept for Err. It returns
+// false, if none of the assets was overridden.
+func fallback(err error, names ...string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	var dropped []string
+	for _, name := range names {
+		if filename, ok := overrides[name]; ok {
+			dropped = append(dropped, filename)
+			delete(overrides, name)
+		}
+	}
+	if len(dropped) == 0 {
+		return false
+	}
+	errs = append(errs, fmt.Errorf("cannot load %s from %s (%s): %v",
+		strings.Join(names, ", "), strings.Join(dropped, ", "), EnvAssetDir, err))
+	return true
+}
+
+// Err returns an error, if override files from the environment could not be
+// loaded. The embedded assets are used in that case, commands decide whether
+// to continue.
+func Err() error {
+	mu.Lock()
+	defer mu.Unlock()
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Errorf("%d asset errors: %s", len(errs), strings.Join(msgs, "; "))
+}
@@ -0,0 +1,173 @@
+package assetutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOverride(t *testing.T) {
+	name := "assets/crossref/formats.json"
+	m := MustLoadStringMap(name)
+	if len(m) == 0 {
+		t.Fatalf("expected entries in %s", name)
+	}
+	dir, err := ioutil.TempDir("", "span-assetutil-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(s string) string {
+		f, err := ioutil.TempFile(dir, "asset-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(s); err != nil {
+			t.Fatal(err)
+		}
+		return f.Name()
+	}
+	defer func() {
+		mu.Lock()
+		delete(overrides, name)
+		mu.Unlock()
+	}()
+
+	n := len(m)
+	if err := Override(name, write(`{"journal-article": `)); err == nil {
+		t.Errorf("expected error for invalid JSON")
+	}
+	if len(m) != n {
+		t.Errorf("map changed on invalid override")
+	}
+	if err := Override(name, write(`{"journal-article": ["x"]}`)); err == nil {
+		t.Errorf("expected error for invalid value type")
+	}
+	if err := Override("assets/missing.json", write(`{}`)); err == nil {
+		t.Errorf("expected error for unknown asset")
+	}
+	if err := Override(name, filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+	filename := write(`{"journal-article": "X"}`)
+	if err := Override(name, filename); err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 || m["journal-article"] != "X" {
+		t.Errorf("got %v, want override", m)
+	}
+	if Overrides()[name] != filename {
+		t.Errorf("override not listed")
+	}
+	if _, err := LoadStringMap(name); err != nil {
+		t.Errorf("got %v, want override to load", err)
+	}
+}
+
+func TestOverrideFlag(t *testing.T) {
+	for _, v := range []string{"", "a", "=b", "a="} {
+		if err := OverrideFlag(v); err == nil {
+			t.Errorf("%q: expected error", v)
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	name := "assets/crossref/formats.json"
+	want, err := LoadStringMap(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := ioutil.TempFile("", "span-assetutil-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(`{"journal-article": `); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	mu.Lock()
+	overrides[name] = f.Name()
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		delete(overrides, name)
+		errs = nil
+		mu.Unlock()
+	}()
+
+	m := MustLoadStringMap(name)
+	if len(m) != len(want) {
+		t.Errorf("got %d entries, want embedded asset with %d", len(m), len(want))
+	}
+	if err := Err(); err == nil {
+		t.Errorf("expected error for invalid override file")
+	}
+	if _, ok := Overrides()[name]; ok {
+		t.Errorf("invalid override still listed")
+	}
+}
+
+func TestOverrideRegexpMap(t *testing.T) {
+	name := "assets/finc/lcc.json"
+	m := MustLoadRegexpMap(name)
+	f, err := ioutil.TempFile("", "span-assetutil-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(`{"^ZZ.*": "Test"}`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer func() {
+		mu.Lock()
+		delete(overrides, name)
+		mu.Unlock()
+	}()
+	if err := Override(name, f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.LookupDefault("ZZ1", "none"); got != "Test" {
+		t.Errorf("LookupDefault: got %q, want override", got)
+	}
+	if len(m.Entries) != 1 {
+		t.Errorf("got %d entries, want 1", len(m.Entries))
+	}
+}
+
+func TestOverrideFilesAllOrNothing(t *testing.T) {
+	first, second := "assets/crossref/formats.json", "assets/finc/lcc.json"
+	m := MustLoadStringMap(first)
+	r := MustLoadRegexpMap(second)
+	n, k := len(m), len(r.Entries)
+	dir, err := ioutil.TempDir("", "span-assetutil-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	valid, invalid := filepath.Join(dir, "valid.json"), filepath.Join(dir, "invalid.json")
+	if err := ioutil.WriteFile(valid, []byte(`{"journal-article": "X"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Valid JSON, but not a valid pattern.
+	if err := ioutil.WriteFile(invalid, []byte(`{"(": "X"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = OverrideFlags([]string{first + "=" + valid, second + "=" + invalid})
+	if err == nil {
+		t.Fatal("expected error for invalid pattern")
+	}
+	if len(m) != n || m["journal-article"] == "X" {
+		t.Errorf("first asset changed, although second is invalid")
+	}
+	if len(r.Entries) != k {
+		t.Errorf("second asset changed")
+	}
+	if o := Overrides(); o[first] != "" || o[second] != "" {
+		t.Errorf("overrides listed after error: %v", o)
+	}
+}
@@ -0,0 +1,159 @@
+// Package cache implements a small key value cache with in-memory, memcached
+// and redis backends, e.g. for lookups shared between runs or machines.
+//
+// Keys are checked in one place, for all backends: memcached limits keys to
+// 250 bytes without whitespace or control characters, so a cache can be
+// swapped without surprises. The limit can be changed per cache.
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/miku/span"
+)
+
+var (
+	// ErrNotFound is returned for missing or expired keys.
+	ErrNotFound = errors.New("cache: not found")
+	// ErrInvalidKey is returned for keys, that are too long or contain
+	// whitespace or control characters.
+	ErrInvalidKey = errors.New("cache: invalid key")
+
+	// MaxKeyLength is the default key limit, including prefix.
+	MaxKeyLength = span.KeyLengthLimit
+)
+
+// Cache stores values by key. A zero ttl means no expiration.
+type Cache interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Close() error
+}
+
+// CheckKey returns ErrInvalidKey for keys, that are empty, longer than max
+// bytes or contain whitespace or control characters.
+func CheckKey(key string, max int) error {
+	if key == "" || len(key) > max {
+		return ErrInvalidKey
+	}
+	for _, r := range key {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+// Open returns a cache for a link, one of memory://,
+// memcached://host:port or redis://[:password@]host:port[/db]. Options are
+// prefix, prepended to all keys, and max_key_length, e.g.
+// redis://localhost:6379/0?prefix=span:&max_key_length=1024.
+func Open(link string) (Cache, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	c := &checked{prefix: u.Query().Get("prefix"), max: MaxKeyLength}
+	if v := u.Query().Get("max_key_length"); v != "" {
+		if c.max, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("cache: invalid max_key_length: %v", err)
+		}
+	}
+	switch u.Scheme {
+	case "memory":
+		c.Cache = NewMemory()
+	case "memcached":
+		c.Cache = &Memcached{Addr: u.Host}
+	case "redis":
+		r := &Redis{Addr: u.Host}
+		if u.User != nil {
+			r.Password, _ = u.User.Password()
+		}
+		if db := strings.Trim(u.Path, "/"); db != "" {
+			if r.DB, err = strconv.Atoi(db); err != nil {
+				return nil, fmt.Errorf("cache: invalid redis database: %s", db)
+			}
+		}
+		c.Cache = r
+	default:
+		return nil, fmt.Errorf("cache: unsupported scheme: %s", u.Scheme)
+	}
+	return c, nil
+}
+
+// checked prefixes and checks keys.
+type checked struct {
+	Cache
+	prefix string
+	max    int
+}
+
+func (c *checked) Get(key string) ([]byte, error) {
+	key = c.prefix + key
+	if err := CheckKey(key, c.max); err != nil {
+		return nil, err
+	}
+	return c.Cache.Get(key)
+}
+
+func (c *checked) Set(key string, value []byte, ttl time.Duration) error {
+	key = c.prefix + key
+	if err := CheckKey(key, c.max); err != nil {
+		return err
+	}
+	return c.Cache.Set(key, value, ttl)
+}
+
+// memoryEntry is a value with optional expiration.
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// Memory is an in-memory cache, safe for concurrent use.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+// NewMemory creates an empty in-memory cache.
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]memoryEntry)}
+}
+
+// Get returns a value.
+func (m *Memory) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(m.entries, key)
+		return nil, ErrNotFound
+	}
+	return e.value, nil
+}
+
+// Set stores a value.
+func (m *Memory) Set(key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := memoryEntry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	m.entries[key] = e
+	return nil
+}
+
+// Close is a no-op.
+func (m *Memory) Close() error { return nil }
@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckKey(t *testing.T) {
+	var cases = []struct {
+		key string
+		max int
+		err error
+	}{
+		{"crossref:member:297", 250, nil},
+		{"", 250, ErrInvalidKey},
+		{strings.Repeat("x", 251), 250, ErrInvalidKey},
+		{strings.Repeat("x", 251), 1024, nil},
+		{"a b", 250, ErrInvalidKey},
+		{"a\x00b", 250, ErrInvalidKey},
+	}
+	for _, c := range cases {
+		if err := CheckKey(c.key, c.max); err != c.err {
+			t.Errorf("CheckKey(%q, %d): got %v, want %v", c.key, c.max, err, c.err)
+		}
+	}
+}
+
+// exercise runs a set and get roundtrip.
+func exercise(t *testing.T, c Cache) {
+	if _, err := c.Get("k"); err != ErrNotFound {
+		t.Errorf("Get: got %v, want %v", err, ErrNotFound)
+	}
+	if err := c.Set("k", []byte("v\r\nw"), time.Hour); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	v, err := c.Get("k")
+	if err != nil || string(v) != "v\r\nw" {
+		t.Errorf("Get: got %q, %v", v, err)
+	}
+	if err := c.Set(strings.Repeat("k", 300), nil, 0); err != ErrInvalidKey {
+		t.Errorf("Set: got %v, want %v", err, ErrInvalidKey)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	c, err := Open("memory://?prefix=test:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exercise(t, c)
+
+	m := NewMemory()
+	m.Set("k", []byte("v"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, err := m.Get("k"); err != ErrNotFound {
+		t.Errorf("expired Get: got %v, want %v", err, ErrNotFound)
+	}
+}
+
+// serve accepts a single connection and answers with f.
+func serve(t *testing.T, f func(*bufio.ReadWriter, map[string]string) error) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+		data := make(map[string]string)
+		for {
+			if err := f(rw, data); err != nil {
+				return
+			}
+			rw.Flush()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestMemcached(t *testing.T) {
+	addr := serve(t, func(rw *bufio.ReadWriter, data map[string]string) error {
+		line, err := readLine(rw.Reader)
+		if err != nil {
+			return err
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "get":
+			if v, ok := data[fields[1]]; ok {
+				fmt.Fprintf(rw, "VALUE %s 0 %d\r\n%s\r\n", fields[1], len(v), v)
+			}
+			fmt.Fprint(rw, "END\r\n")
+		case "set":
+			n, _ := strconv.Atoi(fields[4])
+			b := make([]byte, n+2)
+			if _, err := io.ReadFull(rw, b); err != nil {
+				return err
+			}
+			data[fields[1]] = string(b[:n])
+			fmt.Fprint(rw, "STORED\r\n")
+		}
+		return nil
+	})
+	c, err := Open("memcached://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exercise(t, c)
+}
+
+func TestRedis(t *testing.T) {
+	addr := serve(t, func(rw *bufio.ReadWriter, data map[string]string) error {
+		line, err := readLine(rw.Reader)
+		if err != nil {
+			return err
+		}
+		n, _ := strconv.Atoi(line[1:])
+		var args []string
+		for i := 0; i < n; i++ {
+			line, err := readLine(rw.Reader)
+			if err != nil {
+				return err
+			}
+			size, _ := strconv.Atoi(line[1:])
+			b := make([]byte, size+2)
+			if _, err := io.ReadFull(rw, b); err != nil {
+				return err
+			}
+			args = append(args, string(b[:size]))
+		}
+		switch args[0] {
+		case "AUTH":
+			if args[1] != "secret" {
+				fmt.Fprint(rw, "-WRONGPASS\r\n")
+				return nil
+			}
+			fmt.Fprint(rw, "+OK\r\n")
+		case "SELECT":
+			fmt.Fprint(rw, "+OK\r\n")
+		case "GET":
+			if v, ok := data[args[1]]; ok {
+				fmt.Fprintf(rw, "$%d\r\n%s\r\n", len(v), v)
+			} else {
+				fmt.Fprint(rw, "$-1\r\n")
+			}
+		case "SET":
+			data[args[1]] = args[2]
+			fmt.Fprint(rw, "+OK\r\n")
+		}
+		return nil
+	})
+	c, err := Open("redis://:secret@" + addr + "/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exercise(t, c)
+}
+
+func TestMemcachedConcurrent(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// The answer to "get a" waits for "get b", which requires a second
+	// connection.
+	b := make(chan struct{})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+				for {
+					line, err := readLine(rw.Reader)
+					if err != nil {
+						return
+					}
+					switch line {
+					case "get a":
+						select {
+						case <-b:
+						case <-time.After(5 * time.Second):
+						}
+						fmt.Fprint(rw, "VALUE a 0 1\r\na\r\nEND\r\n")
+					case "get b":
+						close(b)
+						fmt.Fprint(rw, "VALUE b 0 1\r\nb\r\nEND\r\n")
+					}
+					rw.Flush()
+				}
+			}(conn)
+		}
+	}()
+	m := &Memcached{Addr: ln.Addr().String()}
+	defer m.Close()
+	done := make(chan error)
+	go func() {
+		_, err := m.Get("a")
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	if _, err := m.Get("b"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("requests were serialized, took %s", elapsed)
+	}
+}
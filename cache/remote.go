@@ -0,0 +1,276 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DialTimeout limits connection setup and each request to remote caches.
+var DialTimeout = 10 * time.Second
+
+// MaxIdleConns limits the connections kept open per remote cache.
+var MaxIdleConns = 16
+
+// conn is a pool of lazily dialed connections, so concurrent requests do not
+// wait for each other. Each connection serves one request at a time and is
+// dropped after an error.
+type conn struct {
+	mu     sync.Mutex
+	idle   []*client
+	closed bool
+}
+
+// client is a single connection.
+type client struct {
+	c  net.Conn
+	rw *bufio.ReadWriter
+}
+
+// get returns an idle connection or dials addr. The init function runs on new
+// connections, e.g. to authenticate.
+func (c *conn) get(addr string, init func(*bufio.ReadWriter) error) (*client, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		cl := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return cl, nil
+	}
+	c.mu.Unlock()
+	nc, err := net.DialTimeout("tcp", addr, DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	cl := &client{c: nc, rw: bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc))}
+	if init != nil {
+		nc.SetDeadline(time.Now().Add(DialTimeout))
+		if err := init(cl.rw); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return cl, nil
+}
+
+// put returns a connection to the pool.
+func (c *conn) put(cl *client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || len(c.idle) >= MaxIdleConns {
+		cl.c.Close()
+		return
+	}
+	c.idle = append(c.idle, cl)
+}
+
+// do runs f with a connection from the pool.
+func (c *conn) do(addr string, init func(*bufio.ReadWriter) error, f func(*bufio.ReadWriter) error) error {
+	cl, err := c.get(addr, init)
+	if err != nil {
+		return err
+	}
+	cl.c.SetDeadline(time.Now().Add(DialTimeout))
+	err = f(cl.rw)
+	if _, ok := err.(protocolError); err != nil && err != ErrNotFound && !ok {
+		// Network errors leave the connection in an unknown state.
+		cl.c.Close()
+		return err
+	}
+	c.put(cl)
+	return err
+}
+
+// Close closes idle connections; connections in use are closed, when they
+// are returned.
+func (c *conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for _, cl := range c.idle {
+		if e := cl.c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	c.idle, c.closed = nil, true
+	return err
+}
+
+// protocolError is an error reported by the server; the connection is still
+// usable.
+type protocolError string
+
+func (e protocolError) Error() string { return string(e) }
+
+// readLine reads a CRLF terminated line.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Memcached is a client for the memcached text protocol, safe for concurrent
+// use.
+type Memcached struct {
+	Addr string
+	conn
+}
+
+// Get returns a value.
+func (m *Memcached) Get(key string) (value []byte, err error) {
+	err = m.do(m.Addr, nil, func(rw *bufio.ReadWriter) error {
+		if _, err := fmt.Fprintf(rw, "get %s\r\n", key); err != nil {
+			return err
+		}
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+		line, err := readLine(rw.Reader)
+		if err != nil {
+			return err
+		}
+		if line == "END" {
+			return ErrNotFound
+		}
+		// VALUE <key> <flags> <bytes>
+		fields := strings.Fields(line)
+		if len(fields) != 4 || fields[0] != "VALUE" {
+			return fmt.Errorf("memcached: unexpected response: %s", line)
+		}
+		n, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return fmt.Errorf("memcached: unexpected response: %s", line)
+		}
+		value = make([]byte, n+2)
+		if _, err := io.ReadFull(rw, value); err != nil {
+			return err
+		}
+		value = value[:n]
+		if line, err = readLine(rw.Reader); err != nil {
+			return err
+		}
+		if line != "END" {
+			return fmt.Errorf("memcached: unexpected response: %s", line)
+		}
+		return nil
+	})
+	return value, err
+}
+
+// Set stores a value. Memcached takes expiration times over 30 days as unix
+// timestamps.
+func (m *Memcached) Set(key string, value []byte, ttl time.Duration) error {
+	exptime := int64(ttl / time.Second)
+	if ttl > 30*24*time.Hour {
+		exptime = time.Now().Add(ttl).Unix()
+	}
+	return m.do(m.Addr, nil, func(rw *bufio.ReadWriter) error {
+		if _, err := fmt.Fprintf(rw, "set %s 0 %d %d\r\n", key, exptime, len(value)); err != nil {
+			return err
+		}
+		rw.Write(value)
+		rw.WriteString("\r\n")
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+		line, err := readLine(rw.Reader)
+		if err != nil {
+			return err
+		}
+		if line != "STORED" {
+			return protocolError("memcached: " + line)
+		}
+		return nil
+	})
+}
+
+// Redis is a client for the redis protocol (RESP), safe for concurrent use.
+type Redis struct {
+	Addr     string
+	Password string
+	DB       int
+	conn
+}
+
+// command sends a command and reads a single reply. Bulk replies are
+// returned as value, a nil bulk reply as ErrNotFound.
+func command(rw *bufio.ReadWriter, args ...string) (value []byte, err error) {
+	fmt.Fprintf(rw, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(rw, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := rw.Flush(); err != nil {
+		return nil, err
+	}
+	line, err := readLine(rw.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty response")
+	}
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, protocolError("redis: " + line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: unexpected response: %s", line)
+		}
+		if n < 0 {
+			return nil, ErrNotFound
+		}
+		value = make([]byte, n+2)
+		if _, err := io.ReadFull(rw, value); err != nil {
+			return nil, err
+		}
+		return value[:n], nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected response: %s", line)
+	}
+}
+
+// init authenticates and selects the database.
+func (r *Redis) init(rw *bufio.ReadWriter) error {
+	if r.Password != "" {
+		if _, err := command(rw, "AUTH", r.Password); err != nil {
+			return err
+		}
+	}
+	if r.DB != 0 {
+		if _, err := command(rw, "SELECT", strconv.Itoa(r.DB)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns a value.
+func (r *Redis) Get(key string) (value []byte, err error) {
+	err = r.do(r.Addr, r.init, func(rw *bufio.ReadWriter) error {
+		value, err = command(rw, "GET", key)
+		return err
+	})
+	return value, err
+}
+
+// Set stores a value.
+func (r *Redis) Set(key string, value []byte, ttl time.Duration) error {
+	return r.do(r.Addr, r.init, func(rw *bufio.ReadWriter) error {
+		args := []string{"SET", key, string(value)}
+		if ttl > 0 {
+			args = append(args, "PX", strconv.FormatInt(int64(ttl/time.Millisecond), 10))
+		}
+		_, err := command(rw, args...)
+		return err
+	})
+}
@@ -0,0 +1,108 @@
+// Package citation parses citations of edited volumes, as found in dc:source
+// fields of OAI records, e.g. "Knapp, Gudrun-Axeli; Wetterer, Angelika
+// (Hrsg.): Achsen der Differenz (Münster: Westfälisches Dampfboot, 2003),
+// 73-100". German, English and French conventions are recognized.
+package citation
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// dashReplacer normalizes dashes used in page ranges.
+	dashReplacer = strings.NewReplacer("–", "-", "—", "-", "‐", "-", "‑", "-")
+	// imprintPattern matches "(Münster: Westfälisches Dampfboot, 2003)".
+	imprintPattern = regexp.MustCompile(`\(([^():]+):\s*([^()]*?),\s*([12][0-9]{3})\)`)
+	// editorPattern matches editor markers, like "(Hrsg.)", "(Hg.)", "(Eds.)" or
+	// "(dir.)", followed by a colon.
+	editorPattern = regexp.MustCompile(`(?i)\s*\((?:hrsg|hgg?|eds?|dir|éds?)\.?\)\s*:?\s*`)
+	// inPattern matches a leading "In:" or "in:".
+	inPattern = regexp.MustCompile(`(?i)^in:\s*`)
+	// pagesPattern matches page ranges and single pages, with optional
+	// markers, like "S. 73-100", "pp. 73-100" or "p. 5".
+	pagesPattern = regexp.MustCompile(`(?i)(\b(?:s|pp?|seiten?|pages?)\.?\s*)?\b([1-9][0-9]*)(?:\s*-\s*([1-9][0-9]*))?\b`)
+	// yearPattern matches a year following a comma or in parentheses, e.g.
+	// ", 2003" or "21 (2003)".
+	yearPattern = regexp.MustCompile(`[,(]\s*([12][0-9]{3})\b\)?`)
+	// editionPattern matches editions, like "2. Aufl.", "3rd ed." or "2e éd.".
+	editionPattern = regexp.MustCompile(`\b[1-9][0-9]*\.,?\s*(?:\pL+\.,?\s*)*Aufl(?:age|\.)|\b[1-9][0-9]*(?:st|nd|rd|th)\s+ed(?:ition|\.)|\b[1-9][0-9]*e\s+éd(?:ition|\.)`)
+)
+
+// Citation is a parsed citation.
+type Citation struct {
+	Editors   []string
+	Title     string
+	Edition   string
+	Places    []string
+	Publisher string
+	Year      string
+	StartPage string
+	EndPage   string
+}
+
+// Parse parses a citation. Parts, that cannot be found, are left empty. If
+// no structure is found, Title is the whole citation.
+func Parse(s string) Citation {
+	var c Citation
+	s = strings.Join(strings.Fields(dashReplacer.Replace(s)), " ")
+	if s == "" {
+		return c
+	}
+	head, tail := s, ""
+	if loc := lastIndex(imprintPattern, s); loc != nil {
+		head, tail = s[:loc[0]], s[loc[1]:]
+		for _, p := range strings.Split(s[loc[2]:loc[3]], "/") {
+			if p = strings.TrimSpace(p); p != "" {
+				c.Places = append(c.Places, p)
+			}
+		}
+		c.Publisher = strings.TrimSpace(s[loc[4]:loc[5]])
+		c.Year = s[loc[6]:loc[7]]
+	} else if loc := lastIndex(yearPattern, s); loc != nil {
+		head, tail = s[:loc[0]], s[loc[1]:]
+		c.Year = s[loc[2]:loc[3]]
+	} else if i := strings.LastIndex(s, ","); i > 0 {
+		// Without imprint or year, only accept ranges or marked pages.
+		if m := pagesPattern.FindStringSubmatch(s[i+1:]); m != nil && (m[1] != "" || m[3] != "") {
+			head, tail = s[:i], s[i+1:]
+		}
+	}
+	if m := pagesPattern.FindStringSubmatch(tail); m != nil {
+		c.StartPage, c.EndPage = m[2], m[3]
+	}
+	head = inPattern.ReplaceAllString(strings.TrimSpace(head), "")
+	if loc := editorPattern.FindStringIndex(head); loc != nil {
+		c.Editors = splitNames(head[:loc[0]])
+		head = head[loc[1]:]
+	} else if i := strings.Index(head, ": "); i > 0 && tail != "" {
+		head = head[i+2:]
+	}
+	if c.Edition = editionPattern.FindString(head); c.Edition != "" {
+		head = strings.Replace(head, c.Edition, "", 1)
+	}
+	c.Title = strings.TrimRight(strings.TrimSpace(head), " .,;:")
+	if c.Title == "" && c.Editors == nil {
+		c.Title = s
+	}
+	return c
+}
+
+// lastIndex returns the submatch indices of the last match of a pattern.
+func lastIndex(p *regexp.Regexp, s string) []int {
+	all := p.FindAllStringSubmatchIndex(s, -1)
+	if len(all) == 0 {
+		return nil
+	}
+	return all[len(all)-1]
+}
+
+// splitNames splits a list of names, separated by semicolon or slash.
+func splitNames(s string) (names []string) {
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == '/' }) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
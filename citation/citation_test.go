@@ -0,0 +1,68 @@
+package citation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	var cases = []struct {
+		s      string
+		result Citation
+	}{
+		{"", Citation{}},
+		{"Knapp, Gudrun-Axeli; Wetterer, Angelika\n (Hrsg.): Achsen der Differenz. Gesellschaftstheorie und feministische Kritik II (Münster: Westfälisches Dampfboot, 2003), 73–100",
+			Citation{
+				Editors:   []string{"Knapp, Gudrun-Axeli", "Wetterer, Angelika"},
+				Title:     "Achsen der Differenz. Gesellschaftstheorie und feministische Kritik II",
+				Places:    []string{"Münster"},
+				Publisher: "Westfälisches Dampfboot",
+				Year:      "2003",
+				StartPage: "73",
+				EndPage:   "100",
+			}},
+		{"Becker, Ruth (Hrsg.): Handbuch Frauen- und Geschlechterforschung. 3., überarb. u. erw. Aufl. (Wiesbaden: VS Verlag, 2010), S. 12-20",
+			Citation{
+				Editors:   []string{"Becker, Ruth"},
+				Title:     "Handbuch Frauen- und Geschlechterforschung",
+				Edition:   "3., überarb. u. erw. Aufl.",
+				Places:    []string{"Wiesbaden"},
+				Publisher: "VS Verlag",
+				Year:      "2010",
+				StartPage: "12",
+				EndPage:   "20",
+			}},
+		{"Lenz, Ilse (Hrsg.): Die Neue Frauenbewegung. 2. Aufl. (Opladen/Farmington Hills: Budrich, 2010)",
+			Citation{
+				Editors:   []string{"Lenz, Ilse"},
+				Title:     "Die Neue Frauenbewegung",
+				Edition:   "2. Aufl.",
+				Places:    []string{"Opladen", "Farmington Hills"},
+				Publisher: "Budrich",
+				Year:      "2010",
+			}},
+		{"In: Smith, Jane / Doe, John (Eds.): Gender and Power (London: Routledge, 2005), pp. 1-20",
+			Citation{
+				Editors:   []string{"Smith, Jane", "Doe, John"},
+				Title:     "Gender and Power",
+				Places:    []string{"London"},
+				Publisher: "Routledge",
+				Year:      "2005",
+				StartPage: "1",
+				EndPage:   "20",
+			}},
+		{"Feministische Studien 21 (2003), 5-17",
+			Citation{Title: "Feministische Studien 21", Year: "2003", StartPage: "5", EndPage: "17"}},
+		{"Feministische Studien, Jg. 21, H. 2, 2003, 5-20",
+			Citation{Title: "Feministische Studien, Jg. 21, H. 2", Year: "2003", StartPage: "5", EndPage: "20"}},
+		{"Feministische Studien, 5-20",
+			Citation{Title: "Feministische Studien", StartPage: "5", EndPage: "20"}},
+		{"Feministische Studien, Heft 2",
+			Citation{Title: "Feministische Studien, Heft 2"}},
+	}
+	for _, c := range cases {
+		if result := Parse(c.s); !reflect.DeepEqual(result, c.result) {
+			t.Errorf("Parse(%q): got %+v, want %+v", c.s, result, c.result)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Package classify maps subject headings to standard classifications, like
+// DDC, RVK or BK. Mappings are tab separated files, one heading per line,
+// followed by one or more notations:
+//
+//	Accounting	657
+//	Acoustics and Ultrasonics	534	620.2
+//
+// Lines starting with # are ignored. A YAML file names the mapping tables and
+// selects them per source id; sources without an entry use "default":
+//
+//	tables:
+//	  crossref-ddc:
+//	    scheme: ddc
+//	    file: /etc/span/crossref-ddc.tsv
+//	  crossref-rvk:
+//	    scheme: rvk
+//	    file: /etc/span/crossref-rvk.tsv
+//	sources:
+//	  "49": [crossref-ddc, crossref-rvk]
+//	  default: [crossref-ddc]
+//
+// Relative file names are resolved against the directory of the YAML file.
+package classify
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/miku/span/container"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Supported classification schemes.
+const (
+	DDC = "ddc"
+	RVK = "rvk"
+	BK  = "bk"
+)
+
+// DefaultSource selects tables for sources without an entry.
+const DefaultSource = "default"
+
+// Schemes are the supported classification schemes.
+var Schemes = container.NewStringSet(DDC, RVK, BK)
+
+// Table maps normalized headings to notations of a single scheme.
+type Table struct {
+	Scheme  string
+	Entries map[string][]string
+}
+
+// normalize lowercases a heading and collapses whitespace, so lookups
+// tolerate minor variations.
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// LoadTable reads a tab separated mapping for a scheme.
+func LoadTable(scheme string, r io.Reader) (*Table, error) {
+	if !Schemes.Contains(scheme) {
+		return nil, fmt.Errorf("classify: unknown scheme: %s", scheme)
+	}
+	t := &Table{Scheme: scheme, Entries: make(map[string][]string)}
+	br := bufio.NewScanner(r)
+	var i int
+	for br.Scan() {
+		i++
+		line := strings.TrimSpace(br.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		heading := normalize(fields[0])
+		var notations []string
+		for _, f := range fields[1:] {
+			if f = strings.TrimSpace(f); f != "" {
+				notations = append(notations, f)
+			}
+		}
+		if heading == "" || len(notations) == 0 {
+			return nil, fmt.Errorf("classify: line %d: expected heading and notation", i)
+		}
+		t.Entries[heading] = append(t.Entries[heading], notations...)
+	}
+	if err := br.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Lookup returns the notations for a heading.
+func (t *Table) Lookup(heading string) []string {
+	return t.Entries[normalize(heading)]
+}
+
+// Classifier maps the headings of a record to notations, using the tables
+// configured for its source. Headings without any notation are counted. A
+// classifier is safe for concurrent use.
+type Classifier struct {
+	Tables  map[string]*Table
+	Sources map[string][]string
+
+	mu       sync.Mutex
+	unmapped map[string]map[string]int // source id, heading, count
+}
+
+// config is the YAML representation of a classifier.
+type config struct {
+	Tables map[string]struct {
+		Scheme string `yaml:"scheme"`
+		File   string `yaml:"file"`
+	} `yaml:"tables"`
+	Sources map[string][]string `yaml:"sources"`
+}
+
+// LoadFile reads a configuration and all referenced tables.
+func LoadFile(filename string) (*Classifier, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var c config
+	dec := yaml.NewDecoder(f)
+	dec.SetStrict(true)
+	if err := dec.Decode(&c); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("classify: %s: %v", filename, err)
+	}
+	cl := &Classifier{Tables: make(map[string]*Table), Sources: c.Sources}
+	for name, spec := range c.Tables {
+		path := spec.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(filename), path)
+		}
+		tf, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		t, err := LoadTable(spec.Scheme, tf)
+		tf.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%v (table %s)", err, name)
+		}
+		cl.Tables[name] = t
+	}
+	if err := cl.validate(); err != nil {
+		return nil, err
+	}
+	return cl, nil
+}
+
+// validate checks, that sources only reference existing tables.
+func (c *Classifier) validate() error {
+	for sid, names := range c.Sources {
+		for _, name := range names {
+			if _, ok := c.Tables[name]; !ok {
+				return fmt.Errorf("classify: source %s: unknown table: %s", sid, name)
+			}
+		}
+	}
+	return nil
+}
+
+// tables returns the tables for a source.
+func (c *Classifier) tables(sid string) []string {
+	if names, ok := c.Sources[sid]; ok {
+		return names
+	}
+	return c.Sources[DefaultSource]
+}
+
+// Classify returns sorted notations by scheme for the headings of a record
+// of a given source.
+func (c *Classifier) Classify(sid string, headings []string) map[string][]string {
+	names := c.tables(sid)
+	if len(names) == 0 {
+		return nil
+	}
+	sets := make(map[string]*container.StringSet)
+	for _, h := range headings {
+		var found bool
+		for _, name := range names {
+			t := c.Tables[name]
+			for _, v := range t.Lookup(h) {
+				if sets[t.Scheme] == nil {
+					sets[t.Scheme] = container.NewStringSet()
+				}
+				sets[t.Scheme].Add(v)
+				found = true
+			}
+		}
+		if !found && strings.TrimSpace(h) != "" {
+			c.countUnmapped(sid, h)
+		}
+	}
+	result := make(map[string][]string)
+	for scheme, set := range sets {
+		result[scheme] = set.SortedValues()
+	}
+	return result
+}
+
+func (c *Classifier) countUnmapped(sid, heading string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.unmapped == nil {
+		c.unmapped = make(map[string]map[string]int)
+	}
+	if c.unmapped[sid] == nil {
+		c.unmapped[sid] = make(map[string]int)
+	}
+	c.unmapped[sid][heading]++
+}
+
+// Unmapped returns the headings without notation by source id, along with
+// the number of occurrences.
+func (c *Classifier) Unmapped() map[string]map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := make(map[string]map[string]int)
+	for sid, m := range c.unmapped {
+		result[sid] = make(map[string]int)
+		for k, v := range m {
+			result[sid][k] = v
+		}
+	}
+	return result
+}
+
+// WriteUnmapped writes unmapped headings as tab separated source id, count
+// and heading, most frequent first.
+func (c *Classifier) WriteUnmapped(w io.Writer) error {
+	type row struct {
+		sid, heading string
+		count        int
+	}
+	var rows []row
+	for sid, m := range c.Unmapped() {
+		for heading, count := range m {
+			rows = append(rows, row{sid, heading, count})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].count != rows[j].count {
+			return rows[i].count > rows[j].count
+		}
+		if rows[i].sid != rows[j].sid {
+			return rows[i].sid < rows[j].sid
+		}
+		return rows[i].heading < rows[j].heading
+	})
+	bw := bufio.NewWriter(w)
+	for _, r := range rows {
+		if _, err := fmt.Fprintf(bw, "%s\t%d\t%s\n", r.sid, r.count, r.heading); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
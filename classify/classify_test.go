@@ -0,0 +1,97 @@
+package classify
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadTable(t *testing.T) {
+	var cases = []struct {
+		s   string
+		err bool
+	}{
+		{"# comment\nAccounting\t657\n\nAcoustics and Ultrasonics\t534\t620.2\n", false},
+		{"Accounting\n", true},
+		{"\t657\n", true},
+	}
+	for _, c := range cases {
+		_, err := LoadTable(DDC, strings.NewReader(c.s))
+		if (err != nil) != c.err {
+			t.Errorf("LoadTable(%q): got %v, want error %v", c.s, err, c.err)
+		}
+	}
+	if _, err := LoadTable("lcc", strings.NewReader("")); err == nil {
+		t.Errorf("expected error for unknown scheme")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-classify-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"ddc.tsv": "Accounting\t657\nAcoustics  and Ultrasonics\t534\t620.2\n",
+		"rvk.tsv": "accounting\tQP 800\n",
+		"classify.yaml": `
+tables:
+  ddc: {scheme: ddc, file: ddc.tsv}
+  rvk: {scheme: rvk, file: rvk.tsv}
+sources:
+  "49": [ddc, rvk]
+  default: [ddc]
+`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cl, err := LoadFile(filepath.Join(dir, "classify.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases = []struct {
+		sid      string
+		headings []string
+		result   map[string][]string
+	}{
+		{"49", []string{"Accounting", "acoustics and ultrasonics", "Ageing"},
+			map[string][]string{DDC: {"534", "620.2", "657"}, RVK: {"QP 800"}}},
+		{"55", []string{"Accounting"}, map[string][]string{DDC: {"657"}}},
+		{"55", []string{"Ageing"}, map[string][]string{}},
+	}
+	for _, c := range cases {
+		if result := cl.Classify(c.sid, c.headings); !reflect.DeepEqual(result, c.result) {
+			t.Errorf("Classify(%s, %v): got %v, want %v", c.sid, c.headings, result, c.result)
+		}
+	}
+	var buf bytes.Buffer
+	if err := cl.WriteUnmapped(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "49\t1\tAgeing\n55\t1\tAgeing\n"; buf.String() != want {
+		t.Errorf("WriteUnmapped: got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestLoadFileUnknownTable(t *testing.T) {
+	f, err := ioutil.TempFile("", "span-classify-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("sources:\n  \"49\": [missing]\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := LoadFile(f.Name()); err == nil {
+		t.Errorf("expected error for unknown table")
+	}
+}
@@ -0,0 +1,116 @@
+// Package cleanup repairs text fields of converted records: double encoded
+// HTML entities like &amp;amp;, stray markup like <i> or <jats:p>, control
+// characters and Unicode, which is normalized to NFC.
+package cleanup
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/miku/span/container"
+	"github.com/miku/span/formats/finc"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxUnescape limits the number of decoding rounds, e.g. &amp;amp;lt; needs
+// three.
+const maxUnescape = 3
+
+// tagPattern matches common inline HTML and JATS tags and any namespaced tag,
+// e.g. <jats:p> or <mml:math>, with attributes.
+var tagPattern = regexp.MustCompile(`(?i)</?((?:[a-z]+:[a-z][a-z0-9._-]*|a|b|i|u|p|br|em|strong|sub|sup|span|div|font|small|big|tt|sc|italic|bold|underline|title|sec|list|list-item|inline-formula|named-content))(?:\s[^<>]*)?/?>`)
+
+// blockTags are replaced by a space, other tags are removed.
+var blockTags = container.NewStringSet("p", "br", "div", "sec", "title", "list", "list-item")
+
+// Text cleans a single value and collapses whitespace, e.g. for titles and
+// names.
+func Text(s string) string {
+	return strings.Join(strings.Fields(clean(s)), " ")
+}
+
+// Paragraphs cleans a value like Text, but keeps line breaks, e.g. for
+// abstracts.
+func Paragraphs(s string) string {
+	lines := strings.Split(clean(s), "\n")
+	result := lines[:0]
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			result = append(result, line)
+		}
+	}
+	return strings.Join(result, "\n")
+}
+
+// clean decodes entities, removes tags and control characters, except line
+// breaks, and normalizes to NFC.
+func clean(s string) string {
+	for i := 0; i < maxUnescape && strings.Contains(s, "&"); i++ {
+		t := html.UnescapeString(s)
+		if t == s {
+			break
+		}
+		s = t
+	}
+	if strings.Contains(s, "<") {
+		s = tagPattern.ReplaceAllStringFunc(s, func(tag string) string {
+			name := strings.ToLower(tagPattern.FindStringSubmatch(tag)[1])
+			if i := strings.Index(name, ":"); i >= 0 {
+				name = name[i+1:]
+			}
+			if blockTags.Contains(name) {
+				return " "
+			}
+			return ""
+		})
+	}
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n':
+			return r
+		case r == '\t' || r == '\r':
+			return ' '
+		case unicode.IsControl(r), r == '\ufeff', r == '\u200b', r == unicode.ReplacementChar:
+			return -1
+		}
+		return r
+	}, s)
+	return norm.NFC.String(s)
+}
+
+// Record cleans the text fields of a record. Fulltext is left as is. It
+// returns true, if any value changed.
+func Record(is *finc.IntermediateSchema) bool {
+	var changed bool
+	text := func(s *string) {
+		if v := Text(*s); v != *s {
+			*s, changed = v, true
+		}
+	}
+	texts := func(ss []string) {
+		for i := range ss {
+			text(&ss[i])
+		}
+	}
+	for _, s := range []*string{&is.ArticleTitle, &is.ArticleSubtitle, &is.BookTitle,
+		&is.JournalTitle, &is.ShortTitle, &is.Series, &is.Edition} {
+		text(s)
+	}
+	for _, ss := range [][]string{is.Publishers, is.Places, is.Subjects, is.Headings,
+		is.Footnotes} {
+		texts(ss)
+	}
+	for i := range is.Authors {
+		a := &is.Authors[i]
+		for _, s := range []*string{&a.Name, &a.FirstName, &a.LastName, &a.Corporate} {
+			text(s)
+		}
+		texts(a.Affiliations)
+	}
+	if v := Paragraphs(is.Abstract); v != is.Abstract {
+		is.Abstract, changed = v, true
+	}
+	return changed
+}
@@ -0,0 +1,58 @@
+package cleanup
+
+import (
+	"testing"
+
+	"github.com/miku/span/formats/finc"
+)
+
+func TestText(t *testing.T) {
+	var cases = []struct {
+		s, result string
+	}{
+		{"Fish &amp;amp; Chips", "Fish & Chips"},
+		{"Fish &amp;amp;amp; Chips", "Fish & Chips"},
+		{"&lt;i&gt;Homo sapiens&lt;/i&gt; in Europe", "Homo sapiens in Europe"},
+		{"H<sub>2</sub>O and <i>E. coli</i>", "H2O and E. coli"},
+		{"<jats:title>A</jats:title><jats:p>B</jats:p>", "A B"},
+		{"a < b and c > d", "a < b and c > d"},
+		{"x<y", "x<y"},
+		{"Line\x00 with\tcontrol\u200b characters\ufeff", "Line with control characters"},
+		{"Cafe\u0301", "Caf\u00e9"},
+		{"  many   spaces ", "many spaces"},
+		{"", ""},
+	}
+	for _, c := range cases {
+		if result := Text(c.s); result != c.result {
+			t.Errorf("Text(%q): got %q, want %q", c.s, result, c.result)
+		}
+	}
+}
+
+func TestParagraphs(t *testing.T) {
+	s := "First  line &amp;amp; more\n\n<p>Second</p>\r\n"
+	want := "First line & more\nSecond"
+	if result := Paragraphs(s); result != want {
+		t.Errorf("got %q, want %q", result, want)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	is := finc.IntermediateSchema{
+		ArticleTitle: "Fish &amp;amp; Chips",
+		Authors:      []finc.Author{{LastName: "Müller ", FirstName: "Hans"}},
+		Fulltext:     "Fish &amp;amp; Chips",
+	}
+	if !Record(&is) {
+		t.Fatalf("expected change")
+	}
+	if is.ArticleTitle != "Fish & Chips" || is.Authors[0].LastName != "Müller" {
+		t.Errorf("got %q, %q", is.ArticleTitle, is.Authors[0].LastName)
+	}
+	if is.Fulltext != "Fish &amp;amp; Chips" {
+		t.Errorf("fulltext changed: %q", is.Fulltext)
+	}
+	if Record(&is) {
+		t.Errorf("expected no change on second run")
+	}
+}
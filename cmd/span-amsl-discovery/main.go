@@ -1,5 +1,13 @@
 // The span-amsl-discovery tool will create a discovery (now defunkt) like API
 // response from available AMSL endpoints, refs #14456, #14415.
+//
+// With -f, a filter configuration for span-tag is written instead, so tagging
+// follows the collections, ISIL and holding files configured in AMSL:
+//
+//	$ span-amsl-discovery -live https://amsl.example.com -f > filterconfig.json
+//	$ span-tag -c filterconfig.json < intermediate.ldj > tagged.ldj
+//
+// An existing discovery file can be converted with -i.
 package main
 
 import (
@@ -7,284 +15,57 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"os"
-	"strings"
 
-	"github.com/miku/span"
-	"github.com/sethgrid/pester"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/miku/span"
+	"github.com/miku/span/amsl"
 )
 
 var (
-	live       = flag.String("live", "https://example.technology", "AMSL live base url")
-	allowEmpty = flag.Bool("allow-empty", false, "allow empty responses from api")
+	live         = flag.String("live", "https://example.technology", "AMSL live base url")
+	allowEmpty   = flag.Bool("allow-empty", false, "allow empty responses from api")
+	filterConfig = flag.Bool("f", false, "write span-tag filter configuration instead of discovery response")
+	inputFile    = flag.String("i", "", "read discovery response from file instead of api")
+	showVersion  = flag.Bool("v", false, "prints current program version")
 )
 
-// Discovery API response (now defunkt).
-type Discovery struct {
-	ContentFileLabel               string `json:"contentFileLabel,omitempty"`
-	ContentFileURI                 string `json:"contentFileURI,omitempty"`
-	DokumentLabel                  string `json:"DokumentLabel,omitempty"`
-	DokumentURI                    string `json:"DokumentURI,omitempty"`
-	EvaluateHoldingsFileForLibrary string `json:"evaluateHoldingsFileForLibrary"`
-	ExternalLinkToContentFile      string `json:"externalLinkToContentFile,omitempty"`
-	HoldingsFileLabel              string `json:"holdingsFileLabel,omitempty"`
-	HoldingsFileURI                string `json:"holdingsFileURI,omitempty"`
-	ISIL                           string `json:"ISIL"`
-	LinkToContentFile              string `json:"linkToContentFile,omitempty"`
-	LinkToHoldingsFile             string `json:"linkToHoldingsFile,omitempty"`
-	MegaCollection                 string `json:"megaCollection"`
-	ProductISIL                    string `json:"productISIL"`
-	ShardLabel                     string `json:"shardLabel"`
-	SourceID                       string `json:"sourceID"`
-	TechnicalCollectionID          string `json:"technicalCollectionID"`
-}
-
-// MetadataUsage entry from metadata_usage and metadata_usage_concat endpoints.
-type MetadataUsage struct {
-	ISIL                  string `json:"ISIL"`
-	MegaCollection        string `json:"megaCollection"`
-	ProductISIL           string `json:"productISIL"`
-	ShardLabel            string `json:"shardLabel"`
-	SourceID              string `json:"sourceID"`
-	TechnicalCollectionID string `json:"technicalCollectionID"`
-}
-
-// ContentFiles entry from contentfiles endpoint.
-type ContentFiles struct {
-	ContentFileLabel      string `json:"contentFileLabel"`
-	ContentFileURI        string `json:"contentFileURI"`
-	LinkToContentFile     string `json:"linkToContentFile"`
-	MegaCollection        string `json:"megaCollection"`
-	TechnicalCollectionID string `json:"technicalCollectionID"`
-}
-
-// HoldingsFiles entry from holdingsfiles endpoint.
-type HoldingsFiles struct {
-	DokumentLabel string `json:"DokumentLabel"`
-	DokumentURI   string `json:"DokumentURI"`
-	ISIL          string `json:"ISIL"`
-	LinkToFile    string `json:"LinkToFile"`
-}
-
-// HoldingsFileConcat entry from holdings_file_concat endpoint.
-type HoldingsFileConcat struct {
-	ISIL                  string `json:"ISIL"`
-	MegaCollection        string `json:"megaCollection"`
-	ProductISIL           string `json:"productISIL"`
-	ShardLabel            string `json:"shardLabel"`
-	SourceID              string `json:"sourceID"`
-	TechnicalCollectionID string `json:"technicalCollectionID"`
-}
-
-// SeparatedFields splits s on given separator and trims whitespace.
-func SeparatedFields(s, sep string) (result []string) {
-	for _, v := range strings.Split(s, sep) {
-		result = append(result, strings.TrimSpace(v))
-	}
-	return
-}
-
-// readFrom decodes data from reader into value, returning bytes read.
-func readFrom(r io.Reader, v interface{}) (int64, error) {
-	rc := span.NewReaderCounter(r)
-	if err := json.NewDecoder(rc).Decode(v); err != nil {
-		return 0, err
-	}
-	return rc.Count(), nil
-}
-
-// MetadataUsageResponse group many metadata usage items.
-type MetadataUsageResponse []MetadataUsage
-
-// ReadFrom populates a response with MetadataUsage items.
-func (resp *MetadataUsageResponse) ReadFrom(r io.Reader) (int64, error) {
-	return readFrom(r, resp)
-}
-
-// ContentFilesResponse group many content files items.
-type ContentFilesResponse []ContentFiles
-
-// ReadFrom populates a response with MetadataUsage items.
-func (resp *ContentFilesResponse) ReadFrom(r io.Reader) (int64, error) {
-	return readFrom(r, resp)
-}
-
-// HoldingsFiles group many holdings files items.
-type HoldingsFilesResponse []HoldingsFiles
-
-// ReadFrom populates a response with MetadataUsage items.
-func (resp *HoldingsFilesResponse) ReadFrom(r io.Reader) (int64, error) {
-	return readFrom(r, resp)
-}
-
-// HoldingsFileConcatResponse group many holdings files (concat) items.
-type HoldingsFileConcatResponse []HoldingsFileConcat
-
-// ReadFrom populates a response with MetadataUsage items.
-func (resp *HoldingsFileConcatResponse) ReadFrom(r io.Reader) (int64, error) {
-	return readFrom(r, resp)
-}
-
-// fetchLocation fetches a HTTP location into a io.ReaderFrom.
-func fetchLocation(location string, r io.ReaderFrom) (int64, error) {
-	log.Printf("fetching %s", location)
-	resp, err := pester.Get(location)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-	return r.ReadFrom(resp.Body)
-}
-
-// fetchFrom constructs a link and fetches the response into given io.ReaderFrom.
-func fetchFrom(base, kind string, r io.ReaderFrom) (int64, error) {
-	loc := fmt.Sprintf("%s/outboundservices/list?do=%s", base, kind)
-	return fetchLocation(loc, r)
-}
-
 func main() {
 	flag.Parse()
 
-	var (
-		mur MetadataUsageResponse
-		hcr HoldingsFileConcatResponse
-		hfr HoldingsFilesResponse
-		cfr ContentFilesResponse
-	)
-
-	// Where we get the data from.
-	fetchlist := []struct {
-		base string        // The AMSL base URL.
-		kind string        // Name of the query.
-		r    io.ReaderFrom // Struct wrapped in a ReaderFrom.
-	}{
-		{*live, "metadata_usage", &mur},
-		{*live, "holdings_file_concat", &hcr},
-		{*live, "holdingsfiles", &hfr},
-		{*live, "contentfiles", &cfr},
+	if *showVersion {
+		fmt.Println(span.AppVersion)
+		os.Exit(0)
 	}
 
-	for _, ff := range fetchlist {
-		if _, err := fetchFrom(ff.base, ff.kind, ff.r); err != nil {
+	var updates []amsl.Discovery
+	if *inputFile != "" {
+		f, err := os.Open(*inputFile)
+		if err != nil {
 			log.Fatal(err)
 		}
-	}
-
-	// For debugging.
-	sizes := map[string]int{
-		"metadata_usage":       len(mur),
-		"holdings_file_concat": len(hcr),
-		"holdingsfiles":        len(hfr),
-		"contentfiles":         len(cfr),
-	}
-	for k, v := range sizes {
-		log.Printf("%s: %d", k, v)
-		if v == 0 && !*allowEmpty {
-			log.Fatalf("empty response from %s", k)
-		}
-	}
-
-	// This should contain an approximation of the discovery endpoint.
-	var updates []Discovery
-
-	for i, mu := range mur {
-		if i%10000 == 0 {
-			log.Printf("%d of %d done", i, len(mur))
-		}
-		if mu.MegaCollection == "" {
-			log.Printf("skipping empty megaCollection in #L%d", i)
-			continue
-		}
-
-		// Defunkt update.
-		update := Discovery{
-			ISIL:                  mu.ISIL,
-			MegaCollection:        mu.MegaCollection,
-			ProductISIL:           mu.ProductISIL,
-			ShardLabel:            mu.ShardLabel,
-			SourceID:              mu.SourceID,
-			TechnicalCollectionID: mu.TechnicalCollectionID,
-		}
-
-		// Merge fields from content file.
-		for _, cf := range cfr {
-			if cf.MegaCollection != mu.MegaCollection {
-				continue
-			}
-			update.ContentFileLabel = cf.ContentFileLabel
-			update.ContentFileURI = cf.ContentFileURI
-			update.LinkToContentFile = cf.LinkToContentFile
-			break
-		}
-
-		// Default is negative.
-		update.EvaluateHoldingsFileForLibrary = "no"
-
-		// Incorporate new holdings file concat response.
-		for _, hc := range hcr {
-			if hc.MegaCollection != mu.MegaCollection {
-				continue
-			}
-			// ISIL is a list, separated by semicolons.
-			for _, isil := range SeparatedFields(hc.ISIL, ";") {
-				if isil != mu.ISIL {
-					continue
-				}
-				update.ProductISIL = hc.ProductISIL
-				update.ShardLabel = hc.ShardLabel
-				update.EvaluateHoldingsFileForLibrary = "yes"
-				break
-			}
-		}
-
-		// Add link to content file.
-		if update.ContentFileURI != "" {
-			if strings.HasPrefix(update.ContentFileURI, "http://amsl") {
-				update.LinkToContentFile = fmt.Sprintf(
-					"%s/OntoWiki/files/get?setResource=%s", *live, update.ContentFileURI)
-			}
-		}
-
-		// No holding file required? Next item.
-		if update.EvaluateHoldingsFileForLibrary == "no" {
-			updates = append(updates, update)
-			continue
+		defer f.Close()
+		if updates, err = amsl.ReadDiscovery(f); err != nil {
+			log.Fatal(err)
 		}
-
-		for _, hf := range hfr {
-			if hf.ISIL != mu.ISIL {
-				continue
-			}
-			// Create a new item for each holding file.
-			ndoc := Discovery{
-				DokumentLabel:                  hf.DokumentLabel,
-				DokumentURI:                    hf.DokumentURI,
-				LinkToHoldingsFile:             hf.LinkToFile,
-				ContentFileLabel:               update.ContentFileLabel,
-				ContentFileURI:                 update.ContentFileURI,
-				EvaluateHoldingsFileForLibrary: update.EvaluateHoldingsFileForLibrary,
-				ISIL:                           update.ISIL,
-				LinkToContentFile:              update.LinkToContentFile,
-				MegaCollection:                 update.MegaCollection,
-				ProductISIL:                    update.ProductISIL,
-				ShardLabel:                     update.ShardLabel,
-				SourceID:                       update.SourceID,
-				TechnicalCollectionID:          update.TechnicalCollectionID,
-			}
-			if hf.DokumentURI != "" {
-				ndoc.LinkToHoldingsFile = fmt.Sprintf(
-					"%s/OntoWiki/files/get?setResource=%s", *live, hf.DokumentURI)
-			}
-			updates = append(updates, ndoc)
+	} else {
+		client := amsl.Client{Base: *live, AllowEmpty: *allowEmpty}
+		responses, err := client.Fetch()
+		if err != nil {
+			log.Fatal(err)
 		}
+		updates = responses.Discover(*live)
 	}
 
 	bw := bufio.NewWriter(os.Stdout)
 	defer bw.Flush()
 
-	if err := json.NewEncoder(bw).Encode(updates); err != nil {
+	var v interface{} = updates
+	if *filterConfig {
+		v = amsl.FilterConfig(updates)
+	}
+	if err := json.NewEncoder(bw).Encode(v); err != nil {
 		log.Fatal(err)
 	}
 }
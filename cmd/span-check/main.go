@@ -3,6 +3,7 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -13,9 +14,14 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/logging"
 	"github.com/miku/span/parallel"
 	"github.com/miku/span/quality"
+	"github.com/miku/span/schema"
+	"github.com/miku/span/stats"
 )
 
 func main() {
@@ -24,22 +30,60 @@ func main() {
 	showVersion := flag.Bool("v", false, "prints current program version")
 	size := flag.Int("b", 20000, "batch size")
 	numWorkers := flag.Int("w", runtime.NumCPU(), "number of workers")
+	resolve := flag.Bool("doi-resolve", false, "check, whether DOI are registered, one request per record")
+	schemaFile := flag.String("schema", "", "validate against JSON schema file instead, e.g. schema/is-1.0.json")
+	scoreMode := flag.Bool("score", false, "write a quality score per record instead, and a summary per source to stderr")
+	corpusMode := flag.Bool("corpus", false, "write corpus statistics instead: counts per source, collection, year and language, DOI coverage and field completeness")
+	corpusFormat := flag.String("corpus-format", "json", "format of corpus statistics: json or csv")
+	logOptions := logging.RegisterFlags(flag.CommandLine)
 
 	flag.Parse()
+	if err := assetutil.Err(); err != nil {
+		log.Fatal(err)
+	}
+	if err := logOptions.Setup(); err != nil {
+		log.Fatal(err)
+	}
 
 	if *showVersion {
 		fmt.Println(span.AppVersion)
 		os.Exit(0)
 	}
 
+	if *schemaFile != "" {
+		if err := validate(*schemaFile, *verbose, *numWorkers, *size); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *corpusMode {
+		if err := corpus(*corpusFormat, *numWorkers, *size); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *scoreMode {
+		if err := score(*numWorkers, *size); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	errStats := make(map[string]*int64)
 
+	suite := quality.TestSuiteFinc
+	if *resolve {
+		suite = append(suite, quality.DOIResolves{Resolver: &doi.Resolver{}})
+	}
+
 	p := parallel.NewProcessor(bufio.NewReader(os.Stdin), os.Stdout, func(_ int64, b []byte) ([]byte, error) {
 		var is finc.IntermediateSchema
-		if err := json.Unmarshal(b, &is); err != nil {
+		if err := finc.UnmarshalIntermediateSchema(b, &is); err != nil {
 			return b, err
 		}
-		for _, t := range quality.TestSuiteFinc {
+		for _, t := range suite {
 			if err := t.TestRecord(is); err != nil {
 				issue, ok := err.(quality.Issue)
 				if !ok {
@@ -74,3 +118,93 @@ func main() {
 		fmt.Println(string(b))
 	}
 }
+
+// validate checks records against a JSON schema and writes counts and sample
+// ids per violation. With verbose, the violations of each invalid record are
+// written as well.
+func validate(filename string, verbose bool, numWorkers, size int) error {
+	s, err := schema.LoadFile(filename)
+	if err != nil {
+		return err
+	}
+	report := schema.NewReport()
+	p := parallel.NewProcessor(bufio.NewReader(os.Stdin), os.Stdout, func(_ int64, b []byte) ([]byte, error) {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		id, _ := doc["finc.id"].(string)
+		vs := s.Validate(doc)
+		report.Add(id, vs)
+		if !verbose || len(vs) == 0 {
+			return nil, nil
+		}
+		var messages []string
+		for _, v := range vs {
+			messages = append(messages, v.Error())
+		}
+		bb, err := json.Marshal(map[string]interface{}{"id": id, "violations": messages})
+		if err != nil {
+			return nil, err
+		}
+		return append(bb, '\n'), nil
+	})
+	p.NumWorkers = numWorkers
+	p.BatchSize = size
+	if err := p.Run(); err != nil {
+		return err
+	}
+	return json.NewEncoder(os.Stdout).Encode(report)
+}
+
+// score writes a quality assessment per record and a summary per source to
+// stderr.
+func score(numWorkers, size int) error {
+	summary := quality.NewScoreSummary()
+	p := parallel.NewProcessor(bufio.NewReader(os.Stdin), os.Stdout, func(_ int64, b []byte) ([]byte, error) {
+		var is finc.IntermediateSchema
+		if err := finc.UnmarshalIntermediateSchema(b, &is); err != nil {
+			return nil, err
+		}
+		a := quality.Assess(is)
+		summary.Add(a)
+		bb, err := json.Marshal(a)
+		if err != nil {
+			return nil, err
+		}
+		return append(bb, '\n'), nil
+	})
+	p.NumWorkers = numWorkers
+	p.BatchSize = size
+	if err := p.Run(); err != nil {
+		return err
+	}
+	return json.NewEncoder(os.Stderr).Encode(summary)
+}
+
+// corpus writes statistics over all records, as JSON or CSV.
+func corpus(format string, numWorkers, size int) error {
+	if format != "json" && format != "csv" {
+		return fmt.Errorf("unknown corpus format: %s", format)
+	}
+	c := stats.NewCorpus()
+	p := parallel.NewProcessor(bufio.NewReader(os.Stdin), os.Stdout, func(_ int64, b []byte) ([]byte, error) {
+		var is finc.IntermediateSchema
+		if err := finc.UnmarshalIntermediateSchema(b, &is); err != nil {
+			return nil, err
+		}
+		c.Add(&is)
+		return nil, nil
+	})
+	p.NumWorkers = numWorkers
+	p.BatchSize = size
+	if err := p.Run(); err != nil {
+		return err
+	}
+	if format == "csv" {
+		return c.WriteCSV(os.Stdout)
+	}
+	return c.WriteJSON(os.Stdout)
+}
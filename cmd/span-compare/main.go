@@ -13,22 +13,49 @@
 // DE-D275    48   GBI Genios Wiso                     10501192  2050930   -8450262
 // DE-Gla1    49   Crossref                            31640516  31467567  -172949
 // ...
+//
+// Rows with ISIL ALL contain the number of records per source, regardless of
+// institution. Use -f markdown or -f csv for a table to paste elsewhere.
+//
+// With -old and -new, compare two tagging runs instead, e.g. yesterday's
+// tagged intermediate schema (or a SOLR dump) with today's span-tag output and
+// report ISILs attached or detached per source and collection, before
+// reindexing.
+//
+// $ span-compare -old yesterday.ldj.gz -new today.ldj.gz
+// DE-14    49   Crossref   Springer (CrossRef)   0   1207   0   12   ai-49-aHR0c...
+// ...
+//
+// With -records, compare two conversion runs record by record, e.g. span-import
+// output before and after a converter change, and report records added,
+// removed or changed per source, and the number of records changed per field.
+// Volatile fields, like version, are ignored, see -ignore.
+//
+// $ span-compare -records -old before.ldj.gz -new after.ldj.gz
+// 49   Crossref   changed   rft.atitle   1207   ai-49-aHR0c...
+// ...
 package main
 
 import (
 	"bytes"
+	"encoding/csv"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"os"
 	"path"
+	"runtime"
 	"sort"
 	"strings"
 	"text/template"
 
 	"github.com/miku/span"
+	"github.com/miku/span/isdiff"
+	"github.com/miku/span/parallel"
 	"github.com/miku/span/solrutil"
+	"github.com/miku/span/tagdiff"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -214,8 +241,14 @@ var (
 	liveLinkTemplate = flag.String("tl", "https://katalog.ub.uni-leipzig.de/Search/Results?lookfor=source_id:{{ .SourceID }}",
 		"live link template for source (for focus institution)")
 	spanConfigFile   = flag.String("span-config", defaultConfigPath, "for whatislive.url")
-	textile          = flag.Bool("t", false, "emit textile")
+	textile          = flag.Bool("t", false, "emit textile, same as -f textile")
+	format           = flag.String("f", "tab", "output format: tab, textile, markdown, csv")
 	focusInstitution = flag.String("emph", "DE-15", "emphasize institution in textile output")
+	oldFile          = flag.String("old", "", "previous tagged intermediate schema or SOLR dump, requires -new")
+	newFile          = flag.String("new", "", "current tagged intermediate schema or SOLR dump, requires -old")
+	numWorkers       = flag.Int("w", runtime.NumCPU(), "number of workers, with -old and -new")
+	records          = flag.Bool("records", false, "with -old and -new, compare intermediate schema records field by field")
+	ignoreFields     = flag.String("ignore", strings.Join(isdiff.DefaultIgnore, ","), "comma separated fields to ignore, with -records")
 )
 
 // ResultWriter for report generator.
@@ -225,6 +258,50 @@ type ResultWriter interface {
 	Err() error
 }
 
+// newResultWriter returns a writer for a format name.
+func newResultWriter(format string, w io.Writer) (ResultWriter, error) {
+	switch format {
+	case "tab":
+		return &TabWriter{w: w}, nil
+	case "textile":
+		return &TextileWriter{w: w}, nil
+	case "markdown":
+		return &MarkdownWriter{w: w}, nil
+	case "csv":
+		return &CSVWriter{w: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// formatField renders a single table cell.
+func formatField(f interface{}) string {
+	switch t := f.(type) {
+	case string:
+		return t
+	case fmt.Stringer:
+		return t.String()
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", t)
+	case float32, float64:
+		return fmt.Sprintf("%0.4f", t)
+	default:
+		return fmt.Sprintf("%s", t)
+	}
+}
+
+// emphasize marks a value as important in formats, that support it.
+func emphasize(format, s string) string {
+	switch format {
+	case "textile":
+		return fmt.Sprintf("*%s*", s)
+	case "markdown":
+		return fmt.Sprintf("**%s**", s)
+	default:
+		return s
+	}
+}
+
 // TabWriter is the simplest writer.
 type TabWriter struct {
 	w   io.Writer
@@ -279,22 +356,87 @@ func (w *TextileWriter) WriteFields(fields ...interface{}) {
 	}
 	var s []string
 	for _, f := range fields {
-		var v string
-		switch t := f.(type) {
-		case string:
-			v = t
-		case fmt.Stringer:
-			v = t.String()
-		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-			v = fmt.Sprintf("%d", t)
-		case float32, float64:
-			v = fmt.Sprintf("%0.4f", t)
-		default:
-			v = fmt.Sprintf("%s", t)
-		}
-		s = append(s, v)
+		s = append(s, formatField(f))
+	}
+	_, w.err = fmt.Fprintf(w.w, "| %s |\n", strings.Join(s, " | "))
+}
+
+// MarkdownWriter writes markdown tables.
+type MarkdownWriter struct {
+	w       io.Writer
+	columns int
+	err     error
+}
+
+// Err returns any error that happened.
+func (w *MarkdownWriter) Err() error {
+	return w.err
+}
+
+// WriteHeader writes a header and separator line and fixes the number of
+// columns.
+func (w *MarkdownWriter) WriteHeader(header ...string) {
+	if w.columns > 0 || w.err != nil {
+		return
 	}
-	_, w.err = fmt.Fprintf(w.w, fmt.Sprintf("| %s |\n", strings.Join(s, " | ")))
+	w.columns = len(header)
+	sep := make([]string, len(header))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	_, w.err = fmt.Fprintf(w.w, "| %s |\n| %s |\n", strings.Join(header, " | "), strings.Join(sep, " | "))
+}
+
+// WriteFields writes fields, pipes in values are escaped.
+func (w *MarkdownWriter) WriteFields(fields ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	if len(fields) != w.columns {
+		w.err = fmt.Errorf("got %d fields, want %d", len(fields), w.columns)
+		return
+	}
+	var s []string
+	for _, f := range fields {
+		s = append(s, strings.Replace(formatField(f), "|", "\\|", -1))
+	}
+	_, w.err = fmt.Fprintf(w.w, "| %s |\n", strings.Join(s, " | "))
+}
+
+// CSVWriter writes comma separated values, flushing each row.
+type CSVWriter struct {
+	w   *csv.Writer
+	err error
+}
+
+// Err returns any error that happened.
+func (w *CSVWriter) Err() error {
+	return w.err
+}
+
+// WriteHeader writes a header row.
+func (w *CSVWriter) WriteHeader(header ...string) {
+	w.writeRow(header)
+}
+
+// WriteFields writes a row.
+func (w *CSVWriter) WriteFields(fields ...interface{}) {
+	var s []string
+	for _, f := range fields {
+		s = append(s, formatField(f))
+	}
+	w.writeRow(s)
+}
+
+func (w *CSVWriter) writeRow(row []string) {
+	if w.err != nil {
+		return
+	}
+	if w.err = w.w.Write(row); w.err != nil {
+		return
+	}
+	w.w.Flush()
+	w.err = w.w.Error()
 }
 
 // prependHTTP prepends http, if necessary.
@@ -318,71 +460,155 @@ func renderSourceLink(tmpl string, data interface{}, text string) (string, error
 	return fmt.Sprintf(`"%s":%s`, text, buf.String()), nil
 }
 
-func main() {
-	flag.Parse()
-
-	if *whatIsLive {
-		// Fallback configuration.
-		if _, err := os.Stat(*spanConfigFile); os.IsNotExist(err) {
-			*spanConfigFile = "/etc/span/span.json"
-		}
-		if _, err := os.Stat(*spanConfigFile); os.IsNotExist(err) {
-			log.Fatal(err)
+// readLines calls f for each line of a possibly compressed file.
+func readLines(filename string, f func(b []byte) error) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	r, err := span.NewDecompressReader(file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	p := parallel.NewProcessor(r, ioutil.Discard, func(lineno int64, b []byte) ([]byte, error) {
+		if err := f(b); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", filename, lineno+1, err)
 		}
+		return nil, nil
+	})
+	p.NumWorkers = *numWorkers
+	return p.Run()
+}
 
-		var err error
-
-		*liveServer, err = solrutil.FindLiveSolrServer(*spanConfigFile)
+// readDocs reads a possibly compressed file with tagged documents.
+func readDocs(filename string, f func(tagdiff.Doc)) error {
+	return readLines(filename, func(b []byte) error {
+		doc, err := tagdiff.ParseDoc(b)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		*nonliveServer, err = solrutil.FindNonliveSolrServer(*spanConfigFile)
-		if err != nil {
-			log.Fatal(err)
+		f(doc)
+		return nil
+	})
+}
+
+// compareTagging reports ISILs attached or detached between two tagging runs.
+func compareTagging(rw ResultWriter) error {
+	d := tagdiff.New()
+	if err := readDocs(*oldFile, d.AddOld); err != nil {
+		return err
+	}
+	if err := readDocs(*newFile, d.AddNew); err != nil {
+		return err
+	}
+	rw.WriteHeader("ISIL", "Source", "Name", "Collection", "Attached", "Detached", "Added", "Removed", "Examples")
+	for _, c := range d.Changes() {
+		name, ok := SourceNames[c.SourceID]
+		if !ok {
+			name = "XXX: missing source name"
+		}
+		rw.WriteFields(c.ISIL, c.SourceID, name, c.Collection, c.Attached, c.Detached, c.Added, c.Removed,
+			strings.Join(c.IDs, " "))
+		if rw.Err() != nil {
+			return rw.Err()
 		}
-		log.Printf("live=%s, nonlive=%s", *liveServer, *nonliveServer)
 	}
+	return nil
+}
 
-	live := solrutil.Index{Server: prependHTTP(*liveServer)}
-	nonlive := solrutil.Index{Server: prependHTTP(*nonliveServer)}
-
-	sids, err := nonlive.SourceIdentifiers()
-	if err != nil {
-		log.Fatal(err)
+// compareRecords reports records added, removed or changed between two
+// conversion runs, with a row per source and kind of change, followed by a
+// row per changed field.
+func compareRecords(rw ResultWriter) error {
+	var ignore []string
+	for _, name := range strings.Split(*ignoreFields, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			ignore = append(ignore, name)
+		}
 	}
-	sort.Strings(sids)
-
-	institutions, err := nonlive.Institutions()
-	if err != nil {
-		log.Fatal(err)
+	d := isdiff.New(ignore...)
+	if err := readLines(*oldFile, d.AddOld); err != nil {
+		return err
 	}
-	sort.Strings(institutions)
+	if err := readLines(*newFile, d.AddNew); err != nil {
+		return err
+	}
+	rw.WriteHeader("Source", "Name", "Change", "Field", "Records", "Examples")
+	for _, c := range d.Counts() {
+		name, ok := SourceNames[c.SourceID]
+		if !ok {
+			name = "XXX: missing source name"
+		}
+		for _, v := range []struct {
+			change string
+			n      int64
+		}{
+			{"added", c.Added},
+			{"removed", c.Removed},
+			{"changed", c.Changed},
+			{"unchanged", c.Unchanged},
+		} {
+			rw.WriteFields(c.SourceID, name, v.change, "", v.n, strings.Join(c.IDs[v.change], " "))
+		}
+		var fields []string
+		for k := range c.Fields {
+			fields = append(fields, k)
+		}
+		sort.Strings(fields)
+		for _, k := range fields {
+			rw.WriteFields(c.SourceID, name, "changed", k, c.Fields[k], strings.Join(c.IDs[k], " "))
+		}
+		if rw.Err() != nil {
+			return rw.Err()
+		}
+	}
+	return nil
+}
 
-	var rw ResultWriter
-	switch {
-	case *textile:
-		rw = &TextileWriter{w: os.Stdout}
-	default:
-		rw = &TabWriter{w: os.Stdout}
+// compareIndexes reports the number of records per source, overall and per
+// institution, in the live and nonlive index.
+func compareIndexes(rw ResultWriter, live, nonlive solrutil.Index) error {
+	var sids, institutions []string
+	for _, ix := range []solrutil.Index{live, nonlive} {
+		v, err := ix.SourceIdentifiers()
+		if err != nil {
+			return err
+		}
+		sids = append(sids, v...)
+		if v, err = ix.Institutions(); err != nil {
+			return err
+		}
+		institutions = append(institutions, v...)
 	}
+	sids, institutions = unique(sids), unique(institutions)
 
 	rw.WriteHeader("ISIL", "Source", "Name", "Live", "Nonlive", "Diff", "Pct", "Comment")
 
-	// XXX: Parallelize.
-	for _, institution := range institutions {
-		if strings.TrimSpace(institution) == "" {
-			continue
+	// The first row group contains the total number of records per source.
+	for _, institution := range append([]string{""}, institutions...) {
+		query, renderInstitution := "*:*", "ALL"
+		if institution != "" {
+			if strings.TrimSpace(institution) == "" {
+				continue
+			}
+			query, renderInstitution = fmt.Sprintf(`institution:"%s"`, institution), institution
+		}
+		liveCounts, err := live.SourceCounts(query)
+		if err != nil {
+			return err
+		}
+		nonliveCounts, err := nonlive.SourceCounts(query)
+		if err != nil {
+			return err
+		}
+		// Emphasize focussed institution.
+		if institution == *focusInstitution {
+			renderInstitution = emphasize(*format, institution)
 		}
 		for _, sid := range sids {
-			query := fmt.Sprintf(`source_id:"%s" AND institution:"%s"`, sid, institution)
-			numLive, err := live.NumFound(query)
-			if err != nil {
-				log.Fatal(err)
-			}
-			numNonlive, err := nonlive.NumFound(query)
-			if err != nil {
-				log.Fatal(err)
-			}
+			numLive, numNonlive := int64(liveCounts[sid]), int64(nonliveCounts[sid])
 			// XXX: Might catch too much, e.g. DOAJ, refs #14417.
 			if numLive == 0 && numNonlive == 0 {
 				continue
@@ -392,16 +618,6 @@ func main() {
 				name = "XXX: missing source name"
 			}
 
-			// Emphasize focussed institution.
-			var renderInstitution = institution
-			if *textile && institution == *focusInstitution {
-				renderInstitution = fmt.Sprintf("*%s*", institution)
-			}
-
-			// Fields with links.
-			var liveField = fmt.Sprintf("%d", numLive)
-			var nonliveField = fmt.Sprintf("%d", numNonlive)
-
 			// Percentage change, refs #12756.
 			var pctChange float64
 			switch {
@@ -410,21 +626,17 @@ func main() {
 			default:
 				pctChange = (float64(numNonlive-numLive) / (float64(numLive))) * 100
 			}
-
 			// Remove -0.00 from rendering.
 			if pctChange == 0 {
 				pctChange = math.Copysign(pctChange, 1)
 			}
-			var pctChangeField string
-
-			switch {
-			case pctChange > 5.0 || pctChange < -5.0:
-				pctChangeField = fmt.Sprintf("*%0.2f*", pctChange)
-			default:
-				pctChangeField = fmt.Sprintf("%0.2f", pctChange)
+			pctChangeField := fmt.Sprintf("%0.2f", pctChange)
+			if pctChange > 5.0 || pctChange < -5.0 {
+				pctChangeField = emphasize(*format, pctChangeField)
 			}
 
-			if *textile {
+			liveField := fmt.Sprintf("%d", numLive)
+			if *format == "textile" {
 				data := struct {
 					SourceID    string
 					Institution string
@@ -433,24 +645,80 @@ func main() {
 					institution,
 				}
 				// XXX: Put all live link templates into a configaration file (or scrape from wiki).
-				if institution == *focusInstitution {
-					liveField, err = renderSourceLink(*liveLinkTemplate, data, fmt.Sprintf("%d", numLive))
-					if err != nil {
-						log.Fatal(err)
-					}
-				} else {
-					liveField, err = renderSourceLink(*liveLinkTemplate, data, fmt.Sprintf("%d", numLive))
-					if err != nil {
-						log.Fatal(err)
-					}
+				if liveField, err = renderSourceLink(*liveLinkTemplate, data, liveField); err != nil {
+					return err
 				}
-				nonliveField = fmt.Sprintf("%d", numNonlive)
 			}
-
-			rw.WriteFields(renderInstitution, sid, name, liveField, nonliveField, numNonlive-numLive, pctChangeField, "")
+			rw.WriteFields(renderInstitution, sid, name, liveField, fmt.Sprintf("%d", numNonlive),
+				numNonlive-numLive, pctChangeField, "")
 			if rw.Err() != nil {
-				log.Fatal(rw.Err())
+				return rw.Err()
 			}
 		}
 	}
+	return nil
+}
+
+// unique returns the sorted, distinct values.
+func unique(values []string) (result []string) {
+	sort.Strings(values)
+	for i, v := range values {
+		if i > 0 && v == values[i-1] {
+			continue
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+func main() {
+	flag.Parse()
+
+	if *textile {
+		*format = "textile"
+	}
+	rw, err := newResultWriter(*format, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *oldFile != "" || *newFile != "" {
+		if *oldFile == "" || *newFile == "" {
+			log.Fatal("both -old and -new are required")
+		}
+		compare := compareTagging
+		if *records {
+			compare = compareRecords
+		}
+		if err := compare(rw); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	if *whatIsLive {
+		// Fallback configuration.
+		if _, err := os.Stat(*spanConfigFile); os.IsNotExist(err) {
+			*spanConfigFile = "/etc/span/span.json"
+		}
+		if _, err := os.Stat(*spanConfigFile); os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+		*liveServer, err = solrutil.FindLiveSolrServer(*spanConfigFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		*nonliveServer, err = solrutil.FindNonliveSolrServer(*spanConfigFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("live=%s, nonlive=%s", *liveServer, *nonliveServer)
+	}
+
+	live := solrutil.Index{Server: prependHTTP(*liveServer)}
+	nonlive := solrutil.Index{Server: prependHTTP(*nonliveServer)}
+
+	if err := compareIndexes(rw, live, nonlive); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,219 @@
+// span-crossref-enrich fills in pages, volume, issue, ISSN and publisher of
+// intermediate schema records with a DOI from crossref, e.g. for sparse
+// records harvested from OAI repositories, before tagging and export. Values
+// present in a record are kept.
+//
+// Works are looked up in a local snapshot, e.g. from span-crossref-snapshot,
+// or via the API, with responses kept in a cache.
+//
+//	$ span-crossref-enrich -s 28,30 -snapshot snapshot.ldj.gz oai.is > enriched.is
+//	$ span-import -i doaj-oai doaj.xml | span-crossref-enrich -mailto me@example.com \
+//	    -cache redis://localhost:6379/0 | span-tag -c amsl.json | span-export
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
+	"github.com/miku/span/cache"
+	"github.com/miku/span/doi"
+	"github.com/miku/span/enrich"
+	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/parallel"
+)
+
+var (
+	snapshot    = flag.String("snapshot", "", "crossref works snapshot, newline delimited JSON, optionally compressed, instead of API lookups")
+	sources     = flag.String("s", "", "comma separated source ids to enrich, default: all")
+	endpoint    = flag.String("endpoint", enrich.DefaultWorksEndpoint, "works API endpoint")
+	mailto      = flag.String("mailto", "", "contact address for the crossref polite pool")
+	cacheLink   = flag.String("cache", "memory://", "cache for API responses, e.g. redis://localhost:6379/0 or memcached://localhost:11211")
+	cacheTTL    = flag.Duration("cache-ttl", 720*time.Hour, "refetch cached API responses after this time, 0 means never")
+	interval    = flag.Duration("interval", 100*time.Millisecond, "minimum time between API requests")
+	maxRetries  = flag.Int("retries", 5, "retries per API request")
+	numWorkers  = flag.Int("w", runtime.NumCPU(), "number of workers")
+	batchSize   = flag.Int("b", 2000, "batch size")
+	showVersion = flag.Bool("v", false, "prints current program version")
+)
+
+// spool copies stdin into a temporary file, so it can be read twice.
+func spool() (string, error) {
+	f, err := ioutil.TempFile("", "span-crossref-enrich-")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, os.Stdin); err != nil {
+		f.Close()
+		return f.Name(), err
+	}
+	return f.Name(), f.Close()
+}
+
+// wantedDOI collects the normalized DOI of records, that need enrichment.
+func wantedDOI(c *enrich.Crossref, filenames []string) (map[string]bool, error) {
+	var (
+		mu     sync.Mutex
+		wanted = make(map[string]bool)
+	)
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		p := parallel.NewProcessor(f, ioutil.Discard, func(_ int64, b []byte) ([]byte, error) {
+			var is finc.IntermediateSchema
+			if err := finc.UnmarshalIntermediateSchema(b, &is); err != nil {
+				return nil, err
+			}
+			if c.Wants(&is) {
+				mu.Lock()
+				wanted[doi.Clean(is.DOI)] = true
+				mu.Unlock()
+			}
+			return nil, nil
+		})
+		p.NumWorkers = *numWorkers
+		p.BatchSize = *batchSize
+		err = p.Run()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return wanted, nil
+}
+
+// loadSnapshot reads the works for the wanted DOI from a snapshot file.
+func loadSnapshot(filename string, wanted map[string]bool) (enrich.Works, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := span.NewDecompressReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return enrich.LoadWorks(r, func(v string) bool { return wanted[v] })
+}
+
+func main() {
+	flag.Parse()
+	if err := assetutil.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	if *showVersion {
+		fmt.Println(span.AppVersion)
+		os.Exit(0)
+	}
+
+	c := &enrich.Crossref{Sources: make(map[string]bool)}
+	for _, sid := range strings.Split(*sources, ",") {
+		if sid = strings.TrimSpace(sid); sid != "" {
+			c.Sources[sid] = true
+		}
+	}
+
+	filenames := flag.Args()
+	if *snapshot != "" {
+		// Only works of records to enrich are kept in memory, which requires
+		// a first pass over the input.
+		if len(filenames) == 0 {
+			name, err := spool()
+			defer os.Remove(name)
+			if err != nil {
+				log.Fatal(err)
+			}
+			filenames = []string{name}
+		}
+		wanted, err := wantedDOI(c, filenames)
+		if err != nil {
+			log.Fatal(err)
+		}
+		works := make(enrich.Works)
+		if len(wanted) > 0 {
+			if works, err = loadSnapshot(*snapshot, wanted); err != nil {
+				log.Fatal(err)
+			}
+		}
+		log.Printf("found %d of %d DOI in snapshot", len(works), len(wanted))
+		c.Works = works
+	} else {
+		ch, err := cache.Open(*cacheLink)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer ch.Close()
+		c.Works = &enrich.WorksAPI{
+			Endpoint:   *endpoint,
+			Mailto:     *mailto,
+			Cache:      ch,
+			TTL:        *cacheTTL,
+			Interval:   *interval,
+			MaxRetries: *maxRetries,
+		}
+	}
+
+	var readers []io.Reader
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	if len(readers) == 0 {
+		readers = append(readers, os.Stdin)
+	}
+
+	bw := bufio.NewWriter(os.Stdout)
+	defer bw.Flush()
+
+	var enriched int64
+	p := parallel.NewProcessor(io.MultiReader(readers...), bw, func(_ int64, b []byte) ([]byte, error) {
+		var is finc.IntermediateSchema
+		if err := finc.UnmarshalIntermediateSchema(b, &is); err != nil {
+			return nil, err
+		}
+		changed, err := c.Enrich(&is)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			if !bytes.HasSuffix(b, []byte("\n")) {
+				b = append(b, '\n')
+			}
+			return b, nil
+		}
+		atomic.AddInt64(&enriched, 1)
+		bb, err := json.Marshal(is)
+		if err != nil {
+			return nil, err
+		}
+		return append(bb, '\n'), nil
+	})
+	p.NumWorkers = *numWorkers
+	p.BatchSize = *batchSize
+	if err := p.Run(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("enriched %d records", enriched)
+}
@@ -1,5 +1,8 @@
 // Given as single file with crossref works API messages, create a potentially
 // smaller file, which contains only the most recent version of each document.
+// By default, the most recently indexed version of a DOI wins, use -k
+// deposited for the previous behaviour. On ties, the later line wins. Input
+// can be a concatenation of many harvest slices, e.g. from span-crossref-sync.
 //
 // Works in a three stage, two pass fashion: (1) extract, (2) identify, (3) extract.
 // Performance data point (30M compressed records, 11m33.871s):
@@ -21,10 +24,12 @@ import (
 	"os/exec"
 	"runtime/pprof"
 	"strings"
+	"time"
 
 	gzip "github.com/klauspost/pgzip"
 	"github.com/miku/clam"
 	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
 	"github.com/miku/span/formats/crossref"
 	"github.com/miku/span/parallel"
 	log "github.com/sirupsen/logrus"
@@ -47,6 +52,19 @@ LIST="$1" LC_ALL=C awk '
   }' < "$2"
 `
 
+// timestamp returns milliseconds since epoch for a date field, falling back
+// to the date parts, if no timestamp is available.
+func timestamp(d crossref.DateField) (int64, error) {
+	if d.Timestamp > 0 {
+		return d.Timestamp, nil
+	}
+	t, err := d.Date()
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixNano() / int64(time.Millisecond), nil
+}
+
 // WriteFields writes a variable number of fields as tab separated values into a writer.
 func WriteFields(w io.Writer, values ...interface{}) (int, error) {
 	var s []string
@@ -63,8 +81,16 @@ func main() {
 	batchsize := flag.Int("b", 40000, "batch size")
 	cpuProfile := flag.String("cpuprofile", "", "write cpuprofile to file")
 	verbose := flag.Bool("verbose", false, "be verbose")
+	key := flag.String("k", "indexed", "date deciding the most recent version: indexed or deposited")
 
 	flag.Parse()
+	if err := assetutil.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	if *key != "indexed" && *key != "deposited" {
+		log.Fatalf("unknown key: %s", *key)
+	}
 
 	if *verbose {
 		log.SetLevel(log.DebugLevel)
@@ -139,15 +165,20 @@ func main() {
 		if err := json.Unmarshal(b, &doc); err != nil {
 			return nil, err
 		}
-		date, err := doc.Deposited.Date()
+		field := doc.Indexed
+		if *key == "deposited" {
+			field = doc.Deposited
+		}
+		ts, err := timestamp(field)
 		if err != nil {
 			return nil, err
 		}
 		if _, ok := excludes[doc.DOI]; ok {
 			return nil, nil
 		}
+		// DOI are case insensitive.
 		var buf bytes.Buffer
-		if _, err := WriteFields(&buf, lineno+1, date.Format("2006-01-02"), doc.DOI); err != nil {
+		if _, err := WriteFields(&buf, lineno+1, ts, strings.ToLower(doc.DOI)); err != nil {
 			return nil, err
 		}
 		return buf.Bytes(), nil
@@ -170,11 +201,11 @@ func main() {
 		log.Fatal(err)
 	}
 
-	// Stage 2: Identify relevant records. Sort by DOI (3), then date reversed (2);
-	// then unique by DOI (3). Should keep the entry of the last update (filename,
-	// document date, DOI).
+	// Stage 2: Identify relevant records. Sort by DOI (3), then timestamp (2)
+	// and line number (1) reversed; then unique by DOI (3). Keeps the entry of
+	// the last update (line number, timestamp, DOI).
 	fastsort := `LC_ALL=C sort -S20%`
-	cmd := `{{ f }} -k3,3 -rk2,2 {{ input }} | {{ f }} -k3,3 -u | cut -f1 | {{ f }} -n > {{ output }}`
+	cmd := `{{ f }} -k3,3 -k2,2nr -k1,1nr {{ input }} | {{ f }} -s -k3,3 -u | cut -f1 | {{ f }} -n > {{ output }}`
 
 	log.WithFields(log.Fields{
 		"prefix":    "stage 2",
@@ -0,0 +1,346 @@
+// span-crossref-sync harvests crossref works incrementally via the REST API
+// and writes gzip compressed, newline delimited JSON, ready for span-import.
+//
+// Uses cursor based deep paging and the polite pool, if a mailto address is
+// given. Progress is written to a state file after each page, so an
+// interrupted harvest can be resumed by running the same command again; the
+// output file is truncated to the last complete page and appended to in that
+// case. Each page is written as a separate gzip member.
+//
+// With -state-db, the last index date covered by a complete harvest is
+// recorded per endpoint and filter; without an explicit -from, the next
+// harvest starts the day after.
+//
+//	$ span-crossref-sync -mailto me@example.com -from 2019-01-01 -until 2019-01-31 \
+//	    -state 2019-01.state -o 2019-01.ldj.gz
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/miku/span"
+	"github.com/miku/span/state"
+)
+
+// State of a harvest, written after each page.
+type State struct {
+	From     string    `json:"from"`
+	Until    string    `json:"until"`
+	Filter   string    `json:"filter"`
+	Cursor   string    `json:"cursor"`
+	Count    int64     `json:"count"`
+	Offset   int64     `json:"offset"`
+	Total    int64     `json:"total"`
+	Done     bool      `json:"done"`
+	Modified time.Time `json:"modified"`
+}
+
+// sameHarvest returns true, if the state belongs to a harvest with the given parameters.
+func (s State) sameHarvest(from, until, filter string) bool {
+	return s.From == from && s.Until == until && s.Filter == filter
+}
+
+// readState reads a state file. A missing file results in an empty state.
+func readState(filename string) (State, error) {
+	var s State
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(b, &s)
+	return s, err
+}
+
+// writeState replaces the state file atomically.
+func writeState(filename string, s State) error {
+	s.Modified = time.Now()
+	b, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// Page is a single works API response, items are kept as is.
+type Page struct {
+	Status  string `json:"status"`
+	Message struct {
+		NextCursor   string            `json:"next-cursor"`
+		TotalResults int64             `json:"total-results"`
+		Items        []json.RawMessage `json:"items"`
+	} `json:"message"`
+}
+
+// Harvester fetches pages from the works API.
+type Harvester struct {
+	Endpoint   string
+	Mailto     string
+	Rows       int
+	MaxRetries int
+	Interval   time.Duration
+	Client     *http.Client
+
+	last time.Time
+}
+
+// fetch requests a single page, retrying on rate limits and server errors
+// with exponential backoff.
+func (h *Harvester) fetch(filter, cursor string) (*Page, error) {
+	vs := url.Values{}
+	vs.Set("filter", filter)
+	vs.Set("cursor", cursor)
+	vs.Set("rows", fmt.Sprintf("%d", h.Rows))
+	if h.Mailto != "" {
+		vs.Set("mailto", h.Mailto)
+	}
+	link := fmt.Sprintf("%s?%s", h.Endpoint, vs.Encode())
+
+	backoff := h.Interval
+	if backoff < time.Second {
+		backoff = time.Second
+	}
+	var lastErr error
+	for i := 0; i <= h.MaxRetries; i++ {
+		if wait := h.Interval - time.Since(h.last); wait > 0 {
+			time.Sleep(wait)
+		}
+		h.last = time.Now()
+		page, retry, err := h.get(link)
+		if err == nil {
+			return page, nil
+		}
+		if !retry {
+			return nil, err
+		}
+		lastErr = err
+		log.WithFields(log.Fields{"attempt": i + 1, "backoff": backoff}).Warn(err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	return nil, fmt.Errorf("giving up after %d retries: %v", h.MaxRetries, lastErr)
+}
+
+// get performs a single request and reports, whether a failure may be retried.
+func (h *Harvester) get(link string) (page *Page, retry bool, err error) {
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	ua := fmt.Sprintf("span-crossref-sync/%s (https://github.com/miku/span)", span.AppVersion)
+	if h.Mailto != "" {
+		ua = fmt.Sprintf("%s (mailto:%s)", ua, h.Mailto)
+	}
+	req.Header.Set("User-Agent", ua)
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, true, fmt.Errorf("request failed with: %s", resp.Status)
+	case resp.StatusCode >= 400:
+		return nil, false, fmt.Errorf("request to %s failed with: %s", link, resp.Status)
+	}
+	page = new(Page)
+	if err := json.NewDecoder(resp.Body).Decode(page); err != nil {
+		return nil, true, err
+	}
+	return page, false, nil
+}
+
+// writePage writes the items of a page as a single gzip member.
+func writePage(w io.Writer, page *Page) error {
+	if len(page.Message.Items) == 0 {
+		return nil
+	}
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(zw)
+	for _, item := range page.Message.Items {
+		if _, err := bw.Write(item); err != nil {
+			return err
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// recordLastSeen stores the last index date covered by a complete harvest, if
+// a state database is used.
+func recordLastSeen(store *state.Store, key, date string) error {
+	if store == nil {
+		return nil
+	}
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return err
+	}
+	return store.SetLastSeen(key, t)
+}
+
+func main() {
+	endpoint := flag.String("endpoint", "https://api.crossref.org/works", "works API endpoint")
+	mailto := flag.String("mailto", "", "contact address for the polite pool")
+	from := flag.String("from", time.Now().AddDate(0, 0, -1).Format("2006-01-02"), "from-index-date")
+	until := flag.String("until", "", "until-index-date, optional")
+	extraFilter := flag.String("filter", "", "additional filter, e.g. type:journal-article")
+	rows := flag.Int("rows", 1000, "number of items per request")
+	interval := flag.Duration("interval", 1*time.Second, "minimum time between requests")
+	maxRetries := flag.Int("retries", 10, "retries per request")
+	timeout := flag.Duration("timeout", 60*time.Second, "HTTP timeout")
+	outputFile := flag.String("o", "", "output file, gzip compressed")
+	stateFile := flag.String("state", "", "state file, defaults to output file name with .state suffix")
+	stateDB := flag.String("state-db", "", "state database, records the last index date harvested per endpoint and filter")
+	showVersion := flag.Bool("v", false, "prints current program version")
+	verbose := flag.Bool("verbose", false, "be verbose")
+
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println(span.AppVersion)
+		os.Exit(0)
+	}
+	if *verbose {
+		log.SetLevel(log.DebugLevel)
+	}
+	if *outputFile == "" {
+		log.Fatal("output filename required")
+	}
+	if *stateFile == "" {
+		*stateFile = *outputFile + ".state"
+	}
+
+	var (
+		store    *state.Store
+		key      = fmt.Sprintf("crossref|%s|%s", *endpoint, *extraFilter)
+		explicit bool
+	)
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "from" {
+			explicit = true
+		}
+	})
+	if *stateDB != "" {
+		var err error
+		if store, err = state.Open(*stateDB, 10*time.Second); err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+		lastSeen, ok, err := store.LastSeen(key)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if ok && !explicit {
+			*from = lastSeen.AddDate(0, 0, 1).Format("2006-01-02")
+			log.WithField("from", *from).Info("continuing after last harvest")
+		}
+	}
+
+	// Without until, everything indexed before today is covered.
+	covered := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	if *until != "" {
+		covered = *until
+	}
+
+	filter := fmt.Sprintf("from-index-date:%s", *from)
+	if *until != "" {
+		filter = fmt.Sprintf("%s,until-index-date:%s", filter, *until)
+	}
+	if *extraFilter != "" {
+		filter = fmt.Sprintf("%s,%s", filter, *extraFilter)
+	}
+
+	state, err := readState(*stateFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch {
+	case state.sameHarvest(*from, *until, filter) && state.Done:
+		log.WithField("count", state.Count).Info("harvest already complete")
+		if err := recordLastSeen(store, key, covered); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case state.sameHarvest(*from, *until, filter) && state.Cursor != "":
+		log.WithFields(log.Fields{"count": state.Count, "total": state.Total}).Info("resuming harvest")
+	default:
+		state = State{From: *from, Until: *until, Filter: filter, Cursor: "*"}
+	}
+
+	f, err := os.OpenFile(*outputFile, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	// Drop anything written after the last complete page.
+	if err := f.Truncate(state.Offset); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := f.Seek(state.Offset, io.SeekStart); err != nil {
+		log.Fatal(err)
+	}
+
+	h := &Harvester{
+		Endpoint:   *endpoint,
+		Mailto:     *mailto,
+		Rows:       *rows,
+		MaxRetries: *maxRetries,
+		Interval:   *interval,
+		Client:     &http.Client{Timeout: *timeout},
+	}
+
+	for {
+		page, err := h.fetch(filter, state.Cursor)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := writePage(f, page); err != nil {
+			log.Fatal(err)
+		}
+		if state.Offset, err = f.Seek(0, io.SeekCurrent); err != nil {
+			log.Fatal(err)
+		}
+		state.Count += int64(len(page.Message.Items))
+		state.Total = page.Message.TotalResults
+		// The cursor stays the same, until results are exhausted.
+		state.Done = len(page.Message.Items) == 0 || page.Message.NextCursor == ""
+		if page.Message.NextCursor != "" {
+			state.Cursor = page.Message.NextCursor
+		}
+		if err := writeState(*stateFile, state); err != nil {
+			log.Fatal(err)
+		}
+		log.WithFields(log.Fields{"count": state.Count, "total": state.Total}).Debug("page")
+		if state.Done {
+			break
+		}
+	}
+	if err := recordLastSeen(store, key, covered); err != nil {
+		log.Fatal(err)
+	}
+	log.WithFields(log.Fields{"count": state.Count, "output": *outputFile}).Info("harvest complete")
+}
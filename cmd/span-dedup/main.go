@@ -0,0 +1,169 @@
+// The span-dedup tool finds the same article from different sources in one or
+// more intermediate schema files and writes the ids of the records to delete,
+// keeping the record of the source with the highest priority.
+//
+//	$ span-dedup -p 55,85,49 crossref.is elsevier.is > delete.txt
+//
+// Records are grouped by normalized DOI and, with -t, also by title and year.
+// With -verbose, a tab separated line with id, source id, id and source id of
+// the kept record and the reason (doi or title) is written per record instead.
+//
+// With -state-db, ids reported are recorded and later runs only write ids,
+// that have not been reported before, e.g. for incremental delete lists.
+//
+//	$ span-dedup -state-db dedup.db -p 55,49 crossref.is elsevier.is > delete-new.txt
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
+	"github.com/miku/span/dedup"
+	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/parallel"
+	"github.com/miku/span/state"
+)
+
+var (
+	configFile  = flag.String("c", "", `JSON config, e.g. {"priority": ["55", "49"], "title_year": false}`)
+	priority    = flag.String("p", "", "comma separated source ids, highest priority first, overrides config")
+	titleYear   = flag.Bool("t", false, "also match records by normalized title and year")
+	verbose     = flag.Bool("verbose", false, "write id, source id, kept id, kept source id and reason")
+	numWorkers  = flag.Int("w", runtime.NumCPU(), "number of workers")
+	batchSize   = flag.Int("b", 20000, "batch size")
+	stateDB     = flag.String("state-db", "", "state database, only write ids not reported by earlier runs and record them")
+	stateSet    = flag.String("state-set", "dedup", "name of the set of reported ids in the state database")
+	showVersion = flag.Bool("v", false, "prints current program version")
+)
+
+// unreported drops losers already recorded in a state database and records
+// the remaining ones.
+func unreported(filename, set string, losers []dedup.Loser) ([]dedup.Loser, error) {
+	store, err := state.Open(filename, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	ids := make([]string, len(losers))
+	for i, l := range losers {
+		ids[i] = l.ID
+	}
+	missing, err := store.Missing(set, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Add(set, missing...); err != nil {
+		return nil, err
+	}
+	// Missing keeps the order and losers are unique by id.
+	var result []dedup.Loser
+	for _, l := range losers {
+		if len(missing) > 0 && l.ID == missing[0] {
+			result = append(result, l)
+			missing = missing[1:]
+		}
+	}
+	return result, nil
+}
+
+// add reads records from a reader. Sequence numbers are offset per file, so
+// earlier files win between sources of the same priority.
+func add(d *dedup.Deduplicator, r io.Reader, offset int64) error {
+	p := parallel.NewProcessor(r, ioutil.Discard, func(lineno int64, b []byte) ([]byte, error) {
+		var is finc.IntermediateSchema
+		if err := finc.UnmarshalIntermediateSchema(b, &is); err != nil {
+			return nil, err
+		}
+		d.Add(offset+lineno, dedup.RecordOf(is))
+		return nil, nil
+	})
+	p.NumWorkers = *numWorkers
+	p.BatchSize = *batchSize
+	return p.Run()
+}
+
+func main() {
+	flag.Parse()
+	if err := assetutil.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	if *showVersion {
+		fmt.Println(span.AppVersion)
+		os.Exit(0)
+	}
+
+	var config dedup.Config
+	if *configFile != "" {
+		f, err := os.Open(*configFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if config, err = dedup.ReadConfig(f); err != nil {
+			log.Fatal(err)
+		}
+		f.Close()
+	}
+	if *priority != "" {
+		config.Priority = strings.Split(*priority, ",")
+	}
+	if *titleYear {
+		config.TitleYear = true
+	}
+	if len(config.Priority) == 0 {
+		log.Warn("no source priority given, earlier records win")
+	}
+
+	d := dedup.New(config)
+	if flag.NArg() == 0 {
+		if err := add(d, os.Stdin, 0); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for i, filename := range flag.Args() {
+		f, err := os.Open(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := add(d, f, int64(i)<<40); err != nil {
+			log.Fatal(err)
+		}
+		f.Close()
+	}
+
+	bw := bufio.NewWriter(os.Stdout)
+	defer bw.Flush()
+
+	losers := d.Losers()
+	if *stateDB != "" {
+		total := len(losers)
+		var err error
+		if losers, err = unreported(*stateDB, *stateSet, losers); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("%d duplicates reported before", total-len(losers))
+	}
+	for _, l := range losers {
+		var err error
+		if *verbose {
+			_, err = fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%s\n", l.ID, l.SourceID, l.KeptID, l.KeptSourceID, l.Reason)
+		} else {
+			_, err = fmt.Fprintln(bw, l.ID)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	log.Printf("%d duplicates", len(losers))
+}
@@ -2,7 +2,8 @@
 package main
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"io"
@@ -11,14 +12,30 @@ import (
 	"runtime/pprof"
 	"sort"
 	"strings"
+	"sync/atomic"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
+	"github.com/miku/span/classify"
+	"github.com/miku/span/container"
+	"github.com/miku/span/encoding/lineiter"
+	"github.com/miku/span/esutil"
+	"github.com/miku/span/filter"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/licensing/kbart"
+	"github.com/miku/span/logging"
 	"github.com/miku/span/parallel"
+	"github.com/miku/span/solrutil"
 )
 
+// Indexer sends documents to a search server instead of stdout.
+type Indexer interface {
+	io.WriteCloser
+	Indexed() int64
+}
+
 // Exporters holds available export formats
 var Exporters = map[string]func() finc.Exporter{
 	"solr5vu3": func() finc.Exporter { return new(finc.Solr5Vufind3) },
@@ -28,24 +45,86 @@ var Exporters = map[string]func() finc.Exporter{
 func main() {
 	showVersion := flag.Bool("v", false, "prints current program version")
 	size := flag.Int("b", 20000, "batch size")
+	batchMB := flag.Int("batch-mb", parallel.DefaultBatchBytes>>20, "limit the input size of a batch in megabytes, so huge records do not exhaust memory, 0 means no limit")
 	numWorkers := flag.Int("w", runtime.NumCPU(), "number of workers")
+	ordered := flag.Bool("preserve-order", false, "write records in input order")
 	cpuprofile := flag.String("cpuprofile", "", "write cpu profile to file")
 	format := flag.String("o", "solr5vu3", "output format")
 	listFormats := flag.Bool("list", false, "list output formats")
 	withFullrecord := flag.Bool("with-fullrecord", false, "populate fullrecord field with originating intermediate schema record")
+	foldAuthors := flag.Bool("fold-author-facet", false, "remove diacritics from author facet values, e.g. Müller becomes Muller")
+	allfieldsParts := flag.String("allfields", "abstract,fulltext,subjects", "optional parts of the allfields field, comma separated, empty for none")
+	isilFields := flag.Bool("isil-fields", false, "add barcode_* and collection_* fields per attached ISIL, e.g. barcode_de15")
+	fullrecordEncoding := flag.String("fullrecord-encoding", "json", "fullrecord representation, with -with-fullrecord: json or gzip (gzip+base64)")
+	dbFile := flag.String("db", "", "SQLite database file to write to, when using -o sqlite")
+	classificationFile := flag.String("classification", "", "YAML file with subject heading to DDC, RVK and BK mapping tables per source")
+	unmappedFile := flag.String("unmapped-headings", "", "write subject headings without classification to file, with -classification")
+	formatsFile := flag.String("formats", "", "JSON file with site specific format fields, e.g. {\"format_de15\": {\"ElectronicArticle\": \"...\"}}")
+	solrServer := flag.String("solr", "", "post documents to SOLR instead of stdout, e.g. http://localhost:8983/solr/biblio, %s is replaced by the ISIL for one core per ISIL")
+	splitDir := flag.String("split", "", "write documents into one file per ISIL in this directory, instead of stdout")
+	solrBatchSize := flag.Int("solr-batch", solrutil.DefaultBatchSize, "documents per SOLR update request, with -solr")
+	solrConnections := flag.Int("solr-connections", solrutil.DefaultNumConnections, "parallel SOLR update requests, with -solr")
+	solrCommitWithin := flag.Duration("solr-commit-within", 0, "commitWithin for SOLR updates, e.g. 10m, with -solr")
+	solrRetries := flag.Int("solr-retries", solrutil.DefaultMaxRetries, "retries for SOLR updates failed with HTTP 503, with -solr")
+	solrCommit := flag.Bool("solr-commit", false, "commit after all documents are sent, with -solr")
+	esServer := flag.String("es", "", "post documents to Elasticsearch or OpenSearch bulk API instead of stdout, e.g. http://localhost:9200")
+	esIndex := flag.String("es-index", "biblio", "index name, with -es")
+	esBatchSize := flag.Int("es-batch", esutil.DefaultBatchSize, "documents per bulk request, with -es")
+	esConnections := flag.Int("es-connections", esutil.DefaultNumConnections, "parallel bulk requests, with -es")
+	esRetries := flag.Int("es-retries", esutil.DefaultMaxRetries, "retries for requests and documents rejected with HTTP 429 or 503, with -es")
+	esRefresh := flag.Bool("es-refresh", false, "refresh index after all documents are sent, with -es")
+	whereExpr := flag.String("where", "", "export only records matching a filter expression or file, e.g. {\"source\": [\"49\"]}, see span-tag")
+	listAssets := flag.Bool("list-assets", false, "list embedded assets and overrides")
+	var assetFiles span.ArrayFlags
+	flag.Var(&assetFiles, "asset", "replace an embedded asset with a file, name=file, e.g. assets/finc/formats/de15.json=de15.json (repeatable)")
+	logOptions := logging.RegisterFlags(flag.CommandLine)
+	selection := parallel.RegisterSelectionFlags(flag.CommandLine)
+	progressInterval := flag.Duration("progress", 0, "log records processed, MB read, rate and estimated remaining time in this interval, e.g. 1m, 0 disables")
 
 	flag.Parse()
+	if err := logOptions.Setup(); err != nil {
+		log.Fatal(err)
+	}
 
 	if *showVersion {
 		fmt.Println(span.AppVersion)
 		os.Exit(0)
 	}
 
+	if err := selection.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if *batchMB < 0 {
+		log.Fatalf("-batch-mb must not be negative: %d", *batchMB)
+	}
+	var where filter.Filter
+	if *whereExpr != "" {
+		f, err := filter.Parse(*whereExpr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		where = f
+	}
+
+	if err := assetutil.OverrideFlags(assetFiles); err != nil {
+		log.Fatal(err)
+	}
+	if err := assetutil.Err(); err != nil {
+		log.Fatal(err)
+	}
+	if *listAssets {
+		if err := assetutil.WriteNames(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
 	if *listFormats {
 		var keys []string
 		for key := range Exporters {
 			keys = append(keys, key)
 		}
+		keys = append(keys, "sqlite", "kbart")
 		sort.Strings(keys)
 		fmt.Println(strings.Join(keys, "\n"))
 		os.Exit(0)
@@ -60,16 +139,49 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	switch *fullrecordEncoding {
+	case finc.FullrecordJSON, finc.FullrecordGzip:
+	default:
+		log.Fatalf("unknown fullrecord encoding: %s", *fullrecordEncoding)
+	}
+
+	allfieldsOptions, perr := finc.ParseAllfieldsOptions(*allfieldsParts)
+	if perr != nil {
+		log.Fatal(perr)
+	}
+
+	var classifier *classify.Classifier
+	if *classificationFile != "" {
+		var err error
+		if classifier, err = classify.LoadFile(*classificationFile); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("loaded %d classification tables from %s", len(classifier.Tables), *classificationFile)
+	}
+
+	var mappings map[string]container.StringMap
+	if *formatsFile != "" {
+		f, err := os.Open(*formatsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if mappings, err = finc.LoadFormatFields(f); err != nil {
+			log.Fatal(err)
+		}
+		f.Close()
+		log.Printf("loaded %d format fields from %s", len(mappings), *formatsFile)
+	}
+	Exporters["solr5vu3"] = func() finc.Exporter {
+		return &finc.Solr5Vufind3{FormatMappings: mappings, FullrecordEncoding: *fullrecordEncoding,
+			FoldAuthorFacet: *foldAuthors, Classifier: classifier, ISILFields: *isilFields,
+			AllfieldsOptions: &allfieldsOptions}
+	}
+
 	if *format == "solr5vu3v12" {
 		*withFullrecord = true
 		*format = "solr5vu3"
 	}
 
-	exportSchemaFunc, ok := Exporters[*format]
-	if !ok {
-		log.Fatalf("unknown export schema: %s", *format)
-	}
-
 	var reader io.Reader = os.Stdin
 
 	if flag.NArg() > 0 {
@@ -85,14 +197,120 @@ func main() {
 		reader = io.MultiReader(files...)
 	}
 
-	p := parallel.NewProcessor(reader, os.Stdout, func(_ int64, b []byte) ([]byte, error) {
+	// SQLite output is not a line oriented format, so it gets its own path.
+	if *format == "sqlite" {
+		if *dbFile == "" {
+			log.Fatal("sqlite output requires a database file (-db)")
+		}
+		w := &SqliteWriter{Filename: *dbFile, BatchSize: *size, Where: where}
+		n, err := w.WriteFrom(reader)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("wrote %d records to %s", n, *dbFile)
+		os.Exit(0)
+	}
+
+	// KBART output aggregates all records into a single title list.
+	if *format == "kbart" {
+		tl := kbart.NewTitleList()
+		it := lineiter.New(reader)
+		for it.Next() {
+			if len(bytes.TrimSpace(it.Bytes())) == 0 {
+				continue
+			}
+			var is finc.IntermediateSchema
+			if err := finc.UnmarshalIntermediateSchema(it.Bytes(), &is); err != nil {
+				log.Fatal(err)
+			}
+			if where == nil || where.Apply(is) {
+				tl.Add(is)
+			}
+		}
+		if err := it.Err(); err != nil {
+			log.Fatal(err)
+		}
+		if _, err := tl.WriteTo(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("wrote KBART with %s", tl)
+		os.Exit(0)
+	}
+
+	exportSchemaFunc, ok := Exporters[*format]
+	if !ok {
+		log.Fatalf("unknown export schema: %s", *format)
+	}
+
+	var w io.Writer = os.Stdout
+	var indexer Indexer
+	// splitter writes documents per ISIL, to files or SOLR cores.
+	var splitter *span.LabelWriter
+	if *splitDir != "" && (*solrServer != "" || *esServer != "") {
+		log.Fatal("-split and -solr or -es are exclusive")
+	}
+	if *solrServer != "" || *esServer != "" {
+		if *format != "solr5vu3" {
+			log.Fatalf("-solr and -es require solr5vu3 output, got %s", *format)
+		}
+		if *solrServer != "" && *esServer != "" {
+			log.Fatal("-solr and -es are exclusive")
+		}
+	}
+	newUpdater := func(server string) *solrutil.Updater {
+		updater := solrutil.NewUpdater(server)
+		updater.BatchSize = *solrBatchSize
+		updater.NumConnections = *solrConnections
+		updater.CommitWithin = *solrCommitWithin
+		updater.MaxRetries = *solrRetries
+		updater.Commit = *solrCommit
+		return updater
+	}
+	switch {
+	case *splitDir != "":
+		if err := os.MkdirAll(*splitDir, 0755); err != nil {
+			log.Fatal(err)
+		}
+		splitter = span.NewLabelFileWriter(*splitDir, ".ldj")
+	case strings.Contains(*solrServer, "%s"):
+		splitter = &span.LabelWriter{Create: func(isil string) (io.WriteCloser, error) {
+			return newUpdater(fmt.Sprintf(*solrServer, isil)), nil
+		}}
+	case *solrServer != "":
+		updater := newUpdater(*solrServer)
+		indexer, w = updater, updater
+	case *esServer != "":
+		ix := esutil.NewIndexer(*esServer, *esIndex)
+		ix.BatchSize = *esBatchSize
+		ix.NumConnections = *esConnections
+		ix.MaxRetries = *esRetries
+		ix.Refresh = *esRefresh
+		indexer, w = ix, ix
+	}
+
+	var progress *parallel.Progress
+	if *progressInterval > 0 {
+		progress = &parallel.Progress{
+			Interval: *progressInterval,
+			Size:     parallel.InputSize(flag.Args()...),
+			Report:   func(s parallel.Status) { log.Info(s) },
+		}
+		reader = progress.Reader(reader)
+	}
+
+	var n int64
+	p := parallel.NewProcessor(reader, w, func(_ int64, b []byte) ([]byte, error) {
+		atomic.AddInt64(&n, 1)
 		is := finc.IntermediateSchema{}
 
 		// TODO(miku): Unmarshal date correctly.
-		if err := json.Unmarshal(b, &is); err != nil {
+		if err := finc.UnmarshalIntermediateSchema(b, &is); err != nil {
 			log.Printf("failed to unmarshal: %s", string(b))
 			return b, err
 		}
+		if where != nil && !where.Apply(is) {
+			return nil, nil
+		}
 
 		// Get export format.
 		schema := exportSchemaFunc()
@@ -104,13 +322,81 @@ func main() {
 		}
 
 		bb = append(bb, '\n')
+		if splitter != nil {
+			return nil, splitter.Write(is.Labels, bb)
+		}
 		return bb, nil
 	})
 
 	p.NumWorkers = *numWorkers
+	p.PreserveOrder = *ordered
 	p.BatchSize = *size
+	p.BatchBytes = int64(*batchMB) << 20
+	p.Selection = *selection
+	p.Progress = progress
 
-	if err := p.Run(); err != nil {
+	// On interrupt, records already read are still exported.
+	ctx, cancel := span.InterruptContext(context.Background())
+	defer cancel()
+	if progress != nil {
+		progress.Start()
+	}
+	err := p.RunContext(ctx)
+	if progress != nil {
+		progress.Stop()
+	}
+	if indexer != nil {
+		if cerr := indexer.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		log.Printf("indexed %d documents", indexer.Indexed())
+	}
+	if splitter != nil {
+		if cerr := splitter.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		for _, isil := range splitter.Labels() {
+			if v, ok := splitter.Writer(isil); ok {
+				if ix, ok := v.(Indexer); ok {
+					log.Printf("%s: indexed %d documents", isil, ix.Indexed())
+					continue
+				}
+			}
+			log.Printf("%s: %d documents", isil, splitter.Count(isil))
+		}
+	}
+	if err == context.Canceled {
+		log.Warnf("interrupted after %d records, partial output written", atomic.LoadInt64(&n))
+		os.Exit(130)
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
+	if classifier != nil {
+		if err := writeUnmapped(classifier, *unmappedFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// writeUnmapped reports subject headings without classification to a file,
+// or logs their number, if no file is given.
+func writeUnmapped(classifier *classify.Classifier, filename string) error {
+	var total int
+	for sid, m := range classifier.Unmapped() {
+		log.Printf("classify: %d distinct unmapped headings in source %s", len(m), sid)
+		total += len(m)
+	}
+	if filename == "" || total == 0 {
+		return nil
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := classifier.WriteUnmapped(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/miku/span/encoding/lineiter"
+	"github.com/miku/span/filter"
+	"github.com/miku/span/formats/finc"
+)
+
+// sqliteSchema sets up tables and indices. ISSN are kept in a separate table,
+// since a record may carry any number of them.
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS records (
+		id TEXT PRIMARY KEY,
+		doi TEXT,
+		source_id TEXT,
+		date TEXT,
+		record TEXT
+	)`,
+	`CREATE TABLE IF NOT EXISTS issn (id TEXT, issn TEXT)`,
+	`CREATE INDEX IF NOT EXISTS idx_records_doi ON records (doi)`,
+	`CREATE INDEX IF NOT EXISTS idx_records_source_id ON records (source_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_records_date ON records (date)`,
+	`CREATE INDEX IF NOT EXISTS idx_issn_id ON issn (id)`,
+	`CREATE INDEX IF NOT EXISTS idx_issn_issn ON issn (issn)`,
+}
+
+// SqliteWriter writes intermediate schema records into a SQLite database, so
+// deliveries can be inspected with SQL without loading them into SOLR.
+type SqliteWriter struct {
+	Filename  string
+	BatchSize int
+	// Where, if set, limits the records written.
+	Where filter.Filter
+}
+
+// insertBatch inserts a number of records in a single transaction.
+func (w *SqliteWriter) insertBatch(db *sql.DB, batch []finc.IntermediateSchema) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	rstmt, err := tx.Prepare(`INSERT OR REPLACE INTO records (id, doi, source_id, date, record) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer rstmt.Close()
+	dstmt, err := tx.Prepare(`DELETE FROM issn WHERE id = ?`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer dstmt.Close()
+	istmt, err := tx.Prepare(`INSERT INTO issn (id, issn) VALUES (?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer istmt.Close()
+
+	for _, is := range batch {
+		b, err := json.Marshal(is)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := rstmt.Exec(is.ID, is.DOI, is.SourceID, is.Date.Format("2006-01-02"), string(b)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := dstmt.Exec(is.ID); err != nil {
+			tx.Rollback()
+			return err
+		}
+		for _, issn := range is.ISSNList() {
+			if _, err := istmt.Exec(is.ID, issn); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// WriteFrom reads newline delimited intermediate schema records from a reader
+// and inserts the ones matching Where into the database. Returns the number of
+// records written.
+func (w *SqliteWriter) WriteFrom(r io.Reader) (n int64, err error) {
+	db, err := sql.Open("sqlite3", w.Filename)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	for _, s := range sqliteSchema {
+		if _, err := db.Exec(s); err != nil {
+			return 0, err
+		}
+	}
+
+	var batch []finc.IntermediateSchema
+	it := lineiter.New(r)
+	for it.Next() {
+		b := it.Bytes()
+		if len(bytes.TrimSpace(b)) > 0 {
+			var is finc.IntermediateSchema
+			if err := finc.UnmarshalIntermediateSchema(b, &is); err != nil {
+				return n, err
+			}
+			if w.Where == nil || w.Where.Apply(is) {
+				batch = append(batch, is)
+			}
+		}
+		if len(batch) == w.BatchSize {
+			if err := w.insertBatch(db, batch); err != nil {
+				return n, err
+			}
+			n += int64(len(batch))
+			batch = batch[:0]
+		}
+	}
+	if err := it.Err(); err != nil {
+		return n, err
+	}
+	if err := w.insertBatch(db, batch); err != nil {
+		return n, err
+	}
+	n += int64(len(batch))
+	return n, nil
+}
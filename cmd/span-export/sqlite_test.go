@@ -0,0 +1,65 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miku/span/filter"
+)
+
+func TestSqliteWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-export-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := strings.Join([]string{
+		`{"finc.id": "ai-49-a", "finc.source_id": "49", "doi": "10.1/a", "rft.issn": ["1234-5678"], "rft.eissn": ["2345-6789"], "x.date": "2001-02-03T00:00:00Z", "version": "1.0"}`,
+		``,
+		`{"finc.id": "ai-28-b", "finc.source_id": "28", "doi": "10.1/b", "rft.issn": ["1234-5678"], "x.date": "2002-01-01T00:00:00Z", "version": "1.0"}`,
+		`{"finc.id": "ai-49-c", "finc.source_id": "49", "doi": "10.1/c", "x.date": "2003-01-01T00:00:00Z", "version": "1.0"}`,
+	}, "\n")
+	where, err := filter.Parse(`{"source": ["49"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "test.db")
+	w := &SqliteWriter{Filename: filename, BatchSize: 1, Where: where}
+	n, err := w.WriteFrom(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("WriteFrom: got %d records, want 2", n)
+	}
+
+	db, err := sql.Open("sqlite3", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var cases = []struct {
+		query string
+		want  string
+	}{
+		{`SELECT group_concat(id) FROM (SELECT id FROM records ORDER BY id)`, "ai-49-a,ai-49-c"},
+		{`SELECT date FROM records WHERE doi = '10.1/a'`, "2001-02-03"},
+		{`SELECT group_concat(id) FROM issn WHERE issn = '1234-5678'`, "ai-49-a"},
+		{`SELECT count(*) FROM issn`, "2"},
+		{`SELECT json_extract(record, '$."finc.source_id"') FROM records WHERE id = 'ai-49-c'`, "49"},
+	}
+	for _, c := range cases {
+		var got string
+		if err := db.QueryRow(c.query).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", c.query, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: got %q, want %q", c.query, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// span-filter drops or keeps intermediate schema records by DOI or record
+// id, e.g. for takedown requests or to build a partial test index
+// reproducibly. Lists contain one DOI or id (finc.id or finc.record_id) per
+// line.
+//
+//	$ span-filter -x takedown.txt < file.ldj > filtered.ldj
+//	$ span-filter -k sample.txt < file.ldj > sample.ldj
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
+	"github.com/miku/span/container"
+	"github.com/miku/span/doi"
+	"github.com/miku/span/filter"
+	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/parallel"
+)
+
+// loadList reads DOI and ids from files and returns a filter matching any of
+// them.
+func loadList(filenames []string) (filter.Filter, error) {
+	dois, ids := container.NewStringSet(), container.NewStringSet()
+	for _, filename := range filenames {
+		lines, err := span.ReadLines(filename)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			if v := doi.Clean(line); v != "" {
+				dois.Add(v)
+			} else {
+				ids.Add(line)
+			}
+		}
+	}
+	log.Printf("loaded %d DOI and %d ids from %d file(s)", dois.Size(), ids.Size(), len(filenames))
+	return &filter.OrFilter{Filters: []filter.Filter{
+		&filter.DOIFilter{Values: dois},
+		&filter.IDFilter{Values: ids},
+	}}, nil
+}
+
+func main() {
+	var excludeFiles, keepFiles span.ArrayFlags
+	showVersion := flag.Bool("v", false, "prints current program version")
+	size := flag.Int("b", 20000, "batch size")
+	numWorkers := flag.Int("w", runtime.NumCPU(), "number of workers")
+	flag.Var(&excludeFiles, "x", "drop records, whose DOI or id is listed in file (repeatable)")
+	flag.Var(&keepFiles, "k", "keep only records, whose DOI or id is listed in file (repeatable)")
+
+	flag.Parse()
+	if err := assetutil.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	if *showVersion {
+		fmt.Println(span.AppVersion)
+		os.Exit(0)
+	}
+	if len(excludeFiles) == 0 && len(keepFiles) == 0 {
+		log.Fatal("-x or -k required")
+	}
+
+	var exclude, keep filter.Filter
+	var err error
+	if len(excludeFiles) > 0 {
+		if exclude, err = loadList(excludeFiles); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if len(keepFiles) > 0 {
+		if keep, err = loadList(keepFiles); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var reader io.Reader = os.Stdin
+
+	if flag.NArg() > 0 {
+		var files []io.Reader
+		for _, filename := range flag.Args() {
+			f, err := os.Open(filename)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			files = append(files, f)
+		}
+		reader = io.MultiReader(files...)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	var total, dropped int64
+	p := parallel.NewProcessor(bufio.NewReader(reader), w, func(_ int64, b []byte) ([]byte, error) {
+		atomic.AddInt64(&total, 1)
+		is := finc.IntermediateSchema{}
+
+		if err := finc.UnmarshalIntermediateSchema(b, &is); err != nil {
+			log.Printf("failed to unmarshal: %s", string(b))
+			return b, err
+		}
+		if (exclude != nil && exclude.Apply(is)) || (keep != nil && !keep.Apply(is)) {
+			atomic.AddInt64(&dropped, 1)
+			return nil, nil
+		}
+		if !bytes.HasSuffix(b, []byte("\n")) {
+			b = append(b, '\n')
+		}
+		return b, nil
+	})
+
+	p.NumWorkers = *numWorkers
+	p.BatchSize = *size
+
+	if err := p.Run(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("dropped %d of %d records", dropped, total)
+}
@@ -6,11 +6,13 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"strings"
 	"time"
 
+	log "github.com/sirupsen/logrus"
+
+	"github.com/miku/span/assetutil"
 	"github.com/miku/span/container"
 	"github.com/miku/span/licensing/kbart"
 	"github.com/miku/span/solrutil"
@@ -23,6 +25,9 @@ var (
 
 func main() {
 	flag.Parse()
+	if err := assetutil.Err(); err != nil {
+		log.Fatal(err)
+	}
 	*server = solrutil.PrependHTTP(*server)
 
 	if *holdingsFile == "" {
@@ -0,0 +1,27 @@
+//go:build go1.18
+// +build go1.18
+
+package main
+
+import (
+	"context"
+	"io"
+
+	"github.com/miku/span/formats/genios"
+	"github.com/miku/span/parallel"
+)
+
+// convertDelivery converts the documents of genios deliveries in parallel.
+// Documents are passed to the workers as typed values.
+func convertDelivery(ctx context.Context, src *deliveries, w io.Writer) error {
+	p := parallel.NewSourceProcessor[*genios.Document](src, w, func(_ int64, doc *genios.Document) ([]byte, error) {
+		defer doc.Release()
+		return convert(doc)
+	})
+	p.NumWorkers = *numWorkers
+	p.PreserveOrder = *ordered
+	p.Selection = *selection
+	p.Progress = progress
+	setBatchSize(&p.Settings)
+	return p.RunContext(ctx)
+}
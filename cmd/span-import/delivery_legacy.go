@@ -0,0 +1,29 @@
+//go:build !go1.18
+// +build !go1.18
+
+package main
+
+import (
+	"context"
+	"io"
+
+	"github.com/miku/span/formats/genios"
+	"github.com/miku/span/parallel"
+)
+
+// convertDelivery converts the documents of genios deliveries in parallel,
+// for Go versions without generics.
+func convertDelivery(ctx context.Context, src *deliveries, w io.Writer) error {
+	p := parallel.NewItemProcessor(func() (interface{}, error) {
+		return src.Next()
+	}, w, func(_ int64, v interface{}) ([]byte, error) {
+		defer v.(*genios.Document).Release()
+		return convert(v)
+	})
+	p.NumWorkers = *numWorkers
+	p.PreserveOrder = *ordered
+	p.Selection = *selection
+	p.Progress = progress
+	setBatchSize(&p.Settings)
+	return p.RunContext(ctx)
+}
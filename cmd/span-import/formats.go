@@ -0,0 +1,20 @@
+package main
+
+// Formats maintained outside this repository, e.g. under publisher contracts,
+// register themselves with span.RegisterFormat in an init function:
+//
+//	package acme
+//
+//	func init() {
+//		span.RegisterFormat("acme", func() interface{} { return new(Article) })
+//	}
+//
+// They are compiled in with a blank import in this package, e.g. in a file
+// formats_local.go, which is not part of this repository:
+//
+//	package main
+//
+//	import _ "example.com/acme/span/acme"
+//
+// The format is then listed with -list and selected with -i acme, like
+// builtin formats, including -sources overrides.
@@ -2,6 +2,8 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"encoding"
 	"encoding/json"
 	"flag"
@@ -12,12 +14,21 @@ import (
 	"runtime"
 	"runtime/pprof"
 	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
-	"bufio"
-
 	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
+	"github.com/miku/span/cache"
+	"github.com/miku/span/cleanup"
+	"github.com/miku/span/collections"
+	"github.com/miku/span/encoding/xmliter"
+	"github.com/miku/span/enrich"
+	"github.com/miku/span/filter"
 	"github.com/miku/span/formats/ceeol"
 	"github.com/miku/span/formats/crossref"
 	"github.com/miku/span/formats/degruyter"
@@ -38,26 +49,91 @@ import (
 	"github.com/miku/span/formats/ssoar"
 	"github.com/miku/span/formats/thieme"
 	"github.com/miku/span/formats/zvdd"
+	"github.com/miku/span/logging"
+	"github.com/miku/span/manifest"
+	"github.com/miku/span/mint"
 	"github.com/miku/span/parallel"
-	"github.com/miku/xmlstream"
+	"github.com/miku/span/sourceconf"
+	"github.com/miku/span/stats"
 )
 
 var (
 	name        = flag.String("i", "", "input format name")
 	list        = flag.Bool("list", false, "list input formats")
 	numWorkers  = flag.Int("w", runtime.NumCPU(), "number of workers")
+	batchMB     = flag.Int("batch-mb", parallel.DefaultBatchBytes>>20, "limit the input size of a batch in megabytes, so huge records do not exhaust memory, 0 means no limit")
+	outputFile  = flag.String("o", "", "output file or s3://bucket/key, defaults to stdout")
+	verifyMode  = flag.String("verify", "", "check input files against checksum manifests: warn or strict, which refuses corrupted inputs")
+	verifyLog   = flag.String("verify-log", "", "write per file verification results as JSON lines to this file")
+	ordered     = flag.Bool("preserve-order", false, "write records in input order")
 	showVersion = flag.Bool("v", false, "prints current program version")
 	cpuProfile  = flag.String("cpuprofile", "", "write cpu profile to file")
+	typeMapping = flag.String("crossref-types", "", "JSON file overriding crossref type to format, genre and reftype mapping")
+	membersFile = flag.String("crossref-members", "", "resolve crossref member names via API, cached in this file")
+	membersTTL  = flag.Duration("crossref-members-ttl", 720*time.Hour, "refetch cached crossref member names after this duration")
+	offline     = flag.Bool("crossref-members-offline", false, "only use cached crossref member names")
+	sourcesFile = flag.String("sources", os.Getenv("SPAN_SOURCES"), "YAML file with per format overrides of source id, id prefix, collections, format, genre, reftype, languages and batch size (env SPAN_SOURCES)")
+	idStrategy  = flag.String("id-strategies", "", "JSON file mapping source ids to id minting strategies: base64, plain, sha1 or doi")
+	cacheLink   = flag.String("cache", "", "shared cache for lookups like crossref member names, e.g. redis://localhost:6379/0 or memcached://localhost:11211")
+	skipErrors  = flag.Bool("skip-errors", false, "skip records, that cannot be converted, JSON formats only")
+	maxErrors   = flag.Int64("max-errors", 0, "give up after this many errors, implies -skip-errors, 0 means no limit")
+	errorsFile  = flag.String("errors-file", "", "write records, that cannot be converted, to this file, implies -skip-errors")
+	dbmapFile   = flag.String("genios-dbmap", os.Getenv("SPAN_GENIOS_DBMAP"), "genios database to package mapping, file or URL (env SPAN_GENIOS_DBMAP)")
+	fetchRetry  = flag.Int("fetch-retries", span.DefaultMaxRetries, "retries for downloads of links, e.g. -genios-dbmap, negative values disable retries")
+	fetchWait   = flag.Duration("fetch-backoff", span.DefaultBackoff, "wait before the first retry of a download, doubled on each retry")
+	deletions   = flag.String("genios-deletions", "", "write finc ids of documents deleted in genios-zip deliveries to this file")
+	deletedFile = flag.String("deleted", "", "write finc ids of records marked deleted in the input, e.g. OAI records with status deleted, to this file")
+	noFulltext  = flag.String("genios-no-fulltext", "", "comma separated genios database or package names, whose fulltext must not be indexed")
+	langDetect  = flag.String("lang-detector", "whatlanggo", "comma separated language detectors, later ones used as fallback")
+	trustLang   = flag.Bool("lang-trust-record", false, "use the language given in a record, if any, instead of detection")
+	references  = flag.Bool("crossref-references", false, "capture cited DOIs from crossref into x.references, increases output size")
+	fastDecode  = flag.Bool("crossref-fast-decode", false, "decode only crossref fields needed for conversion, faster for documents with many references or links")
+	issnFile    = flag.String("issn-registry", "", "CSV or TSV snapshot of journal titles and publishers by ISSN, to fill in missing values")
+	issnCorrect = flag.Bool("issn-correct", false, "replace a 0 check digit with X, if that makes an invalid ISSN valid")
+	reportFile  = flag.String("report", "", "write run statistics, converted, skipped and failed records per source, as JSON to this file")
+	summary     = flag.Bool("summary", false, "write run statistics summary to stderr")
+	cleanText   = flag.Bool("cleanup", false, "decode double encoded entities, strip markup and control characters from text fields, per format setting in -sources takes precedence")
+	listAssets  = flag.Bool("list-assets", false, "list embedded assets and overrides")
+	rulesFile   = flag.String("collection-rules", os.Getenv("SPAN_COLLECTION_RULES"), "YAML file with rules to rename, merge or drop packages and mega collections (env SPAN_COLLECTION_RULES)")
+	whereExpr   = flag.String("where", "", "keep only converted records matching a filter expression or file, e.g. {\"source\": [\"49\"]}, see span-tag")
+	logOptions  = logging.RegisterFlags(flag.CommandLine)
+	selection   = parallel.RegisterSelectionFlags(flag.CommandLine)
+	progressInt = flag.Duration("progress", 0, "log records processed, MB read, rate and estimated remaining time in this interval, e.g. 1m, 0 disables")
+
+	// assetFiles replace embedded assets, refs. -asset.
+	assetFiles span.ArrayFlags
+
+	// registry is used to fill in missing journal titles and publishers.
+	registry enrich.ISSNRegistry
+	// enriched counts records changed by the registry.
+	enriched int64
+
+	// collector counts converted, skipped and failed records.
+	collector = stats.New("")
+
+	// overrides are the configured values for the input format, refs. -sources.
+	overrides sourceconf.Source
+	// collectionRules rewrite packages and mega collections, refs. -collection-rules.
+	collectionRules collections.Rules
+	// where selects converted records, refs. -where.
+	where filter.Filter
+	// progress counts records and bytes read, refs. -progress.
+	progress *parallel.Progress
+	// deleted receives finc ids of deleted records, refs. -deleted.
+	deleted   io.Writer
+	deletedMu sync.Mutex
 )
 
 // Factory creates things.
 type Factory func() interface{}
 
-// FormatMap maps format name to pointer to format struct.
+// FormatMap maps builtin format names to pointer to format struct. These are
+// registered with span.RegisterFormat, along with formats from packages
+// compiled in, refs. formats.go.
 var FormatMap = map[string]Factory{
 	"ceeol":         func() interface{} { return new(ceeol.Article) },
 	"ceeol-marcxml": func() interface{} { return new(ceeol.Record) },
-	"crossref":      func() interface{} { return new(crossref.Document) },
+	"crossref":      func() interface{} { return crossref.NewDocument() },
 	"degruyter":     func() interface{} { return new(degruyter.Article) },
 	"disson":        func() interface{} { return new(disson.Record) },
 	"doaj-oai":      func() interface{} { return new(doaj.Record) },
@@ -80,79 +156,226 @@ var FormatMap = map[string]Factory{
 	"zvdd-mets":     func() interface{} { return new(zvdd.MetsRecord) },
 }
 
+func init() {
+	for name, f := range FormatMap {
+		span.RegisterFormat(name, f)
+	}
+}
+
 // IntermediateSchemaer wrap a basic conversion method.
 type IntermediateSchemaer interface {
 	ToIntermediateSchema() (*finc.IntermediateSchema, error)
 }
 
-// processXML converts XML based formats, given a format name. It reads XML as
-// stream and converts record them to an intermediate // schema (at the
-// moment).
-func processXML(r io.Reader, w io.Writer, name string) error {
-	if _, ok := FormatMap[name]; !ok {
-		return fmt.Errorf("unknown format name: %s", name)
-	}
-	obj := FormatMap[name]()
-	scanner := xmlstream.NewScanner(bufio.NewReader(r), obj)
-	scanner.Decoder.Strict = false // Errors of the invalid character entity kind are common.
-	for scanner.Scan() {
-		tag := scanner.Element()
-		converter, ok := tag.(IntermediateSchemaer)
-		if !ok {
-			return fmt.Errorf("cannot convert to intermediate schema: %T", tag)
+// Decoder is implemented by formats with a faster JSON decoder than
+// json.Unmarshal.
+type Decoder interface {
+	Decode(b []byte) error
+}
+
+// Releaser is implemented by pooled values, that can be reused after
+// conversion.
+type Releaser interface {
+	Release()
+}
+
+// convert converts a value to intermediate schema and returns it as a line
+// of JSON. Skipped records result in no output.
+func convert(v interface{}) ([]byte, error) {
+	converter, ok := v.(IntermediateSchemaer)
+	if !ok {
+		return nil, fmt.Errorf("cannot convert to intermediate schema: %T", v)
+	}
+	output, err := converter.ToIntermediateSchema()
+	if skip, ok := err.(span.Skip); ok {
+		collector.Skipped(output, skip.Reason)
+		if skip.Reason == span.SkipDeleted {
+			if err := writeDeleted(output); err != nil {
+				return nil, err
+			}
 		}
-		output, err := converter.ToIntermediateSchema()
-		if err != nil {
-			if _, ok := err.(span.Skip); ok {
+		return nil, nil
+	}
+	if err != nil {
+		collector.Error(output)
+		return nil, err
+	}
+	return finish(output)
+}
+
+// finish applies overrides, -where and postprocessing to a converted record
+// and returns it as a line of JSON. Records not selected result in no output.
+func finish(output *finc.IntermediateSchema) ([]byte, error) {
+	if err := overrides.Apply(output); err != nil {
+		collector.Error(output)
+		return nil, err
+	}
+	if where != nil && !where.Apply(*output) {
+		collector.Skipped(output, "not selected")
+		return nil, nil
+	}
+	postprocess(output)
+	bb, err := json.Marshal(output)
+	if err != nil {
+		return nil, err
+	}
+	bb = append(bb, '\n')
+	return bb, nil
+}
+
+// writeDeleted writes the finc id of a deleted record, refs. -deleted.
+func writeDeleted(is *finc.IntermediateSchema) error {
+	if deleted == nil || is == nil || is.ID == "" {
+		return nil
+	}
+	id, err := overrides.RewriteID(is.SourceID, is.ID)
+	if err != nil {
+		return err
+	}
+	deletedMu.Lock()
+	defer deletedMu.Unlock()
+	_, err = io.WriteString(deleted, id+"\n")
+	return err
+}
+
+// postprocess applies checks and enrichments to a converted record and
+// counts it.
+func postprocess(is *finc.IntermediateSchema) {
+	if *cleanText && cleanup.Record(is) {
+		collector.Inc(is, "text_cleaned")
+	}
+	if collectionRules.Apply(is) {
+		collector.Inc(is, "collections_rewritten")
+	}
+	checkISSN(is)
+	if registry != nil && registry.Enrich(is) {
+		atomic.AddInt64(&enriched, 1)
+	}
+	collector.Converted(is)
+}
+
+// checkISSN counts ISSN with invalid shape or check digit per source and
+// corrects them, if requested. Invalid ISSN are kept.
+func checkISSN(is *finc.IntermediateSchema) {
+	for _, issns := range []*[]string{&is.ISSN, &is.EISSN} {
+		for i, s := range *issns {
+			v, err := span.ParseISSN(s)
+			if err == nil {
+				err = v.Validate()
+			}
+			if err == nil {
 				continue
 			}
-			return err
-		}
-		if err := json.NewEncoder(w).Encode(output); err != nil {
-			return err
+			if c, ok := v.Correct(); ok && *issnCorrect {
+				(*issns)[i] = string(c)
+				collector.Inc(is, "issn_corrected")
+			} else {
+				collector.Inc(is, "issn_invalid")
+			}
 		}
 	}
-	return scanner.Err()
 }
 
-// processJSON convert JSON based formats. Input is interpreted as newline delimited JSON.
-func processJSON(r io.Reader, w io.Writer, name string) error {
-	if _, ok := FormatMap[name]; !ok {
-		return fmt.Errorf("unknown format name: %s", name)
+// processXML converts XML based formats. It reads XML as stream and converts
+// records to an intermediate schema in parallel.
+func processXML(ctx context.Context, r io.Reader, w io.Writer, format span.Format) error {
+	elem := xmliter.ElementName(format.New())
+	if elem == "" {
+		return fmt.Errorf("%s: cannot find XML element name", format.Name)
 	}
-	p := parallel.NewProcessor(r, w, func(_ int64, b []byte) ([]byte, error) {
-		v := FormatMap[name]()
-		if err := json.Unmarshal(b, v); err != nil {
+	dec := xmliter.NewDecoder(r, elem)
+	next := func() (interface{}, error) {
+		v := format.New()
+		if err := dec.Decode(v); err != nil {
 			return nil, err
 		}
-		converter, ok := v.(IntermediateSchemaer)
-		if !ok {
-			return nil, fmt.Errorf("cannot convert to intermediate schema: %T", v)
-		}
-		output, err := converter.ToIntermediateSchema()
-		if _, ok := err.(span.Skip); ok {
-			return nil, nil
+		return v, nil
+	}
+	p := parallel.NewItemProcessor(next, w, func(_ int64, v interface{}) ([]byte, error) {
+		return convert(v)
+	})
+	// The size of an element is the input consumed since the last one.
+	var offset int64
+	p.ItemSize = func(_ interface{}) int {
+		last := offset
+		offset = dec.InputOffset()
+		return int(offset - last)
+	}
+	p.NumWorkers = *numWorkers
+	p.PreserveOrder = *ordered
+	p.Selection = *selection
+	p.Progress = progress
+	setBatchSize(&p.Settings)
+	return p.RunContext(ctx)
+}
+
+// batchBytes returns the batch size limit in bytes, refs. -batch-mb.
+func batchBytes() int64 {
+	if overrides.BatchMB > 0 {
+		return int64(overrides.BatchMB) << 20
+	}
+	return int64(*batchMB) << 20
+}
+
+// setBatchSize sets batch size limits, as configured.
+func setBatchSize(p *parallel.Settings) {
+	p.BatchBytes = batchBytes()
+	if overrides.BatchSize > 0 {
+		p.BatchSize = overrides.BatchSize
+	}
+}
+
+// processJSON convert JSON based formats. Input is interpreted as newline delimited JSON.
+func processJSON(ctx context.Context, r io.Reader, w io.Writer, format span.Format) error {
+	p := parallel.NewProcessor(r, w, func(_ int64, b []byte) ([]byte, error) {
+		v := format.New()
+		if r, ok := v.(Releaser); ok {
+			defer r.Release()
 		}
-		if err != nil {
-			return nil, err
+		var err error
+		if dec, ok := v.(Decoder); ok {
+			err = dec.Decode(b)
+		} else {
+			err = json.Unmarshal(b, v)
 		}
-		bb, err := json.Marshal(output)
 		if err != nil {
+			collector.Error(nil)
 			return nil, err
 		}
-		bb = append(bb, '\n')
-		return bb, nil
+		return convert(v)
 	})
-	return p.RunWorkers(*numWorkers)
+	p.PreserveOrder = *ordered
+	p.Selection = *selection
+	p.Progress = progress
+	if *skipErrors || *maxErrors > 0 || *errorsFile != "" {
+		errlog := &parallel.ErrorLog{MaxErrors: *maxErrors}
+		if *errorsFile != "" {
+			f, err := os.Create(*errorsFile)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			errlog.W = f
+		}
+		p.OnError = errlog.Handle
+		defer func() {
+			if n := errlog.Count(); n > 0 {
+				log.Warnf("skipped %d records with errors", n)
+			}
+		}()
+	}
+	p.NumWorkers = *numWorkers
+	p.BatchBytes = batchBytes()
+	if overrides.BatchSize > 0 {
+		p.BatchSize = overrides.BatchSize
+	}
+	return p.RunContext(ctx)
 }
 
 // processText processes a single record from raw bytes.
-func processText(r io.Reader, w io.Writer, name string) error {
-	if _, ok := FormatMap[name]; !ok {
-		return fmt.Errorf("unknown format name: %s", name)
-	}
+func processText(r io.Reader, w io.Writer, format span.Format) error {
 	// Get the format.
-	data := FormatMap[name]()
+	data := format.New()
 
 	// We need an unmarshaller first.
 	unmarshaler, ok := data.(encoding.TextUnmarshaler)
@@ -164,6 +387,7 @@ func processText(r io.Reader, w io.Writer, name string) error {
 		return err
 	}
 	if err := unmarshaler.UnmarshalText(b); err != nil {
+		collector.Error(nil)
 		return err
 	}
 
@@ -173,17 +397,160 @@ func processText(r io.Reader, w io.Writer, name string) error {
 		return fmt.Errorf("cannot convert to intermediate schema: %T", data)
 	}
 	output, err := converter.ToIntermediateSchema()
-	if _, ok := err.(span.Skip); ok {
+	if skip, ok := err.(span.Skip); ok {
+		collector.Skipped(output, skip.Reason)
+		if skip.Reason == span.SkipDeleted {
+			return writeDeleted(output)
+		}
 		return nil
 	}
+	if err != nil {
+		collector.Error(output)
+		return err
+	}
+	line, err := finish(output)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(line)
+	return err
+}
+
+// processGeniosDelivery converts genios zip deliveries, applying deletion
+// lists. If dw is not nil, the finc ids of deleted documents are written to it.
+// Sampling and limits apply to all deliveries together, as for other inputs.
+func processGeniosDelivery(ctx context.Context, w io.Writer, dw io.Writer, paths []string) error {
+	var ds deliveries
+	defer ds.Close()
+	for _, path := range paths {
+		delivery, err := genios.OpenDelivery(path)
+		if err != nil {
+			return err
+		}
+		ds.deliveries = append(ds.deliveries, delivery)
+	}
+	if err := convertDelivery(ctx, &ds, w); err != nil {
+		return err
+	}
+	if dw == nil {
+		return nil
+	}
+	for i, delivery := range ds.deliveries {
+		// With -head or -sample, documents delivered again after their
+		// deletion may not have been read, so they would still be listed.
+		if err := delivery.Drain(); err != nil {
+			return err
+		}
+		resolved, unresolved := genios.DeletedFincIDs(delivery.Deletions())
+		for _, id := range resolved {
+			id, err := overrides.RewriteID(genios.SourceID, id)
+			if err != nil {
+				return err
+			}
+			if _, err := io.WriteString(dw, id+"\n"); err != nil {
+				return err
+			}
+		}
+		if len(unresolved) > 0 {
+			log.Warnf("%s: %d deleted document ids without source, cannot derive finc id", paths[i], len(unresolved))
+		}
+	}
+	return nil
+}
+
+// deliveries reads the documents of a number of genios deliveries in turn.
+type deliveries struct {
+	deliveries []*genios.Delivery
+	current    int
+}
+
+// Next returns the next document of the current or a later delivery.
+func (d *deliveries) Next() (*genios.Document, error) {
+	for d.current < len(d.deliveries) {
+		doc, err := d.deliveries[d.current].Next()
+		if err == io.EOF {
+			d.current++
+			continue
+		}
+		return doc, err
+	}
+	return nil, io.EOF
+}
+
+// Close closes all deliveries and returns the first error.
+func (d *deliveries) Close() error {
+	var first error
+	for _, delivery := range d.deliveries {
+		if err := delivery.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// verifyInputs checks local input files against checksum manifests shipped
+// with them, refs. -verify.
+func verifyInputs(paths []string) error {
+	var local []string
+	for _, p := range paths {
+		if !strings.Contains(p, "://") {
+			local = append(local, p)
+		}
+	}
+	results, err := manifest.Verify(local...)
 	if err != nil {
 		return err
 	}
-	return json.NewEncoder(w).Encode(output)
+	var enc *json.Encoder
+	if *verifyLog != "" {
+		f, err := os.Create(*verifyLog)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		enc = json.NewEncoder(f)
+	}
+	var failed int
+	for _, r := range results {
+		if enc != nil {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		switch {
+		case r.Failed():
+			failed++
+			log.WithFields(log.Fields{"manifest": r.Manifest, "status": r.Status}).Warn(r.Filename)
+		case r.Status == manifest.StatusUnlisted:
+			log.WithField("status", r.Status).Debug(r.Filename)
+		}
+	}
+	if failed > 0 && *verifyMode == "strict" {
+		return fmt.Errorf("%d input files failed verification", failed)
+	}
+	return nil
 }
 
 func main() {
+	flag.Var(&assetFiles, "asset", "replace an embedded asset with a file, name=file, e.g. assets/crossref/formats.json=formats.json (repeatable)")
 	flag.Parse()
+	if err := logOptions.Setup(); err != nil {
+		log.Fatal(err)
+	}
+	if err := assetutil.OverrideFlags(assetFiles); err != nil {
+		log.Fatal(err)
+	}
+	if err := assetutil.Err(); err != nil {
+		log.Fatal(err)
+	}
+	span.DefaultMaxRetries, span.DefaultBackoff = *fetchRetry, *fetchWait
+	if *listAssets {
+		if err := assetutil.WriteNames(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+	collector.Default = *name
 
 	if *showVersion {
 		fmt.Println(span.AppVersion)
@@ -199,27 +566,168 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
-	if *list {
-		var keys []string
-		for k := range FormatMap {
-			keys = append(keys, k)
+	crossref.CaptureReferences = *references
+	crossref.FastDecode = *fastDecode
+
+	if *membersFile != "" || *cacheLink != "" {
+		crossref.Members = &crossref.MemberResolver{
+			CacheFile: *membersFile,
+			TTL:       *membersTTL,
+			Offline:   *offline,
 		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			fmt.Println(k)
+		if *cacheLink != "" {
+			c, err := cache.Open(*cacheLink)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer c.Close()
+			crossref.Members.Cache = c
+		}
+	}
+
+	if *dbmapFile != "" {
+		var r io.Reader
+		if strings.HasPrefix(*dbmapFile, "http://") || strings.HasPrefix(*dbmapFile, "https://") {
+			r = &span.LinkReader{Link: *dbmapFile}
+		} else {
+			f, err := os.Open(*dbmapFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			r = f
+		}
+		m, err := genios.LoadDatabaseMap(r)
+		if err != nil {
+			log.Fatal(err)
+		}
+		genios.DatabaseMap = m
+		log.Printf("loaded genios database map with %d entries from %s", len(m), *dbmapFile)
+	}
+
+	detector, err := span.NewLanguageDetector(*langDetect)
+	if err != nil {
+		log.Fatal(err)
+	}
+	span.DefaultLanguageDetector = detector
+	span.TrustRecordLanguage = *trustLang
+
+	for _, name := range strings.Split(*noFulltext, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			genios.NoFulltext.Add(name)
+		}
+	}
+
+	if *typeMapping != "" {
+		f, err := os.Open(*typeMapping)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := crossref.LoadTypeMapping(f); err != nil {
+			log.Fatal(err)
+		}
+		f.Close()
+	}
+
+	if *sourcesFile != "" {
+		c, err := sourceconf.LoadFile(*sourcesFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		overrides = c[*name]
+		if overrides.Cleanup != nil {
+			*cleanText = *overrides.Cleanup
+		}
+	}
+
+	if err := selection.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if *batchMB < 0 {
+		log.Fatalf("-batch-mb must not be negative: %d", *batchMB)
+	}
+	if *whereExpr != "" {
+		f, err := filter.Parse(*whereExpr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		where = f
+	}
+
+	if *rulesFile != "" {
+		rules, err := collections.LoadFile(*rulesFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		collectionRules = rules
+	}
+
+	if *idStrategy != "" {
+		f, err := os.Open(*idStrategy)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := mint.LoadStrategies(f); err != nil {
+			log.Fatal(err)
+		}
+		f.Close()
+	}
+
+	if *issnFile != "" {
+		f, err := span.Open(*issnFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if registry, err = enrich.LoadISSNRegistry(f); err != nil {
+			log.Fatal(err)
+		}
+		f.Close()
+		log.Printf("loaded ISSN registry with %d entries from %s", len(registry), *issnFile)
+	}
+
+	if *list {
+		for _, name := range span.FormatNames() {
+			fmt.Println(name)
 		}
 		os.Exit(0)
 	}
 
-	w := bufio.NewWriter(os.Stdout)
-	defer w.Flush()
+	var out io.WriteCloser = os.Stdout
+	if *outputFile != "" {
+		f, err := span.Create(*outputFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	// Flushed and closed at the end, since uploads may fail on close.
+
+	switch *verifyMode {
+	case "":
+	case "warn", "strict":
+		if err := verifyInputs(flag.Args()); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("unknown verify mode: %s", *verifyMode)
+	}
+
+	if *deletedFile != "" {
+		f, err := os.Create(*deletedFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		deleted = f
+	}
 
 	var reader io.Reader = os.Stdin
 
 	if flag.NArg() > 0 {
 		var files []io.Reader
 		for _, filename := range flag.Args() {
-			f, err := os.Open(filename)
+			f, err := span.Open(filename)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -229,23 +737,49 @@ func main() {
 		reader = io.MultiReader(files...)
 	}
 
-	switch *name {
-	// XXX: Configure this in one place.
-	case "highwire", "ceeol", "ieee", "genios", "jstor", "thieme-tm",
-		"zvdd", "degruyter", "zvdd-mets", "hhbd", "thieme-nlm", "olms",
-		"olms-mets", "ssoar", "disson", "genderopen", "mediarep-dim",
-		"ceeol-marcxml", "doaj-oai":
-		if err := processXML(reader, w, *name); err != nil {
-			log.Fatal(err)
+	if *progressInt > 0 {
+		progress = &parallel.Progress{
+			Interval: *progressInt,
+			Report:   func(s parallel.Status) { log.Info(s) },
 		}
-	case "doaj", "doaj-api", "crossref", "dummy":
-		if err := processJSON(reader, w, *name); err != nil {
-			log.Fatal(err)
+		// Bytes of zip deliveries are not counted, so there is no estimate.
+		if *name != "genios-zip" {
+			progress.Size = parallel.InputSize(flag.Args()...)
+			reader = progress.Reader(reader)
+		}
+		progress.Start()
+	}
+
+	// An interrupt stops reading input, records already read are converted
+	// and written and the run statistics are reported.
+	ctx, cancel := span.InterruptContext(context.Background())
+	defer cancel()
+	var interrupted bool
+	check := func(err error) {
+		if err == context.Canceled {
+			interrupted = true
+			return
 		}
-	case "imslp":
-		if err := processText(reader, w, *name); err != nil {
+		if err != nil {
 			log.Fatal(err)
 		}
+	}
+
+	switch *name {
+	case "genios-zip":
+		if flag.NArg() == 0 {
+			log.Fatal("genios-zip requires zip files or directories as arguments")
+		}
+		var dw io.Writer
+		if *deletions != "" {
+			f, err := os.Create(*deletions)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			dw = f
+		}
+		check(processGeniosDelivery(ctx, w, dw, flag.Args()))
 	case "elsevier-tar":
 		shipment, err := elsevier.NewShipment(reader)
 		if err != nil {
@@ -255,9 +789,16 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
-		encoder := json.NewEncoder(w)
-		for _, doc := range docs {
-			if encoder.Encode(doc); err != nil {
+		for i := range docs {
+			if ctx.Err() != nil {
+				check(ctx.Err())
+				break
+			}
+			b, err := finish(&docs[i])
+			if err != nil {
+				log.Fatal(err)
+			}
+			if _, err := w.Write(b); err != nil {
 				log.Fatal(err)
 			}
 		}
@@ -265,6 +806,118 @@ func main() {
 		if *name == "" {
 			log.Fatalf("input format required")
 		}
-		log.Fatalf("unknown format: %s", *name)
+		format, ok := span.LookupFormat(*name)
+		if !ok {
+			log.Fatalf("unknown format: %s", *name)
+		}
+		switch format.Kind {
+		case span.XMLFormat:
+			check(processXML(ctx, reader, w, format))
+		case span.JSONFormat:
+			check(processJSON(ctx, reader, w, format))
+			if crossref.Members != nil {
+				if err := crossref.Members.Flush(); err != nil {
+					log.Fatal(err)
+				}
+				if n, err := crossref.Members.Errors(); n > 0 {
+					log.Warnf("crossref: %d member lookup errors, publisher used as collection if unresolved, last: %v", n, err)
+				}
+			}
+		case span.TextFormat:
+			if err := processText(reader, w, format); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if progress != nil {
+		progress.Stop()
+	}
+	if *outputFile != "" {
+		if err := out.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if registry != nil {
+		log.Printf("filled in journal title or publisher for %d records from ISSN registry", enriched)
+	}
+
+	// Report run statistics, refs. -report, -summary.
+	if *reportFile != "" {
+		f, err := os.Create(*reportFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := collector.WriteJSON(f); err != nil {
+			log.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *summary {
+		if err := collector.WriteSummary(os.Stderr); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Report invalid ISSN per source, refs. -issn-correct.
+	report := collector.Report()
+	var sids []string
+	for sid := range report.Sources {
+		sids = append(sids, sid)
+	}
+	sort.Strings(sids)
+	for _, sid := range sids {
+		counters := report.Sources[sid].Counters
+		if counters["issn_invalid"] > 0 || counters["issn_corrected"] > 0 {
+			log.Warnf("source %s: %d invalid ISSN, %d corrected", sid, counters["issn_invalid"], counters["issn_corrected"])
+		}
+	}
+
+	// Report genios databases without package names, refs. -genios-dbmap.
+	unmapped := genios.UnmappedDatabases()
+	var dbs []string
+	for db := range unmapped {
+		dbs = append(dbs, db)
+	}
+	sort.Strings(dbs)
+	for _, db := range dbs {
+		log.Warnf("genios: %d documents from unmapped database %s", unmapped[db], db)
+	}
+	// Report, where genios dates came from, if any fallback was used.
+	sources := genios.DateSources()
+	var total int
+	for _, v := range sources {
+		total += v
+	}
+	if total > sources["year"]+sources["date"] {
+		log.WithFields(log.Fields{
+			"year":   sources["year"],
+			"date":   sources["date"],
+			"source": sources["source"],
+			"text":   sources["text"],
+			"issue":  sources["issue"],
+			"volume": sources["volume"],
+			"none":   sources["none"],
+		}).Info("genios: date fallbacks")
+	}
+	// Report documents without title, refs. assets/genios/titles.json.
+	titles := genios.TitleSources()
+	if titles["text"]+titles["source"]+titles["none"] > 0 {
+		log.WithFields(log.Fields{
+			"title":  titles["title"],
+			"text":   titles["text"],
+			"source": titles["source"],
+			"none":   titles["none"],
+		}).Info("genios: title fallbacks")
+	}
+	if interrupted {
+		log.Warnf("interrupted after %d records, partial output written", collector.Report().Total.Converted)
+		os.Exit(130)
 	}
 }
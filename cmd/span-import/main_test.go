@@ -0,0 +1,142 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/encoding/xmliter"
+	"github.com/miku/span/formats/genios"
+	"github.com/miku/span/parallel"
+)
+
+// writeZip creates a zip file with the given file names and contents.
+func writeZip(t *testing.T, filename string, files map[string]string) {
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessGeniosDeliveryHead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-import-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// S__2 is deleted and delivered again later, S__9 stays deleted.
+	writeZip(t, filepath.Join(dir, "20190101.zip"), map[string]string{
+		"a.xml":           `<Document ID="1"><Source>S</Source><Title>A</Title></Document>`,
+		"LOESCHLISTE.txt": "S__2\nS__9\n",
+	})
+	writeZip(t, filepath.Join(dir, "20190102.zip"), map[string]string{
+		"b.xml": `<Document ID="2"><Source>S</Source><Title>B</Title></Document>`,
+	})
+
+	defer func(s parallel.Selection) { *selection = s }(*selection)
+	*selection = parallel.Selection{Limit: 1}
+
+	var out, deleted bytes.Buffer
+	if err := processGeniosDelivery(context.Background(), &out, &deleted, []string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	want := (genios.Document{ID: "9", Source: "S"}).FincID() + "\n"
+	if deleted.String() != want {
+		t.Errorf("deletions: got %q, want %q", deleted.String(), want)
+	}
+	if n := strings.Count(out.String(), "\n"); n > 1 {
+		t.Errorf("got %d records, want at most 1 with -head 1", n)
+	}
+}
+
+func TestProcessGeniosDeliverySelection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-import-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var paths []string
+	for _, name := range []string{"a.zip", "b.zip"} {
+		filename := filepath.Join(dir, name)
+		writeZip(t, filename, map[string]string{
+			"1.xml": `<Document ID="` + name + `1"><Source>S</Source><Title>T</Title><Year>2019</Year></Document>`,
+			"2.xml": `<Document ID="` + name + `2"><Source>S</Source><Title>T</Title><Year>2019</Year></Document>`,
+		})
+		paths = append(paths, filename)
+	}
+
+	defer func(s parallel.Selection) { *selection = s }(*selection)
+	*selection = parallel.Selection{Limit: 1}
+
+	var out bytes.Buffer
+	if err := processGeniosDelivery(context.Background(), &out, nil, paths); err != nil {
+		t.Fatal(err)
+	}
+	// The limit applies to all deliveries, not to each.
+	if n := strings.Count(out.String(), "\n"); n != 1 {
+		t.Errorf("got %d records, want 1 with -head 1: %s", n, out.String())
+	}
+}
+
+func TestXMLFormatElementNames(t *testing.T) {
+	for _, name := range span.FormatNames() {
+		format, _ := span.LookupFormat(name)
+		if format.Kind != span.XMLFormat {
+			continue
+		}
+		if xmliter.ElementName(format.New()) == "" {
+			t.Errorf("%s: no XML element name", name)
+		}
+	}
+	if format, ok := span.LookupFormat("ieee"); !ok {
+		t.Error("ieee format not registered")
+	} else if got := xmliter.ElementName(format.New()); got != "publication" {
+		t.Errorf("ieee: got element name %q, want publication", got)
+	}
+}
+
+func TestProcessXMLTruncated(t *testing.T) {
+	format, ok := span.LookupFormat("genderopen")
+	if !ok {
+		t.Fatal("genderopen format not registered")
+	}
+	record := `<Record><header status="deleted"><identifier>oai:www.genderopen.de:25595/1</identifier></header></Record>`
+	var cases = []struct {
+		about string
+		input string
+		err   bool
+	}{
+		{"complete", `<Records>` + record + record + `</Records>`, false},
+		{"unclosed wrapper", `<Records>` + record + record, false},
+		{"truncated record", `<Records>` + record + `<Record><header status="del`, true},
+	}
+	for _, c := range cases {
+		err := processXML(context.Background(), strings.NewReader(c.input), ioutil.Discard, format)
+		if (err != nil) != c.err {
+			t.Errorf("%s: got %v, want error %v", c.about, err, c.err)
+		}
+	}
+}
@@ -1,4 +1,5 @@
-// span-oa-filter will set x.oa to true, if the given KBART file validates a record.
+// span-oa-filter will set x.oa to true, if the given KBART file validates a
+// record or its DOI or ISSN is listed in an open access list.
 package main
 
 import (
@@ -6,14 +7,18 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync/atomic"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
 	"github.com/miku/span/filter"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/openaccess"
 	"github.com/miku/span/parallel"
 )
 
@@ -74,6 +79,7 @@ func main() {
 
 	var excludeSourceIdentifiersFlags span.ArrayFlags
 	var openAccessSourceIdentifiersFlags span.ArrayFlags
+	var listFiles, unpaywallFiles, oaKbartFiles span.ArrayFlags
 
 	showVersion := flag.Bool("v", false, "prints current program version")
 	kbartFile := flag.String("f", "", "path to a single KBART file")
@@ -82,8 +88,14 @@ func main() {
 	verbose := flag.Bool("verbose", false, "debug output")
 	flag.Var(&excludeSourceIdentifiersFlags, "xsid", "exclude a given SID from checks, x.oa will always be false (repeatable)")
 	flag.Var(&openAccessSourceIdentifiersFlags, "oasid", "always set x.oa true for a given sid (repeatable)")
+	flag.Var(&listFiles, "l", "file with one DOI or ISSN per line, optionally followed by a tab and a status, e.g. gold (repeatable)")
+	flag.Var(&unpaywallFiles, "unpaywall", "unpaywall snapshot, JSON lines, may be compressed (repeatable)")
+	flag.Var(&oaKbartFiles, "oa-kbart", "KBART file of open access journals, regardless of coverage, status gold (repeatable)")
 
 	flag.Parse()
+	if err := assetutil.Err(); err != nil {
+		log.Fatal(err)
+	}
 
 	if *showVersion {
 		fmt.Println(span.AppVersion)
@@ -116,6 +128,11 @@ func main() {
 		log.Printf("loaded free content map with %d entries", len(lookup))
 	}
 
+	list, err := loadList(listFiles, unpaywallFiles, oaKbartFiles)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	excludeSids := make(map[string]bool)
 	for _, sid := range excludeSourceIdentifiersFlags {
 		excludeSids[sid] = true
@@ -129,9 +146,10 @@ func main() {
 	w := bufio.NewWriter(os.Stdout)
 	defer w.Flush()
 
+	var listed int64
 	p := parallel.NewProcessor(bufio.NewReader(os.Stdin), w, func(_ int64, b []byte) ([]byte, error) {
 		var is finc.IntermediateSchema
-		if err := json.Unmarshal(b, &is); err != nil {
+		if err := finc.UnmarshalIntermediateSchema(b, &is); err != nil {
 			return nil, err
 		}
 
@@ -144,6 +162,8 @@ func main() {
 				// Set OA by KBART: various list (e.g. KBART in AMSL, OA GOLD list, maybe more in this format).
 				if filter.Apply(is) {
 					is.OpenAccess = true
+					// Journals listed as OA in KBART (e.g. DOAJ) are gold.
+					is.OAStatus = finc.OAStatusGold
 				}
 
 				// Additionally, compare free content API results.
@@ -156,6 +176,12 @@ func main() {
 						}
 					}
 				}
+
+				// Article level lists take precedence over collection level
+				// information.
+				if list.Len() > 0 && list.Apply(&is) {
+					atomic.AddInt64(&listed, 1)
+				}
 			}
 		}
 
@@ -171,4 +197,55 @@ func main() {
 	if err := p.Run(); err != nil {
 		log.Fatal(err)
 	}
+	if list.Len() > 0 {
+		log.Printf("%d records flagged by open access lists", listed)
+	}
+}
+
+// loadList reads open access lists of DOI and ISSN from files, which may be
+// compressed.
+func loadList(lines, unpaywall, kbartFiles []string) (*openaccess.List, error) {
+	list := openaccess.New()
+	read := func(filename string, f func(io.Reader) error) error {
+		file, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r, err := span.NewDecompressReader(file)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		if err := f(r); err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+		return nil
+	}
+	for _, filename := range lines {
+		if err := read(filename, list.ReadLines); err != nil {
+			return nil, err
+		}
+	}
+	for _, filename := range unpaywall {
+		err := read(filename, func(r io.Reader) error {
+			skipped, err := list.ReadUnpaywall(r)
+			if skipped > 0 {
+				log.Warnf("%s: skipped %d records with invalid DOI", filename, skipped)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, filename := range kbartFiles {
+		if err := read(filename, list.ReadKBART); err != nil {
+			return nil, err
+		}
+	}
+	if list.Len() > 0 {
+		log.Printf("loaded open access list with %d DOI and %d ISSN", len(list.DOI), len(list.ISSN))
+	}
+	return list, nil
 }
@@ -0,0 +1,239 @@
+// span-oai-harvest harvests records from an OAI-PMH endpoint and writes each
+// raw ListRecords response to a separate file, which can be fed into
+// span-import as is, e.g. with -i doaj-oai.
+//
+// Long date ranges are split into windows. Each window is written into its
+// own directory, which is moved into place once the window is complete, so
+// an interrupted harvest is resumed at the first incomplete window. Without
+// -from, the harvest continues at the end of the last harvest into the same
+// directory, or, with -state-db, the last harvest of the endpoint, prefix and
+// set recorded in a state database.
+//
+//	$ span-oai-harvest -endpoint https://www.doaj.org/oai.article -prefix oai_dc \
+//	    -from 2019-01-01 -window 168h -o doaj
+//	$ cat doaj/*/*.xml | span-import -i doaj-oai
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
+	"github.com/miku/span/oai"
+	"github.com/miku/span/state"
+)
+
+const dateLayout = "2006-01-02"
+
+// State is written after each complete window.
+type State struct {
+	Endpoint string    `json:"endpoint"`
+	Prefix   string    `json:"prefix"`
+	Set      string    `json:"set"`
+	Until    string    `json:"until"`
+	Modified time.Time `json:"modified"`
+}
+
+// readState reads a state file. A missing file results in an empty state.
+func readState(filename string) (State, error) {
+	var s State
+	b, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(b, &s)
+	return s, err
+}
+
+// writeState replaces the state file atomically.
+func writeState(filename string, s State) error {
+	s.Modified = time.Now()
+	b, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// Window is a date range, both ends inclusive.
+type Window struct {
+	From, Until time.Time
+}
+
+// Name is used as directory name.
+func (w Window) Name() string {
+	return fmt.Sprintf("%s_%s", w.From.Format(dateLayout), w.Until.Format(dateLayout))
+}
+
+// windows splits a date range into windows of a given size; zero size means
+// a single window.
+func windows(from, until time.Time, size time.Duration) (result []Window) {
+	if size <= 0 {
+		return []Window{{from, until}}
+	}
+	days := int(size.Hours() / 24)
+	if days < 1 {
+		days = 1
+	}
+	for start := from; !start.After(until); start = start.AddDate(0, 0, days) {
+		end := start.AddDate(0, 0, days-1)
+		if end.After(until) {
+			end = until
+		}
+		result = append(result, Window{start, end})
+	}
+	return result
+}
+
+// harvestWindow writes all responses of a window into a temporary directory,
+// which is renamed into dir on success.
+func harvestWindow(client *oai.Client, req oai.Request, dir string) (int, error) {
+	tmp := dir + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return 0, err
+	}
+	var page, count int
+	err := client.ListRecords(req, func(resp *oai.Response) error {
+		page++
+		count += len(resp.ListRecords.Records)
+		filename := filepath.Join(tmp, fmt.Sprintf("%06d.xml", page))
+		log.WithFields(log.Fields{"page": page, "count": count}).Debug(filename)
+		return ioutil.WriteFile(filename, resp.Raw, 0644)
+	})
+	if err != nil {
+		return count, err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return count, err
+	}
+	return count, os.Rename(tmp, dir)
+}
+
+func main() {
+	endpoint := flag.String("endpoint", "", "OAI endpoint")
+	prefix := flag.String("prefix", "oai_dc", "metadata prefix")
+	set := flag.String("set", "", "set, optional")
+	from := flag.String("from", "", "from date, 2006-01-02, defaults to the end of the last harvest, if any")
+	until := flag.String("until", time.Now().AddDate(0, 0, -1).Format(dateLayout), "until date, 2006-01-02")
+	window := flag.Duration("window", 0, "harvest in windows of this many days, e.g. 168h, 0 harvests the whole range at once")
+	maxRetries := flag.Int("retries", 10, "retries per request")
+	outputDir := flag.String("o", "", "output directory")
+	stateDB := flag.String("state-db", "", "state database, records the end of each harvest per endpoint, prefix and set")
+	showVersion := flag.Bool("v", false, "prints current program version")
+	verbose := flag.Bool("verbose", false, "be verbose")
+
+	flag.Parse()
+	if err := assetutil.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	if *showVersion {
+		fmt.Println(span.AppVersion)
+		os.Exit(0)
+	}
+	if *verbose {
+		log.SetLevel(log.DebugLevel)
+	}
+	if *endpoint == "" || *outputDir == "" {
+		log.Fatal("endpoint and output directory required")
+	}
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		log.Fatal(err)
+	}
+	var (
+		store    *state.Store
+		key      = fmt.Sprintf("oai|%s|%s|%s", *endpoint, *prefix, *set)
+		lastSeen time.Time
+	)
+	if *stateDB != "" {
+		var err error
+		if store, err = state.Open(*stateDB, 10*time.Second); err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+		if lastSeen, _, err = store.LastSeen(key); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	stateFile := filepath.Join(*outputDir, ".state")
+	state, err := readState(stateFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var start time.Time
+	switch {
+	case *from != "":
+		if start, err = time.Parse(dateLayout, *from); err != nil {
+			log.Fatal(err)
+		}
+	case !lastSeen.IsZero():
+		start = lastSeen.AddDate(0, 0, 1)
+	case state.Endpoint == *endpoint && state.Prefix == *prefix && state.Set == *set && state.Until != "":
+		last, err := time.Parse(dateLayout, state.Until)
+		if err != nil {
+			log.Fatal(err)
+		}
+		start = last.AddDate(0, 0, 1)
+	default:
+		log.Fatal("from date required for an initial harvest")
+	}
+	end, err := time.Parse(dateLayout, *until)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if start.After(end) {
+		log.WithField("until", state.Until).Info("nothing to harvest")
+		os.Exit(0)
+	}
+
+	client := oai.NewClient(*endpoint)
+	client.MaxRetries = *maxRetries
+
+	for _, w := range windows(start, end, *window) {
+		dir := filepath.Join(*outputDir, w.Name())
+		if _, err := os.Stat(dir); err == nil {
+			log.WithField("window", w.Name()).Debug("window already harvested")
+			continue
+		}
+		req := oai.Request{
+			MetadataPrefix: *prefix,
+			Set:            *set,
+			From:           w.From.Format(dateLayout),
+			Until:          w.Until.Format(dateLayout),
+		}
+		count, err := harvestWindow(client, req, dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		state = State{Endpoint: *endpoint, Prefix: *prefix, Set: *set, Until: req.Until}
+		if err := writeState(stateFile, state); err != nil {
+			log.Fatal(err)
+		}
+		if store != nil {
+			if err := store.SetLastSeen(key, w.Until); err != nil {
+				log.Fatal(err)
+			}
+		}
+		log.WithFields(log.Fields{"window": w.Name(), "count": count}).Info("harvested")
+	}
+}
@@ -15,6 +15,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
 	"github.com/miku/span/formats/finc"
 	"github.com/miku/span/parallel"
 )
@@ -25,6 +26,9 @@ func main() {
 	numWorkers := flag.Int("w", runtime.NumCPU(), "number of workers")
 
 	flag.Parse()
+	if err := assetutil.Err(); err != nil {
+		log.Fatal(err)
+	}
 
 	if *showVersion {
 		fmt.Println(span.AppVersion)
@@ -52,7 +56,7 @@ func main() {
 	p := parallel.NewProcessor(bufio.NewReader(reader), w, func(_ int64, b []byte) ([]byte, error) {
 		is := finc.IntermediateSchema{}
 
-		if err := json.Unmarshal(b, &is); err != nil {
+		if err := finc.UnmarshalIntermediateSchema(b, &is); err != nil {
 			log.Printf("failed to unmarshal: %s", string(b))
 			return b, err
 		}
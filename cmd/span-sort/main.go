@@ -0,0 +1,76 @@
+// span-sort sorts newline delimited JSON records by a field, finc.id by
+// default, so two conversions of the same input produce byte-identical
+// output, regardless of the order in which records were converted. Large
+// inputs are sorted in runs, using temporary files.
+//
+//	$ span-import -i crossref crossref.ldj | span-sort > intermediate.ldj
+//	$ span-sort -k finc.record_id -S 1024 file.ldj > sorted.ldj
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/miku/span"
+	"github.com/miku/span/linesort"
+)
+
+// fieldKey returns a key function, that extracts the value of a top level
+// field. Strings are compared unquoted, other values as serialized. Records
+// without the field sort first.
+func fieldKey(name string) linesort.KeyFunc {
+	return func(line []byte) ([]byte, error) {
+		var doc map[string]json.RawMessage
+		if err := json.Unmarshal(line, &doc); err != nil {
+			return nil, err
+		}
+		raw, ok := doc[name]
+		if !ok {
+			return nil, nil
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return []byte(s), nil
+		}
+		return raw, nil
+	}
+}
+
+func main() {
+	showVersion := flag.Bool("v", false, "prints current program version")
+	key := flag.String("k", "finc.id", "sort by this top level field, records with equal values are sorted by content")
+	maxMB := flag.Int64("S", linesort.DefaultMaxBytes>>20, "sort this many megabytes in memory, larger inputs are sorted using temporary files")
+	tempDir := flag.String("T", "", "directory for temporary files, defaults to the system temporary directory")
+
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println(span.AppVersion)
+		os.Exit(0)
+	}
+
+	var reader io.Reader = os.Stdin
+
+	if flag.NArg() > 0 {
+		var files []io.Reader
+		for _, filename := range flag.Args() {
+			f, err := os.Open(filename)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			files = append(files, f)
+		}
+		reader = io.MultiReader(files...)
+	}
+
+	s := linesort.Sorter{Key: fieldKey(*key), MaxBytes: *maxMB << 20, TempDir: *tempDir}
+	if err := s.Sort(reader, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
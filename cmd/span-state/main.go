@@ -0,0 +1,84 @@
+// span-state exports, imports and lists the state database used by
+// span-oai-harvest, span-crossref-sync and span-dedup with -state-db.
+//
+//	$ span-state -db state.db -export > state.ldj
+//	$ span-state -db other.db -import state.ldj
+//	$ span-state -db state.db -l
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/miku/span"
+	"github.com/miku/span/state"
+)
+
+func main() {
+	dbFile := flag.String("db", "", "state database")
+	export := flag.Bool("export", false, "write all state as newline delimited JSON to stdout")
+	importFile := flag.String("import", "", "import state from newline delimited JSON file, - for stdin")
+	list := flag.Bool("l", false, "list sets with their size")
+	deleteSet := flag.String("delete-set", "", "remove a set and all its ids")
+	timeout := flag.Duration("timeout", 10*time.Second, "wait at most this long for other processes to release the database")
+	showVersion := flag.Bool("v", false, "prints current program version")
+
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println(span.AppVersion)
+		os.Exit(0)
+	}
+	if *dbFile == "" {
+		log.Fatal("state database required")
+	}
+
+	store, err := state.Open(*dbFile, *timeout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	if *importFile != "" {
+		var r io.Reader = os.Stdin
+		if *importFile != "-" {
+			f, err := os.Open(*importFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			r = f
+		}
+		if err := store.Import(r); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *deleteSet != "" {
+		if err := store.DeleteSet(*deleteSet); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *list {
+		names, err := store.Sets()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, name := range names {
+			n, err := store.Len(name)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("%s\t%d\n", name, n)
+		}
+	}
+	if *export {
+		if err := store.Export(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
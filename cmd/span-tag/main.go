@@ -4,23 +4,46 @@
 //
 // $ span-tag -c '{"DE-15": {"any": {}}}' < input.ldj > output.ldj
 //
+// Holding files can be given per ISIL on the command line as well, a record
+// is then tagged, if a holding file covers its date, volume and issue:
+//
+// $ span-tag -f DE-15:kbart.tsv -f DE-14:https://example.com/kbart < input.ldj
+//
+// Holding files per ISIL, e.g. EZB exports, can be fetched into a cache
+// directory, they are downloaded again only if they changed:
+//
+// $ span-tag -ezb-url 'https://example.com/kbart?isil=%s' -ezb DE-15 < input.ldj
+//
+// Institutions running their own index can receive only their records, with
+// one file per ISIL, e.g. out/DE-15.ldj:
+//
+// $ span-tag -c filterconfig.json -split out < input.ldj
+//
 package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync/atomic"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
 	"github.com/miku/span/filter"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/licensing/kbart"
+	"github.com/miku/span/logging"
 	"github.com/miku/span/parallel"
 )
 
@@ -28,19 +51,68 @@ func main() {
 	config := flag.String("c", "", "JSON config file for filters")
 	version := flag.Bool("v", false, "show version")
 	size := flag.Int("b", 20000, "batch size")
+	batchMB := flag.Int("batch-mb", parallel.DefaultBatchBytes>>20, "limit the input size of a batch in megabytes, so huge records do not exhaust memory, 0 means no limit")
 	numWorkers := flag.Int("w", runtime.NumCPU(), "number of workers")
+	ordered := flag.Bool("preserve-order", false, "write records in input order")
 	cpuProfile := flag.String("cpuprofile", "", "write cpu profile to file")
 	unfreeze := flag.String("unfreeze", "", "unfreeze filterconfig from a frozen file")
+	whereExpr := flag.String("where", "", "process only records matching a filter expression or file, e.g. {\"source\": [\"49\"]}")
+	splitDir := flag.String("split", "", "write tagged records into one file per ISIL in this directory, instead of stdout")
+
+	var holdingsFiles span.ArrayFlags
+	flag.Var(&holdingsFiles, "f", "ISIL:file or ISIL:URL of a holding file, in addition to config (repeatable)")
+	holdingsConfig := flag.String("holdings-config", "", "JSON file mapping ISIL to holding files or URLs, loaded concurrently")
+	var ezbISIL span.ArrayFlags
+	flag.Var(&ezbISIL, "ezb", "fetch holding file for ISIL from -ezb-url (repeatable)")
+	ezbLink := flag.String("ezb-url", "", "holding file location, %s is replaced by the ISIL")
+	compiledHoldings := flag.String("compiled-holdings", "", "keep parsed holding files in this directory and reuse them while the file is unchanged, e.g. ~/.cache/span/compiled")
+	strictHoldings := flag.Bool("strict-holdings", false, "fail on holding file rows with a different number of fields than the header")
+	ezbCache := flag.String("ezb-cache", filepath.Join(os.Getenv("HOME"), ".cache", "span", "holdings"), "cache directory for fetched holding files")
+	fetchRetries := flag.Int("fetch-retries", span.DefaultMaxRetries, "retries for downloads of links, e.g. holding files, negative values disable retries")
+	fetchBackoff := flag.Duration("fetch-backoff", span.DefaultBackoff, "wait before the first retry of a download, doubled on each retry")
+	logOptions := logging.RegisterFlags(flag.CommandLine)
+	selection := parallel.RegisterSelectionFlags(flag.CommandLine)
+	progressInterval := flag.Duration("progress", 0, "log records processed, MB read, rate and estimated remaining time in this interval, e.g. 1m, 0 disables")
 
 	flag.Parse()
+	if err := assetutil.Err(); err != nil {
+		log.Fatal(err)
+	}
+	span.DefaultMaxRetries, span.DefaultBackoff = *fetchRetries, *fetchBackoff
+
+	var exitCode int
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	if err := logOptions.Setup(); err != nil {
+		log.Fatal(err)
+	}
 
 	if *version {
 		fmt.Println(span.AppVersion)
 		os.Exit(0)
 	}
 
-	if *config == "" && *unfreeze == "" {
-		log.Fatal("config file required")
+	if err := selection.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if *batchMB < 0 {
+		log.Fatalf("-batch-mb must not be negative: %d", *batchMB)
+	}
+	var where filter.Filter
+	if *whereExpr != "" {
+		f, err := filter.Parse(*whereExpr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		where = f
+	}
+
+	if *config == "" && *unfreeze == "" && len(holdingsFiles) == 0 && len(ezbISIL) == 0 && *holdingsConfig == "" {
+		log.Fatal("config file or holding files required")
 	}
 
 	if *cpuProfile != "" {
@@ -65,18 +137,78 @@ func main() {
 		*config = filterconfig
 	}
 
-	// Test, if we are given JSON directly.
-	err := json.Unmarshal([]byte(*config), &tagger)
-	if err != nil {
-		// Fallback to parse config file.
-		f, err := os.Open(*config)
+	// Holding files are read while the configuration is decoded.
+	filter.StrictHoldings = *strictHoldings
+	filter.CompiledHoldingsDir = *compiledHoldings
+
+	if *config != "" {
+		// Test, if we are given JSON directly.
+		err := json.Unmarshal([]byte(*config), &tagger)
+		if err != nil {
+			// Fallback to parse config file.
+			f, err := os.Open(*config)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			if err := json.NewDecoder(f).Decode(&tagger); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	for _, v := range holdingsFiles {
+		parts := strings.SplitN(v, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			log.Fatalf("want ISIL:file, got %s", v)
+		}
+		f, err := filter.NewHoldingsFilter(parts[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		tagger.Add(parts[0], f)
+	}
+
+	if *holdingsConfig != "" {
+		f, err := os.Open(*holdingsConfig)
 		if err != nil {
 			log.Fatal(err)
 		}
-		defer f.Close()
-		if err := json.NewDecoder(f).Decode(&tagger); err != nil {
+		hc, err := filter.ReadHoldingsConfig(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", *holdingsConfig, err)
+		}
+		filters, err := hc.Filters(*numWorkers)
+		if err != nil {
 			log.Fatal(err)
 		}
+		var isils []string
+		for isil := range filters {
+			isils = append(isils, isil)
+		}
+		sort.Strings(isils)
+		for _, isil := range isils {
+			tagger.Add(isil, filters[isil])
+		}
+	}
+
+	if len(ezbISIL) > 0 {
+		if !strings.Contains(*ezbLink, "%s") {
+			log.Fatal("-ezb-url with placeholder for ISIL required")
+		}
+		fetcher := kbart.CachedFetcher{Link: *ezbLink, Dir: *ezbCache, Strict: *strictHoldings}
+		for _, isil := range ezbISIL {
+			filename, err := fetcher.Fetch(isil)
+			if err != nil {
+				log.Fatal(err)
+			}
+			f, err := filter.NewHoldingsFilter(filename)
+			if err != nil {
+				log.Fatal(err)
+			}
+			tagger.Add(isil, f)
+		}
 	}
 
 	w := bufio.NewWriter(os.Stdout)
@@ -97,11 +229,36 @@ func main() {
 		reader = io.MultiReader(files...)
 	}
 
+	var progress *parallel.Progress
+	if *progressInterval > 0 {
+		progress = &parallel.Progress{
+			Interval: *progressInterval,
+			Size:     parallel.InputSize(flag.Args()...),
+			Report:   func(s parallel.Status) { log.Info(s) },
+		}
+		reader = progress.Reader(reader)
+	}
+
+	// With -split, records are written per ISIL, untagged records are dropped.
+	var splitter *span.LabelWriter
+	var untagged int64
+	if *splitDir != "" {
+		if err := os.MkdirAll(*splitDir, 0755); err != nil {
+			log.Fatal(err)
+		}
+		splitter = span.NewLabelFileWriter(*splitDir, ".ldj")
+	}
+
+	var n int64
 	p := parallel.NewProcessor(bufio.NewReader(reader), w, func(_ int64, b []byte) ([]byte, error) {
+		atomic.AddInt64(&n, 1)
 		var is finc.IntermediateSchema
-		if err := json.Unmarshal(b, &is); err != nil {
+		if err := finc.UnmarshalIntermediateSchema(b, &is); err != nil {
 			return b, err
 		}
+		if where != nil && !where.Apply(is) {
+			return nil, nil
+		}
 
 		tagged := tagger.Tag(is)
 
@@ -110,13 +267,48 @@ func main() {
 			return bb, err
 		}
 		bb = append(bb, '\n')
+		if splitter != nil {
+			if len(tagged.Labels) == 0 {
+				atomic.AddInt64(&untagged, 1)
+			}
+			return nil, splitter.Write(tagged.Labels, bb)
+		}
 		return bb, nil
 	})
 
 	p.NumWorkers = *numWorkers
+	p.PreserveOrder = *ordered
 	p.BatchSize = *size
+	p.BatchBytes = int64(*batchMB) << 20
+	p.Selection = *selection
+	p.Progress = progress
 
-	if err := p.Run(); err != nil {
+	// On interrupt, records already read are still tagged and written. The
+	// exit is deferred, so output is flushed and temporary files are removed.
+	ctx, cancel := span.InterruptContext(context.Background())
+	defer cancel()
+	if progress != nil {
+		progress.Start()
+	}
+	err := p.RunContext(ctx)
+	if progress != nil {
+		progress.Stop()
+	}
+	if splitter != nil {
+		if cerr := splitter.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		for _, isil := range splitter.Labels() {
+			log.Printf("%s: %d records", isil, splitter.Count(isil))
+		}
+		log.Printf("dropped %d untagged records", atomic.LoadInt64(&untagged))
+	}
+	if err == context.Canceled {
+		log.Warnf("interrupted after %d records, partial output written", atomic.LoadInt64(&n))
+		exitCode = 130
+		return
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
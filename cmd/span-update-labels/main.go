@@ -17,6 +17,7 @@ import (
 	"bufio"
 
 	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
 	"github.com/miku/span/formats/finc"
 	"github.com/miku/span/parallel"
 )
@@ -37,6 +38,9 @@ func main() {
 	numWorkers := flag.Int("w", runtime.NumCPU(), "number of workers")
 
 	flag.Parse()
+	if err := assetutil.Err(); err != nil {
+		log.Fatal(err)
+	}
 
 	if *showVersion {
 		fmt.Println(span.AppVersion)
@@ -77,7 +81,7 @@ func main() {
 
 	p := parallel.NewProcessor(bufio.NewReader(os.Stdin), w, func(_ int64, b []byte) ([]byte, error) {
 		var is finc.IntermediateSchema
-		if err := json.Unmarshal(b, &is); err != nil {
+		if err := finc.UnmarshalIntermediateSchema(b, &is); err != nil {
 			return nil, err
 		}
 		if v, ok := labelMap[is.ID]; ok {
@@ -0,0 +1,158 @@
+// Package collections rewrites package and collection names of converted
+// records, so renames on the index side do not require changes to each
+// format. Rules are read from YAML and applied in order:
+//
+//	# Rename Genios (LIT) to Genios LIT and so on.
+//	- field: mega_collections
+//	  match: 'Genios \((.*)\)'
+//	  replace: 'Genios $1'
+//	# Rename one package and drop another.
+//	- field: packages
+//	  lookup:
+//	    LIT: Literatur
+//	    OLD: ""
+//
+// A rule either has a regular expression, which must match the whole value,
+// with a replacement, or a lookup table. Field is packages, mega_collections
+// or empty for both. Values rewritten to the empty string are removed,
+// duplicates, e.g. after merging two collections, are removed as well.
+package collections
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/miku/span/formats/finc"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Fields, that can be rewritten.
+const (
+	Packages        = "packages"
+	MegaCollections = "mega_collections"
+)
+
+// Rule rewrites values of a field.
+type Rule struct {
+	Field   string            `yaml:"field"`
+	Match   string            `yaml:"match"`
+	Replace string            `yaml:"replace"`
+	Lookup  map[string]string `yaml:"lookup"`
+
+	pattern *regexp.Regexp
+}
+
+// Rules are applied in order.
+type Rules []Rule
+
+// Load reads and compiles rules.
+func Load(r io.Reader) (Rules, error) {
+	var rules Rules
+	dec := yaml.NewDecoder(r)
+	dec.SetStrict(true)
+	if err := dec.Decode(&rules); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("collection rules: %v", err)
+	}
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("collection rules: rule %d: %v", i+1, err)
+		}
+	}
+	return rules, nil
+}
+
+// LoadFile reads rules from a file.
+func LoadFile(filename string) (Rules, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// compile validates a rule and compiles its pattern.
+func (r *Rule) compile() error {
+	switch r.Field {
+	case "", Packages, MegaCollections:
+	default:
+		return fmt.Errorf("unknown field: %s", r.Field)
+	}
+	switch {
+	case r.Match != "" && len(r.Lookup) > 0:
+		return fmt.Errorf("match and lookup are exclusive")
+	case r.Match == "" && len(r.Lookup) == 0:
+		return fmt.Errorf("match or lookup required")
+	case r.Match == "" && r.Replace != "":
+		return fmt.Errorf("replace requires match")
+	case r.Match != "":
+		p, err := regexp.Compile("^(?:" + r.Match + ")$")
+		if err != nil {
+			return err
+		}
+		r.pattern = p
+	}
+	return nil
+}
+
+// rewrite returns the new value and whether the rule applied.
+func (r Rule) rewrite(s string) (string, bool) {
+	if r.pattern != nil {
+		if !r.pattern.MatchString(s) {
+			return s, false
+		}
+		return r.pattern.ReplaceAllString(s, r.Replace), true
+	}
+	v, ok := r.Lookup[s]
+	if !ok {
+		return s, false
+	}
+	return v, true
+}
+
+// applyValues rewrites a list of values and returns it with empty values and
+// duplicates removed.
+func (r Rule) applyValues(values []string) ([]string, bool) {
+	var (
+		changed bool
+		result  []string
+		seen    = make(map[string]bool)
+	)
+	for _, v := range values {
+		w, ok := r.rewrite(v)
+		if ok && w != v {
+			changed = true
+		}
+		if w == "" || seen[w] {
+			changed = changed || ok
+			continue
+		}
+		seen[w] = true
+		result = append(result, w)
+	}
+	if !changed {
+		return values, false
+	}
+	return result, true
+}
+
+// Apply rewrites packages and mega collections of a record and returns true,
+// if any value changed.
+func (rules Rules) Apply(is *finc.IntermediateSchema) bool {
+	var changed bool
+	for _, r := range rules {
+		if r.Field == "" || r.Field == Packages {
+			if v, ok := r.applyValues(is.Packages); ok {
+				is.Packages, changed = v, true
+			}
+		}
+		if r.Field == "" || r.Field == MegaCollections {
+			if v, ok := r.applyValues(is.MegaCollections); ok {
+				is.MegaCollections, changed = v, true
+			}
+		}
+	}
+	return changed
+}
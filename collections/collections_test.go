@@ -0,0 +1,76 @@
+package collections
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span/formats/finc"
+)
+
+const testRules = `
+- field: mega_collections
+  match: 'Genios \((.*)\)'
+  replace: 'Genios $1'
+- field: packages
+  lookup:
+    A: C
+    B: C
+    OLD: ""
+- match: 'Test.*'
+`
+
+func TestApply(t *testing.T) {
+	rules, err := Load(strings.NewReader(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases = []struct {
+		packages, collections         []string
+		wantPackages, wantCollections []string
+		changed                       bool
+	}{
+		{
+			[]string{"A", "B", "D"}, []string{"Genios (LIT)", "Genios"},
+			[]string{"C", "D"}, []string{"Genios LIT", "Genios"}, true,
+		},
+		{
+			[]string{"OLD"}, []string{"Genios (LIT) extra"},
+			nil, []string{"Genios (LIT) extra"}, true,
+		},
+		{
+			[]string{"Testpaket", "D"}, []string{"Test"},
+			[]string{"D"}, nil, true,
+		},
+		{
+			[]string{"D"}, []string{"Crossref"},
+			[]string{"D"}, []string{"Crossref"}, false,
+		},
+	}
+	for _, c := range cases {
+		is := finc.IntermediateSchema{Packages: c.packages, MegaCollections: c.collections}
+		changed := rules.Apply(&is)
+		if changed != c.changed {
+			t.Errorf("Apply(%v, %v): got changed %v, want %v", c.packages, c.collections, changed, c.changed)
+		}
+		if !reflect.DeepEqual(is.Packages, c.wantPackages) || !reflect.DeepEqual(is.MegaCollections, c.wantCollections) {
+			t.Errorf("Apply(%v, %v): got %v, %v, want %v, %v", c.packages, c.collections,
+				is.Packages, is.MegaCollections, c.wantPackages, c.wantCollections)
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	var cases = []string{
+		"- field: titles\n  lookup: {A: B}\n",
+		"- match: 'A'\n  lookup: {A: B}\n",
+		"- replace: 'B'\n",
+		"- match: '('\n",
+		"- match: 'A'\n  unknown: 1\n",
+	}
+	for _, s := range cases {
+		if _, err := Load(strings.NewReader(s)); err == nil {
+			t.Errorf("Load(%q): expected error", s)
+		}
+	}
+}
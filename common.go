@@ -51,6 +51,10 @@ type Skip struct {
 	Reason string
 }
 
+// SkipDeleted is the reason for skipping records, which are marked deleted
+// in the source, e.g. OAI records with status "deleted".
+const SkipDeleted = "deleted"
+
 // Error returns the reason for skipping.
 func (s Skip) Error() string {
 	return fmt.Sprintf("SKIP %s", s.Reason)
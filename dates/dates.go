@@ -0,0 +1,197 @@
+// Package dates parses publication dates in the forms found in publisher
+// data, e.g. 2004, 2004-03, 03/2004, 15.03.2004, 20040315, March 2004,
+// "Spring 2004", "Frühjahr 2004" or 2003/04, along with the granularity of
+// the value. Missing months and days are set to the first.
+package dates
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoDate is returned for values, that cannot be parsed.
+var ErrNoDate = errors.New("no date")
+
+// Granularity is the precision of a date.
+type Granularity int
+
+// Granularities, from coarse to fine.
+const (
+	Year Granularity = iota + 1
+	Season
+	Month
+	Day
+)
+
+func (g Granularity) String() string {
+	switch g {
+	case Year:
+		return "year"
+	case Season:
+		return "season"
+	case Month:
+		return "month"
+	case Day:
+		return "day"
+	}
+	return "unknown"
+}
+
+// Date is a parsed date. For ranges, like 2003-2004, the date is the start
+// and End the start of the last part.
+type Date struct {
+	time.Time
+	Granularity Granularity
+	End         time.Time
+}
+
+// IsRange returns true, if the value was a range.
+func (d Date) IsRange() bool {
+	return !d.End.IsZero()
+}
+
+var (
+	// months maps English and German month names and abbreviations.
+	months = map[string]time.Month{
+		"jan": 1, "january": 1, "januar": 1, "jänner": 1,
+		"feb": 2, "february": 2, "februar": 2,
+		"mar": 3, "march": 3, "mär": 3, "märz": 3, "maerz": 3,
+		"apr": 4, "april": 4,
+		"may": 5, "mai": 5,
+		"jun": 6, "june": 6, "juni": 6,
+		"jul": 7, "july": 7, "juli": 7,
+		"aug": 8, "august": 8,
+		"sep": 9, "sept": 9, "september": 9,
+		"oct": 10, "october": 10, "okt": 10, "oktober": 10,
+		"nov": 11, "november": 11,
+		"dec": 12, "december": 12, "dez": 12, "dezember": 12,
+	}
+	// seasons maps seasons to the month they start in.
+	seasons = map[string]time.Month{
+		"spring": 3, "frühjahr": 3, "frühling": 3, "fruehjahr": 3,
+		"summer": 6, "sommer": 6,
+		"fall": 9, "autumn": 9, "herbst": 9,
+		"winter": 12,
+	}
+
+	isoPattern      = regexp.MustCompile(`^(\d{4})-(\d{1,2})(?:-(\d{1,2}))?(?:[T ].*)?$`)
+	compactPattern  = regexp.MustCompile(`^(\d{4})(\d{2})(\d{2})$`)
+	slashPattern    = regexp.MustCompile(`^(\d{4})/(\d{1,2})(?:/(\d{1,2}))?$`)
+	germanPattern   = regexp.MustCompile(`^(\d{1,2})\.\s?(\d{1,2})\.\s?(\d{4})$`)
+	monthPattern    = regexp.MustCompile(`^(\d{1,2})[./](\d{4})$`)
+	yearPattern     = regexp.MustCompile(`^\d{4}$`)
+	rangePattern    = regexp.MustCompile(`^(\d{4})\s?(?:[-/–]\s?(\d{4})|[/–]\s?(\d{2}))$`)
+	namedPattern    = regexp.MustCompile(`^(?:(\d{1,2})\.?\s+)?(\p{L}+)\.?(?:\s+(\d{1,2}),?)?\s+(\d{4})$`)
+	namedEndPattern = regexp.MustCompile(`^(\p{L}+)\s?[-/–]\s?(\p{L}+)\.?\s+(\d{4})$`)
+)
+
+// Parse parses a date. A value like 2004-05 is read as May 2004, while
+// 2004/05, 2004–05 and 2004-2005 are ranges, since the second part follows the
+// first.
+func Parse(s string) (Date, error) {
+	s = strings.TrimSpace(strings.Trim(strings.TrimSpace(s), "[]()"))
+	if s == "" {
+		return Date{}, ErrNoDate
+	}
+	if yearPattern.MatchString(s) {
+		return date(atoi(s), 1, 1, Year)
+	}
+	if m := rangePattern.FindStringSubmatch(s); m != nil {
+		start, end := atoi(m[1]), atoi(m[2])
+		if m[3] != "" {
+			if end = atoi(m[3]); end == (start+1)%100 {
+				end = start + 1
+			}
+		}
+		if end == start+1 || (m[2] != "" && end > start) {
+			d, err := date(start, 1, 1, Year)
+			d.End = time.Date(end, 1, 1, 0, 0, 0, 0, time.UTC)
+			return d, err
+		}
+	}
+	if m := isoPattern.FindStringSubmatch(s); m != nil {
+		if m[3] == "" {
+			return date(atoi(m[1]), atoi(m[2]), 1, Month)
+		}
+		return date(atoi(m[1]), atoi(m[2]), atoi(m[3]), Day)
+	}
+	if m := compactPattern.FindStringSubmatch(s); m != nil {
+		return date(atoi(m[1]), atoi(m[2]), atoi(m[3]), Day)
+	}
+	if m := slashPattern.FindStringSubmatch(s); m != nil {
+		if m[3] == "" {
+			return date(atoi(m[1]), atoi(m[2]), 1, Month)
+		}
+		return date(atoi(m[1]), atoi(m[2]), atoi(m[3]), Day)
+	}
+	if m := germanPattern.FindStringSubmatch(s); m != nil {
+		return date(atoi(m[3]), atoi(m[2]), atoi(m[1]), Day)
+	}
+	if m := monthPattern.FindStringSubmatch(s); m != nil {
+		return date(atoi(m[2]), atoi(m[1]), 1, Month)
+	}
+	if m := namedPattern.FindStringSubmatch(s); m != nil {
+		name := strings.ToLower(m[2])
+		if month, ok := seasons[name]; ok && m[1] == "" && m[3] == "" {
+			return date(atoi(m[4]), int(month), 1, Season)
+		}
+		if month, ok := months[name]; ok {
+			switch {
+			case m[1] != "" && m[3] == "":
+				return date(atoi(m[4]), int(month), atoi(m[1]), Day)
+			case m[1] == "" && m[3] != "":
+				return date(atoi(m[4]), int(month), atoi(m[3]), Day)
+			case m[1] == "" && m[3] == "":
+				return date(atoi(m[4]), int(month), 1, Month)
+			}
+		}
+	}
+	if m := namedEndPattern.FindStringSubmatch(s); m != nil {
+		first, ok := months[strings.ToLower(m[1])]
+		last, ok2 := months[strings.ToLower(m[2])]
+		if ok && ok2 {
+			d, err := date(atoi(m[3]), int(first), 1, Month)
+			d.End = time.Date(atoi(m[3]), last, 1, 0, 0, 0, 0, time.UTC)
+			return d, err
+		}
+	}
+	return Date{}, ErrNoDate
+}
+
+// FromParts returns a date from year, month and day, as in crossref
+// date-parts. Missing or invalid months and days lower the granularity.
+func FromParts(parts ...int) (Date, error) {
+	switch {
+	case len(parts) == 0:
+		return Date{}, ErrNoDate
+	case len(parts) == 1 || parts[1] < 1 || parts[1] > 12:
+		return date(parts[0], 1, 1, Year)
+	case len(parts) == 2:
+		return date(parts[0], parts[1], 1, Month)
+	}
+	d, err := date(parts[0], parts[1], parts[2], Day)
+	if err != nil {
+		return date(parts[0], parts[1], 1, Month)
+	}
+	return d, nil
+}
+
+// date returns a date, if year, month and day are valid.
+func date(year, month, day int, g Granularity) (Date, error) {
+	if year < 1 || year > 9999 {
+		return Date{}, ErrNoDate
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Month() != time.Month(month) || t.Day() != day {
+		return Date{}, ErrNoDate
+	}
+	return Date{Time: t, Granularity: g}, nil
+}
+
+func atoi(s string) int {
+	v, _ := strconv.Atoi(s)
+	return v
+}
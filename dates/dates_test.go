@@ -0,0 +1,101 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	var cases = []struct {
+		s           string
+		date        string
+		granularity Granularity
+		end         string
+		err         error
+	}{
+		{"2004", "2004-01-01", Year, "", nil},
+		{" 2004 ", "2004-01-01", Year, "", nil},
+		{"[2004]", "2004-01-01", Year, "", nil},
+		{"2004-03", "2004-03-01", Month, "", nil},
+		{"2004-3", "2004-03-01", Month, "", nil},
+		{"2004/03", "2004-03-01", Month, "", nil},
+		{"03/2004", "2004-03-01", Month, "", nil},
+		{"3.2004", "2004-03-01", Month, "", nil},
+		{"2004-03-15", "2004-03-15", Day, "", nil},
+		{"2004-03-15T10:00:00Z", "2004-03-15", Day, "", nil},
+		{"20040315", "2004-03-15", Day, "", nil},
+		{"15.03.2004", "2004-03-15", Day, "", nil},
+		{"5. 3. 2004", "2004-03-05", Day, "", nil},
+		{"March 2004", "2004-03-01", Month, "", nil},
+		{"Mar. 2004", "2004-03-01", Month, "", nil},
+		{"März 2004", "2004-03-01", Month, "", nil},
+		{"15 March 2004", "2004-03-15", Day, "", nil},
+		{"15. März 2004", "2004-03-15", Day, "", nil},
+		{"March 15, 2004", "2004-03-15", Day, "", nil},
+		{"Spring 2004", "2004-03-01", Season, "", nil},
+		{"Frühjahr 2004", "2004-03-01", Season, "", nil},
+		{"Herbst 2004", "2004-09-01", Season, "", nil},
+		{"Winter 2004", "2004-12-01", Season, "", nil},
+		{"2003-2004", "2003-01-01", Year, "2004-01-01", nil},
+		{"2003/04", "2003-01-01", Year, "2004-01-01", nil},
+		{"1999/00", "1999-01-01", Year, "2000-01-01", nil},
+		{"2003–04", "2003-01-01", Year, "2004-01-01", nil},
+		{"2004-05", "2004-05-01", Month, "", nil},
+		{"2010-11", "2010-11-01", Month, "", nil},
+		{"2000-01", "2000-01-01", Month, "", nil},
+		{"2003–2005", "2003-01-01", Year, "2005-01-01", nil},
+		{"Jan-Feb 2004", "2004-01-01", Month, "2004-02-01", nil},
+		{"2004-13", "", 0, "", ErrNoDate},
+		{"31.02.2004", "", 0, "", ErrNoDate},
+		{"Someday 2004", "", 0, "", ErrNoDate},
+		{"", "", 0, "", ErrNoDate},
+		{"n.d.", "", 0, "", ErrNoDate},
+	}
+	for _, c := range cases {
+		d, err := Parse(c.s)
+		if err != c.err {
+			t.Errorf("Parse(%q): got %v, want %v", c.s, err, c.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if v := d.Format("2006-01-02"); v != c.date || d.Granularity != c.granularity {
+			t.Errorf("Parse(%q): got %s (%s), want %s (%s)", c.s, v, d.Granularity, c.date, c.granularity)
+		}
+		var end string
+		if d.IsRange() {
+			end = d.End.Format("2006-01-02")
+		}
+		if end != c.end {
+			t.Errorf("Parse(%q): got end %q, want %q", c.s, end, c.end)
+		}
+	}
+}
+
+func TestFromParts(t *testing.T) {
+	var cases = []struct {
+		parts       []int
+		date        time.Time
+		granularity Granularity
+		err         error
+	}{
+		{[]int{2004}, time.Date(2004, 1, 1, 0, 0, 0, 0, time.UTC), Year, nil},
+		{[]int{2004, 3}, time.Date(2004, 3, 1, 0, 0, 0, 0, time.UTC), Month, nil},
+		{[]int{2004, 3, 15}, time.Date(2004, 3, 15, 0, 0, 0, 0, time.UTC), Day, nil},
+		{[]int{2004, 2, 31}, time.Date(2004, 2, 1, 0, 0, 0, 0, time.UTC), Month, nil},
+		{[]int{2004, 0}, time.Date(2004, 1, 1, 0, 0, 0, 0, time.UTC), Year, nil},
+		{[]int{0}, time.Time{}, 0, ErrNoDate},
+		{nil, time.Time{}, 0, ErrNoDate},
+	}
+	for _, c := range cases {
+		d, err := FromParts(c.parts...)
+		if err != c.err {
+			t.Errorf("FromParts(%v): got %v, want %v", c.parts, err, c.err)
+			continue
+		}
+		if !d.Time.Equal(c.date) || d.Granularity != c.granularity {
+			t.Errorf("FromParts(%v): got %v (%s), want %v (%s)", c.parts, d.Time, d.Granularity, c.date, c.granularity)
+		}
+	}
+}
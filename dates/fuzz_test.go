@@ -0,0 +1,27 @@
+//go:build go1.18
+// +build go1.18
+
+package dates
+
+import "testing"
+
+func FuzzParse(f *testing.F) {
+	for _, s := range []string{"2003", "2003-2004", "1999/00", "2004-02-29", "20040229", "29.02.2004",
+		"02/2004", "Feb 2004", "Februar 2004", "Spring 2004", "Jan-Feb 2004", "2004-02-29T12:00:00Z"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		d, err := Parse(s)
+		if err != nil {
+			return
+		}
+		if d.IsRange() && d.End.Before(d.Time) {
+			t.Errorf("Parse(%q): range ends before it starts: %v", s, d)
+		}
+		for _, parts := range [][]int{{d.Year()}, {d.Year(), int(d.Month())}, {d.Year(), int(d.Month()), d.Day()}} {
+			if _, err := FromParts(parts...); err != nil {
+				t.Errorf("FromParts(%v) of Parse(%q): %v", parts, s, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,230 @@
+// Package dedup finds records describing the same article across sources,
+// e.g. an article from Crossref and from a publisher feed. Records are grouped
+// by normalized DOI and, optionally, by title and year. From each group, the
+// record of the source with the highest priority is kept, all others are
+// reported for deletion.
+package dedup
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/miku/span/doi"
+	"github.com/miku/span/formats/finc"
+)
+
+// MinTitleLength is the minimum length of a normalized title used for
+// matching, so titles like "Editorial" or "Book reviews" do not match.
+const MinTitleLength = 20
+
+var nonWord = regexp.MustCompile(`[^\pL\pN]+`)
+
+// Config sets source ids by priority, highest first, and whether records
+// without DOI are matched by title and year. Sources not listed have the
+// lowest priority.
+type Config struct {
+	Priority  []string `json:"priority"`
+	TitleYear bool     `json:"title_year"`
+}
+
+// ReadConfig reads a JSON configuration, e.g. {"priority": ["55", "49"]}.
+func ReadConfig(r io.Reader) (Config, error) {
+	var c Config
+	err := json.NewDecoder(r).Decode(&c)
+	return c, err
+}
+
+// Record is the part of an intermediate schema record needed for matching.
+type Record struct {
+	ID       string
+	SourceID string
+	DOI      string
+	Title    string
+	Year     int
+}
+
+// RecordOf extracts matching information from a record.
+func RecordOf(is finc.IntermediateSchema) Record {
+	title := is.ArticleTitle
+	if title == "" {
+		title = is.BookTitle
+	}
+	return Record{
+		ID:       is.ID,
+		SourceID: is.SourceID,
+		DOI:      doi.Clean(is.DOI),
+		Title:    title,
+		Year:     is.Date.Year(),
+	}
+}
+
+// titleKey returns a normalized title and year or the empty string, if the
+// title is too short or the year is unknown.
+func (r Record) titleKey() string {
+	t := strings.TrimSpace(nonWord.ReplaceAllString(strings.ToLower(r.Title), " "))
+	if len(t) < MinTitleLength || r.Year <= 1 {
+		return ""
+	}
+	return t + "|" + strconv.Itoa(r.Year)
+}
+
+// Loser is a record to delete, along with the record kept instead.
+type Loser struct {
+	ID           string `json:"id"`
+	SourceID     string `json:"sid"`
+	KeptID       string `json:"kept"`
+	KeptSourceID string `json:"kept_sid"`
+	// Reason is doi or title.
+	Reason string `json:"reason"`
+}
+
+// entry is a record at a position in the input.
+type entry struct {
+	Record
+	seq int64
+}
+
+// Deduplicator collects records and finds duplicates, safe for concurrent
+// use. Records are kept in memory, only the fields needed for matching.
+type Deduplicator struct {
+	Config
+
+	mu      sync.Mutex
+	entries []entry
+}
+
+// New creates a new deduplicator.
+func New(c Config) *Deduplicator {
+	return &Deduplicator{Config: c}
+}
+
+// Add adds a record. The sequence number orders records of the same priority,
+// earlier records win.
+func (d *Deduplicator) Add(seq int64, r Record) {
+	if r.ID == "" {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries = append(d.entries, entry{Record: r, seq: seq})
+}
+
+// Losers returns the records to delete, sorted by id. A group contains at
+// most one DOI: records are only matched by title and year, if at least one
+// side has no DOI.
+func (d *Deduplicator) Losers() []Loser {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries := d.entries
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+
+	rank := make(map[string]int)
+	for i, sid := range d.Priority {
+		if _, ok := rank[sid]; !ok {
+			rank[sid] = i
+		}
+	}
+	rankOf := func(sid string) int {
+		if r, ok := rank[sid]; ok {
+			return r
+		}
+		return len(d.Priority)
+	}
+
+	// Union find with the DOI of each group at its root.
+	parent := make([]int, len(entries))
+	groupDOI := make([]string, len(entries))
+	for i, e := range entries {
+		parent[i] = i
+		groupDOI[i] = e.DOI
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		a, b := find(i), find(j)
+		if a == b {
+			return
+		}
+		if groupDOI[a] != "" && groupDOI[b] != "" && groupDOI[a] != groupDOI[b] {
+			return
+		}
+		if groupDOI[a] == "" {
+			groupDOI[a] = groupDOI[b]
+		}
+		parent[b] = a
+	}
+
+	byDOI := make(map[string]int)
+	byTitle := make(map[string]int)
+	for i, e := range entries {
+		if e.DOI != "" {
+			if j, ok := byDOI[e.DOI]; ok {
+				union(j, i)
+			} else {
+				byDOI[e.DOI] = i
+			}
+		}
+	}
+	if d.TitleYear {
+		for i, e := range entries {
+			key := e.titleKey()
+			if key == "" {
+				continue
+			}
+			if j, ok := byTitle[key]; ok {
+				union(j, i)
+			} else {
+				byTitle[key] = i
+			}
+		}
+	}
+
+	// Find the winner of each group.
+	winner := make(map[int]int)
+	for i, e := range entries {
+		root := find(i)
+		w, ok := winner[root]
+		if !ok || rankOf(e.SourceID) < rankOf(entries[w].SourceID) {
+			winner[root] = i
+		}
+	}
+	var losers []Loser
+	for i, e := range entries {
+		w := winner[find(i)]
+		if w == i || entries[w].ID == e.ID {
+			continue
+		}
+		reason := "title"
+		if e.DOI != "" && e.DOI == entries[w].DOI {
+			reason = "doi"
+		}
+		losers = append(losers, Loser{
+			ID:           e.ID,
+			SourceID:     e.SourceID,
+			KeptID:       entries[w].ID,
+			KeptSourceID: entries[w].SourceID,
+			Reason:       reason,
+		})
+	}
+	sort.SliceStable(losers, func(i, j int) bool { return losers[i].ID < losers[j].ID })
+	// The same record may appear more than once in the input.
+	var result []Loser
+	for i, l := range losers {
+		if i > 0 && l.ID == losers[i-1].ID {
+			continue
+		}
+		result = append(result, l)
+	}
+	return result
+}
@@ -0,0 +1,67 @@
+package dedup
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miku/span/formats/finc"
+)
+
+func TestRecordOf(t *testing.T) {
+	is := finc.IntermediateSchema{ID: "ai-49-1", SourceID: "49", DOI: "https://doi.org/10.1000/ABC",
+		BookTitle: "A Book", Date: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)}
+	want := Record{ID: "ai-49-1", SourceID: "49", DOI: "10.1000/abc", Title: "A Book", Year: 2019}
+	if r := RecordOf(is); r != want {
+		t.Errorf("got %+v, want %+v", r, want)
+	}
+}
+
+func TestLosers(t *testing.T) {
+	title := "On the Origin of Species by Means of Natural Selection"
+	records := []Record{
+		{ID: "ai-49-1", SourceID: "49", DOI: "10.1000/a", Title: title, Year: 2019},
+		{ID: "ai-55-1", SourceID: "55", DOI: "10.1000/a", Title: title, Year: 2019},
+		{ID: "ai-85-1", SourceID: "85", DOI: "10.1000/a"},
+		{ID: "ai-48-1", SourceID: "48", Title: strings.ToUpper(title) + ".", Year: 2019},
+		{ID: "ai-48-2", SourceID: "48", Title: title, Year: 2018},
+		{ID: "ai-49-2", SourceID: "49", DOI: "10.1000/b", Title: "Editorial", Year: 2019},
+		{ID: "ai-55-2", SourceID: "55", DOI: "10.1000/c", Title: "Editorial", Year: 2019},
+		{ID: "ai-49-3", SourceID: "49", DOI: "10.1000/d", Title: title + " II", Year: 2019},
+		{ID: "ai-55-3", SourceID: "55", DOI: "10.1000/e", Title: title + " II", Year: 2019},
+		{ID: "ai-49-1", SourceID: "49", DOI: "10.1000/a"},
+	}
+	var cases = []struct {
+		about  string
+		config Config
+		losers []Loser
+	}{
+		{
+			"doi, crossref last",
+			Config{Priority: []string{"55", "85", "49"}},
+			[]Loser{
+				{ID: "ai-49-1", SourceID: "49", KeptID: "ai-55-1", KeptSourceID: "55", Reason: "doi"},
+				{ID: "ai-85-1", SourceID: "85", KeptID: "ai-55-1", KeptSourceID: "55", Reason: "doi"},
+			},
+		},
+		{
+			"doi and title, unlisted sources last",
+			Config{Priority: []string{"49"}, TitleYear: true},
+			[]Loser{
+				{ID: "ai-48-1", SourceID: "48", KeptID: "ai-49-1", KeptSourceID: "49", Reason: "title"},
+				{ID: "ai-55-1", SourceID: "55", KeptID: "ai-49-1", KeptSourceID: "49", Reason: "doi"},
+				{ID: "ai-85-1", SourceID: "85", KeptID: "ai-49-1", KeptSourceID: "49", Reason: "doi"},
+			},
+		},
+	}
+	for _, c := range cases {
+		d := New(c.config)
+		for i, r := range records {
+			d.Add(int64(i), r)
+		}
+		if losers := d.Losers(); !reflect.DeepEqual(losers, c.losers) {
+			t.Errorf("%s: got %+v, want %+v", c.about, losers, c.losers)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package doi normalizes and validates digital object identifiers, refs.
+// https://www.doi.org/doi_handbook/2_Numbering.html.
+package doi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MaxLength is the maximum length of a DOI we accept, longer strings are
+// usually extraction errors.
+const MaxLength = 300
+
+var (
+	// ErrInvalid is returned for strings, that are not a DOI.
+	ErrInvalid = errors.New("invalid doi")
+
+	// pattern is prefix (10. followed by a registrant code, which may have
+	// subdivisions) and a non-empty suffix.
+	pattern = regexp.MustCompile(`^10\.[0-9]{4,9}(\.[0-9]+)*/[^\s]+$`)
+	// findPattern finds DOI in longer strings.
+	findPattern = regexp.MustCompile(`10\.[0-9]{4,9}(\.[0-9]+)*/[^\s"<>]+`)
+
+	// prefixes found before DOI in the wild, lowercase.
+	prefixes = []string{
+		"https://doi.org/",
+		"http://doi.org/",
+		"https://dx.doi.org/",
+		"http://dx.doi.org/",
+		"https://www.doi.org/",
+		"http://www.doi.org/",
+		"doi.org/",
+		"dx.doi.org/",
+		"info:doi/",
+		"urn:doi:",
+		"doi:",
+		"doi ",
+	}
+)
+
+// Normalize strips resolver prefixes, whitespace and trailing punctuation and
+// returns a lowercase DOI. DOI are case insensitive.
+func Normalize(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			s = strings.TrimSpace(s[len(prefix):])
+			break
+		}
+	}
+	if strings.Contains(s, "%2f") {
+		if u, err := url.PathUnescape(s); err == nil {
+			s = u
+		}
+	}
+	s = strings.TrimRight(s, ".,;")
+	if len(s) > MaxLength || !pattern.MatchString(s) {
+		return "", ErrInvalid
+	}
+	return s, nil
+}
+
+// Clean returns the normalized DOI or the empty string, if s is not a DOI.
+func Clean(s string) string {
+	v, err := Normalize(s)
+	if err != nil {
+		return ""
+	}
+	return v
+}
+
+// Find returns the first normalized DOI found in a string, e.g. in a link.
+func Find(s string) string {
+	return Clean(findPattern.FindString(s))
+}
+
+// FromIdentifiers returns the first DOI found in a list of identifiers of any
+// form, e.g. "doi:10.1000/182", "https://doi.org/10.1000/182", a bare DOI or
+// a publisher link containing one, as found in dc:identifier fields.
+func FromIdentifiers(ids ...string) string {
+	for _, id := range ids {
+		if v := Clean(id); v != "" {
+			return v
+		}
+	}
+	for _, id := range ids {
+		if v := Find(id); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Resolver checks, whether DOI are registered, using the handle API of the
+// DOI proxy.
+type Resolver struct {
+	Client *http.Client
+	// Server defaults to https://doi.org.
+	Server string
+}
+
+// Resolves returns true, if a DOI is registered.
+func (r *Resolver) Resolves(s string) (bool, error) {
+	v, err := Normalize(s)
+	if err != nil {
+		return false, err
+	}
+	server := r.Server
+	if server == "" {
+		server = "https://doi.org"
+	}
+	client := r.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return false, err
+	}
+	// Setting the path escapes characters like "#", "?" or "<", found in SICI
+	// style DOI.
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/handles/" + v
+	link := u.String()
+	resp, err := client.Get(link)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return false, fmt.Errorf("%s: %s", link, resp.Status)
+	}
+	// Response codes: 1 found, 100 handle not found, 200 no values found.
+	var payload struct {
+		ResponseCode int `json:"responseCode"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return false, fmt.Errorf("%s: %v", link, err)
+	}
+	return payload.ResponseCode == 1, nil
+}
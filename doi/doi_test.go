@@ -0,0 +1,108 @@
+package doi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	var cases = []struct {
+		s      string
+		result string
+		err    error
+	}{
+		{"", "", ErrInvalid},
+		{"10.1000/182", "10.1000/182", nil},
+		{" 10.1000/ABC.def ", "10.1000/abc.def", nil},
+		{"https://doi.org/10.1002/0470841559.ch1", "10.1002/0470841559.ch1", nil},
+		{"http://dx.doi.org/10.1007/978-3-658-10838-0", "10.1007/978-3-658-10838-0", nil},
+		{"doi:10.1038/nphys1170", "10.1038/nphys1170", nil},
+		{"DOI: 10.1038/nphys1170.", "10.1038/nphys1170", nil},
+		{"10.1000.10/123456", "10.1000.10/123456", nil},
+		{"10.1000%2F182", "10.1000/182", nil},
+		{"10.1000/", "", ErrInvalid},
+		{"10.12/abc", "", ErrInvalid},
+		{"11.1000/abc", "", ErrInvalid},
+		{"10.1000/a b", "", ErrInvalid},
+		{"see 10.1000/182", "", ErrInvalid},
+		{"http://example.com/10.1000/182", "", ErrInvalid},
+	}
+	for _, c := range cases {
+		result, err := Normalize(c.s)
+		if err != c.err || result != c.result {
+			t.Errorf("Normalize(%q) got %q, %v, want %q, %v", c.s, result, err, c.result, c.err)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	var cases = []struct {
+		s      string
+		result string
+	}{
+		{"", ""},
+		{"no doi", ""},
+		{"http://link.springer.com/10.1007/978-3-658-15644-2", "10.1007/978-3-658-15644-2"},
+		{`<a href="https://doi.org/10.1000/ABC">`, "10.1000/abc"},
+	}
+	for _, c := range cases {
+		if result := Find(c.s); result != c.result {
+			t.Errorf("Find(%q) got %q, want %q", c.s, result, c.result)
+		}
+	}
+}
+
+func TestFromIdentifiers(t *testing.T) {
+	var cases = []struct {
+		ids    []string
+		result string
+	}{
+		{nil, ""},
+		{[]string{"urn:ISBN:978-3-643-50677-6", "no doi"}, ""},
+		{[]string{"urn:ISBN:978-3-643-50677-6", "doi:10.1000/182"}, "10.1000/182"},
+		{[]string{"https://www.doi.org/10.1000/182"}, "10.1000/182"},
+		{[]string{"info:doi/10.1000/182"}, "10.1000/182"},
+		{[]string{"http://link.springer.com/10.1007/978-3-658-15644-2", "10.1000/182"}, "10.1000/182"},
+		{[]string{"DOI 10.1000/182 (print)"}, "10.1000/182"},
+	}
+	for _, c := range cases {
+		if result := FromIdentifiers(c.ids...); result != c.result {
+			t.Errorf("FromIdentifiers(%q) got %q, want %q", c.ids, result, c.result)
+		}
+	}
+}
+
+func TestResolves(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/handles/10.1000/182",
+			"/api/handles/10.1002/(sici)1097-4571(199806)49:8<693::aid-asi4>3.0.co;2-0",
+			"/api/handles/10.1000/a#b?c":
+			w.Write([]byte(`{"responseCode": 1}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"responseCode": 100}`))
+		}
+	}))
+	defer ts.Close()
+
+	r := Resolver{Server: ts.URL}
+	var cases = []struct {
+		s      string
+		result bool
+		err    error
+	}{
+		{"https://doi.org/10.1000/182", true, nil},
+		{"10.1000/404", false, nil},
+		{"10.1002/(SICI)1097-4571(199806)49:8<693::AID-ASI4>3.0.CO;2-0", true, nil},
+		{"10.1000/a#b?c", true, nil},
+		{"garbage", false, ErrInvalid},
+	}
+	for _, c := range cases {
+		result, err := r.Resolves(c.s)
+		if result != c.result || err != c.err {
+			t.Errorf("Resolves(%q) got %v, %v, want %v, %v", c.s, result, err, c.result, c.err)
+		}
+	}
+}
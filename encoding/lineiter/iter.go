@@ -0,0 +1,115 @@
+// Package lineiter reads lines from very large inputs, like JSON lines with
+// crossref works of several megabytes each, without allocating per line.
+// Unlike bufio.Scanner, lines are not limited to 64KB by default.
+package lineiter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+)
+
+// DefaultMaxLineSize limits the length of a line, so a missing newline in a
+// large file does not exhaust memory.
+const DefaultMaxLineSize = 256 << 20
+
+// ErrTooLong is returned for lines exceeding the maximum line size.
+var ErrTooLong = errors.New("lineiter: line too long")
+
+// Iterator reads lines. The bytes returned by Bytes are only valid until the
+// next call to Next, they are a view into a buffer, that is reused.
+type Iterator struct {
+	// MaxLineSize is the maximum length of a line in bytes, including the
+	// newline; zero means DefaultMaxLineSize.
+	MaxLineSize int
+
+	r    *bufio.Reader
+	buf  []byte // Reused for lines longer than the read buffer.
+	line []byte
+	n    int64
+	err  error
+}
+
+// New returns an iterator over the lines of r.
+func New(r io.Reader) *Iterator {
+	return &Iterator{r: bufio.NewReaderSize(r, 1<<16)}
+}
+
+// Next advances to the next line, which is then available through Bytes. It
+// returns false at the end of the input or on error, see Err. A last line
+// without newline is returned as well.
+func (it *Iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	max := it.MaxLineSize
+	if max <= 0 {
+		max = DefaultMaxLineSize
+	}
+	b, err := it.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		it.buf = append(it.buf[:0], b...)
+		for err == bufio.ErrBufferFull {
+			if len(it.buf) > max {
+				it.err = ErrTooLong
+				return false
+			}
+			b, err = it.r.ReadSlice('\n')
+			it.buf = append(it.buf, b...)
+		}
+		b = it.buf
+	}
+	if len(b) > max {
+		it.err = ErrTooLong
+		return false
+	}
+	if err != nil && err != io.EOF {
+		it.err = err
+		return false
+	}
+	if len(b) == 0 {
+		it.err = io.EOF
+		return false
+	}
+	if err == io.EOF {
+		it.err = io.EOF
+	}
+	it.n++
+	it.line = b
+	return true
+}
+
+// Bytes returns the current line, including the newline, if any.
+func (it *Iterator) Bytes() []byte {
+	return it.line
+}
+
+// Line returns the number of the current line, starting at 1.
+func (it *Iterator) Line() int64 {
+	return it.n
+}
+
+// Err returns the first error, that is not io.EOF.
+func (it *Iterator) Err() error {
+	if it.err == io.EOF {
+		return nil
+	}
+	return it.err
+}
+
+// Iterate calls f for each line, with the line number. The bytes passed to f
+// must not be retained. It stops at the first error returned by the iterator
+// or f, or when the context is done.
+func Iterate(ctx context.Context, r io.Reader, f func(lineno int64, b []byte) error) error {
+	it := New(r)
+	for it.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := f(it.Line(), it.Bytes()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
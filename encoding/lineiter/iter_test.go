@@ -0,0 +1,70 @@
+package lineiter
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestIterator(t *testing.T) {
+	long := strings.Repeat("x", 200000)
+	var cases = []struct {
+		about string
+		input string
+		max   int
+		lines []string
+		err   error
+	}{
+		{"empty", "", 0, nil, nil},
+		{"lines", "a\nb\n", 0, []string{"a\n", "b\n"}, nil},
+		{"no trailing newline", "a\nb", 0, []string{"a\n", "b"}, nil},
+		{"empty lines", "\n\na\n", 0, []string{"\n", "\n", "a\n"}, nil},
+		{"long line", "a\n" + long + "\nb\n", 0, []string{"a\n", long + "\n", "b\n"}, nil},
+		{"too long", "a\n" + long + "\nb\n", 1000, []string{"a\n"}, ErrTooLong},
+		{"too long, short buffer", "abc\n", 2, nil, ErrTooLong},
+	}
+	for _, c := range cases {
+		it := New(strings.NewReader(c.input))
+		it.MaxLineSize = c.max
+		var lines []string
+		for it.Next() {
+			lines = append(lines, string(it.Bytes()))
+			if it.Line() != int64(len(lines)) {
+				t.Errorf("%s: got line number %d, want %d", c.about, it.Line(), len(lines))
+			}
+		}
+		if it.Err() != c.err {
+			t.Errorf("%s: got %v, want %v", c.about, it.Err(), c.err)
+		}
+		if strings.Join(lines, "|") != strings.Join(c.lines, "|") || len(lines) != len(c.lines) {
+			t.Errorf("%s: got %d lines, want %d", c.about, len(lines), len(c.lines))
+		}
+	}
+}
+
+func TestIterate(t *testing.T) {
+	var buf bytes.Buffer
+	err := Iterate(context.Background(), strings.NewReader("a\nb\nc"), func(lineno int64, b []byte) error {
+		buf.Write(bytes.TrimSpace(b))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "abc" {
+		t.Errorf("got %q, want %q", buf.String(), "abc")
+	}
+}
+
+func BenchmarkIterator(b *testing.B) {
+	line := `{"DOI": "10.1000/182", "title": ["` + strings.Repeat("x", 1000) + `"]}` + "\n"
+	input := strings.Repeat(line, 1000)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		it := New(strings.NewReader(input))
+		for it.Next() {
+		}
+	}
+}
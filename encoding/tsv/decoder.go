@@ -6,32 +6,78 @@ package tsv
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"io"
 	"reflect"
-	"sync"
-
 	"strings"
+	"sync"
 
 	"github.com/fatih/structs"
-	"github.com/miku/span"
 )
 
+// ErrFieldCount is returned in strict mode for rows with a different number of
+// fields than the header.
+var ErrFieldCount = errors.New("wrong number of fields")
+
+// ParseError reports the location of a row, that could not be decoded.
+type ParseError struct {
+	Line   int   // Line number, starting at 1.
+	Offset int64 // Byte offset of the start of the line.
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d (offset %d): %v", e.Line, e.Offset, e.Err)
+}
+
 // A Decoder reads and decodes TSV rows from an input stream.
 type Decoder struct {
-	Header    []string         // Column names.
-	Separator string           // Field separator.
-	r         *span.SkipReader // The underlying reader.
-	once      sync.Once
+	Header    []string // Column names.
+	Separator string   // Field separator.
+	// Strict rejects rows with a different number of fields than the header,
+	// e.g. from truncated or garbled exports. Otherwise missing fields are
+	// left empty and extra fields are ignored.
+	Strict bool
+	r      *bufio.Reader // The underlying reader.
+	once   sync.Once
+	line   int   // Lines read so far.
+	offset int64 // Bytes read so far.
+	width  int   // Number of fields in the header.
 }
 
 // NewDecoder returns a new decoder with tab as field separator.
 func NewDecoder(r io.Reader) *Decoder {
-	return &Decoder{r: span.NewSkipReader(bufio.NewReader(r)), Separator: "\t"}
+	return &Decoder{r: bufio.NewReader(r), Separator: "\t"}
 }
 
 // NewDecoderSeparator creates a new decoder with a given separator.
 func NewDecoderSeparator(r io.Reader, sep string) *Decoder {
-	return &Decoder{r: span.NewSkipReader(bufio.NewReader(r)), Separator: sep}
+	return &Decoder{r: bufio.NewReader(r), Separator: sep}
+}
+
+// readLine returns the next non-empty line, with surrounding whitespace
+// removed, the line as read without the line ending, its line number and
+// byte offset. A last line without a newline is returned, too.
+func (dec *Decoder) readLine() (line, raw string, lineno int, offset int64, err error) {
+	for {
+		s, err := dec.r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", "", 0, 0, err
+		}
+		if err == io.EOF && s == "" {
+			return "", "", 0, 0, io.EOF
+		}
+		dec.line++
+		offset = dec.offset
+		dec.offset += int64(len(s))
+		if line = strings.TrimSpace(s); line != "" {
+			return line, strings.TrimRight(s, "\r\n"), dec.line, offset, nil
+		}
+		if err == io.EOF {
+			return "", "", 0, 0, io.EOF
+		}
+	}
 }
 
 // readHeader attempts to read the first row and store the column names. If the
@@ -39,18 +85,26 @@ func NewDecoderSeparator(r io.Reader, sep string) *Decoder {
 func (dec *Decoder) readHeader() (err error) {
 	dec.once.Do(func() {
 		if len(dec.Header) > 0 {
+			dec.width = len(dec.Header)
 			return
 		}
-		var line string
-		if line, err = dec.r.ReadString('\n'); err != nil {
+		var line, raw string
+		if line, raw, _, _, err = dec.readLine(); err != nil {
 			return
 		}
 		dec.Header = strings.Split(line, dec.Separator)
+		dec.width = len(strings.Split(raw, dec.Separator))
 	})
 	return
 }
 
-// Decode a single entry, reuse csv struct tags.
+// Line returns the number of lines read so far.
+func (dec *Decoder) Line() int {
+	return dec.line
+}
+
+// Decode a single entry, reuse csv struct tags. Errors concerning a single
+// row are returned as *ParseError.
 func (dec *Decoder) Decode(v interface{}) error {
 	if err := dec.readHeader(); err != nil {
 		return err
@@ -58,9 +112,15 @@ func (dec *Decoder) Decode(v interface{}) error {
 	if reflect.TypeOf(v).Elem().Kind() != reflect.Struct {
 		return nil
 	}
-	line, err := dec.r.ReadString('\n')
-	if err == io.EOF {
-		return io.EOF
+	line, raw, lineno, offset, err := dec.readLine()
+	if err != nil {
+		return err
+	}
+	if dec.Strict {
+		if n := len(strings.Split(raw, dec.Separator)); n != dec.width {
+			return &ParseError{Line: lineno, Offset: offset,
+				Err: fmt.Errorf("%v: got %d, want %d", ErrFieldCount, n, dec.width)}
+		}
 	}
 	record := strings.Split(line, dec.Separator)
 
@@ -78,7 +138,7 @@ func (dec *Decoder) Decode(v interface{}) error {
 				break // Record has too few columns.
 			}
 			if err := f.Set(record[i]); err != nil {
-				return err
+				return &ParseError{Line: lineno, Offset: offset, Err: err}
 			}
 		}
 	}
@@ -1,6 +1,7 @@
 package tsv
 
 import (
+	"io"
 	"reflect"
 	"strings"
 	"testing"
@@ -173,3 +174,49 @@ func BenchmarkDecodeKbart(b *testing.B) {
 // BenchmarkDecodeKbart-4   	   50000	     33831 ns/op
 // PASS
 // ok  	github.com/miku/span/encoding/tsv	1.990s
+
+func TestDecodeStrict(t *testing.T) {
+	var cases = []struct {
+		about  string
+		s      string
+		strict bool
+		n      int
+		line   int
+	}{
+		{"ok", "publication_title\tprint_identifier\nA\t1\nB\t2\n", true, 2, 0},
+		{"no newline", "publication_title\tprint_identifier\nA\t1\nB\t2", true, 2, 0},
+		{"short", "publication_title\tprint_identifier\nA\t1\n\nB\n", true, 1, 4},
+		{"long", "publication_title\tprint_identifier\nA\t1\t2\n", true, 0, 2},
+		{"lenient", "publication_title\tprint_identifier\nA\t1\nB\n", false, 2, 0},
+	}
+	for _, c := range cases {
+		dec := NewDecoder(strings.NewReader(c.s))
+		dec.Strict = c.strict
+		var n int
+		var err error
+		for {
+			var v TestSimple
+			if err = dec.Decode(&v); err != nil {
+				break
+			}
+			n++
+		}
+		if n != c.n {
+			t.Errorf("%s: got %d rows, want %d", c.about, n, c.n)
+		}
+		if c.line == 0 {
+			if err != io.EOF {
+				t.Errorf("%s: got %v, want EOF", c.about, err)
+			}
+			continue
+		}
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%s: got %v, want parse error", c.about, err)
+			continue
+		}
+		if perr.Line != c.line {
+			t.Errorf("%s: got line %d, want %d", c.about, perr.Line, c.line)
+		}
+	}
+}
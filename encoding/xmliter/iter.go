@@ -0,0 +1,126 @@
+// Package xmliter streams elements of a given name from XML, at any depth. The
+// input may be a wrapper element with many records, a number of concatenated
+// documents or a file, whose closing wrapper element is missing. Common
+// malformations, like unknown entities, are tolerated.
+package xmliter
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// tagReader notes, whether markup was started since the last reset, so a
+// truncated element can be told from a missing closing wrapper element.
+type tagReader struct {
+	*bufio.Reader
+	tag bool
+}
+
+// ReadByte is used by the XML decoder for all input.
+func (r *tagReader) ReadByte() (byte, error) {
+	b, err := r.Reader.ReadByte()
+	if err == nil && b == '<' {
+		r.tag = true
+	}
+	return b, err
+}
+
+// A Decoder reads elements of a given name from an input stream.
+type Decoder struct {
+	Name string // Local name of the elements to decode.
+	r    *tagReader
+	dec  *xml.Decoder
+}
+
+// NewDecoder returns a decoder for elements with the given local name.
+func NewDecoder(r io.Reader, name string) *Decoder {
+	tr := &tagReader{Reader: bufio.NewReader(r)}
+	dec := xml.NewDecoder(tr)
+	dec.Strict = false // Errors of the invalid character entity kind are common.
+	dec.Entity = xml.HTMLEntity
+	return &Decoder{Name: name, r: tr, dec: dec}
+}
+
+// Decode decodes the next element into v and returns io.EOF, if there are no
+// more elements. Input ending between elements is tolerated, even if closing
+// wrapper elements are missing. Input ending within an element or tag results
+// in a syntax error, so truncated files do not lose records silently.
+func (d *Decoder) Decode(v interface{}) error {
+	for {
+		d.r.tag = false
+		t, err := d.dec.Token()
+		if serr, ok := err.(*xml.SyntaxError); ok && serr.Msg == "unexpected EOF" && !d.r.tag {
+			return io.EOF
+		}
+		if err != nil {
+			return err
+		}
+		se, ok := t.(xml.StartElement)
+		if !ok || se.Name.Local != d.Name {
+			continue
+		}
+		return d.dec.DecodeElement(v, &se)
+	}
+}
+
+// InputOffset returns the number of bytes of input consumed so far.
+func (d *Decoder) InputOffset() int64 {
+	return d.dec.InputOffset()
+}
+
+// ElementName returns the local element name of a struct or pointer to a
+// struct, as given by the tag of its XMLName field, or of an embedded
+// xml.Name, or the name of the type, as used by encoding/xml. Other values
+// have no name.
+func ElementName(v interface{}) string {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return ""
+	}
+	f, ok := t.FieldByName("XMLName")
+	if !ok {
+		// Some formats embed xml.Name, e.g. ieee.Publication.
+		if f, ok = t.FieldByName("Name"); !ok || !f.Anonymous || f.Type != reflect.TypeOf(xml.Name{}) {
+			return t.Name()
+		}
+	}
+	name := strings.Split(f.Tag.Get("xml"), ",")[0]
+	// A name may be qualified with a namespace, e.g. "ns name".
+	if i := strings.LastIndex(name, " "); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" {
+		return t.Name()
+	}
+	return name
+}
+
+// Iterate decodes all elements with the given name into values created by
+// newValue and calls f for each. It stops at the first error returned by the
+// decoder or f, or when the context is done.
+func Iterate(ctx context.Context, r io.Reader, name string, newValue func() interface{}, f func(v interface{}) error) error {
+	dec := NewDecoder(r, name)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		v := newValue()
+		err := dec.Decode(v)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := f(v); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package xmliter
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type record struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title"`
+}
+
+// errSyntax stands for any syntax error.
+var errSyntax = errors.New("syntax error")
+
+func TestIterate(t *testing.T) {
+	errStop := errors.New("stop")
+	var cases = []struct {
+		about  string
+		input  string
+		titles string
+		stopAt string
+		err    error
+	}{
+		{"empty", "", "", "", nil},
+		{"nested", `<a><b><r id="1"><title>x</title></r></b><r id="2"/></a>`, "1:x,2:", "", nil},
+		{"entity", `<r id="1"><title>a&nbsp;b &amp; c</title></r>`, "1:a\u00a0b & c", "", nil},
+		{"unclosed wrapper", `<a><r id="1"/><r id="2"/>` + "\n", "1:,2:", "", nil},
+		{"truncated element", `<a><r id="1"/><r id="2"><title>x`, "1:", "", errSyntax},
+		{"truncated tag", `<a><r id="1"/><r i`, "1:", "", errSyntax},
+		{"truncated wrapper end", `<a><r id="1"/></`, "1:", "", errSyntax},
+		{"concatenated", `<?xml version="1.0"?><r id="1"/>` + "\n" + `<?xml version="1.0"?><r id="2"/>`, "1:,2:", "", nil},
+		{"callback error", `<a><r id="1"/><r id="2"/><r id="3"/></a>`, "1:,2:", "2", errStop},
+	}
+	for _, c := range cases {
+		var titles []string
+		err := Iterate(context.Background(), strings.NewReader(c.input), "r",
+			func() interface{} { return new(record) },
+			func(v interface{}) error {
+				r := v.(*record)
+				titles = append(titles, r.ID+":"+r.Title)
+				if r.ID == c.stopAt {
+					return errStop
+				}
+				return nil
+			})
+		if _, ok := err.(*xml.SyntaxError); ok && c.err == errSyntax {
+			err = errSyntax
+		}
+		if err != c.err {
+			t.Errorf("%s: got %v, want %v", c.about, err, c.err)
+		}
+		if got := strings.Join(titles, ","); got != c.titles {
+			t.Errorf("%s: got %q, want %q", c.about, got, c.titles)
+		}
+	}
+}
+
+func TestIterateCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var n int
+	err := Iterate(ctx, strings.NewReader(`<r/><r/><r/>`), "r",
+		func() interface{} { return new(record) },
+		func(v interface{}) error {
+			n++
+			cancel()
+			return nil
+		})
+	if err != context.Canceled || n != 1 {
+		t.Errorf("Iterate: got %v after %d elements, want context.Canceled after 1", err, n)
+	}
+}
+
+func TestElementName(t *testing.T) {
+	type plain struct{}
+	type named struct {
+		XMLName xml.Name `xml:"Record"`
+	}
+	type qualified struct {
+		XMLName xml.Name `xml:"http://www.loc.gov/METS/ mets"`
+	}
+	type embedded struct {
+		xml.Name `xml:"publication"`
+	}
+	var cases = []struct {
+		v    interface{}
+		want string
+	}{
+		{nil, ""},
+		{1, ""},
+		{plain{}, "plain"},
+		{named{}, "Record"},
+		{new(named), "Record"},
+		{new(qualified), "mets"},
+		{new(embedded), "publication"},
+	}
+	for _, c := range cases {
+		if got := ElementName(c.v); got != c.want {
+			t.Errorf("ElementName(%T): got %q, want %q", c.v, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package enrich
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miku/span"
+	"github.com/miku/span/cache"
+	"github.com/miku/span/doi"
+	"github.com/miku/span/formats/crossref"
+	"github.com/miku/span/formats/finc"
+)
+
+// DefaultWorksEndpoint is the crossref works API.
+const DefaultWorksEndpoint = "https://api.crossref.org/works"
+
+// ErrWorkNotFound is returned for DOI unknown to crossref.
+var ErrWorkNotFound = errors.New("enrich: work not found")
+
+// Work has the fields of a crossref work used to fill in sparse records. It
+// decodes from a works API message or a line of a crossref snapshot.
+type Work struct {
+	DOI       string   `json:"DOI"`
+	Page      string   `json:"page,omitempty"`
+	Volume    string   `json:"volume,omitempty"`
+	Issue     string   `json:"issue,omitempty"`
+	Publisher string   `json:"publisher,omitempty"`
+	ISSN      []string `json:"ISSN,omitempty"`
+	IssnType  []struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"issn-type,omitempty"`
+}
+
+// serials returns print and electronic ISSN. Without type information, all
+// ISSN count as print, like in the crossref conversion.
+func (w *Work) serials() (issn, eissn []string) {
+	if len(w.IssnType) == 0 {
+		return w.ISSN, nil
+	}
+	for _, t := range w.IssnType {
+		if t.Type == "electronic" {
+			eissn = append(eissn, t.Value)
+		} else {
+			issn = append(issn, t.Value)
+		}
+	}
+	return issn, eissn
+}
+
+// WorkLookup finds a work by normalized DOI and returns ErrWorkNotFound, if
+// there is none.
+type WorkLookup interface {
+	Work(doi string) (*Work, error)
+}
+
+// Works is an in-memory lookup, e.g. loaded from a snapshot.
+type Works map[string]*Work
+
+// Work implements WorkLookup.
+func (w Works) Work(doi string) (*Work, error) {
+	if v, ok := w[doi]; ok {
+		return v, nil
+	}
+	return nil, ErrWorkNotFound
+}
+
+// LoadWorks reads newline delimited crossref works, e.g. the output of
+// span-crossref-snapshot, and keeps those, whose normalized DOI is accepted by
+// keep; a nil keep accepts all. Later lines win.
+func LoadWorks(r io.Reader, keep func(doi string) bool) (Works, error) {
+	works := make(Works)
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(strings.TrimSpace(string(b))) > 0 {
+			w := new(Work)
+			if err := json.Unmarshal(b, w); err != nil {
+				return nil, err
+			}
+			if v := doi.Clean(w.DOI); v != "" && (keep == nil || keep(v)) {
+				works[v] = w
+			}
+		}
+		if err == io.EOF {
+			return works, nil
+		}
+	}
+}
+
+// cachedWork is a cache entry, a work or a DOI unknown to crossref.
+type cachedWork struct {
+	Work    *Work     `json:"work,omitempty"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// WorksAPI looks up works via the crossref API. Responses, including unknown
+// DOI, are kept in Cache for TTL, if set; zero TTL means no expiration.
+// Requests are at least Interval apart. Safe for concurrent use.
+type WorksAPI struct {
+	Endpoint   string
+	Mailto     string
+	Cache      cache.Cache
+	TTL        time.Duration
+	Interval   time.Duration
+	MaxRetries int
+	Client     *http.Client
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// wait blocks until the next request may be sent.
+func (a *WorksAPI) wait() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if d := a.Interval - time.Since(a.last); d > 0 {
+		time.Sleep(d)
+	}
+	a.last = time.Now()
+}
+
+// fetch requests a single work, retrying on rate limits and server errors.
+func (a *WorksAPI) fetch(v string) (*Work, error) {
+	endpoint, client := a.Endpoint, a.Client
+	if endpoint == "" {
+		endpoint = DefaultWorksEndpoint
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	link := fmt.Sprintf("%s/%s", strings.TrimRight(endpoint, "/"), url.PathEscape(v))
+	if a.Mailto != "" {
+		link = fmt.Sprintf("%s?mailto=%s", link, url.QueryEscape(a.Mailto))
+	}
+	backoff := time.Second
+	for i := 0; ; i++ {
+		a.wait()
+		req, err := http.NewRequest("GET", link, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", fmt.Sprintf("span/%s (https://github.com/miku/span)", span.AppVersion))
+		resp, err := client.Do(req)
+		if err == nil {
+			work, retry, err := decodeWork(resp)
+			if !retry {
+				return work, err
+			}
+		}
+		if i >= a.MaxRetries {
+			return nil, fmt.Errorf("request to %s failed after %d retries", link, a.MaxRetries)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// decodeWork reads a works API response and reports, whether a failure may be
+// retried.
+func decodeWork(resp *http.Response) (work *Work, retry bool, err error) {
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, false, ErrWorkNotFound
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, true, fmt.Errorf("request failed with: %s", resp.Status)
+	case resp.StatusCode >= 400:
+		return nil, false, fmt.Errorf("request failed with: %s", resp.Status)
+	}
+	var payload struct {
+		Message Work `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, true, err
+	}
+	return &payload.Message, false, nil
+}
+
+// Work implements WorkLookup.
+func (a *WorksAPI) Work(v string) (*Work, error) {
+	key := "crossref:work:" + v
+	if a.Cache != nil {
+		b, err := a.Cache.Get(key)
+		switch err {
+		case nil:
+			var entry cachedWork
+			if err := json.Unmarshal(b, &entry); err != nil {
+				return nil, err
+			}
+			if a.TTL == 0 || time.Since(entry.Fetched) < a.TTL {
+				if entry.Work == nil {
+					return nil, ErrWorkNotFound
+				}
+				return entry.Work, nil
+			}
+		case cache.ErrNotFound, cache.ErrInvalidKey:
+		default:
+			return nil, err
+		}
+	}
+	work, err := a.fetch(v)
+	if err != nil && err != ErrWorkNotFound {
+		return nil, err
+	}
+	if a.Cache != nil {
+		b, merr := json.Marshal(cachedWork{Work: work, Fetched: time.Now()})
+		if merr != nil {
+			return nil, merr
+		}
+		if serr := a.Cache.Set(key, b, a.TTL); serr != nil && serr != cache.ErrInvalidKey {
+			return nil, serr
+		}
+	}
+	return work, err
+}
+
+// Crossref fills in pages, volume, issue, ISSN and publisher of records with
+// a DOI from crossref. If Sources is not empty, only records from these
+// sources are changed.
+type Crossref struct {
+	Works   WorkLookup
+	Sources map[string]bool
+}
+
+// Wants returns true, if a record from a matching source has a DOI and lacks
+// at least one field, that can be filled in.
+func (c *Crossref) Wants(is *finc.IntermediateSchema) bool {
+	if len(c.Sources) > 0 && !c.Sources[is.SourceID] {
+		return false
+	}
+	if doi.Clean(is.DOI) == "" {
+		return false
+	}
+	return is.Pages.IsZero() || is.Volume == "" || is.Issue == "" ||
+		len(is.ISSNList()) == 0 || len(is.Publishers) == 0
+}
+
+// Enrich sets missing fields from the crossref work of the DOI of the record.
+// Returns true, if the record was changed. Records, whose DOI is not known to
+// crossref, are left as is.
+func (c *Crossref) Enrich(is *finc.IntermediateSchema) (changed bool, err error) {
+	if !c.Wants(is) {
+		return false, nil
+	}
+	work, err := c.Works.Work(doi.Clean(is.DOI))
+	if err == ErrWorkNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if is.Pages.IsZero() && work.Page != "" {
+		pi := crossref.ParsePages(work.Page)
+		is.Pages = finc.Pages{Start: pi.First, End: pi.Last, Count: pi.PageCount(), Raw: pi.RawMessage}
+		changed = true
+	}
+	if v := strings.TrimLeft(work.Volume, "0"); is.Volume == "" && v != "" {
+		is.Volume = v
+		changed = true
+	}
+	if v := strings.TrimLeft(work.Issue, "0"); is.Issue == "" && v != "" {
+		is.Issue = v
+		changed = true
+	}
+	if issn, eissn := work.serials(); len(is.ISSNList()) == 0 && len(issn)+len(eissn) > 0 {
+		is.ISSN, is.EISSN = issn, eissn
+		changed = true
+	}
+	if len(is.Publishers) == 0 && work.Publisher != "" {
+		is.Publishers = []string{work.Publisher}
+		changed = true
+	}
+	return changed, nil
+}
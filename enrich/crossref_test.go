@@ -0,0 +1,120 @@
+package enrich
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span/cache"
+	"github.com/miku/span/formats/finc"
+)
+
+const worksSnapshot = `{"DOI": "10.1000/A", "page": "12-15", "volume": "07", "issue": "2", "publisher": "P", "ISSN": ["1234-5678", "2345-6789"], "issn-type": [{"type": "print", "value": "1234-5678"}, {"type": "electronic", "value": "2345-6789"}]}
+{"DOI": "10.1000/b", "volume": "1", "ISSN": ["3456-7890"]}
+`
+
+func TestLoadWorks(t *testing.T) {
+	works, err := LoadWorks(strings.NewReader(worksSnapshot), func(doi string) bool { return doi == "10.1000/a" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(works) != 1 || works["10.1000/a"] == nil {
+		t.Errorf("LoadWorks: got %v, want 10.1000/a only", works)
+	}
+}
+
+func TestCrossrefEnrich(t *testing.T) {
+	works, err := LoadWorks(strings.NewReader(worksSnapshot), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Crossref{Works: works, Sources: map[string]bool{"28": true, "30": true}}
+	var cases = []struct {
+		about   string
+		is      finc.IntermediateSchema
+		changed bool
+		want    finc.IntermediateSchema
+	}{
+		{
+			"no doi",
+			finc.IntermediateSchema{SourceID: "28"},
+			false,
+			finc.IntermediateSchema{SourceID: "28"},
+		},
+		{
+			"other source",
+			finc.IntermediateSchema{SourceID: "49", DOI: "10.1000/a"},
+			false,
+			finc.IntermediateSchema{SourceID: "49", DOI: "10.1000/a"},
+		},
+		{
+			"unknown doi",
+			finc.IntermediateSchema{SourceID: "28", DOI: "10.1000/c"},
+			false,
+			finc.IntermediateSchema{SourceID: "28", DOI: "10.1000/c"},
+		},
+		{
+			"all fields",
+			finc.IntermediateSchema{SourceID: "28", DOI: "https://doi.org/10.1000/a"},
+			true,
+			finc.IntermediateSchema{SourceID: "28", DOI: "https://doi.org/10.1000/a",
+				Pages: finc.Pages{Start: "12", End: "15", Count: 4, Raw: "12-15"}, Volume: "7", Issue: "2",
+				ISSN: []string{"1234-5678"}, EISSN: []string{"2345-6789"}, Publishers: []string{"P"}},
+		},
+		{
+			"existing values are kept",
+			finc.IntermediateSchema{SourceID: "30", DOI: "10.1000/b", Volume: "3", EISSN: []string{"9999-9999"}},
+			false,
+			finc.IntermediateSchema{SourceID: "30", DOI: "10.1000/b", Volume: "3", EISSN: []string{"9999-9999"}},
+		},
+		{
+			"untyped issn",
+			finc.IntermediateSchema{SourceID: "30", DOI: "10.1000/b"},
+			true,
+			finc.IntermediateSchema{SourceID: "30", DOI: "10.1000/b", Volume: "1", ISSN: []string{"3456-7890"}},
+		},
+	}
+	for _, tc := range cases {
+		changed, err := c.Enrich(&tc.is)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.about, err)
+		}
+		if changed != tc.changed || !reflect.DeepEqual(tc.is, tc.want) {
+			t.Errorf("%s: got %v %+v, want %v %+v", tc.about, changed, tc.is, tc.changed, tc.want)
+		}
+	}
+}
+
+func TestWorksAPI(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/works/10.1000/a" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `{"status": "ok", "message": {"DOI": "10.1000/a", "volume": "7"}}`)
+	}))
+	defer ts.Close()
+
+	c, err := cache.Open("memory://")
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := &WorksAPI{Endpoint: ts.URL + "/works", Cache: c}
+	for i := 0; i < 2; i++ {
+		work, err := api.Work("10.1000/a")
+		if err != nil || work.Volume != "7" {
+			t.Fatalf("Work: got %v, %v", work, err)
+		}
+		if _, err := api.Work("10.1000/x"); err != ErrWorkNotFound {
+			t.Fatalf("Work: got %v, want %v", err, ErrWorkNotFound)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2, responses should be cached", requests)
+	}
+}
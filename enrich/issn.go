@@ -0,0 +1,141 @@
+// Package enrich adds missing information to intermediate schema records
+// from local reference data.
+package enrich
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/miku/span/formats/finc"
+)
+
+// serialNumber matches ISSN with or without hyphen.
+var serialNumber = regexp.MustCompile(`\b[0-9]{4}-?[0-9]{3}[0-9xX]\b`)
+
+// Journal is a registry entry.
+type Journal struct {
+	Title     string
+	Publisher string
+}
+
+// ISSNRegistry maps ISSN in standard form to journal title and publisher.
+type ISSNRegistry map[string]Journal
+
+// Column names, lowercase, of supported snapshots: the CrossRef title list
+// (JournalTitle, Publisher, pissn, eissn, additionalIssns), KBART and generic
+// title, publisher, issn tables.
+var (
+	titleColumns     = []string{"journaltitle", "publication_title", "title"}
+	publisherColumns = []string{"publisher", "publisher_name"}
+	issnColumns      = []string{"pissn", "eissn", "additionalissns", "print_identifier", "online_identifier", "issn", "issn_l"}
+)
+
+// findISSN returns ISSN in standard form found in s.
+func findISSN(s string) (result []string) {
+	for _, v := range serialNumber.FindAllString(s, -1) {
+		v = strings.ToUpper(strings.Replace(v, "-", "", 1))
+		result = append(result, v[:4]+"-"+v[4:])
+	}
+	return result
+}
+
+// columnIndex returns the index of the first column found in header or -1.
+func columnIndex(header []string, names []string) int {
+	for _, name := range names {
+		for i, h := range header {
+			if strings.ToLower(strings.TrimSpace(h)) == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// LoadISSNRegistry reads a CSV or tab separated snapshot with a header row.
+// Columns are recognized by name, e.g. JournalTitle, Publisher, pissn and
+// eissn in the CrossRef title list. The first entry for an ISSN wins.
+func LoadISSNRegistry(r io.Reader) (ISSNRegistry, error) {
+	br := bufio.NewReader(r)
+	peek, _ := br.Peek(4096)
+	cr := csv.NewReader(br)
+	if line := strings.SplitN(string(peek), "\n", 2)[0]; strings.Count(line, "\t") > strings.Count(line, ",") {
+		cr.Comma = '\t'
+		cr.LazyQuotes = true
+	}
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return ISSNRegistry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var (
+		title     = columnIndex(header, titleColumns)
+		publisher = columnIndex(header, publisherColumns)
+		issns     []int
+	)
+	for i, h := range header {
+		for _, name := range issnColumns {
+			if strings.ToLower(strings.TrimSpace(h)) == name {
+				issns = append(issns, i)
+				break
+			}
+		}
+	}
+	get := func(record []string, i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	registry := make(ISSNRegistry)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		journal := Journal{Title: get(record, title), Publisher: get(record, publisher)}
+		if journal.Title == "" && journal.Publisher == "" {
+			continue
+		}
+		for _, i := range issns {
+			for _, issn := range findISSN(get(record, i)) {
+				if _, ok := registry[issn]; !ok {
+					registry[issn] = journal
+				}
+			}
+		}
+	}
+	return registry, nil
+}
+
+// Enrich sets journal title and publisher, if missing, from the first ISSN of
+// the record found in the registry. Returns true, if the record was changed.
+func (r ISSNRegistry) Enrich(is *finc.IntermediateSchema) (changed bool) {
+	if is.JournalTitle != "" && len(is.Publishers) > 0 {
+		return false
+	}
+	for _, issn := range is.ISSNList() {
+		journal, ok := r[strings.ToUpper(issn)]
+		if !ok {
+			continue
+		}
+		if is.JournalTitle == "" && journal.Title != "" {
+			is.JournalTitle = journal.Title
+			changed = true
+		}
+		if len(is.Publishers) == 0 && journal.Publisher != "" {
+			is.Publishers = []string{journal.Publisher}
+			changed = true
+		}
+		return changed
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package enrich
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span/formats/finc"
+)
+
+func TestLoadISSNRegistry(t *testing.T) {
+	var cases = []struct {
+		about  string
+		input  string
+		result ISSNRegistry
+	}{
+		{"empty", "", ISSNRegistry{}},
+		{
+			"crossref title list",
+			"JournalTitle,JournalID,Publisher,pissn,eissn,additionalIssns,doi\n" +
+				`"Acta, Something",1,Pub A,0001527X,1234-5678,"2345-6789; 3456-7890",10.1/x` + "\n" +
+				"Other,2,Pub B,0001527X,,,\n",
+			ISSNRegistry{
+				"0001-527X": {"Acta, Something", "Pub A"},
+				"1234-5678": {"Acta, Something", "Pub A"},
+				"2345-6789": {"Acta, Something", "Pub A"},
+				"3456-7890": {"Acta, Something", "Pub A"},
+			},
+		},
+		{
+			"kbart",
+			"publication_title\tprint_identifier\tonline_identifier\tpublisher_name\n" +
+				"J\t1111-2222\t\tP\n",
+			ISSNRegistry{"1111-2222": {"J", "P"}},
+		},
+	}
+	for _, c := range cases {
+		result, err := LoadISSNRegistry(strings.NewReader(c.input))
+		if err != nil {
+			t.Fatalf("%s: %v", c.about, err)
+		}
+		if !reflect.DeepEqual(result, c.result) {
+			t.Errorf("%s: got %v, want %v", c.about, result, c.result)
+		}
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	r := ISSNRegistry{"1234-5678": {"Journal", "Publisher"}}
+	var cases = []struct {
+		is      finc.IntermediateSchema
+		changed bool
+		title   string
+		pubs    []string
+	}{
+		{finc.IntermediateSchema{}, false, "", nil},
+		{finc.IntermediateSchema{EISSN: []string{"1234-5678"}}, true, "Journal", []string{"Publisher"}},
+		{finc.IntermediateSchema{ISSN: []string{"1234-5678"}, JournalTitle: "J"}, true, "J", []string{"Publisher"}},
+		{finc.IntermediateSchema{ISSN: []string{"1234-5678"}, JournalTitle: "J", Publishers: []string{"P"}}, false, "J", []string{"P"}},
+	}
+	for _, c := range cases {
+		changed := r.Enrich(&c.is)
+		if changed != c.changed || c.is.JournalTitle != c.title || !reflect.DeepEqual(c.is.Publishers, c.pubs) {
+			t.Errorf("got %v %q %v, want %v %q %v", changed, c.is.JournalTitle, c.is.Publishers, c.changed, c.title, c.pubs)
+		}
+	}
+}
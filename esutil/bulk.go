@@ -0,0 +1,315 @@
+// Package esutil implements a bulk indexing client for Elasticsearch and
+// OpenSearch.
+package esutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Indexer defaults.
+const (
+	DefaultBatchSize      = 1000
+	DefaultNumConnections = 4
+	DefaultMaxRetries     = 5
+	DefaultRetryWait      = time.Second
+	// MaxLoggedFailures limits the number of logged document failures.
+	MaxLoggedFailures = 10
+)
+
+// Indexer sends documents to the bulk API in batches, over a number of
+// parallel connections. It accepts newline delimited JSON documents, as written
+// by span-export; the id field is used as document id. Writes block while all
+// connections are busy. Indexer is not safe for concurrent use.
+type Indexer struct {
+	// Server, e.g. http://localhost:9200, may contain user and password.
+	Server    string
+	Index     string
+	BatchSize int
+	// NumConnections is the number of concurrent bulk requests.
+	NumConnections int
+	// MaxRetries for requests rejected with HTTP 429 or 503 or failed with a
+	// network error, as well as for single documents rejected with 429 or 503.
+	// The wait between retries starts at RetryWait and doubles.
+	MaxRetries int
+	RetryWait  time.Duration
+	// Refresh the index on Close, so documents are searchable.
+	Refresh bool
+	Client  *http.Client
+
+	once    sync.Once
+	batches chan []item
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	err     error
+	line    []byte
+	batch   []item
+	indexed int64
+	failed  int64
+}
+
+// item is a single document with its id.
+type item struct {
+	id  string
+	doc []byte
+}
+
+// bulkResponse is the part of a bulk response needed to find failed items.
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string          `json:"_id"`
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// NewIndexer creates an indexer with default settings.
+func NewIndexer(server, index string) *Indexer {
+	if !strings.HasPrefix(server, "http") {
+		server = "http://" + server
+	}
+	return &Indexer{
+		Server:         strings.TrimRight(server, "/"),
+		Index:          index,
+		BatchSize:      DefaultBatchSize,
+		NumConnections: DefaultNumConnections,
+		MaxRetries:     DefaultMaxRetries,
+		RetryWait:      DefaultRetryWait,
+		Client:         http.DefaultClient,
+	}
+}
+
+// start launches the workers.
+func (ix *Indexer) start() {
+	if ix.BatchSize < 1 {
+		ix.BatchSize = DefaultBatchSize
+	}
+	if ix.NumConnections < 1 {
+		ix.NumConnections = 1
+	}
+	if ix.Client == nil {
+		ix.Client = http.DefaultClient
+	}
+	ix.batches = make(chan []item)
+	for i := 0; i < ix.NumConnections; i++ {
+		ix.wg.Add(1)
+		go func() {
+			defer ix.wg.Done()
+			for b := range ix.batches {
+				if ix.Err() != nil {
+					continue
+				}
+				if err := ix.send(b); err != nil {
+					ix.setErr(err)
+				}
+			}
+		}()
+	}
+}
+
+// Err returns the first error, that stopped indexing.
+func (ix *Indexer) Err() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.err
+}
+
+func (ix *Indexer) setErr(err error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.err == nil {
+		ix.err = err
+	}
+}
+
+// Indexed returns the number of documents indexed.
+func (ix *Indexer) Indexed() int64 {
+	return atomic.LoadInt64(&ix.indexed)
+}
+
+// Failed returns the number of documents rejected.
+func (ix *Indexer) Failed() int64 {
+	return atomic.LoadInt64(&ix.failed)
+}
+
+// Write buffers documents, one per line, and sends full batches.
+func (ix *Indexer) Write(p []byte) (int, error) {
+	ix.once.Do(ix.start)
+	if err := ix.Err(); err != nil {
+		return 0, err
+	}
+	ix.line = append(ix.line, p...)
+	for {
+		i := bytes.IndexByte(ix.line, '\n')
+		if i < 0 {
+			break
+		}
+		if err := ix.add(ix.line[:i]); err != nil {
+			return 0, err
+		}
+		ix.line = ix.line[i+1:]
+	}
+	return len(p), nil
+}
+
+// add adds a document to the current batch.
+func (ix *Indexer) add(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil
+	}
+	var doc struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	if doc.ID == "" {
+		return fmt.Errorf("document without id: %s", b)
+	}
+	ix.batch = append(ix.batch, item{id: doc.ID, doc: append([]byte(nil), b...)})
+	if len(ix.batch) >= ix.BatchSize {
+		ix.flush()
+	}
+	return nil
+}
+
+// flush sends the current batch to a worker.
+func (ix *Indexer) flush() {
+	if len(ix.batch) == 0 {
+		return
+	}
+	ix.batches <- ix.batch
+	ix.batch = nil
+}
+
+// Close sends remaining documents, waits for all batches and refreshes the
+// index, if requested. An error is returned, if documents were rejected.
+func (ix *Indexer) Close() error {
+	ix.once.Do(ix.start)
+	err := ix.add(ix.line)
+	ix.line = nil
+	ix.flush()
+	close(ix.batches)
+	ix.wg.Wait()
+	if err != nil {
+		return err
+	}
+	if err := ix.Err(); err != nil {
+		return err
+	}
+	if n := ix.Failed(); n > 0 {
+		return fmt.Errorf("%d documents rejected", n)
+	}
+	if ix.Refresh {
+		_, err := ix.post(fmt.Sprintf("%s/%s/_refresh", ix.Server, ix.Index), nil)
+		return err
+	}
+	return nil
+}
+
+// body renders a bulk request.
+func (ix *Indexer) body(items []item) []byte {
+	var buf bytes.Buffer
+	for _, it := range items {
+		action, _ := json.Marshal(map[string]interface{}{
+			"index": map[string]string{"_index": ix.Index, "_id": it.id},
+		})
+		buf.Write(action)
+		buf.WriteByte('\n')
+		buf.Write(it.doc)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// send indexes a batch and retries documents rejected temporarily, e.g. when
+// queues are full.
+func (ix *Indexer) send(items []item) error {
+	wait := ix.RetryWait
+	for attempt := 0; ; attempt++ {
+		b, err := ix.post(ix.Server+"/_bulk", ix.body(items))
+		if err != nil {
+			return err
+		}
+		var resp bulkResponse
+		if err := json.Unmarshal(b, &resp); err != nil {
+			return fmt.Errorf("invalid bulk response: %v", err)
+		}
+		if len(resp.Items) != len(items) {
+			return fmt.Errorf("bulk response has %d items, want %d", len(resp.Items), len(items))
+		}
+		var retry []item
+		for i, result := range resp.Items {
+			for _, r := range result {
+				switch {
+				case r.Status < 300:
+					atomic.AddInt64(&ix.indexed, 1)
+				case temporary(r.Status) && attempt < ix.MaxRetries:
+					retry = append(retry, items[i])
+				default:
+					if n := atomic.AddInt64(&ix.failed, 1); n <= MaxLoggedFailures {
+						log.Warnf("document %s rejected with HTTP %d: %s", items[i].id, r.Status, r.Error)
+					}
+				}
+			}
+		}
+		if len(retry) == 0 {
+			return nil
+		}
+		log.Warnf("%d documents rejected temporarily, retrying in %s", len(retry), wait)
+		time.Sleep(wait)
+		wait *= 2
+		items = retry
+	}
+}
+
+// temporary returns true for status codes worth a retry.
+func temporary(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// post sends a request body and returns the response, retrying on temporary
+// failures.
+func (ix *Indexer) post(link string, body []byte) ([]byte, error) {
+	wait := ix.RetryWait
+	for attempt := 0; ; attempt++ {
+		b, retry, err := ix.postOnce(link, body)
+		if err == nil || !retry || attempt >= ix.MaxRetries {
+			return b, err
+		}
+		log.Warnf("%v, retrying in %s", err, wait)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// postOnce sends a request and reports, whether a failed request is worth a
+// retry.
+func (ix *Indexer) postOnce(link string, body []byte) (b []byte, retry bool, err error) {
+	resp, err := ix.Client.Post(link, "application/x-ndjson", bytes.NewReader(body))
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, temporary(resp.StatusCode),
+			fmt.Errorf("bulk request failed with HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if b, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, true, err
+	}
+	return b, false, nil
+}
@@ -0,0 +1,98 @@
+package esutil
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBulk rejects each document with the given status once, then accepts it.
+func fakeBulk(t *testing.T, status int) (*httptest.Server, map[string]int) {
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/_bulk" {
+			return
+		}
+		var items []interface{}
+		var errors bool
+		br := bufio.NewScanner(r.Body)
+		for br.Scan() {
+			var action struct {
+				Index struct {
+					Index string `json:"_index"`
+					ID    string `json:"_id"`
+				} `json:"index"`
+			}
+			if err := json.Unmarshal(br.Bytes(), &action); err != nil {
+				t.Fatal(err)
+			}
+			if action.Index.Index != "biblio" {
+				t.Errorf("got index %q, want biblio", action.Index.Index)
+			}
+			br.Scan()
+			id := action.Index.ID
+			seen[id]++
+			code := 201
+			if seen[id] == 1 && status != 0 {
+				code = status
+				errors = true
+			}
+			items = append(items, map[string]interface{}{
+				"index": map[string]interface{}{"_id": id, "status": code},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"errors": errors, "items": items})
+	}))
+	return ts, seen
+}
+
+func writeDocs(t *testing.T, ix *Indexer, n int) {
+	var lines []string
+	for i := 0; i < n; i++ {
+		lines = append(lines, fmt.Sprintf(`{"id": "%d", "title": "t"}`, i))
+	}
+	if _, err := ix.Write([]byte(strings.Join(lines, "\n"))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexerRetry(t *testing.T) {
+	ts, seen := fakeBulk(t, http.StatusTooManyRequests)
+	defer ts.Close()
+
+	ix := NewIndexer(ts.URL, "biblio")
+	ix.BatchSize = 3
+	ix.RetryWait = time.Millisecond
+	ix.Refresh = true
+	writeDocs(t, ix, 10)
+	if err := ix.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ix.Indexed() != 10 || ix.Failed() != 0 {
+		t.Errorf("got %d indexed, %d failed, want 10, 0", ix.Indexed(), ix.Failed())
+	}
+	if len(seen) != 10 || seen["0"] != 2 {
+		t.Errorf("got %v, want each document twice", seen)
+	}
+}
+
+func TestIndexerRejected(t *testing.T) {
+	ts, _ := fakeBulk(t, http.StatusBadRequest)
+	defer ts.Close()
+
+	ix := NewIndexer(ts.URL, "biblio")
+	ix.RetryWait = time.Millisecond
+	writeDocs(t, ix, 2)
+	if err := ix.Close(); err == nil || ix.Failed() != 2 {
+		t.Errorf("got %v, %d failed, want error and 2 failed", err, ix.Failed())
+	}
+}
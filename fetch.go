@@ -0,0 +1,131 @@
+package span
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Retry defaults apply to fetchers without explicit settings, including the
+// ones used by LinkReader and SavedLink values created in other packages.
+// Commands and tests may change them before fetching.
+var (
+	// DefaultMaxRetries is the number of retries, if not set otherwise.
+	DefaultMaxRetries = 5
+	// DefaultBackoff is the wait time before the first retry, doubled on
+	// each retry.
+	DefaultBackoff = 1 * time.Second
+)
+
+// Fetcher downloads links, retrying with exponential backoff on network
+// errors, rate limits and server errors. Interrupted downloads are resumed
+// with range requests, if the server supports them. The zero value is usable.
+type Fetcher struct {
+	MaxRetries int           // Zero means DefaultMaxRetries, negative values disable retries.
+	Backoff    time.Duration // Zero means DefaultBackoff.
+	Timeout    time.Duration // Timeout per request, zero means no timeout.
+	UserAgent  string        // Defaults to span/AppVersion.
+	Header     http.Header   // Additional request headers.
+}
+
+// retryable marks errors, that may go away on retry.
+type retryable struct {
+	err error
+}
+
+func (e retryable) Error() string { return e.err.Error() }
+
+// Fetch writes the content of a link to w.
+func (f Fetcher) Fetch(link string, w io.Writer) error {
+	retries, backoff := f.MaxRetries, f.Backoff
+	switch {
+	case retries == 0:
+		retries = DefaultMaxRetries
+	case retries < 0:
+		retries = 0
+	}
+	if backoff == 0 {
+		backoff = DefaultBackoff
+	}
+	client := &http.Client{Timeout: f.Timeout}
+	var written int64
+	var err error
+	for i := 0; ; i++ {
+		var n int64
+		n, err = f.fetchFrom(client, link, w, written)
+		written += n
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(retryable); !ok || i >= retries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	if e, ok := err.(retryable); ok {
+		return fmt.Errorf("giving up on %s after %d retries: %v", link, retries, e.err)
+	}
+	return err
+}
+
+// fetchFrom requests a link from a given offset on and returns the number of
+// bytes written.
+func (f Fetcher) fetchFrom(client *http.Client, link string, w io.Writer, offset int64) (int64, error) {
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return 0, err
+	}
+	for k, vs := range f.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	ua := f.UserAgent
+	if ua == "" {
+		ua = fmt.Sprintf("span/%s", AppVersion)
+	}
+	req.Header.Set("User-Agent", ua)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, retryable{err}
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return 0, retryable{fmt.Errorf("request to %s failed with: %s", link, resp.Status)}
+	case resp.StatusCode >= 400:
+		return 0, fmt.Errorf("request to %s failed with: %s", link, resp.Status)
+	}
+	if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		// No range support, skip what we already have.
+		if _, err := io.CopyN(ioutil.Discard, resp.Body, offset); err != nil {
+			return 0, retryable{err}
+		}
+	}
+	body := &errReader{r: resp.Body}
+	n, err := io.Copy(w, body)
+	if err != nil && body.err != nil {
+		return n, retryable{err}
+	}
+	return n, err
+}
+
+// errReader remembers read errors, to tell them apart from write errors.
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,98 @@
+package span
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetcher(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	var requests int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&requests, 1)
+		if r.Header.Get("User-Agent") != "test" || r.Header.Get("X-Token") != "secret" {
+			http.Error(w, "missing headers", http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/flaky":
+			if n < 3 {
+				http.Error(w, "try again", http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, content)
+		case "/interrupted":
+			if rng := r.Header.Get("Range"); rng != "" {
+				offset, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+				w.WriteHeader(http.StatusPartialContent)
+				fmt.Fprint(w, content[offset:])
+				return
+			}
+			// Announce everything, but deliver only half.
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			fmt.Fprint(w, content[:len(content)/2])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	f := Fetcher{
+		MaxRetries: 3,
+		Backoff:    time.Millisecond,
+		UserAgent:  "test",
+		Header:     http.Header{"X-Token": []string{"secret"}},
+	}
+	var tests = []struct {
+		path     string
+		err      bool
+		requests int64
+	}{
+		{"/flaky", false, 3},
+		{"/interrupted", false, 2},
+		{"/missing", true, 1},
+	}
+	for _, tt := range tests {
+		atomic.StoreInt64(&requests, 0)
+		var buf bytes.Buffer
+		err := f.Fetch(ts.URL+tt.path, &buf)
+		if (err != nil) != tt.err {
+			t.Errorf("Fetch(%s): got %v, want error %v", tt.path, err, tt.err)
+		}
+		if !tt.err && buf.String() != content {
+			t.Errorf("Fetch(%s): got %d bytes, want %d", tt.path, buf.Len(), len(content))
+		}
+		if n := atomic.LoadInt64(&requests); n != tt.requests {
+			t.Errorf("Fetch(%s): got %d requests, want %d", tt.path, n, tt.requests)
+		}
+	}
+}
+
+func TestFetcherRetriesDisabled(t *testing.T) {
+	var requests int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		http.Error(w, "try again", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	f := Fetcher{MaxRetries: -1, Backoff: time.Millisecond}
+	var buf bytes.Buffer
+	err := f.Fetch(ts.URL, &buf)
+	if err == nil {
+		t.Fatalf("Fetch: want error")
+	}
+	if !strings.Contains(err.Error(), "after 0 retries") {
+		t.Errorf("Fetch: got %q, want retry count 0", err)
+	}
+	if n := atomic.LoadInt64(&requests); n != 1 {
+		t.Errorf("Fetch: got %d requests, want 1", n)
+	}
+}
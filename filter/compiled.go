@@ -0,0 +1,112 @@
+package filter
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CompiledHoldingsDir, if set, keeps parsed holding files in this directory,
+// keyed by the checksum of the file, so a file, that has not changed, need not
+// be parsed again in the next run.
+var CompiledHoldingsDir = ""
+
+// compiledVersion must change with the layout of CacheValue or
+// licensing.Entry, so older compiled files are not used.
+const compiledVersion = 1
+
+// fileChecksum returns the hex encoded SHA256 of a file.
+func fileChecksum(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// compiledFilename returns the location of a compiled holding file. Files
+// read in strict mode are kept apart, so a lenient read never passes for a
+// strict one.
+func compiledFilename(sum string) string {
+	name := fmt.Sprintf("%s-v%d", sum, compiledVersion)
+	if StrictHoldings {
+		name += "-strict"
+	}
+	return filepath.Join(CompiledHoldingsDir, name+".gob")
+}
+
+// loadCompiled reads a compiled holding file.
+func loadCompiled(filename string) (v CacheValue, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return v, err
+	}
+	defer f.Close()
+	err = gob.NewDecoder(f).Decode(&v)
+	return v, err
+}
+
+// saveCompiled writes a compiled holding file. The file is moved into place
+// at the end, so concurrent runs do not see partial files.
+func saveCompiled(filename string, v CacheValue) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(filename), "span-holdings-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := gob.NewEncoder(f).Encode(v); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
+
+// putCompiled adds a holding file to the cache, using a compiled version of
+// it, if there is one. Otherwise the file is parsed and compiled for the next
+// run. Problems with compiled files are logged, but are not fatal.
+func (c *HoldingsCache) putCompiled(filename string) error {
+	if _, ok := c.get(filename); ok {
+		log.Printf("[holdings] already cached: %s", filename)
+		return nil
+	}
+	sum, err := fileChecksum(filename)
+	if err != nil {
+		return err
+	}
+	compiled := compiledFilename(sum)
+	v, err := loadCompiled(compiled)
+	if err == nil {
+		log.Printf("[holdings] read (compiled): %s", filename)
+		c.set(filename, v)
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		log.Warnf("[holdings] ignoring %s: %v", compiled, err)
+	}
+	if err := c.readFile(filename); err != nil {
+		return err
+	}
+	v, _ = c.get(filename)
+	if err := saveCompiled(compiled, v); err != nil {
+		log.Warnf("[holdings] cannot write %s: %v", compiled, err)
+	}
+	return nil
+}
@@ -2,25 +2,27 @@ package filter
 
 import (
 	"encoding/json"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
 
 	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
 )
 
 // DOIFilter allows records with a given DOI. Can be used in conjuction with
-// "not" to create blacklists.
+// "not" to create blacklists. DOI are compared normalized, so resolver
+// prefixes and case do not matter.
 type DOIFilter struct {
-	Values []string
+	Values *container.StringSet
 }
 
 // Apply applies the filter.
 func (f *DOIFilter) Apply(is finc.IntermediateSchema) bool {
-	for _, v := range f.Values {
-		if v == is.DOI {
-			return true
-		}
-	}
-	return false
+	v := doi.Clean(is.DOI)
+	return v != "" && f.Values.Contains(v)
 }
 
 // UnmarshalJSON turns a config fragment into a filter.
@@ -29,18 +31,41 @@ func (f *DOIFilter) UnmarshalJSON(p []byte) error {
 		DOI struct {
 			Values []string `json:"list"`
 			File   string   `json:"file"`
+			Link   string   `json:"url"`
 		} `json:"doi"`
 	}
 	if err := json.Unmarshal(p, &s); err != nil {
 		return err
 	}
+	f.Values = container.NewStringSet()
+
+	if s.DOI.Link != "" {
+		slink := span.SavedLink{Link: s.DOI.Link}
+		filename, err := slink.Save()
+		if err != nil {
+			return err
+		}
+		defer slink.Remove()
+		s.DOI.File = filename
+	}
 	if s.DOI.File != "" {
 		lines, err := span.ReadLines(s.DOI.File)
 		if err != nil {
 			return err
 		}
-		f.Values = append(f.Values, lines...)
+		s.DOI.Values = append(s.DOI.Values, lines...)
+	}
+	var skipped int
+	for _, v := range s.DOI.Values {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		if w := doi.Clean(v); w != "" {
+			f.Values.Add(w)
+		} else {
+			skipped++
+		}
 	}
-	f.Values = append(f.Values, s.DOI.Values...)
+	log.Printf("doi: collected %d DOI, skipped %d invalid", f.Values.Size(), skipped)
 	return nil
 }
@@ -18,6 +18,9 @@ package filter
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
 
 	"github.com/miku/span/formats/finc"
 )
@@ -51,6 +54,23 @@ func (t *Tree) Apply(is finc.IntermediateSchema) bool {
 	return t.Root.Apply(is)
 }
 
+// Parse returns a filter from an expression, e.g. {"source": ["49"]}, or from
+// a file containing one.
+func Parse(s string) (Filter, error) {
+	b := []byte(s)
+	if !strings.HasPrefix(strings.TrimSpace(s), "{") {
+		var err error
+		if b, err = ioutil.ReadFile(s); err != nil {
+			return nil, err
+		}
+	}
+	var tree Tree
+	if err := json.Unmarshal(b, &tree); err != nil {
+		return nil, err
+	}
+	return &tree, nil
+}
+
 // Tagger takes a list of tags (ISILs) and annotates an intermediate schema
 // according to a number of filters, defined per label. The tagger is loaded
 // directly from JSON.
@@ -59,16 +79,31 @@ type Tagger struct {
 }
 
 // Tag takes an intermediate schema record and returns a labeled version of that
-// record.
+// record. New labels are added in sorted order, so output is reproducible.
 func (t *Tagger) Tag(is finc.IntermediateSchema) finc.IntermediateSchema {
+	var tags []string
 	for tag, filter := range t.FilterMap {
 		if filter.Apply(is) {
-			is.Labels = append(is.Labels, tag)
+			tags = append(tags, tag)
 		}
 	}
+	sort.Strings(tags)
+	is.Labels = append(is.Labels, tags...)
 	return is
 }
 
+// Add adds a filter for a label. If the label has a filter already, either
+// filter suffices.
+func (t *Tagger) Add(label string, f Filter) {
+	if t.FilterMap == nil {
+		t.FilterMap = make(map[string]Tree)
+	}
+	if tree, ok := t.FilterMap[label]; ok {
+		f = &OrFilter{Filters: []Filter{tree.Root, f}}
+	}
+	t.FilterMap[label] = Tree{Root: f}
+}
+
 // UnmarshalJSON unmarshals a complete filter config from serialized JSON.
 func (t *Tagger) UnmarshalJSON(p []byte) error {
 	t.FilterMap = make(map[string]Tree)
@@ -89,6 +124,12 @@ func unmarshalFilter(name string, raw json.RawMessage) (Filter, error) {
 			return nil, err
 		}
 		return &filter, nil
+	case "id":
+		var filter IDFilter
+		if err := json.Unmarshal(raw, &filter); err != nil {
+			return nil, err
+		}
+		return &filter, nil
 	case "issn":
 		var filter ISSNFilter
 		if err := json.Unmarshal(raw, &filter); err != nil {
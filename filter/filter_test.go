@@ -2,6 +2,13 @@ package filter
 
 import (
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/miku/span/formats/finc"
@@ -181,3 +188,223 @@ func TestNotFilter1(t *testing.T) {
 		}
 	}
 }
+
+func TestTaggerAdd(t *testing.T) {
+	f, err := ioutil.TempFile("", "span-kbart-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	kbart := "publication_title\tprint_identifier\tonline_identifier\tdate_first_issue_online\tnum_first_vol_online\tnum_first_issue_online\tdate_last_issue_online\tnum_last_vol_online\tnum_last_issue_online\ttitle_url\tfirst_author\ttitle_id\tembargo_info\n" +
+		"J\t1234-5678\t\t2000\t\t\t2010\t\t\t\t\t\t\n"
+	if _, err := f.WriteString(kbart); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	hf, err := NewHoldingsFilter(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tagger Tagger
+	tagger.Add("DE-1", hf)
+	tagger.Add("DE-1", &SourceFilter{Values: []string{"1"}})
+	tagger.Add("DE-2", hf)
+
+	var tests = []struct {
+		record finc.IntermediateSchema
+		labels []string
+	}{
+		{finc.IntermediateSchema{ISSN: []string{"1234-5678"}, RawDate: "2005-01-01"}, []string{"DE-1", "DE-2"}},
+		{finc.IntermediateSchema{ISSN: []string{"1234-5678"}, RawDate: "2015-01-01"}, nil},
+		{finc.IntermediateSchema{ISSN: []string{"1234-5678"}, RawDate: "2015-01-01", SourceID: "1"}, []string{"DE-1"}},
+	}
+	for _, test := range tests {
+		labels := tagger.Tag(test.record).Labels
+		sort.Strings(labels)
+		if !reflect.DeepEqual(labels, test.labels) {
+			t.Errorf("Tag got %v, want %v", labels, test.labels)
+		}
+	}
+}
+
+func TestTagSorted(t *testing.T) {
+	var tagger Tagger
+	for _, isil := range []string{"DE-15", "DE-1", "DE-14", "DE-105"} {
+		tagger.Add(isil, &SourceFilter{Values: []string{"1"}})
+	}
+	want := []string{"X", "DE-1", "DE-105", "DE-14", "DE-15"}
+	for i := 0; i < 10; i++ {
+		labels := tagger.Tag(finc.IntermediateSchema{SourceID: "1", Labels: []string{"X"}}).Labels
+		if !reflect.DeepEqual(labels, want) {
+			t.Fatalf("Tag got %v, want %v", labels, want)
+		}
+	}
+}
+
+func TestDOIBlacklist(t *testing.T) {
+	s := `
+    {
+        "and": [
+            {"source": ["49"]},
+            {"not": {"doi": {"list": ["10.1000/BLOCKED", " 10.1000/other ", "https://doi.org/10.1000/Resolved", "doi:10.1000/prefixed.", "not a doi"]}}}
+        ]
+    }
+    `
+	var tests = []struct {
+		record finc.IntermediateSchema
+		result bool
+	}{
+		{finc.IntermediateSchema{SourceID: "49", DOI: "10.1000/ok"}, true},
+		{finc.IntermediateSchema{SourceID: "49"}, true},
+		{finc.IntermediateSchema{SourceID: "49", DOI: "10.1000/blocked"}, false},
+		{finc.IntermediateSchema{SourceID: "49", DOI: "10.1000/Other"}, false},
+		{finc.IntermediateSchema{SourceID: "49", DOI: "10.1000/resolved"}, false},
+		{finc.IntermediateSchema{SourceID: "49", DOI: "10.1000/PREFIXED"}, false},
+		{finc.IntermediateSchema{SourceID: "49", DOI: "http://dx.doi.org/10.1000/blocked"}, false},
+		{finc.IntermediateSchema{SourceID: "49", DOI: "not a doi"}, true},
+		{finc.IntermediateSchema{SourceID: "48", DOI: "10.1000/ok"}, false},
+	}
+
+	var tree Tree
+	if err := json.Unmarshal([]byte(s), &tree); err != nil {
+		t.Fatalf("invalid filter: %s", err)
+	}
+	for _, test := range tests {
+		result := tree.Apply(test.record)
+		if result != test.result {
+			t.Errorf("Apply(%+v) got %v, want %v", test.record, result, test.result)
+		}
+	}
+}
+
+func TestIDFilter(t *testing.T) {
+	s := `{"not": {"id": {"list": ["ai-49-abc", " 12345 "]}}}`
+	var tests = []struct {
+		record finc.IntermediateSchema
+		result bool
+	}{
+		{finc.IntermediateSchema{ID: "ai-49-xyz"}, true},
+		{finc.IntermediateSchema{}, true},
+		{finc.IntermediateSchema{ID: "ai-49-abc"}, false},
+		{finc.IntermediateSchema{ID: "ai-49-ABC"}, true},
+		{finc.IntermediateSchema{ID: "ai-28-xyz", RecordID: "12345"}, false},
+	}
+
+	var tree Tree
+	if err := json.Unmarshal([]byte(s), &tree); err != nil {
+		t.Fatalf("invalid filter: %s", err)
+	}
+	for _, test := range tests {
+		result := tree.Apply(test.record)
+		if result != test.result {
+			t.Errorf("Apply(%+v) got %v, want %v", test.record, result, test.result)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	f, err := Parse(` {"or": [{"source": ["49"]}, {"collection": ["A"]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		record finc.IntermediateSchema
+		result bool
+	}{
+		{finc.IntermediateSchema{SourceID: "49"}, true},
+		{finc.IntermediateSchema{SourceID: "48", MegaCollections: []string{"A"}}, true},
+		{finc.IntermediateSchema{SourceID: "48"}, false},
+	}
+	for _, test := range tests {
+		if result := f.Apply(test.record); result != test.result {
+			t.Errorf("Apply(%+v) got %v, want %v", test.record, result, test.result)
+		}
+	}
+	if _, err := Parse(`{"unknown": {}}`); err == nil {
+		t.Errorf("expected error for unknown filter")
+	}
+	if _, err := Parse("/does/not/exist.json"); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
+
+func TestCompiledHoldings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-compiled-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "kbart.tsv")
+	kbart := "publication_title\tprint_identifier\tdate_first_issue_online\n" +
+		"J\t1234-5678\t2000\n"
+	if err := ioutil.WriteFile(filename, []byte(kbart), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(c HoldingsCache, d string) { Cache, CompiledHoldingsDir = c, d }(Cache, CompiledHoldingsDir)
+	CompiledHoldingsDir = filepath.Join(dir, "compiled")
+
+	for i := 0; i < 2; i++ {
+		Cache = make(HoldingsCache)
+		hf, err := NewHoldingsFilter(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hf.CachedValues[filename].SerialNumberMap["1234-5678"]) != 1 {
+			t.Errorf("run %d: got %v", i, hf.CachedValues[filename].SerialNumberMap)
+		}
+		files, err := filepath.Glob(filepath.Join(CompiledHoldingsDir, "*.gob"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 {
+			t.Errorf("run %d: got %d compiled files, want 1", i, len(files))
+		}
+	}
+}
+
+func TestHoldingsConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-holdings-config-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"a.tsv": "publication_title\tprint_identifier\nA\t1234-5678\n",
+		"b.tsv": "publication_title\tprint_identifier\nB\t2345-6789\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := fmt.Sprintf(`{"DE-1": ["%s"], "DE-2": ["%s", {"location": "%s", "format": "kbart"}]}`,
+		filepath.Join(dir, "a.tsv"), filepath.Join(dir, "a.tsv"), filepath.Join(dir, "b.tsv"))
+	hc, err := ReadHoldingsConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filters, err := hc.Filters(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases = []struct {
+		isil string
+		issn string
+		want bool
+	}{
+		{"DE-1", "1234-5678", true},
+		{"DE-1", "2345-6789", false},
+		{"DE-2", "1234-5678", true},
+		{"DE-2", "2345-6789", true},
+	}
+	for _, c := range cases {
+		is := finc.IntermediateSchema{ISSN: []string{c.issn}, RawDate: "2000-01-01"}
+		if got := filters[c.isil].Apply(is); got != c.want {
+			t.Errorf("%s %s: got %v, want %v", c.isil, c.issn, got, c.want)
+		}
+	}
+	if _, err := ReadHoldingsConfig(strings.NewReader(`{"DE-1": [{"location": "a.xml", "format": "ovid"}]}`)); err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+}
@@ -3,9 +3,11 @@ package filter
 import (
 	"archive/zip"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"strings"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -29,31 +31,64 @@ type CacheValue struct {
 // (rows from KBART) by ISSN, wiso database name or title.
 type HoldingsCache map[string]CacheValue
 
+// cacheMu guards the cache while holding files are loaded, possibly
+// concurrently. Lookups during tagging happen after loading and need no lock.
+var cacheMu sync.RWMutex
+
+// get returns a cached item.
+func (c *HoldingsCache) get(key string) (CacheValue, bool) {
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+	v, ok := (*c)[key]
+	return v, ok
+}
+
+// set caches an item.
+func (c *HoldingsCache) set(key string, v CacheValue) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	(*c)[key] = v
+}
+
 // register reads a holding file from a reader and caches it under the given
 // key. If the given reader is also an io.Close, close it.
 func (c *HoldingsCache) register(key string, r io.Reader) error {
-	if _, ok := (*c)[key]; ok {
+	if _, ok := c.get(key); ok {
 		log.Printf("[holdings] already cached: %s", key)
 		return nil
 	}
 	h := new(kbart.Holdings)
-	if _, err := h.ReadFrom(r); err != nil {
-		return err
+	read := h.ReadFrom
+	if StrictHoldings {
+		read = h.ReadFromStrict
+	}
+	if _, err := read(r); err != nil {
+		return fmt.Errorf("%s: %v", key, err)
 	}
 	// Precompute shortcuts to entries.
-	(*c)[key] = CacheValue{
+	c.set(key, CacheValue{
 		SerialNumberMap: h.SerialNumberMap(),
 		WisoDatabaseMap: h.WisoDatabaseMap(),
 		TitleMap:        h.TitleMap(),
-	}
+	})
 	if rc, ok := r.(io.Closer); ok {
 		return rc.Close()
 	}
 	return nil
 }
 
-// putFile parses a holding file and adds it to the cache.
+// putFile parses a holding file and adds it to the cache. With
+// CompiledHoldingsDir set, a compiled version of the file is used, if
+// possible.
 func (c *HoldingsCache) putFile(filename string) error {
+	if CompiledHoldingsDir != "" {
+		return c.putCompiled(filename)
+	}
+	return c.readFile(filename)
+}
+
+// readFile parses a plain or zipped holding file and adds it to the cache.
+func (c *HoldingsCache) readFile(filename string) error {
 	_, err := zip.OpenReader(filename)
 	if err != nil {
 		file, err := os.Open(filename)
@@ -74,9 +109,24 @@ func (c *HoldingsCache) putLink(link string) error {
 	return c.register(link, &span.ZipOrPlainLinkReader{Link: link})
 }
 
+// put adds a holding file or link to the cache, unless it is already cached.
+func (c *HoldingsCache) put(name string) error {
+	if _, ok := c.get(name); ok {
+		return nil
+	}
+	if strings.Contains(name, "://") {
+		return c.putLink(name)
+	}
+	return c.putFile(name)
+}
+
 // Cache caches holdings information.
 var Cache = make(HoldingsCache)
 
+// StrictHoldings rejects holding files with malformed rows, instead of reading
+// them as far as possible.
+var StrictHoldings = false
+
 // HoldingsFilter compares a record to a kbart file. Since this filter lives in
 // memory and the configuration for a single run (which this filter value is
 // part of) might contain many other holdings filters, we only want to store
@@ -95,11 +145,43 @@ type HoldingsFilter struct {
 // count returns the number of entries loaded for this filter.
 func (f *HoldingsFilter) count() (count int) {
 	for _, name := range f.Names {
-		count += len(Cache[name].SerialNumberMap)
+		item, _ := Cache.get(name)
+		count += len(item.SerialNumberMap)
 	}
 	return
 }
 
+// NewHoldingsFilter returns a filter for a number of holding files, given as
+// filenames or URLs.
+func NewHoldingsFilter(names ...string) (*HoldingsFilter, error) {
+	f := &HoldingsFilter{}
+	if err := f.load(names...); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// load adds holdings files or links to the cache and to this filter.
+func (f *HoldingsFilter) load(names ...string) error {
+	for _, name := range names {
+		// Allow files to appear in urls field (for unfreeze).
+		name = strings.TrimPrefix(name, "file://")
+		if err := Cache.put(name); err != nil {
+			return err
+		}
+		f.Names = append(f.Names, name)
+	}
+	if f.CachedValues == nil {
+		f.CachedValues = make(map[string]*CacheValue)
+	}
+	for _, name := range f.Names {
+		item, _ := Cache.get(name)
+		f.CachedValues[name] = &item
+	}
+	log.Printf("[holdings] loaded %d files or links with %d entries", len(f.Names), f.count())
+	return nil
+}
+
 // UnmarshalJSON deserializes this filter.
 func (f *HoldingsFilter) UnmarshalJSON(p []byte) error {
 	var s struct {
@@ -114,45 +196,15 @@ func (f *HoldingsFilter) UnmarshalJSON(p []byte) error {
 	if err := json.Unmarshal(p, &s); err != nil {
 		return err
 	}
-	for _, fn := range s.Holdings.Filenames {
-		if err := Cache.putFile(fn); err != nil {
-			return err
-		}
-		f.Names = append(f.Names, fn)
-	}
+	names := s.Holdings.Filenames
 	if s.Holdings.Filename != "" {
-		if err := Cache.putFile(s.Holdings.Filename); err != nil {
-			return err
-		}
-		f.Names = append(f.Names, s.Holdings.Filename)
+		names = append(names, s.Holdings.Filename)
 	}
-	for _, link := range s.Holdings.Links {
-		// Allow files to appear in urls field (for unfreeze).
-		if strings.HasPrefix(link, "file://") {
-			filename := strings.Replace(link, "file://", "", 1)
-			if err := Cache.putFile(filename); err != nil {
-				return err
-			}
-			f.Names = append(f.Names, filename)
-		} else {
-			if err := Cache.putLink(link); err != nil {
-				return err
-			}
-			f.Names = append(f.Names, link)
-		}
+	if err := f.load(append(names, s.Holdings.Links...)...); err != nil {
+		return err
 	}
-
 	f.Verbose = s.Holdings.Verbose
 	f.CompareByTitle = s.Holdings.CompareByTitle
-
-	if f.CachedValues == nil {
-		f.CachedValues = make(map[string]*CacheValue)
-	}
-	for _, name := range f.Names {
-		item := Cache[name]
-		f.CachedValues[name] = &item
-	}
-	log.Printf("[holdings] loaded %d files or links with %d entries", len(f.Names), f.count())
 	return nil
 }
 
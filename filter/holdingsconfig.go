@@ -0,0 +1,116 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// HoldingsSource is a holding file or link for an ISIL.
+type HoldingsSource struct {
+	Location string `json:"location"`
+	// Format of the holding file, only "kbart" (default) is supported.
+	Format string `json:"format,omitempty"`
+}
+
+// UnmarshalJSON accepts a plain location as well, e.g. "de15.tsv".
+func (s *HoldingsSource) UnmarshalJSON(p []byte) error {
+	var location string
+	if err := json.Unmarshal(p, &location); err == nil {
+		*s = HoldingsSource{Location: location}
+		return nil
+	}
+	type plain HoldingsSource
+	return json.Unmarshal(p, (*plain)(s))
+}
+
+// HoldingsConfig maps ISIL to holding files or links, e.g.
+//
+//	{
+//	  "DE-15": ["de15.tsv", {"location": "https://example.com/de15.zip", "format": "kbart"}],
+//	  "DE-14": ["https://example.com/de14.tsv"]
+//	}
+type HoldingsConfig map[string][]HoldingsSource
+
+// ReadHoldingsConfig reads and checks a holdings configuration.
+func ReadHoldingsConfig(r io.Reader) (HoldingsConfig, error) {
+	var hc HoldingsConfig
+	if err := json.NewDecoder(r).Decode(&hc); err != nil {
+		return nil, err
+	}
+	for isil, sources := range hc {
+		for _, s := range sources {
+			if s.Location == "" {
+				return nil, fmt.Errorf("%s: holdings location missing", isil)
+			}
+			switch s.Format {
+			case "", "kbart":
+			default:
+				return nil, fmt.Errorf("%s: unsupported holdings format: %s", isil, s.Format)
+			}
+		}
+	}
+	return hc, nil
+}
+
+// Filters loads all holding files and links with a number of workers and
+// returns a holdings filter per ISIL. Files shared by several ISIL are only
+// read once.
+func (hc HoldingsConfig) Filters(numWorkers int) (map[string]*HoldingsFilter, error) {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, sources := range hc {
+		for _, s := range sources {
+			name := strings.TrimPrefix(s.Location, "file://")
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	queue := make(chan string)
+	errc := make(chan error, len(names))
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range queue {
+				if err := Cache.put(name); err != nil {
+					errc <- fmt.Errorf("%s: %v", name, err)
+				}
+			}
+		}()
+	}
+	for _, name := range names {
+		queue <- name
+	}
+	close(queue)
+	wg.Wait()
+	close(errc)
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+
+	filters := make(map[string]*HoldingsFilter)
+	for isil, sources := range hc {
+		var locations []string
+		for _, s := range sources {
+			locations = append(locations, s.Location)
+		}
+		f, err := NewHoldingsFilter(locations...)
+		if err != nil {
+			return nil, err
+		}
+		filters[isil] = f
+	}
+	return filters, nil
+}
@@ -0,0 +1,64 @@
+package filter
+
+import (
+	"encoding/json"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/formats/finc"
+)
+
+// IDFilter allows records with a given finc.id or finc.record_id. Can be used
+// in conjuction with "not" to create blacklists. Identifiers are compared
+// case sensitive.
+type IDFilter struct {
+	Values *container.StringSet
+}
+
+// Apply applies the filter.
+func (f *IDFilter) Apply(is finc.IntermediateSchema) bool {
+	return (is.ID != "" && f.Values.Contains(is.ID)) ||
+		(is.RecordID != "" && f.Values.Contains(is.RecordID))
+}
+
+// UnmarshalJSON turns a config fragment into a filter.
+func (f *IDFilter) UnmarshalJSON(p []byte) error {
+	var s struct {
+		ID struct {
+			Values []string `json:"list"`
+			File   string   `json:"file"`
+			Link   string   `json:"url"`
+		} `json:"id"`
+	}
+	if err := json.Unmarshal(p, &s); err != nil {
+		return err
+	}
+	f.Values = container.NewStringSet()
+
+	if s.ID.Link != "" {
+		slink := span.SavedLink{Link: s.ID.Link}
+		filename, err := slink.Save()
+		if err != nil {
+			return err
+		}
+		defer slink.Remove()
+		s.ID.File = filename
+	}
+	if s.ID.File != "" {
+		lines, err := span.ReadLines(s.ID.File)
+		if err != nil {
+			return err
+		}
+		s.ID.Values = append(s.ID.Values, lines...)
+	}
+	for _, v := range s.ID.Values {
+		if v = strings.TrimSpace(v); v != "" {
+			f.Values.Add(v)
+		}
+	}
+	log.Printf("id: collected %d identifiers", f.Values.Size())
+	return nil
+}
@@ -71,7 +71,9 @@ func (f *ISSNFilter) UnmarshalJSON(p []byte) error {
 		}
 	}
 	// Add any ISSN given as string in configuration.
-	f.Values.AddAll(s.ISSN.Values...)
+	for _, v := range s.ISSN.Values {
+		f.Values.Add(strings.ToUpper(strings.TrimSpace(v)))
+	}
 	log.Printf("issn: collected %d ISSN", f.Values.Size())
 	return nil
 }
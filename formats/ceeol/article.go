@@ -9,6 +9,7 @@ import (
 
 	"github.com/miku/span"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
 	"github.com/shantanubhadoria/go-roman/roman"
 )
 
@@ -84,9 +85,7 @@ func (article *Article) ToIntermediateSchema() (*finc.IntermediateSchema, error)
 		output.OpenAccess = true
 	}
 	output.Issue = article.Issue
-	output.StartPage = article.StartPage
-	output.EndPage = article.EndPage
-	output.PageCount = article.PageCount
+	output.Pages = finc.NewPages(article.StartPage, article.EndPage, article.PageCount)
 	output.Abstract = article.Description
 	output.Publishers = append(output.Publishers, article.Publisher)
 	if article.PublisherEnglish != "" && article.PublisherEnglish != article.Publisher {
@@ -112,7 +111,7 @@ func (article *Article) ToIntermediateSchema() (*finc.IntermediateSchema, error)
 	output.Subjects = article.SubjectTerms
 	output.URL = append(output.URL, article.ArticleURL)
 	output.RecordID = article.UniqueID
-	output.ID = fmt.Sprintf("ai-%s-%s", SourceIdentifier, article.UniqueID)
+	output.ID = mint.ID(SourceIdentifier, article.UniqueID, "", mint.Plain)
 	output.SourceID = SourceIdentifier
 	output.Format = Format
 	output.Genre = Genre
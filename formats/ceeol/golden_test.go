@@ -0,0 +1,11 @@
+package ceeol
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/ceeol", golden.XML("Article", func() golden.Converter { return new(Article) }))
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/miku/span"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
 	"github.com/shantanubhadoria/go-roman/roman"
 )
 
@@ -275,7 +276,7 @@ func (r Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 		return output, span.Skip{Reason: err.Error()}
 	}
 	output.RecordID = v
-	output.ID = fmt.Sprintf("ai-53-%s", output.RecordID)
+	output.ID = mint.ID(SourceIdentifier, output.RecordID, "", mint.Plain)
 
 	v, err = r.Title()
 	if err != nil {
@@ -0,0 +1,114 @@
+package crossref
+
+import "encoding/json"
+
+// FastDecode makes Decode skip fields, that are not needed for conversion.
+// Large parts of works documents, like links, relations and, unless
+// CaptureReferences is set, references, are then scanned, but not allocated.
+var FastDecode = false
+
+// unmarshal decodes JSON, it can be replaced by a faster implementation with
+// the jsoniter build tag.
+var unmarshal = json.Unmarshal
+
+// leanDocument has only the fields of a Document used by ToIntermediateSchema.
+// The types must match those in Document.
+type leanDocument struct {
+	Abstract string `json:"abstract"`
+	Author   []struct {
+		Affiliation []struct {
+			Name string `json:"name"`
+		} `json:"affiliation"`
+		Family string `json:"family"`
+		Given  string `json:"given"`
+		// Name is set for organizations, e.g. consortia.
+		Name  string `json:"name"`
+		ORCID string `json:"ORCID"`
+	} `json:"author"`
+	ContainerTitle []string `json:"container-title"`
+	DOI            string
+	Funder         []struct {
+		Award []string `json:"award"`
+		DOI   string
+		Name  string `json:"name"`
+	} `json:"funder"`
+	ISSN     []string
+	ISBN     []string
+	IsbnType []struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"isbn-type"`
+	Issue    string    `json:"issue"`
+	Issued   DateField `json:"issued"`
+	Language string    `json:"language"`
+	License  []struct {
+		ContentVersion string    `json:"content-version"`
+		DelayInDays    int64     `json:"delay-in-days"`
+		Start          DateField `json:"start"`
+		URL            string
+	} `json:"license"`
+	Member              string    `json:"member"`
+	Page                string    `json:"page"`
+	PublishedPrint      DateField `json:"published-print"`
+	Publisher           string    `json:"publisher"`
+	ShortContainerTitle []string  `json:"short-container-title"`
+	Subject             []string  `json:"subject"`
+	Subtitle            []string  `json:"subtitle"`
+	Title               []string  `json:"title"`
+	Type                string    `json:"type"`
+	URL                 string    `json:"URL"`
+	Volume              string    `json:"volume"`
+}
+
+// leanDocumentReferences adds references, refs. CaptureReferences.
+type leanDocumentReferences struct {
+	leanDocument
+	Reference []struct {
+		DOI string
+		Key string `json:"key"`
+	} `json:"reference"`
+}
+
+// Decode decodes a works document from JSON, like json.Unmarshal, but only
+// the fields needed for conversion, if FastDecode is set.
+func (doc *Document) Decode(b []byte) error {
+	if !FastDecode {
+		return unmarshal(b, doc)
+	}
+	var v leanDocumentReferences
+	var err error
+	if CaptureReferences {
+		err = unmarshal(b, &v)
+	} else {
+		err = unmarshal(b, &v.leanDocument)
+	}
+	if err != nil {
+		return err
+	}
+	l := v.leanDocument
+	doc.Abstract = l.Abstract
+	doc.Author = l.Author
+	doc.ContainerTitle = l.ContainerTitle
+	doc.DOI = l.DOI
+	doc.Funder = l.Funder
+	doc.ISSN = l.ISSN
+	doc.ISBN = l.ISBN
+	doc.IsbnType = l.IsbnType
+	doc.Issue = l.Issue
+	doc.Issued = l.Issued
+	doc.Language = l.Language
+	doc.License = l.License
+	doc.Member = l.Member
+	doc.Page = l.Page
+	doc.PublishedPrint = l.PublishedPrint
+	doc.Publisher = l.Publisher
+	doc.Reference = v.Reference
+	doc.ShortContainerTitle = l.ShortContainerTitle
+	doc.Subject = l.Subject
+	doc.Subtitle = l.Subtitle
+	doc.Title = l.Title
+	doc.Type = l.Type
+	doc.URL = l.URL
+	doc.Volume = l.Volume
+	return nil
+}
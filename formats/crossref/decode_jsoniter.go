@@ -0,0 +1,12 @@
+//go:build jsoniter
+// +build jsoniter
+
+package crossref
+
+import jsoniter "github.com/json-iterator/go"
+
+// Build with -tags jsoniter to decode crossref documents with jsoniter,
+// which is compatible with encoding/json, but faster.
+func init() {
+	unmarshal = jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal
+}
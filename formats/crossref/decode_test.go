@@ -0,0 +1,119 @@
+package crossref
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// modernDocument returns a works document as served today, with many
+// references and links, which are not needed for conversion.
+func modernDocument() []byte {
+	var refs, links []string
+	for i := 0; i < 200; i++ {
+		refs = append(refs, fmt.Sprintf(`{"key": "ref%d", "DOI": "10.1234/ref.%d", "doi-asserted-by": "crossref",
+			"unstructured": "Author A, Author B (2001) A cited work on a topic %d. Journal of Things 12:34-56"}`, i, i, i))
+	}
+	for i := 0; i < 10; i++ {
+		links = append(links, fmt.Sprintf(`{"URL": "https://example.com/fulltext/%d.pdf", "content-type": "application/pdf",
+			"content-version": "vor", "intended-application": "text-mining"}`, i))
+	}
+	return []byte(`{"DOI": "10.1234/abc", "URL": "https://doi.org/10.1234/abc", "type": "journal-article",
+		"title": ["A title"], "subtitle": ["A subtitle"], "container-title": ["A journal"],
+		"publisher": "A publisher", "member": "1234", "ISSN": ["1234-5679"], "issue": "01", "volume": "007",
+		"page": "1-10", "language": "en", "subject": ["Things"],
+		"abstract": "<jats:title>Abstract</jats:title><jats:p>Some text.</jats:p>",
+		"author": [{"given": "A.", "family": "Author", "ORCID": "http://orcid.org/0000-0002-1825-0097",
+			"affiliation": [{"name": "A university"}]}, {"name": "A consortium"}],
+		"funder": [{"name": "A funder", "DOI": "10.13039/1", "award": ["123"]}],
+		"isbn-type": [{"type": "electronic", "value": "9783161484100"}],
+		"license": [{"URL": "https://creativecommons.org/licenses/by/4.0/", "content-version": "vor", "delay-in-days": 0,
+			"start": {"date-parts": [[2019, 1, 1]]}}],
+		"issued": {"date-parts": [[2019, 1, 1]]}, "published-print": {"date-parts": [[2019, 2, 1]]},
+		"indexed": {"date-parts": [[2020, 1, 1]], "date-time": "2020-01-01T00:00:00Z", "timestamp": 1577836800000},
+		"relation": {"cites": []}, "content-domain": {"domain": ["example.com"], "crossmark-restriction": false},
+		"link": [` + strings.Join(links, ",") + `], "reference": [` + strings.Join(refs, ",") + `]}`)
+}
+
+func TestDecode(t *testing.T) {
+	defer func(fast, refs bool) { FastDecode, CaptureReferences = fast, refs }(FastDecode, CaptureReferences)
+	lines := append(readFixture(t), modernDocument())
+	for _, refs := range []bool{false, true} {
+		CaptureReferences = refs
+		for _, line := range lines {
+			var docs [2]Document
+			for i, fast := range []bool{false, true} {
+				FastDecode = fast
+				if err := docs[i].Decode(line); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, wantErr := docs[0].ToIntermediateSchema()
+			got, err := docs[1].ToIntermediateSchema()
+			if (err != nil) != (wantErr != nil) {
+				t.Fatalf("got %v, want %v", err, wantErr)
+			}
+			wb, _ := json.Marshal(want)
+			gb, _ := json.Marshal(got)
+			if !bytes.Equal(gb, wb) {
+				t.Errorf("references %v: got %s, want %s", refs, gb, wb)
+			}
+		}
+	}
+}
+
+func TestCaptureReferences(t *testing.T) {
+	defer func(fast, refs bool) { FastDecode, CaptureReferences = fast, refs }(FastDecode, CaptureReferences)
+	b := []byte(`{"DOI": "10.1234/abc", "URL": "https://doi.org/10.1234/abc", "type": "journal-article",
+		"title": ["A title"], "container-title": ["A journal"], "issued": {"date-parts": [[2019, 1, 1]]},
+		"reference": [{"key": "r1", "DOI": "10.1000/ABC"}, {"key": "r2", "unstructured": "No DOI"},
+			{"key": "r3", "DOI": "10.1000/abc"}, {"key": "r4", "DOI": "https://doi.org/10.1000/xyz"}]}`)
+	for _, fast := range []bool{false, true} {
+		FastDecode = fast
+		for _, refs := range []bool{false, true} {
+			CaptureReferences = refs
+			var doc Document
+			if err := doc.Decode(b); err != nil {
+				t.Fatal(err)
+			}
+			output, err := doc.ToIntermediateSchema()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []string
+			if refs {
+				want = []string{"10.1000/abc", "10.1000/xyz"}
+			}
+			if !reflect.DeepEqual(output.References, want) {
+				t.Errorf("fast %v, references %v: got %q, want %q", fast, refs, output.References, want)
+			}
+			ob, err := json.Marshal(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := bytes.Contains(ob, []byte(`"x.references"`)); got != refs {
+				t.Errorf("fast %v, references %v: got x.references in output %v, want %v", fast, refs, got, refs)
+			}
+		}
+	}
+}
+
+func benchmarkDecode(b *testing.B, fast bool) {
+	defer func(v bool) { FastDecode = v }(FastDecode)
+	FastDecode = fast
+	data := modernDocument()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var doc Document
+		if err := doc.Decode(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B)     { benchmarkDecode(b, false) }
+func BenchmarkDecodeFast(b *testing.B) { benchmarkDecode(b, true) }
@@ -22,17 +22,23 @@
 package crossref
 
 import (
-	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
+	"io"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/miku/span"
 	"github.com/miku/span/assetutil"
+	"github.com/miku/span/dates"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
+	"github.com/miku/span/names"
 )
 
 const (
@@ -65,10 +71,64 @@ var (
 		regexp.MustCompile(`[?]{6,}`),
 	}
 
+	// CaptureReferences enables capturing cited DOIs into x.references. Off
+	// by default, since it increases the output size considerably.
+	CaptureReferences = false
+
+	// orcidPattern matches a bare ORCID, e.g. 0000-0002-1825-0097.
+	orcidPattern = regexp.MustCompile(`^[0-9]{4}-[0-9]{4}-[0-9]{4}-[0-9]{3}[0-9X]$`)
+
+	// jatsTitle matches a leading, generic abstract heading.
+	jatsTitle = regexp.MustCompile(`^\s*<jats:title>\s*(?i:abstract|summary)\s*</jats:title>`)
+	// blockTag matches tags that separate words, markupTag any other tag.
+	blockTag  = regexp.MustCompile(`</?(jats:)?(p|title|sec|list|list-item|break|br)\b[^>]*>`)
+	markupTag = regexp.MustCompile(`<[^>]*>`)
+
+	// Members resolves member identifiers to collection names, optional. If
+	// nil or the lookup fails, the publisher name is used.
+	Members *MemberResolver
+
 	// Future ends soon.
 	Future = time.Now().Add(time.Hour * 24 * 365 * 2)
+
+	// publisherBlacklist lists test publishers, whose records are skipped.
+	publisherBlacklist = []string{"Crossref Testing", "test", "crossref-test"}
+
+	// documentPool holds released documents for reuse.
+	documentPool = sync.Pool{New: func() interface{} { return new(Document) }}
 )
 
+// TypeMapping maps a crossref type to finc format, genre and RIS reftype.
+type TypeMapping struct {
+	Format  string `json:"format"`
+	Genre   string `json:"genre"`
+	RefType string `json:"reftype"`
+}
+
+// LoadTypeMapping reads a JSON object from crossref type to TypeMapping, e.g.
+// {"dataset": {"format": "ElectronicResourceRemoteAccess", "genre":
+// "unknown", "reftype": "DATA"}}, and overrides the default mappings from
+// assets/crossref. Empty values keep the default. Not safe for concurrent use
+// with conversions, call it once at startup.
+func LoadTypeMapping(r io.Reader) error {
+	var mapping map[string]TypeMapping
+	if err := json.NewDecoder(r).Decode(&mapping); err != nil {
+		return err
+	}
+	for typ, m := range mapping {
+		if m.Format != "" {
+			Formats[typ] = m.Format
+		}
+		if m.Genre != "" {
+			Genres[typ] = m.Genre
+		}
+		if m.RefType != "" {
+			RefTypes[typ] = m.RefType
+		}
+	}
+	return nil
+}
+
 // BulkResponse for a bulk request containing multiple items.
 type BulkResponse struct {
 	Status         string `json:"status"`
@@ -95,21 +155,37 @@ type DateField struct {
 type Document struct {
 	Abstract string `json:"abstract"`
 	Author   []struct {
+		Affiliation []struct {
+			Name string `json:"name"`
+		} `json:"affiliation"`
 		Family string `json:"family"`
 		Given  string `json:"given"`
+		// Name is set for organizations, e.g. consortia.
+		Name  string `json:"name"`
+		ORCID string `json:"ORCID"`
 	} `json:"author"`
 	ContainerTitle []string `json:"container-title"`
 	ContentDomain  struct {
 		CrossmarkRestriction bool          `json:"crossmark-restriction"`
 		Domain               []interface{} `json:"domain"`
 	} `json:"content-domain"`
-	Created             DateField `json:"created"`
-	DOI                 string
-	Deposited           DateField `json:"deposited"`
+	Created   DateField `json:"created"`
+	DOI       string
+	Deposited DateField `json:"deposited"`
+	Funder    []struct {
+		Award []string `json:"award"`
+		DOI   string
+		Name  string `json:"name"`
+	} `json:"funder"`
 	ISSN                []string
 	Indexed             DateField `json:"indexed"`
-	IsReferencedByCount int64     `json:"is-referenced-by-count"`
-	IssnType            []struct {
+	ISBN                []string
+	IsReferencedByCount int64 `json:"is-referenced-by-count"`
+	IsbnType            []struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"isbn-type"`
+	IssnType []struct {
 		Type  string `json:"type"`
 		Value string `json:"value"`
 	} `json:"issn-type"`
@@ -140,7 +216,11 @@ type Document struct {
 	Publisher       string        `json:"publisher"`
 	ReferenceCount  int64         `json:"reference-count"`
 	ReferencesCount int64         `json:"references-count"`
-	Relation        struct {
+	Reference       []struct {
+		DOI string
+		Key string `json:"key"`
+	} `json:"reference"`
+	Relation struct {
 	} `json:"relation"`
 	Score               float64     `json:"score"`
 	ShortContainerTitle []string    `json:"short-container-title"`
@@ -154,62 +234,190 @@ type Document struct {
 	Volume              string      `json:"volume"`
 }
 
-// PageInfo holds various page related data.
-type PageInfo struct {
-	RawMessage string
-	StartPage  int
-	EndPage    int
+// NewDocument returns an empty document, reusing a released one, if
+// possible. Decoding many documents this way saves allocations.
+func NewDocument() *Document {
+	return documentPool.Get().(*Document)
 }
 
-// PageCount returns the number of pages, or zero if this cannot be determined.
-func (pi *PageInfo) PageCount() int {
-	if pi.StartPage != 0 && pi.EndPage != 0 {
-		// an article, that starts at page 19 and ends at page 19 has one page
-		count := pi.EndPage - pi.StartPage + 1
-		if count > 0 {
-			return count
-		}
-	}
-	return 0
+// Release resets the document and returns it for reuse by NewDocument. The
+// document must not be used afterwards. Converted records may share strings
+// and slices with the document, which are not reused, so they remain valid.
+func (doc *Document) Release() {
+	*doc = Document{}
+	documentPool.Put(doc)
 }
 
+// Authors returns the authors, with ORCID and affiliations, if available.
+// Organizations are returned as corporate authors.
 func (doc *Document) Authors() (authors []finc.Author) {
 	for _, ra := range doc.Author {
-		authors = append(authors, finc.Author{
-			FirstName: AuthorReplacer.Replace(span.UnescapeTrim(ra.Given)),
-			LastName:  AuthorReplacer.Replace(span.UnescapeTrim(ra.Family)),
-		})
+		author := finc.Author{
+			FirstName: names.StripTitles(names.Clean(AuthorReplacer.Replace(span.UnescapeTrim(ra.Given)))),
+			LastName:  names.Clean(AuthorReplacer.Replace(span.UnescapeTrim(ra.Family))),
+			ORCID:     normalizeORCID(ra.ORCID),
+		}
+		if author.FirstName == "" && author.LastName == "" {
+			author.Corporate = names.Clean(span.UnescapeTrim(ra.Name))
+		}
+		for _, aff := range ra.Affiliation {
+			if name := span.UnescapeTrim(aff.Name); name != "" {
+				author.Affiliations = append(author.Affiliations, name)
+			}
+		}
+		authors = append(authors, author)
 	}
 	return authors
 }
 
+// Funders returns funders and awards. Funder DOIs are normalized, invalid
+// ones dropped.
+func (doc *Document) Funders() (funders []finc.Funder) {
+	for _, f := range doc.Funder {
+		funder := finc.Funder{Name: span.UnescapeTrim(f.Name), DOI: doi.Clean(f.DOI)}
+		if funder.Name == "" && funder.DOI == "" {
+			continue
+		}
+		for _, award := range f.Award {
+			if award = strings.TrimSpace(award); award != "" {
+				funder.Awards = append(funder.Awards, award)
+			}
+		}
+		funders = append(funders, funder)
+	}
+	return funders
+}
+
+// AbstractText returns the abstract as plain text. Crossref abstracts come
+// with JATS markup, e.g. <jats:title>Abstract</jats:title><jats:p>...</jats:p>.
+// A leading generic heading is dropped, all other tags are removed.
+func (doc *Document) AbstractText() string {
+	s := jatsTitle.ReplaceAllString(doc.Abstract, "")
+	s = blockTag.ReplaceAllString(s, " ")
+	s = markupTag.ReplaceAllString(s, "")
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+// ISBNs returns print and electronic ISBN as hyphenated ISBN-13. If the type
+// is not known, an ISBN is considered print. Invalid ISBN are dropped.
+func (doc *Document) ISBNs() (isbn, eisbn []string) {
+	typed := make(map[string]bool)
+	for _, t := range doc.IsbnType {
+		typed[t.Value] = true
+		v := span.NormalizeISBN(t.Value)
+		if v == "" {
+			continue
+		}
+		switch t.Type {
+		case "electronic":
+			eisbn = append(eisbn, v)
+		default:
+			isbn = append(isbn, v)
+		}
+	}
+	for _, v := range doc.ISBN {
+		if typed[v] {
+			continue
+		}
+		if v := span.NormalizeISBN(v); v != "" {
+			isbn = append(isbn, v)
+		}
+	}
+	return isbn, eisbn
+}
+
+// References returns the normalized and deduplicated DOIs of cited works.
+// References without a valid DOI are ignored.
+func (doc *Document) References() (dois []string) {
+	seen := make(map[string]bool)
+	for _, ref := range doc.Reference {
+		v := doi.Clean(ref.DOI)
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		dois = append(dois, v)
+	}
+	return dois
+}
+
+// Licenses returns the deduplicated license URLs.
+func (doc *Document) Licenses() (licenses []string) {
+	seen := make(map[string]bool)
+	for _, l := range doc.License {
+		u := strings.TrimSpace(l.URL)
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		licenses = append(licenses, u)
+	}
+	return licenses
+}
+
+// OAStatus guesses the open access status from license information. An
+// immediate creative commons license on the published version means hybrid
+// (gold would require the journal to be in DOAJ, which we do not know here,
+// refs. openaccess.List.ReadKBART), on the accepted manuscript green. Other
+// licenses are considered closed. Returns the empty string, if there is no
+// license information.
+func (doc *Document) OAStatus() string {
+	var status string
+	for _, l := range doc.License {
+		if !strings.Contains(strings.ToLower(l.URL), "creativecommons.org") || l.DelayInDays > 0 {
+			if status == "" {
+				status = finc.OAStatusClosed
+			}
+			continue
+		}
+		switch l.ContentVersion {
+		case "vor", "unspecified", "":
+			return finc.OAStatusHybrid
+		case "am":
+			status = finc.OAStatusGreen
+		}
+	}
+	return status
+}
+
+// normalizeORCID turns "http://orcid.org/0000-0002-1825-0097" into
+// "0000-0002-1825-0097". Returns the empty string for malformed values or
+// values with a wrong check digit.
+func normalizeORCID(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		s = s[i+1:]
+	}
+	if !orcidPattern.MatchString(s) {
+		return ""
+	}
+	// ISO 7064 11,2 check digit, https://support.orcid.org/hc/en-us/articles/360006897674
+	var total int
+	for _, c := range s[:len(s)-1] {
+		if c == '-' {
+			continue
+		}
+		total = (total + int(c-'0')) * 2
+	}
+	check := "0123456789X"[(12-total%11)%11]
+	if s[len(s)-1] != check {
+		return ""
+	}
+	return s
+}
+
 // ID is of the form <kind>-<source-id>-<id-base64-unpadded>
 // We simple map any primary key of the source (preferably a URL)
 // to a safer alphabet. Since the base64 part is not meant to be decoded
 // we drop the padding. It is simple enough to recover the original value.
+// Another strategy can be configured, see package mint.
 func (doc *Document) ID() string {
-	return fmt.Sprintf("ai-%s-%s", SourceID, base64.RawURLEncoding.EncodeToString([]byte(doc.URL)))
+	return mint.ID(SourceID, doc.URL, doc.DOI, mint.Base64)
 }
 
 // PageInfo parses a page specfication in a best effort manner into a PageInfo struct.
 func (doc *Document) PageInfo() PageInfo {
-	pi := PageInfo{RawMessage: doc.Page}
-	parts := strings.Split(doc.Page, "-")
-	if len(parts) != 2 {
-		return pi
-	}
-	spage, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return pi
-	}
-	pi.StartPage = spage
-
-	epage, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return pi
-	}
-	pi.EndPage = epage
-	return pi
+	return ParsePages(doc.Page)
 }
 
 // Date returns a time.Date in a best effort manner. Date parts seem to be always
@@ -220,26 +428,18 @@ func (d *DateField) Date() (t time.Time, err error) {
 	if len(d.DateParts) == 0 {
 		return t, errNoDate
 	}
-	parts := d.DateParts[0]
-	var ds string
-	switch len(parts) {
-	case 1:
-		ds = fmt.Sprintf("%04d-01-01", parts[0])
-	case 2:
-		ds = fmt.Sprintf("%04d-%02d-01", parts[0], parts[1])
-	case 3:
-		ds = fmt.Sprintf("%04d-%02d-%02d", parts[0], parts[1], parts[2])
-	default:
-		return t, nil
+	date, err := dates.FromParts(d.DateParts[0]...)
+	if err != nil {
+		return t, err
 	}
-	return time.Parse("2006-01-02", ds)
+	return date.Time, nil
 }
 
 // CombinedTitle returns a longish title.
 func (doc *Document) CombinedTitle() string {
 	if len(doc.Title) > 0 {
 		if len(doc.Subtitle) > 0 {
-			return span.UnescapeTrim(fmt.Sprintf("%s : %s", strings.Join(doc.Title, " "), strings.Join(doc.Subtitle, " ")))
+			return span.UnescapeTrim(strings.Join(doc.Title, " ") + " : " + strings.Join(doc.Subtitle, " "))
 		}
 		return span.UnescapeTrim(strings.Join(doc.Title, " "))
 	}
@@ -249,6 +449,35 @@ func (doc *Document) CombinedTitle() string {
 	return ""
 }
 
+// JournalTitles returns the full and the abbreviated journal title. Some
+// records list an abbreviation next to the full title in container-title, in
+// any order, so the longest title counts as full title, and the shortest as
+// abbreviation, unless short-container-title is given.
+func (doc *Document) JournalTitles() (full, short string) {
+	for _, s := range doc.ContainerTitle {
+		s = span.UnescapeTrim(s)
+		if s == "" {
+			continue
+		}
+		if len(s) > len(full) {
+			full = s
+		}
+		if short == "" || len(s) < len(short) {
+			short = s
+		}
+	}
+	for _, s := range doc.ShortContainerTitle {
+		if s = span.UnescapeTrim(s); s != "" {
+			short = s
+			break
+		}
+	}
+	if strings.EqualFold(short, full) {
+		short = ""
+	}
+	return full, short
+}
+
 // FindShortTitle returns the first main title only.
 func (doc *Document) FindShortTitle() (s string) {
 	if len(doc.Title) > 0 {
@@ -290,9 +519,6 @@ func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	}
 
 	output.ID = doc.ID()
-	if len(output.ID) > span.KeyLengthLimit {
-		return output, span.Skip{Reason: fmt.Sprintf("ID_TOO_LONG %s", output.ID)}
-	}
 
 	if output.Date.After(Future) {
 		return output, span.Skip{Reason: fmt.Sprintf("TOO_FUTURISTIC %s", output.ID)}
@@ -322,7 +548,10 @@ func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 		return output, span.Skip{Reason: fmt.Sprintf("TOO_LONG_TITLE %s", output.ID)}
 	}
 
-	output.DOI = doc.DOI // refs #6312 and #10923, most // URL seem valid
+	// refs #6312 and #10923, most // URL seem valid
+	if output.DOI = doi.Clean(doc.DOI); output.DOI == "" {
+		return output, span.Skip{Reason: fmt.Sprintf("INVALID_DOI %s", output.ID)}
+	}
 	output.Format = Formats.LookupDefault(doc.Type, DefaultFormat)
 	output.Genre = Genres.LookupDefault(doc.Type, "unknown")
 	output.ISSN = doc.ISSN
@@ -331,20 +560,38 @@ func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output.Publishers = append(output.Publishers, doc.Publisher)
 	output.RefType = RefTypes.LookupDefault(doc.Type, "GEN")
 	output.SourceID = SourceID
-	output.Subjects = doc.Subject
+	output.Subjects = NormalizeSubjects(doc.Subject)
 	output.Type = doc.Type
 	output.URL = append(output.URL, doc.URL)
 	output.Volume = strings.TrimLeft(doc.Volume, "0")
 
-	if len(doc.ContainerTitle) > 0 {
-		output.JournalTitle = span.UnescapeTrim(doc.ContainerTitle[0])
-	} else {
-		return output, span.Skip{Reason: fmt.Sprintf("NO_JTITLE %s", output.ID)}
-	}
+	output.ISBN, output.EISBN = doc.ISBNs()
 
-	// refs #10864
-	if strings.HasPrefix(doc.Type, "book-") {
-		output.ArticleTitle = fmt.Sprintf("%s: %s", output.JournalTitle, output.ArticleTitle)
+	// Chapters belong to a book, monographs are books; everything else
+	// belongs to a journal (or some other container).
+	switch doc.Type {
+	case "book-chapter", "book-part", "book-section":
+		if len(doc.ContainerTitle) == 0 {
+			return output, span.Skip{Reason: fmt.Sprintf("NO_BTITLE %s", output.ID)}
+		}
+		output.BookTitle = span.UnescapeTrim(doc.ContainerTitle[0])
+		// refs #10864
+		output.ArticleTitle = fmt.Sprintf("%s: %s", output.BookTitle, output.ArticleTitle)
+	case "monograph":
+		output.BookTitle = output.ArticleTitle
+		if len(doc.ContainerTitle) > 0 {
+			output.Series = span.UnescapeTrim(doc.ContainerTitle[0])
+		}
+	default:
+		if len(doc.ContainerTitle) > 0 {
+			output.JournalTitle, output.ShortTitle = doc.JournalTitles()
+		} else {
+			return output, span.Skip{Reason: fmt.Sprintf("NO_JTITLE %s", output.ID)}
+		}
+		// refs #10864
+		if strings.HasPrefix(doc.Type, "book-") {
+			output.ArticleTitle = fmt.Sprintf("%s: %s", output.JournalTitle, output.ArticleTitle)
+		}
 	}
 
 	if len(doc.Subtitle) > 0 {
@@ -352,6 +599,12 @@ func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	}
 
 	output.Authors = doc.Authors()
+	output.Funders = doc.Funders()
+	output.License = doc.Licenses()
+	output.OAStatus = doc.OAStatus()
+	if CaptureReferences {
+		output.References = doc.References()
+	}
 
 	// TODO(miku): do we need a config for these things?
 	// Maybe a generic filter (in js?) that will gather exclusion rules?
@@ -360,33 +613,33 @@ func (doc *Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	// }
 
 	pi := doc.PageInfo()
-	output.StartPage = fmt.Sprintf("%d", pi.StartPage)
-	output.EndPage = fmt.Sprintf("%d", pi.EndPage)
-	output.Pages = pi.RawMessage
-	output.PageCount = fmt.Sprintf("%d", pi.PageCount())
+	output.Pages = finc.Pages{Start: pi.First, End: pi.Last, Count: pi.PageCount(), Raw: pi.RawMessage}
 
 	// TODO: use a file for this
-	publisherBlacklist := []string{
-		"Crossref Testing",
-		"test",
-		"crossref-test",
-	}
-
 	for _, s := range publisherBlacklist {
 		if doc.Publisher == s {
 			return output, span.Skip{Reason: fmt.Sprintf("BLACKLISTED_COLLECTION %s", output.ID)}
 		}
 	}
 
-	if doc.Publisher == "" {
-		output.MegaCollections = []string{fmt.Sprintf("X-U (CrossRef)")}
-	} else {
+	var member string
+	if Members != nil && doc.Member != "" {
+		// Failed lookups are counted by the resolver and reported by the caller.
+		member, _ = Members.Lookup(doc.Member)
+	}
+
+	switch {
+	case member != "":
+		output.MegaCollections = []string{member + " (CrossRef)"}
+	case doc.Publisher == "":
+		output.MegaCollections = []string{"X-U (CrossRef)"}
+	default:
 		publisher := span.UnescapeTrim(strings.Replace(doc.Publisher, "\n", " ", -1))
-		output.MegaCollections = []string{fmt.Sprintf("%s (CrossRef)", publisher)}
+		output.MegaCollections = []string{publisher + " (CrossRef)"}
 	}
 
 	// refs. #13613
-	output.Abstract = doc.Abstract
+	output.Abstract = doc.AbstractText()
 
 	return output, nil
 }
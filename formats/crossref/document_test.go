@@ -0,0 +1,400 @@
+package crossref
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/formats/finc"
+)
+
+func TestAbstractText(t *testing.T) {
+	var cases = []struct {
+		abstract string
+		result   string
+	}{
+		{"", ""},
+		{"Plain text.", "Plain text."},
+		{"<jats:title>Abstract</jats:title><jats:p>Some <jats:italic>text</jats:italic>.</jats:p>", "Some text."},
+		{"<jats:p>A</jats:p>\n<jats:p>B &amp; C</jats:p>", "A B & C"},
+		{"<jats:title>Background</jats:title><jats:p>A</jats:p>", "Background A"},
+		{"<jats:sec><jats:title>ABSTRACT</jats:title></jats:sec>", "ABSTRACT"},
+		{"<p>H<sub>2</sub>O</p>", "H2O"},
+	}
+	for _, c := range cases {
+		doc := Document{Abstract: c.abstract}
+		if r := doc.AbstractText(); r != c.result {
+			t.Errorf("AbstractText(%q): got %q, want %q", c.abstract, r, c.result)
+		}
+	}
+}
+
+func TestParsePages(t *testing.T) {
+	var cases = []struct {
+		s           string
+		first, last string
+		start, end  int
+		count       int
+	}{
+		{"", "", "", 0, 0, 0},
+		{"123-130", "123", "130", 123, 130, 8},
+		{"19-19", "19", "19", 19, 19, 1},
+		{"19", "19", "19", 19, 19, 1},
+		{"xii-xv", "xii", "xv", 12, 15, 4},
+		{"IX-XI", "IX", "XI", 9, 11, 3},
+		{"e0123456", "e0123456", "e0123456", 123456, 123456, 1},
+		{"S12-S19", "S12", "S19", 12, 19, 8},
+		{"1-5, 12-13", "1", "13", 1, 13, 7},
+		{"1–5", "1", "5", 1, 5, 5},
+		{"130-123", "130", "123", 130, 123, 0},
+		{"S12-19", "", "", 0, 0, 0},
+		{"1-2-3", "", "", 0, 0, 0},
+		{"n/a", "", "", 0, 0, 0},
+	}
+	for _, c := range cases {
+		pi := ParsePages(c.s)
+		if pi.First != c.first || pi.Last != c.last || pi.StartPage != c.start ||
+			pi.EndPage != c.end || pi.PageCount() != c.count {
+			t.Errorf("ParsePages(%q): got %s %s %d %d %d, want %s %s %d %d %d", c.s,
+				pi.First, pi.Last, pi.StartPage, pi.EndPage, pi.PageCount(),
+				c.first, c.last, c.start, c.end, c.count)
+		}
+	}
+}
+
+// readFixture returns the lines of the crossref fixture.
+func readFixture(tb testing.TB) [][]byte {
+	f, err := os.Open("../../fixtures/crossref/works.ldj")
+	if err != nil {
+		tb.Skipf("fixture: %v", err)
+	}
+	defer f.Close()
+	var lines [][]byte
+	br := bufio.NewScanner(f)
+	br.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for br.Scan() {
+		lines = append(lines, append([]byte(nil), br.Bytes()...))
+	}
+	if err := br.Err(); err != nil {
+		tb.Fatal(err)
+	}
+	return lines
+}
+
+// convertLine decodes and converts a line, as span-import does.
+func convertLine(doc *Document, b []byte) ([]byte, error) {
+	if err := json.Unmarshal(b, doc); err != nil {
+		return nil, err
+	}
+	output, err := doc.ToIntermediateSchema()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(output)
+}
+
+func TestRelease(t *testing.T) {
+	for _, line := range readFixture(t) {
+		want, wantErr := convertLine(new(Document), line)
+		// Converted twice with a released document, results must not differ.
+		for i := 0; i < 2; i++ {
+			doc := NewDocument()
+			if !reflect.DeepEqual(*doc, Document{}) {
+				t.Fatalf("NewDocument: got non-empty document %s", doc.DOI)
+			}
+			got, err := convertLine(doc, line)
+			doc.Release()
+			if (err != nil) != (wantErr != nil) || !bytes.Equal(got, want) {
+				t.Errorf("got %s, %v, want %s, %v", got, err, want, wantErr)
+			}
+		}
+	}
+}
+
+func BenchmarkConvert(b *testing.B) {
+	lines := readFixture(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			convertLine(new(Document), line)
+		}
+	}
+}
+
+func BenchmarkConvertPooled(b *testing.B) {
+	lines := readFixture(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			doc := NewDocument()
+			convertLine(doc, line)
+			doc.Release()
+		}
+	}
+}
+
+func TestNormalizeSubjects(t *testing.T) {
+	var cases = []struct {
+		subjects []string
+		want     []string
+	}{
+		{nil, nil},
+		{[]string{"General Medicine"}, nil},
+		{[]string{" Oncology ", "oncology", "ONCOLOGY"}, []string{"Oncology"}},
+		{[]string{"Cancer  Research", "General", "Ecology, Evolution &amp; Behavior", ""},
+			[]string{"Cancer Research", "Ecology, Evolution & Behavior"}},
+	}
+	for _, c := range cases {
+		if got := NormalizeSubjects(c.subjects); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("NormalizeSubjects(%q): got %q, want %q", c.subjects, got, c.want)
+		}
+	}
+}
+
+func TestJournalTitles(t *testing.T) {
+	var cases = []struct {
+		doc   Document
+		full  string
+		short string
+	}{
+		{Document{}, "", ""},
+		{Document{ContainerTitle: []string{"Nature"}}, "Nature", ""},
+		{Document{ContainerTitle: []string{"J Investig Dermatol", "Journal of Investigative Dermatology"}},
+			"Journal of Investigative Dermatology", "J Investig Dermatol"},
+		{Document{ContainerTitle: []string{"Substance Use &amp; Misuse", "Subst Use Misuse"}},
+			"Substance Use & Misuse", "Subst Use Misuse"},
+		{Document{ContainerTitle: []string{"Journal of Applied Physics"}, ShortContainerTitle: []string{"", "J. Appl. Phys."}},
+			"Journal of Applied Physics", "J. Appl. Phys."},
+		{Document{ContainerTitle: []string{"Nature"}, ShortContainerTitle: []string{"nature"}}, "Nature", ""},
+	}
+	for _, c := range cases {
+		full, short := c.doc.JournalTitles()
+		if full != c.full || short != c.short {
+			t.Errorf("JournalTitles(%q, %q): got %q, %q, want %q, %q",
+				c.doc.ContainerTitle, c.doc.ShortContainerTitle, full, short, c.full, c.short)
+		}
+	}
+}
+
+// testDocument returns a minimal document, that can be converted.
+func testDocument(doi, typ string) Document {
+	doc := Document{
+		DOI:            doi,
+		URL:            "http://dx.doi.org/" + doi,
+		Title:          []string{"T"},
+		ContainerTitle: []string{"J"},
+		Type:           typ,
+	}
+	doc.Issued.DateParts = []DatePart{{2001, 1, 1}}
+	return doc
+}
+
+func TestToIntermediateSchemaDOI(t *testing.T) {
+	var cases = []struct {
+		doi  string
+		want string
+		skip bool
+	}{
+		{"10.1000/ABC", "10.1000/abc", false},
+		{"https://doi.org/10.1000/x", "10.1000/x", false},
+		{" 10.1000/x ", "10.1000/x", false},
+		{"", "", true},
+		{"not a doi", "", true},
+	}
+	for _, c := range cases {
+		doc := testDocument(c.doi, "journal-article")
+		output, err := doc.ToIntermediateSchema()
+		if c.skip {
+			if _, ok := err.(span.Skip); !ok {
+				t.Errorf("ToIntermediateSchema(%q): got %v, want skip", c.doi, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ToIntermediateSchema(%q): got %v, want nil", c.doi, err)
+			continue
+		}
+		if output.DOI != c.want {
+			t.Errorf("ToIntermediateSchema(%q): got DOI %q, want %q", c.doi, output.DOI, c.want)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	var doc Document
+	b := []byte(`{"reference": [{"DOI": "10.1000/ABC"}, {"key": "ref2"}, {"DOI": "10.1000/abc"},
+		{"DOI": "invalid"}, {"DOI": "https://doi.org/10.1000/x"}]}`)
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.1000/abc", "10.1000/x"}
+	if got := doc.References(); !reflect.DeepEqual(got, want) {
+		t.Errorf("References: got %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeORCID(t *testing.T) {
+	var cases = []struct {
+		s    string
+		want string
+	}{
+		{"", ""},
+		{"http://orcid.org/0000-0002-1825-0097", "0000-0002-1825-0097"},
+		{"https://orcid.org/0000-0002-1694-233x", "0000-0002-1694-233X"},
+		{" 0000-0002-1825-0097 ", "0000-0002-1825-0097"},
+		{"0000-0002-1825-0098", ""},
+		{"0000-0002-1825-009", ""},
+		{"0000_0002_1825_0097", ""},
+		{"http://orcid.org/", ""},
+	}
+	for _, c := range cases {
+		if got := normalizeORCID(c.s); got != c.want {
+			t.Errorf("normalizeORCID(%q): got %q, want %q", c.s, got, c.want)
+		}
+	}
+}
+
+func TestAuthors(t *testing.T) {
+	var doc Document
+	b := []byte(`{"author": [
+		{"given": "Josiah", "family": "Carberry", "ORCID": "http://orcid.org/0000-0002-1825-0097",
+		 "affiliation": [{"name": "Brown University"}, {"name": " "}, {"name": "Wesleyan &amp; Co"}]},
+		{"given": "Jane", "family": "Doe", "ORCID": "0000-0002-1825-0098"},
+		{"name": "The Example Consortium"}]}`)
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	want := []finc.Author{
+		{FirstName: "Josiah", LastName: "Carberry", ORCID: "0000-0002-1825-0097",
+			Affiliations: []string{"Brown University", "Wesleyan & Co"}},
+		{FirstName: "Jane", LastName: "Doe"},
+		{Corporate: "The Example Consortium"},
+	}
+	if got := doc.Authors(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Authors: got %+v, want %+v", got, want)
+	}
+}
+
+func TestFunders(t *testing.T) {
+	var doc Document
+	b := []byte(`{"funder": [
+		{"name": "National Science Foundation", "DOI": "10.13039/100000001", "award": ["CHE-1234", " ", "CHE-5678 "]},
+		{"DOI": "https://doi.org/10.13039/501100001659", "award": []},
+		{"name": "Example &amp; Trust", "DOI": "n/a"},
+		{"name": " ", "award": ["orphan"]}]}`)
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	want := []finc.Funder{
+		{Name: "National Science Foundation", DOI: "10.13039/100000001", Awards: []string{"CHE-1234", "CHE-5678"}},
+		{DOI: "10.13039/501100001659"},
+		{Name: "Example & Trust"},
+	}
+	if got := doc.Funders(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Funders: got %+v, want %+v", got, want)
+	}
+}
+
+func TestLicensesAndOAStatus(t *testing.T) {
+	var cases = []struct {
+		about    string
+		license  string
+		licenses []string
+		status   string
+	}{
+		{"none", `[]`, nil, ""},
+		{"cc on published version", `[{"URL": "http://creativecommons.org/licenses/by/4.0/", "content-version": "vor", "delay-in-days": 0}]`,
+			[]string{"http://creativecommons.org/licenses/by/4.0/"}, finc.OAStatusHybrid},
+		{"cc unspecified", `[{"URL": "https://creativecommons.org/licenses/by-nc/4.0", "content-version": "unspecified"}]`,
+			[]string{"https://creativecommons.org/licenses/by-nc/4.0"}, finc.OAStatusHybrid},
+		{"cc on accepted manuscript", `[{"URL": "http://www.elsevier.com/tdm/userlicense/1.0/", "content-version": "tdm"},
+			{"URL": "http://creativecommons.org/licenses/by-nc-nd/4.0/", "content-version": "am", "delay-in-days": 0}]`,
+			[]string{"http://www.elsevier.com/tdm/userlicense/1.0/", "http://creativecommons.org/licenses/by-nc-nd/4.0/"}, finc.OAStatusGreen},
+		{"accepted manuscript, then published version", `[{"URL": "http://creativecommons.org/licenses/by/4.0/", "content-version": "am"},
+			{"URL": "http://creativecommons.org/licenses/by/4.0/", "content-version": "vor"}]`,
+			[]string{"http://creativecommons.org/licenses/by/4.0/"}, finc.OAStatusHybrid},
+		{"cc with embargo", `[{"URL": "http://creativecommons.org/licenses/by/4.0/", "content-version": "vor", "delay-in-days": 365}]`,
+			[]string{"http://creativecommons.org/licenses/by/4.0/"}, finc.OAStatusClosed},
+		{"publisher license", `[{"URL": "http://www.springer.com/tdm", "content-version": "vor"}, {"URL": " "}]`,
+			[]string{"http://www.springer.com/tdm"}, finc.OAStatusClosed},
+	}
+	for _, c := range cases {
+		var doc Document
+		if err := json.Unmarshal([]byte(`{"license": `+c.license+`}`), &doc); err != nil {
+			t.Fatalf("%s: %v", c.about, err)
+		}
+		if got := doc.Licenses(); !reflect.DeepEqual(got, c.licenses) {
+			t.Errorf("%s: Licenses: got %q, want %q", c.about, got, c.licenses)
+		}
+		if got := doc.OAStatus(); got != c.status {
+			t.Errorf("%s: OAStatus: got %q, want %q", c.about, got, c.status)
+		}
+	}
+}
+
+func TestTypeMapping(t *testing.T) {
+	defer func(formats, genres, reftypes map[string]string) {
+		Formats, Genres, RefTypes = formats, genres, reftypes
+	}(Formats, Genres, RefTypes)
+	clone := func(m map[string]string) map[string]string {
+		c := make(map[string]string)
+		for k, v := range m {
+			c[k] = v
+		}
+		return c
+	}
+	Formats, Genres, RefTypes = clone(Formats), clone(Genres), clone(RefTypes)
+
+	type mapping struct{ format, genre, reftype string }
+	convert := func(typ string) mapping {
+		doc := testDocument("10.1000/x", typ)
+		output, err := doc.ToIntermediateSchema()
+		if err != nil {
+			t.Fatalf("%s: %v", typ, err)
+		}
+		return mapping{output.Format, output.Genre, output.RefType}
+	}
+	var cases = []struct {
+		typ  string
+		want mapping
+	}{
+		{"book-chapter", mapping{"ElectronicBookPart", "bookitem", "ECHAP"}},
+		{"proceedings-article", mapping{"ElectronicProceeding", "proceeding", "CONF"}},
+		{"dataset", mapping{"ElectronicResourceRemoteAccess", "document", "DATA"}},
+		{"no-such-type", mapping{"ElectronicArticle", "unknown", "GEN"}},
+	}
+	for _, c := range cases {
+		if got := convert(c.typ); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.typ, got, c.want)
+		}
+	}
+
+	// Overrides, as given with span-import -crossref-types. Empty values keep
+	// the default.
+	override := `{"dataset": {"format": "Dataset", "genre": "", "reftype": "DBASE"},
+		"no-such-type": {"format": "ElectronicResource", "genre": "document", "reftype": "ELEC"}}`
+	if err := LoadTypeMapping(strings.NewReader(override)); err != nil {
+		t.Fatal(err)
+	}
+	cases = []struct {
+		typ  string
+		want mapping
+	}{
+		{"book-chapter", mapping{"ElectronicBookPart", "bookitem", "ECHAP"}},
+		{"dataset", mapping{"Dataset", "document", "DBASE"}},
+		{"no-such-type", mapping{"ElectronicResource", "document", "ELEC"}},
+	}
+	for _, c := range cases {
+		if got := convert(c.typ); got != c.want {
+			t.Errorf("override %s: got %v, want %v", c.typ, got, c.want)
+		}
+	}
+	if err := LoadTypeMapping(strings.NewReader(`{"dataset": "Dataset"}`)); err == nil {
+		t.Error("LoadTypeMapping: got nil, want error for malformed mapping")
+	}
+}
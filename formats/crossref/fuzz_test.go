@@ -0,0 +1,33 @@
+//go:build go1.18
+// +build go1.18
+
+package crossref
+
+import "testing"
+
+func FuzzDecode(f *testing.F) {
+	f.Add(modernDocument())
+	f.Add([]byte(`{"URL": "x", "title": ["t"], "container-title": ["c"], "issued": {"date-parts": [[2000]]}}`))
+	f.Add([]byte(`{"issued": {"date-parts": [[]]}, "page": "xiv-3"}`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		defer func(v bool) { FastDecode = v }(FastDecode)
+		for _, fast := range []bool{false, true} {
+			FastDecode = fast
+			var doc Document
+			if err := doc.Decode(b); err != nil {
+				return
+			}
+			doc.ToIntermediateSchema()
+		}
+	})
+}
+
+func FuzzParsePages(f *testing.F) {
+	for _, s := range []string{"1-10", "xiv-3", "e123", "12-", "-", "S12-S15", "1–10"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		pi := ParsePages(s)
+		pi.PageCount()
+	})
+}
@@ -0,0 +1,11 @@
+package crossref
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/crossref", golden.JSONLines(func() golden.Converter { return new(Document) }))
+}
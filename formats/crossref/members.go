@@ -0,0 +1,363 @@
+package crossref
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miku/span/cache"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultMembersEndpoint is the crossref members API.
+const DefaultMembersEndpoint = "https://api.crossref.org/members"
+
+var (
+	// ErrUnknownMember is returned, if a member cannot be resolved.
+	ErrUnknownMember = errors.New("unknown crossref member")
+	// ErrMembersUnavailable is returned, while requests to the members API
+	// are suspended after repeated failures.
+	ErrMembersUnavailable = errors.New("crossref members API unavailable")
+)
+
+const (
+	// DefaultNegativeTTL is the time unknown members are cached.
+	DefaultNegativeTTL = 24 * time.Hour
+	// DefaultMaxFailures is the number of consecutive failed requests, after
+	// which requests are suspended.
+	DefaultMaxFailures = 5
+	// DefaultRetryAfter is the time requests are suspended.
+	DefaultRetryAfter = time.Minute
+
+	// memberKeyPrefix prefixes member identifiers in the shared cache.
+	memberKeyPrefix = "crossref:member:"
+)
+
+// memberEntry is a cached member name. Unknown members are cached, too.
+type memberEntry struct {
+	Name    string    `json:"name,omitempty"`
+	Unknown bool      `json:"unknown,omitempty"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// result returns the name or ErrUnknownMember.
+func (e memberEntry) result() (string, error) {
+	if e.Unknown {
+		return "", ErrUnknownMember
+	}
+	return e.Name, nil
+}
+
+// memberCall is a lookup in flight, shared by concurrent callers.
+type memberCall struct {
+	done chan struct{}
+	name string
+	err  error
+}
+
+// MemberResolver resolves crossref member identifiers to names via the
+// members API and keeps results in memory, in a JSON file on disk and, if
+// Cache is set, in a shared cache. With Offline set, only the caches are
+// consulted; otherwise entries older than TTL and unknown members older than
+// NegativeTTL are refetched. If a refetch fails, a stale entry is still used.
+// Concurrent lookups of a member result in a single request and after
+// MaxFailures failed requests in a row, requests are suspended for
+// RetryAfter. Errors are counted, see Errors. Safe for concurrent use.
+type MemberResolver struct {
+	CacheFile   string
+	Cache       cache.Cache
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	Offline     bool
+	Endpoint    string
+	Client      *http.Client
+	MaxFailures int
+	RetryAfter  time.Duration
+
+	mu        sync.Mutex
+	cache     map[string]memberEntry
+	inflight  map[string]*memberCall
+	loaded    bool
+	dirty     bool
+	failures  int       // Consecutive failed requests.
+	suspended time.Time // Requests are suspended until then.
+	errors    int64
+	lastErr   error
+}
+
+// load reads the cache file, if it exists. Must be called with lock held.
+func (r *MemberResolver) load() error {
+	if r.loaded {
+		return nil
+	}
+	r.cache = make(map[string]memberEntry)
+	r.inflight = make(map[string]*memberCall)
+	r.loaded = true
+	if r.CacheFile == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(r.CacheFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &r.cache)
+}
+
+// fetch requests the name of a single member from the API.
+func (r *MemberResolver) fetch(id string) (string, error) {
+	endpoint, client := r.Endpoint, r.Client
+	if endpoint == "" {
+		endpoint = DefaultMembersEndpoint
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	link := fmt.Sprintf("%s/%s", strings.TrimRight(endpoint, "/"), id)
+	resp, err := client.Get(link)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrUnknownMember
+	}
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("request to %s failed with: %s", link, resp.Status)
+	}
+	var payload struct {
+		Message struct {
+			PrimaryName string `json:"primary-name"`
+		} `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(payload.Message.PrimaryName)
+	if name == "" {
+		return "", ErrUnknownMember
+	}
+	return name, nil
+}
+
+// fresh returns true, if an entry need not be refetched.
+func (r *MemberResolver) fresh(e memberEntry) bool {
+	if r.Offline {
+		return true
+	}
+	ttl := r.TTL
+	if e.Unknown {
+		if ttl = r.NegativeTTL; ttl == 0 {
+			ttl = DefaultNegativeTTL
+		}
+	}
+	return ttl == 0 || time.Since(e.Fetched) < ttl
+}
+
+// count records an error.
+func (r *MemberResolver) count(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors++
+	r.lastErr = err
+}
+
+// Errors returns the number of errors during lookups, like failed requests
+// or shared cache errors, and the last error. Unknown members are not
+// counted.
+func (r *MemberResolver) Errors() (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.errors, r.lastErr
+}
+
+// allow returns ErrMembersUnavailable, while requests are suspended.
+func (r *MemberResolver) allow() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Now().Before(r.suspended) {
+		return ErrMembersUnavailable
+	}
+	return nil
+}
+
+// record counts failed requests in a row and suspends requests, if there are
+// too many.
+func (r *MemberResolver) record(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil || err == ErrUnknownMember {
+		r.failures = 0
+		return
+	}
+	r.failures++
+	max, wait := r.MaxFailures, r.RetryAfter
+	if max <= 0 {
+		max = DefaultMaxFailures
+	}
+	if wait <= 0 {
+		wait = DefaultRetryAfter
+	}
+	if r.failures >= max {
+		r.failures = 0
+		r.suspended = time.Now().Add(wait)
+		log.Warnf("crossref: %d member requests failed, suspending requests for %s: %v", max, wait, err)
+	}
+}
+
+// shared returns an entry from the shared cache.
+func (r *MemberResolver) shared(id string) (memberEntry, bool, error) {
+	var entry memberEntry
+	b, err := r.Cache.Get(memberKeyPrefix + id)
+	if err == cache.ErrNotFound {
+		return entry, false, nil
+	}
+	if err != nil {
+		return entry, false, err
+	}
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return entry, false, err
+	}
+	return entry, true, nil
+}
+
+// memo keeps an entry in memory, marking it for the cache file, if it was
+// fetched.
+func (r *MemberResolver) memo(id string, entry memberEntry, fetched bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache[id] = entry
+	if fetched {
+		r.dirty = true
+	}
+}
+
+// Lookup returns the name for a member identifier, e.g. "297".
+func (r *MemberResolver) Lookup(id string) (string, error) {
+	r.mu.Lock()
+	if err := r.load(); err != nil {
+		r.mu.Unlock()
+		r.count(err)
+		return "", err
+	}
+	entry, ok := r.cache[id]
+	if ok && r.fresh(entry) {
+		r.mu.Unlock()
+		return entry.result()
+	}
+	if c, ok := r.inflight[id]; ok {
+		r.mu.Unlock()
+		<-c.done
+		return c.name, c.err
+	}
+	c := &memberCall{done: make(chan struct{})}
+	r.inflight[id] = c
+	r.mu.Unlock()
+
+	c.name, c.err = r.resolve(id, entry, ok)
+	if c.err != nil && c.err != ErrUnknownMember {
+		r.count(c.err)
+	}
+	r.mu.Lock()
+	delete(r.inflight, id)
+	r.mu.Unlock()
+	close(c.done)
+	return c.name, c.err
+}
+
+// resolve looks up a member in the shared cache, then requests it from the
+// API. The entry is a stale value from memory, if ok is true.
+func (r *MemberResolver) resolve(id string, entry memberEntry, ok bool) (string, error) {
+	if r.Cache != nil {
+		e, found, err := r.shared(id)
+		switch {
+		case err != nil:
+			// Continue with the API, so cache problems do not change output.
+			r.count(fmt.Errorf("shared cache: %v", err))
+		case found:
+			r.memo(id, e, false)
+			if r.fresh(e) {
+				return e.result()
+			}
+			entry, ok = e, true
+		}
+	}
+	if r.Offline {
+		if ok {
+			return entry.result()
+		}
+		return "", ErrUnknownMember
+	}
+	if err := r.allow(); err != nil {
+		if ok && !entry.Unknown {
+			return entry.Name, nil
+		}
+		return "", err
+	}
+	name, err := r.fetch(id)
+	r.record(err)
+	switch {
+	case err == ErrUnknownMember:
+		entry = memberEntry{Unknown: true, Fetched: time.Now()}
+	case err != nil:
+		if ok && !entry.Unknown {
+			return entry.Name, nil
+		}
+		return "", err
+	default:
+		entry = memberEntry{Name: name, Fetched: time.Now()}
+	}
+	r.memo(id, entry, true)
+	if r.Cache != nil {
+		b, err := json.Marshal(entry)
+		if err == nil {
+			err = r.Cache.Set(memberKeyPrefix+id, b, 0)
+		}
+		if err != nil {
+			r.count(fmt.Errorf("shared cache: %v", err))
+		}
+	}
+	return entry.result()
+}
+
+// Flush writes the cache back to disk, if anything changed. The file is
+// replaced atomically.
+func (r *MemberResolver) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.dirty || r.CacheFile == "" {
+		return nil
+	}
+	b, err := json.Marshal(r.cache)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(r.CacheFile), ".span-members-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), r.CacheFile); err != nil {
+		return err
+	}
+	r.dirty = false
+	return nil
+}
@@ -0,0 +1,214 @@
+package crossref
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miku/span/cache"
+)
+
+func TestMemberResolver(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/members/297" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `{"status": "ok", "message": {"id": 297, "primary-name": "Springer Nature"}}`)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "span-members-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cacheFile := filepath.Join(dir, "members.json")
+
+	r := &MemberResolver{CacheFile: cacheFile, TTL: time.Hour, Endpoint: ts.URL + "/members"}
+	for i := 0; i < 2; i++ {
+		name, err := r.Lookup("297")
+		if err != nil {
+			t.Fatalf("Lookup: %v", err)
+		}
+		if name != "Springer Nature" {
+			t.Errorf("Lookup: got %q, want Springer Nature", name)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+	if _, err := r.Lookup("1"); err != ErrUnknownMember {
+		t.Errorf("Lookup: got %v, want %v", err, ErrUnknownMember)
+	}
+	if err := r.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	// A new resolver in offline mode uses the cache file only.
+	offline := &MemberResolver{CacheFile: cacheFile, Offline: true, Endpoint: ts.URL + "/members"}
+	if name, err := offline.Lookup("297"); err != nil || name != "Springer Nature" {
+		t.Errorf("offline Lookup: got %q, %v", name, err)
+	}
+	if _, err := offline.Lookup("1"); err != ErrUnknownMember {
+		t.Errorf("offline Lookup: got %v, want %v", err, ErrUnknownMember)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("got %d requests, want 2", n)
+	}
+}
+
+func TestMemberResolverSharedCache(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprintln(w, `{"status": "ok", "message": {"id": 297, "primary-name": "Springer Nature"}}`)
+	}))
+	defer ts.Close()
+
+	c := cache.NewMemory()
+	for i := 0; i < 2; i++ {
+		// Resolvers share lookups through the cache.
+		r := &MemberResolver{Cache: c, TTL: time.Hour, Endpoint: ts.URL + "/members"}
+		if name, err := r.Lookup("297"); err != nil || name != "Springer Nature" {
+			t.Errorf("Lookup: got %q, %v", name, err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestMemberResolverUnknown(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	r := &MemberResolver{TTL: time.Hour, Endpoint: ts.URL + "/members"}
+	for i := 0; i < 3; i++ {
+		if _, err := r.Lookup("1"); err != ErrUnknownMember {
+			t.Errorf("Lookup: got %v, want %v", err, ErrUnknownMember)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+	if n, _ := r.Errors(); n != 0 {
+		t.Errorf("got %d errors, want 0", n)
+	}
+}
+
+func TestMemberResolverConcurrent(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprintln(w, `{"status": "ok", "message": {"id": 297, "primary-name": "Springer Nature"}}`)
+	}))
+	defer ts.Close()
+
+	r := &MemberResolver{TTL: time.Hour, Endpoint: ts.URL + "/members"}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if name, err := r.Lookup("297"); err != nil || name != "Springer Nature" {
+				t.Errorf("Lookup: got %q, %v", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestMemberResolverSuspend(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	r := &MemberResolver{
+		TTL:         time.Hour,
+		Endpoint:    ts.URL + "/members",
+		MaxFailures: 2,
+		RetryAfter:  time.Hour,
+	}
+	for _, id := range []string{"1", "2", "3", "4"} {
+		if _, err := r.Lookup(id); err == nil {
+			t.Errorf("Lookup(%s): got nil, want error", id)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("got %d requests, want 2", n)
+	}
+	if n, err := r.Errors(); n != 4 || err != ErrMembersUnavailable {
+		t.Errorf("Errors: got %d, %v, want 4, %v", n, err, ErrMembersUnavailable)
+	}
+}
+
+// countingCache counts requests and fails, if err is set.
+type countingCache struct {
+	cache.Cache
+	gets int32
+	err  error
+}
+
+func (c *countingCache) Get(key string) ([]byte, error) {
+	atomic.AddInt32(&c.gets, 1)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.Cache.Get(key)
+}
+
+func (c *countingCache) Set(key string, value []byte, ttl time.Duration) error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.Cache.Set(key, value, ttl)
+}
+
+func TestMemberResolverSharedCacheMemo(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status": "ok", "message": {"id": 297, "primary-name": "Springer Nature"}}`)
+	}))
+	defer ts.Close()
+
+	c := &countingCache{Cache: cache.NewMemory()}
+	r := &MemberResolver{Cache: c, TTL: time.Hour, Endpoint: ts.URL + "/members"}
+	for i := 0; i < 3; i++ {
+		if name, err := r.Lookup("297"); err != nil || name != "Springer Nature" {
+			t.Errorf("Lookup: got %q, %v", name, err)
+		}
+	}
+	if n := atomic.LoadInt32(&c.gets); n != 1 {
+		t.Errorf("got %d cache requests, want 1", n)
+	}
+
+	// Cache errors are counted, but do not change the result.
+	failing := &countingCache{Cache: cache.NewMemory(), err: fmt.Errorf("connection refused")}
+	r = &MemberResolver{Cache: failing, TTL: time.Hour, Endpoint: ts.URL + "/members"}
+	if name, err := r.Lookup("297"); err != nil || name != "Springer Nature" {
+		t.Errorf("Lookup: got %q, %v", name, err)
+	}
+	if n, err := r.Errors(); n != 2 || err == nil {
+		t.Errorf("Errors: got %d, %v, want 2 errors", n, err)
+	}
+}
@@ -0,0 +1,125 @@
+package crossref
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PageInfo holds various page related data.
+type PageInfo struct {
+	RawMessage string
+	StartPage  int
+	EndPage    int
+	// First and Last are the page labels as found, e.g. "S12" or "xii".
+	First string
+	Last  string
+	// Count is the total number of pages over all ranges, if known.
+	Count int
+}
+
+// PageCount returns the number of pages, or zero if this cannot be determined.
+func (pi *PageInfo) PageCount() int {
+	if pi.Count > 0 {
+		return pi.Count
+	}
+	if pi.StartPage != 0 && pi.EndPage != 0 {
+		// an article, that starts at page 19 and ends at page 19 has one page
+		count := pi.EndPage - pi.StartPage + 1
+		if count > 0 {
+			return count
+		}
+	}
+	return 0
+}
+
+// pageLabel is a parsed page, e.g. "S12" has prefix S and number 12.
+type pageLabel struct {
+	prefix string
+	number int
+}
+
+// romanValues for lower case roman numerals.
+var romanValues = map[rune]int{'i': 1, 'v': 5, 'x': 10, 'l': 50, 'c': 100, 'd': 500, 'm': 1000}
+
+// parseRoman parses a roman numeral like "xiv", case insensitive.
+func parseRoman(s string) (int, bool) {
+	if s == "" {
+		return 0, false
+	}
+	var total, last int
+	runes := []rune(strings.ToLower(s))
+	for i := len(runes) - 1; i >= 0; i-- {
+		v, ok := romanValues[runes[i]]
+		if !ok {
+			return 0, false
+		}
+		if v < last {
+			total -= v
+		} else {
+			total += v
+			last = v
+		}
+	}
+	return total, true
+}
+
+// parsePageLabel parses "123", "xii", "S12" or electronic article numbers
+// like "e0123456".
+func parsePageLabel(s string) (pageLabel, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return pageLabel{number: n}, true
+	}
+	if n, ok := parseRoman(s); ok {
+		return pageLabel{prefix: "roman", number: n}, true
+	}
+	i := strings.IndexAny(s, "0123456789")
+	if i < 1 {
+		return pageLabel{}, false
+	}
+	n, err := strconv.Atoi(s[i:])
+	if err != nil {
+		return pageLabel{}, false
+	}
+	return pageLabel{prefix: s[:i], number: n}, true
+}
+
+// ParsePages parses page specifications like "123-130", "xii-xv", "S12-S19",
+// "e0123456" or multiple ranges, like "1-5, 12-13". The page count includes
+// the first page. Electronic article numbers count as a single page.
+func ParsePages(s string) PageInfo {
+	pi := PageInfo{RawMessage: s}
+	replacer := strings.NewReplacer("–", "-", "—", "-", ";", ",")
+	var count int
+	for i, r := range strings.Split(replacer.Replace(s), ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		parts := strings.Split(r, "-")
+		if len(parts) > 2 {
+			return PageInfo{RawMessage: s}
+		}
+		first, last := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[len(parts)-1])
+		start, ok := parsePageLabel(first)
+		if !ok {
+			return PageInfo{RawMessage: s}
+		}
+		end, ok := parsePageLabel(last)
+		if !ok || start.prefix != end.prefix {
+			return PageInfo{RawMessage: s}
+		}
+		if i == 0 {
+			pi.First, pi.StartPage = first, start.number
+		}
+		pi.Last, pi.EndPage = last, end.number
+		if count >= 0 && end.number >= start.number {
+			count += end.number - start.number + 1
+		} else {
+			count = -1
+		}
+	}
+	if count > 0 {
+		pi.Count = count
+	}
+	return pi
+}
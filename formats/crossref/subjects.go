@@ -0,0 +1,32 @@
+package crossref
+
+import (
+	"strings"
+
+	"github.com/miku/span"
+	"github.com/miku/span/assetutil"
+)
+
+// SubjectBlocklist contains subjects, that carry no information, like "General
+// Medicine", in lower case. Blocked subjects are dropped.
+var SubjectBlocklist = assetutil.MustLoadStringSet("assets/crossref/subject-blocklist.txt")
+
+// NormalizeSubjects trims subjects, collapses whitespace and removes
+// duplicates, ignoring case, as well as blocked subjects. The first spelling
+// of a subject is kept.
+func NormalizeSubjects(subjects []string) (result []string) {
+	seen := make(map[string]bool)
+	for _, s := range subjects {
+		s = strings.Join(strings.Fields(span.UnescapeTrim(s)), " ")
+		if s == "" {
+			continue
+		}
+		key := strings.ToLower(s)
+		if seen[key] || SubjectBlocklist.Contains(key) {
+			continue
+		}
+		seen[key] = true
+		result = append(result, s)
+	}
+	return result
+}
@@ -23,13 +23,13 @@ package degruyter
 //
 
 import (
-	"encoding/base64"
 	"encoding/xml"
 	"fmt"
 
-	"github.com/miku/span"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
 	"github.com/miku/span/formats/jats"
+	"github.com/miku/span/mint"
 )
 
 const (
@@ -56,7 +56,7 @@ func (article *Article) Identifiers() (jats.Identifiers, error) {
 		return ids, err
 	}
 	locator := fmt.Sprintf("http://dx.doi.org/%s", doi)
-	id := fmt.Sprintf("ai-%s-%s", SourceID, base64.RawURLEncoding.EncodeToString([]byte(locator)))
+	id := mint.ID(SourceID, locator, doi, mint.Base64)
 	return jats.Identifiers{DOI: doi, URL: locator, ID: id}, nil
 }
 
@@ -72,14 +72,10 @@ func (article *Article) ToIntermediateSchema() (*finc.IntermediateSchema, error)
 		return output, err
 	}
 
-	id := ids.ID
-	if len(id) > span.KeyLengthLimit {
-		return output, span.Skip{Reason: fmt.Sprintf("id too long: %s", id)}
-	}
-	output.ID = id
+	output.ID = ids.ID
 	output.RecordID = ids.DOI
 
-	output.DOI = ids.DOI
+	output.DOI = doi.Clean(ids.DOI)
 	output.URL = append(output.URL, ids.URL)
 
 	output.Format = Format
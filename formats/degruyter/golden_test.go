@@ -0,0 +1,11 @@
+package degruyter
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/degruyter", golden.XML("article", func() golden.Converter { return new(Article) }))
+}
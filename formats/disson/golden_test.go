@@ -0,0 +1,11 @@
+package disson
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/disson", golden.XML("Record", func() golden.Converter { return new(Record) }))
+}
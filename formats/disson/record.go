@@ -10,6 +10,7 @@ import (
 	"github.com/miku/span"
 	"github.com/miku/span/formats/finc"
 	"github.com/miku/span/formats/marc"
+	"github.com/miku/span/mint"
 )
 
 type Record struct {
@@ -49,7 +50,7 @@ func (r Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output := finc.NewIntermediateSchema()
 	output.SourceID = "13"
 	output.RecordID = r.MustGetControlField("001")
-	output.ID = fmt.Sprintf("ai-%s-%s", output.SourceID, output.RecordID)
+	output.ID = mint.ID(output.SourceID, output.RecordID, "", mint.Plain)
 	output.Format = "ElectronicThesis"
 	output.Genre = "book"
 	output.MegaCollections = []string{"Diss online"}
@@ -67,9 +68,8 @@ func (r Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output.Abstract = r.MustGetFirstDataField("520.a")
 	output.ArticleTitle = r.Title()
 
-	for _, p := range r.MustGetDataFields("264.a") {
-		output.Places = append(output.Places, p)
-	}
+	output.Places = r.Places()
+	output.Edition = r.Edition()
 	for _, p := range r.MustGetDataFields("264.b") {
 		output.Publishers = append(output.Publishers, p)
 	}
@@ -34,7 +34,9 @@ import (
 	"github.com/miku/span"
 	"github.com/miku/span/assetutil"
 	"github.com/miku/span/container"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
 )
 
 const (
@@ -181,14 +183,11 @@ func (doc Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	}
 	output.RawDate = output.Date.Format("2006-01-02")
 
-	id := fmt.Sprintf("ai-%s-%s", SourceIdentifier, doc.ID)
-	if len(id) > span.KeyLengthLimit {
-		return output, span.Skip{Reason: fmt.Sprintf("id too long: %s", id)}
-	}
+	id := mint.ID(SourceIdentifier, doc.ID, doi.Clean(doc.DOI()), mint.Plain)
 
 	output.ArticleTitle = doc.BibJSON.Title
 	output.Authors = doc.Authors()
-	output.DOI = doc.DOI()
+	output.DOI = doi.Clean(doc.DOI())
 	output.Format = Format
 	output.Genre = Genre
 	output.ISSN = doc.Index.ISSN
@@ -222,15 +221,7 @@ func (doc Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 		output.URL = append(output.URL, "https://doaj.org/article/"+doc.ID)
 	}
 
-	output.StartPage = doc.BibJSON.StartPage
-	output.EndPage = doc.BibJSON.EndPage
-
-	if sp, err := strconv.Atoi(doc.BibJSON.StartPage); err == nil {
-		if ep, err := strconv.Atoi(doc.BibJSON.EndPage); err == nil {
-			output.PageCount = fmt.Sprintf("%d", ep-sp)
-			output.Pages = fmt.Sprintf("%d-%d", sp, ep)
-		}
-	}
+	output.Pages = finc.NewPages(doc.BibJSON.StartPage, doc.BibJSON.EndPage, "")
 
 	subjects := container.NewStringSet()
 	for _, s := range doc.Index.SchemaCode {
@@ -249,7 +240,8 @@ func (doc Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	for _, l := range doc.Index.Language {
 		languages.Add(LanguageMap.LookupDefault(l, "und"))
 	}
-	output.Languages = languages.Values()
+	output.Languages = languages.SortedValues()
+	output.OAStatus = finc.OAStatusGold
 
 	output.RefType = DefaultRefType
 	return output, nil
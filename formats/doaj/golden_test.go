@@ -0,0 +1,11 @@
+package doaj
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/doaj", golden.JSONLines(func() golden.Converter { return new(Response) }))
+}
@@ -9,7 +9,9 @@ import (
 
 	"github.com/miku/span"
 	"github.com/miku/span/container"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
 )
 
 // ArticleV1 represents an API v1 response.
@@ -113,14 +115,11 @@ func (doc ArticleV1) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	if doc.Id == "" {
 		return output, span.Skip{Reason: "no identifier in source"}
 	}
-	id := fmt.Sprintf("ai-%s-%s", SourceIdentifier, doc.Id)
-	if len(id) > span.KeyLengthLimit {
-		return output, span.Skip{Reason: fmt.Sprintf("id too long: %s", id)}
-	}
+	id := mint.ID(SourceIdentifier, doc.Id, doi.Clean(doc.DOI()), mint.Plain)
 
 	output.ArticleTitle = doc.Bibjson.Title
 	output.Authors = doc.Authors()
-	output.DOI = doc.DOI()
+	output.DOI = doi.Clean(doc.DOI())
 	output.Format = Format
 	output.Genre = Genre
 
@@ -155,15 +154,7 @@ func (doc ArticleV1) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 		output.URL = append(output.URL, "https://doaj.org/article/"+doc.Id)
 	}
 
-	output.StartPage = doc.Bibjson.StartPage
-	output.EndPage = doc.Bibjson.EndPage
-
-	if sp, err := strconv.Atoi(doc.Bibjson.StartPage); err == nil {
-		if ep, err := strconv.Atoi(doc.Bibjson.EndPage); err == nil {
-			output.PageCount = fmt.Sprintf("%d", ep-sp)
-			output.Pages = fmt.Sprintf("%d-%d", sp, ep)
-		}
-	}
+	output.Pages = finc.NewPages(doc.Bibjson.StartPage, doc.Bibjson.EndPage, "")
 
 	subjects := container.NewStringSet()
 	for _, s := range doc.Bibjson.Subject {
@@ -186,8 +177,9 @@ func (doc ArticleV1) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 		}
 		languages.Add(detected)
 	}
-	output.Languages = languages.Values()
+	output.Languages = languages.SortedValues()
 	output.OpenAccess = true
+	output.OAStatus = finc.OAStatusGold
 
 	output.RefType = DefaultRefType
 	return output, nil
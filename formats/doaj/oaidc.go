@@ -11,7 +11,9 @@ import (
 
 	"github.com/miku/span"
 	"github.com/miku/span/container"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
 )
 
 // Record was generated 2019-03-07 22:40:57 by tir on hayiti.
@@ -58,18 +60,13 @@ func (record Record) Date() (time.Time, error) {
 
 // DOI returns DOI or empty string.
 func (record Record) DOI() string {
-	for _, id := range record.Metadata.Dc.Identifier {
-		if strings.HasPrefix(id, "10.") {
-			return id
-		}
-	}
-	return ""
+	return doi.FromIdentifiers(record.Metadata.Dc.Identifier...)
 }
 
 // Authors returns authors.
 func (record Record) Authors() (authors []finc.Author) {
 	for _, creator := range record.Metadata.Dc.Creator {
-		authors = append(authors, finc.Author{Name: html.UnescapeString(creator)})
+		authors = append(authors, finc.ParseAuthor(html.UnescapeString(creator)))
 	}
 	return authors
 }
@@ -202,36 +199,31 @@ func (record Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output.Date = date
 	output.RawDate = date.Format("2006-01-02")
 	output.Authors = record.Authors()
-	output.DOI = record.DOI()
+	output.DOI = doi.Clean(record.DOI())
 	output.RecordID = record.Identifier()
 	if output.RecordID == "" {
 		return output, fmt.Errorf("missing record id")
 	}
 	output.SourceID = "28"
-	output.ID = fmt.Sprintf("ai-28-%s", output.RecordID)
+	output.ID = mint.ID(SourceIdentifier, output.RecordID, output.DOI, mint.Plain)
 
 	output.URL = record.Links()
 	output.JournalTitle = record.JournalTitle()
 	output.Volume = record.Volume()
 	output.Issue = record.Issue()
 	output.ISSN = record.ISSN()
-	output.StartPage = record.StartPage()
-	output.EndPage = record.EndPage()
-	output.PageCount = record.Pages()
-	output.Pages = fmt.Sprintf("%s-%s", output.StartPage, output.EndPage)
-	if output.Pages == "-" {
-		output.Pages = ""
-	}
+	output.Pages = finc.NewPages(record.StartPage(), record.EndPage(), "")
 
 	languages := container.NewStringSet()
 	for _, l := range record.Metadata.Dc.Language {
 		languages.Add(LanguageMap.LookupDefault(l, "und"))
 	}
-	output.Languages = languages.Values()
+	output.Languages = languages.SortedValues()
 	output.Format = "ElectronicArticle"
 	output.Genre = "article"
 	output.RefType = "EJOUR"
 	output.MegaCollections = []string{"DOAJ Directory of Open Access Journals"}
+	output.OAStatus = finc.OAStatusGold
 
 	// Subjects, if LCSH can be resolved.
 	output.Subjects = record.Subjects()
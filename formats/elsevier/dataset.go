@@ -4,7 +4,6 @@ package elsevier
 import (
 	"archive/tar"
 	"bytes"
-	"encoding/base64"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -17,7 +16,9 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/kennygrant/sanitize"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
 )
 
 const (
@@ -476,14 +477,14 @@ func (s Shipment) BatchConvert() ([]finc.IntermediateSchema, error) {
 				}
 
 				output.Authors = article.Authors()
-				output.DOI = article.ItemInfo.Doi
+				output.DOI = doi.Clean(article.ItemInfo.Doi)
 				output.Format = Format
 				output.Genre = Genre
 				output.ISSN = []string{si.IssueInfo.Issn}
 				output.Issue = si.IssueInfo.VolumeIssueNumber.IssFirst
 				output.Languages = []string{"eng"}
 				output.MegaCollections = []string{Collection}
-				output.ID = fmt.Sprintf("ai-%s-%s", SourceID, base64.RawURLEncoding.EncodeToString([]byte(article.ItemInfo.Doi)))
+				output.ID = mint.ID(SourceID, article.ItemInfo.Doi, article.ItemInfo.Doi, mint.Base64)
 				output.RecordID = article.ItemInfo.Doi
 				output.RefType = DefaultRefType
 				output.SourceID = SourceID
@@ -492,9 +493,7 @@ func (s Shipment) BatchConvert() ([]finc.IntermediateSchema, error) {
 				output.ArticleTitle = article.Title()
 				output.JournalTitle = ji.JournalIssueProperties.CollectionTitle
 
-				output.StartPage = ii.Pages.FirstPage
-				output.EndPage = ii.Pages.LastPage
-				output.Pages = ii.Pages.Total()
+				output.Pages = finc.NewPages(ii.Pages.FirstPage, ii.Pages.LastPage, "")
 
 				output.URL = []string{
 					fmt.Sprintf("http://doi.org/%s", article.ItemInfo.Doi),
@@ -0,0 +1,38 @@
+package elsevier
+
+import (
+	"io"
+	"testing"
+
+	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/golden"
+)
+
+// converted wraps an already converted record, since shipments are converted
+// as a whole.
+type converted finc.IntermediateSchema
+
+func (c *converted) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
+	return (*finc.IntermediateSchema)(c), nil
+}
+
+// decodeShipment converts a tar shipment.
+func decodeShipment(r io.Reader) ([]golden.Converter, error) {
+	shipment, err := NewShipment(r)
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := shipment.BatchConvert()
+	if err != nil {
+		return nil, err
+	}
+	var result []golden.Converter
+	for i := range outputs {
+		result = append(result, (*converted)(&outputs[i]))
+	}
+	return result, nil
+}
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/elsevier", decodeShipment)
+}
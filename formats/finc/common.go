@@ -22,9 +22,13 @@
 package finc
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"strings"
 
 	"github.com/miku/span/assetutil"
+	"github.com/miku/span/container"
 )
 
 var (
@@ -32,19 +36,27 @@ var (
 	LanguageMap    = assetutil.MustLoadStringMap("assets/finc/iso-639-3-language.json")
 	AIAccessFacet  = "Electronic Resources"
 
-	FormatDe105  = assetutil.MustLoadStringMap("assets/finc/formats/de105.json")
-	FormatDe14   = assetutil.MustLoadStringMap("assets/finc/formats/de14.json")
-	FormatDe15   = assetutil.MustLoadStringMap("assets/finc/formats/de15.json")
-	FormatDe520  = assetutil.MustLoadStringMap("assets/finc/formats/de520.json")
-	FormatDe540  = assetutil.MustLoadStringMap("assets/finc/formats/de540.json")
-	FormatDeCh1  = assetutil.MustLoadStringMap("assets/finc/formats/dech1.json")
-	FormatDed117 = assetutil.MustLoadStringMap("assets/finc/formats/ded117.json")
-	FormatDeGla1 = assetutil.MustLoadStringMap("assets/finc/formats/degla1.json")
-	FormatDel152 = assetutil.MustLoadStringMap("assets/finc/formats/del152.json")
-	FormatDel189 = assetutil.MustLoadStringMap("assets/finc/formats/del189.json")
-	FormatDeZi4  = assetutil.MustLoadStringMap("assets/finc/formats/dezi4.json")
-	FormatDeZwi2 = assetutil.MustLoadStringMap("assets/finc/formats/dezwi2.json")
-	FormatNrw    = assetutil.MustLoadStringMap("assets/finc/formats/nrw.json")
+	// LeadingArticles lists articles per ISO 639-3 language code, which are
+	// ignored when sorting titles.
+	LeadingArticles = assetutil.MustLoadStringSliceMap("assets/finc/articles.json")
+
+	// FormatFields are the site specific format facets, keyed by SOLR field
+	// name. Use LoadFormatFields to read a different set of fields at runtime.
+	FormatFields = map[string]container.StringMap{
+		"format_de105":  assetutil.MustLoadStringMap("assets/finc/formats/de105.json"),
+		"format_de14":   assetutil.MustLoadStringMap("assets/finc/formats/de14.json"),
+		"format_de15":   assetutil.MustLoadStringMap("assets/finc/formats/de15.json"),
+		"format_de520":  assetutil.MustLoadStringMap("assets/finc/formats/de520.json"),
+		"format_de540":  assetutil.MustLoadStringMap("assets/finc/formats/de540.json"),
+		"format_dech1":  assetutil.MustLoadStringMap("assets/finc/formats/dech1.json"),
+		"format_ded117": assetutil.MustLoadStringMap("assets/finc/formats/ded117.json"),
+		"format_degla1": assetutil.MustLoadStringMap("assets/finc/formats/degla1.json"),
+		"format_del152": assetutil.MustLoadStringMap("assets/finc/formats/del152.json"),
+		"format_del189": assetutil.MustLoadStringMap("assets/finc/formats/del189.json"),
+		"format_dezi4":  assetutil.MustLoadStringMap("assets/finc/formats/dezi4.json"),
+		"format_dezwi2": assetutil.MustLoadStringMap("assets/finc/formats/dezwi2.json"),
+		"format_nrw":    assetutil.MustLoadStringMap("assets/finc/formats/nrw.json"),
+	}
 )
 
 // AuthorReplacer is a special cleaner for author names.
@@ -57,3 +69,24 @@ var AuthorReplacer = strings.NewReplacer(
 	"Author Index", "",
 	"AUTHOR Index", "",
 	"AUTHOR INDEX", "")
+
+// LoadFormatFields reads site specific format facets from JSON. The top level
+// keys are SOLR field names, the values map finc formats to facet values, e.g.
+// {"format_de15": {"ElectronicArticle": "Article, E-Article", ...}, ...}.
+func LoadFormatFields(r io.Reader) (map[string]container.StringMap, error) {
+	fields := make(map[string]map[string]string)
+	if err := json.NewDecoder(r).Decode(&fields); err != nil {
+		return nil, err
+	}
+	result := make(map[string]container.StringMap)
+	for name, m := range fields {
+		if solrFieldNames[name] {
+			return nil, fmt.Errorf("format field collides with solr field: %s", name)
+		}
+		if !strings.HasPrefix(name, "format_") {
+			return nil, fmt.Errorf("format field name must start with format_: %s", name)
+		}
+		result[name] = container.StringMap(m)
+	}
+	return result, nil
+}
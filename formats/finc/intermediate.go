@@ -27,12 +27,23 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/miku/span/names"
 )
 
 const (
 	IntermediateSchemaRecordType = "is"
 	AIRecordType                 = "ai"
-	IntermediateSchemaVersion    = "0.9"
+	IntermediateSchemaVersion    = "1.0"
+)
+
+// Open access status values, refs. x.oa_status.
+const (
+	OAStatusGold   = "gold"
+	OAStatusGreen  = "green"
+	OAStatusHybrid = "hybrid"
+	OAStatusBronze = "bronze"
+	OAStatusClosed = "closed"
 )
 
 var (
@@ -64,6 +75,26 @@ type Author struct {
 	// Constraint Definition of KEV Metadata Format for "book", Excerpt,
 	// https://groups.niso.org/apps/group_public/download.php/14833/z39_88_2004_r2010.pdf#page=55).
 	Corporate string `json:"rft.aucorp,omitempty"`
+
+	// ORCID, bare identifier, e.g. 0000-0002-1825-0097.
+	ORCID        string   `json:"x.orcid,omitempty"`
+	Affiliations []string `json:"x.affiliations,omitempty"`
+}
+
+// Funder is an organization funding the research, with optional award or
+// grant numbers. DOI refers to the Open Funder Registry, if known.
+type Funder struct {
+	Name   string   `json:"name,omitempty"`
+	DOI    string   `json:"doi,omitempty"`
+	Awards []string `json:"awards,omitempty"`
+}
+
+// String returns the funder name, or the DOI, if there is no name.
+func (f Funder) String() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	return f.DOI
 }
 
 // String returns a formatted author string.
@@ -81,6 +112,20 @@ func (author *Author) String() string {
 	return author.ID
 }
 
+// ParseAuthor creates an author from a raw name, see names.Parse. Corporate
+// names are stored as Corporate, names, that cannot be split, as Name.
+func ParseAuthor(s string) Author {
+	n := names.Parse(s)
+	switch {
+	case n.Corporate:
+		return Author{Corporate: n.Raw}
+	case n.Last != "":
+		return Author{FirstName: n.First, LastName: n.Last}
+	default:
+		return Author{Name: n.Raw}
+	}
+}
+
 // IntermediateSchema abstract and collects the values of various input formats.
 // Goal is to simplify further processing by using a single format, from which
 // the next artifacts can be derived, e.g. records for solr indices.
@@ -115,14 +160,11 @@ type IntermediateSchema struct {
 	Edition      string   `json:"rft.edition,omitempty"`
 	EISBN        []string `json:"rft.eisbn,omitempty"`
 	EISSN        []string `json:"rft.eissn,omitempty"`
-	EndPage      string   `json:"rft.epage,omitempty"`
 	Genre        string   `json:"rft.genre,omitempty"`
 	ISBN         []string `json:"rft.isbn,omitempty"`
 	ISSN         []string `json:"rft.issn,omitempty"`
 	Issue        string   `json:"rft.issue,omitempty"`
 	JournalTitle string   `json:"rft.jtitle,omitempty"`
-	PageCount    string   `json:"rft.tpages,omitempty"`
-	Pages        string   `json:"rft.pages,omitempty"`
 	Part         string   `json:"rft.part,omitempty"`
 	Places       []string `json:"rft.place,omitempty"`
 	Publishers   []string `json:"rft.pub,omitempty"`
@@ -132,10 +174,14 @@ type IntermediateSchema struct {
 	RawDate string    `json:"rft.date,omitempty"`
 	Date    time.Time `json:"x.date,omitempty"`
 
-	Season     string `json:"rft.ssn,omitempty"`
-	Series     string `json:"rft.series,omitempty"`
+	// Pages are serialized as rft.spage, rft.epage, rft.tpages and
+	// rft.pages.
+	Pages Pages `json:"-"`
+
+	Season string `json:"rft.ssn,omitempty"`
+	Series string `json:"rft.series,omitempty"`
+	// ShortTitle is the abbreviated journal title, e.g. "J. Appl. Phys.".
 	ShortTitle string `json:"rft.stitle,omitempty"`
-	StartPage  string `json:"rft.spage,omitempty"`
 	Volume     string `json:"rft.volume,omitempty"`
 
 	Abstract  string   `json:"abstract,omitempty"`
@@ -161,9 +207,18 @@ type IntermediateSchema struct {
 	// OpenAccess, refs. #8986, prototype
 	OpenAccess bool     `json:"x.oa,omitempty"`
 	License    []string `json:"x.license,omitempty"`
+	// OAStatus is one of gold, green, hybrid, bronze or closed, empty if
+	// unknown.
+	OAStatus string `json:"x.oa_status,omitempty"`
 
 	// Footnote, via solr schema, refs #13653
 	Footnotes []string `json:"x.footnotes,omitempty"`
+
+	// Funders and grants, as found in crossref.
+	Funders []Funder `json:"x.funders,omitempty"`
+
+	// References are DOIs of cited works, optional.
+	References []string `json:"x.references,omitempty"`
 }
 
 // NewIntermediateSchema creates a new intermediate schema document with the
@@ -205,13 +260,62 @@ func (is *IntermediateSchema) ParsedDate() time.Time {
 	return t
 }
 
+// AllfieldsOptions selects the optional parts of allfields, since large
+// fulltexts bloat some indices, while others rely on them.
+type AllfieldsOptions struct {
+	Abstract bool
+	Fulltext bool
+	Subjects bool
+}
+
+// DefaultAllfieldsOptions includes all parts.
+var DefaultAllfieldsOptions = AllfieldsOptions{Abstract: true, Fulltext: true, Subjects: true}
+
+// ParseAllfieldsOptions parses a comma separated list of parts to include,
+// e.g. "abstract,subjects". An empty string includes none of the optional
+// parts.
+func ParseAllfieldsOptions(s string) (opts AllfieldsOptions, err error) {
+	for _, part := range strings.Split(s, ",") {
+		switch strings.TrimSpace(part) {
+		case "":
+		case "abstract":
+			opts.Abstract = true
+		case "fulltext":
+			opts.Fulltext = true
+		case "subjects":
+			opts.Subjects = true
+		default:
+			return opts, fmt.Errorf("unknown allfields part: %s", part)
+		}
+	}
+	return opts, nil
+}
+
 // Allfields returns a combination of various fields.
 func (is *IntermediateSchema) Allfields() string {
+	return is.AllfieldsWith(DefaultAllfieldsOptions)
+}
+
+// AllfieldsWith returns a combination of various fields, with optional parts
+// selected by opts.
+func (is *IntermediateSchema) AllfieldsWith(opts AllfieldsOptions) string {
 	var authors []string
 	for _, author := range is.Authors {
 		authors = append(authors, author.String())
 	}
 
+	var subjects []string
+	var abstract, fulltext string
+	if opts.Subjects {
+		subjects = is.Subjects
+	}
+	if opts.Abstract {
+		abstract = is.Abstract
+	}
+	if opts.Fulltext {
+		fulltext = is.Fulltext
+	}
+
 	fields := [][]string{
 		// multivalued
 		authors,
@@ -221,16 +325,16 @@ func (is *IntermediateSchema) Allfields() string {
 		is.ISSN,
 		is.Places,
 		is.Publishers,
-		is.Subjects,
+		subjects,
 		is.URL,
 		{
 			// single-valued
-			is.Abstract,
+			abstract,
 			is.ArticleSubtitle,
 			is.ArticleTitle,
 			is.BookTitle,
 			is.Edition,
-			is.Fulltext,
+			fulltext,
 			is.JournalTitle,
 			is.Series,
 			is.ShortTitle,
@@ -254,13 +358,57 @@ func btoi(b bool) int {
 	return 0
 }
 
+// MegaCollection returns the first mega collection, for code that expects a
+// single collection, as in schema versions before 1.0.
+func (is *IntermediateSchema) MegaCollection() string {
+	for _, c := range is.MegaCollections {
+		if c != "" {
+			return c
+		}
+	}
+	return ""
+}
+
+// HasMegaCollection returns true, if the record belongs to the collection.
+func (is *IntermediateSchema) HasMegaCollection(name string) bool {
+	for _, c := range is.MegaCollections {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AddMegaCollection adds a collection, unless it is empty or already present.
+func (is *IntermediateSchema) AddMegaCollection(name string) {
+	if name = strings.TrimSpace(name); name == "" || is.HasMegaCollection(name) {
+		return
+	}
+	is.MegaCollections = append(is.MegaCollections, name)
+}
+
+// trimPunctuation removes trailing ISBD punctuation, which some sources keep
+// in place and publisher names.
+func trimPunctuation(s string) string {
+	return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(s), " :;,/"))
+}
+
 // Imprint MARC 260 a, b, c (trad.)
 func (is *IntermediateSchema) Imprint() (s string) {
 	var places, publisher string
-	year := is.Date.Year()
-	places = strings.Join(is.Places, " ; ")
+	var year int
+	if !is.Date.IsZero() {
+		year = is.Date.Year()
+	}
+	var ps []string
+	for _, p := range is.Places {
+		if p = trimPunctuation(p); p != "" {
+			ps = append(ps, p)
+		}
+	}
+	places = strings.Join(ps, " ; ")
 	if len(is.Publishers) > 0 {
-		publisher = is.Publishers[0]
+		publisher = trimPunctuation(is.Publishers[0])
 	}
 
 	mask := btoi(places != "") + 2*btoi(publisher != "") + 4*btoi(year != 0)
@@ -284,14 +432,44 @@ func (is *IntermediateSchema) Imprint() (s string) {
 	return
 }
 
-// SortableTitle is loosely based on getSortableTitle in SOLRMARC.
+// defaultArticleLanguages are used for records without language.
+var defaultArticleLanguages = []string{"eng"}
+
+// StripLeadingArticle removes a leading article, like "The" or "Der", from a
+// lowercase title, considering the articles of the given languages. Elided
+// articles, like "l'", may be followed directly by the next word.
+func StripLeadingArticle(title string, languages []string) string {
+	if len(languages) == 0 {
+		languages = defaultArticleLanguages
+	}
+	s := strings.TrimLeft(title, " \"'«»„“”‘’([¿¡")
+	for _, lang := range languages {
+		for _, article := range LeadingArticles.LookupDefault(lang, nil) {
+			if !strings.HasPrefix(s, article) {
+				continue
+			}
+			rest := s[len(article):]
+			if strings.HasSuffix(article, "'") && rest != "" {
+				return strings.TrimSpace(rest)
+			}
+			if strings.HasPrefix(rest, " ") && strings.TrimSpace(rest) != "" {
+				return strings.TrimSpace(rest)
+			}
+		}
+	}
+	return s
+}
+
+// SortableTitle is loosely based on getSortableTitle in SOLRMARC. Leading
+// articles are removed by record language.
 func (is *IntermediateSchema) SortableTitle() string {
-	switch {
-	case is.BookTitle != "":
-		return strings.ToLower(NonAlphaNumeric.ReplaceAllString(is.BookTitle, ""))
-	default:
-		return strings.ToLower(NonAlphaNumeric.ReplaceAllString(is.ArticleTitle, ""))
+	title := is.ArticleTitle
+	if is.BookTitle != "" {
+		title = is.BookTitle
 	}
+	title = strings.Replace(strings.ToLower(title), "’", "'", -1)
+	title = StripLeadingArticle(title, is.Languages)
+	return NonAlphaNumeric.ReplaceAllString(title, "")
 }
 
 // SortableAuthor is loosely based on getSortableAuthor in SOLRMARC.
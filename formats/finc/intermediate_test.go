@@ -0,0 +1,83 @@
+package finc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestImprint(t *testing.T) {
+	date := time.Date(2003, 1, 1, 0, 0, 0, 0, time.UTC)
+	var cases = []struct {
+		about string
+		is    IntermediateSchema
+		want  string
+	}{
+		{"empty", IntermediateSchema{}, ""},
+		{"year", IntermediateSchema{Date: date}, "2003"},
+		{"place", IntermediateSchema{Places: []string{"Münster"}}, "Münster"},
+		{"all", IntermediateSchema{Places: []string{"Münster"}, Publishers: []string{"Dampfboot"}, Date: date},
+			"Münster : Dampfboot, 2003"},
+		{"places", IntermediateSchema{Places: []string{"Opladen", "Farmington Hills"}, Date: date},
+			"Opladen ; Farmington Hills : 2003"},
+		{"punctuation", IntermediateSchema{Places: []string{"Berlin :", " "}, Publishers: []string{"de Gruyter,"}},
+			"Berlin : de Gruyter"},
+	}
+	for _, c := range cases {
+		if got := c.is.Imprint(); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.about, got, c.want)
+		}
+	}
+}
+
+func TestSortableTitle(t *testing.T) {
+	var cases = []struct {
+		is   IntermediateSchema
+		want string
+	}{
+		{IntermediateSchema{ArticleTitle: "The Title"}, "title"},
+		{IntermediateSchema{ArticleTitle: "A"}, "a"},
+		{IntermediateSchema{ArticleTitle: "Der Titel", Languages: []string{"deu"}}, "titel"},
+		{IntermediateSchema{ArticleTitle: "Der Titel", Languages: []string{"eng"}}, "der titel"},
+		{IntermediateSchema{ArticleTitle: "L'histoire", Languages: []string{"fra"}}, "histoire"},
+		{IntermediateSchema{ArticleTitle: "La casa", Languages: []string{"spa"}}, "casa"},
+		{IntermediateSchema{ArticleTitle: "\"Un’altra storia\"", Languages: []string{"ita"}}, "altra storia\""},
+		{IntermediateSchema{ArticleTitle: "Theory", BookTitle: "An Introduction"}, "introduction"},
+	}
+	for _, c := range cases {
+		if got := c.is.SortableTitle(); got != c.want {
+			t.Errorf("SortableTitle(%q, %v): got %q, want %q", c.is.ArticleTitle, c.is.Languages, got, c.want)
+		}
+	}
+}
+
+func TestAllfieldsWith(t *testing.T) {
+	is := IntermediateSchema{
+		ArticleTitle: "Title",
+		Abstract:     "Abstract",
+		Fulltext:     "Fulltext",
+		Subjects:     []string{"Subject"},
+	}
+	var cases = []struct {
+		parts string
+		want  string
+	}{
+		{"abstract,fulltext,subjects", "Subject Abstract Title Fulltext"},
+		{"", "Title"},
+		{"subjects, abstract", "Subject Abstract Title"},
+	}
+	for _, c := range cases {
+		opts, err := ParseAllfieldsOptions(c.parts)
+		if err != nil {
+			t.Fatalf("%q: %v", c.parts, err)
+		}
+		if got := is.AllfieldsWith(opts); got != c.want {
+			t.Errorf("%q: got %q, want %q", c.parts, got, c.want)
+		}
+	}
+	if got := is.Allfields(); got != is.AllfieldsWith(DefaultAllfieldsOptions) {
+		t.Errorf("Allfields: got %q", got)
+	}
+	if _, err := ParseAllfieldsOptions("abstract,authors"); err == nil {
+		t.Errorf("expected error for unknown part")
+	}
+}
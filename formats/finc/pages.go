@@ -0,0 +1,115 @@
+package finc
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Pages describes the pages of an article or chapter. Zero values mean
+// unknown: empty labels and a zero count.
+type Pages struct {
+	// Start and End are page labels as found, e.g. "12", "xii" or "S12".
+	Start string
+	End   string
+	// Count is the total number of pages, zero if unknown.
+	Count int
+	// Raw is the page specification, e.g. "12-15" or "1-5, 12-13".
+	Raw string
+}
+
+// NewPages creates pages from start and end labels and a page count, as
+// found in many formats. An invalid or zero count is replaced by the number
+// of pages between numeric start and end labels, if possible. The raw
+// specification is the range of start and end.
+func NewPages(start, end, count string) Pages {
+	p := Pages{Start: strings.TrimSpace(start), End: strings.TrimSpace(end), Count: ParseCount(count)}
+	if p.Count == 0 {
+		if s, err := strconv.Atoi(p.Start); err == nil && s > 0 {
+			if e, err := strconv.Atoi(p.End); err == nil && e >= s {
+				p.Count = e - s + 1
+			}
+		}
+	}
+	switch {
+	case p.Start != "" && p.End != "":
+		p.Raw = p.Start + "-" + p.End
+	case p.Start != "":
+		p.Raw = p.Start
+	}
+	return p
+}
+
+// ParseCount returns a positive page count or zero, e.g. for "", "0", "n/a"
+// or "-1".
+func ParseCount(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// IsZero returns true, if nothing is known about the pages.
+func (p Pages) IsZero() bool {
+	return p == Pages{}
+}
+
+// String returns the raw specification or the range of start and end.
+func (p Pages) String() string {
+	switch {
+	case p.Raw != "":
+		return p.Raw
+	case p.Start != "" && p.End != "":
+		return p.Start + "-" + p.End
+	default:
+		return p.Start
+	}
+}
+
+// pagesFields is the serialized form of Pages, string fields as used by
+// consumers of the intermediate schema.
+type pagesFields struct {
+	StartPage string `json:"rft.spage,omitempty"`
+	EndPage   string `json:"rft.epage,omitempty"`
+	PageCount string `json:"rft.tpages,omitempty"`
+	Pages     string `json:"rft.pages,omitempty"`
+}
+
+func (f pagesFields) pages() Pages {
+	return Pages{Start: f.StartPage, End: f.EndPage, Count: ParseCount(f.PageCount), Raw: f.Pages}
+}
+
+func fieldsOf(p Pages) pagesFields {
+	f := pagesFields{StartPage: p.Start, EndPage: p.End, Pages: p.Raw}
+	if p.Count > 0 {
+		f.PageCount = strconv.Itoa(p.Count)
+	}
+	return f
+}
+
+// intermediateSchema has no methods, so it is serialized field by field.
+type intermediateSchema IntermediateSchema
+
+// MarshalJSON writes pages as string fields rft.spage, rft.epage, rft.tpages
+// and rft.pages, as before pages were structured.
+func (is IntermediateSchema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		*intermediateSchema
+		pagesFields
+	}{(*intermediateSchema)(&is), fieldsOf(is.Pages)})
+}
+
+// UnmarshalJSON reads pages from string fields. Unknown page counts, like
+// "0", are dropped.
+func (is *IntermediateSchema) UnmarshalJSON(b []byte) error {
+	v := struct {
+		*intermediateSchema
+		pagesFields
+	}{intermediateSchema: (*intermediateSchema)(is)}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	is.Pages = v.pagesFields.pages()
+	return nil
+}
@@ -0,0 +1,91 @@
+package finc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewPages(t *testing.T) {
+	var cases = []struct {
+		start, end, count string
+		want              Pages
+	}{
+		{"", "", "", Pages{}},
+		{"12", "15", "", Pages{Start: "12", End: "15", Count: 4, Raw: "12-15"}},
+		{"12", "12", "", Pages{Start: "12", End: "12", Count: 1, Raw: "12-12"}},
+		{"12", "15", "10", Pages{Start: "12", End: "15", Count: 10, Raw: "12-15"}},
+		{"12", "15", "0", Pages{Start: "12", End: "15", Count: 4, Raw: "12-15"}},
+		{" 12 ", "", "", Pages{Start: "12", Raw: "12"}},
+		{"15", "12", "", Pages{Start: "15", End: "12", Raw: "15-12"}},
+		{"xii", "xv", "n/a", Pages{Start: "xii", End: "xv", Raw: "xii-xv"}},
+		{"", "", "7", Pages{Count: 7}},
+	}
+	for _, c := range cases {
+		if got := NewPages(c.start, c.end, c.count); got != c.want {
+			t.Errorf("NewPages(%q, %q, %q): got %+v, want %+v", c.start, c.end, c.count, got, c.want)
+		}
+	}
+}
+
+func TestParseCount(t *testing.T) {
+	var cases = map[string]int{"": 0, "0": 0, "-1": 0, "n/a": 0, "12": 12, " 3 ": 3}
+	for s, want := range cases {
+		if got := ParseCount(s); got != want {
+			t.Errorf("ParseCount(%q): got %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestPagesJSON(t *testing.T) {
+	var cases = []struct {
+		about string
+		b     string
+		want  Pages
+		out   []string
+	}{
+		{
+			"all fields",
+			`{"version": "1.0", "rft.spage": "12", "rft.epage": "15", "rft.tpages": "4", "rft.pages": "12-15"}`,
+			Pages{Start: "12", End: "15", Count: 4, Raw: "12-15"},
+			[]string{`"rft.spage":"12"`, `"rft.epage":"15"`, `"rft.tpages":"4"`, `"rft.pages":"12-15"`},
+		},
+		{
+			"unknown count",
+			`{"version": "1.0", "rft.spage": "1", "rft.tpages": "0"}`,
+			Pages{Start: "1"},
+			[]string{`"rft.spage":"1"`},
+		},
+		{
+			"no pages",
+			`{"version": "1.0", "rft.atitle": "A"}`,
+			Pages{},
+			nil,
+		},
+	}
+	for _, c := range cases {
+		var is IntermediateSchema
+		if err := UnmarshalIntermediateSchema([]byte(c.b), &is); err != nil {
+			t.Fatalf("%s: %v", c.about, err)
+		}
+		if is.Pages != c.want {
+			t.Errorf("%s: got %+v, want %+v", c.about, is.Pages, c.want)
+		}
+		b, err := json.Marshal(is)
+		if err != nil {
+			t.Fatalf("%s: %v", c.about, err)
+		}
+		s := string(b)
+		for _, v := range c.out {
+			if !strings.Contains(s, v) {
+				t.Errorf("%s: %s missing in %s", c.about, v, s)
+			}
+		}
+		if strings.Count(s, "page") != len(c.out) {
+			t.Errorf("%s: got %s, want page fields %v only", c.about, s, c.out)
+		}
+		if !strings.Contains(s, `"version":"1.0"`) {
+			t.Errorf("%s: other fields missing in %s", c.about, s)
+		}
+	}
+}
@@ -2,14 +2,42 @@
 package finc
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/kennygrant/sanitize"
+	"github.com/miku/span/classify"
 	"github.com/miku/span/container"
+	"github.com/miku/span/names"
 )
 
+const (
+	// FullrecordJSON stores the intermediate schema as is.
+	FullrecordJSON = "json"
+	// FullrecordGzip stores the gzip compressed, base64 encoded intermediate
+	// schema, prefixed with "gzip:", to keep index size down.
+	FullrecordGzip = "gzip"
+)
+
+// gzipBase64 compresses a byte slice and returns it base64 encoded, prefixed
+// with "gzip:", similar to the "blob:" references.
+func gzipBase64(b []byte) (string, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return "gzip:" + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
 // Solr5Vufind3 is the basic solr 5 schema as of 2016-04-14. It is based on
 // VuFind 3. Same as Solr5Vufind3v12, but with fullrecord field, refs. #8031.
 type Solr5Vufind3 struct {
@@ -18,15 +46,23 @@ type Solr5Vufind3 struct {
 	AuthorCorporate      []string `json:"author_corporate,omitempty"`
 	Authors              []string `json:"author,omitempty"`
 	AuthorSort           string   `json:"author_sort,omitempty"`
+	AuthorORCID          []string `json:"author_orcid,omitempty"`
+	AuthorAffiliation    []string `json:"author_affiliation,omitempty"`
 	SecondaryAuthors     []string `json:"author2,omitempty"`
 	Allfields            string   `json:"allfields,omitempty"`
 	Edition              string   `json:"edition,omitempty"`
 	FacetAvail           []string `json:"facet_avail"`
+	FacetOA              string   `json:"facet_oa,omitempty"`
 	FincClassFacet       []string `json:"finc_class_facet,omitempty"`
+	DDCFacet             []string `json:"ddc_facet,omitempty"`
+	RVKFacet             []string `json:"rvk_facet,omitempty"`
+	BKFacet              []string `json:"bk_facet,omitempty"`
 	Footnotes            []string `json:"footnote,omitempty"`
 	Formats              []string `json:"format,omitempty"`
 	Fullrecord           string   `json:"fullrecord,omitempty"`
 	Fulltext             string   `json:"fulltext,omitempty"`
+	Funders              []string `json:"funder,omitempty"`
+	FunderAwards         []string `json:"funder_award,omitempty"`
 	HierarchyParentTitle []string `json:"hierarchy_parent_title,omitempty"`
 	ID                   string   `json:"id,omitempty"`
 	Institutions         []string `json:"institution,omitempty"`
@@ -52,25 +88,92 @@ type Solr5Vufind3 struct {
 	Physical             []string `json:"physical,omitempty"`
 	Description          string   `json:"description"`
 
-	ContainerIssue     string `json:"container_issue,omitempty"`
-	ContainerStartPage string `json:"container_start_page,omitempty"`
-	ContainerTitle     string `json:"container_title,omitempty"`
-	ContainerVolume    string `json:"container_volume,omitempty"`
-
-	FormatDe105  []string `json:"format_de105,omitempty"`
-	FormatDe14   []string `json:"format_de14,omitempty"`
-	FormatDe15   []string `json:"format_de15,omitempty"`
-	FormatDe520  []string `json:"format_de520,omitempty"`
-	FormatDe540  []string `json:"format_de540,omitempty"`
-	FormatDeCh1  []string `json:"format_dech1,omitempty"`
-	FormatDed117 []string `json:"format_ded117,omitempty"`
-	FormatDeGla1 []string `json:"format_degla1,omitempty"`
-	FormatDel152 []string `json:"format_del152,omitempty"`
-	FormatDel189 []string `json:"format_del189,omitempty"`
-	FormatDeZi4  []string `json:"format_dezi4,omitempty"`
-	FormatDeZwi2 []string `json:"format_dezwi2,omitempty"`
-	FormatNrw    []string `json:"format_nrw,omitempty"`
-	BranchNrw    string   `json:"branch_nrw,omitempty"` // refs #11605
+	ContainerIssue      string `json:"container_issue,omitempty"`
+	ContainerStartPage  string `json:"container_start_page,omitempty"`
+	ContainerTitle      string `json:"container_title,omitempty"`
+	ContainerTitleShort string `json:"container_title_short,omitempty"`
+	ContainerVolume     string `json:"container_volume,omitempty"`
+
+	BranchNrw string `json:"branch_nrw,omitempty"` // refs #11605
+
+	// FullrecordEncoding selects the representation of the fullrecord field,
+	// if requested, one of FullrecordJSON (default) or FullrecordGzip.
+	FullrecordEncoding string `json:"-"`
+	// FormatMappings configures site specific format facets, keyed by field
+	// name. If nil, FormatFields is used.
+	FormatMappings map[string]container.StringMap `json:"-"`
+	// FoldAuthorFacet removes diacritics from author facet values.
+	FoldAuthorFacet bool `json:"-"`
+	// Classifier maps subjects to DDC, RVK and BK notations, if set.
+	Classifier *classify.Classifier `json:"-"`
+	// AllfieldsOptions selects the optional parts of allfields. If nil,
+	// DefaultAllfieldsOptions is used.
+	AllfieldsOptions *AllfieldsOptions `json:"-"`
+	// ISILFields adds barcode and collection fields per attached ISIL, like
+	// barcode_de15 and collection_de15.
+	ISILFields bool `json:"-"`
+	// Formats and other fields per site, serialized as top level fields.
+	SiteFields map[string][]string `json:"-"`
+}
+
+// isilSuffix turns an ISIL into a field name suffix, e.g. DE-Ch1 into dech1.
+func isilSuffix(isil string) string {
+	return strings.ToLower(strings.Replace(isil, "-", "", -1))
+}
+
+// solrFieldNames are the top level keys of the serialized document, site
+// fields must not shadow them.
+var solrFieldNames = jsonFieldNames(reflect.TypeOf(Solr5Vufind3{}))
+
+// jsonFieldNames returns the JSON keys of the exported fields of a struct type.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// MarshalJSON serializes the document and adds site specific fields as top
+// level keys. Site fields colliding with document fields are an error, as
+// the resulting JSON would contain duplicate keys.
+func (s *Solr5Vufind3) MarshalJSON() ([]byte, error) {
+	for name := range s.SiteFields {
+		if solrFieldNames[name] {
+			return nil, fmt.Errorf("site field collides with solr field: %s", name)
+		}
+	}
+	type plain Solr5Vufind3
+	b, err := json.Marshal((*plain)(s))
+	if err != nil {
+		return nil, err
+	}
+	if len(s.SiteFields) == 0 {
+		return b, nil
+	}
+	extra, err := json.Marshal(s.SiteFields)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 2 {
+		return extra, nil
+	}
+	// Splice {"a": 1} and {"b": 2} into {"a": 1,"b": 2}.
+	result := make([]byte, 0, len(b)+len(extra))
+	result = append(result, b[:len(b)-1]...)
+	result = append(result, ',')
+	return append(result, extra[1:]...), nil
 }
 
 // Export fulfuls finc.Exporter interface, so we can plug this into cmd/span-export. Takes
@@ -84,7 +187,11 @@ func (s *Solr5Vufind3) Export(is IntermediateSchema, withFullrecord bool) ([]byt
 
 // convert converts intermediate schema to the Solr5Vufind3. The struct fields are populated.
 func (s *Solr5Vufind3) convert(is IntermediateSchema, withFullrecord bool) error {
-	s.Allfields = is.Allfields()
+	if s.AllfieldsOptions != nil {
+		s.Allfields = is.AllfieldsWith(*s.AllfieldsOptions)
+	} else {
+		s.Allfields = is.Allfields()
+	}
 	s.Formats = append(s.Formats, is.Format)
 	s.Fullrecord = "blob:" + is.ID
 	s.Fulltext = is.Fulltext
@@ -106,6 +213,8 @@ func (s *Solr5Vufind3) convert(is IntermediateSchema, withFullrecord bool) error
 
 	if is.JournalTitle != "" {
 		s.Series = append(s.Series, is.JournalTitle)
+	} else if is.Genre == "bookitem" && is.BookTitle != "" {
+		s.Series = append(s.Series, is.BookTitle)
 	}
 	if is.Series != "" {
 		s.Series = append(s.Series, is.Series)
@@ -139,11 +248,18 @@ func (s *Solr5Vufind3) convert(is IntermediateSchema, withFullrecord bool) error
 			classes.Add(class)
 		}
 	}
-	s.FincClassFacet = classes.Values()
+	s.FincClassFacet = classes.SortedValues()
+
+	if s.Classifier != nil {
+		notations := s.Classifier.Classify(is.SourceID, is.Subjects)
+		s.DDCFacet = notations[classify.DDC]
+		s.RVKFacet = notations[classify.RVK]
+		s.BKFacet = notations[classify.BK]
+	}
 
 	var sanitized string
 	switch {
-	case is.BookTitle != "":
+	case is.BookTitle != "" && is.Genre != "bookitem":
 		sanitized = sanitize.HTML(is.BookTitle)
 	default:
 		sanitized = sanitize.HTML(is.ArticleTitle)
@@ -185,43 +301,75 @@ func (s *Solr5Vufind3) convert(is IntermediateSchema, withFullrecord bool) error
 			continue
 		}
 		authors = append(authors, sanitized)
-		s.AuthorFacet = append(s.AuthorFacet, sanitized)
+		if s.FoldAuthorFacet {
+			s.AuthorFacet = append(s.AuthorFacet, names.Fold(sanitized))
+		} else {
+			s.AuthorFacet = append(s.AuthorFacet, sanitized)
+		}
 	}
 
 	if len(authorCorporate) > 0 {
 		s.AuthorCorporate = authorCorporate
 	}
 
+	// Pass through identifiers and affiliations for author disambiguation.
+	affiliations := container.NewStringSet()
+	for _, author := range is.Authors {
+		if author.ORCID != "" {
+			s.AuthorORCID = append(s.AuthorORCID, author.ORCID)
+		}
+		for _, aff := range author.Affiliations {
+			if !affiliations.Contains(aff) {
+				affiliations.Add(aff)
+				s.AuthorAffiliation = append(s.AuthorAffiliation, aff)
+			}
+		}
+	}
+
 	// refs #7092, gh #8, refs #12310
 	if len(authors) > 0 {
 		s.Authors = authors
 		s.AuthorSort = strings.ToLower(authors[0])
 	}
 
+	for _, f := range is.Funders {
+		s.Funders = append(s.Funders, f.String())
+		s.FunderAwards = append(s.FunderAwards, f.Awards...)
+	}
+
 	s.AccessFacet = AIAccessFacet
 	s.BranchNrw = s.AccessFacet // refs #11605
 
-	// Site specific formats, TODO: fix this now.
-	s.FormatDe105 = []string{FormatDe105.LookupDefault(is.Format, "")}
-	s.FormatDe14 = []string{FormatDe14.LookupDefault(is.Format, "")}
-	s.FormatDe15 = []string{FormatDe15.LookupDefault(is.Format, "")}
-	s.FormatDe520 = []string{FormatDe520.LookupDefault(is.Format, "")}
-	s.FormatDe540 = []string{FormatDe540.LookupDefault(is.Format, "")}
-	s.FormatDeCh1 = []string{FormatDeCh1.LookupDefault(is.Format, "")}
-	s.FormatDed117 = []string{FormatDed117.LookupDefault(is.Format, "")}
-	s.FormatDeGla1 = []string{FormatDeGla1.LookupDefault(is.Format, "")}
-	s.FormatDel152 = []string{FormatDel152.LookupDefault(is.Format, "")}
-	s.FormatDel189 = []string{FormatDel189.LookupDefault(is.Format, "")}
-	s.FormatDeZi4 = []string{FormatDeZi4.LookupDefault(is.Format, "")}
-	s.FormatDeZwi2 = []string{FormatDeZwi2.LookupDefault(is.Format, "")}
-	s.FormatNrw = []string{FormatNrw.LookupDefault(is.Format, "")}
+	// Site specific formats, configurable via FormatMappings.
+	mappings := s.FormatMappings
+	if mappings == nil {
+		mappings = FormatFields
+	}
+	s.SiteFields = make(map[string][]string)
+	for name, m := range mappings {
+		s.SiteFields[name] = []string{m.LookupDefault(is.Format, "")}
+	}
 
 	s.ContainerVolume = is.Volume
 	s.ContainerIssue = is.Issue
-	s.ContainerStartPage = is.StartPage
+	s.ContainerStartPage = is.Pages.Start
 	s.ContainerTitle = is.JournalTitle
+	s.ContainerTitleShort = is.ShortTitle
+	if is.Genre == "bookitem" && s.ContainerTitle == "" {
+		s.ContainerTitle = is.BookTitle
+	}
 
 	s.Institutions = is.Labels
+	if s.ISILFields {
+		// Electronic records have no items, the record id serves as barcode.
+		for _, isil := range is.Labels {
+			suffix := isilSuffix(isil)
+			s.SiteFields["barcode_"+suffix] = []string{is.ID}
+			if len(is.MegaCollections) > 0 {
+				s.SiteFields["collection_"+suffix] = is.MegaCollections
+			}
+		}
+	}
 	s.Description = is.Abstract
 
 	if withFullrecord {
@@ -230,7 +378,16 @@ func (s *Solr5Vufind3) convert(is IntermediateSchema, withFullrecord bool) error
 		if err != nil {
 			return err
 		}
-		s.Fullrecord = string(b)
+		switch s.FullrecordEncoding {
+		case "", FullrecordJSON:
+			s.Fullrecord = string(b)
+		case FullrecordGzip:
+			if s.Fullrecord, err = gzipBase64(b); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown fullrecord encoding: %s", s.FullrecordEncoding)
+		}
 	}
 
 	// Default facet for online contents, refs #11285.
@@ -238,9 +395,10 @@ func (s *Solr5Vufind3) convert(is IntermediateSchema, withFullrecord bool) error
 	if is.OpenAccess {
 		s.FacetAvail = append(s.FacetAvail, "Free")
 	}
+	s.FacetOA = is.OAStatus
 
 	// refs #11478
-	s.Physical = []string{is.Pages}
+	s.Physical = []string{is.Pages.Raw}
 
 	// refs #14215
 	if is.SourceID == "48" {
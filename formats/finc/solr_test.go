@@ -0,0 +1,159 @@
+package finc
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSolr5Vufind3ISILFields(t *testing.T) {
+	is := IntermediateSchema{
+		ID:              "ai-49-abc",
+		Labels:          []string{"DE-15", "DE-Ch1"},
+		MegaCollections: []string{"Crossref"},
+		OpenAccess:      true,
+	}
+	var cases = []struct {
+		about string
+		isil  bool
+		want  map[string]interface{}
+	}{
+		{"off", false, map[string]interface{}{
+			"barcode_de15": nil,
+		}},
+		{"on", true, map[string]interface{}{
+			"barcode_de15":     []interface{}{"ai-49-abc"},
+			"barcode_dech1":    []interface{}{"ai-49-abc"},
+			"collection_de15":  []interface{}{"Crossref"},
+			"collection_dech1": []interface{}{"Crossref"},
+			"facet_avail":      []interface{}{"Online", "Free"},
+		}},
+	}
+	for _, c := range cases {
+		s := &Solr5Vufind3{ISILFields: c.isil}
+		b, err := s.Export(is, false)
+		if err != nil {
+			t.Fatalf("%s: %v", c.about, err)
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(b, &doc); err != nil {
+			t.Fatalf("%s: %v", c.about, err)
+		}
+		for k, v := range c.want {
+			if !reflect.DeepEqual(doc[k], v) {
+				t.Errorf("%s: %s: got %v, want %v", c.about, k, doc[k], v)
+			}
+		}
+	}
+}
+
+func TestSolr5Vufind3Funders(t *testing.T) {
+	is := IntermediateSchema{
+		ID: "ai-49-abc",
+		Funders: []Funder{
+			{Name: "National Science Foundation", DOI: "10.13039/100000001", Awards: []string{"CHE-1234", "CHE-5678"}},
+			{DOI: "10.13039/501100001659"},
+			{Name: "Example Trust", Awards: []string{"ET-1"}},
+		},
+	}
+	b, err := new(Solr5Vufind3).Export(is, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"funder":       []interface{}{"National Science Foundation", "10.13039/501100001659", "Example Trust"},
+		"funder_award": []interface{}{"CHE-1234", "CHE-5678", "ET-1"},
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(doc[k], v) {
+			t.Errorf("%s: got %v, want %v", k, doc[k], v)
+		}
+	}
+
+	b, err = new(Solr5Vufind3).Export(IntermediateSchema{ID: "ai-49-abc"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = nil
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"funder", "funder_award"} {
+		if _, ok := doc[k]; ok {
+			t.Errorf("%s: got %v, want no field", k, doc[k])
+		}
+	}
+}
+
+func TestSolr5Vufind3OpenAccessFacets(t *testing.T) {
+	var cases = []struct {
+		oa     bool
+		status string
+		avail  []interface{}
+		facet  interface{}
+	}{
+		{false, "", []interface{}{"Online"}, nil},
+		{false, OAStatusClosed, []interface{}{"Online"}, OAStatusClosed},
+		{true, OAStatusGold, []interface{}{"Online", "Free"}, OAStatusGold},
+		{true, OAStatusGreen, []interface{}{"Online", "Free"}, OAStatusGreen},
+		{true, OAStatusHybrid, []interface{}{"Online", "Free"}, OAStatusHybrid},
+	}
+	for _, c := range cases {
+		is := IntermediateSchema{ID: "ai-49-abc", OpenAccess: c.oa, OAStatus: c.status}
+		b, err := new(Solr5Vufind3).Export(is, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(b, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(doc["facet_avail"], c.avail) {
+			t.Errorf("%q: facet_avail: got %v, want %v", c.status, doc["facet_avail"], c.avail)
+		}
+		if !reflect.DeepEqual(doc["facet_oa"], c.facet) {
+			t.Errorf("%q: facet_oa: got %v, want %v", c.status, doc["facet_oa"], c.facet)
+		}
+	}
+}
+
+func TestLoadFormatFieldsCollision(t *testing.T) {
+	var cases = []struct {
+		about string
+		input string
+		err   bool
+	}{
+		{"site field", `{"format_de15": {"Article": "Artikel"}}`, false},
+		{"missing prefix", `{"facet_de15": {"Article": "Artikel"}}`, true},
+		{"collides with format", `{"format": {"Article": "Artikel"}}`, true},
+	}
+	for _, c := range cases {
+		_, err := LoadFormatFields(strings.NewReader(c.input))
+		if (err != nil) != c.err {
+			t.Errorf("%s: got err %v, want error %v", c.about, err, c.err)
+		}
+	}
+}
+
+func TestSolr5Vufind3SiteFieldCollision(t *testing.T) {
+	s := &Solr5Vufind3{
+		ID:         "ai-49-abc",
+		SiteFields: map[string][]string{"id": {"shadowed"}},
+	}
+	if _, err := json.Marshal(s); err == nil {
+		t.Fatalf("expected error for site field colliding with id")
+	}
+	s.SiteFields = map[string][]string{"format_de15": {"Artikel"}}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"format_de15":["Artikel"]`) {
+		t.Errorf("site field missing: %s", b)
+	}
+}
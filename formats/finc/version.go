@@ -0,0 +1,171 @@
+package finc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrMissingVersion is returned, if a serialized record carries no version.
+	ErrMissingVersion = errors.New("intermediate schema version missing")
+	// ErrUnsupportedVersion is returned for versions, we cannot migrate from.
+	ErrUnsupportedVersion = errors.New("unsupported intermediate schema version")
+)
+
+// Migration upgrades a serialized record, decoded into a generic map, from
+// one version of the intermediate schema to the next.
+type Migration struct {
+	From string
+	To   string
+	Func func(doc map[string]interface{}) error
+}
+
+// Migrations lists all known upgrade steps. Records are migrated step by step,
+// until they reach IntermediateSchemaVersion. Versions before 0.9 did not
+// differ from 0.9 in any way relevant to this package.
+var Migrations = []Migration{
+	{From: "0.9", To: "1.0", Func: migrateMegaCollections},
+}
+
+// migrateMegaCollections turns a single string valued finc.mega_collection
+// into a list, as found in 0.9 records.
+func migrateMegaCollections(doc map[string]interface{}) error {
+	v, ok := doc["finc.mega_collection"]
+	if !ok {
+		return nil
+	}
+	switch t := v.(type) {
+	case string:
+		if t == "" {
+			delete(doc, "finc.mega_collection")
+		} else {
+			doc["finc.mega_collection"] = []interface{}{t}
+		}
+	case []interface{}, nil:
+	default:
+		return fmt.Errorf("cannot migrate finc.mega_collection of type %T", v)
+	}
+	return nil
+}
+
+// versionBefore compares two dotted versions numerically, e.g. 0.1 < 0.9 < 0.10.
+func versionBefore(a, b string) (bool, error) {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var u, v int
+		var err error
+		if i < len(pa) {
+			if u, err = strconv.Atoi(pa[i]); err != nil {
+				return false, ErrUnsupportedVersion
+			}
+		}
+		if i < len(pb) {
+			if v, err = strconv.Atoi(pb[i]); err != nil {
+				return false, ErrUnsupportedVersion
+			}
+		}
+		if u != v {
+			return u < v, nil
+		}
+	}
+	return false, nil
+}
+
+// Migrate upgrades a generic representation of a record to the current
+// version. It is an error, if the record has no version or a version newer
+// than the current one.
+func Migrate(doc map[string]interface{}) error {
+	version, _ := doc["version"].(string)
+	if version == "" {
+		return ErrMissingVersion
+	}
+	if version != IntermediateSchemaVersion {
+		newer, err := versionBefore(IntermediateSchemaVersion, version)
+		if err != nil {
+			return err
+		}
+		if newer {
+			return fmt.Errorf("%v: %s", ErrUnsupportedVersion, version)
+		}
+	}
+	if older, _ := versionBefore(version, Migrations[0].From); older {
+		version = Migrations[0].From
+	}
+	for _, m := range Migrations {
+		if m.From != version {
+			continue
+		}
+		if err := m.Func(doc); err != nil {
+			return err
+		}
+		version = m.To
+	}
+	if version != IntermediateSchemaVersion {
+		return fmt.Errorf("%v: no migration path from %s", ErrUnsupportedVersion, doc["version"])
+	}
+	doc["version"] = version
+	return nil
+}
+
+// UnmarshalIntermediateSchema decodes a serialized record and transparently
+// upgrades it to the current version, if necessary. Current records are
+// decoded once, only older records take the slower path through a generic
+// map.
+func UnmarshalIntermediateSchema(b []byte, is *IntermediateSchema) error {
+	err := json.Unmarshal(b, is)
+	current := is.Version == IntermediateSchemaVersion
+	if current {
+		e, ok := err.(*json.UnmarshalTypeError)
+		if !ok || e.Field != "finc.mega_collection" {
+			return err
+		}
+		// Some writers label records as current, but still use a single
+		// string valued finc.mega_collection.
+	} else if _, ok := err.(*json.SyntaxError); ok {
+		return err
+	}
+	*is = IntermediateSchema{}
+	doc := make(map[string]interface{})
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+	if current {
+		if err := migrateMegaCollections(doc); err != nil {
+			return err
+		}
+	} else if err := Migrate(doc); err != nil {
+		return err
+	}
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(migrated, is)
+}
+
+// Decoder reads a stream of serialized records, e.g. newline delimited JSON,
+// and upgrades older records on the fly.
+type Decoder struct {
+	dec *json.Decoder
+}
+
+// NewDecoder returns a decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: json.NewDecoder(r)}
+}
+
+// Decode reads the next record. Returns io.EOF at the end of the input.
+func (d *Decoder) Decode(is *IntermediateSchema) error {
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		return err
+	}
+	return UnmarshalIntermediateSchema(raw, is)
+}
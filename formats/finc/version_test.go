@@ -0,0 +1,110 @@
+package finc
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalIntermediateSchema(t *testing.T) {
+	var cases = []struct {
+		about string
+		b     string
+		err   bool
+		mc    []string
+	}{
+		{"current", `{"version": "1.0", "finc.mega_collection": ["A"]}`, false, []string{"A"}},
+		{"0.9 string", `{"version": "0.9", "finc.mega_collection": "A"}`, false, []string{"A"}},
+		{"0.9 list", `{"version": "0.9", "finc.mega_collection": ["A", "B"]}`, false, []string{"A", "B"}},
+		{"0.1 string", `{"version": "0.1", "finc.mega_collection": "A"}`, false, []string{"A"}},
+		{"empty", `{"version": "0.9", "finc.mega_collection": ""}`, false, nil},
+		{"current string", `{"version": "1.0", "finc.mega_collection": "A", "finc.id": "x"}`, false, []string{"A"}},
+		{"missing", `{"finc.mega_collection": ["A"]}`, true, nil},
+		{"newer", `{"version": "2.0"}`, true, nil},
+		{"garbage", `{"version": "x.y"}`, true, nil},
+		{"syntax", `{"version": "1.0", "finc.id"`, true, nil},
+	}
+	for _, c := range cases {
+		var is IntermediateSchema
+		err := UnmarshalIntermediateSchema([]byte(c.b), &is)
+		if (err != nil) != c.err {
+			t.Errorf("%s: got err %v, want err %v", c.about, err, c.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if is.Version != IntermediateSchemaVersion {
+			t.Errorf("%s: got version %s, want %s", c.about, is.Version, IntermediateSchemaVersion)
+		}
+		if !reflect.DeepEqual(is.MegaCollections, c.mc) {
+			t.Errorf("%s: got %v, want %v", c.about, is.MegaCollections, c.mc)
+		}
+	}
+}
+
+func TestDecoderFixture(t *testing.T) {
+	f, err := os.Open("../../schema/fixtures/0.9/crossref.is")
+	if err != nil {
+		t.Skipf("fixture: %v", err)
+	}
+	defer f.Close()
+	var is IntermediateSchema
+	if err := NewDecoder(f).Decode(&is); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	want := []string{"Nature Publishing Group (CrossRef)"}
+	if !reflect.DeepEqual(is.MegaCollections, want) {
+		t.Errorf("Decode: got %v, want %v", is.MegaCollections, want)
+	}
+}
+
+func TestMegaCollectionRoundTrip(t *testing.T) {
+	for _, b := range []string{
+		`{"version": "0.9", "finc.mega_collection": "A"}`,
+		`{"version": "1.0", "finc.mega_collection": "A"}`,
+		`{"version": "1.0", "finc.mega_collection": ["A"]}`,
+	} {
+		var is, again IntermediateSchema
+		if err := UnmarshalIntermediateSchema([]byte(b), &is); err != nil {
+			t.Fatalf("%s: %v", b, err)
+		}
+		out, err := json.Marshal(is)
+		if err != nil {
+			t.Fatalf("%s: %v", b, err)
+		}
+		if err := UnmarshalIntermediateSchema(out, &again); err != nil {
+			t.Fatalf("%s: %v", out, err)
+		}
+		if again.MegaCollection() != "A" || len(again.MegaCollections) != 1 {
+			t.Errorf("%s: got %v after round trip", b, again.MegaCollections)
+		}
+	}
+}
+
+func TestAddMegaCollection(t *testing.T) {
+	var is IntermediateSchema
+	if is.MegaCollection() != "" {
+		t.Errorf("got %q, want empty collection", is.MegaCollection())
+	}
+	for _, name := range []string{"A", "", " B ", "A"} {
+		is.AddMegaCollection(name)
+	}
+	if want := []string{"A", "B"}; !reflect.DeepEqual(is.MegaCollections, want) {
+		t.Errorf("got %v, want %v", is.MegaCollections, want)
+	}
+	if !is.HasMegaCollection("B") || is.HasMegaCollection("C") {
+		t.Errorf("HasMegaCollection: unexpected result for %v", is.MegaCollections)
+	}
+}
+
+func BenchmarkUnmarshalIntermediateSchema(b *testing.B) {
+	data := []byte(`{"version": "1.0", "finc.id": "ai-49-x", "finc.mega_collection": ["A"], "rft.atitle": "Title", "authors": [{"rft.aulast": "Doe"}]}`)
+	for i := 0; i < b.N; i++ {
+		var is IntermediateSchema
+		if err := UnmarshalIntermediateSchema(data, &is); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package genderopen
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/genderopen", golden.XML("Record", func() golden.Converter { return new(Record) }))
+}
@@ -4,19 +4,17 @@ package genderopen
 import (
 	"encoding/base64"
 	"encoding/xml"
-	"fmt"
-	"regexp"
-	"strconv"
 	"strings"
-	"time"
 
 	"github.com/miku/span"
+	"github.com/miku/span/citation"
+	"github.com/miku/span/dates"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
+	"github.com/miku/span/oai"
 )
 
-// bookTitlePattern for extracting book title from dc.source.
-var bookTitlePattern = regexp.MustCompile(`([^:]*):([^\(]*)`)
-
 // Record was generated 2018-05-11 14:30:28 by tir on sol.
 type Record struct {
 	XMLName xml.Name `xml:"Record"`
@@ -89,51 +87,65 @@ type Record struct {
 	} `xml:"about"`
 }
 
-// BookTitle parses book title out of a citation string. Input may be "Knapp,
+// Citation returns the parsed citation found in dc.source, e.g. "Knapp,
 // Gudrun-Axeli; Wetterer, Angelika\n (Hrsg.): Achsen der Differenz.
 // Gesellschaftstheorie und feministische Kritik II (Münster: Westfälisches
-// Dampfboot, 2003), 73-100", https://play.golang.org/p/LApV7V_Ogz5. Fallback
-// to original string, refs #13024.
+// Dampfboot, 2003), 73-100".
+func (r *Record) Citation() citation.Citation {
+	return citation.Parse(r.Metadata.Dc.Source.Text)
+}
+
+// BookTitle parses book title out of a citation string. Fallback to original
+// string, refs #13024.
 func (r *Record) BookTitle() string {
-	s := strings.Replace(r.Metadata.Dc.Source.Text, "\n", " ", -2)
-	matches := bookTitlePattern.FindStringSubmatch(s)
-	if len(matches) == 3 {
-		return strings.TrimSpace(matches[2])
+	if t := r.Citation().Title; t != "" {
+		return t
 	}
-	return s
+	return strings.Replace(r.Metadata.Dc.Source.Text, "\n", " ", -1)
 }
 
-func parsePages(s string) (start, end, total string) {
-	p := regexp.MustCompile(`([1-9][0-9]*)-([1-9][0-9]*)`)
-	match := p.FindStringSubmatch(s)
-	if len(match) < 3 {
-		return "", "", ""
-	}
-	ss, es := match[1], match[2]
-	u, _ := strconv.Atoi(ss)
-	v, _ := strconv.Atoi(es)
-	return ss, es, fmt.Sprintf("%d", v-u)
+// Places returns the places of publication, if the citation in dc.source
+// has an imprint like "(Münster: Westfälisches Dampfboot, 2003)". Multiple
+// places are separated by slashes, e.g. "Opladen/Farmington Hills".
+func (r *Record) Places() []string {
+	return r.Citation().Places
 }
 
-// stringsContainsAny returns true, if vals contains v, comparisons are case
-// insensitive.
-func stringsContainsAny(v string, vals []string) bool {
-	for _, vv := range vals {
-		if strings.ToLower(v) == strings.ToLower(vv) {
-			return true
-		}
+// Edition returns an edition statement found in dc.source, like "2. Aufl.".
+func (r *Record) Edition() string {
+	return r.Citation().Edition
+}
+
+// Clues returns the values, that help to guess the kind of record, with ISSN
+// and ISBN from the converted record.
+func (record Record) Clues(output *finc.IntermediateSchema) oai.Clues {
+	var types []string
+	for _, t := range record.Metadata.Dc.Type {
+		types = append(types, t.Text)
+	}
+	return oai.Clues{
+		Types:  types,
+		ISSN:   output.ISSN,
+		ISBN:   output.ISBN,
+		Source: record.Metadata.Dc.Source.Text,
 	}
-	return false
+}
+
+// Deleted returns true, if the OAI header marks the record as deleted.
+func (record Record) Deleted() bool {
+	return record.Header.Status == "deleted"
 }
 
 func (record Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output := finc.NewIntermediateSchema()
 
 	output.SourceID = "162"
-	encodedRecordID := base64.RawURLEncoding.EncodeToString([]byte(record.Header.Identifier.Text))
-	output.RecordID = encodedRecordID
-	output.ID = fmt.Sprintf("ai-%s-%s", output.SourceID, output.RecordID)
-	output.MegaCollections = append(output.MegaCollections, "Gender Open")
+	output.RecordID = base64.RawURLEncoding.EncodeToString([]byte(record.Header.Identifier.Text))
+	output.ID = mint.ID(output.SourceID, record.Header.Identifier.Text, "", mint.Base64)
+	if record.Deleted() {
+		return output, span.Skip{Reason: span.SkipDeleted}
+	}
+	output.AddMegaCollection("Gender Open")
 	output.Genre = "article"
 	output.RefType = "EJOUR"
 	output.Format = "ElectronicArticle"
@@ -142,8 +154,9 @@ func (record Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output.ArticleTitle = record.Metadata.Dc.Title.Text
 
 	for _, v := range record.Metadata.Dc.Creator {
-		output.Authors = append(output.Authors, finc.Author{Name: v.Text})
+		output.Authors = append(output.Authors, finc.ParseAuthor(v.Text))
 	}
+	var ids []string
 	for _, v := range record.Metadata.Dc.Identifier {
 		if strings.HasPrefix(v.Text, "http") {
 			output.URL = append(output.URL, v.Text)
@@ -151,13 +164,21 @@ func (record Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 		if strings.HasPrefix(v.Text, "urn:ISSN:") {
 			output.ISSN = append(output.ISSN, strings.Replace(v.Text, "urn:ISSN:", "", 1))
 		}
-		if strings.HasPrefix(v.Text, "http://dx.doi.org/") {
-			output.DOI = strings.Replace(v.Text, "http://dx.doi.org/", "", -1)
+		if strings.HasPrefix(strings.ToLower(v.Text), "urn:isbn:") {
+			if isbn := span.NormalizeISBN(v.Text); isbn != "" {
+				output.ISBN = append(output.ISBN, isbn)
+			}
 		}
+		ids = append(ids, v.Text)
 	}
+	output.DOI = doi.FromIdentifiers(ids...)
 
 	// Article from books, articles from journals.
-	if stringsContainsAny(output.ArticleTitle, []string{"zeitschrift", "journal"}) || len(output.ISSN) > 0 {
+	kind, ok := oai.DetectKind(record.Clues(output))
+	if ok {
+		output.Genre, output.RefType, output.Format = kind.Genre, kind.RefType, kind.Format
+	}
+	if ok && kind.Genre == "article" {
 		output.JournalTitle = record.Metadata.Dc.Source.Text
 	} else {
 		output.BookTitle = record.BookTitle()
@@ -165,6 +186,9 @@ func (record Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	for _, p := range record.Metadata.Dc.Publisher {
 		output.Publishers = append(output.Publishers, p.Text)
 	}
+	c := record.Citation()
+	output.Places = c.Places
+	output.Edition = c.Edition
 
 	if record.Metadata.Dc.Date.Text == "" {
 		return output, span.Skip{Reason: "empty date"}
@@ -172,23 +196,20 @@ func (record Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	if len(record.Metadata.Dc.Date.Text) < 4 {
 		return output, span.Skip{Reason: "short date"}
 	}
-	if record.Metadata.Dc.Date.Text != "" {
-		s := record.Metadata.Dc.Date.Text[:4]
-		date, err := time.Parse("2006", s)
-		if err != nil {
+	// Full dates, like 2004-03-12, or a year, possibly with a suffix.
+	date, err := dates.Parse(record.Metadata.Dc.Date.Text)
+	if err != nil {
+		if date, err = dates.Parse(record.Metadata.Dc.Date.Text[:4]); err != nil {
 			return output, err
 		}
-		output.Date = date
-		output.RawDate = output.Date.Format("2006-01-02")
 	}
+	output.Date = date.Time
+	output.RawDate = output.Date.Format("2006-01-02")
 
 	for _, s := range record.Metadata.Dc.Subject {
 		output.Subjects = append(output.Subjects, s.Text)
 	}
-	start, end, total := parsePages(record.Metadata.Dc.Source.Text)
-	output.StartPage = start
-	output.EndPage = end
-	output.PageCount = total
+	output.Pages = finc.NewPages(c.StartPage, c.EndPage, "")
 	output.OpenAccess = true
 
 	return output, nil
@@ -0,0 +1,90 @@
+package genderopen
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+
+	"github.com/miku/span"
+)
+
+func TestPlacesAndEdition(t *testing.T) {
+	var cases = []struct {
+		source  string
+		places  []string
+		edition string
+	}{
+		{"", nil, ""},
+		{"Knapp, Gudrun-Axeli; Wetterer, Angelika\n (Hrsg.): Achsen der Differenz. Gesellschaftstheorie und feministische Kritik II (Münster: Westfälisches Dampfboot, 2003), 73-100",
+			[]string{"Münster"}, ""},
+		{"Becker, Ruth (Hrsg.): Handbuch Frauen- und Geschlechterforschung. 3., überarb. u. erw. Aufl. (Wiesbaden: VS Verlag, 2010), 12-20",
+			[]string{"Wiesbaden"}, "3., überarb. u. erw. Aufl."},
+		{"Lenz, Ilse (Hrsg.): Die Neue Frauenbewegung. 2. Aufl. (Opladen/Farmington Hills: Budrich, 2010)",
+			[]string{"Opladen", "Farmington Hills"}, "2. Aufl."},
+		{"Feministische Studien 21 (2003), 5-17", nil, ""},
+	}
+	for _, c := range cases {
+		var r Record
+		r.Metadata.Dc.Source.Text = c.source
+		if got := r.Places(); !reflect.DeepEqual(got, c.places) {
+			t.Errorf("Places(%q): got %v, want %v", c.source, got, c.places)
+		}
+		if got := r.Edition(); got != c.edition {
+			t.Errorf("Edition(%q): got %q, want %q", c.source, got, c.edition)
+		}
+	}
+}
+
+func TestDeleted(t *testing.T) {
+	var cases = []struct {
+		doc     string
+		deleted bool
+	}{
+		{`<Record><header><identifier>oai:www.genderopen.de:25595/1</identifier></header></Record>`, false},
+		{`<Record><header status="deleted"><identifier>oai:www.genderopen.de:25595/2</identifier></header></Record>`, true},
+	}
+	for _, c := range cases {
+		var record Record
+		if err := xml.Unmarshal([]byte(c.doc), &record); err != nil {
+			t.Fatal(err)
+		}
+		output, err := record.ToIntermediateSchema()
+		skip, ok := err.(span.Skip)
+		if deleted := ok && skip.Reason == span.SkipDeleted; deleted != c.deleted {
+			t.Errorf("%s: got deleted %v, want %v", c.doc, deleted, c.deleted)
+		}
+		if output.ID == "" {
+			t.Errorf("%s: missing id", c.doc)
+		}
+	}
+}
+
+func TestKind(t *testing.T) {
+	var cases = []struct {
+		doc          string
+		genre        string
+		journalTitle string
+		bookTitle    string
+	}{
+		{`<Record><metadata><dc><type>doc-type:bookPart</type><date>2003</date>
+			<source>Knapp, Gudrun-Axeli (Hrsg.): Achsen der Differenz (Münster: Westfälisches Dampfboot, 2003), 73-100</source>
+			</dc></metadata></Record>`, "bookitem", "", "Achsen der Differenz"},
+		{`<Record><metadata><dc><type>Text</type><date>2003</date>
+			<source>Feministische Studien, Jg. 21, H. 2, 2003, 5-20</source>
+			</dc></metadata></Record>`, "article", "Feministische Studien, Jg. 21, H. 2, 2003, 5-20", ""},
+	}
+	for _, c := range cases {
+		var record Record
+		if err := xml.Unmarshal([]byte(c.doc), &record); err != nil {
+			t.Fatal(err)
+		}
+		output, err := record.ToIntermediateSchema()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output.Genre != c.genre || output.JournalTitle != c.journalTitle || output.BookTitle != c.bookTitle {
+			t.Errorf("got %q %q %q, want %q %q %q", output.Genre, output.JournalTitle, output.BookTitle,
+				c.genre, c.journalTitle, c.bookTitle)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package genios
+
+import (
+	"archive/zip"
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// isDeletionList returns true, if a file in a delivery looks like a deletion
+// list (LOESCHLISTE).
+func isDeletionList(name string) bool {
+	base := strings.ToLower(filepath.Base(name))
+	return strings.Contains(base, "loesch") || strings.Contains(base, "delet")
+}
+
+// ReadDeletionList reads identifiers from a deletion list, one per line.
+// Identifiers are either of the form SOURCE__ID or a bare document ID. Empty
+// lines and lines starting with # are ignored.
+func ReadDeletionList(r io.Reader) (ids []string, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ids = append(ids, line)
+	}
+	return ids, scanner.Err()
+}
+
+// Delivery reads documents from a genios delivery: a zip file containing XML
+// documents and deletion lists, or a directory of such zip files. Zip files
+// are processed in lexicographic order, which is chronological for the usual
+// dated file names. A document is skipped, if it is listed in a deletion list
+// of the same or a later zip file.
+type Delivery struct {
+	Filenames []string
+
+	// deleted maps an identifier to the index of the last zip file, that
+	// deletes it.
+	deleted map[string]int
+	// revived are deleted identifiers, that are delivered again later.
+	revived map[string]bool
+
+	// Iteration state.
+	index   int
+	archive *zip.ReadCloser
+	files   []*zip.File
+	rc      io.ReadCloser
+	reader  *Reader
+}
+
+// OpenDelivery opens a single zip file or all zip files in a directory and
+// reads all deletion lists.
+func OpenDelivery(path string) (*Delivery, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &Delivery{deleted: make(map[string]int), revived: make(map[string]bool)}
+	if fi.IsDir() {
+		infos, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			if !info.IsDir() && strings.HasSuffix(strings.ToLower(info.Name()), ".zip") {
+				d.Filenames = append(d.Filenames, filepath.Join(path, info.Name()))
+			}
+		}
+	} else {
+		d.Filenames = []string{path}
+	}
+	sort.Strings(d.Filenames)
+	for i, filename := range d.Filenames {
+		if err := d.readDeletions(i, filename); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// readDeletions reads all deletion lists from a single zip file.
+func (d *Delivery) readDeletions(index int, filename string) error {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	for _, f := range archive.File {
+		if !isDeletionList(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		ids, err := ReadDeletionList(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			d.deleted[id] = index
+		}
+	}
+	return nil
+}
+
+// Deletions returns all identifiers found in deletion lists, sorted. Only
+// after all documents have been read, documents deleted and delivered again
+// later are excluded, refs. Drain.
+func (d *Delivery) Deletions() (ids []string) {
+	for id := range d.deleted {
+		if !d.revived[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// isDeleted returns true, if a document from the zip file with the given
+// index is deleted by the same or a later delivery.
+func (d *Delivery) isDeleted(doc *Document, index int) bool {
+	ids := []string{doc.SourceAndID(), strings.TrimSpace(doc.ID)}
+	for _, id := range ids {
+		if i, ok := d.deleted[id]; ok && i >= index {
+			return true
+		}
+	}
+	for _, id := range ids {
+		if _, ok := d.deleted[id]; ok {
+			d.revived[id] = true
+		}
+	}
+	return false
+}
+
+// closeCurrent closes the currently open file and archive, if any.
+func (d *Delivery) closeCurrent() error {
+	var err error
+	if d.rc != nil {
+		err = d.rc.Close()
+		d.rc, d.reader = nil, nil
+	}
+	if d.archive != nil && len(d.files) == 0 {
+		if cerr := d.archive.Close(); err == nil {
+			err = cerr
+		}
+		d.archive = nil
+	}
+	return err
+}
+
+// Next returns the next document, that has not been deleted, or io.EOF.
+func (d *Delivery) Next() (*Document, error) {
+	for {
+		if d.reader != nil {
+			doc, err := d.reader.Next()
+			if err == io.EOF {
+				if err := d.closeCurrent(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if d.isDeleted(doc, d.index-1) {
+				doc.Release()
+				continue
+			}
+			return doc, nil
+		}
+		if len(d.files) > 0 {
+			f := d.files[0]
+			d.files = d.files[1:]
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			d.rc, d.reader = rc, NewReader(rc)
+			continue
+		}
+		if d.archive != nil {
+			if err := d.closeCurrent(); err != nil {
+				return nil, err
+			}
+		}
+		if d.index == len(d.Filenames) {
+			return nil, io.EOF
+		}
+		archive, err := zip.OpenReader(d.Filenames[d.index])
+		if err != nil {
+			return nil, err
+		}
+		d.index++
+		d.archive = archive
+		for _, f := range archive.File {
+			if strings.HasSuffix(strings.ToLower(f.Name), ".xml") && !isDeletionList(f.Name) {
+				d.files = append(d.files, f)
+			}
+		}
+		sort.Slice(d.files, func(i, j int) bool { return d.files[i].Name < d.files[j].Name })
+	}
+}
+
+// Drain reads the remaining documents without returning them, so Deletions
+// is complete, even if iteration stopped early, e.g. for a sample.
+func (d *Delivery) Drain() error {
+	for {
+		doc, err := d.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		doc.Release()
+	}
+}
+
+// Close releases any open files.
+func (d *Delivery) Close() error {
+	d.files = nil
+	return d.closeCurrent()
+}
@@ -0,0 +1,127 @@
+package genios
+
+import (
+	"archive/zip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeZip creates a zip file with the given file names and contents.
+func writeZip(t *testing.T, filename string, files map[string]string) {
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDelivery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-genios-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeZip(t, filepath.Join(dir, "20190101.zip"), map[string]string{
+		"a.xml":           `<Document ID="1"><Source>S</Source></Document>`,
+		"b.xml":           `<Document ID="2"><Source>S</Source></Document>`,
+		"c.xml":           `<Document ID="3"><Source>S</Source></Document>`,
+		"LOESCHLISTE.txt": "3\n",
+	})
+	writeZip(t, filepath.Join(dir, "20190102.zip"), map[string]string{
+		"d.xml":           `<Document ID="3"><Source>S</Source></Document>`,
+		"e.xml":           `<Document ID="4"><Source>S</Source></Document>`,
+		"loeschliste.txt": "# deleted\nS__1\n",
+	})
+	if err := ioutil.WriteFile(filepath.Join(dir, "README"), []byte("ignored"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := OpenDelivery(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	var ids []string
+	for {
+		doc, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, doc.SourceAndID())
+	}
+	// 1 is deleted later, 3 is deleted and delivered again.
+	want := []string{"S__2", "S__3", "S__4"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("Next: got %v, want %v", ids, want)
+	}
+	// 3 was delivered again, so it is not deleted.
+	if got := d.Deletions(); !reflect.DeepEqual(got, []string{"S__1"}) {
+		t.Errorf("Deletions: got %v", got)
+	}
+}
+
+func TestDeliveryDrain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-genios-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeZip(t, filepath.Join(dir, "20190101.zip"), map[string]string{
+		"a.xml":           `<Document ID="1"><Source>S</Source></Document>`,
+		"LOESCHLISTE.txt": "S__2\nS__9\n",
+	})
+	writeZip(t, filepath.Join(dir, "20190102.zip"), map[string]string{
+		"b.xml": `<Document ID="2"><Source>S</Source></Document>`,
+	})
+	d, err := OpenDelivery(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if _, err := d.Next(); err != nil {
+		t.Fatal(err)
+	}
+	// 2 is delivered again, but has not been read yet.
+	if got := d.Deletions(); !reflect.DeepEqual(got, []string{"S__2", "S__9"}) {
+		t.Errorf("Deletions: got %v", got)
+	}
+	if err := d.Drain(); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Deletions(); !reflect.DeepEqual(got, []string{"S__9"}) {
+		t.Errorf("Deletions after Drain: got %v, want [S__9]", got)
+	}
+}
+
+func TestDeletedFincIDs(t *testing.T) {
+	resolved, unresolved := DeletedFincIDs([]string{"3", "S__1"})
+	if want := []string{(Document{ID: "1", Source: "S"}).FincID()}; !reflect.DeepEqual(resolved, want) {
+		t.Errorf("DeletedFincIDs: got %v, want %v", resolved, want)
+	}
+	if !reflect.DeepEqual(unresolved, []string{"3"}) {
+		t.Errorf("DeletedFincIDs: got %v, want [3]", unresolved)
+	}
+}
@@ -22,11 +22,13 @@
 package genios
 
 import (
-	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -34,7 +36,9 @@ import (
 	"github.com/miku/span"
 	"github.com/miku/span/assetutil"
 	"github.com/miku/span/container"
+	"github.com/miku/span/dates"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
 )
 
 const (
@@ -54,6 +58,10 @@ const (
 	maxAuthorLength = 200
 	minAuthorLength = 4
 	maxTitleLength  = 2048
+	// maxFallbackTitleLength limits titles taken from the fulltext.
+	maxFallbackTitleLength = 200
+	// maxDateSearchLength limits the search for dates in fulltext.
+	maxDateSearchLength = 1000
 )
 
 // Document represents a Genios document.
@@ -81,12 +89,117 @@ var (
 	rawDateReplacer = strings.NewReplacer(`"`, "", "\n", "", "\t", "")
 	// acceptedLanguages restricts the possible languages for detection.
 	acceptedLanguages = container.NewStringSet("deu", "eng")
-	// dbmap maps a database name to one or more "package names"
-	dbmap = assetutil.MustLoadStringSliceMap("assets/genios/dbmap.json")
+	// languageGuesser only looks at the beginning of long texts.
+	languageGuesser = span.LanguageGuesser{Accepted: acceptedLanguages, MinLength: 20, MaxLength: 4096}
+	// DatabaseMap maps a database name to one or more "package names". Use
+	// LoadDatabaseMap to read a different mapping at runtime.
+	DatabaseMap = assetutil.MustLoadStringSliceMap("assets/genios/dbmap.json")
+
+	// RefTypeMap maps a module, database or package name to a RIS type, e.g.
+	// for newspapers or ebooks; DefaultRefType is used for unmapped names.
+	RefTypeMap = assetutil.MustLoadStringMap("assets/genios/reftypes.json")
+	// FormatMap maps a module, database or package name to a finc format;
+	// Format is used for unmapped names.
+	FormatMap = assetutil.MustLoadStringMap("assets/genios/formats.json")
+
+	// NoFulltext lists database or package names (as in DatabaseMap), whose
+	// fulltext must not be indexed, e.g. for licensing reasons.
+	NoFulltext = container.NewStringSet()
+
+	// unmapped counts documents from databases without package names.
+	unmapped   = make(map[string]int)
+	unmappedMu sync.Mutex
 	// yearPattern matches YYYY
 	yearPattern = regexp.MustCompile(`[12][0-9][0-9][0-9]`)
+	// datePattern matches DD.MM.YYYY, with optional leading zeros.
+	datePattern = regexp.MustCompile(`\b[0-3]?[0-9]\.\s?[01]?[0-9]\.\s?[12][0-9][0-9][0-9]\b`)
+
+	// dateSources counts the fields dates were found in.
+	dateSources   = make(map[string]int)
+	dateSourcesMu sync.Mutex
+
+	// TitleFallbacks maps a database name to the fields tried in order, if
+	// a document has no title: "text" (first sentence of the fulltext) or
+	// "source" (source and date). The "*" entry applies to all other
+	// databases. An empty list skips documents without title.
+	TitleFallbacks = assetutil.MustLoadStringSliceMap("assets/genios/titles.json")
+	// titleSources counts the fields titles were taken from.
+	titleSources   = make(map[string]int)
+	titleSourcesMu sync.Mutex
+	// sentenceEnd matches the end of a sentence.
+	sentenceEnd = regexp.MustCompile(`[.!?](\s|$)`)
+
+	// authorClues mark author substrings, that are not names; this is just
+	// the tip of the iceberg.
+	authorClues = []string{"www.", "http:", "&quot", "part 1 of", "part 2 of",
+		"Copyright", "(c)", "All rights reserved", "he said"}
+
+	// documentPool holds released documents for reuse.
+	documentPool = sync.Pool{New: func() interface{} { return new(Document) }}
 )
 
+// LoadDatabaseMap reads and validates a database to package names mapping,
+// in the same format as assets/genios/dbmap.json.
+func LoadDatabaseMap(r io.Reader) (container.StringSliceMap, error) {
+	var m container.StringSliceMap
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	if len(m) == 0 {
+		return nil, fmt.Errorf("genios: empty database map")
+	}
+	for db, names := range m {
+		if strings.TrimSpace(db) == "" {
+			return nil, fmt.Errorf("genios: empty database name")
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("genios: no package names for database %s", db)
+		}
+		for _, name := range names {
+			if strings.TrimSpace(name) == "" {
+				return nil, fmt.Errorf("genios: empty package name for database %s", db)
+			}
+		}
+	}
+	return m, nil
+}
+
+// UnmappedDatabases returns the names of databases seen during conversion,
+// which had no package names, along with the number of documents.
+func UnmappedDatabases() map[string]int {
+	unmappedMu.Lock()
+	defer unmappedMu.Unlock()
+	result := make(map[string]int)
+	for k, v := range unmapped {
+		result[k] = v
+	}
+	return result
+}
+
+// NewDocument returns an empty document, reusing a released one, if
+// possible.
+func NewDocument() *Document {
+	return documentPool.Get().(*Document)
+}
+
+// Release resets the document and returns it for reuse by NewDocument. The
+// document must not be used afterwards.
+func (doc *Document) Release() {
+	*doc = Document{}
+	documentPool.Put(doc)
+}
+
+// Size returns the approximate size of the document in bytes, dominated by
+// the fulltext, so batches of large documents can be kept small.
+func (doc *Document) Size() int {
+	size := len(doc.Title) + len(doc.Abstract) + len(doc.Text) + len(doc.Descriptors) +
+		len(doc.Source) + len(doc.PublicationTitle)
+	for _, s := range doc.RawAuthors {
+		size += len(s)
+	}
+	return size
+}
+
 // Headings returns subject headings.
 func (doc Document) Headings() []string {
 	var headings []string
@@ -106,35 +219,174 @@ func (doc Document) Headings() []string {
 }
 
 // Date returns the date as noted in the document. There might be two values:
-// Date and Year. Defaults to Year, fallback to Date, refs #12193.
+// Date and Year. Defaults to Year, fallback to Date, refs #12193. If both are
+// unusable, look for dates in Source or the beginning of Text and finally for
+// a year in Issue or Volume.
 func (doc Document) Date() (time.Time, error) {
-	rawYear := strings.TrimSpace(rawDateReplacer.Replace(doc.Year))
-	if yearPattern.MatchString(rawYear) {
-		// Prefer Year, refs #12193.
-		return time.Parse("2006", rawYear)
+	t, _, err := doc.date()
+	return t, err
+}
+
+// date returns the date along with the name of the field it was found in.
+func (doc Document) date() (time.Time, string, error) {
+	// Prefer Year, refs #12193.
+	if d, err := dates.Parse(rawDateReplacer.Replace(doc.Year)); err == nil {
+		return d.Time, "year", nil
 	}
-	// Fallback to Date, refs #12193.
+	// Fallback to Date, refs #12193, usually YYYYMMDD, sometimes with a time.
 	raw := strings.TrimSpace(rawDateReplacer.Replace(doc.RawDate))
-	if len(raw) > 8 {
-		raw = raw[:8]
+	d, err := dates.Parse(raw)
+	if err != nil && len(raw) > 8 {
+		d, err = dates.Parse(raw[:8])
+	}
+	if err == nil {
+		return d.Time, "date", nil
+	}
+	if t, ok := findDate(doc.Source); ok {
+		return t, "source", nil
+	}
+	if t, ok := findYear(doc.Source); ok {
+		return t, "source", nil
+	}
+	text := doc.Text
+	if len(text) > maxDateSearchLength {
+		text = text[:maxDateSearchLength]
+	}
+	if t, ok := findDate(text); ok {
+		return t, "text", nil
+	}
+	if t, ok := findYear(doc.Issue); ok {
+		return t, "issue", nil
+	}
+	if t, ok := findYear(doc.Volume); ok {
+		return t, "volume", nil
+	}
+	return time.Time{}, "", err
+}
+
+// findDate returns the first valid date of the form 2.1.2006 in s.
+func findDate(s string) (time.Time, bool) {
+	for _, m := range datePattern.FindAllStringSubmatch(s, -1) {
+		d, err := dates.Parse(m[0])
+		if err == nil && plausibleYear(d.Year()) {
+			return d.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// findYear returns the first plausible year in s.
+func findYear(s string) (time.Time, bool) {
+	for _, v := range yearPattern.FindAllString(s, -1) {
+		t, err := time.Parse("2006", v)
+		if err == nil && plausibleYear(t.Year()) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// plausibleYear excludes years, that are likely page numbers or similar.
+func plausibleYear(year int) bool {
+	return year >= 1900 && year <= time.Now().Year()+1
+}
+
+// DateSources returns the number of documents, by field the date was taken
+// from; "none" counts documents without a usable date.
+func DateSources() map[string]int {
+	dateSourcesMu.Lock()
+	defer dateSourcesMu.Unlock()
+	result := make(map[string]int)
+	for k, v := range dateSources {
+		result[k] = v
+	}
+	return result
+}
+
+// title returns the title or a fallback, along with the name of the field it
+// was taken from, see TitleFallbacks.
+func (doc Document) title(withFulltext bool, date time.Time) (string, string, error) {
+	if t := strings.TrimSpace(doc.Title); t != "" {
+		return t, "title", nil
+	}
+	fallbacks, ok := TitleFallbacks[doc.DB]
+	if !ok {
+		fallbacks = TitleFallbacks["*"]
+	}
+	for _, f := range fallbacks {
+		switch f {
+		case "text":
+			if !withFulltext {
+				continue
+			}
+			if t := firstSentence(doc.Text); t != "" {
+				return t, f, nil
+			}
+		case "source":
+			source := strings.Join(strings.Fields(doc.Source), " ")
+			if isNomenNescio(source) {
+				continue
+			}
+			if date.IsZero() {
+				return source, f, nil
+			}
+			return source + ", " + date.Format("02.01.2006"), f, nil
+		default:
+			return "", "", fmt.Errorf("unknown title fallback for %s: %s", doc.DB, f)
+		}
 	}
-	return time.Parse("20060102", raw)
+	return "", "", fmt.Errorf("empty title")
+}
+
+// firstSentence returns the first sentence of a text, cut at a word boundary,
+// if it is too long.
+func firstSentence(text string) string {
+	if len(text) > 4*maxFallbackTitleLength {
+		text = text[:4*maxFallbackTitleLength]
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if loc := sentenceEnd.FindStringIndex(text); loc != nil {
+		text = text[:loc[0]+1]
+	}
+	if len(text) > maxFallbackTitleLength {
+		text = text[:maxFallbackTitleLength]
+		if i := strings.LastIndex(text, " "); i > 0 {
+			text = text[:i]
+		}
+		text = strings.TrimRight(text, ",;:-") + " ..."
+	}
+	if isNomenNescio(text) {
+		return ""
+	}
+	return text
+}
+
+// TitleSources returns the number of documents, by field the title was taken
+// from; "none" counts skipped documents without a usable title.
+func TitleSources() map[string]int {
+	titleSourcesMu.Lock()
+	defer titleSourcesMu.Unlock()
+	result := make(map[string]int)
+	for k, v := range titleSources {
+		result[k] = v
+	}
+	return result
 }
 
 // SourceAndID will probably be a unique identifier. An ID alone might not be enough.
 func (doc Document) SourceAndID() string {
-	return fmt.Sprintf("%s__%s", strings.TrimSpace(doc.Source), strings.TrimSpace(doc.ID))
+	return strings.TrimSpace(doc.Source) + "__" + strings.TrimSpace(doc.ID)
 }
 
 // URL returns a constructed URL at the publishers site.
 func (doc Document) URL() string {
-	return fmt.Sprintf("https://www.wiso-net.de/document/%s", doc.SourceAndID())
+	return "https://www.wiso-net.de/document/" + doc.SourceAndID()
 }
 
 // isNomenNescio returns true, if the field is de-facto empty.
 func isNomenNescio(s string) bool {
-	t := strings.ToLower(strings.TrimSpace(s))
-	return t == "n.n." || t == ""
+	t := strings.TrimSpace(s)
+	return t == "" || strings.EqualFold(t, "n.n.")
 }
 
 // stringContainsAny returns true, if string contains any of the strings given.
@@ -147,8 +399,14 @@ func stringContainsAny(s string, needles []string) bool {
 	return false
 }
 
-// Authors returns a list of authors. Formatting is not cleaned up, so you'll
-// get any combination of surname and given names.
+// parseAuthorName splits a raw name into first and last name, see
+// finc.ParseAuthor.
+func parseAuthorName(s string) finc.Author {
+	return finc.ParseAuthor(s)
+}
+
+// Authors returns a list of authors. Names are split into first and last
+// name, if possible.
 func (doc Document) Authors() (authors []finc.Author) {
 	for _, s := range doc.RawAuthors {
 		fields := strings.FieldsFunc(s, func(r rune) bool {
@@ -169,14 +427,11 @@ func (doc Document) Authors() (authors []finc.Author) {
 			if len(name) < minAuthorLength {
 				continue
 			}
-			// Author substrings to filter out, this is just the tip of the iceberg.
-			clues := []string{"www.", "http:", "&quot", "part 1 of", "part 2 of",
-				"Copyright", "(c)", "All rights reserved", "he said"}
-			if stringContainsAny(name, clues) {
+			if stringContainsAny(name, authorClues) {
 				continue
 			}
 			if len(name) < maxAuthorLength {
-				authors = append(authors, finc.Author{Name: name})
+				authors = append(authors, parseAuthorName(name))
 			}
 		}
 	}
@@ -189,48 +444,102 @@ func (doc Document) ISSNList() []string {
 	for _, s := range span.ISSNPattern.FindAllString(doc.ISSN, -1) {
 		issns.Add(s)
 	}
-	return issns.Values()
+	return issns.SortedValues()
 }
 
 // FincID uses SourceAndID as starting point.
 func (doc Document) FincID() string {
-	return fmt.Sprintf("ai-%s-%s", SourceID, base64.RawURLEncoding.EncodeToString([]byte(doc.SourceAndID())))
+	return fincID(doc.SourceAndID())
 }
 
-// Languages returns the given and guessed languages found in abstract and
-// fulltext. Note: This is slow. Skip detection on too short strings.
-func (doc Document) Languages() []string {
-	set := container.NewStringSet()
-
-	vals := []string{doc.Title, doc.Text}
+// fincID derives the finc identifier from a SOURCE__ID value.
+func fincID(sourceAndID string) string {
+	return mint.ID(SourceID, sourceAndID, "", mint.Base64)
+}
 
-	for _, s := range vals {
-		if len(s) < 20 {
-			continue
+// DeletedFincIDs turns identifiers from deletion lists into finc identifiers,
+// which can be used to remove withdrawn documents from an index. Only
+// identifiers of the form SOURCE__ID can be resolved, bare document IDs are
+// returned as unresolved.
+func DeletedFincIDs(ids []string) (resolved, unresolved []string) {
+	for _, id := range ids {
+		if strings.Contains(id, "__") {
+			resolved = append(resolved, fincID(id))
+		} else {
+			unresolved = append(unresolved, id)
 		}
-		lang, err := span.DetectLang3(s)
-		if err != nil {
-			continue
+	}
+	return resolved, unresolved
+}
+
+// Languages returns the guessed languages found in title and fulltext, or the
+// given language, if span.TrustRecordLanguage is set.
+func (doc Document) Languages() []string {
+	return languageGuesser.Guess(doc.Language, doc.Title, doc.Text)
+}
+
+// fulltextSuppressed returns true, if the database or any of its package
+// names is listed in NoFulltext.
+func (doc Document) fulltextSuppressed(packageNames []string) bool {
+	if NoFulltext.Contains(doc.DB) {
+		return true
+	}
+	for _, name := range packageNames {
+		if NoFulltext.Contains(name) {
+			return true
 		}
-		if !acceptedLanguages.Contains(lang) {
-			continue
+	}
+	return false
+}
+
+// kindNames returns the names used to look up RIS type and format: modules
+// first, then database and package names.
+func (doc Document) kindNames(packageNames []string) []string {
+	names := make([]string, 0, len(doc.Modules)+1+len(packageNames))
+	names = append(names, doc.Modules...)
+	names = append(names, doc.DB)
+	return append(names, packageNames...)
+}
+
+// RefTypeAndFormat returns the RIS type, finc format and genre of a document,
+// based on the first module, database or package name found in RefTypeMap
+// and FormatMap, respectively.
+func (doc Document) RefTypeAndFormat(packageNames []string) (refType, format, genre string) {
+	refType, format, genre = DefaultRefType, Format, Genre
+	names := doc.kindNames(packageNames)
+	for _, name := range names {
+		if v, ok := RefTypeMap[strings.TrimSpace(name)]; ok {
+			refType = v
+			break
 		}
-		if lang == "und" {
-			continue
+	}
+	for _, name := range names {
+		if v, ok := FormatMap[strings.TrimSpace(name)]; ok {
+			format = v
+			break
 		}
-		set.Add(lang)
 	}
-
-	return set.Values()
+	if refType == "EBOOK" {
+		genre = "book"
+	}
+	return refType, format, genre
 }
 
 // ToIntermediateSchema converts a genios document into an intermediate schema document.
-// Will fail/skip records with unusable dates.
+// Will fail/skip records with unusable dates, see DateSources.
 func (doc Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	var err error
 	output := finc.NewIntermediateSchema()
 
-	output.Date, err = doc.Date()
+	var source string
+	output.Date, source, err = doc.date()
+	dateSourcesMu.Lock()
+	if err != nil {
+		dateSources["none"]++
+	} else {
+		dateSources[source]++
+	}
+	dateSourcesMu.Unlock()
 	if err != nil {
 		return output, span.Skip{Reason: err.Error()}
 	}
@@ -240,18 +549,30 @@ func (doc Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 
 	output.URL = append(output.URL, doc.URL())
 
-	if isNomenNescio(doc.Abstract) {
+	var packageNames = DatabaseMap.LookupDefault(doc.DB, []string{})
+	withFulltext := !doc.fulltextSuppressed(packageNames)
+
+	if isNomenNescio(doc.Abstract) && withFulltext {
 		cutoff := len(doc.Text)
 		if cutoff > textAsAbstractCutoff {
 			cutoff = textAsAbstractCutoff
 		}
 		output.Abstract = strings.TrimSpace(doc.Text[:cutoff])
-	} else {
+	} else if !isNomenNescio(doc.Abstract) {
 		output.Abstract = strings.TrimSpace(doc.Abstract)
-
 	}
 
-	output.ArticleTitle = strings.TrimSpace(doc.Title)
+	output.ArticleTitle, source, err = doc.title(withFulltext, output.Date)
+	titleSourcesMu.Lock()
+	if err != nil {
+		titleSources["none"]++
+	} else {
+		titleSources[source]++
+	}
+	titleSourcesMu.Unlock()
+	if err != nil {
+		return output, span.Skip{Reason: err.Error()}
+	}
 	if len(output.ArticleTitle) > maxTitleLength {
 		return output, span.Skip{Reason: fmt.Sprintf("article title too long: %d", len(output.ArticleTitle))}
 	}
@@ -269,16 +590,15 @@ func (doc Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 		output.Volume = strings.TrimSpace(doc.Volume)
 	}
 
-	output.Fulltext = doc.Text
-	output.Format = Format
-	output.Genre = Genre
+	if withFulltext {
+		output.Fulltext = doc.Text
+	}
+	output.RefType, output.Format, output.Genre = doc.RefTypeAndFormat(packageNames)
 	output.Languages = doc.Languages()
 
-	var packageNames = dbmap.LookupDefault(doc.DB, []string{})
-
-	var prefixedPackageNames []string
+	prefixedPackageNames := make([]string, 0, len(packageNames))
 	for _, name := range packageNames {
-		prefixedPackageNames = append(prefixedPackageNames, fmt.Sprintf("Genios (%s)", name))
+		prefixedPackageNames = append(prefixedPackageNames, "Genios ("+name+")")
 	}
 
 	// hack, to move Genios (LIT) further down
@@ -286,7 +606,9 @@ func (doc Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 
 	// Note DB name as well as package name (Wiwi, Sowi, Recht, etc.) as well
 	// as kind, which - a bit confusingly - is also package in licensing terms (FZS).
-	output.Packages = append([]string{doc.DB}, prefixedPackageNames...)
+	output.Packages = make([]string, 0, 1+len(prefixedPackageNames)+len(doc.Modules))
+	output.Packages = append(output.Packages, doc.DB)
+	output.Packages = append(output.Packages, prefixedPackageNames...)
 
 	// 2018-06-01, Modules are added, (1) add them in addition to existing
 	// package names, later XXX: (2) remove own tags.
@@ -295,26 +617,19 @@ func (doc Document) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	if len(prefixedPackageNames) > 0 {
 		output.MegaCollections = []string{prefixedPackageNames[0]}
 	} else {
-		log.Printf("genios: db is not associated with package: %s, using generic default", doc.DB)
-		output.MegaCollections = []string{fmt.Sprintf("Genios")}
+		unmappedMu.Lock()
+		if unmapped[doc.DB] == 0 {
+			log.Printf("genios: db is not associated with package: %s, using generic default", doc.DB)
+		}
+		unmapped[doc.DB]++
+		unmappedMu.Unlock()
+		output.MegaCollections = []string{"Genios"}
 	}
 
-	id := doc.FincID()
-	// 250 is a limit on memcached keys; offending key was:
-	// ai-48-R1JFUl9fU2NoZWliIEVsZWt0cm90ZWNobmlrIEdtYkggwr\
-	// dTdGV1ZXJ1bmdzYmF1IMK3SW5kdXN0cmllLUVsZWt0cm9uaWsgwr\
-	// dFbGVrdHJvbWFzY2hpbmVuYmF1IMK3SW5kdXN0cmllLVNlcnZpY2\
-	// UgwrdEYW5mb3NzLVN5c3RlbXBhcnRuZXIgwrdEYW5mb3NzIERyaX\
-	// ZlcyBDZW50ZXIgwrdNYXJ0aW4gU2ljaGVyaGVpdHN0ZWNobmlr
-	if len(id) > span.KeyLengthLimit {
-		return output, span.Skip{Reason: fmt.Sprintf("id too long: %s", id)}
-	}
-	output.ID = id
+	output.ID = doc.FincID()
 	output.RecordID = doc.ID
 	output.SourceID = SourceID
 	output.Subjects = doc.Headings()
 
-	output.RefType = DefaultRefType
-
 	return output, nil
 }
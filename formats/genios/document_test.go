@@ -0,0 +1,153 @@
+package genios
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miku/span/container"
+)
+
+func TestParseAuthorName(t *testing.T) {
+	var cases = []struct {
+		s                     string
+		name, first, lastname string
+	}{
+		{"Müller, Hans", "", "Hans", "Müller"},
+		{"Hans Müller", "", "Hans", "Müller"},
+		{"Hans-Peter Müller", "", "Hans-Peter", "Müller"},
+		{"Anna Maria Schmidt", "", "Anna Maria", "Schmidt"},
+		{"Ludwig van Beethoven", "", "Ludwig", "van Beethoven"},
+		{"Ursula von der Leyen", "", "Ursula", "von der Leyen"},
+		{"von Weizsäcker, Richard", "", "Richard", "von Weizsäcker"},
+		{"Prof. Dr. Hans Müller", "", "Hans", "Müller"},
+		{"Dr. Müller", "Dr. Müller", "", ""},
+		{"Redaktion", "Redaktion", "", ""},
+		{"Hans Müller und Anna Schmidt", "Hans Müller und Anna Schmidt", "", ""},
+		{"Müller, Hans, Schmidt, Anna", "Müller, Hans, Schmidt, Anna", "", ""},
+	}
+	for _, c := range cases {
+		a := parseAuthorName(c.s)
+		if a.Name != c.name || a.FirstName != c.first || a.LastName != c.lastname {
+			t.Errorf("parseAuthorName(%q): got %q %q %q, want %q %q %q",
+				c.s, a.Name, a.FirstName, a.LastName, c.name, c.first, c.lastname)
+		}
+	}
+}
+
+func TestNoFulltext(t *testing.T) {
+	defer func(s *container.StringSet) { NoFulltext = s }(NoFulltext)
+	NoFulltext = container.NewStringSet("SECRET")
+
+	var cases = []struct {
+		doc          Document
+		fulltext     string
+		withAbstract bool
+	}{
+		{Document{DB: "OPEN", Year: "2019", Title: "Lorem", Text: "Lorem ipsum"}, "Lorem ipsum", true},
+		{Document{DB: "SECRET", Year: "2019", Title: "Lorem", Text: "Lorem ipsum"}, "", false},
+	}
+	for _, c := range cases {
+		is, err := c.doc.ToIntermediateSchema()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if is.Fulltext != c.fulltext {
+			t.Errorf("%s: got fulltext %q, want %q", c.doc.DB, is.Fulltext, c.fulltext)
+		}
+		if (is.Abstract != "") != c.withAbstract {
+			t.Errorf("%s: got abstract %q", c.doc.DB, is.Abstract)
+		}
+	}
+}
+
+func TestDate(t *testing.T) {
+	var cases = []struct {
+		doc    Document
+		date   string
+		source string
+	}{
+		{Document{Year: "2019", RawDate: "20180101"}, "2019-01-01", "year"},
+		{Document{Year: "o.J.", RawDate: "20180304"}, "2018-03-04", "date"},
+		{Document{Source: "Handelsblatt vom 12.03.2017, S. 12"}, "2017-03-12", "source"},
+		{Document{Source: "Die Zeit Nr. 12/2016"}, "2016-01-01", "source"},
+		{Document{Source: "Die Zeit", Text: "Berlin, 1. 2. 2015. Lorem ipsum"}, "2015-02-01", "text"},
+		{Document{Issue: "2014/03", Volume: "12"}, "2014-01-01", "issue"},
+		{Document{Issue: "3", Volume: "2013"}, "2013-01-01", "volume"},
+		{Document{Issue: "3", Volume: "1234"}, "", ""},
+		{Document{}, "", ""},
+	}
+	for _, c := range cases {
+		date, source, err := c.doc.date()
+		if c.date == "" {
+			if err == nil {
+				t.Errorf("date(%v): got %v, want error", c.doc, date)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("date(%v): %v", c.doc, err)
+			continue
+		}
+		if date.Format("2006-01-02") != c.date || source != c.source {
+			t.Errorf("date(%v): got %v %s, want %s %s", c.doc, date.Format("2006-01-02"), source, c.date, c.source)
+		}
+	}
+}
+
+func TestRefTypeAndFormat(t *testing.T) {
+	var cases = []struct {
+		doc     Document
+		refType string
+		format  string
+		genre   string
+	}{
+		{Document{DB: "XZWF"}, DefaultRefType, Format, Genre},
+		{Document{DB: "XZWF", Modules: []string{"Zeitungen"}}, "NEWS", "ElectronicNewspaper", "article"},
+		{Document{DB: "XZWF", Modules: []string{"unknown", "eBooks"}}, "EBOOK", "ElectronicBook", "book"},
+	}
+	for _, c := range cases {
+		refType, format, genre := c.doc.RefTypeAndFormat(nil)
+		if refType != c.refType || format != c.format || genre != c.genre {
+			t.Errorf("%v: got %s %s %s, want %s %s %s", c.doc.Modules,
+				refType, format, genre, c.refType, c.format, c.genre)
+		}
+	}
+}
+
+func TestTitle(t *testing.T) {
+	defer func(m container.StringSliceMap) { TitleFallbacks = m }(TitleFallbacks)
+	TitleFallbacks = container.StringSliceMap{
+		"*":    {"text", "source"},
+		"NEWS": {"source"},
+		"NONE": {},
+	}
+	date := time.Date(2017, 3, 12, 0, 0, 0, 0, time.UTC)
+	var cases = []struct {
+		doc    Document
+		title  string
+		source string
+	}{
+		{Document{Title: " Title \n"}, "Title", "title"},
+		{Document{Text: "Lorem ipsum.\nDolor sit amet."}, "Lorem ipsum.", "text"},
+		{Document{Text: "n.n.", Source: "Handelsblatt"}, "Handelsblatt, 12.03.2017", "source"},
+		{Document{DB: "NEWS", Text: "Lorem ipsum.", Source: "Handelsblatt"}, "Handelsblatt, 12.03.2017", "source"},
+		{Document{DB: "NONE", Text: "Lorem ipsum.", Source: "Handelsblatt"}, "", ""},
+		{Document{}, "", ""},
+	}
+	for _, c := range cases {
+		title, source, err := c.doc.title(true, date)
+		if c.title == "" {
+			if err == nil {
+				t.Errorf("title(%v): got %q, want error", c.doc, title)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("title(%v): %v", c.doc, err)
+			continue
+		}
+		if title != c.title || source != c.source {
+			t.Errorf("title(%v): got %q %s, want %q %s", c.doc, title, source, c.title, c.source)
+		}
+	}
+}
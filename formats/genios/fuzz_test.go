@@ -0,0 +1,26 @@
+//go:build go1.18
+// +build go1.18
+
+package genios
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func FuzzReader(f *testing.F) {
+	f.Add([]byte(`<GENIOS><Document ID="1" DB="A"><Year>2001</Year><Title>T</Title></Document></GENIOS>`))
+	f.Add([]byte(`<Document ID="1" DB="A"><Date>20010203</Date><Authors><Author>A, B; C/D</Author></Authors></Document>`))
+	f.Add([]byte(`<Document><Text>12.03.2004 &nbsp;</Text><Issue>2004</Issue></Document>`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		r := NewReader(bytes.NewReader(b))
+		for i := 0; i < 100; i++ {
+			doc, err := r.Next()
+			if err == io.EOF || err != nil {
+				return
+			}
+			doc.ToIntermediateSchema()
+		}
+	})
+}
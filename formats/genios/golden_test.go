@@ -0,0 +1,11 @@
+package genios
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/genios", golden.XML("Document", func() golden.Converter { return new(Document) }))
+}
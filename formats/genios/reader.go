@@ -0,0 +1,32 @@
+package genios
+
+import (
+	"io"
+
+	"github.com/miku/span/encoding/xmliter"
+)
+
+// Reader streams documents from XML. The input may contain any number of
+// Document elements at any depth, e.g. a GENIOS wrapper element or a number
+// of concatenated files.
+type Reader struct {
+	dec *xmliter.Decoder
+}
+
+// NewReader returns a reader, that reads documents from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: xmliter.NewDecoder(r, "Document")}
+}
+
+// Next returns the next document or io.EOF, if there are no more documents.
+// A missing closing wrapper element at the end of the input is tolerated.
+// Documents may be released for reuse after conversion, see
+// Document.Release.
+func (r *Reader) Next() (*Document, error) {
+	doc := NewDocument()
+	if err := r.dec.Decode(doc); err != nil {
+		doc.Release()
+		return nil, err
+	}
+	return doc, nil
+}
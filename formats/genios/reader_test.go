@@ -0,0 +1,87 @@
+package genios
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	var cases = []struct {
+		about string
+		r     io.Reader
+		ids   []string
+	}{
+		{"empty", strings.NewReader(""), nil},
+		{"wrapped", strings.NewReader(`<GENIOS><Document ID="1" DB="A"/><Document ID="2" DB="A"/></GENIOS>`), []string{"1", "2"}},
+		{"unclosed wrapper", strings.NewReader(`<GENIOS><Document ID="1"/>`), []string{"1"}},
+		{"concatenated", strings.NewReader(`<Document ID="1"/>` + "\n" + `<?xml version="1.0"?><Document ID="2"/>`), []string{"1", "2"}},
+	}
+	for _, c := range cases {
+		var ids []string
+		r := NewReader(c.r)
+		for {
+			doc, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", c.about, err)
+			}
+			ids = append(ids, doc.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(c.ids, ",") {
+			t.Errorf("%s: got %v, want %v", c.about, ids, c.ids)
+		}
+	}
+}
+
+func TestReaderFixture(t *testing.T) {
+	f, err := os.Open("../../fixtures/genios/genios.xml")
+	if err != nil {
+		t.Skipf("fixture: %v", err)
+	}
+	defer f.Close()
+	doc, err := NewReader(f).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ID != "200101002" || doc.DB != "XZWF" {
+		t.Errorf("Next: got %s %s, want 200101002 XZWF", doc.ID, doc.DB)
+	}
+}
+
+// benchmarkConvert reads, converts and serializes fixture documents,
+// optionally releasing them after conversion.
+func benchmarkConvert(b *testing.B, release bool) {
+	data, err := ioutil.ReadFile("../../fixtures/genios/genios.xml")
+	if err != nil {
+		b.Skipf("fixture: %v", err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := NewReader(bytes.NewReader(data))
+		for {
+			doc, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+			if output, err := doc.ToIntermediateSchema(); err == nil {
+				json.Marshal(output)
+			}
+			if release {
+				doc.Release()
+			}
+		}
+	}
+}
+
+func BenchmarkConvert(b *testing.B)       { benchmarkConvert(b, false) }
+func BenchmarkConvertPooled(b *testing.B) { benchmarkConvert(b, true) }
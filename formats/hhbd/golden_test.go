@@ -0,0 +1,11 @@
+package hhbd
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/hhbd", golden.XML("Record", func() golden.Converter { return new(Record) }))
+}
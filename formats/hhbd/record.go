@@ -1,7 +1,6 @@
 package hhbd
 
 import (
-	"encoding/base64"
 	"encoding/xml"
 	"fmt"
 	"regexp"
@@ -11,7 +10,9 @@ import (
 	"github.com/miku/span"
 
 	"github.com/miku/span/assetutil"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
 )
 
 var (
@@ -21,13 +22,16 @@ var (
 	RefTypes    = assetutil.MustLoadStringMap("assets/hhbd/reftypes.json")
 )
 
+// uniqueStrings removes duplicates, keeping the first occurrence, so output
+// is stable.
 func uniqueStrings(s []string) (result []string) {
 	m := make(map[string]bool)
 	for _, v := range s {
+		if m[v] {
+			continue
+		}
 		m[v] = true
-	}
-	for k := range m {
-		result = append(result, k)
+		result = append(result, v)
 	}
 	return
 }
@@ -125,7 +129,7 @@ func (record Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output := finc.NewIntermediateSchema()
 	output.RecordID = record.Header.Identifier.Text
 	output.SourceID = "107"
-	output.ID = fmt.Sprintf("ai-%s-%s", output.SourceID, base64.RawURLEncoding.EncodeToString([]byte(output.RecordID)))
+	output.ID = mint.ID(output.SourceID, output.RecordID, "", mint.Base64)
 	output.ArticleTitle = record.Metadata.Dc.Title.Text
 	output.MegaCollections = []string{"sid-107-col-heidelberg"}
 
@@ -161,7 +165,7 @@ func (record Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 			"[Korres.]", "", "[Begr.]", "", "[Verstorb.]", "", "[Vorredn.]", "",
 			"[Komp.]", "")
 		creator = r.Replace(creator)
-		output.Authors = append(output.Authors, finc.Author{Name: creator})
+		output.Authors = append(output.Authors, finc.ParseAuthor(creator))
 	}
 
 	// Subjects.
@@ -186,8 +190,10 @@ func (record Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output.Subjects = uniqueStrings(output.Subjects)
 	output.Abstract = record.Metadata.Dc.Alternative.Text
 
-	// URLs.
+	// URLs and DOI.
+	var ids []string
 	for _, id := range record.Metadata.Dc.Identifier {
+		ids = append(ids, id.Text)
 		if strings.HasPrefix(id.Text, "http") {
 			// XXX: Target contains IIIF manifest.
 			output.URL = append(output.URL, id.Text)
@@ -208,6 +214,7 @@ func (record Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 			}
 		}
 	}
+	output.DOI = doi.FromIdentifiers(ids...)
 
 	// Languages.
 	for _, l := range record.Metadata.Dc.Language {
@@ -0,0 +1,11 @@
+package highwire
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/highwire", golden.XML("Record", func() golden.Converter { return new(Record) }))
+}
@@ -1,14 +1,15 @@
 package highwire
 
 import (
-	"encoding/base64"
 	"encoding/xml"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/miku/span"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
 )
 
 // SourceIdentifier for internal bookkeeping.
@@ -50,8 +51,7 @@ type Record struct {
 func (r Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output := finc.NewIntermediateSchema()
 
-	encodedIdentifier := base64.RawURLEncoding.EncodeToString([]byte(r.Header.Identifier))
-	output.ID = fmt.Sprintf("ai-%s-%s", SourceIdentifier, encodedIdentifier)
+	output.ID = mint.ID(SourceIdentifier, r.Header.Identifier, "", mint.Base64)
 	output.RecordID = r.Header.Identifier
 	output.SourceID = SourceIdentifier
 	output.Genre = Genre
@@ -66,16 +66,14 @@ func (r Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 		output.ArticleTitle = r.Metadata.DC.Title[0]
 	}
 	for _, v := range r.Metadata.DC.Creator {
-		v = strings.TrimSpace(v)
-		v = strings.TrimRight(v, ",")
-		output.Authors = append(output.Authors, finc.Author{Name: v})
+		output.Authors = append(output.Authors, finc.ParseAuthor(v))
 	}
 	for _, v := range r.Metadata.DC.Identifier {
 		if strings.HasPrefix(v, "http") {
 			output.URL = append(output.URL, v)
 		}
-		if strings.HasPrefix(v, "http://dx.doi.org/") {
-			output.DOI = strings.Replace(v, "http://dx.doi.org/", "", -1)
+		if v := doi.Clean(v); v != "" {
+			output.DOI = v
 		}
 	}
 	output.Abstract = strings.Join(r.Metadata.DC.Description, "\n")
@@ -0,0 +1,11 @@
+package ieee
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/ieee", golden.XML("publication", func() golden.Converter { return new(Publication) }))
+}
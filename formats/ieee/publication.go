@@ -1,7 +1,6 @@
 package ieee
 
 import (
-	"encoding/base64"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -9,10 +8,11 @@ import (
 	"strings"
 	"time"
 
-	log "github.com/sirupsen/logrus"
-
 	"github.com/miku/span"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/logging"
+	"github.com/miku/span/mint"
 )
 
 const (
@@ -290,7 +290,7 @@ func (p Publication) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 
 	date, err := p.Date()
 	if err != nil {
-		log.Printf("date problem: %s: %s", err, is.ArticleTitle)
+		logging.Record(is).Warnf("date problem: %s: %s", err, is.ArticleTitle)
 		return is, span.Skip{Reason: err.Error()}
 	}
 	is.Date = date
@@ -306,24 +306,22 @@ func (p Publication) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 
 	if p.Volume.Article.Articleinfo.Amsid != "" {
 		is.URL = append(is.URL, fmt.Sprintf("http://ieeexplore.ieee.org/stamp/stamp.jsp?arnumber=%s", p.Volume.Article.Articleinfo.Amsid))
-		is.ID = fmt.Sprintf("ai-89-%s", base64.RawURLEncoding.EncodeToString([]byte(p.Volume.Article.Articleinfo.Amsid)))
+		is.ID = mint.ID(SourceID, p.Volume.Article.Articleinfo.Amsid, doi.Clean(p.Volume.Article.Articleinfo.Articledoi), mint.Base64)
 		is.RecordID = p.Volume.Article.Articleinfo.Amsid
 	} else {
-		log.Printf("warning: no identifier: %s", is.ArticleTitle)
+		logging.Record(is).Warnf("no identifier: %s", is.ArticleTitle)
 		return is, ErrNoIdentifier
 	}
-	if p.Volume.Article.Articleinfo.Articledoi != "" {
-		is.DOI = p.Volume.Article.Articleinfo.Articledoi
+	if v := doi.Clean(p.Volume.Article.Articleinfo.Articledoi); v != "" {
+		is.DOI = v
 		is.URL = append(is.URL, fmt.Sprintf("http://doi.org/%s", is.DOI))
 	}
 
 	is.Volume = p.Volume.Volumeinfo.Volumenum
 	is.Issue = p.Volume.Article.Articleinfo.Issuenum
 
-	is.StartPage = p.Volume.Article.Articleinfo.Artpagenums.Startpage
-	is.EndPage = p.Volume.Article.Articleinfo.Artpagenums.Endpage
-	is.Pages = fmt.Sprintf("%s-%s", is.StartPage, is.EndPage)
-	is.PageCount = p.PageCount()
+	is.Pages = finc.NewPages(p.Volume.Article.Articleinfo.Artpagenums.Startpage,
+		p.Volume.Article.Articleinfo.Artpagenums.Endpage, p.PageCount())
 
 	is.Publishers = []string{"IEEE"}
 
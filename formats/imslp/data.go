@@ -1,11 +1,9 @@
 package imslp
 
 import (
-	"encoding/base64"
-	"fmt"
-
 	"github.com/beevik/etree"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
 )
 
 // SourceIdentifier of IMSLP.
@@ -29,8 +27,7 @@ func (data *Data) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output := finc.NewIntermediateSchema()
 	output.SourceID = SourceIdentifier
 	for _, t := range doc.FindElements("//var/recordId") {
-		encoded := base64.RawURLEncoding.EncodeToString([]byte(t.Text()))
-		output.ID = fmt.Sprintf("ai-%s-%s", SourceIdentifier, encoded)
+		output.ID = mint.ID(SourceIdentifier, t.Text(), "", mint.Base64)
 		output.RecordID = t.Text()
 	}
 	for _, t := range doc.FindElements("//var[@name='Work Title']/string") {
@@ -0,0 +1,11 @@
+package imslp
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/imslp", golden.Text(func() golden.Converter { return new(Data) }))
+}
@@ -23,6 +23,7 @@ var (
 var (
 	// Restricts the possible languages for detection.
 	acceptedLanguages = container.NewStringSet("deu", "eng", "fra", "ita", "spa")
+	languageGuesser   = span.LanguageGuesser{Accepted: acceptedLanguages, MinLength: 20}
 
 	// Candidate patterns for parsing publishing dates.
 	datePatterns = []string{
@@ -439,31 +440,19 @@ func (article *Article) Languages() []string {
 	if article.Front.Article.Abstract.Lang != "" {
 		base, err := language.ParseBase(article.Front.Article.Abstract.Lang)
 		if err == nil {
+			if span.TrustRecordLanguage {
+				return []string{base.ISO3()}
+			}
 			set.Add(base.ISO3())
 		}
 	}
 
-	vals := []string{
+	set.AddAll(languageGuesser.Guess("",
 		article.Front.Article.Abstract.Value,
 		article.Front.Article.TranslatedAbstract.Title.Value,
-		article.Body.Section.Value,
-	}
-
-	for _, s := range vals {
-		if len(s) < 20 {
-			continue
-		}
-		lang, err := span.DetectLang3(s)
-		if err != nil || lang == "und" {
-			continue
-		}
-		if !acceptedLanguages.Contains(lang) {
-			continue
-		}
-		set.Add(lang)
-	}
+		article.Body.Section.Value)...)
 
-	return set.Values()
+	return set.SortedValues()
 }
 
 func clipString(s string, length int) string {
@@ -499,10 +488,7 @@ func (article *Article) ToIntermediateSchema() (*finc.IntermediateSchema, error)
 	output.Subjects = article.Subjects()
 	output.Volume = article.Front.Article.Volume.Value
 
-	output.StartPage = article.Front.Article.FirstPage.Value
-	output.EndPage = article.Front.Article.LastPage.Value
-	output.PageCount = article.PageCount()
-	output.Pages = fmt.Sprintf("%s-%s", output.StartPage, output.EndPage)
+	output.Pages = finc.NewPages(article.Front.Article.FirstPage.Value, article.Front.Article.LastPage.Value, "")
 
 	return output, nil
 }
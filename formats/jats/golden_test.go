@@ -0,0 +1,11 @@
+package jats
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/jats", golden.XML("article", func() golden.Converter { return new(Article) }))
+}
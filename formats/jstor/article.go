@@ -24,7 +24,6 @@ package jstor
 //
 
 import (
-	"encoding/base64"
 	"encoding/xml"
 	"fmt"
 	"regexp"
@@ -33,8 +32,10 @@ import (
 	"github.com/kennygrant/sanitize"
 	"github.com/miku/span"
 	"github.com/miku/span/container"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
 	"github.com/miku/span/formats/jats"
+	"github.com/miku/span/mint"
 	"golang.org/x/text/language"
 )
 
@@ -68,7 +69,7 @@ func (article *Article) Identifiers() (jats.Identifiers, error) {
 	locator := article.Front.Article.SelfURI.Value
 
 	doi := DOIPattern.FindString(locator)
-	id := fmt.Sprintf("ai-%s-%s", SourceID, base64.RawURLEncoding.EncodeToString([]byte(locator)))
+	id := mint.ID(SourceID, locator, doi, mint.Base64)
 	return jats.Identifiers{DOI: doi, URL: locator, ID: id}, nil
 }
 
@@ -95,7 +96,7 @@ func (article *Article) Languages() []string {
 			}
 		}
 	}
-	return set.Values()
+	return set.SortedValues()
 }
 
 // ReviewedProduct returns the string of the reviewed thing in a best-effort way.
@@ -125,13 +126,9 @@ func (article *Article) ToIntermediateSchema() (*finc.IntermediateSchema, error)
 	if err != nil {
 		return output, err
 	}
-	output.DOI = ids.DOI
+	output.DOI = doi.Clean(ids.DOI)
 
-	id := ids.ID
-	if len(id) > span.KeyLengthLimit {
-		return output, span.Skip{Reason: fmt.Sprintf("id too long: %s", id)}
-	}
-	output.ID = id
+	output.ID = ids.ID
 	output.RecordID = ids.DOI
 
 	output.URL = append(output.URL, ids.URL)
@@ -0,0 +1,11 @@
+package jstor
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/jstor", golden.XML("article", func() golden.Converter { return new(Article) }))
+}
@@ -117,3 +117,38 @@ func (r Record) GetDataFields(spec string) (result []string, err error) {
 	}
 	return result, nil
 }
+
+// trimISBD removes brackets and trailing ISBD punctuation from a subfield
+// value, e.g. "[Berlin] :" becomes "Berlin".
+func trimISBD(s string) string {
+	s = strings.TrimRight(strings.TrimSpace(s), " :;,/=")
+	return strings.TrimSpace(strings.Trim(s, "[]"))
+}
+
+// Places returns the places of publication from 264.a, or from 260.a for
+// older records.
+func (r Record) Places() (places []string) {
+	for _, spec := range []string{"264.a", "260.a"} {
+		for _, v := range r.MustGetDataFields(spec) {
+			if v = trimISBD(v); v != "" {
+				places = append(places, v)
+			}
+		}
+		if len(places) > 0 {
+			break
+		}
+	}
+	return places
+}
+
+// Edition returns the edition statement from 250.a and 250.b, e.g. "2.
+// Aufl.".
+func (r Record) Edition() string {
+	var parts []string
+	for _, spec := range []string{"250.a", "250.b"} {
+		if v := trimISBD(r.MustGetFirstDataField(spec)); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, " / ")
+}
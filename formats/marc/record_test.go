@@ -0,0 +1,44 @@
+package marc
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestPlacesAndEdition(t *testing.T) {
+	var cases = []struct {
+		about   string
+		fields  string
+		places  []string
+		edition string
+	}{
+		{"empty", ``, nil, ""},
+		{
+			"rda",
+			`<datafield tag="264"><subfield code="a">Berlin :</subfield><subfield code="a">[Boston]</subfield></datafield>
+			<datafield tag="260"><subfield code="a">Wien</subfield></datafield>
+			<datafield tag="250"><subfield code="a">2. Aufl. /</subfield><subfield code="b">bearb. von A. Autor</subfield></datafield>`,
+			[]string{"Berlin", "Boston"}, "2. Aufl. / bearb. von A. Autor",
+		},
+		{
+			"aacr2",
+			`<datafield tag="260"><subfield code="a">Wien ;</subfield></datafield>
+			<datafield tag="250"><subfield code="a">3rd ed.</subfield></datafield>`,
+			[]string{"Wien"}, "3rd ed.",
+		},
+	}
+	for _, c := range cases {
+		var r Record
+		s := `<Record><metadata><record>` + c.fields + `</record></metadata></Record>`
+		if err := xml.Unmarshal([]byte(s), &r); err != nil {
+			t.Fatalf("%s: %v", c.about, err)
+		}
+		if got := r.Places(); !reflect.DeepEqual(got, c.places) {
+			t.Errorf("%s: got places %v, want %v", c.about, got, c.places)
+		}
+		if got := r.Edition(); got != c.edition {
+			t.Errorf("%s: got edition %q, want %q", c.about, got, c.edition)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
 )
 
@@ -94,12 +95,11 @@ func (r *Dim) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output.ISBN = r.FieldValues("dc", "identifier", "isbn")
 	output.ISSN = r.FieldValues("dc", "identifier", "issn")
 	output.URL = r.FieldValues("dc", "identifier", "uri")
+	output.DOI = doi.FromIdentifiers(append(r.FieldValues("dc", "identifier", "doi"), output.URL...)...)
 	output.Issue = r.FieldValue("local", "source", "issue")
 	output.Volume = r.FieldValue("local", "source", "volume")
 	output.RawDate = r.FieldValue("dc", "date", "issued")
-	output.StartPage = r.FieldValue("local", "source", "spage")
-	output.EndPage = r.FieldValue("local", "source", "epage")
-	output.PageCount = r.PageCount()
+	output.Pages = finc.NewPages(r.FieldValue("local", "source", "spage"), r.FieldValue("local", "source", "epage"), "")
 
 	date, err := time.Parse("2006", output.RawDate)
 	if err != nil {
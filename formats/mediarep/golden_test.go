@@ -0,0 +1,11 @@
+package mediarep
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/mediarep", golden.XML("Record", func() golden.Converter { return new(Dim) }))
+}
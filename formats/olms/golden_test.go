@@ -0,0 +1,11 @@
+package olms
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/olms", golden.XML("record", func() golden.Converter { return new(Record) }))
+}
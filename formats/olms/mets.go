@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"strings"
 
-	log "github.com/sirupsen/logrus"
-
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/logging"
+	"github.com/miku/span/mint"
 )
 
 // MetsRecord was generated 2018-03-02 12:54:13 by tir on hayiti.
@@ -272,19 +272,19 @@ func (record MetsRecord) ToIntermediateSchema() (*finc.IntermediateSchema, error
 		return output, fmt.Errorf("cannot find identifier: %s", record.Header.Identifier.Text)
 	}
 	output.RecordID = record.Header.Identifier.Text
-	output.ID = fmt.Sprintf("ai-%s-%s", output.SourceID, parts[1])
-	output.MegaCollections = append(output.MegaCollections, "Olms")
+	output.ID = mint.ID(output.SourceID, parts[1], "", mint.Plain)
+	output.AddMegaCollection("Olms")
 	output.Genre = "article"
 	output.RefType = "EJOUR"
 
+	logger := logging.Record(output)
 	for i, sec := range record.Metadata.Mets.DmdSec {
-		log.Printf("%d, %s", i, sec.MdWrap.XmlData.Mods.TitleInfo.Title.Text)
-		log.Printf("%d, %s", i, sec.MdWrap.XmlData.Mods.Location.URL.Text)
-		log.Printf("%d, %s", i, sec.MdWrap.XmlData.Mods.RecordInfo.RecordIdentifier.Text)
+		logger.Debugf("%d, %s", i, sec.MdWrap.XmlData.Mods.TitleInfo.Title.Text)
+		logger.Debugf("%d, %s", i, sec.MdWrap.XmlData.Mods.Location.URL.Text)
+		logger.Debugf("%d, %s", i, sec.MdWrap.XmlData.Mods.RecordInfo.RecordIdentifier.Text)
 		for j, id := range sec.MdWrap.XmlData.Mods.Identifier {
-			log.Printf("%d-%d, %s (%s)", i, j, id.Text, id.Type)
+			logger.Debugf("%d-%d, %s (%s)", i, j, id.Text, id.Type)
 		}
-		log.Printf("----")
 	}
 	return output, nil
 }
@@ -7,8 +7,11 @@ import (
 	"time"
 
 	"github.com/miku/span"
+	"github.com/miku/span/doi"
 
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
+	"github.com/miku/span/oai"
 )
 
 // Record was generated 2018-03-01 19:44:04 by tir on hayiti.
@@ -79,21 +82,32 @@ func (record Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 		return output, fmt.Errorf("cannot find identifier: %s", record.Header.Identifier.Text)
 	}
 	output.RecordID = record.Header.Identifier.Text
-	output.ID = fmt.Sprintf("ai-%s-%s", output.SourceID, parts[1])
-	output.MegaCollections = append(output.MegaCollections, "Olms")
+	output.ID = mint.ID(output.SourceID, parts[1], "", mint.Plain)
+	output.AddMegaCollection("Olms")
 	output.Genre = "article"
 	output.RefType = "EJOUR"
 
+	var types []string
+	for _, t := range record.Metadata.Dc.Type {
+		types = append(types, t.Text)
+	}
+	if kind, ok := oai.DetectKind(oai.Clues{Types: types}); ok {
+		output.Genre, output.RefType, output.Format = kind.Genre, kind.RefType, kind.Format
+	}
+
 	output.ArticleTitle = record.Metadata.Dc.Title.Text
 	output.BookTitle = record.Metadata.Dc.Source.Text
 	for _, v := range record.Metadata.Dc.Creator {
-		output.Authors = append(output.Authors, finc.Author{Name: v.Text})
+		output.Authors = append(output.Authors, finc.ParseAuthor(v.Text))
 	}
+	var ids []string
 	for _, v := range record.Metadata.Dc.Identifier {
 		if strings.HasPrefix(v.Text, "http") {
 			output.URL = append(output.URL, v.Text)
 		}
+		ids = append(ids, v.Text)
 	}
+	output.DOI = doi.FromIdentifiers(ids...)
 
 	output.Publishers = append(output.Publishers, record.Metadata.Dc.Publisher.Text)
 	if record.Metadata.Dc.Date.Text == "" {
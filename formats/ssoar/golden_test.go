@@ -0,0 +1,11 @@
+package ssoar
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/ssoar", golden.XML("Record", func() golden.Converter { return new(Record) }))
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/miku/span"
 	"github.com/miku/span/formats/finc"
 	"github.com/miku/span/formats/marc"
+	"github.com/miku/span/logging"
+	"github.com/miku/span/mint"
 )
 
 type Record struct {
@@ -175,7 +177,7 @@ func (r Record) FindPages() (string, string, string) {
 			}
 			if len(sms[0]) >= 3 {
 				pagecount := stringDifference(sms[0][2], sms[0][1])
-				return sms[0][0], sms[0][1], pagecount
+				return sms[0][1], sms[0][2], pagecount
 			}
 		}
 	}
@@ -190,15 +192,15 @@ func (r Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 		return output, err
 	}
 
+	output.RecordID = id
+	output.SourceID = "30"
+
 	if t, ok := r.HasEmbargo(); ok {
 		msg := fmt.Sprintf("embargo restriction for %s", id)
-		log.Printf("embargo for %s expires on %s", id, t.Format("2006-01-02"))
+		logging.Record(output).Infof("embargo for %s expires on %s", id, t.Format("2006-01-02"))
 		return output, span.Skip{Reason: msg}
 	}
-
-	output.RecordID = id
-	output.SourceID = "30"
-	output.ID = fmt.Sprintf("ai-%s-%s", output.SourceID, output.RecordID)
+	output.ID = mint.ID(output.SourceID, output.RecordID, "", mint.Plain)
 	output.Format = r.FindFormat()
 	output.MegaCollections = []string{"SSOAR Social Science Open Access Repository"}
 
@@ -224,19 +226,17 @@ func (r Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	output.RawDate = r.FindYear()
 	date, err := time.Parse("2006-01-02", output.RawDate)
 	if err != nil {
-		log.Fatal(err)
+		return output, fmt.Errorf("%s: %v", id, err)
 	}
 	output.Date = date
 	output.Languages = r.MustGetDataFields("041.a")
-	output.StartPage, output.EndPage, output.PageCount = r.FindPages()
+	start, end, _ := r.FindPages()
+	output.Pages = finc.NewPages(start, end, "")
 	output.Series = r.MustGetFirstDataField("490.a")
 	output.ISBN = r.MustGetDataFields("020.a")
 
-	for _, place := range r.MustGetDataFields("264.a") {
-		if place != "" {
-			output.Places = append(output.Places, place)
-		}
-	}
+	output.Places = r.Places()
+	output.Edition = r.Edition()
 	if pub := r.MustGetFirstDataField("264.b"); pub != "" {
 		output.Publishers = append(output.Publishers, pub)
 	}
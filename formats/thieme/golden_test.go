@@ -0,0 +1,11 @@
+package thieme
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/thieme", golden.XML("Record", func() golden.Converter { return new(Record) }))
+}
@@ -1,7 +1,6 @@
 package thieme
 
 import (
-	"encoding/base64"
 	"encoding/xml"
 	"fmt"
 	"strings"
@@ -10,7 +9,9 @@ import (
 	"github.com/kennygrant/sanitize"
 	"github.com/miku/span"
 	"github.com/miku/span/assetutil"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
 )
 
 const (
@@ -247,8 +248,7 @@ func (record Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 
 	output.JournalTitle = article.Front.JournalMeta.JournalTitleGroup.JournalTitle.Text
 	output.ArticleTitle = article.Front.ArticleMeta.TitleGroup.ArticleTitle.Text
-	output.StartPage = article.Front.ArticleMeta.Fpage.Text
-	output.EndPage = article.Front.ArticleMeta.Lpage.Text
+	output.Pages = finc.NewPages(article.Front.ArticleMeta.Fpage.Text, article.Front.ArticleMeta.Lpage.Text, "")
 	output.Volume = article.Front.ArticleMeta.Volume.Text
 	output.Issue = article.Front.ArticleMeta.Issue.Text
 
@@ -290,13 +290,13 @@ func (record Record) ToIntermediateSchema() (*finc.IntermediateSchema, error) {
 	}
 
 	if article.Front.ArticleMeta.ArticleID.PubIDType == "doi" {
-		output.DOI = article.Front.ArticleMeta.ArticleID.Text
+		output.DOI = doi.Clean(article.Front.ArticleMeta.ArticleID.Text)
 	} else {
 		return output, fmt.Errorf("unknown id type: %s", article.Front.ArticleMeta.ArticleID.PubIDType)
 	}
 
 	output.RecordID = output.DOI
-	output.ID = fmt.Sprintf("ai-60-%s", base64.RawURLEncoding.EncodeToString([]byte(output.DOI)))
+	output.ID = mint.ID(SourceID, output.DOI, output.DOI, mint.Base64)
 
 	var authors []finc.Author
 	for _, contrib := range article.Front.ArticleMeta.ContribGroup.Contrib {
@@ -0,0 +1,11 @@
+package zvdd
+
+import (
+	"testing"
+
+	"github.com/miku/span/golden"
+)
+
+func TestGolden(t *testing.T) {
+	golden.Test(t, "../../fixtures/zvdd", golden.XML("record", func() golden.Converter { return new(MetsRecord) }))
+}
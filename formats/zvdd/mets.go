@@ -1,7 +1,6 @@
 package zvdd
 
 import (
-	"encoding/base64"
 	"encoding/xml"
 	"fmt"
 	"regexp"
@@ -10,7 +9,9 @@ import (
 	"time"
 
 	"github.com/miku/span"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
 )
 
 var simpleDatePattern = regexp.MustCompile("[12][0-9][0-9][0-9]")
@@ -214,13 +215,19 @@ func (r *MetsRecord) ToIntermediateSchema() (output *finc.IntermediateSchema, er
 	mods := mets.DmdSection.Wrap.Data.Mods
 	output.ArticleTitle = mods.TitleInfo.Title
 	output.ArticleSubtitle = mods.TitleInfo.SubTitle
-	output.DOI = r.DOI()
+	output.DOI = doi.Clean(r.DOI())
 	output.URL = r.URL()
 
 	// Use earliest date.
 	var dates []string
 	for _, origin := range mods.Origin {
 		output.Publishers = origin.Publisher
+		if t := origin.Place.Term; t.Type == "text" && strings.TrimSpace(t.Value) != "" {
+			output.Places = append(output.Places, strings.TrimSpace(t.Value))
+		}
+		if len(origin.Edition) > 0 && output.Edition == "" {
+			output.Edition = strings.TrimSpace(origin.Edition[0])
+		}
 		for _, di := range origin.DateIssued {
 			dates = append(dates, di.Date)
 		}
@@ -250,8 +257,7 @@ func (r *MetsRecord) ToIntermediateSchema() (output *finc.IntermediateSchema, er
 	}
 
 	output.SourceID = "93"
-	output.ID = fmt.Sprintf("ai-%s-%s", output.SourceID,
-		base64.RawURLEncoding.EncodeToString([]byte(r.Header.Identifier)))
+	output.ID = mint.ID(output.SourceID, r.Header.Identifier, "", mint.Base64)
 	output.RecordID = r.Header.Identifier
 	output.MegaCollections = []string{"ZVDD"}
 	output.Genre = "document"
@@ -1,12 +1,14 @@
 package zvdd
 
 import (
-	"encoding/base64"
 	"encoding/xml"
 	"fmt"
 	"strings"
 
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/mint"
+	"github.com/miku/span/oai"
 )
 
 // SourceIdentifier for internal bookkeeping.
@@ -55,25 +57,27 @@ func (r DublicCoreRecord) ToIntermediateSchema() (*finc.IntermediateSchema, erro
 	urnlink := fmt.Sprintf("http://nbn-resolving.de/%s", urn)
 	output.URL = append(output.URL, urnlink)
 
-	encodedIdentifier := base64.RawURLEncoding.EncodeToString([]byte(urn))
-	output.ID = fmt.Sprintf("ai-%s-%s", SourceIdentifier, encodedIdentifier)
+	output.ID = mint.ID(SourceIdentifier, urn, "", mint.Base64)
 	output.RecordID = urn
 	output.SourceID = SourceIdentifier
 	output.Genre = Genre
 	output.Format = Format
 	output.RefType = DefaultRefType
 	output.MegaCollections = []string{Collection}
+	if kind, ok := oai.DetectKind(oai.Clues{Types: r.Metadata.DC.Type}); ok {
+		output.Genre, output.RefType, output.Format = kind.Genre, kind.RefType, kind.Format
+	}
 
 	if len(r.Metadata.DC.Title) > 0 {
 		output.ArticleTitle = r.Metadata.DC.Title[0]
 	}
 	for _, v := range r.Metadata.DC.Creator {
-		v = strings.TrimSpace(v)
-		v = strings.TrimRight(v, ",")
-		output.Authors = append(output.Authors, finc.Author{Name: v})
+		output.Authors = append(output.Authors, finc.ParseAuthor(v))
 	}
 	output.Abstract = strings.Join(r.Metadata.DC.Source, "\n")
 
+	output.DOI = doi.FromIdentifiers(r.Metadata.DC.Identifier...)
+
 	for _, v := range r.Metadata.DC.Publisher {
 		output.Publishers = append(output.Publishers, v)
 	}
@@ -0,0 +1,220 @@
+package span
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FTPTimeout limits connection setup for FTP and SFTP.
+var FTPTimeout = 30 * time.Second
+
+// credentials returns user and password from the URL or from the environment
+// variables PREFIX_USER and PREFIX_PASSWORD.
+func credentials(u *url.URL, prefix string) (user, password string) {
+	user, password = os.Getenv(prefix+"_USER"), os.Getenv(prefix+"_PASSWORD")
+	if u.User != nil {
+		user = u.User.Username()
+		if p, ok := u.User.Password(); ok {
+			password = p
+		}
+	}
+	return user, password
+}
+
+// remotePath returns the path of a file relative to the login directory, like
+// curl; use a double slash for absolute paths, e.g. ftp://host//srv/file.
+func remotePath(u *url.URL) string {
+	return strings.TrimPrefix(u.Path, "/")
+}
+
+// ftpReader reads a file over a passive mode data connection.
+type ftpReader struct {
+	data net.Conn
+	ctrl *textproto.Conn
+}
+
+func (r *ftpReader) Read(p []byte) (int, error) {
+	return r.data.Read(p)
+}
+
+// Close closes the data connection, checks the transfer status and logs out.
+func (r *ftpReader) Close() error {
+	r.data.Close()
+	defer r.ctrl.Close()
+	if _, _, err := r.ctrl.ReadResponse(226); err != nil {
+		return err
+	}
+	_, err := ftpCmd(r.ctrl, 221, "QUIT")
+	return err
+}
+
+// ftpCmd sends a command and reads the response.
+func ftpCmd(c *textproto.Conn, expectCode int, format string, args ...interface{}) (string, error) {
+	if _, err := c.Cmd(format, args...); err != nil {
+		return "", err
+	}
+	_, msg, err := c.ReadResponse(expectCode)
+	return msg, err
+}
+
+// OpenFTP opens a file given as ftp://[user[:password]@]host[:port]/path. User
+// and password default to SPAN_FTP_USER and SPAN_FTP_PASSWORD or anonymous.
+func OpenFTP(link string) (io.ReadCloser, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "21")
+	}
+	conn, err := net.DialTimeout("tcp", host, FTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := textproto.NewConn(conn)
+	r, err := openFTP(c, u)
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("ftp: %s%s: %v", u.Host, u.Path, err)
+	}
+	return r, nil
+}
+
+// openFTP logs in, starts a binary passive mode transfer and returns a reader.
+func openFTP(c *textproto.Conn, u *url.URL) (*ftpReader, error) {
+	if _, _, err := c.ReadResponse(220); err != nil {
+		return nil, err
+	}
+	user, password := credentials(u, "SPAN_FTP")
+	if user == "" {
+		user, password = "anonymous", "anonymous"
+	}
+	if _, err := c.Cmd("USER %s", user); err != nil {
+		return nil, err
+	}
+	code, msg, err := c.ReadResponse(0)
+	if err != nil {
+		return nil, err
+	}
+	switch code {
+	case 230:
+	case 331:
+		if _, err := ftpCmd(c, 230, "PASS %s", password); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("login failed: %d %s", code, msg)
+	}
+	if _, err := ftpCmd(c, 200, "TYPE I"); err != nil {
+		return nil, err
+	}
+	// Extended passive mode, refs. RFC 2428, e.g. "Entering Extended Passive Mode (|||6446|)".
+	msg, err = ftpCmd(c, 229, "EPSV")
+	if err != nil {
+		return nil, err
+	}
+	start, end := strings.Index(msg, "(|||"), strings.LastIndex(msg, "|)")
+	if start == -1 || end < start+4 {
+		return nil, fmt.Errorf("cannot parse EPSV response: %s", msg)
+	}
+	port, err := strconv.Atoi(msg[start+4 : end])
+	if err != nil {
+		return nil, err
+	}
+	data, err := net.DialTimeout("tcp", net.JoinHostPort(u.Hostname(), strconv.Itoa(port)), FTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.Cmd("RETR %s", remotePath(u)); err != nil {
+		data.Close()
+		return nil, err
+	}
+	// 125 data connection already open, 150 about to open data connection.
+	if code, msg, err = c.ReadResponse(0); err != nil || (code != 125 && code != 150) {
+		data.Close()
+		if err == nil {
+			err = fmt.Errorf("%d %s", code, msg)
+		}
+		return nil, err
+	}
+	return &ftpReader{data: data, ctrl: c}, nil
+}
+
+// sftpCommand returns the command to fetch a file given as
+// sftp://[user[:password]@]host[:port]/path into a local file. User and
+// password default to SPAN_SFTP_USER and SPAN_SFTP_PASSWORD; a key file can
+// be set with SPAN_SFTP_IDENTITY. Password authentication requires sshpass.
+func sftpCommand(u *url.URL, dst string) (cmd *exec.Cmd, batch string) {
+	user, password := credentials(u, "SPAN_SFTP")
+	args := []string{"-q", "-b", "-", "-o", fmt.Sprintf("ConnectTimeout=%d", int(FTPTimeout.Seconds()))}
+	if identity := os.Getenv("SPAN_SFTP_IDENTITY"); identity != "" {
+		args = append(args, "-i", identity)
+	}
+	if password != "" {
+		// Batch mode disables password prompts, sshpass answers them.
+		args = append(args, "-o", "BatchMode=no")
+	}
+	if u.Port() != "" {
+		args = append(args, "-P", u.Port())
+	}
+	target := u.Hostname()
+	if user != "" {
+		target = user + "@" + target
+	}
+	args = append(args, target)
+	if password != "" {
+		cmd = exec.Command("sshpass", append([]string{"-e", "sftp"}, args...)...)
+		cmd.Env = append(os.Environ(), "SSHPASS="+password)
+	} else {
+		cmd = exec.Command("sftp", args...)
+	}
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+	}
+	batch = fmt.Sprintf("get %s %s\n", quote(remotePath(u)), quote(dst))
+	return cmd, batch
+}
+
+// tempFileReader removes the file on close.
+type tempFileReader struct {
+	*os.File
+}
+
+func (r tempFileReader) Close() error {
+	defer os.Remove(r.Name())
+	return r.File.Close()
+}
+
+// OpenSFTP fetches a file via the sftp command into a temporary file and
+// returns a reader, see sftpCommand for options.
+func OpenSFTP(link string) (io.ReadCloser, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile("", "span-sftp-")
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	cmd, batch := sftpCommand(u, f.Name())
+	cmd.Stdin = strings.NewReader(batch)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("sftp: %s%s: %v: %s", u.Host, u.Path, err, strings.TrimSpace(string(out)))
+	}
+	if f, err = os.Open(f.Name()); err != nil {
+		return nil, err
+	}
+	return tempFileReader{f}, nil
+}
@@ -0,0 +1,211 @@
+// Package golden compares records converted from fixtures against stored
+// golden files, so converter changes surface their exact effects. Each input
+// file in a fixture directory, e.g. fixtures/crossref/works.ldj, has a golden
+// file next to it, works.ldj.golden, with the intermediate schema of every
+// record, or the reason it was skipped or failed.
+//
+// After an intended change, golden files are rewritten with:
+//
+//	$ go test ./formats/crossref -run Golden -update
+//
+// or, for all packages at once, with SPAN_UPDATE_GOLDEN=1 set.
+package golden
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+	"github.com/miku/span/encoding/xmliter"
+	"github.com/miku/span/formats/finc"
+)
+
+// Suffix is appended to input filenames to name golden files.
+const Suffix = ".golden"
+
+var update = flag.Bool("update", false, "update golden files")
+
+// Converter is a record, that can be converted to an intermediate schema.
+type Converter interface {
+	ToIntermediateSchema() (*finc.IntermediateSchema, error)
+}
+
+// DecodeFunc reads all records from a fixture.
+type DecodeFunc func(r io.Reader) ([]Converter, error)
+
+// JSONLines decodes one record per line, with values created by newValue.
+func JSONLines(newValue func() Converter) DecodeFunc {
+	return func(r io.Reader) ([]Converter, error) {
+		var result []Converter
+		br := bufio.NewReader(r)
+		for {
+			b, err := br.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			if len(bytes.TrimSpace(b)) > 0 {
+				v := newValue()
+				if err := json.Unmarshal(b, v); err != nil {
+					return nil, err
+				}
+				result = append(result, v)
+			}
+			if err == io.EOF {
+				return result, nil
+			}
+		}
+	}
+}
+
+// XML decodes all elements with the given local name, at any depth.
+func XML(name string, newValue func() Converter) DecodeFunc {
+	return func(r io.Reader) ([]Converter, error) {
+		var result []Converter
+		dec := xmliter.NewDecoder(r, name)
+		for {
+			v := newValue()
+			err := dec.Decode(v)
+			if err == io.EOF {
+				return result, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, v)
+		}
+	}
+}
+
+// Text decodes the whole input as a single record, with UnmarshalText.
+func Text(newValue func() Converter) DecodeFunc {
+	return func(r io.Reader) ([]Converter, error) {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		v := newValue()
+		u, ok := v.(encoding.TextUnmarshaler)
+		if !ok {
+			return nil, fmt.Errorf("%T does not implement encoding.TextUnmarshaler", v)
+		}
+		if err := u.UnmarshalText(b); err != nil {
+			return nil, err
+		}
+		return []Converter{v}, nil
+	}
+}
+
+// Updating returns true, if golden files should be rewritten.
+func Updating() bool {
+	return *update || os.Getenv("SPAN_UPDATE_GOLDEN") != ""
+}
+
+// Render converts records and returns the text stored in golden files.
+func Render(records []Converter) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, v := range records {
+		fmt.Fprintf(&buf, "# %d\n", i+1)
+		is, err := v.ToIntermediateSchema()
+		if skip, ok := err.(span.Skip); ok {
+			fmt.Fprintf(&buf, "skipped: %s\n", skip.Reason)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(&buf, "error: %v\n", err)
+			continue
+		}
+		b, err := json.MarshalIndent(is, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// Test converts the records of each input file in dir and compares the
+// result with its golden file. Missing golden files are errors, unless
+// updating.
+func Test(t *testing.T, dir string, decode DecodeFunc) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inputs []string
+	for _, fi := range files {
+		if fi.IsDir() || strings.HasSuffix(fi.Name(), Suffix) || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		inputs = append(inputs, filepath.Join(dir, fi.Name()))
+	}
+	if len(inputs) == 0 {
+		t.Fatalf("no fixtures in %s", dir)
+	}
+	sort.Strings(inputs)
+	for _, input := range inputs {
+		got, err := renderFile(input, decode)
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+		if Updating() {
+			if err := ioutil.WriteFile(input+Suffix, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(input + Suffix)
+		if err != nil {
+			t.Errorf("%s: %v, run with -update to create", input, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: output differs from golden file, %s; run with -update, if intended",
+				input, firstDiff(got, want))
+		}
+	}
+}
+
+// renderFile decodes and renders a single fixture.
+func renderFile(filename string, decode DecodeFunc) ([]byte, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, err := decode(f)
+	if err != nil {
+		return nil, err
+	}
+	return Render(records)
+}
+
+// firstDiff describes the first differing line.
+func firstDiff(got, want []byte) string {
+	g, w := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	for i := 0; i < len(g) || i < len(w); i++ {
+		var gl, wl string
+		if i < len(g) {
+			gl = g[i]
+		}
+		if i < len(w) {
+			wl = w[i]
+		}
+		if gl != wl {
+			return fmt.Sprintf("line %d: got %q, want %q", i+1, gl, wl)
+		}
+	}
+	return "no difference"
+}
@@ -1,19 +1,115 @@
 package span
 
 import (
+	"archive/tar"
 	"archive/zip"
 	"bufio"
 	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
+	"os/exec"
 	"strings"
 	"sync"
 	"sync/atomic"
 )
 
+// Magic bytes of supported compression formats.
+var (
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicBzip2 = []byte("BZh")
+	magicXz    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	magicZstd  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// nopCloser wraps a reader with a no-op close.
+type nopCloser struct {
+	io.Reader
+}
+
+func (nopCloser) Close() error { return nil }
+
+// cmdReader reads the output of an external decompressor. The exit status is
+// checked at the end of the output, so corrupt or truncated input results in
+// an error, not in a short read.
+type cmdReader struct {
+	io.ReadCloser
+	name   string
+	cmd    *exec.Cmd
+	waited bool
+	err    error
+	// cleanup, if set, is called after the command finished.
+	cleanup func()
+}
+
+// Read reads output and waits for the command to finish at the end.
+func (r *cmdReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err == io.EOF {
+		if werr := r.wait(); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// wait waits for the command once.
+func (r *cmdReader) wait() error {
+	if !r.waited {
+		r.waited = true
+		if err := r.cmd.Wait(); err != nil {
+			r.err = fmt.Errorf("%s: %v", r.name, err)
+		}
+		if r.cleanup != nil {
+			r.cleanup()
+		}
+	}
+	return r.err
+}
+
+// Close waits for the command to finish.
+func (r *cmdReader) Close() error {
+	r.ReadCloser.Close()
+	return r.wait()
+}
+
+// NewDecompressReader returns a reader, that transparently decompresses gzip,
+// bzip2, xz or zstd compressed content, detected by its magic bytes. Other
+// content is passed through. The xz and zstd formats require the xz and zstd
+// executables.
+func NewDecompressReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	// A short read results in an error, but magic bytes can still be compared.
+	head, _ := br.Peek(6)
+	var name string
+	switch {
+	case bytes.HasPrefix(head, magicGzip):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(head, magicBzip2) && len(head) > 3 && head[3] >= '1' && head[3] <= '9':
+		return nopCloser{bzip2.NewReader(br)}, nil
+	case bytes.HasPrefix(head, magicXz):
+		name = "xz"
+	case bytes.HasPrefix(head, magicZstd):
+		name = "zstd"
+	default:
+		return nopCloser{br}, nil
+	}
+	cmd := exec.Command(name, "-dc")
+	cmd.Stdin = br
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%s compressed input requires %s: %v", name, name, err)
+	}
+	return &cmdReader{ReadCloser: stdout, name: name, cmd: cmd}, nil
+}
+
 // ReaderCounter counts the number of bytes read.
 type ReaderCounter struct {
 	count int64
@@ -37,9 +133,12 @@ func (counter *ReaderCounter) Count() int64 {
 	return atomic.LoadInt64(&counter.count)
 }
 
-// LinkReader implements io.Reader for a URL.
+// LinkReader implements io.Reader for a URL, including s3, ftp and sftp URLs.
+// Compressed content is decompressed. Download options, like retries, are set via the embedded
+// Fetcher.
 type LinkReader struct {
 	Link string
+	Fetcher
 	buf  bytes.Buffer
 	once sync.Once
 }
@@ -47,17 +146,29 @@ type LinkReader struct {
 // fill copies the content of the URL into the internal buffer.
 func (r *LinkReader) fill() (err error) {
 	r.once.Do(func() {
-		var resp *http.Response
-		resp, err = http.Get(r.Link)
+		var raw bytes.Buffer
+		if isObjectLink(r.Link) {
+			var obj io.ReadCloser
+			if obj, err = openRaw(r.Link); err != nil {
+				return
+			}
+			_, err = io.Copy(&raw, obj)
+			obj.Close()
+		} else {
+			err = r.Fetch(r.Link, &raw)
+		}
 		if err != nil {
 			return
 		}
-		defer resp.Body.Close()
-		if resp.StatusCode >= 400 {
-			err = fmt.Errorf("request to %s failed with: %s", r.Link, resp.Status)
+		var rc io.ReadCloser
+		if rc, err = NewDecompressReader(&raw); err != nil {
+			return
+		}
+		if _, err = io.Copy(&r.buf, rc); err != nil {
+			rc.Close()
 			return
 		}
-		_, err = io.Copy(&r.buf, resp.Body)
+		err = rc.Close()
 	})
 	return err
 }
@@ -69,21 +180,23 @@ func (r *LinkReader) Read(p []byte) (int, error) {
 	return r.buf.Read(p)
 }
 
-// SavedLink saves the content of a URL to a file.
+// SavedLink saves the content of a URL to a file, as is.
 type SavedLink struct {
 	Link string
-	f    *os.File
+	Fetcher
+	f *os.File
 }
 
-// Save link to a temporary file, return the filename.
+// Save link to a temporary file, return the filename. On error, the
+// temporary file is removed.
 func (s *SavedLink) Save() (filename string, err error) {
-	r := &LinkReader{Link: s.Link}
 	s.f, err = ioutil.TempFile("", "span-")
 	if err != nil {
 		return
 	}
 	defer s.f.Close()
-	if _, err = io.Copy(s.f, r); err != nil {
+	if err = s.Fetch(s.Link, s.f); err != nil {
+		s.Remove()
 		return
 	}
 	return s.f.Name(), nil
@@ -91,7 +204,9 @@ func (s *SavedLink) Save() (filename string, err error) {
 
 // Remove remove any left over temporary file.
 func (s *SavedLink) Remove() {
-	_ = os.Remove(s.f.Name())
+	if s.f != nil {
+		_ = os.Remove(s.f.Name())
+	}
 }
 
 // ZipContentReader returns the concatenated content of all files in a zip archive
@@ -134,11 +249,69 @@ func (r *ZipContentReader) Read(p []byte) (int, error) {
 	return r.buf.Read(p)
 }
 
+// TarContentReader returns the concatenated content of all regular files in a
+// tar archive given by its filename, which may be compressed, e.g. tar.gz. All
+// content is temporarily stored in memory, so this type should only be used
+// with smaller archives.
+type TarContentReader struct {
+	Filename string
+	buf      bytes.Buffer
+	once     sync.Once
+}
+
+// fill populates the internal buffer with the content of all archive members.
+func (r *TarContentReader) fill() (err error) {
+	r.once.Do(func() {
+		f := &FileReader{Filename: r.Filename}
+		defer f.Close()
+		tr := tar.NewReader(f)
+		for {
+			var hdr *tar.Header
+			hdr, err = tr.Next()
+			if err == io.EOF {
+				err = nil
+				return
+			}
+			if err != nil {
+				return
+			}
+			if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+				continue
+			}
+			if _, err = io.Copy(&r.buf, tr); err != nil {
+				return
+			}
+		}
+	})
+	return
+}
+
+// Read returns the content of all archive members.
+func (r *TarContentReader) Read(p []byte) (int, error) {
+	if err := r.fill(); err != nil {
+		return 0, err
+	}
+	return r.buf.Read(p)
+}
+
+// isTar returns true, if the file looks like a tar archive, possibly compressed.
+func isTar(filename string) bool {
+	f := &FileReader{Filename: filename}
+	defer f.Close()
+	header := make([]byte, 512)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false
+	}
+	return bytes.HasPrefix(header[257:], []byte("ustar"))
+}
+
 // FileReader creates a ReadCloser from a filename. If postpones error handling
-// up until the first read.  TODO(miku): Throw this out.
+// up until the first read. Compressed content is decompressed.
+// TODO(miku): Throw this out.
 type FileReader struct {
 	Filename string
 	f        *os.File
+	rc       io.ReadCloser
 	once     sync.Once
 }
 
@@ -147,7 +320,10 @@ func (r *FileReader) openFile() (err error) {
 		if r.f != nil {
 			return
 		}
-		r.f, err = os.Open(r.Filename)
+		if r.f, err = os.Open(r.Filename); err != nil {
+			return
+		}
+		r.rc, err = NewDecompressReader(r.f)
 	})
 	return err
 }
@@ -157,24 +333,36 @@ func (r *FileReader) Read(p []byte) (n int, err error) {
 	if err = r.openFile(); err != nil {
 		return
 	}
-	n, err = r.f.Read(p)
+	if r.rc == nil {
+		return 0, io.EOF
+	}
+	n, err = r.rc.Read(p)
 	if err == io.EOF {
-		defer r.Close()
+		// Do not drop errors on close.
+		if cerr := r.Close(); cerr != nil {
+			err = cerr
+		}
 	}
 	return
 }
 
 // Close closes the file.
 func (r *FileReader) Close() (err error) {
+	if r.rc != nil {
+		err = r.rc.Close()
+		r.rc = nil
+	}
 	if r.f != nil {
-		err = r.f.Close()
+		if cerr := r.f.Close(); err == nil {
+			err = cerr
+		}
 		r.f = nil
 	}
 	return
 }
 
-// ZipOrPlainLinkReader is a reader that transparently handles zipped and uncompressed
-// content, given a URL as string.
+// ZipOrPlainLinkReader is a reader that transparently handles zipped, tar
+// archived, compressed and uncompressed content, given a URL as string.
 type ZipOrPlainLinkReader struct {
 	Link string
 	buf  bytes.Buffer
@@ -196,6 +384,11 @@ func (r *ZipOrPlainLinkReader) fill() (err error) {
 			// If there is no error with zip, assume it was a zip and return.
 			return
 		}
+		r.buf.Reset()
+		if isTar(filename) {
+			_, err = io.Copy(&r.buf, &TarContentReader{Filename: filename})
+			return
+		}
 		// Error with zip? Return plain content.
 		_, err = io.Copy(&r.buf, &FileReader{Filename: filename})
 	})
@@ -216,16 +409,20 @@ type SavedReaders struct {
 	f       *os.File
 }
 
-// Save saves all readers to a temporary file and returns the filename.
+// Save saves all readers to a temporary file and returns the filename. On
+// error, the temporary file is removed.
 func (r *SavedReaders) Save() (filename string, err error) {
 	r.f, err = ioutil.TempFile("", "span-")
 	if err != nil {
 		return
 	}
 	if _, err = io.Copy(r.f, io.MultiReader(r.Readers...)); err != nil {
+		r.f.Close()
+		r.Remove()
 		return
 	}
 	if err = r.f.Close(); err != nil {
+		r.Remove()
 		return
 	}
 	filename = r.f.Name()
@@ -234,7 +431,9 @@ func (r *SavedReaders) Save() (filename string, err error) {
 
 // Remove remove any left over temporary file.
 func (r *SavedReaders) Remove() {
-	_ = os.Remove(r.f.Name())
+	if r.f != nil {
+		_ = os.Remove(r.f.Name())
+	}
 }
 
 // ReadLines returns a list of trimmed lines in a file. Empty lines are skipped.
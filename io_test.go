@@ -2,20 +2,30 @@ package span
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/base64"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"strings"
 	"testing"
+	"time"
 )
 
+// testFetcher retries once after a short wait, so tests fail fast without a
+// network.
+var testFetcher = Fetcher{MaxRetries: 1, Backoff: 10 * time.Millisecond}
+
 func TestLinkReader(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping going out to the net")
 	}
 	r := &LinkReader{
-		Link: "https://httpbin.org/bytes/1?seed=0",
+		Link:    "https://httpbin.org/bytes/1?seed=0",
+		Fetcher: testFetcher,
 	}
 	var buf bytes.Buffer
 	w := base64.NewEncoder(base64.StdEncoding, &buf)
@@ -35,7 +45,7 @@ func TestSavedLink(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping going out to the net")
 	}
-	slink := SavedLink{Link: "https://httpbin.org/bytes/1?seed=0"}
+	slink := SavedLink{Link: "https://httpbin.org/bytes/1?seed=0", Fetcher: testFetcher}
 	fn, err := slink.Save()
 	if err != nil {
 		t.Errorf(err.Error())
@@ -100,3 +110,111 @@ func TestSavedReaders(t *testing.T) {
 		t.Errorf("SavedReaders: file exists, but should be deleted: %v", fn)
 	}
 }
+
+func TestDecompressReader(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	io.WriteString(zw, "hello\n")
+	zw.Close()
+	bz, _ := base64.StdEncoding.DecodeString("QlpoOTFBWSZTWcHAgOIAAAFBAAAQAkSgADDNAMNGKZcXckU4UJDBwIDi")
+
+	var tests = []struct {
+		about string
+		r     io.Reader
+		out   string
+	}{
+		{"empty", strings.NewReader(""), ""},
+		{"plain", strings.NewReader("hello\n"), "hello\n"},
+		{"plain, short", strings.NewReader("BZ"), "BZ"},
+		{"gzip", bytes.NewReader(gz.Bytes()), "hello\n"},
+		{"bzip2", bytes.NewReader(bz), "hello\n"},
+	}
+	for _, name := range []string{"xz", "zstd"} {
+		out, err := exec.Command("sh", "-c", "printf 'hello\\n' | "+name).Output()
+		if err != nil {
+			t.Logf("skipping %s: %v", name, err)
+			continue
+		}
+		tests = append(tests, struct {
+			about string
+			r     io.Reader
+			out   string
+		}{name, bytes.NewReader(out), "hello\n"})
+	}
+
+	for _, tt := range tests {
+		rc, err := NewDecompressReader(tt.r)
+		if err != nil {
+			t.Errorf("%s: %v", tt.about, err)
+			continue
+		}
+		b, err := ioutil.ReadAll(rc)
+		if err != nil {
+			t.Errorf("%s: %v", tt.about, err)
+		}
+		if err := rc.Close(); err != nil {
+			t.Errorf("%s: %v", tt.about, err)
+		}
+		if string(b) != tt.out {
+			t.Errorf("%s: got %q, want %q", tt.about, string(b), tt.out)
+		}
+	}
+}
+
+func TestDecompressReaderTruncated(t *testing.T) {
+	out, err := exec.Command("sh", "-c", "seq 1 100000 | xz").Output()
+	if err != nil {
+		t.Skipf("skipping xz: %v", err)
+	}
+	truncated := out[:len(out)/2]
+	rc, err := NewDecompressReader(bytes.NewReader(truncated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if _, err := ioutil.ReadAll(rc); err == nil {
+		t.Errorf("ReadAll: got nil, want error for truncated xz input")
+	}
+
+	f, err := ioutil.TempFile("", "span-truncated-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(truncated); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := ioutil.ReadAll(&FileReader{Filename: f.Name()}); err == nil {
+		t.Errorf("FileReader: got nil, want error for truncated xz input")
+	}
+}
+
+func TestTarContentReader(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := io.Copy(&buf, &TarContentReader{Filename: "fixtures/t.tar.gz"})
+	if err != nil {
+		t.Error(err)
+	}
+	want, got := "a\nb\n", buf.String()
+	if want != got {
+		t.Errorf("TarContentReader: got %v, want %v", got, want)
+	}
+	if !isTar("fixtures/t.tar.gz") || isTar("fixtures/z.zip") {
+		t.Errorf("isTar: tar archive not detected")
+	}
+}
+
+func TestZipOrPlainLinkReader(t *testing.T) {
+	ts := httptest.NewServer(http.FileServer(http.Dir("fixtures")))
+	defer ts.Close()
+	for _, name := range []string{"z.zip", "t.tar.gz"} {
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, &ZipOrPlainLinkReader{Link: ts.URL + "/" + name}); err != nil {
+			t.Error(err)
+		}
+		if want, got := "a\nb\n", buf.String(); want != got {
+			t.Errorf("ZipOrPlainLinkReader(%s): got %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package span
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrInvalidISBN is returned for strings, that are not a valid ISBN.
+	ErrInvalidISBN = errors.New("invalid isbn")
+	// ErrNoISBN10 is returned, if an ISBN-13 has no ISBN-10 form (979 prefix).
+	ErrNoISBN10 = errors.New("isbn has no isbn-10 form")
+)
+
+// isbnRange is a registrant range, the registrant element has the length of
+// the bounds.
+type isbnRange struct {
+	lo, hi string
+}
+
+// isbnRanges are registrant ranges of the largest registration groups with
+// prefix 978, after the ISBN range message of the International ISBN Agency.
+// ISBN from other groups are not hyphenated.
+var isbnRanges = map[string][]isbnRange{
+	// English language area.
+	"0": {{"00", "19"}, {"200", "699"}, {"7000", "8499"}, {"85000", "89999"},
+		{"900000", "949999"}, {"9500000", "9999999"}},
+	"1": {{"00", "09"}, {"100", "399"}, {"4000", "5499"}, {"55000", "86979"},
+		{"869800", "998999"}, {"9990000", "9999999"}},
+	// French language area.
+	"2": {{"00", "19"}, {"200", "349"}, {"35000", "39999"}, {"400", "699"},
+		{"7000", "8399"}, {"84000", "89999"}, {"900000", "949999"}, {"9500000", "9999999"}},
+	// German language area.
+	"3": {{"00", "02"}, {"030", "033"}, {"0340", "0369"}, {"03700", "03999"},
+		{"04", "19"}, {"200", "699"}, {"7000", "8499"}, {"85000", "89999"},
+		{"900000", "949999"}, {"9500000", "9539999"}, {"95400", "96999"},
+		{"9700000", "9849999"}, {"98500", "99999"}},
+}
+
+// ISBN is an International Standard Book Number, ten or thirteen characters
+// without hyphens.
+type ISBN string
+
+// ParseISBN parses an ISBN-10 or ISBN-13 with or without hyphens, spaces or
+// an "ISBN", "urn:isbn:" or CrossRef id prefix and validates the check digit.
+func ParseISBN(s string) (ISBN, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	for _, prefix := range []string{"HTTP://ID.CROSSREF.ORG/ISBN/", "URN:ISBN:", "ISBN-13:", "ISBN-10:", "ISBN:", "ISBN"} {
+		if strings.HasPrefix(s, prefix) {
+			s = s[len(prefix):]
+			break
+		}
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r == 'X':
+			b.WriteRune(r)
+		case r == '-' || r == ' ':
+		default:
+			return "", ErrInvalidISBN
+		}
+	}
+	v := ISBN(b.String())
+	if !v.Valid() {
+		return "", ErrInvalidISBN
+	}
+	return v, nil
+}
+
+// NormalizeISBN returns a hyphenated ISBN-13 or the empty string, if s is not
+// a valid ISBN.
+func NormalizeISBN(s string) string {
+	v, err := ParseISBN(s)
+	if err != nil {
+		return ""
+	}
+	return v.ISBN13().Hyphenate()
+}
+
+// isbn10CheckDigit computes the check digit for the first nine digits.
+func isbn10CheckDigit(s string) byte {
+	var sum int
+	for i := 0; i < 9; i++ {
+		sum += (10 - i) * int(s[i]-'0')
+	}
+	switch c := (11 - sum%11) % 11; c {
+	case 10:
+		return 'X'
+	default:
+		return byte('0' + c)
+	}
+}
+
+// isbn13CheckDigit computes the check digit for the first twelve digits.
+func isbn13CheckDigit(s string) byte {
+	var sum int
+	for i := 0; i < 12; i++ {
+		w := 1
+		if i%2 == 1 {
+			w = 3
+		}
+		sum += w * int(s[i]-'0')
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// digits returns true, if s only contains digits.
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Valid returns true, if length and check digit are correct.
+func (v ISBN) Valid() bool {
+	s := string(v)
+	switch len(s) {
+	case 10:
+		return digits(s[:9]) && isbn10CheckDigit(s) == s[9]
+	case 13:
+		return digits(s) && (strings.HasPrefix(s, "978") || strings.HasPrefix(s, "979")) &&
+			isbn13CheckDigit(s) == s[12]
+	default:
+		return false
+	}
+}
+
+// ISBN13 returns the thirteen digit form of a valid ISBN.
+func (v ISBN) ISBN13() ISBN {
+	if len(v) != 10 {
+		return v
+	}
+	s := "978" + string(v[:9])
+	return ISBN(s + string(isbn13CheckDigit(s)))
+}
+
+// ISBN10 returns the ten digit form of a valid ISBN, if there is one.
+func (v ISBN) ISBN10() (ISBN, error) {
+	switch {
+	case len(v) == 10:
+		return v, nil
+	case strings.HasPrefix(string(v), "978"):
+		s := string(v[3:12])
+		return ISBN(s + string(isbn10CheckDigit(s))), nil
+	default:
+		return "", ErrNoISBN10
+	}
+}
+
+// Hyphenate separates prefix, registration group, registrant, publication and
+// check digit, e.g. 978-3-16-148410-0. If the registrant range is not known,
+// the ISBN is returned unchanged.
+func (v ISBN) Hyphenate() string {
+	s := string(v)
+	var prefix string
+	if len(s) == 13 {
+		prefix, s = s[:3], s[3:]
+		if prefix != "978" {
+			return string(v)
+		}
+	}
+	if len(s) != 10 {
+		return string(v)
+	}
+	group, rest := s[:1], s[1:9]
+	for _, r := range isbnRanges[group] {
+		n := len(r.lo)
+		if rest[:n] < r.lo || rest[:n] > r.hi {
+			continue
+		}
+		parts := []string{group, rest[:n], rest[n:], s[9:]}
+		if prefix != "" {
+			parts = append([]string{prefix}, parts...)
+		}
+		return strings.Join(parts, "-")
+	}
+	return string(v)
+}
+
+// String returns the hyphenated form.
+func (v ISBN) String() string {
+	return v.Hyphenate()
+}
@@ -0,0 +1,97 @@
+package span
+
+import "testing"
+
+func TestParseISBN(t *testing.T) {
+	var cases = []struct {
+		s      string
+		result ISBN
+		err    error
+	}{
+		{"", "", ErrInvalidISBN},
+		{"0-306-40615-2", "0306406152", nil},
+		{"0-306-40615-3", "", ErrInvalidISBN},
+		{"3-16-148410-x", "316148410X", nil},
+		{"978-3-16-148410-0", "9783161484100", nil},
+		{"978 3 16 148410 1", "", ErrInvalidISBN},
+		{"urn:ISBN:978-0-306-40615-7", "9780306406157", nil},
+		{"ISBN 978-0-306-40615-7", "9780306406157", nil},
+		{"http://id.crossref.org/isbn/9780306406157", "9780306406157", nil},
+		{"979-10-90636-07-1", "9791090636071", nil},
+		{"977-1-234567-89-0", "", ErrInvalidISBN},
+		{"978-0-306-40615-7 (pbk.)", "", ErrInvalidISBN},
+	}
+	for _, c := range cases {
+		result, err := ParseISBN(c.s)
+		if err != c.err {
+			t.Errorf("ParseISBN(%q): got %v, want %v", c.s, err, c.err)
+		}
+		if result != c.result {
+			t.Errorf("ParseISBN(%q): got %q, want %q", c.s, result, c.result)
+		}
+	}
+}
+
+func TestISBNConversion(t *testing.T) {
+	var cases = []struct {
+		isbn10 ISBN
+		isbn13 ISBN
+	}{
+		{"0306406152", "9780306406157"},
+		{"316148410X", "9783161484100"},
+		{"1402894627", "9781402894626"},
+	}
+	for _, c := range cases {
+		if v := c.isbn10.ISBN13(); v != c.isbn13 {
+			t.Errorf("ISBN13(%s): got %s, want %s", c.isbn10, v, c.isbn13)
+		}
+		v, err := c.isbn13.ISBN10()
+		if err != nil {
+			t.Error(err)
+		}
+		if v != c.isbn10 {
+			t.Errorf("ISBN10(%s): got %s, want %s", c.isbn13, v, c.isbn10)
+		}
+	}
+	if _, err := ISBN("9791090636071").ISBN10(); err != ErrNoISBN10 {
+		t.Errorf("ISBN10: got %v, want %v", err, ErrNoISBN10)
+	}
+}
+
+func TestISBNHyphenate(t *testing.T) {
+	var cases = []struct {
+		isbn   ISBN
+		result string
+	}{
+		{"9783161484100", "978-3-16-148410-0"},
+		{"316148410X", "3-16-148410-X"},
+		{"9780306406157", "978-0-306-40615-7"},
+		{"9781402894626", "978-1-4028-9462-6"},
+		{"9783643506771", "978-3-643-50677-1"},
+		{"9783954003310", "978-3-95400-331-0"},
+		{"9791090636071", "9791090636071"},
+		{"9788420471839", "9788420471839"},
+	}
+	for _, c := range cases {
+		if v := c.isbn.Hyphenate(); v != c.result {
+			t.Errorf("Hyphenate(%s): got %s, want %s", c.isbn, v, c.result)
+		}
+	}
+}
+
+func TestNormalizeISBN(t *testing.T) {
+	var cases = []struct {
+		s      string
+		result string
+	}{
+		{"3-16-148410-X", "978-3-16-148410-0"},
+		{"9783161484100", "978-3-16-148410-0"},
+		{"3-16-148410-0", ""},
+		{"n/a", ""},
+	}
+	for _, c := range cases {
+		if v := NormalizeISBN(c.s); v != c.result {
+			t.Errorf("NormalizeISBN(%q): got %q, want %q", c.s, v, c.result)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package isdiff compares two conversion runs in intermediate schema, e.g.
+// the output of span-import before and after a converter change, record by
+// record. Records are aligned by finc.id and reported as added, removed or
+// changed, with the number of records changed per field.
+package isdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// MaxSampleIDs is the number of record ids kept per change, for review.
+const MaxSampleIDs = 5
+
+// ErrMissingID is returned for records without finc.id.
+var ErrMissingID = errors.New("missing finc.id")
+
+// DefaultIgnore are fields, that are expected to change between runs: the
+// schema version, update indicators like file dates and labels attached by
+// span-tag.
+var DefaultIgnore = []string{"version", "x.indicator", "x.labels"}
+
+// record is a compact version of a record of the old run.
+type record struct {
+	SourceID string
+	// Fields maps field names to a hash of the value.
+	Fields map[string]uint64
+}
+
+// Counts are the changes for a source.
+type Counts struct {
+	SourceID  string `json:"sid"`
+	Added     int64  `json:"added"`
+	Removed   int64  `json:"removed"`
+	Changed   int64  `json:"changed"`
+	Unchanged int64  `json:"unchanged"`
+	// Fields counts changed records per field.
+	Fields map[string]int64 `json:"fields,omitempty"`
+	// IDs are sample ids, per kind of change or field.
+	IDs map[string][]string `json:"ids,omitempty"`
+}
+
+// sample keeps a record id for review.
+func (c *Counts) sample(key, id string) {
+	if len(c.IDs[key]) < MaxSampleIDs {
+		c.IDs[key] = append(c.IDs[key], id)
+	}
+}
+
+// Differ compares two runs, safe for concurrent use. All records of the old
+// run must be added before the records of the new run. Only hashes of the old
+// run are kept in memory.
+type Differ struct {
+	// Ignore lists fields, that are not compared.
+	Ignore map[string]bool
+
+	mu     sync.Mutex
+	old    map[string]record
+	counts map[string]*Counts
+}
+
+// New creates a differ, ignoring the given fields, e.g. DefaultIgnore.
+func New(ignore ...string) *Differ {
+	d := &Differ{
+		Ignore: make(map[string]bool),
+		old:    make(map[string]record),
+		counts: make(map[string]*Counts),
+	}
+	for _, name := range ignore {
+		d.Ignore[name] = true
+	}
+	return d
+}
+
+// parse returns the id, source id and hashed fields of a record.
+func (d *Differ) parse(b []byte) (id string, r record, err error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return "", r, err
+	}
+	if err := json.Unmarshal(doc["finc.id"], &id); err != nil || id == "" {
+		return "", r, ErrMissingID
+	}
+	_ = json.Unmarshal(doc["finc.source_id"], &r.SourceID)
+	r.Fields = make(map[string]uint64, len(doc))
+	var buf bytes.Buffer
+	for k, v := range doc {
+		if d.Ignore[k] {
+			continue
+		}
+		buf.Reset()
+		if err := json.Compact(&buf, v); err != nil {
+			return "", r, err
+		}
+		if s := buf.String(); s == "null" || s == `""` || s == "[]" || s == "{}" {
+			// Empty values are the same as missing ones.
+			continue
+		}
+		h := fnv.New64a()
+		h.Write(buf.Bytes())
+		r.Fields[k] = h.Sum64()
+	}
+	return id, r, nil
+}
+
+// get returns the counts for a source, must be called with the lock held.
+func (d *Differ) get(sid string) *Counts {
+	c := d.counts[sid]
+	if c == nil {
+		c = &Counts{SourceID: sid, Fields: make(map[string]int64), IDs: make(map[string][]string)}
+		d.counts[sid] = c
+	}
+	return c
+}
+
+// AddOld adds a record of the old run.
+func (d *Differ) AddOld(b []byte) error {
+	id, r, err := d.parse(b)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.old[id] = r
+	return nil
+}
+
+// AddNew adds a record of the new run and compares it to the old version.
+func (d *Differ) AddNew(b []byte) error {
+	id, r, err := d.parse(b)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c := d.get(r.SourceID)
+	prev, ok := d.old[id]
+	if !ok {
+		c.Added++
+		c.sample("added", id)
+		return nil
+	}
+	delete(d.old, id)
+	changed := changedFields(prev.Fields, r.Fields)
+	if len(changed) == 0 {
+		c.Unchanged++
+		return nil
+	}
+	c.Changed++
+	c.sample("changed", id)
+	for _, name := range changed {
+		c.Fields[name]++
+		c.sample(name, id)
+	}
+	return nil
+}
+
+// changedFields returns the sorted names of fields, that differ.
+func changedFields(a, b map[string]uint64) (names []string) {
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			names = append(names, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Counts returns the changes per source, sorted by source id. Old records
+// not seen in the new run are counted as removed.
+func (d *Differ) Counts() []Counts {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var ids []string
+	for id := range d.old {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		c := d.get(d.old[id].SourceID)
+		c.Removed++
+		c.sample("removed", id)
+		delete(d.old, id)
+	}
+	var result []Counts
+	for _, c := range d.counts {
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].SourceID < result[j].SourceID
+	})
+	return result
+}
@@ -0,0 +1,60 @@
+package isdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCounts(t *testing.T) {
+	d := New(DefaultIgnore...)
+	for _, s := range []string{
+		`{"finc.id": "1", "finc.source_id": "49", "rft.atitle": "A", "version": "0.9"}`,
+		`{"finc.id": "2", "finc.source_id": "49", "rft.atitle": "B", "doi": "10.1/x"}`,
+		`{"finc.id": "3", "finc.source_id": "55", "rft.atitle": "C"}`,
+	} {
+		if err := d.AddOld([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, s := range []string{
+		`{"finc.id": "1", "finc.source_id": "49", "rft.atitle":  "A", "version": "1.0", "url": []}`,
+		`{"finc.id": "2", "finc.source_id": "49", "rft.atitle": "B.", "languages": ["eng"]}`,
+		`{"finc.id": "4", "finc.source_id": "49", "rft.atitle": "D"}`,
+	} {
+		if err := d.AddNew([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []Counts{
+		{
+			SourceID:  "49",
+			Added:     1,
+			Changed:   1,
+			Unchanged: 1,
+			Fields:    map[string]int64{"doi": 1, "languages": 1, "rft.atitle": 1},
+			IDs: map[string][]string{
+				"added":      {"4"},
+				"changed":    {"2"},
+				"doi":        {"2"},
+				"languages":  {"2"},
+				"rft.atitle": {"2"},
+			},
+		},
+		{
+			SourceID: "55",
+			Removed:  1,
+			Fields:   map[string]int64{},
+			IDs:      map[string][]string{"removed": {"3"}},
+		},
+	}
+	if got := d.Counts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestMissingID(t *testing.T) {
+	d := New()
+	if err := d.AddOld([]byte(`{"finc.source_id": "49"}`)); err != ErrMissingID {
+		t.Errorf("got %v, want %v", err, ErrMissingID)
+	}
+}
@@ -0,0 +1,69 @@
+package span
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrInvalidISSN is returned for strings, that do not look like an ISSN.
+	ErrInvalidISSN = errors.New("invalid issn")
+	// ErrISSNCheckDigit is returned, if the check digit does not match.
+	ErrISSNCheckDigit = errors.New("issn check digit mismatch")
+)
+
+// ISSN is an International Standard Serial Number in standard form, 1234-567X.
+type ISSN string
+
+// ParseISSN parses an ISSN with or without hyphen and returns it in standard
+// form. The check digit is not verified, use Validate.
+func ParseISSN(s string) (ISSN, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) == 8 {
+		s = s[:4] + "-" + s[4:]
+	}
+	if !ISSNPattern.MatchString(s) || len(s) != 9 {
+		return "", ErrInvalidISSN
+	}
+	return ISSN(s), nil
+}
+
+// checkDigit computes the check digit of a well-formed ISSN.
+func (v ISSN) checkDigit() byte {
+	s := string(v[:4]) + string(v[5:8])
+	var sum int
+	for i := 0; i < 7; i++ {
+		sum += (8 - i) * int(s[i]-'0')
+	}
+	switch c := (11 - sum%11) % 11; c {
+	case 10:
+		return 'X'
+	default:
+		return byte('0' + c)
+	}
+}
+
+// Validate checks shape and check digit.
+func (v ISSN) Validate() error {
+	if len(v) != 9 || !ISSNPattern.MatchString(string(v)) {
+		return ErrInvalidISSN
+	}
+	if v.checkDigit() != v[8] {
+		return ErrISSNCheckDigit
+	}
+	return nil
+}
+
+// Valid returns true, if shape and check digit are correct.
+func (v ISSN) Valid() bool {
+	return v.Validate() == nil
+}
+
+// Correct fixes the common transcription error of a 0 instead of an X as
+// check digit. Returns the corrected ISSN and true, if it was changed.
+func (v ISSN) Correct() (ISSN, bool) {
+	if v.Validate() != ErrISSNCheckDigit || v[8] != '0' || v.checkDigit() != 'X' {
+		return v, false
+	}
+	return v[:8] + "X", true
+}
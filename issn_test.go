@@ -0,0 +1,48 @@
+package span
+
+import "testing"
+
+func TestISSNValidate(t *testing.T) {
+	var cases = []struct {
+		s   string
+		err error
+	}{
+		{"", ErrInvalidISSN},
+		{"1234", ErrInvalidISSN},
+		{"0317-8471", nil},
+		{"03178471", nil},
+		{"2434-561x", nil},
+		{"2434-5610", ErrISSNCheckDigit},
+		{"0317-8472", ErrISSNCheckDigit},
+		{"0317-847", ErrInvalidISSN},
+		{"0317-84711", ErrInvalidISSN},
+	}
+	for _, c := range cases {
+		v, err := ParseISSN(c.s)
+		if err == nil {
+			err = v.Validate()
+		}
+		if err != c.err {
+			t.Errorf("%q: got %v, want %v", c.s, err, c.err)
+		}
+	}
+}
+
+func TestISSNCorrect(t *testing.T) {
+	var cases = []struct {
+		issn    ISSN
+		result  ISSN
+		changed bool
+	}{
+		{"2434-5610", "2434-561X", true},
+		{"2434-561X", "2434-561X", false},
+		{"0317-8472", "0317-8472", false},
+		{"0317-8471", "0317-8471", false},
+	}
+	for _, c := range cases {
+		result, changed := c.issn.Correct()
+		if result != c.result || changed != c.changed {
+			t.Errorf("Correct(%s): got %s %v, want %s %v", c.issn, result, changed, c.result, c.changed)
+		}
+	}
+}
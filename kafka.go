@@ -0,0 +1,171 @@
+package span
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// KafkaCommand is the kcat (formerly kafkacat) executable used to consume and
+// produce Kafka messages.
+var KafkaCommand = "kcat"
+
+// kafkaArgs returns kcat arguments for a topic given as
+// kafka://[user[:password]@]broker[,broker...]/topic[?options], to consume
+// (produce false) or produce messages, one per line. User and password default
+// to SPAN_KAFKA_USER and SPAN_KAFKA_PASSWORD and enable SASL PLAIN. They are
+// returned as configuration lines, which must not be passed on the command
+// line, where other users can see them, refs. kafkaCommand.
+//
+// Options are compression (producer, e.g. snappy, gzip, lz4, zstd), group
+// (consumer group, commits offsets), offset (consumer, default beginning),
+// follow (consumer, wait for new messages instead of exiting at the end of
+// the topic) and librdkafka properties, e.g. security.protocol=SASL_SSL.
+func kafkaArgs(u *url.URL, produce bool) (args, config []string, err error) {
+	topic := strings.Trim(u.Path, "/")
+	if u.Host == "" || topic == "" || strings.Contains(topic, "/") {
+		return nil, nil, fmt.Errorf("kafka: want kafka://broker[,broker...]/topic, got %s%s", u.Host, u.Path)
+	}
+	q := u.Query()
+	args = []string{"-q", "-b", u.Host}
+	if produce {
+		args = append(args, "-P", "-t", topic)
+		if c := q.Get("compression"); c != "" {
+			args = append(args, "-z", c)
+		}
+	} else {
+		if group := q.Get("group"); group != "" {
+			args = append(args, "-G", group)
+		} else {
+			offset := q.Get("offset")
+			if offset == "" {
+				offset = "beginning"
+			}
+			args = append(args, "-C", "-o", offset)
+		}
+		if q.Get("follow") != "true" {
+			args = append(args, "-e")
+		}
+	}
+	// Properties are passed on in a stable order.
+	var keys []string
+	for k := range q {
+		if strings.Contains(k, ".") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-X", k+"="+q.Get(k))
+	}
+	if user, password := credentials(u, "SPAN_KAFKA"); user != "" {
+		if q.Get("security.protocol") == "" {
+			args = append(args, "-X", "security.protocol=SASL_SSL")
+		}
+		config = []string{"sasl.mechanisms=PLAIN", "sasl.username=" + user, "sasl.password=" + password}
+	}
+	if !produce && q.Get("group") != "" {
+		// A balanced consumer takes topics as arguments.
+		args = append(args, topic)
+	} else if !produce {
+		args = append(args, "-t", topic)
+	}
+	return args, config, nil
+}
+
+// kafkaCommand returns a kcat command for a topic. Configuration lines are
+// written to a temporary file, readable only by the current user, and passed
+// with -F. The returned function removes the file and must be called, once the
+// command has finished.
+func kafkaCommand(link string, produce bool) (*exec.Cmd, func(), error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, nil, err
+	}
+	args, config, err := kafkaArgs(u, produce)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {}
+	if len(config) > 0 {
+		// TempFile creates files with mode 0600.
+		f, err := ioutil.TempFile("", "span-kcat-")
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanup = func() { os.Remove(f.Name()) }
+		if _, err := io.WriteString(f, strings.Join(config, "\n")+"\n"); err != nil {
+			f.Close()
+			cleanup()
+			return nil, nil, err
+		}
+		if err := f.Close(); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		args = append([]string{"-F", f.Name()}, args...)
+	}
+	cmd := exec.Command(KafkaCommand, args...)
+	cmd.Stderr = os.Stderr
+	return cmd, cleanup, nil
+}
+
+// OpenKafka reads messages from a Kafka topic, one per line, see kafkaArgs
+// for the link format. By default, reading stops at the end of the topic.
+func OpenKafka(link string) (io.ReadCloser, error) {
+	cmd, cleanup, err := kafkaCommand(link, false)
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("kafka requires %s: %v", KafkaCommand, err)
+	}
+	return &cmdReader{ReadCloser: stdout, name: KafkaCommand, cmd: cmd, cleanup: cleanup}, nil
+}
+
+// cmdWriter writes to the input of an external command.
+type cmdWriter struct {
+	io.WriteCloser
+	cmd     *exec.Cmd
+	cleanup func()
+}
+
+// Close closes the input and waits for the command to finish.
+func (w *cmdWriter) Close() error {
+	defer w.cleanup()
+	if err := w.WriteCloser.Close(); err != nil {
+		return err
+	}
+	return w.cmd.Wait()
+}
+
+// CreateKafka writes messages to a Kafka topic, one per line, see kafkaArgs
+// for the link format. Messages are delivered, when Close returns without
+// error.
+func CreateKafka(link string) (io.WriteCloser, error) {
+	cmd, cleanup, err := kafkaCommand(link, true)
+	if err != nil {
+		return nil, err
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("kafka requires %s: %v", KafkaCommand, err)
+	}
+	return &cmdWriter{WriteCloser: stdin, cmd: cmd, cleanup: cleanup}, nil
+}
@@ -0,0 +1,145 @@
+package span
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKafkaArgs(t *testing.T) {
+	var cases = []struct {
+		link    string
+		produce bool
+		args    []string
+		config  []string
+		err     bool
+	}{
+		{"kafka://k1:9092,k2:9092/raw", false,
+			[]string{"-q", "-b", "k1:9092,k2:9092", "-C", "-o", "beginning", "-e", "-t", "raw"}, nil, false},
+		{"kafka://k1:9092/raw?group=span&follow=true", false,
+			[]string{"-q", "-b", "k1:9092", "-G", "span", "raw"}, nil, false},
+		{"kafka://k1:9092/is?compression=snappy&message.max.bytes=2000000", true,
+			[]string{"-q", "-b", "k1:9092", "-P", "-t", "is", "-z", "snappy", "-X", "message.max.bytes=2000000"}, nil, false},
+		{"kafka://u:secret@k1:9092/is", true,
+			[]string{"-q", "-b", "k1:9092", "-P", "-t", "is", "-X", "security.protocol=SASL_SSL"},
+			[]string{"sasl.mechanisms=PLAIN", "sasl.username=u", "sasl.password=secret"}, false},
+		{"kafka://k1:9092/", false, nil, nil, true},
+		{"kafka:///topic", false, nil, nil, true},
+	}
+	for _, c := range cases {
+		u, err := url.Parse(c.link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args, config, err := kafkaArgs(u, c.produce)
+		if (err != nil) != c.err {
+			t.Errorf("kafkaArgs(%s): got %v, want error %v", c.link, err, c.err)
+		}
+		if !reflect.DeepEqual(args, c.args) {
+			t.Errorf("kafkaArgs(%s): got %v, want %v", c.link, args, c.args)
+		}
+		if !reflect.DeepEqual(config, c.config) {
+			t.Errorf("kafkaArgs(%s): got config %v, want %v", c.link, config, c.config)
+		}
+	}
+}
+
+func TestKafkaPasswordNotInArgs(t *testing.T) {
+	defer func(u, p string) {
+		os.Setenv("SPAN_KAFKA_USER", u)
+		os.Setenv("SPAN_KAFKA_PASSWORD", p)
+	}(os.Getenv("SPAN_KAFKA_USER"), os.Getenv("SPAN_KAFKA_PASSWORD"))
+	os.Setenv("SPAN_KAFKA_USER", "u")
+	os.Setenv("SPAN_KAFKA_PASSWORD", "envsecret")
+
+	for _, link := range []string{"kafka://u:urlsecret@k1:9092/is", "kafka://k1:9092/is"} {
+		for _, produce := range []bool{false, true} {
+			cmd, cleanup, err := kafkaCommand(link, produce)
+			if err != nil {
+				t.Fatal(err)
+			}
+			args := strings.Join(cmd.Args, " ")
+			if strings.Contains(args, "secret") {
+				t.Errorf("%s: password in arguments: %s", link, args)
+			}
+			if len(cmd.Args) < 3 || cmd.Args[1] != "-F" {
+				t.Fatalf("%s: want -F config file, got %s", link, args)
+			}
+			filename := cmd.Args[2]
+			fi, err := os.Stat(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mode := fi.Mode().Perm(); mode != 0600 {
+				t.Errorf("%s: config file mode %v, want 0600", link, mode)
+			}
+			b, err := ioutil.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(b), "sasl.password=") {
+				t.Errorf("%s: password missing in config file: %s", link, b)
+			}
+			cleanup()
+			if _, err := os.Stat(filename); !os.IsNotExist(err) {
+				t.Errorf("%s: config file not removed: %v", link, err)
+			}
+		}
+	}
+}
+
+func TestKafkaRoundtrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-kafka-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// A fake kcat, that keeps produced messages in a file.
+	script := filepath.Join(dir, "kcat")
+	topic := filepath.Join(dir, "topic")
+	body := "#!/bin/sh\ncase \"$*\" in *-P*) cat > " + topic + ";; *) cat " + topic + ";; esac\n"
+	if err := ioutil.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer func(s string) { KafkaCommand = s }(KafkaCommand)
+	KafkaCommand = script
+	// Configuration files with credentials go here and must be removed.
+	tmp := filepath.Join(dir, "tmp")
+	if err := os.Mkdir(tmp, 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer func(s string) { os.Setenv("TMPDIR", s) }(os.Getenv("TMPDIR"))
+	os.Setenv("TMPDIR", tmp)
+
+	w, err := Create("kafka://u:p@localhost:9092/is")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("{\"a\": 1}\n{\"a\": 2}\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Open("kafka://u:p@localhost:9092/is")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(b), "\n"); got != 2 {
+		t.Errorf("got %d messages, want 2", got)
+	}
+	if files, _ := filepath.Glob(filepath.Join(tmp, "*")); len(files) > 0 {
+		t.Errorf("configuration files left: %v", files)
+	}
+}
@@ -1,14 +1,151 @@
 package span
 
 import (
+	"fmt"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/abadojack/whatlanggo"
+	"github.com/miku/span/container"
 )
 
+// LanguageDetector guesses the language of a text.
+type LanguageDetector interface {
+	// Detect returns a three letter language code or "und".
+	Detect(text string) (string, error)
+}
+
+// LanguageDetectorFunc turns a function into a LanguageDetector.
+type LanguageDetectorFunc func(text string) (string, error)
+
+// Detect calls the function.
+func (f LanguageDetectorFunc) Detect(text string) (string, error) {
+	return f(text)
+}
+
+// LanguageDetectorChain tries detectors in order, until one of them returns a
+// language other than "und".
+type LanguageDetectorChain []LanguageDetector
+
+// Detect returns the first determined language.
+func (c LanguageDetectorChain) Detect(text string) (string, error) {
+	var err error
+	for _, d := range c {
+		var lang string
+		lang, err = d.Detect(text)
+		if err == nil && lang != "" && lang != "und" {
+			return lang, nil
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return "und", nil
+}
+
+// LanguageDetectors are the available backends by name. Backends, that
+// require additional libraries, need to register here.
+var LanguageDetectors = map[string]LanguageDetector{
+	"whatlanggo": LanguageDetectorFunc(func(text string) (string, error) {
+		lang := whatlanggo.LangToString(whatlanggo.Detect(text).Lang)
+		if lang == "" {
+			return "und", nil
+		}
+		return lang, nil
+	}),
+}
+
+// DefaultLanguageDetector is used by DetectLang3.
+var DefaultLanguageDetector LanguageDetector = LanguageDetectors["whatlanggo"]
+
+// TrustRecordLanguage lets a LanguageGuesser prefer a language given in the
+// record itself and skip detection.
+var TrustRecordLanguage = false
+
+// NewLanguageDetector returns a detector for a comma separated list of
+// backend names, later ones being used as fallback.
+func NewLanguageDetector(names string) (LanguageDetector, error) {
+	var chain LanguageDetectorChain
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		d, ok := LanguageDetectors[name]
+		if !ok {
+			var available []string
+			for k := range LanguageDetectors {
+				available = append(available, k)
+			}
+			sort.Strings(available)
+			return nil, fmt.Errorf("unknown language detector: %s, available: %s",
+				name, strings.Join(available, ", "))
+		}
+		chain = append(chain, d)
+	}
+	switch len(chain) {
+	case 0:
+		return nil, fmt.Errorf("no language detector given")
+	case 1:
+		return chain[0], nil
+	}
+	return chain, nil
+}
+
 // DetectLang3 returns the best guess 3-letter language code for a given text.
 func DetectLang3(text string) (string, error) {
-	return whatlanggo.LangToString(whatlanggo.Detect(text).Lang), nil
+	return DefaultLanguageDetector.Detect(text)
+}
+
+// LanguageGuesser guesses languages of a record from a few of its texts,
+// restricted to a set of accepted languages.
+type LanguageGuesser struct {
+	// Accepted languages, all languages are accepted if nil.
+	Accepted *container.StringSet
+	// MinLength, shorter texts are not analyzed.
+	MinLength int
+	// MaxLength, only a prefix of this many bytes of longer texts is analyzed.
+	MaxLength int
+	// Detector to use, defaults to DefaultLanguageDetector.
+	Detector LanguageDetector
+}
+
+// Guess returns the languages of the given texts. If TrustRecordLanguage is
+// set and the record language can be resolved, only that is returned.
+func (g LanguageGuesser) Guess(recordLanguage string, texts ...string) []string {
+	set := container.NewStringSet()
+	if TrustRecordLanguage {
+		if lang := LanguageIdentifier(recordLanguage); lang != "" {
+			return []string{lang}
+		}
+	}
+	detector := g.Detector
+	if detector == nil {
+		detector = DefaultLanguageDetector
+	}
+	for _, s := range texts {
+		if len(s) < g.MinLength {
+			continue
+		}
+		if g.MaxLength > 0 && len(s) > g.MaxLength {
+			// Do not cut within a rune.
+			i := g.MaxLength
+			for i > 0 && !utf8.RuneStart(s[i]) {
+				i--
+			}
+			s = s[:i]
+		}
+		lang, err := detector.Detect(s)
+		if err != nil || lang == "und" {
+			continue
+		}
+		if g.Accepted != nil && !g.Accepted.Contains(lang) {
+			continue
+		}
+		set.Add(lang)
+	}
+	return set.Values()
 }
 
 // LanguageIdentifier returns the three letter identifier from any string.
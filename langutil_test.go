@@ -0,0 +1,63 @@
+package span
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/miku/span/container"
+)
+
+// prefixDetector returns the text up to the first space as language.
+var prefixDetector = LanguageDetectorFunc(func(text string) (string, error) {
+	return strings.Fields(text + " und")[0], nil
+})
+
+func TestLanguageGuesser(t *testing.T) {
+	defer func(v bool) { TrustRecordLanguage = v }(TrustRecordLanguage)
+
+	var tests = []struct {
+		about  string
+		g      LanguageGuesser
+		trust  bool
+		record string
+		texts  []string
+		out    []string
+	}{
+		{"detect", LanguageGuesser{}, false, "", []string{"deu text", "eng text"}, []string{"deu", "eng"}},
+		{"undetermined", LanguageGuesser{}, false, "", []string{" ", ""}, nil},
+		{"accepted", LanguageGuesser{Accepted: container.NewStringSet("eng")}, false, "", []string{"deu text", "eng text"}, []string{"eng"}},
+		{"too short", LanguageGuesser{MinLength: 5}, false, "", []string{"deu", "eng text"}, []string{"eng"}},
+		{"prefix only", LanguageGuesser{MaxLength: 5}, false, "", []string{"deutsch text"}, []string{"deuts"}},
+		{"record ignored", LanguageGuesser{}, false, "fr", []string{"deu text"}, []string{"deu"}},
+		{"record trusted", LanguageGuesser{}, true, "fr", []string{"deu text"}, []string{"fra"}},
+		{"record unknown", LanguageGuesser{}, true, "xx", []string{"deu text"}, []string{"deu"}},
+	}
+
+	for _, tt := range tests {
+		TrustRecordLanguage = tt.trust
+		tt.g.Detector = prefixDetector
+		out := tt.g.Guess(tt.record, tt.texts...)
+		sort.Strings(out)
+		if !reflect.DeepEqual(out, tt.out) {
+			t.Errorf("%s: got %v, want %v", tt.about, out, tt.out)
+		}
+	}
+}
+
+func TestNewLanguageDetector(t *testing.T) {
+	if _, err := NewLanguageDetector("whatlanggo"); err != nil {
+		t.Errorf("NewLanguageDetector: %v", err)
+	}
+	if _, err := NewLanguageDetector("whatlanggo,unknown"); err == nil {
+		t.Errorf("NewLanguageDetector: want error for unknown detector")
+	}
+	chain := LanguageDetectorChain{
+		LanguageDetectorFunc(func(string) (string, error) { return "und", nil }),
+		prefixDetector,
+	}
+	if lang, _ := chain.Detect("eng text"); lang != "eng" {
+		t.Errorf("LanguageDetectorChain: got %s, want eng", lang)
+	}
+}
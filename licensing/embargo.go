@@ -24,6 +24,9 @@ var (
 
 	ErrBeforeMovingWall = errors.New("before moving wall")
 	ErrAfterMovingWall  = errors.New("after moving wall")
+
+	// Now is the reference date for moving walls, can be replaced in tests.
+	Now = time.Now
 )
 
 // Embargo holds moving wall information.
@@ -87,13 +90,25 @@ var (
 // starting embargo coming first. The two statements should be separated by a
 // semicolon. For example, "R10Y;P30D" describes an archive in which the past 10
 // calendar years of content are available, except for the most current 30 days.
+//
+// Delays as found in some holdings formats, e.g. "-1Y" or "-12M", are
+// understood as well and mean the same as "P1Y" and "P12M".
 type Embargo string
 
+// normalize trims whitespace and turns a delay into an embargo.
+func (embargo Embargo) normalize() string {
+	e := strings.TrimSpace(string(embargo))
+	if strings.HasPrefix(e, "-") {
+		return "P" + e[1:]
+	}
+	return e
+}
+
 // Duration converts embargo like P12M, P1M, R10Y into a time.Duration. This
 // duration will be positive. Time differences will have small shifts due to a
 // month and a year being a fixed number of hours.
 func (embargo Embargo) Duration() (dur time.Duration, err error) {
-	e := strings.TrimSpace(string(embargo))
+	e := embargo.normalize()
 	if len(e) == 0 {
 		return
 	}
@@ -124,31 +139,45 @@ func (embargo Embargo) Duration() (dur time.Duration, err error) {
 
 // AccessBeginsAtWall returns true, if access begins at the moving wall.
 func (embargo Embargo) AccessBeginsAtWall() bool {
-	return strings.HasPrefix(strings.TrimSpace(string(embargo)), "R")
+	return strings.HasPrefix(embargo.normalize(), "R")
 }
 
 // AccessEndsAtWall returns true, if access end at the moving wall.
 func (embargo Embargo) AccessEndsAtWall() bool {
-	return strings.HasPrefix(strings.TrimSpace(string(embargo)), "P")
+	return strings.HasPrefix(embargo.normalize(), "P")
 }
 
 // Compatible returns true, if the given date is validated by the embargo relative to the current time.
 func (embargo Embargo) Compatible(t time.Time) error {
-	return embargo.CompatibleTo(t, time.Now())
+	return embargo.CompatibleTo(t, Now())
 }
 
-// CompatibleTo returns true, if the given date in validated by this embargo relative to another date.
-func (embargo Embargo) CompatibleTo(t time.Time, relative time.Time) error {
+// Boundary returns the moving wall relative to a given date. The embargo
+// must consist of a single statement.
+func (embargo Embargo) Boundary(relative time.Time) (time.Time, error) {
 	dur, err := embargo.Duration()
 	if err != nil {
-		return err
-	}
-	wall := relative.Add(-dur)
-	if embargo.AccessBeginsAtWall() && t.Before(wall) {
-		return ErrBeforeMovingWall
+		return time.Time{}, err
 	}
-	if embargo.AccessEndsAtWall() && t.After(wall) {
-		return ErrAfterMovingWall
+	return relative.Add(-dur), nil
+}
+
+// CompatibleTo returns true, if the given date in validated by this embargo
+// relative to another date. Combined statements, like "R10Y;P30D" are checked
+// one by one.
+func (embargo Embargo) CompatibleTo(t time.Time, relative time.Time) error {
+	for _, e := range strings.Split(string(embargo), ";") {
+		e := Embargo(e)
+		wall, err := e.Boundary(relative)
+		if err != nil {
+			return err
+		}
+		if e.AccessBeginsAtWall() && t.Before(wall) {
+			return ErrBeforeMovingWall
+		}
+		if e.AccessEndsAtWall() && t.After(wall) {
+			return ErrAfterMovingWall
+		}
 	}
 	return nil
 }
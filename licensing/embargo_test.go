@@ -38,6 +38,9 @@ func TestEmbargoDuration(t *testing.T) {
 		{
 			embargo: Embargo("RRR"), dur: 0, err: ErrInvalidEmbargo,
 		},
+		{
+			embargo: Embargo("-12M"), dur: mustParseDuration("8760h"), err: nil,
+		},
 	}
 	for _, c := range cases {
 		dur, err := c.embargo.Duration()
@@ -100,6 +103,24 @@ func TestEmbargoCompatible(t *testing.T) {
 			rel:     mustParseTime("2006-01-02", "2001-01-01"),
 			err:     nil,
 		},
+		{
+			embargo: Embargo("R10Y;P30D"), // Last ten years, except the last 30 days.
+			t:       mustParseTime("2006-01-02", "2000-12-01"),
+			rel:     mustParseTime("2006-01-02", "2001-01-01"),
+			err:     nil,
+		},
+		{
+			embargo: Embargo("R10Y;P30D"),
+			t:       mustParseTime("2006-01-02", "2000-12-20"),
+			rel:     mustParseTime("2006-01-02", "2001-01-01"),
+			err:     ErrAfterMovingWall,
+		},
+		{
+			embargo: Embargo("R10Y;P30D"),
+			t:       mustParseTime("2006-01-02", "1980-01-01"),
+			rel:     mustParseTime("2006-01-02", "2001-01-01"),
+			err:     ErrBeforeMovingWall,
+		},
 	}
 	for _, c := range cases {
 		err := c.embargo.CompatibleTo(c.t, c.rel)
@@ -109,6 +130,29 @@ func TestEmbargoCompatible(t *testing.T) {
 	}
 }
 
+func TestEmbargoBoundary(t *testing.T) {
+	var cases = []struct {
+		embargo Embargo
+		rel     time.Time
+		result  time.Time
+		err     error
+	}{
+		{Embargo(""), mustParseTime("2006-01-02", "2001-01-01"), mustParseTime("2006-01-02", "2001-01-01"), nil},
+		{Embargo("P1D"), mustParseTime("2006-01-02", "2001-01-01"), mustParseTime("2006-01-02", "2000-12-31"), nil},
+		{Embargo("R1M"), mustParseTime("2006-01-02", "2001-01-01"), mustParseTime("2006-01-02 15:04", "2000-12-01 14:00"), nil},
+		{Embargo("X1Y"), mustParseTime("2006-01-02", "2001-01-01"), time.Time{}, ErrInvalidEmbargo},
+	}
+	for _, c := range cases {
+		result, err := c.embargo.Boundary(c.rel)
+		if err != c.err {
+			t.Errorf("Boundary(%v, %v): got %v, want %v", c.embargo, c.rel, err, c.err)
+		}
+		if !result.Equal(c.result) {
+			t.Errorf("Boundary(%v, %v): got %v, want %v", c.embargo, c.rel, result, c.result)
+		}
+	}
+}
+
 func TestEmbargoAccessBeginsAtWall(t *testing.T) {
 	var cases = []struct {
 		e                  Embargo
@@ -120,6 +164,7 @@ func TestEmbargoAccessBeginsAtWall(t *testing.T) {
 		{Embargo("R10M"), true},
 		{Embargo("P10M"), false},
 		{Embargo("?10M"), false},
+		{Embargo("-1Y"), false},
 	}
 
 	for _, c := range cases {
@@ -129,3 +174,24 @@ func TestEmbargoAccessBeginsAtWall(t *testing.T) {
 		}
 	}
 }
+
+func TestEmbargoDelay(t *testing.T) {
+	defer func(f func() time.Time) { Now = f }(Now)
+	Now = func() time.Time { return mustParseTime("2006-01-02", "2010-06-01") }
+
+	var cases = []struct {
+		embargo Embargo
+		t       time.Time
+		err     error
+	}{
+		{Embargo("-1Y"), mustParseTime("2006-01-02", "2009-05-01"), nil},
+		{Embargo("-1Y"), mustParseTime("2006-01-02", "2009-07-01"), ErrAfterMovingWall},
+		{Embargo(" -12M "), mustParseTime("2006-01-02", "2009-07-01"), ErrAfterMovingWall},
+		{Embargo("P1Y"), mustParseTime("2006-01-02", "2009-07-01"), ErrAfterMovingWall},
+	}
+	for _, c := range cases {
+		if err := c.embargo.Compatible(c.t); err != c.err {
+			t.Errorf("Compatible(%v, %v): got %v, want %v", c.embargo, c.t, err, c.err)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package licensing
+
+import (
+	"sort"
+	"time"
+)
+
+// Point is a position in a serial, given by year, volume and issue. Zero
+// values are unknown.
+type Point struct {
+	Year   int
+	Volume int
+	Issue  int
+}
+
+// ParsePoint parses date, volume and issue strings as found in records.
+func ParsePoint(date, volume, issue string) (Point, error) {
+	t, _, err := parseWithGranularity(date)
+	if err != nil {
+		return Point{}, err
+	}
+	return Point{Year: t.Year(), Volume: findInt(volume), Issue: findInt(issue)}, nil
+}
+
+// IsZero returns true, if nothing is known about this point.
+func (p Point) IsZero() bool {
+	return p == Point{}
+}
+
+// Compare returns -1, 0 or 1, if p is before, at or after q. Like Entry.Covers,
+// volume and issue are only compared within the same year; unknown values are
+// compatible with anything.
+func (p Point) Compare(q Point) int {
+	cmp := func(a, b int) int {
+		switch {
+		case a == 0 || b == 0 || a == b:
+			return 0
+		case a < b:
+			return -1
+		default:
+			return 1
+		}
+	}
+	if p.Year == 0 || q.Year == 0 {
+		return 0
+	}
+	if c := cmp(p.Year, q.Year); c != 0 {
+		return c
+	}
+	if c := cmp(p.Volume, q.Volume); c != 0 {
+		return c
+	}
+	return cmp(p.Issue, q.Issue)
+}
+
+// Interval is a coverage range with an optional moving wall. A zero Begin or
+// End is open.
+type Interval struct {
+	Begin   Point
+	End     Point
+	Embargo Embargo
+}
+
+// Interval returns the coverage of this entry.
+func (entry *Entry) Interval() Interval {
+	iv := Interval{Embargo: Embargo(entry.Embargo)}
+	if t, _, err := parseWithGranularity(entry.FirstIssueDate); err == nil {
+		iv.Begin = Point{Year: t.Year(), Volume: findInt(entry.FirstVolume), Issue: findInt(entry.FirstIssue)}
+	}
+	if t, _, err := parseWithGranularity(entry.LastIssueDate); err == nil {
+		iv.End = Point{Year: t.Year(), Volume: findInt(entry.LastVolume), Issue: findInt(entry.LastIssue)}
+	}
+	return iv
+}
+
+// Covers returns true, if the interval covers a point today.
+func (iv Interval) Covers(p Point) bool {
+	return iv.CoversAt(p, Now())
+}
+
+// CoversAt returns true, if the interval covers a point, with the moving wall
+// relative to a given date. The embargo is evaluated for the first day of the
+// year of the point, as Entry.Covers does for years.
+func (iv Interval) CoversAt(p Point, relative time.Time) bool {
+	return iv.covers(p, time.Date(p.Year, time.January, 1, 0, 0, 0, 0, time.UTC), relative)
+}
+
+// CoversDate returns true, if the interval covers a publication date today.
+// The moving wall is evaluated for the exact date.
+func (iv Interval) CoversDate(t time.Time) bool {
+	return iv.covers(Point{Year: t.Year()}, t, Now())
+}
+
+// covers checks the bounds for a point and the moving wall for a date.
+func (iv Interval) covers(p Point, t, relative time.Time) bool {
+	if !iv.Begin.IsZero() && p.Compare(iv.Begin) < 0 {
+		return false
+	}
+	if !iv.End.IsZero() && p.Compare(iv.End) > 0 {
+		return false
+	}
+	if p.Year == 0 {
+		return true
+	}
+	return iv.Embargo.CompatibleTo(t, relative) == nil
+}
+
+// adjoins returns true, if an interval ending at p is followed by one
+// starting at q without gap. Years without volume and issue are complete.
+func adjoins(p, q Point) bool {
+	if p.IsZero() || q.IsZero() {
+		return true
+	}
+	if p.Year+1 == q.Year && p.Volume == 0 && p.Issue == 0 && q.Volume == 0 && q.Issue == 0 {
+		return true
+	}
+	return q.Compare(p) <= 0
+}
+
+// later returns true, if p is a later end than q. An unknown volume or issue
+// extends to the end of the year or volume.
+func later(p, q Point) bool {
+	switch {
+	case p.Year != q.Year:
+		return p.Year > q.Year
+	case p.Volume != q.Volume:
+		return q.Volume != 0 && (p.Volume == 0 || p.Volume > q.Volume)
+	default:
+		return q.Issue != 0 && (p.Issue == 0 || p.Issue > q.Issue)
+	}
+}
+
+// Intervals groups coverage ranges, e.g. all entries for an ISSN.
+type Intervals []Interval
+
+// Covers returns true, if any interval covers a point today.
+func (ivs Intervals) Covers(p Point) bool {
+	return ivs.CoversAt(p, Now())
+}
+
+// CoversAt returns true, if any interval covers a point, relative to a date.
+func (ivs Intervals) CoversAt(p Point, relative time.Time) bool {
+	for _, iv := range ivs {
+		if iv.CoversAt(p, relative) {
+			return true
+		}
+	}
+	return false
+}
+
+// CoversDate returns true, if any interval covers a publication date today.
+func (ivs Intervals) CoversDate(t time.Time) bool {
+	for _, iv := range ivs {
+		if iv.CoversDate(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge combines overlapping or adjacent intervals with the same embargo.
+// The result is sorted by embargo and begin.
+func (ivs Intervals) Merge() Intervals {
+	sorted := make(Intervals, len(ivs))
+	copy(sorted, ivs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Embargo != b.Embargo {
+			return a.Embargo < b.Embargo
+		}
+		if a.Begin.Year != b.Begin.Year {
+			return a.Begin.Year < b.Begin.Year
+		}
+		if a.Begin.Volume != b.Begin.Volume {
+			return a.Begin.Volume < b.Begin.Volume
+		}
+		return a.Begin.Issue < b.Begin.Issue
+	})
+	var result Intervals
+	for _, iv := range sorted {
+		n := len(result)
+		if n == 0 || result[n-1].Embargo != iv.Embargo || !adjoins(result[n-1].End, iv.Begin) {
+			result = append(result, iv)
+			continue
+		}
+		last := &result[n-1]
+		if !last.End.IsZero() && (iv.End.IsZero() || later(iv.End, last.End)) {
+			last.End = iv.End
+		}
+	}
+	return result
+}
+
+// Coverage maps ISSN to intervals.
+type Coverage map[string]Intervals
+
+// Covered returns true, if a publication date is covered for an ISSN today.
+func (c Coverage) Covered(issn string, t time.Time) bool {
+	return c[NormalizeSerialNumber(issn)].CoversDate(t)
+}
@@ -0,0 +1,143 @@
+package licensing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIntervalCoversAt(t *testing.T) {
+	relative := mustParseTime("2006-01-02", "2010-06-01")
+	var cases = []struct {
+		about    string
+		interval Interval
+		point    Point
+		result   bool
+	}{
+		{"open interval", Interval{}, Point{Year: 1900}, true},
+		{"unknown point", Interval{Begin: Point{Year: 2000}}, Point{}, true},
+		{"before begin", Interval{Begin: Point{Year: 2000}}, Point{Year: 1999}, false},
+		{"after end", Interval{End: Point{Year: 2000}}, Point{Year: 2001}, false},
+		{"in range", Interval{Begin: Point{Year: 2000}, End: Point{Year: 2005}}, Point{Year: 2003, Volume: 1}, true},
+		{"volume before first volume", Interval{Begin: Point{Year: 2000, Volume: 3}}, Point{Year: 2000, Volume: 2}, false},
+		{"volume only compared in same year", Interval{Begin: Point{Year: 2000, Volume: 3}}, Point{Year: 2001, Volume: 2}, true},
+		{"issue after last issue", Interval{End: Point{Year: 2000, Volume: 3, Issue: 4}}, Point{Year: 2000, Volume: 3, Issue: 5}, false},
+		{"unknown issue", Interval{End: Point{Year: 2000, Volume: 3, Issue: 4}}, Point{Year: 2000, Volume: 3}, true},
+		{"moving wall, access ends", Interval{Embargo: "P1Y"}, Point{Year: 2010}, false},
+		{"moving wall, access ends, ok", Interval{Embargo: "P1Y"}, Point{Year: 2009}, true},
+		{"moving wall, access begins", Interval{Embargo: "R2Y"}, Point{Year: 2007}, false},
+		{"moving wall, access begins, ok", Interval{Embargo: "R2Y"}, Point{Year: 2009}, true},
+	}
+	for _, c := range cases {
+		if result := c.interval.CoversAt(c.point, relative); result != c.result {
+			t.Errorf("%s: CoversAt(%v) got %v, want %v", c.about, c.point, result, c.result)
+		}
+	}
+}
+
+func TestEntryInterval(t *testing.T) {
+	entry := Entry{FirstIssueDate: "1990-05-01", FirstVolume: "1", LastIssueDate: "2000", LastIssue: "no. 12", Embargo: "P1Y"}
+	want := Interval{Begin: Point{Year: 1990, Volume: 1}, End: Point{Year: 2000, Issue: 12}, Embargo: "P1Y"}
+	if got := entry.Interval(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Interval() got %+v, want %+v", got, want)
+	}
+	// Interval and Covers agree on entries without moving wall.
+	for _, date := range []string{"1989", "1990", "1995", "2000", "2001"} {
+		p, err := ParsePoint(date, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := Entry{FirstIssueDate: "1990", LastIssueDate: "2000"}
+		if (e.Covers(date, "", "") == nil) != e.Interval().Covers(p) {
+			t.Errorf("Covers and Interval disagree on %s", date)
+		}
+	}
+}
+
+func TestIntervalsMerge(t *testing.T) {
+	var cases = []struct {
+		about  string
+		input  Intervals
+		result Intervals
+	}{
+		{"empty", nil, nil},
+		{
+			"overlapping",
+			Intervals{{Begin: Point{Year: 2003}, End: Point{Year: 2010}}, {Begin: Point{Year: 2000}, End: Point{Year: 2005}}},
+			Intervals{{Begin: Point{Year: 2000}, End: Point{Year: 2010}}},
+		},
+		{
+			"adjacent years",
+			Intervals{{Begin: Point{Year: 2000}, End: Point{Year: 2004}}, {Begin: Point{Year: 2005}}},
+			Intervals{{Begin: Point{Year: 2000}}},
+		},
+		{
+			"gap",
+			Intervals{{Begin: Point{Year: 2000}, End: Point{Year: 2004}}, {Begin: Point{Year: 2006}}},
+			Intervals{{Begin: Point{Year: 2000}, End: Point{Year: 2004}}, {Begin: Point{Year: 2006}}},
+		},
+		{
+			"gap between volumes",
+			Intervals{{Begin: Point{Year: 2000}, End: Point{Year: 2004, Volume: 4}}, {Begin: Point{Year: 2004, Volume: 6}}},
+			Intervals{{Begin: Point{Year: 2000}, End: Point{Year: 2004, Volume: 4}}, {Begin: Point{Year: 2004, Volume: 6}}},
+		},
+		{
+			"contained",
+			Intervals{{Begin: Point{Year: 2000}, End: Point{Year: 2010}}, {Begin: Point{Year: 2002}, End: Point{Year: 2004}}},
+			Intervals{{Begin: Point{Year: 2000}, End: Point{Year: 2010}}},
+		},
+		{
+			"unknown volume extends to end of year",
+			Intervals{{Begin: Point{Year: 2000}, End: Point{Year: 2004, Volume: 4}}, {Begin: Point{Year: 2002}, End: Point{Year: 2004}}},
+			Intervals{{Begin: Point{Year: 2000}, End: Point{Year: 2004}}},
+		},
+		{
+			"different embargo",
+			Intervals{{Begin: Point{Year: 2000}, Embargo: "P1Y"}, {Begin: Point{Year: 2000}}},
+			Intervals{{Begin: Point{Year: 2000}}, {Begin: Point{Year: 2000}, Embargo: "P1Y"}},
+		},
+	}
+	for _, c := range cases {
+		if result := c.input.Merge(); !reflect.DeepEqual(result, c.result) {
+			t.Errorf("%s: Merge got %+v, want %+v", c.about, result, c.result)
+		}
+	}
+}
+
+func TestIntervalsCovers(t *testing.T) {
+	ivs := Intervals{{Begin: Point{Year: 2000}, End: Point{Year: 2004}}, {Begin: Point{Year: 2006}, Embargo: "P1Y"}}
+	relative := time.Date(2010, 6, 1, 0, 0, 0, 0, time.UTC)
+	for year, want := range map[int]bool{1999: false, 2002: true, 2005: false, 2008: true, 2010: false} {
+		if got := ivs.CoversAt(Point{Year: year}, relative); got != want {
+			t.Errorf("CoversAt(%d) got %v, want %v", year, got, want)
+		}
+	}
+}
+
+func TestCoverageCovered(t *testing.T) {
+	defer func(f func() time.Time) { Now = f }(Now)
+	Now = func() time.Time { return time.Date(2010, 6, 1, 0, 0, 0, 0, time.UTC) }
+
+	c := Coverage{
+		"1234-5678": {{Begin: Point{Year: 2000}, Embargo: "-12M"}},
+		"2345-678X": {{Begin: Point{Year: 2000}, End: Point{Year: 2004}}},
+	}
+	var cases = []struct {
+		issn   string
+		t      time.Time
+		result bool
+	}{
+		{"1234-5678", time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC), false},
+		{"1234-5678", time.Date(2009, 5, 1, 0, 0, 0, 0, time.UTC), true},
+		{"1234-5678", time.Date(2009, 7, 1, 0, 0, 0, 0, time.UTC), false},
+		{"12345678", time.Date(2009, 5, 1, 0, 0, 0, 0, time.UTC), true},
+		{"2345-678x", time.Date(2004, 12, 31, 0, 0, 0, 0, time.UTC), true},
+		{"2345-678X", time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{"0000-0000", time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, tc := range cases {
+		if result := c.Covered(tc.issn, tc.t); result != tc.result {
+			t.Errorf("Covered(%s, %v) got %v, want %v", tc.issn, tc.t, result, tc.result)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package kbart
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sethgrid/pester"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/miku/span"
+)
+
+// cacheInfo is stored next to a cached file for conditional requests.
+type cacheInfo struct {
+	Link         string `json:"link"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last-modified,omitempty"`
+}
+
+// CachedFetcher downloads holding files per ISIL, e.g. the EZB national
+// license exports, into a cache directory. Files are only downloaded again,
+// if the server reports a change via ETag or Last-Modified. If the server
+// cannot be reached, a cached file is used.
+type CachedFetcher struct {
+	// Link is a location with a single %s, which is replaced by the ISIL.
+	Link string
+	// Dir is the cache directory, one file per ISIL.
+	Dir    string
+	Client *http.Client
+	// Strict rejects holding files with malformed rows, see ReadFromStrict.
+	Strict bool
+}
+
+// filename returns the cache location for an ISIL.
+func (f *CachedFetcher) filename(isil string) string {
+	return filepath.Join(f.Dir, strings.Replace(isil, string(filepath.Separator), "_", -1)+".tsv")
+}
+
+// readInfo returns information about a cached file, if there is any.
+func (f *CachedFetcher) readInfo(filename, link string) (info cacheInfo, ok bool) {
+	if _, err := os.Stat(filename); err != nil {
+		return info, false
+	}
+	b, err := ioutil.ReadFile(filename + ".json")
+	if err != nil {
+		return info, false
+	}
+	if err := json.Unmarshal(b, &info); err != nil || info.Link != link {
+		return info, false
+	}
+	return info, true
+}
+
+// Fetch updates the cached holding file for an ISIL and returns its filename.
+func (f *CachedFetcher) Fetch(isil string) (string, error) {
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return "", err
+	}
+	link := fmt.Sprintf(f.Link, isil)
+	filename := f.filename(isil)
+	info, cached := f.readInfo(filename, link)
+
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("span/%s", span.AppVersion))
+	if cached && info.ETag != "" {
+		req.Header.Set("If-None-Match", info.ETag)
+	}
+	if cached && info.LastModified != "" {
+		req.Header.Set("If-Modified-Since", info.LastModified)
+	}
+	var resp *http.Response
+	if f.Client != nil {
+		resp, err = f.Client.Do(req)
+	} else {
+		resp, err = pester.Do(req)
+	}
+	if err != nil {
+		if cached {
+			log.Printf("[holdings] %s: %v, using cached %s", isil, err, filename)
+			return filename, nil
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached:
+		log.Printf("[holdings] %s: not modified: %s", isil, filename)
+		return filename, nil
+	case resp.StatusCode >= 500 && cached:
+		log.Printf("[holdings] %s: %s, using cached %s", isil, resp.Status, filename)
+		return filename, nil
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("%s: %s", link, resp.Status)
+	}
+	tmp, err := ioutil.TempFile(f.Dir, ".span-holdings-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(cacheInfo{
+		Link:         link,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	})
+	if err != nil {
+		return "", err
+	}
+	log.Printf("[holdings] %s: fetched %s", isil, filename)
+	return filename, ioutil.WriteFile(filename+".json", b, 0644)
+}
+
+// Holdings fetches and parses the holding file for an ISIL.
+func (f *CachedFetcher) Holdings(isil string) (Holdings, error) {
+	filename, err := f.Fetch(isil)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var h Holdings
+	read := h.ReadFrom
+	if f.Strict {
+		read = h.ReadFromStrict
+	}
+	if _, err := read(file); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return h, nil
+}
+
+// HoldingsMap fetches and parses holding files for a number of ISIL.
+func (f *CachedFetcher) HoldingsMap(isils ...string) (map[string]Holdings, error) {
+	result := make(map[string]Holdings)
+	for _, isil := range isils {
+		h, err := f.Holdings(isil)
+		if err != nil {
+			return nil, err
+		}
+		result[isil] = h
+	}
+	return result, nil
+}
@@ -0,0 +1,60 @@
+package kbart
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestCachedFetcher(t *testing.T) {
+	kbart := "publication_title\tprint_identifier\tonline_identifier\n" +
+		"J\t1234-5678\t\n"
+	var downloads int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("isil") != "DE-15" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(kbart))
+	}))
+
+	dir, err := ioutil.TempDir("", "span-kbart-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := CachedFetcher{Link: ts.URL + "/?isil=%s", Dir: dir, Client: http.DefaultClient}
+	for i := 0; i < 2; i++ {
+		h, err := f.Holdings("DE-15")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(h) != 1 || h[0].PrintIdentifier != "1234-5678" {
+			t.Errorf("got %v, want a single entry", h)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("got %d downloads, want 1", downloads)
+	}
+	if _, err := f.Fetch("DE-14"); err == nil {
+		t.Errorf("expected error for unknown ISIL")
+	}
+	// Cached file is used, if the server is gone.
+	ts.Close()
+	m, err := f.HoldingsMap("DE-15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m["DE-15"]) != 1 {
+		t.Errorf("got %v, want cached holdings", m)
+	}
+}
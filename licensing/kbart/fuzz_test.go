@@ -0,0 +1,29 @@
+//go:build go1.18
+// +build go1.18
+
+package kbart
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzHoldings(f *testing.F) {
+	header := "publication_title\tprint_identifier\tonline_identifier\tdate_first_issue_online\t" +
+		"num_first_vol_online\tnum_first_issue_online\tdate_last_issue_online\tnum_last_vol_online\t" +
+		"num_last_issue_online\ttitle_url\tfirst_author\ttitle_id\tembargo_info\tcoverage_depth\t" +
+		"coverage_notes\tpublisher_name\n"
+	f.Add([]byte(header + "J\t1234-5678\t\t2000\t1\t1\t2010\t10\t2\thttp://x\t\t\tP1Y\tfulltext\t\tP\n"))
+	f.Add([]byte(header + "J\t\t\t2000-01-01\t\t\t\t\t\t\t\t\tR5M\t\t\t\n"))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var h Holdings
+		if _, err := h.ReadFrom(bytes.NewReader(b)); err != nil {
+			return
+		}
+		h.SerialNumberMap()
+		h.WisoDatabaseMap()
+		for _, e := range h {
+			e.Covers("2005-01-01", "1", "1")
+		}
+	})
+}
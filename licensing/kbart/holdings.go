@@ -25,19 +25,52 @@ import (
 // methods.
 type Holdings []licensing.Entry
 
+// EntryReader reads entries one by one from a KBART file, so large files
+// need not be held in memory.
+type EntryReader struct {
+	dec *tsv.Decoder
+}
+
+// NewEntryReader reads tab separated content with a single header row. In
+// strict mode, rows with a different number of fields than the header are an
+// error, otherwise they are read as far as possible.
+func NewEntryReader(r io.Reader, strict bool) *EntryReader {
+	dec := tsv.NewDecoder(r)
+	dec.Strict = strict
+	return &EntryReader{dec: dec}
+}
+
+// Read returns the next entry or io.EOF at the end of the input. Malformed
+// rows are reported as *tsv.ParseError with line number and offset.
+func (r *EntryReader) Read() (licensing.Entry, error) {
+	var entry licensing.Entry
+	err := r.dec.Decode(&entry)
+	return entry, err
+}
+
 // ReadFrom create holdings struct from a reader. Expects tab separated content with
 // a single header row.
 func (h *Holdings) ReadFrom(r io.Reader) (int64, error) {
+	return h.readFrom(r, false)
+}
+
+// ReadFromStrict is like ReadFrom, but fails on rows with a different number
+// of fields than the header, so broken exports do not silently shrink
+// coverage.
+func (h *Holdings) ReadFromStrict(r io.Reader) (int64, error) {
+	return h.readFrom(r, true)
+}
+
+func (h *Holdings) readFrom(r io.Reader, strict bool) (int64, error) {
 	var wc span.WriteCounter
-	dec := tsv.NewDecoder(io.TeeReader(r, &wc))
+	er := NewEntryReader(io.TeeReader(r, &wc), strict)
 	for {
-		var entry licensing.Entry
-		err := dec.Decode(&entry)
+		entry, err := er.Read()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return 0, err
+			return int64(wc.Count()), err
 		}
 		*h = append(*h, entry)
 	}
@@ -47,23 +80,39 @@ func (h *Holdings) ReadFrom(r io.Reader) (int64, error) {
 // SerialNumberMap creates a map from ISSN to associated licensing entries.
 // This is here for performance mostly, so we can access relevant licensing
 // entry by ISSN.  XXX: Do not replicate entries, just index into them.
+//
+// An ISSN often appears in more than one entry, e.g. with national license
+// and consortial coverage. All distinct entries are kept, in file order.
 func (h *Holdings) SerialNumberMap() map[string][]licensing.Entry {
-	cache := make(map[string]map[licensing.Entry]bool)
+	seen := make(map[string]map[licensing.Entry]bool)
+	result := make(map[string][]licensing.Entry)
 	for _, e := range *h {
 		for _, issn := range e.ISSNList() {
-			if cache[issn] == nil {
-				cache[issn] = make(map[licensing.Entry]bool)
+			if seen[issn] == nil {
+				seen[issn] = make(map[licensing.Entry]bool)
 			}
-			cache[issn][e] = true
+			if seen[issn][e] {
+				continue
+			}
+			seen[issn][e] = true
+			result[issn] = append(result[issn], e)
 		}
 	}
-	// Make unique.
-	result := make(map[string][]licensing.Entry)
-	for issn, entrymap := range cache {
-		for k := range entrymap {
-			result[issn] = append(result[issn], k)
+	return result
+}
+
+// IntervalMap maps ISSN to the merged coverage of all associated entries.
+func (h *Holdings) IntervalMap() licensing.Coverage {
+	result := make(licensing.Coverage)
+	for _, e := range *h {
+		iv := e.Interval()
+		for _, issn := range e.ISSNList() {
+			result[issn] = append(result[issn], iv)
 		}
 	}
+	for issn, ivs := range result {
+		result[issn] = ivs.Merge()
+	}
 	return result
 }
 
@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"errors"
 	"os"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/miku/span/container"
+	"github.com/miku/span/encoding/tsv"
 	"github.com/miku/span/licensing"
 )
 
@@ -136,3 +138,53 @@ func TestWisoDatabaseMap(t *testing.T) {
 		t.Errorf("WisoDatabaseMap: got %v, want %v", len(m), want)
 	}
 }
+
+func TestIntervalMap(t *testing.T) {
+	h := Holdings{
+		{PrintIdentifier: "1234-5678", FirstIssueDate: "2000", LastIssueDate: "2004"},
+		{OnlineIdentifier: "1234-5678", FirstIssueDate: "2003"},
+		{PrintIdentifier: "2345-6789", FirstIssueDate: "1990", LastIssueDate: "1995"},
+	}
+	m := h.IntervalMap()
+	if len(m) != 2 {
+		t.Fatalf("got %d ISSN, want 2", len(m))
+	}
+	want := licensing.Intervals{{Begin: licensing.Point{Year: 2000}}}
+	if !reflect.DeepEqual(m["1234-5678"], want) {
+		t.Errorf("got %+v, want %+v", m["1234-5678"], want)
+	}
+	if m["2345-6789"].Covers(licensing.Point{Year: 1996}) {
+		t.Errorf("1996 should not be covered")
+	}
+}
+
+func TestSerialNumberMapKeepsAllEntries(t *testing.T) {
+	h := Holdings{
+		{PrintIdentifier: "1234-5678", CoverageNotes: "national license"},
+		{OnlineIdentifier: "1234-5678", CoverageNotes: "consortium"},
+		{PrintIdentifier: "1234-5678", CoverageNotes: "national license"},
+	}
+	m := h.SerialNumberMap()
+	var notes []string
+	for _, e := range m["1234-5678"] {
+		notes = append(notes, e.CoverageNotes)
+	}
+	if want := []string{"national license", "consortium"}; !reflect.DeepEqual(notes, want) {
+		t.Errorf("got %v, want %v", notes, want)
+	}
+}
+
+func TestReadFromStrict(t *testing.T) {
+	s := "publication_title\tprint_identifier\nA\t1234-5678\nB\n"
+	var lenient, strict Holdings
+	if _, err := lenient.ReadFrom(strings.NewReader(s)); err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	if len(lenient) != 2 {
+		t.Errorf("ReadFrom: got %d entries, want 2", len(lenient))
+	}
+	_, err := strict.ReadFromStrict(strings.NewReader(s))
+	if perr, ok := err.(*tsv.ParseError); !ok || perr.Line != 3 {
+		t.Errorf("ReadFromStrict: got %v, want parse error in line 3", err)
+	}
+}
@@ -0,0 +1,262 @@
+package kbart
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/licensing"
+)
+
+// TitleListHeader are the standard KBART columns written by TitleList.
+var TitleListHeader = []string{
+	"publication_title",
+	"print_identifier",
+	"online_identifier",
+	"date_first_issue_online",
+	"num_first_vol_online",
+	"num_first_issue_online",
+	"date_last_issue_online",
+	"num_last_vol_online",
+	"num_last_issue_online",
+	"title_url",
+	"first_author",
+	"title_id",
+	"embargo_info",
+	"coverage_depth",
+	"coverage_notes",
+	"publisher_name",
+}
+
+// issueMark is a point in the coverage of a journal.
+type issueMark struct {
+	date   time.Time
+	volume string
+	issue  string
+}
+
+// before returns true, if this mark comes before another, comparing date,
+// volume and issue in that order.
+func (m issueMark) before(o issueMark) bool {
+	if !m.date.Equal(o.date) {
+		return m.date.Before(o.date)
+	}
+	if u, v := leadingInt(m.volume), leadingInt(o.volume); u != v {
+		return u < v
+	}
+	return leadingInt(m.issue) < leadingInt(o.issue)
+}
+
+// titleCoverage collects the observed coverage for a single journal.
+type titleCoverage struct {
+	title     string
+	print     string
+	online    string
+	publisher string
+	first     issueMark
+	last      issueMark
+}
+
+// TitleList aggregates a stream of intermediate schema records into a list
+// of KBART entries, one per journal, with first and last coverage derived
+// from dates, volumes and issues actually seen. Records are grouped, if they
+// share any ISSN, EISSN or journal title, even transitively.
+type TitleList struct {
+	parent map[string]string         // union-find over identifiers
+	titles map[string]*titleCoverage // coverage by root identifier
+}
+
+// NewTitleList creates a new, empty title list.
+func NewTitleList() *TitleList {
+	return &TitleList{
+		parent: make(map[string]string),
+		titles: make(map[string]*titleCoverage),
+	}
+}
+
+// identifiers returns the identifiers of a record, prefixed by kind, so an
+// ISSN and a title never collide.
+func identifiers(is finc.IntermediateSchema) (ids []string) {
+	for _, v := range append(append([]string{}, is.ISSN...), is.EISSN...) {
+		if v = strings.TrimSpace(v); v != "" {
+			ids = append(ids, "issn:"+v)
+		}
+	}
+	if v := strings.TrimSpace(is.JournalTitle); v != "" {
+		ids = append(ids, "title:"+v)
+	}
+	return ids
+}
+
+// find returns the root identifier of a group, compressing the path on the way.
+func (t *TitleList) find(id string) string {
+	p, ok := t.parent[id]
+	if !ok {
+		t.parent[id] = id
+		return id
+	}
+	if p == id {
+		return id
+	}
+	root := t.find(p)
+	t.parent[id] = root
+	return root
+}
+
+// union joins the groups of two identifiers and their coverage, returning
+// the new root.
+func (t *TitleList) union(a, b string) string {
+	ra, rb := t.find(a), t.find(b)
+	if ra == rb {
+		return ra
+	}
+	t.parent[rb] = ra
+	if c, ok := t.titles[rb]; ok {
+		delete(t.titles, rb)
+		if d, ok := t.titles[ra]; ok {
+			d.merge(c)
+		} else {
+			t.titles[ra] = c
+		}
+	}
+	return ra
+}
+
+// merge adds the coverage of another group.
+func (c *titleCoverage) merge(o *titleCoverage) {
+	if c.title == "" {
+		c.title = o.title
+	}
+	if c.print == "" {
+		c.print = o.print
+	}
+	if c.online == "" {
+		c.online = o.online
+	}
+	if c.publisher == "" {
+		c.publisher = o.publisher
+	}
+	if o.first.before(c.first) {
+		c.first = o.first
+	}
+	if c.last.before(o.last) {
+		c.last = o.last
+	}
+}
+
+// Add records the coverage of a single record. Records without a date or
+// without journal information are ignored.
+func (t *TitleList) Add(is finc.IntermediateSchema) {
+	ids := identifiers(is)
+	if len(ids) == 0 || is.Date.IsZero() {
+		return
+	}
+	key := t.find(ids[0])
+	for _, id := range ids[1:] {
+		key = t.union(key, id)
+	}
+	mark := issueMark{date: is.Date, volume: is.Volume, issue: is.Issue}
+	c, ok := t.titles[key]
+	if !ok {
+		c = &titleCoverage{first: mark, last: mark}
+		t.titles[key] = c
+	}
+	if c.title == "" {
+		c.title = strings.TrimSpace(is.JournalTitle)
+	}
+	if c.print == "" && len(is.ISSN) > 0 {
+		c.print = is.ISSN[0]
+	}
+	if c.online == "" && len(is.EISSN) > 0 {
+		c.online = is.EISSN[0]
+	}
+	if c.publisher == "" && len(is.Publishers) > 0 {
+		c.publisher = is.Publishers[0]
+	}
+	if mark.before(c.first) {
+		c.first = mark
+	}
+	if c.last.before(mark) {
+		c.last = mark
+	}
+}
+
+// Entries returns the aggregated coverage as licensing entries, sorted by
+// publication title.
+func (t *TitleList) Entries() (entries []licensing.Entry) {
+	for _, c := range t.titles {
+		entries = append(entries, licensing.Entry{
+			PublicationTitle: c.title,
+			PrintIdentifier:  c.print,
+			OnlineIdentifier: c.online,
+			FirstIssueDate:   c.first.date.Format("2006-01-02"),
+			FirstVolume:      c.first.volume,
+			FirstIssue:       c.first.issue,
+			LastIssueDate:    c.last.date.Format("2006-01-02"),
+			LastVolume:       c.last.volume,
+			LastIssue:        c.last.issue,
+			CoverageDepth:    "fulltext",
+			PublisherName:    c.publisher,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].PublicationTitle != entries[j].PublicationTitle {
+			return entries[i].PublicationTitle < entries[j].PublicationTitle
+		}
+		return entries[i].PrintIdentifier < entries[j].PrintIdentifier
+	})
+	return entries
+}
+
+// WriteTo writes the title list as tab separated KBART, including a header row.
+func (t *TitleList) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	write := func(fields ...string) error {
+		cleaned := make([]string, len(fields))
+		for i, f := range fields {
+			cleaned[i] = strings.Replace(f, "\t", " ", -1)
+		}
+		n, err := io.WriteString(w, strings.Join(cleaned, "\t")+"\n")
+		total += int64(n)
+		return err
+	}
+	if err := write(TitleListHeader...); err != nil {
+		return total, err
+	}
+	for _, e := range t.Entries() {
+		if err := write(e.PublicationTitle, e.PrintIdentifier, e.OnlineIdentifier,
+			e.FirstIssueDate, e.FirstVolume, e.FirstIssue,
+			e.LastIssueDate, e.LastVolume, e.LastIssue,
+			e.TitleURL, e.FirstAuthor, e.TitleID, e.Embargo,
+			e.CoverageDepth, e.CoverageNotes, e.PublisherName); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// String returns the number of titles, for logging.
+func (t *TitleList) String() string {
+	return fmt.Sprintf("%d titles", len(t.titles))
+}
+
+// leadingInt returns the first number found in a volume or issue string, or
+// zero, if there is none.
+func leadingInt(s string) int {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			continue
+		}
+		if b.Len() > 0 {
+			break
+		}
+	}
+	i, _ := strconv.Atoi(b.String())
+	return i
+}
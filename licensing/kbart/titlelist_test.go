@@ -0,0 +1,102 @@
+package kbart
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/miku/span/formats/finc"
+)
+
+func TestTitleList(t *testing.T) {
+	date := func(s string) time.Time {
+		v, _ := time.Parse("2006-01-02", s)
+		return v
+	}
+	records := []finc.IntermediateSchema{
+		{JournalTitle: "J", ISSN: []string{"1234-5678"}, Date: date("2001-01-01"), Volume: "2", Issue: "1"},
+		{JournalTitle: "J", ISSN: []string{"1234-5678"}, Date: date("2000-01-01"), Volume: "1", Issue: "3"},
+		{JournalTitle: "J", ISSN: []string{"1234-5678"}, Date: date("2000-01-01"), Volume: "1", Issue: "2"},
+		{JournalTitle: "J", ISSN: []string{"1234-5678"}, Date: date("2001-01-01"), Volume: "2", Issue: "10"},
+		{JournalTitle: "A", Date: date("1999-01-01"), Publishers: []string{"P"}},
+		{JournalTitle: "No date"},
+	}
+	tl := NewTitleList()
+	for _, r := range records {
+		tl.Add(r)
+	}
+	entries := tl.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Entries: got %d, want 2", len(entries))
+	}
+	if entries[0].PublicationTitle != "A" || entries[0].PublisherName != "P" {
+		t.Errorf("Entries: got %v, want title A, publisher P", entries[0])
+	}
+	e := entries[1]
+	if e.FirstIssueDate != "2000-01-01" || e.FirstVolume != "1" || e.FirstIssue != "2" {
+		t.Errorf("first: got %s %s %s, want 2000-01-01 1 2", e.FirstIssueDate, e.FirstVolume, e.FirstIssue)
+	}
+	if e.LastIssueDate != "2001-01-01" || e.LastVolume != "2" || e.LastIssue != "10" {
+		t.Errorf("last: got %s %s %s, want 2001-01-01 2 10", e.LastIssueDate, e.LastVolume, e.LastIssue)
+	}
+
+	// Output must be readable as holdings again.
+	var buf bytes.Buffer
+	if _, err := tl.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	h := new(Holdings)
+	if _, err := h.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(*h) != 2 {
+		t.Errorf("ReadFrom: got %d entries, want 2", len(*h))
+	}
+	if _, ok := h.SerialNumberMap()["1234-5678"]; !ok {
+		t.Errorf("SerialNumberMap: missing 1234-5678")
+	}
+}
+
+func TestTitleListMergesSharedIdentifiers(t *testing.T) {
+	date := func(s string) time.Time {
+		v, _ := time.Parse("2006-01-02", s)
+		return v
+	}
+	records := []finc.IntermediateSchema{
+		{JournalTitle: "J", ISSN: []string{"1111-1111"}, Date: date("2001-01-01")},
+		{JournalTitle: "K", ISSN: []string{"2222-2222"}, Date: date("1999-01-01")},
+		{EISSN: []string{"3333-3333"}, Date: date("2005-01-01")},
+		// Links the three groups above.
+		{JournalTitle: "K", ISSN: []string{"1111-1111"}, EISSN: []string{"3333-3333"}, Date: date("2003-01-01")},
+		{JournalTitle: "Other", Date: date("2000-01-01")},
+	}
+	tl := NewTitleList()
+	for _, r := range records {
+		tl.Add(r)
+	}
+	entries := tl.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Entries: got %d, want 2: %v", len(entries), entries)
+	}
+	e := entries[0]
+	if e.FirstIssueDate != "1999-01-01" || e.LastIssueDate != "2005-01-01" {
+		t.Errorf("coverage: got %s to %s, want 1999-01-01 to 2005-01-01", e.FirstIssueDate, e.LastIssueDate)
+	}
+	if e.OnlineIdentifier != "3333-3333" {
+		t.Errorf("online: got %q, want 3333-3333", e.OnlineIdentifier)
+	}
+}
+
+func TestTitleListWriteToKeepsHeader(t *testing.T) {
+	tl := NewTitleList()
+	tl.Add(finc.IntermediateSchema{JournalTitle: "J", Date: time.Now()})
+	header := append([]string{}, TitleListHeader...)
+	if _, err := tl.WriteTo(&bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range header {
+		if TitleListHeader[i] != header[i] {
+			t.Errorf("TitleListHeader changed: got %q, want %q", TitleListHeader[i], header[i])
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Package linesort sorts lines by a key, e.g. newline delimited JSON records
+// by id, so outputs of parallel conversions can be compared byte by byte.
+// Inputs larger than memory are sorted in runs, which are written to
+// temporary files and merged.
+package linesort
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// DefaultMaxBytes is the default size of a run held in memory.
+const DefaultMaxBytes = 256 << 20
+
+// KeyFunc extracts the sort key from a line.
+type KeyFunc func(line []byte) ([]byte, error)
+
+// Sorter sorts lines by key, lines with equal keys by their content, so the
+// result does not depend on input order. Empty lines are dropped, a missing
+// final newline is added.
+type Sorter struct {
+	Key KeyFunc
+	// MaxBytes limits the size of lines sorted in memory at once, defaults to
+	// DefaultMaxBytes.
+	MaxBytes int64
+	// TempDir for runs, defaults to the system temporary directory.
+	TempDir string
+}
+
+// entry is a line with its key.
+type entry struct {
+	key  []byte
+	line []byte
+}
+
+func less(a, b entry) bool {
+	if c := bytes.Compare(a.key, b.key); c != 0 {
+		return c < 0
+	}
+	return bytes.Compare(a.line, b.line) < 0
+}
+
+// Sort reads lines from r and writes them sorted to w.
+func (s *Sorter) Sort(r io.Reader, w io.Writer) error {
+	maxBytes := s.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	var (
+		entries []entry
+		size    int64
+		runs    []string
+	)
+	defer func() {
+		for _, name := range runs {
+			os.Remove(name)
+		}
+	}()
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(bytes.TrimSpace(b)) > 0 {
+			if b[len(b)-1] != '\n' {
+				b = append(b, '\n')
+			}
+			key, kerr := s.Key(b)
+			if kerr != nil {
+				return kerr
+			}
+			entries = append(entries, entry{key: key, line: b})
+			size += int64(len(b) + len(key))
+		}
+		if err == io.EOF {
+			break
+		}
+		if size >= maxBytes {
+			name, werr := s.writeRun(entries)
+			if werr != nil {
+				return werr
+			}
+			runs = append(runs, name)
+			entries, size = nil, 0
+		}
+	}
+	bw := bufio.NewWriter(w)
+	if len(runs) == 0 {
+		sortEntries(entries)
+		for _, e := range entries {
+			if _, err := bw.Write(e.line); err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	}
+	if len(entries) > 0 {
+		name, err := s.writeRun(entries)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, name)
+	}
+	if err := s.merge(runs, bw); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func sortEntries(entries []entry) {
+	sort.Slice(entries, func(i, j int) bool { return less(entries[i], entries[j]) })
+}
+
+// writeRun sorts entries and writes them to a temporary file.
+func (s *Sorter) writeRun(entries []entry) (string, error) {
+	sortEntries(entries)
+	f, err := ioutil.TempFile(s.TempDir, "span-linesort-")
+	if err != nil {
+		return "", err
+	}
+	bw := bufio.NewWriter(f)
+	for _, e := range entries {
+		if _, err := bw.Write(e.line); err != nil {
+			f.Close()
+			return f.Name(), err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return f.Name(), err
+	}
+	return f.Name(), f.Close()
+}
+
+// run is a sorted file being merged.
+type run struct {
+	br   *bufio.Reader
+	head entry
+}
+
+// runHeap orders runs by their current line.
+type runHeap []*run
+
+func (h runHeap) Len() int            { return len(h) }
+func (h runHeap) Less(i, j int) bool  { return less(h[i].head, h[j].head) }
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*run)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// next reads the next line of a run, returns false at the end.
+func (s *Sorter) next(r *run) (bool, error) {
+	b, err := r.br.ReadBytes('\n')
+	if err == io.EOF && len(b) == 0 {
+		return false, nil
+	}
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	key, err := s.Key(b)
+	if err != nil {
+		return false, err
+	}
+	r.head = entry{key: key, line: b}
+	return true, nil
+}
+
+// merge merges sorted runs into w.
+func (s *Sorter) merge(names []string, w io.Writer) error {
+	var h runHeap
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r := &run{br: bufio.NewReader(f)}
+		ok, err := s.next(r)
+		if err != nil {
+			return err
+		}
+		if ok {
+			h = append(h, r)
+		}
+	}
+	heap.Init(&h)
+	for h.Len() > 0 {
+		r := h[0]
+		if _, err := w.Write(r.head.line); err != nil {
+			return err
+		}
+		ok, err := s.next(r)
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return nil
+}
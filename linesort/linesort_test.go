@@ -0,0 +1,61 @@
+package linesort
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// firstField uses the text before the first tab as key.
+func firstField(line []byte) ([]byte, error) {
+	if i := bytes.IndexByte(line, '\t'); i >= 0 {
+		return line[:i], nil
+	}
+	return line, nil
+}
+
+func TestSort(t *testing.T) {
+	var lines []string
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		lines = append(lines, fmt.Sprintf("%04d\t%d\n", rng.Intn(500), rng.Intn(3)))
+	}
+	want := append([]string(nil), lines...)
+	sort.Strings(want)
+
+	dir, err := ioutil.TempDir("", "linesort-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var cases = []struct {
+		about    string
+		input    string
+		maxBytes int64
+		want     string
+	}{
+		{"empty", "", 0, ""},
+		{"no final newline", "b\n\na", 0, "a\nb\n"},
+		{"in memory", strings.Join(lines, ""), 0, strings.Join(want, "")},
+		{"runs", strings.Join(lines, ""), 100, strings.Join(want, "")},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		s := Sorter{Key: firstField, MaxBytes: c.maxBytes, TempDir: dir}
+		if err := s.Sort(strings.NewReader(c.input), &buf); err != nil {
+			t.Fatalf("%s: %v", c.about, err)
+		}
+		if buf.String() != c.want {
+			t.Errorf("%s: got %q, want %q", c.about, buf.String(), c.want)
+		}
+	}
+	if files, err := ioutil.ReadDir(dir); err != nil || len(files) > 0 {
+		t.Errorf("got %d temporary files left, %v", len(files), err)
+	}
+}
@@ -0,0 +1,62 @@
+// Package logging configures the logger shared by all span packages and
+// commands, which is logrus, imported as log. It adds level and output format
+// flags and a logger for messages about a single record.
+package logging
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/miku/span/formats/finc"
+)
+
+// Options are log level and output format.
+type Options struct {
+	Level  string
+	Format string
+}
+
+// RegisterFlags adds -log-level and -log-format to a flag set.
+func RegisterFlags(fs *flag.FlagSet) *Options {
+	var opts Options
+	fs.StringVar(&opts.Level, "log-level", "info", "log level: debug, info, warn, error")
+	fs.StringVar(&opts.Format, "log-format", "text", "log format: text or json")
+	return &opts
+}
+
+// Setup configures the standard logger, which writes to stderr.
+func (opts *Options) Setup() error {
+	level, err := log.ParseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+	log.SetLevel(level)
+	log.SetOutput(os.Stderr)
+	switch opts.Format {
+	case "", "text":
+		log.SetFormatter(&log.TextFormatter{})
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("unknown log format: %s", opts.Format)
+	}
+	return nil
+}
+
+// Record returns a logger with source id and record id of a record as fields.
+func Record(is *finc.IntermediateSchema) *log.Entry {
+	fields := log.Fields{}
+	if is == nil {
+		return log.WithFields(fields)
+	}
+	if is.SourceID != "" {
+		fields["sid"] = is.SourceID
+	}
+	if is.RecordID != "" {
+		fields["rid"] = is.RecordID
+	}
+	return log.WithFields(fields)
+}
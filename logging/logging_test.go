@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/miku/span/formats/finc"
+)
+
+func TestSetup(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts := RegisterFlags(fs)
+	if err := fs.Parse([]string{"-log-level", "warn", "-log-format", "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := opts.Setup(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		log.SetLevel(log.InfoLevel)
+		log.SetFormatter(&log.TextFormatter{})
+	}()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	Record(&finc.IntermediateSchema{SourceID: "49", RecordID: "10.1/x"}).Info("hidden")
+	Record(&finc.IntermediateSchema{SourceID: "49", RecordID: "10.1/x"}).Warn("shown")
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a single JSON entry, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "shown" || entry["sid"] != "49" || entry["rid"] != "10.1/x" {
+		t.Errorf("got %v", entry)
+	}
+	for _, o := range []Options{{Level: "loud"}, {Level: "info", Format: "xml"}} {
+		if err := o.Setup(); err == nil {
+			t.Errorf("%+v: expected error", o)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Package manifest verifies publisher deliveries against MD5 or SHA256
+// checksum manifests shipped along with them, e.g. MD5SUMS, delivery.sha256 or
+// per file sidecars like file.zip.md5. Both GNU (hash  file) and BSD style
+// (SHA256 (file) = hash) lines are understood.
+package manifest
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Verification status of a file.
+const (
+	StatusOK       = "ok"
+	StatusMismatch = "mismatch"
+	StatusMissing  = "missing"  // Listed in a manifest, but not found.
+	StatusUnlisted = "unlisted" // Not listed in any manifest.
+)
+
+// Entry is a single line of a manifest.
+type Entry struct {
+	Filename  string
+	Algorithm string
+	Checksum  string
+}
+
+// Result of verifying a single file.
+type Result struct {
+	Filename  string `json:"filename"`
+	Manifest  string `json:"manifest,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Expected  string `json:"expected,omitempty"`
+	Actual    string `json:"actual,omitempty"`
+	Status    string `json:"status"`
+}
+
+// Failed returns true, if the file is corrupted or missing.
+func (r Result) Failed() bool {
+	return r.Status == StatusMismatch || r.Status == StatusMissing
+}
+
+var (
+	bsdLine   = regexp.MustCompile(`^(MD5|SHA256) ?\((.+)\) ?= ?([0-9a-fA-F]+)$`)
+	gnuLine   = regexp.MustCompile(`^([0-9a-fA-F]{32}|[0-9a-fA-F]{64})(?:\s+\*?(.+))?$`)
+	manifests = regexp.MustCompile(`(?i)(md5|sha256|checksum)`)
+)
+
+// algorithm guesses the algorithm from the length of a hex checksum.
+func algorithm(checksum string) string {
+	switch len(checksum) {
+	case 32:
+		return "md5"
+	case 64:
+		return "sha256"
+	}
+	return ""
+}
+
+// Parse reads a manifest. A line with only a checksum, as found in sidecar
+// files, results in an entry without filename.
+func Parse(r io.Reader) (entries []Entry, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m := bsdLine.FindStringSubmatch(line); m != nil {
+			entries = append(entries, Entry{
+				Filename:  m[2],
+				Algorithm: strings.ToLower(m[1]),
+				Checksum:  strings.ToLower(m[3]),
+			})
+			continue
+		}
+		if m := gnuLine.FindStringSubmatch(line); m != nil {
+			entries = append(entries, Entry{
+				Filename:  strings.TrimPrefix(m[2], "./"),
+				Algorithm: algorithm(m[1]),
+				Checksum:  strings.ToLower(m[1]),
+			})
+			continue
+		}
+		return nil, fmt.Errorf("manifest: cannot parse line: %s", line)
+	}
+	return entries, scanner.Err()
+}
+
+// IsManifest returns true, if the filename looks like a checksum manifest.
+func IsManifest(filename string) bool {
+	return manifests.MatchString(filepath.Base(filename))
+}
+
+// Checksum computes the hex encoded checksum of a file.
+func Checksum(filename, algorithm string) (string, error) {
+	var h hash.Hash
+	switch algorithm {
+	case "md5":
+		h = md5.New()
+	case "sha256":
+		h = sha256.New()
+	default:
+		return "", fmt.Errorf("manifest: unsupported algorithm: %s", algorithm)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readManifest parses a manifest file. Entries without filename belong to
+// the sidecar's file, e.g. a.zip for a.zip.md5.
+func readManifest(filename string) ([]Entry, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	base := filepath.Base(filename)
+	for i, e := range entries {
+		if e.Filename == "" {
+			entries[i].Filename = strings.TrimSuffix(base, filepath.Ext(base))
+		}
+	}
+	return entries, nil
+}
+
+// verify checks a file against an entry.
+func verify(path, manifest string, e Entry) Result {
+	result := Result{
+		Filename:  path,
+		Manifest:  manifest,
+		Algorithm: e.Algorithm,
+		Expected:  e.Checksum,
+	}
+	actual, err := Checksum(path, e.Algorithm)
+	switch {
+	case os.IsNotExist(err):
+		result.Status = StatusMissing
+	case err != nil:
+		result.Status = StatusMismatch
+		result.Actual = err.Error()
+	case actual != e.Checksum:
+		result.Status = StatusMismatch
+		result.Actual = actual
+	default:
+		result.Status = StatusOK
+		result.Actual = actual
+	}
+	return result
+}
+
+// Verify checks files against all manifests found in the directories of the
+// given files. Directories are verified completely, including files listed
+// in manifests, but missing. Files not mentioned in any manifest are
+// reported as unlisted.
+func Verify(paths ...string) (results []Result, err error) {
+	type listing struct {
+		manifest string
+		entry    Entry
+	}
+	// Manifest entries by cleaned path, per directory.
+	listed := make(map[string][]listing)
+	seen := make(map[string]bool)
+	load := func(dir string) error {
+		if seen[dir] {
+			return nil
+		}
+		seen[dir] = true
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			if info.IsDir() || !IsManifest(info.Name()) {
+				continue
+			}
+			manifest := filepath.Join(dir, info.Name())
+			entries, err := readManifest(manifest)
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				p := filepath.Clean(filepath.Join(dir, e.Filename))
+				listed[p] = append(listed[p], listing{manifest, e})
+			}
+		}
+		return nil
+	}
+	var files []string
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			if err := load(filepath.Dir(path)); err != nil {
+				return nil, err
+			}
+			files = append(files, filepath.Clean(path))
+			continue
+		}
+		if err := load(path); err != nil {
+			return nil, err
+		}
+		infos, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			if !info.IsDir() && !IsManifest(info.Name()) {
+				files = append(files, filepath.Join(path, info.Name()))
+			}
+		}
+		for p := range listed {
+			if filepath.Dir(p) == filepath.Clean(path) {
+				files = append(files, p)
+			}
+		}
+	}
+	sort.Strings(files)
+	done := make(map[string]bool)
+	for _, f := range files {
+		if done[f] {
+			continue
+		}
+		done[f] = true
+		ls, ok := listed[f]
+		if !ok {
+			results = append(results, Result{Filename: f, Status: StatusUnlisted})
+			continue
+		}
+		for _, l := range ls {
+			results = append(results, verify(f, l.manifest, l.entry))
+		}
+	}
+	return results, nil
+}
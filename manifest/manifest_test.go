@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	input := `# comment
+d41d8cd98f00b204e9800998ecf8427e  a.xml
+e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855 *./b.zip
+SHA256 (c d.xml) = E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855
+d41d8cd98f00b204e9800998ecf8427e
+`
+	entries, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{"a.xml", "md5", "d41d8cd98f00b204e9800998ecf8427e"},
+		{"b.zip", "sha256", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"c d.xml", "sha256", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"", "md5", "d41d8cd98f00b204e9800998ecf8427e"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Parse: got %v, want %v", entries, want)
+	}
+	if _, err := Parse(strings.NewReader("not a checksum  a.xml\n")); err == nil {
+		t.Errorf("Parse: want error for invalid line")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-manifest-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"empty.xml":          "",
+		"corrupt.xml":        "x",
+		"extra.xml":          "",
+		"sidecar.zip":        "",
+		"MD5SUMS":            "d41d8cd98f00b204e9800998ecf8427e  empty.xml\nd41d8cd98f00b204e9800998ecf8427e  corrupt.xml\nd41d8cd98f00b204e9800998ecf8427e  gone.xml\n",
+		"sidecar.zip.sha256": "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	results, err := Verify(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := make(map[string]string)
+	for _, r := range results {
+		status[filepath.Base(r.Filename)] = r.Status
+	}
+	want := map[string]string{
+		"empty.xml":   StatusOK,
+		"corrupt.xml": StatusMismatch,
+		"gone.xml":    StatusMissing,
+		"extra.xml":   StatusUnlisted,
+		"sidecar.zip": StatusOK,
+	}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("Verify: got %v, want %v", status, want)
+	}
+	// A single file is checked against manifests in its directory.
+	results, err = Verify(filepath.Join(dir, "corrupt.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !results[0].Failed() {
+		t.Errorf("Verify: got %v, want a single failure", results)
+	}
+}
@@ -0,0 +1,103 @@
+// Package mint builds record ids of the form ai-<source id>-<local part>,
+// e.g. ai-49-aHR0cDovL2R4LmRvaS5vcmcvMTAuMTAwMS8x, from a source id and an
+// identifier of the record within the source.
+//
+// The local part is derived by a strategy, which can be configured per source.
+// Converters pass their historical default, so ids stay the same, unless a
+// strategy is configured. Ids never exceed span.KeyLengthLimit: a local part,
+// that would be too long, is replaced by a SHA1 hash.
+package mint
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miku/span"
+)
+
+// Strategy derives the local part of an id.
+type Strategy string
+
+const (
+	// Base64 encodes the identifier as unpadded base64url.
+	Base64 Strategy = "base64"
+	// Plain uses the identifier as is, for identifiers known to be short and
+	// without whitespace.
+	Plain Strategy = "plain"
+	// SHA1 uses the hex encoded SHA1 hash of the identifier.
+	SHA1 Strategy = "sha1"
+	// DOI encodes the lowercased DOI as unpadded base64url, so the same DOI
+	// results in the same local part in all sources. Records without a DOI
+	// use the default strategy of the converter.
+	DOI Strategy = "doi"
+)
+
+// Strategies maps source ids to configured strategies. Not safe for
+// concurrent use with conversions, set it once at startup.
+var Strategies = make(map[string]Strategy)
+
+// Valid returns true, if the strategy is known.
+func (s Strategy) Valid() bool {
+	switch s {
+	case Base64, Plain, SHA1, DOI:
+		return true
+	}
+	return false
+}
+
+// LoadStrategies reads a JSON object from source id to strategy name, e.g.
+// {"49": "sha1", "55": "doi"}, and adds it to Strategies.
+func LoadStrategies(r io.Reader) error {
+	var m map[string]Strategy
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return err
+	}
+	for sid, s := range m {
+		if !s.Valid() {
+			return fmt.Errorf("unknown id strategy for source %s: %s", sid, s)
+		}
+		Strategies[sid] = s
+	}
+	return nil
+}
+
+// ID returns the record id for a source and a record identifier, using the
+// strategy configured for the source or def. The doi is only used by the DOI
+// strategy and may be empty.
+func ID(sid, identifier, doi string, def Strategy) string {
+	s, ok := Strategies[sid]
+	if !ok {
+		s = def
+	}
+	if s == DOI {
+		if doi != "" {
+			identifier = strings.ToLower(doi)
+		} else {
+			s = def
+		}
+	}
+	prefix := fmt.Sprintf("ai-%s-", sid)
+	var local string
+	switch s {
+	case Plain:
+		local = identifier
+	case SHA1:
+		local = hash(identifier)
+	default:
+		local = base64.RawURLEncoding.EncodeToString([]byte(identifier))
+	}
+	if len(prefix)+len(local) > span.KeyLengthLimit {
+		local = hash(identifier)
+	}
+	return prefix + local
+}
+
+func hash(s string) string {
+	h := sha1.Sum([]byte(s))
+	return hex.EncodeToString(h[:])
+}
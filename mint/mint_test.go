@@ -0,0 +1,58 @@
+package mint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miku/span"
+)
+
+func TestID(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	var cases = []struct {
+		about      string
+		strategies map[string]Strategy
+		sid        string
+		identifier string
+		doi        string
+		def        Strategy
+		result     string
+	}{
+		{"base64 default", nil, "49", "http://dx.doi.org/10.1/x", "", Base64,
+			"ai-49-aHR0cDovL2R4LmRvaS5vcmcvMTAuMS94"},
+		{"plain default", nil, "28", "0a1b2c", "", Plain, "ai-28-0a1b2c"},
+		{"configured sha1", map[string]Strategy{"28": SHA1}, "28", "0a1b2c", "", Plain,
+			"ai-28-" + hash("0a1b2c")},
+		{"other source unaffected", map[string]Strategy{"49": SHA1}, "28", "0a1b2c", "", Plain,
+			"ai-28-0a1b2c"},
+		{"doi", map[string]Strategy{"49": DOI}, "49", "http://dx.doi.org/10.1/X", "10.1/X", Base64,
+			"ai-49-MTAuMS94"},
+		{"doi missing", map[string]Strategy{"49": DOI}, "49", "abc", "", Plain, "ai-49-abc"},
+		{"too long", nil, "48", long, "", Base64, "ai-48-" + hash(long)},
+		{"too long plain", nil, "28", long, "", Plain, "ai-28-" + hash(long)},
+	}
+	defer func() { Strategies = make(map[string]Strategy) }()
+	for _, c := range cases {
+		Strategies = c.strategies
+		result := ID(c.sid, c.identifier, c.doi, c.def)
+		if result != c.result {
+			t.Errorf("%s: got %s, want %s", c.about, result, c.result)
+		}
+		if len(result) > span.KeyLengthLimit {
+			t.Errorf("%s: id exceeds key limit: %d", c.about, len(result))
+		}
+	}
+}
+
+func TestLoadStrategies(t *testing.T) {
+	defer func() { Strategies = make(map[string]Strategy) }()
+	if err := LoadStrategies(strings.NewReader(`{"49": "sha1", "55": "doi"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if Strategies["49"] != SHA1 || Strategies["55"] != DOI {
+		t.Errorf("got %v", Strategies)
+	}
+	if err := LoadStrategies(strings.NewReader(`{"49": "md5"}`)); err == nil {
+		t.Errorf("expected error for unknown strategy")
+	}
+}
@@ -0,0 +1,150 @@
+// Package names normalizes author names, which come in many forms, e.g.
+// "Müller, Hans", "Prof. Dr. Hans Müller", "MÜLLER , H." or "Deutsche
+// Gesellschaft für Soziologie".
+package names
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/miku/span/container"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// particles start a last name, e.g. Ludwig van Beethoven.
+	particles = container.NewStringSet("von", "vom", "van", "de", "der", "den", "zu", "zum", "zur",
+		"di", "da", "du", "del", "della", "le", "la", "ten", "ter")
+
+	// titles are stripped from the beginning of a name.
+	titles = container.NewStringSet("prof.", "dr.", "dr.-ing.", "dipl.-ing.", "pd", "mag.",
+		"prof", "dr", "priv.-doz.", "univ.-prof.", "mr.", "mrs.", "ms.", "sir")
+
+	// corporateWords mark names of organizations, compared case insensitive
+	// with single words of a name.
+	corporateWords = container.NewStringSet("university", "universität", "universite", "université",
+		"universidad", "universita", "università", "institute", "institut", "instituto", "society",
+		"gesellschaft", "association", "verein", "verband", "foundation", "stiftung", "committee",
+		"kommission", "commission", "consortium", "collaboration", "council", "ministry",
+		"ministerium", "department", "agency", "organization", "organisation", "group",
+		"network", "centre", "center", "zentrum", "academy", "akademie", "gmbh", "e.v.",
+		"inc.", "ltd.", "llc", "team", "office", "bundesamt", "landesamt")
+
+	// separators are unified, e.g. dashes and spaces.
+	separators = strings.NewReplacer(
+		"\u00a0", " ", // no-break space
+		"\u2009", " ", // thin space
+		"\u2010", "-", // hyphen
+		"\u2011", "-", // non-breaking hyphen
+		"\u2013", "-", // en dash
+		"\u2212", "-", // minus
+		"\u2019", "'", // right single quotation mark
+		"`", "'",
+	)
+
+	// folder removes diacritics.
+	folder = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+	// letters, that do not decompose.
+	foldReplacer = strings.NewReplacer("ß", "ss", "æ", "ae", "Æ", "AE", "œ", "oe", "Œ", "OE",
+		"ø", "o", "Ø", "O", "ł", "l", "Ł", "L", "đ", "d", "Đ", "D", "ı", "i")
+)
+
+// Name is a parsed name. If a personal name cannot be split, First and Last
+// are empty.
+type Name struct {
+	First     string
+	Last      string
+	Corporate bool
+	// Raw is the cleaned name.
+	Raw string
+}
+
+// Clean unifies dashes, spaces and apostrophes, collapses whitespace and
+// fixes spacing around commas, e.g. "Müller ,Hans" becomes "Müller, Hans".
+func Clean(s string) string {
+	s = separators.Replace(s)
+	s = strings.Join(strings.Fields(s), " ")
+	s = strings.Replace(s, " ,", ",", -1)
+	if strings.Contains(s, ",") {
+		parts := strings.Split(s, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		s = strings.Join(parts, ", ")
+	}
+	return strings.Trim(s, " ;,")
+}
+
+// StripTitles removes academic and other titles from the beginning of a name,
+// e.g. "Prof. Dr. Hans Müller" becomes "Hans Müller".
+func StripTitles(s string) string {
+	fields := strings.Fields(s)
+	i := 0
+	for i < len(fields) && titles.Contains(strings.ToLower(fields[i])) {
+		i++
+	}
+	return strings.Join(fields[i:], " ")
+}
+
+// IsCorporate returns true, if a name looks like the name of an
+// organization.
+func IsCorporate(s string) bool {
+	for _, f := range strings.Fields(strings.ToLower(s)) {
+		if corporateWords.Contains(strings.Trim(f, ",;:()")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fold transliterates letters with diacritics to their base letters, e.g. for
+// facets, where "Müller" and "Muller" should be the same value.
+func Fold(s string) string {
+	result, _, err := transform.String(folder, foldReplacer.Replace(s))
+	if err != nil {
+		return s
+	}
+	return result
+}
+
+// Parse splits a name into first and last name. Handles "Last, First" as
+// well as "First Last", with name particles belonging to the last name.
+// Titles are stripped, corporate names are not split. Lists of names, e.g.
+// "A und B" or "A, B, C, D", are not split and returned as raw value.
+func Parse(s string) Name {
+	s = Clean(s)
+	if IsCorporate(s) {
+		return Name{Raw: s, Corporate: true}
+	}
+	if strings.Count(s, ",") == 1 {
+		parts := strings.SplitN(s, ",", 2)
+		last, first := strings.TrimSpace(parts[0]), StripTitles(parts[1])
+		if last != "" && first != "" && !strings.Contains(last, " und ") {
+			return Name{First: first, Last: last, Raw: s}
+		}
+		return Name{Raw: s}
+	}
+	if strings.Contains(s, ",") || strings.Contains(s, " und ") || strings.Contains(s, "&") {
+		return Name{Raw: s}
+	}
+	fields := strings.Fields(StripTitles(s))
+	if len(fields) < 2 {
+		return Name{Raw: s}
+	}
+	// The last name starts at the first particle or is the last field.
+	i := len(fields) - 1
+	for j := 1; j < len(fields)-1; j++ {
+		if particles.Contains(fields[j]) {
+			i = j
+			break
+		}
+	}
+	return Name{
+		First: strings.Join(fields[:i], " "),
+		Last:  strings.Join(fields[i:], " "),
+		Raw:   s,
+	}
+}
@@ -0,0 +1,46 @@
+package names
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	var cases = []struct {
+		s    string
+		name Name
+	}{
+		{"Müller, Hans", Name{First: "Hans", Last: "Müller", Raw: "Müller, Hans"}},
+		{"Müller ,Hans", Name{First: "Hans", Last: "Müller", Raw: "Müller, Hans"}},
+		{"Hans  Müller", Name{First: "Hans", Last: "Müller", Raw: "Hans Müller"}},
+		{"Ludwig van Beethoven", Name{First: "Ludwig", Last: "van Beethoven", Raw: "Ludwig van Beethoven"}},
+		{"Prof. Dr. Hans Müller", Name{First: "Hans", Last: "Müller", Raw: "Prof. Dr. Hans Müller"}},
+		{"Müller, Dr. Hans", Name{First: "Hans", Last: "Müller", Raw: "Müller, Dr. Hans"}},
+		{"Dr. Müller", Name{Raw: "Dr. Müller"}},
+		{"Hans–Peter Müller", Name{First: "Hans-Peter", Last: "Müller", Raw: "Hans-Peter Müller"}},
+		{"Deutsche Gesellschaft für Soziologie", Name{Corporate: true, Raw: "Deutsche Gesellschaft für Soziologie"}},
+		{"University of Leipzig", Name{Corporate: true, Raw: "University of Leipzig"}},
+		{"Müller, Hans, Schmidt, Anna", Name{Raw: "Müller, Hans, Schmidt, Anna"}},
+		{"Hans Müller und Anna Schmidt", Name{Raw: "Hans Müller und Anna Schmidt"}},
+		{"", Name{}},
+	}
+	for _, c := range cases {
+		if name := Parse(c.s); name != c.name {
+			t.Errorf("Parse(%q): got %+v, want %+v", c.s, name, c.name)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	var cases = []struct {
+		s, result string
+	}{
+		{"Müller", "Muller"},
+		{"Dvořák, Antonín", "Dvorak, Antonin"},
+		{"Straße", "Strasse"},
+		{"Łukasz Søndergaard", "Lukasz Sondergaard"},
+		{"Smith", "Smith"},
+	}
+	for _, c := range cases {
+		if result := Fold(c.s); result != c.result {
+			t.Errorf("Fold(%q): got %q, want %q", c.s, result, c.result)
+		}
+	}
+}
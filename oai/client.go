@@ -0,0 +1,193 @@
+// Package oai implements an OAI-PMH client for harvesting, refs.
+// http://www.openarchives.org/OAI/openarchivesprotocol.html.
+package oai
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/miku/span"
+)
+
+// ErrNoRecordsMatch is returned by ListRecords, if a request is empty.
+const ErrNoRecordsMatch = "noRecordsMatch"
+
+// Request holds the parameters of an OAI request. If ResumptionToken is set,
+// other arguments are ignored, as required by the protocol.
+type Request struct {
+	Verb            string
+	MetadataPrefix  string
+	Set             string
+	From            string
+	Until           string
+	ResumptionToken string
+}
+
+// Values returns the request as query parameters.
+func (r Request) Values() url.Values {
+	vs := url.Values{}
+	vs.Set("verb", r.Verb)
+	if r.ResumptionToken != "" {
+		vs.Set("resumptionToken", r.ResumptionToken)
+		return vs
+	}
+	for k, v := range map[string]string{
+		"metadataPrefix": r.MetadataPrefix,
+		"set":            r.Set,
+		"from":           r.From,
+		"until":          r.Until,
+	} {
+		if v != "" {
+			vs.Set(k, v)
+		}
+	}
+	return vs
+}
+
+// Error is an OAI-PMH protocol error.
+type Error struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("oai: %s: %s", e.Code, e.Message)
+}
+
+// Response contains the parts of a response needed for harvesting, along with
+// the raw response body.
+type Response struct {
+	XMLName      xml.Name `xml:"OAI-PMH"`
+	ResponseDate string   `xml:"responseDate"`
+	Error        *Error   `xml:"error"`
+	ListRecords  struct {
+		Records         []struct{} `xml:"record"`
+		ResumptionToken struct {
+			Value            string `xml:",chardata"`
+			CompleteListSize string `xml:"completeListSize,attr"`
+			Cursor           string `xml:"cursor,attr"`
+		} `xml:"resumptionToken"`
+	} `xml:"ListRecords"`
+	Raw []byte `xml:"-"`
+}
+
+// Client talks to an OAI endpoint. Requests answered with 503 are retried
+// after the time given in the Retry-After header, others with backoff.
+type Client struct {
+	Endpoint   string
+	MaxRetries int
+	UserAgent  string
+	Client     *http.Client
+	// MaxRetryAfter caps the time to wait, if a server asks for more.
+	MaxRetryAfter time.Duration
+}
+
+// NewClient returns a client with defaults.
+func NewClient(endpoint string) *Client {
+	return &Client{
+		Endpoint:      endpoint,
+		MaxRetries:    10,
+		UserAgent:     fmt.Sprintf("span/%s", span.AppVersion),
+		Client:        &http.Client{Timeout: 5 * time.Minute},
+		MaxRetryAfter: 10 * time.Minute,
+	}
+}
+
+// retryAfter returns the wait time requested by the server.
+func retryAfter(resp *http.Response, fallback time.Duration) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if s, err := strconv.Atoi(v); err == nil {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return fallback
+}
+
+// Do performs a request and parses the response. Protocol errors are returned
+// as Error.
+func (c *Client) Do(r Request) (*Response, error) {
+	link := fmt.Sprintf("%s?%s", c.Endpoint, r.Values().Encode())
+	backoff := time.Second
+	var lastErr error
+	for i := 0; i <= c.MaxRetries; i++ {
+		req, err := http.NewRequest("GET", link, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", c.UserAgent)
+		resp, err := c.Client.Do(req)
+		if err != nil {
+			lastErr = err
+			time.Sleep(backoff)
+			backoff *= 2
+			continue
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusServiceUnavailable:
+			wait := retryAfter(resp, backoff)
+			if wait > c.MaxRetryAfter {
+				wait = c.MaxRetryAfter
+			}
+			lastErr = fmt.Errorf("%s: %s", link, resp.Status)
+			time.Sleep(wait)
+			backoff *= 2
+			continue
+		case resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("%s: %s", link, resp.Status)
+			time.Sleep(backoff)
+			backoff *= 2
+			continue
+		case resp.StatusCode >= 400:
+			return nil, fmt.Errorf("%s: %s", link, resp.Status)
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		var response Response
+		dec := xml.NewDecoder(bytes.NewReader(b))
+		dec.Strict = false
+		if err := dec.Decode(&response); err != nil {
+			return nil, fmt.Errorf("%s: %v", link, err)
+		}
+		response.Raw = b
+		if response.Error != nil {
+			return &response, *response.Error
+		}
+		return &response, nil
+	}
+	return nil, fmt.Errorf("giving up after %d retries: %v", c.MaxRetries, lastErr)
+}
+
+// ListRecords harvests all records for a request, following resumption
+// tokens, and calls f with each response. An empty result is not an error.
+func (c *Client) ListRecords(r Request, f func(*Response) error) error {
+	r.Verb = "ListRecords"
+	for {
+		resp, err := c.Do(r)
+		if e, ok := err.(Error); ok && e.Code == ErrNoRecordsMatch {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := f(resp); err != nil {
+			return err
+		}
+		token := resp.ListRecords.ResumptionToken.Value
+		if token == "" {
+			return nil
+		}
+		r = Request{Verb: r.Verb, ResumptionToken: token}
+	}
+}
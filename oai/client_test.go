@@ -0,0 +1,75 @@
+package oai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+const responseTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/">
+<responseDate>2019-01-01T00:00:00Z</responseDate>
+%s
+</OAI-PMH>`
+
+func TestListRecords(t *testing.T) {
+	var requests int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&requests, 1)
+		q := r.URL.Query()
+		if q.Get("verb") != "ListRecords" {
+			t.Errorf("unexpected verb: %s", q.Get("verb"))
+		}
+		switch {
+		case q.Get("set") == "empty":
+			fmt.Fprintf(w, responseTemplate, `<error code="noRecordsMatch">no records</error>`)
+		case q.Get("set") == "bad":
+			fmt.Fprintf(w, responseTemplate, `<error code="badArgument">bad</error>`)
+		case n == 1:
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+		case q.Get("resumptionToken") == "":
+			if q.Get("metadataPrefix") != "oai_dc" || q.Get("from") != "2019-01-01" {
+				t.Errorf("unexpected query: %s", r.URL.RawQuery)
+			}
+			fmt.Fprintf(w, responseTemplate, `<ListRecords><record/><record/>
+				<resumptionToken completeListSize="3" cursor="0">next</resumptionToken></ListRecords>`)
+		case q.Get("resumptionToken") == "next" && q.Get("metadataPrefix") == "":
+			fmt.Fprintf(w, responseTemplate, `<ListRecords><record/><resumptionToken completeListSize="3" cursor="2"/></ListRecords>`)
+		default:
+			http.Error(w, "bad request", http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	var tests = []struct {
+		set     string
+		pages   int
+		records int
+		err     bool
+	}{
+		{"", 2, 3, false},
+		{"empty", 0, 0, false},
+		{"bad", 0, 0, true},
+	}
+	for _, tt := range tests {
+		c := NewClient(ts.URL)
+		var pages, records int
+		err := c.ListRecords(Request{MetadataPrefix: "oai_dc", Set: tt.set, From: "2019-01-01"}, func(resp *Response) error {
+			pages++
+			records += len(resp.ListRecords.Records)
+			if len(resp.Raw) == 0 {
+				t.Errorf("raw response missing")
+			}
+			return nil
+		})
+		if (err != nil) != tt.err {
+			t.Errorf("ListRecords(%s): got %v, want error %v", tt.set, err, tt.err)
+		}
+		if pages != tt.pages || records != tt.records {
+			t.Errorf("ListRecords(%s): got %d pages, %d records, want %d, %d", tt.set, pages, records, tt.pages, tt.records)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package oai
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/miku/span/assetutil"
+)
+
+// Kind is the genre, RIS type and finc format of a record.
+type Kind struct {
+	Name    string
+	Genre   string
+	RefType string
+	Format  string
+}
+
+var (
+	// Types maps lowercase dc:type values, including DRIVER (doc-type:) and
+	// OpenAIRE (info:eu-repo/semantics/) vocabularies, to a kind name.
+	Types = assetutil.MustLoadStringMap("assets/oai/types.json")
+
+	// Kinds, named like crossref types.
+	Kinds = map[string]Kind{
+		"journal-article":     {"journal-article", "article", "EJOUR", "ElectronicArticle"},
+		"book":                {"book", "book", "EBOOK", "eBook"},
+		"book-chapter":        {"book-chapter", "bookitem", "ECHAP", "ElectronicBookPart"},
+		"proceedings-article": {"proceedings-article", "proceeding", "CONF", "ElectronicProceeding"},
+		"dissertation":        {"dissertation", "book", "THES", "ElectronicThesis"},
+		"report":              {"report", "report", "RPRT", "ElectronicArticle"},
+	}
+
+	// editorPattern matches editor markers and imprints of edited volumes in
+	// citations, e.g. "Knapp, Gudrun-Axeli (Hrsg.): ..." or "(Münster:
+	// Westfälisches Dampfboot, 2003)".
+	editorPattern = regexp.MustCompile(`(?i)\((?:hrsg|hg|eds?)\.?\)|^in:|\([^():]+:[^()]*,\s*[12][0-9]{3}\)`)
+	// periodicalPattern matches volume and issue markers, e.g. "Jg. 12",
+	// "Vol. 3, No. 4" or "12 (2003) 4".
+	periodicalPattern = regexp.MustCompile(`(?i)\b(?:jg|jahrgang|vol|heft|h|no|nr)\.?\s*[0-9]+|\b[0-9]+\s*\([12][0-9]{3}\)\s*,?\s*[0-9]+`)
+)
+
+// Clues are the parts of a record, that hint at its kind.
+type Clues struct {
+	// Types are dc:type values.
+	Types []string
+	ISSN  []string
+	ISBN  []string
+	// Source is the citation found in dc:source.
+	Source string
+}
+
+// DetectKind guesses the kind of a record. The dc:type values are tried
+// first, then ISSN and ISBN, then the structure of the citation. The boolean
+// is false, if there is no clue at all.
+func DetectKind(c Clues) (Kind, bool) {
+	for _, t := range c.Types {
+		if name, ok := Types[strings.ToLower(strings.TrimSpace(t))]; ok {
+			if k, ok := Kinds[name]; ok {
+				return k, true
+			}
+		}
+	}
+	switch {
+	case len(c.ISSN) > 0:
+		return Kinds["journal-article"], true
+	case len(c.ISBN) > 0 && strings.TrimSpace(c.Source) != "":
+		return Kinds["book-chapter"], true
+	case len(c.ISBN) > 0:
+		return Kinds["book"], true
+	}
+	source := strings.Join(strings.Fields(c.Source), " ")
+	switch {
+	case source == "":
+	case editorPattern.MatchString(source):
+		return Kinds["book-chapter"], true
+	case periodicalPattern.MatchString(source):
+		return Kinds["journal-article"], true
+	}
+	return Kind{}, false
+}
@@ -0,0 +1,29 @@
+package oai
+
+import "testing"
+
+func TestDetectKind(t *testing.T) {
+	var cases = []struct {
+		clues Clues
+		name  string
+	}{
+		{Clues{}, ""},
+		{Clues{Types: []string{"Text"}}, ""},
+		{Clues{Types: []string{"Text", "doc-type:bookPart"}, ISSN: []string{"1234-5678"}}, "book-chapter"},
+		{Clues{Types: []string{"info:eu-repo/semantics/article"}}, "journal-article"},
+		{Clues{Types: []string{"Monograph"}}, "book"},
+		{Clues{ISSN: []string{"1234-5678"}}, "journal-article"},
+		{Clues{ISBN: []string{"9783643506776"}}, "book"},
+		{Clues{ISBN: []string{"9783643506776"}, Source: "Lakitsch, Maximilian (Hrsg.): Krieg"}, "book-chapter"},
+		{Clues{Source: "Knapp, Gudrun-Axeli; Wetterer, Angelika\n (Hrsg.): Achsen der Differenz (Münster: Westfälisches Dampfboot, 2003), 73-100"}, "book-chapter"},
+		{Clues{Source: "Feministische Studien, Jg. 21, H. 2, 2003, 5-20"}, "journal-article"},
+		{Clues{Source: "Zeitschrift für Frauenforschung 12 (1994) 3, 45-60"}, "journal-article"},
+		{Clues{Source: "Berlin"}, ""},
+	}
+	for _, c := range cases {
+		k, ok := DetectKind(c.clues)
+		if ok != (c.name != "") || k.Name != c.name {
+			t.Errorf("DetectKind(%v): got %q %v, want %q", c.clues, k.Name, ok, c.name)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Package openaccess flags records as open access, if their DOI or ISSN is
+// found in a list, e.g. an unpaywall snapshot, an OA KBART file or a plain
+// list of DOI and ISSN.
+package openaccess
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/doi"
+	"github.com/miku/span/formats/finc"
+	"github.com/miku/span/licensing/kbart"
+)
+
+// Statuses are the accepted open access status values.
+var Statuses = container.NewStringSet(finc.OAStatusGold, finc.OAStatusGreen,
+	finc.OAStatusHybrid, finc.OAStatusBronze)
+
+// List maps normalized DOI and ISSN to an open access status, which may be
+// empty, if unknown.
+type List struct {
+	DOI  map[string]string
+	ISSN map[string]string
+}
+
+// New returns an empty list.
+func New() *List {
+	return &List{DOI: make(map[string]string), ISSN: make(map[string]string)}
+}
+
+// Len returns the number of DOI and ISSN.
+func (l *List) Len() int {
+	return len(l.DOI) + len(l.ISSN)
+}
+
+// add adds a DOI or ISSN with a status and returns an error, if the value is
+// neither.
+func (l *List) add(v, status string) error {
+	status = strings.ToLower(strings.TrimSpace(status))
+	if status != "" && !Statuses.Contains(status) {
+		return fmt.Errorf("invalid open access status: %s", status)
+	}
+	if d, err := doi.Normalize(v); err == nil {
+		l.DOI[d] = status
+		return nil
+	}
+	if issn, err := span.ParseISSN(v); err == nil {
+		l.ISSN[string(issn)] = status
+		return nil
+	}
+	return fmt.Errorf("neither DOI nor ISSN: %s", v)
+}
+
+// ReadLines reads one DOI or ISSN per line, optionally followed by a tab and
+// a status, e.g. "10.1234/5678\tgold". Empty lines and lines starting with #
+// are ignored.
+func (l *List) ReadLines(r io.Reader) error {
+	br := bufio.NewScanner(r)
+	br.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var i int
+	for br.Scan() {
+		i++
+		line := strings.TrimSpace(br.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 2)
+		var status string
+		if len(fields) == 2 {
+			status = fields[1]
+		}
+		if err := l.add(fields[0], status); err != nil {
+			return fmt.Errorf("line %d: %v", i, err)
+		}
+	}
+	return br.Err()
+}
+
+// ReadUnpaywall reads an unpaywall snapshot, one JSON object per line. Only
+// DOI marked as open access are added, with their status. Records with a
+// DOI, that cannot be parsed, are skipped; the number of skipped records is
+// returned.
+func (l *List) ReadUnpaywall(r io.Reader) (skipped int, err error) {
+	dec := json.NewDecoder(r)
+	for {
+		var doc struct {
+			DOI      string `json:"doi"`
+			IsOA     bool   `json:"is_oa"`
+			OAStatus string `json:"oa_status"`
+		}
+		if err := dec.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			return skipped, err
+		}
+		if !doc.IsOA {
+			continue
+		}
+		d, err := doi.Normalize(doc.DOI)
+		if err != nil {
+			skipped++
+			continue
+		}
+		status := strings.ToLower(doc.OAStatus)
+		if !Statuses.Contains(status) {
+			status = ""
+		}
+		l.DOI[d] = status
+	}
+	return skipped, nil
+}
+
+// ReadKBART reads ISSN from a KBART file listing open access journals, which
+// are considered gold.
+func (l *List) ReadKBART(r io.Reader) error {
+	var h kbart.Holdings
+	if _, err := h.ReadFrom(r); err != nil {
+		return err
+	}
+	for _, e := range h {
+		for _, issn := range e.ISSNList() {
+			l.ISSN[issn] = finc.OAStatusGold
+		}
+	}
+	return nil
+}
+
+// Lookup returns the status of a record, by DOI first, then by ISSN.
+func (l *List) Lookup(is finc.IntermediateSchema) (status string, ok bool) {
+	if d, err := doi.Normalize(is.DOI); err == nil {
+		if status, ok = l.DOI[d]; ok {
+			return status, true
+		}
+	}
+	for _, v := range is.ISSNList() {
+		issn, err := span.ParseISSN(v)
+		if err != nil {
+			continue
+		}
+		if status, ok = l.ISSN[string(issn)]; ok {
+			return status, true
+		}
+	}
+	return "", false
+}
+
+// Apply sets the open access flag of a listed record and the status, if
+// known and the record has none or is marked closed. Gold also replaces
+// hybrid, since converters guess hybrid for openly licensed articles without
+// knowing, whether the journal is open access, e.g. in DOAJ. Returns true, if
+// the record is listed.
+func (l *List) Apply(is *finc.IntermediateSchema) bool {
+	status, ok := l.Lookup(*is)
+	if !ok {
+		return false
+	}
+	is.OpenAccess = true
+	switch {
+	case status == "":
+	case is.OAStatus == "", is.OAStatus == finc.OAStatusClosed:
+		is.OAStatus = status
+	case status == finc.OAStatusGold && is.OAStatus == finc.OAStatusHybrid:
+		is.OAStatus = status
+	}
+	return true
+}
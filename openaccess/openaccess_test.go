@@ -0,0 +1,85 @@
+package openaccess
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miku/span/formats/finc"
+)
+
+func TestApply(t *testing.T) {
+	l := New()
+	if err := l.ReadLines(strings.NewReader("# OA list\n10.1234/ABC\tgold\n1234-5679\n\n")); err != nil {
+		t.Fatal(err)
+	}
+	unpaywall := `{"doi": "10.5555/x", "is_oa": true, "oa_status": "bronze"}
+{"doi": "10.5555/closed", "is_oa": false, "oa_status": "closed"}
+{"doi": "broken", "is_oa": true}
+`
+	skipped, err := l.ReadUnpaywall(strings.NewReader(unpaywall))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skipped != 1 {
+		t.Errorf("ReadUnpaywall: got %d skipped, want 1", skipped)
+	}
+	var cases = []struct {
+		is         finc.IntermediateSchema
+		listed     bool
+		wantStatus string
+	}{
+		{finc.IntermediateSchema{DOI: "https://doi.org/10.1234/abc"}, true, finc.OAStatusGold},
+		{finc.IntermediateSchema{DOI: "10.5555/x", OAStatus: finc.OAStatusClosed}, true, finc.OAStatusBronze},
+		{finc.IntermediateSchema{DOI: "10.5555/x", OAStatus: finc.OAStatusHybrid}, true, finc.OAStatusHybrid},
+		{finc.IntermediateSchema{EISSN: []string{"12345679"}}, true, ""},
+		{finc.IntermediateSchema{DOI: "10.5555/closed"}, false, ""},
+		{finc.IntermediateSchema{ISSN: []string{"0000-0000"}}, false, ""},
+	}
+	for _, c := range cases {
+		is := c.is
+		if listed := l.Apply(&is); listed != c.listed || is.OpenAccess != c.listed || is.OAStatus != c.wantStatus {
+			t.Errorf("Apply(%v, %v): got %v, %v, %q, want %v, %q", c.is.DOI, c.is.ISSNList(),
+				listed, is.OpenAccess, is.OAStatus, c.listed, c.wantStatus)
+		}
+	}
+}
+
+func TestReadLinesInvalid(t *testing.T) {
+	var cases = []string{
+		"not an identifier\n",
+		"10.1234/abc\tpurple\n",
+	}
+	for _, s := range cases {
+		if err := New().ReadLines(strings.NewReader(s)); err == nil {
+			t.Errorf("ReadLines(%q): expected error", s)
+		}
+	}
+}
+
+func TestReadKBART(t *testing.T) {
+	// Journals in DOAJ, as KBART.
+	doaj := "publication_title\tprint_identifier\tonline_identifier\n" +
+		"Journal of Examples\t1234-5679\t2345-6780\n"
+	l := New()
+	if err := l.ReadKBART(strings.NewReader(doaj)); err != nil {
+		t.Fatal(err)
+	}
+	var cases = []struct {
+		is         finc.IntermediateSchema
+		listed     bool
+		wantStatus string
+	}{
+		{finc.IntermediateSchema{ISSN: []string{"1234-5679"}}, true, finc.OAStatusGold},
+		{finc.IntermediateSchema{EISSN: []string{"2345-6780"}, OAStatus: finc.OAStatusClosed}, true, finc.OAStatusGold},
+		{finc.IntermediateSchema{ISSN: []string{"1234-5679"}, OAStatus: finc.OAStatusHybrid}, true, finc.OAStatusGold},
+		{finc.IntermediateSchema{ISSN: []string{"1234-5679"}, OAStatus: finc.OAStatusGreen}, true, finc.OAStatusGreen},
+		{finc.IntermediateSchema{ISSN: []string{"0000-0000"}, OAStatus: finc.OAStatusHybrid}, false, finc.OAStatusHybrid},
+	}
+	for _, c := range cases {
+		is := c.is
+		if listed := l.Apply(&is); listed != c.listed || is.OpenAccess != c.listed || is.OAStatus != c.wantStatus {
+			t.Errorf("Apply(%v, %q): got %v, %v, %q, want %v, %q", c.is.ISSNList(), c.is.OAStatus,
+				listed, is.OpenAccess, is.OAStatus, c.listed, c.wantStatus)
+		}
+	}
+}
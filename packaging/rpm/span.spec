@@ -25,7 +25,13 @@ mkdir -p $RPM_BUILD_ROOT/usr/sbin
 install -m 755 span-amsl-discovery $RPM_BUILD_ROOT/usr/sbin
 install -m 755 span-check $RPM_BUILD_ROOT/usr/sbin
 install -m 755 span-compare $RPM_BUILD_ROOT/usr/sbin
+install -m 755 span-crossref-sync $RPM_BUILD_ROOT/usr/sbin
+install -m 755 span-crossref-enrich $RPM_BUILD_ROOT/usr/sbin
+install -m 755 span-dedup $RPM_BUILD_ROOT/usr/sbin
+install -m 755 span-oai-harvest $RPM_BUILD_ROOT/usr/sbin
 install -m 755 span-export $RPM_BUILD_ROOT/usr/sbin
+install -m 755 span-filter $RPM_BUILD_ROOT/usr/sbin
+install -m 755 span-sort $RPM_BUILD_ROOT/usr/sbin
 install -m 755 span-freeze $RPM_BUILD_ROOT/usr/sbin
 install -m 755 span-hcov $RPM_BUILD_ROOT/usr/sbin
 install -m 755 span-import $RPM_BUILD_ROOT/usr/sbin
@@ -34,6 +40,7 @@ install -m 755 span-oa-filter $RPM_BUILD_ROOT/usr/sbin
 install -m 755 span-redact $RPM_BUILD_ROOT/usr/sbin
 install -m 755 span-report $RPM_BUILD_ROOT/usr/sbin
 install -m 755 span-review $RPM_BUILD_ROOT/usr/sbin
+install -m 755 span-state $RPM_BUILD_ROOT/usr/sbin
 install -m 755 span-tag $RPM_BUILD_ROOT/usr/sbin
 install -m 755 span-update-labels $RPM_BUILD_ROOT/usr/sbin
 install -m 755 span-webhookd $RPM_BUILD_ROOT/usr/sbin
@@ -60,7 +67,13 @@ rm -rf %{_topdir}/BUILD/%{name}
 /usr/sbin/span-amsl-discovery
 /usr/sbin/span-check
 /usr/sbin/span-compare
+/usr/sbin/span-crossref-sync
+/usr/sbin/span-crossref-enrich
+/usr/sbin/span-dedup
+/usr/sbin/span-oai-harvest
 /usr/sbin/span-export
+/usr/sbin/span-filter
+/usr/sbin/span-sort
 /usr/sbin/span-freeze
 /usr/sbin/span-hcov
 /usr/sbin/span-import
@@ -69,6 +82,7 @@ rm -rf %{_topdir}/BUILD/%{name}
 /usr/sbin/span-redact
 /usr/sbin/span-report
 /usr/sbin/span-review
+/usr/sbin/span-state
 /usr/sbin/span-tag
 /usr/sbin/span-update-labels
 /usr/sbin/span-webhookd
@@ -0,0 +1,320 @@
+package parallel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// NextFunc returns the next item or io.EOF, if there are no more items.
+type NextFunc func() (interface{}, error)
+
+// ItemFunc transforms an item with a given number into a slice of bytes.
+type ItemFunc func(lineno int64, v interface{}) ([]byte, error)
+
+// ItemErrorHandler is called with a failed item and the error. If it returns
+// nil, the item is skipped and processing continues.
+type ItemErrorHandler func(lineno int64, v interface{}, err error) error
+
+// item is a value along with its position in the input.
+type item struct {
+	lineno int64
+	v      interface{}
+}
+
+// DefaultBatchBytes limits the size of a batch, so a few huge records do not
+// exhaust memory.
+const DefaultBatchBytes = 64 << 20
+
+// Sizer is implemented by items, that know their approximate size in bytes.
+type Sizer interface {
+	Size() int
+}
+
+// Settings control batching, concurrency and output order of item and source
+// processors.
+type Settings struct {
+	BatchSize int
+	// BatchBytes sends a batch before it is full, once its items add up to
+	// this many bytes, zero means no limit.
+	BatchBytes int64
+	NumWorkers int
+	// PreserveOrder writes results in input order, at the cost of buffering
+	// a few batches.
+	PreserveOrder bool
+	// Selection limits or samples the items read.
+	Selection Selection
+	// Progress, if set, counts the items processed.
+	Progress *Progress
+}
+
+// defaultSettings are the settings of new processors.
+func defaultSettings() Settings {
+	return Settings{
+		BatchSize:  1000,
+		BatchBytes: DefaultBatchBytes,
+		NumWorkers: runtime.NumCPU(),
+	}
+}
+
+// batcher reads items into batches, so the processing loop does not need to
+// know the type of the items.
+type batcher interface {
+	// read reads the next item into the pending batch and returns its size
+	// in bytes, if known, or io.EOF.
+	read() (int, error)
+	// drop removes the item read last, e.g. if it is not selected.
+	drop()
+	// pending returns the number of items in the pending batch.
+	pending() int
+	// take starts a new batch and returns a function, that transforms the
+	// items of the previous one, writes the results to a buffer and returns
+	// the first error.
+	take() func(buf *bytes.Buffer) error
+}
+
+// ItemProcessor reads items sequentially, e.g. decoded XML elements, and
+// transforms them in parallel. The number of batches in flight is bounded, so
+// reading blocks, when workers or the writer fall behind.
+type ItemProcessor struct {
+	Settings
+	// ItemSize, if set, returns the size of an item in bytes. Otherwise sizes
+	// are taken from items implementing Sizer, other items count as zero.
+	ItemSize func(v interface{}) int
+	// OnError, if set, decides what happens with items, that fail to
+	// transform. By default, processing stops at the first error.
+	OnError ItemErrorHandler
+	next    NextFunc
+	w       io.Writer
+	f       ItemFunc
+	lineno  int64
+	items   []item
+}
+
+// NewItemProcessor creates a new processor, which reads items from next,
+// applies a function and writes results to a writer.
+func NewItemProcessor(next NextFunc, w io.Writer, f ItemFunc) *ItemProcessor {
+	return &ItemProcessor{
+		Settings: defaultSettings(),
+		next:     next,
+		w:        w,
+		f:        f,
+	}
+}
+
+// Run starts the workers and returns the first error of the iterator, any
+// worker or the writer.
+func (p *ItemProcessor) Run() error {
+	return p.RunContext(context.Background())
+}
+
+// RunContext is like Run, but stops reading items, when the context is done.
+// Items already read are transformed and written, so output is flushed
+// cleanly, and the context error is returned.
+func (p *ItemProcessor) RunContext(ctx context.Context) error {
+	p.lineno, p.items = 0, nil
+	return p.Settings.run(ctx, p.w, p)
+}
+
+func (p *ItemProcessor) read() (int, error) {
+	v, err := p.next()
+	if err != nil {
+		return 0, err
+	}
+	p.items = append(p.items, item{lineno: p.lineno, v: v})
+	p.lineno++
+	if p.BatchBytes > 0 {
+		return p.size(v), nil
+	}
+	return 0, nil
+}
+
+func (p *ItemProcessor) drop() { p.items = p.items[:len(p.items)-1] }
+
+func (p *ItemProcessor) pending() int { return len(p.items) }
+
+func (p *ItemProcessor) take() func(buf *bytes.Buffer) error {
+	items := p.items
+	p.items = make([]item, 0, p.BatchSize)
+	return func(buf *bytes.Buffer) (first error) {
+		for _, it := range items {
+			r, err := p.f(it.lineno, it.v)
+			if err != nil && p.OnError != nil {
+				r, err = nil, p.OnError(it.lineno, it.v, err)
+			}
+			if err != nil && first == nil {
+				first = err
+			}
+			buf.Write(r)
+		}
+		return first
+	}
+}
+
+// run reads batches and transforms them with a number of workers.
+func (s *Settings) run(ctx context.Context, w io.Writer, b batcher) error {
+	type batch struct {
+		seq int64
+		n   int
+		f   func(*bytes.Buffer) error
+	}
+	type result struct {
+		seq int64
+		b   []byte
+	}
+
+	// The first error wins. After an error, items already queued are still
+	// processed, but no new items are read.
+	var (
+		mu       sync.Mutex
+		firstErr error
+	)
+	setErr := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	getErr := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr
+	}
+
+	numWorkers, batchSize := s.NumWorkers, s.BatchSize
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	queue := make(chan batch)
+	out := make(chan result)
+	done := make(chan bool)
+	// inflight limits the number of batches, that may be buffered for
+	// reordering.
+	inflight := make(chan bool, 2*numWorkers)
+
+	// The worker transforms a batch and sends the concatenated results.
+	worker := func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		for bt := range queue {
+			var buf bytes.Buffer
+			if err := bt.f(&buf); err != nil {
+				setErr(err)
+			}
+			if s.Progress != nil {
+				s.Progress.Add(int64(bt.n))
+			}
+			out <- result{seq: bt.seq, b: buf.Bytes()}
+		}
+	}
+
+	// The writer buffers writes and restores the input order, if required.
+	writer := func() {
+		bw := bufio.NewWriter(w)
+		write := func(b []byte) {
+			if _, err := bw.Write(b); err != nil {
+				setErr(err)
+			}
+		}
+		pending := make(map[int64][]byte)
+		var next int64
+		for r := range out {
+			if !s.PreserveOrder {
+				write(r.b)
+				continue
+			}
+			pending[r.seq] = r.b
+			for {
+				b, ok := pending[next]
+				if !ok {
+					break
+				}
+				write(b)
+				delete(pending, next)
+				next++
+				<-inflight
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			setErr(err)
+		}
+		done <- true
+	}
+
+	var wg sync.WaitGroup
+	go writer()
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go worker(&wg)
+	}
+
+	var seq, size int64
+	sel := newSelector(s.Selection)
+	send := func() {
+		if s.PreserveOrder {
+			inflight <- true
+		}
+		n := b.pending()
+		queue <- batch{seq: seq, n: n, f: b.take()}
+		seq++
+		size = 0
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			setErr(err)
+			break
+		}
+		if sel.done() {
+			break
+		}
+		n, err := b.read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			setErr(err)
+			break
+		}
+		if !sel.take() {
+			b.drop()
+			continue
+		}
+		size += int64(n)
+		if b.pending() == batchSize || (s.BatchBytes > 0 && size >= s.BatchBytes) {
+			// Only check for worker or write errors once per batch.
+			if getErr() != nil {
+				break
+			}
+			send()
+		}
+	}
+	if err := getErr(); b.pending() > 0 && (err == nil || err == ctx.Err()) {
+		send()
+	}
+
+	close(queue)
+	wg.Wait()
+	close(out)
+	<-done
+
+	return getErr()
+}
+
+// size returns the size of an item, if known.
+func (p *ItemProcessor) size(v interface{}) int {
+	if p.ItemSize != nil {
+		return p.ItemSize(v)
+	}
+	if s, ok := v.(Sizer); ok {
+		return s.Size()
+	}
+	return 0
+}
@@ -18,14 +18,19 @@
 //     #1 2
 //     #2 3
 //
-// Note that the order of the input is not guaranteed to be preserved. If you
-// care about the exact position, utilize the originating line number passed
-// into the transforming function.
+// Note that the order of the input is not guaranteed to be preserved, unless
+// PreserveOrder is set. If you care about the exact position, utilize the
+// originating line number passed into the transforming function.
+//
+// Items other than lines, e.g. decoded XML elements, can be processed with an
+// ItemProcessor.
 package parallel
 
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"fmt"
 	"io"
 	"runtime"
 	"sync"
@@ -80,15 +85,65 @@ func (bb *BytesBatch) Slice() []Record {
 // an error. A common denominator of functions that transform data.
 type TransformerFunc func(lineno int64, b []byte) ([]byte, error)
 
+// ErrorHandler is called with a failed record and the error. If it returns
+// nil, the record is skipped and processing continues.
+type ErrorHandler func(lineno int64, b []byte, err error) error
+
+// ErrorLog is an ErrorHandler, that counts errors, optionally writes failed
+// records to a writer and gives up after MaxErrors errors (zero means no
+// limit). Safe for concurrent use.
+type ErrorLog struct {
+	MaxErrors int64
+	W         io.Writer
+
+	mu    sync.Mutex
+	count int64
+}
+
+// Handle records an error. Fulfils ErrorHandler.
+func (e *ErrorLog) Handle(lineno int64, b []byte, err error) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.count++
+	if e.W != nil {
+		if _, werr := e.W.Write(b); werr != nil {
+			return werr
+		}
+	}
+	if e.MaxErrors > 0 && e.count >= e.MaxErrors {
+		return fmt.Errorf("giving up after %d errors, last at line %d: %v", e.count, lineno+1, err)
+	}
+	return nil
+}
+
+// Count returns the number of errors seen so far.
+func (e *ErrorLog) Count() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.count
+}
+
 // Processor can process lines in parallel.
 type Processor struct {
-	BatchSize       int
+	BatchSize int
+	// BatchBytes sends a batch before it is full, once its records add up to
+	// this many bytes, zero means no limit.
+	BatchBytes      int64
 	RecordSeparator byte
 	NumWorkers      int
 	SkipEmptyLines  bool
-	r               io.Reader
-	w               io.Writer
-	f               TransformerFunc
+	// PreserveOrder writes results in input order.
+	PreserveOrder bool
+	// OnError, if set, decides what happens with records, that fail to
+	// transform. By default, processing stops at the first error.
+	OnError ErrorHandler
+	// Selection limits or samples the records read.
+	Selection Selection
+	// Progress, if set, counts the records processed.
+	Progress *Progress
+	r        io.Reader
+	w        io.Writer
+	f        TransformerFunc
 }
 
 // NewProcessor creates a new line processor, which reads lines from a reader,
@@ -96,6 +151,7 @@ type Processor struct {
 func NewProcessor(r io.Reader, w io.Writer, f TransformerFunc) *Processor {
 	return &Processor{
 		BatchSize:       10000,
+		BatchBytes:      DefaultBatchBytes,
 		RecordSeparator: '\n',
 		NumWorkers:      runtime.NumCPU(),
 		SkipEmptyLines:  true,
@@ -113,88 +169,39 @@ func (p *Processor) RunWorkers(numWorkers int) error {
 
 // Run starts the workers, crunching through the input.
 func (p *Processor) Run() error {
+	return p.RunContext(context.Background())
+}
 
-	// wErr signals a worker or writer error. If an error occurs, the items in
-	// the queue are still process, just no items are added to the queue. There
-	// is only one way to toggle this, from false to true, so we don't care
-	// about synchronisation.
-	var wErr error
-
-	// The worker fetches items from a queue, executes f and sends the result to the out channel.
-	worker := func(queue chan []Record, out chan []byte, f TransformerFunc, wg *sync.WaitGroup) {
-		defer wg.Done()
-		for batch := range queue {
-			for _, record := range batch {
-				r, err := f(record.lineno, record.value)
-				if err != nil {
-					wErr = err
-				}
-				out <- r
+// RunContext is like Run, but stops reading input, when the context is done.
+// Lines already read are processed and written.
+func (p *Processor) RunContext(ctx context.Context) error {
+	br := bufio.NewReader(p.r)
+	next := func() (interface{}, error) {
+		for {
+			b, err := br.ReadBytes(p.RecordSeparator)
+			if err != nil {
+				return nil, err
 			}
-		}
-	}
-
-	// The writer collects and buffers writes.
-	writer := func(w io.Writer, bc chan []byte, done chan bool) {
-		bw := bufio.NewWriter(w)
-		for b := range bc {
-			if _, err := bw.Write(b); err != nil {
-				wErr = err
+			if len(bytes.TrimSpace(b)) == 0 && p.SkipEmptyLines {
+				continue
 			}
+			return b, nil
 		}
-		if err := bw.Flush(); err != nil {
-			wErr = err
-		}
-		done <- true
-	}
-
-	queue := make(chan []Record)
-	out := make(chan []byte)
-	done := make(chan bool)
-
-	var wg sync.WaitGroup
-
-	go writer(p.w, out, done)
-
-	for i := 0; i < p.NumWorkers; i++ {
-		wg.Add(1)
-		go worker(queue, out, p.f, &wg)
 	}
-
-	batch := NewBytesBatchCapacity(p.BatchSize)
-	br := bufio.NewReader(p.r)
-	var i int64
-
-	for {
-		b, err := br.ReadBytes(p.RecordSeparator)
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-		if len(bytes.TrimSpace(b)) == 0 && p.SkipEmptyLines {
-			continue
-		}
-		batch.Add(Record{lineno: i, value: b})
-		if batch.Size() == p.BatchSize {
-			// To avoid checking on each loop, we only check for worker or write errors here.
-			if wErr != nil {
-				break
-			}
-			queue <- batch.Slice()
-			batch.Reset()
+	ip := NewItemProcessor(next, p.w, func(lineno int64, v interface{}) ([]byte, error) {
+		return p.f(lineno, v.([]byte))
+	})
+	ip.BatchSize = p.BatchSize
+	ip.BatchBytes = p.BatchBytes
+	ip.ItemSize = func(v interface{}) int { return len(v.([]byte)) }
+	ip.NumWorkers = p.NumWorkers
+	ip.PreserveOrder = p.PreserveOrder
+	ip.Selection = p.Selection
+	ip.Progress = p.Progress
+	if p.OnError != nil {
+		ip.OnError = func(lineno int64, v interface{}, err error) error {
+			return p.OnError(lineno, v.([]byte), err)
 		}
-		i++
 	}
-
-	queue <- batch.Slice()
-	batch.Reset()
-
-	close(queue)
-	wg.Wait()
-	close(out)
-	<-done
-
-	return wErr
+	return ip.RunContext(ctx)
 }
@@ -2,7 +2,9 @@ package parallel
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"strings"
 	"testing"
@@ -118,3 +120,176 @@ func TestSimple(t *testing.T) {
 		}
 	}
 }
+
+func TestErrorLog(t *testing.T) {
+	f := func(_ int64, b []byte) ([]byte, error) {
+		if strings.HasPrefix(string(b), "x") {
+			return nil, errFake1
+		}
+		return b, nil
+	}
+	var cases = []struct {
+		about     string
+		input     string
+		maxErrors int64
+		expected  string
+		failed    string
+		err       bool
+	}{
+		{"no errors", "a\nb\n", 0, "a\nb\n", "", false},
+		{"skip and collect", "a\nx1\nb\nx2\n", 0, "a\nb\n", "x1\nx2\n", false},
+		{"limit not reached", "a\nx1\nb\n", 2, "a\nb\n", "x1\n", false},
+		{"limit reached", "a\nx1\nx2\n", 2, "", "", true},
+	}
+	for _, c := range cases {
+		var buf, failed bytes.Buffer
+		errlog := &ErrorLog{MaxErrors: c.maxErrors, W: &failed}
+		p := NewProcessor(strings.NewReader(c.input), &buf, f)
+		p.OnError = errlog.Handle
+		err := p.Run()
+		if (err != nil) != c.err {
+			t.Errorf("%s: got %v, want error %v", c.about, err, c.err)
+		}
+		if c.err {
+			continue
+		}
+		if !LinesEqual(buf.String(), c.expected) {
+			t.Errorf("%s: got %q, want %q", c.about, buf.String(), c.expected)
+		}
+		if !LinesEqual(failed.String(), c.failed) {
+			t.Errorf("%s: failed: got %q, want %q", c.about, failed.String(), c.failed)
+		}
+	}
+}
+
+func TestPreserveOrder(t *testing.T) {
+	var input, expected bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&input, "%d\n", i)
+		fmt.Fprintf(&expected, "#%d\n", i)
+	}
+	var buf bytes.Buffer
+	p := NewProcessor(&input, &buf, func(_ int64, b []byte) ([]byte, error) {
+		return append([]byte("#"), b...), nil
+	})
+	p.BatchSize = 7
+	p.NumWorkers = 8
+	p.PreserveOrder = true
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected.String() {
+		t.Errorf("p.Run: output not in input order")
+	}
+}
+
+func TestItemProcessor(t *testing.T) {
+	items := []interface{}{1, 2, 3, errFake1, 5}
+	var cases = []struct {
+		about    string
+		n        int
+		expected string
+		err      error
+	}{
+		{"all items", 3, "1\n2\n3\n", nil},
+		{"iterator error", 5, "", errFake1},
+	}
+	for _, c := range cases {
+		var i int
+		next := func() (interface{}, error) {
+			if i == c.n {
+				return nil, io.EOF
+			}
+			v := items[i]
+			i++
+			if err, ok := v.(error); ok {
+				return nil, err
+			}
+			return v, nil
+		}
+		var buf bytes.Buffer
+		p := NewItemProcessor(next, &buf, func(_ int64, v interface{}) ([]byte, error) {
+			return []byte(fmt.Sprintf("%d\n", v)), nil
+		})
+		p.BatchSize = 2
+		p.PreserveOrder = true
+		err := p.Run()
+		if err != c.err {
+			t.Errorf("%s: got %v, want %v", c.about, err, c.err)
+		}
+		if c.err == nil && buf.String() != c.expected {
+			t.Errorf("%s: got %q, want %q", c.about, buf.String(), c.expected)
+		}
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var n int
+	next := func() (interface{}, error) {
+		n++
+		if n == 5 {
+			cancel()
+		}
+		return fmt.Sprintf("%d\n", n), nil
+	}
+	var buf bytes.Buffer
+	p := NewItemProcessor(next, &buf, func(_ int64, v interface{}) ([]byte, error) {
+		return []byte(v.(string)), nil
+	})
+	p.PreserveOrder = true
+	if err := p.RunContext(ctx); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+	if want := "1\n2\n3\n4\n5\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+// sized is an item with a size.
+type sized int
+
+func (s sized) Size() int { return int(s) }
+
+func TestBatchBytes(t *testing.T) {
+	var cases = []struct {
+		batchBytes int64
+		itemSize   func(v interface{}) int
+	}{
+		{0, nil},
+		{1, nil},
+		{100, nil},
+		{100, func(v interface{}) int { return 1000 }},
+	}
+	for _, c := range cases {
+		var i int
+		next := func() (interface{}, error) {
+			if i == 10 {
+				return nil, io.EOF
+			}
+			i++
+			return sized(i * 10), nil
+		}
+		var buf bytes.Buffer
+		p := NewItemProcessor(next, &buf, func(_ int64, v interface{}) ([]byte, error) {
+			return []byte(fmt.Sprintf("%d\n", v)), nil
+		})
+		p.BatchBytes = c.batchBytes
+		p.ItemSize = c.itemSize
+		p.PreserveOrder = true
+		if err := p.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if want := "10\n20\n30\n40\n50\n60\n70\n80\n90\n100\n"; buf.String() != want {
+			t.Errorf("BatchBytes %d: got %q, want %q", c.batchBytes, buf.String(), want)
+		}
+	}
+	p := &ItemProcessor{}
+	if got := p.size(sized(42)); got != 42 {
+		t.Errorf("size: got %d, want 42", got)
+	}
+	if got := p.size("abc"); got != 0 {
+		t.Errorf("size: got %d, want 0", got)
+	}
+}
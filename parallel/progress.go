@@ -0,0 +1,178 @@
+package parallel
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Progress counts records processed and bytes read and reports them
+// periodically, so long running jobs can be told apart from hung ones. A
+// processor with a Progress adds the records it has processed, bytes are
+// counted by reading the raw input through Reader. Progress is safe for
+// concurrent use.
+type Progress struct {
+	// Interval between reports, defaults to one minute.
+	Interval time.Duration
+	// Size is the total input size in bytes, if known, used to estimate the
+	// remaining time.
+	Size int64
+	// Report is called with the current status, by default the status is
+	// logged.
+	Report func(Status)
+
+	records int64 // atomic
+	bytes   int64 // atomic
+	started time.Time
+	once    sync.Once
+	stop    chan bool
+	done    chan bool
+}
+
+// Status is a snapshot of the progress.
+type Status struct {
+	Records int64
+	Bytes   int64
+	Size    int64
+	Elapsed time.Duration
+}
+
+// Rate returns the number of records processed per second.
+func (s Status) Rate() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Records) / s.Elapsed.Seconds()
+}
+
+// Remaining estimates the remaining time from the bytes read so far and the
+// input size. Returns false, if the size is unknown or nothing has been read.
+func (s Status) Remaining() (time.Duration, bool) {
+	if s.Size <= 0 || s.Bytes <= 0 || s.Elapsed <= 0 {
+		return 0, false
+	}
+	if s.Bytes >= s.Size {
+		return 0, true
+	}
+	perByte := float64(s.Elapsed) / float64(s.Bytes)
+	return time.Duration(perByte * float64(s.Size-s.Bytes)).Round(time.Second), true
+}
+
+// String formats the status for logging, e.g. "120000 records, 512.3 MB
+// read (25.1%), 2000 records/s, elapsed 1m0s, ETA 3m0s".
+func (s Status) String() string {
+	msg := fmt.Sprintf("%d records, %0.1f MB read", s.Records, float64(s.Bytes)/1048576)
+	if s.Size > 0 {
+		msg += fmt.Sprintf(" (%0.1f%%)", 100*float64(s.Bytes)/float64(s.Size))
+	}
+	msg += fmt.Sprintf(", %0.0f records/s, elapsed %s", s.Rate(), s.Elapsed.Round(time.Second))
+	if d, ok := s.Remaining(); ok {
+		msg += fmt.Sprintf(", ETA %s", d)
+	}
+	return msg
+}
+
+// Reader returns a reader, that counts the bytes read from r.
+func (p *Progress) Reader(r io.Reader) io.Reader {
+	return &countingReader{r: r, n: &p.bytes}
+}
+
+// Add adds a number of processed records.
+func (p *Progress) Add(n int64) {
+	atomic.AddInt64(&p.records, n)
+}
+
+// Status returns the current status.
+func (p *Progress) Status() Status {
+	s := Status{
+		Records: atomic.LoadInt64(&p.records),
+		Bytes:   atomic.LoadInt64(&p.bytes),
+		Size:    p.Size,
+	}
+	if !p.started.IsZero() {
+		s.Elapsed = time.Since(p.started)
+	}
+	return s
+}
+
+// Start starts periodic reporting.
+func (p *Progress) Start() {
+	p.once.Do(func() {
+		p.started = time.Now()
+		p.stop, p.done = make(chan bool), make(chan bool)
+		interval := p.Interval
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					p.report()
+				case <-p.stop:
+					close(p.done)
+					return
+				}
+			}
+		}()
+	})
+}
+
+// Stop stops periodic reporting and reports the final status.
+func (p *Progress) Stop() {
+	if p.stop == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+	p.stop = nil
+	p.report()
+}
+
+func (p *Progress) report() {
+	if p.Report != nil {
+		p.Report(p.Status())
+		return
+	}
+	log.Println(p.Status())
+}
+
+// InputSize returns the total size of files or of standard input, if no
+// files are given. Returns zero, if the size is unknown, e.g. for pipes or
+// URLs.
+func InputSize(filenames ...string) int64 {
+	if len(filenames) == 0 {
+		fi, err := os.Stdin.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return 0
+		}
+		return fi.Size()
+	}
+	var size int64
+	for _, filename := range filenames {
+		fi, err := os.Stat(filename)
+		if err != nil || !fi.Mode().IsRegular() {
+			return 0
+		}
+		size += fi.Size()
+	}
+	return size
+}
+
+// countingReader counts bytes read.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	atomic.AddInt64(r.n, int64(n))
+	return n, err
+}
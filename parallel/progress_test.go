@@ -0,0 +1,55 @@
+package parallel
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	input := strings.Repeat("abc\n", 1000)
+	var reports []Status
+	progress := &Progress{
+		Size:   int64(len(input)),
+		Report: func(s Status) { reports = append(reports, s) },
+	}
+	p := NewProcessor(progress.Reader(strings.NewReader(input)), ioutil.Discard, func(_ int64, b []byte) ([]byte, error) {
+		return b, nil
+	})
+	p.BatchSize = 7
+	p.Progress = progress
+	progress.Start()
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	progress.Stop()
+	if len(reports) != 1 {
+		t.Fatalf("got %d reports, want 1", len(reports))
+	}
+	s := reports[0]
+	if s.Records != 1000 || s.Bytes != int64(len(input)) {
+		t.Errorf("got %d records and %d bytes, want 1000 and %d", s.Records, s.Bytes, len(input))
+	}
+	if d, ok := s.Remaining(); !ok || d != 0 {
+		t.Errorf("Remaining: got %v, %v, want 0, true", d, ok)
+	}
+}
+
+func TestStatusRemaining(t *testing.T) {
+	var cases = []struct {
+		s    Status
+		want time.Duration
+		ok   bool
+	}{
+		{Status{Bytes: 100, Size: 400, Elapsed: time.Minute}, 3 * time.Minute, true},
+		{Status{Bytes: 100, Elapsed: time.Minute}, 0, false},
+		{Status{Size: 400, Elapsed: time.Minute}, 0, false},
+		{Status{Bytes: 500, Size: 400, Elapsed: time.Minute}, 0, true},
+	}
+	for _, c := range cases {
+		if got, ok := c.s.Remaining(); got != c.want || ok != c.ok {
+			t.Errorf("Remaining(%+v): got %v, %v, want %v, %v", c.s, got, ok, c.want, c.ok)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package parallel
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+)
+
+// Selection restricts the items read by a processor, e.g. to test changes on
+// a small part of a large input. Items are sampled first, then limited. The
+// zero value selects all items.
+type Selection struct {
+	// Limit stops reading after this many items, zero means no limit.
+	Limit int64
+	// Rate is the probability, that an item is selected, zero means all.
+	Rate float64
+	// Seed makes samples reproducible.
+	Seed int64
+}
+
+// RegisterSelectionFlags adds -head, -sample and -seed to a flag set.
+func RegisterSelectionFlags(fs *flag.FlagSet) *Selection {
+	var s Selection
+	fs.Int64Var(&s.Limit, "head", 0, "process only the first N records, 0 means all")
+	fs.Float64Var(&s.Rate, "sample", 0, "process a random sample of records, with this rate between 0 and 1, e.g. 0.01")
+	fs.Int64Var(&s.Seed, "seed", 1, "random seed for -sample, the same seed gives the same sample")
+	return &s
+}
+
+// Validate checks limit and rate.
+func (s Selection) Validate() error {
+	if s.Limit < 0 {
+		return fmt.Errorf("limit must not be negative: %d", s.Limit)
+	}
+	if s.Rate < 0 || s.Rate > 1 {
+		return fmt.Errorf("sample rate must be between 0 and 1: %v", s.Rate)
+	}
+	return nil
+}
+
+// selector keeps track of selected items.
+type selector struct {
+	Selection
+	rng      *rand.Rand
+	selected int64
+}
+
+func newSelector(s Selection) *selector {
+	sel := &selector{Selection: s}
+	if s.Rate > 0 {
+		sel.rng = rand.New(rand.NewSource(s.Seed))
+	}
+	return sel
+}
+
+// done returns true, if the limit is reached.
+func (s *selector) done() bool {
+	return s.Limit > 0 && s.selected >= s.Limit
+}
+
+// take decides, whether the next item is selected.
+func (s *selector) take() bool {
+	if s.rng != nil && s.rng.Float64() >= s.Rate {
+		return false
+	}
+	s.selected++
+	return true
+}
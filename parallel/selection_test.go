@@ -0,0 +1,66 @@
+package parallel
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSelection(t *testing.T) {
+	input := strings.Repeat("x\n", 1000)
+	var cases = []struct {
+		sel      Selection
+		min, max int
+	}{
+		{Selection{}, 1000, 1000},
+		{Selection{Limit: 10}, 10, 10},
+		{Selection{Limit: 2000}, 1000, 1000},
+		{Selection{Rate: 0.1, Seed: 1}, 50, 150},
+		{Selection{Rate: 0.1, Seed: 1, Limit: 20}, 20, 20},
+		{Selection{Rate: 1}, 1000, 1000},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		p := NewProcessor(strings.NewReader(input), &buf, func(_ int64, b []byte) ([]byte, error) {
+			return b, nil
+		})
+		p.BatchSize = 7
+		p.Selection = c.sel
+		if err := p.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(buf.String(), "\n"); n < c.min || n > c.max {
+			t.Errorf("%+v: got %d records, want %d to %d", c.sel, n, c.min, c.max)
+		}
+	}
+}
+
+func TestSelectionReproducible(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 1000; i++ {
+		input.WriteString(strings.Repeat("x", i%17) + "\n")
+	}
+	run := func() string {
+		var buf bytes.Buffer
+		p := NewProcessor(strings.NewReader(input.String()), &buf, func(lineno int64, b []byte) ([]byte, error) {
+			return []byte(string(rune('a'+lineno%26)) + "\n"), nil
+		})
+		p.PreserveOrder = true
+		p.Selection = Selection{Rate: 0.05, Seed: 42}
+		if err := p.Run(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	if a, b := run(), run(); a != b || a == "" {
+		t.Errorf("samples differ or empty: %q, %q", a, b)
+	}
+}
+
+func TestSelectionValidate(t *testing.T) {
+	for _, s := range []Selection{{Limit: -1}, {Rate: -0.1}, {Rate: 1.5}} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("%+v: expected error", s)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+//go:build go1.18
+// +build go1.18
+
+package parallel
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// Source yields items of a single type, e.g. a genios delivery yielding
+// *genios.Document values. Next returns io.EOF, if there are no more items.
+type Source[T any] interface {
+	Next() (T, error)
+}
+
+// typedItem is a value of a source along with its position in the input.
+type typedItem[T any] struct {
+	lineno int64
+	v      T
+}
+
+// SourceProcessor reads items from a typed source and transforms them in
+// parallel, like ItemProcessor, but items are kept in typed batches and never
+// converted to interface values.
+type SourceProcessor[T any] struct {
+	Settings
+	// ItemSize, if set, returns the size of an item in bytes, refs.
+	// BatchBytes.
+	ItemSize func(v T) int
+	// OnError, if set, decides what happens with items, that fail to
+	// transform. By default, processing stops at the first error.
+	OnError func(lineno int64, v T, err error) error
+	src     Source[T]
+	w       io.Writer
+	f       func(lineno int64, v T) ([]byte, error)
+	lineno  int64
+	items   []typedItem[T]
+}
+
+// NewSourceProcessor creates a new processor, which reads items from a
+// source, applies a function and writes results to a writer.
+func NewSourceProcessor[T any](src Source[T], w io.Writer, f func(lineno int64, v T) ([]byte, error)) *SourceProcessor[T] {
+	return &SourceProcessor[T]{
+		Settings: defaultSettings(),
+		src:      src,
+		w:        w,
+		f:        f,
+	}
+}
+
+// SetErrorHandler sets a handler for items, that fail to transform.
+func (p *SourceProcessor[T]) SetErrorHandler(h func(lineno int64, v T, err error) error) {
+	p.OnError = h
+}
+
+// Run starts the workers and returns the first error of the source, any
+// worker or the writer.
+func (p *SourceProcessor[T]) Run() error {
+	return p.RunContext(context.Background())
+}
+
+// RunContext is like Run, but stops reading items, when the context is done.
+func (p *SourceProcessor[T]) RunContext(ctx context.Context) error {
+	p.lineno, p.items = 0, nil
+	return p.Settings.run(ctx, p.w, p)
+}
+
+func (p *SourceProcessor[T]) read() (int, error) {
+	v, err := p.src.Next()
+	if err != nil {
+		return 0, err
+	}
+	p.items = append(p.items, typedItem[T]{lineno: p.lineno, v: v})
+	p.lineno++
+	if p.BatchBytes > 0 && p.ItemSize != nil {
+		return p.ItemSize(v), nil
+	}
+	return 0, nil
+}
+
+func (p *SourceProcessor[T]) drop() { p.items = p.items[:len(p.items)-1] }
+
+func (p *SourceProcessor[T]) pending() int { return len(p.items) }
+
+func (p *SourceProcessor[T]) take() func(buf *bytes.Buffer) error {
+	items := p.items
+	p.items = make([]typedItem[T], 0, p.BatchSize)
+	return func(buf *bytes.Buffer) (first error) {
+		for _, it := range items {
+			r, err := p.f(it.lineno, it.v)
+			if err != nil && p.OnError != nil {
+				r, err = nil, p.OnError(it.lineno, it.v, err)
+			}
+			if err != nil && first == nil {
+				first = err
+			}
+			buf.Write(r)
+		}
+		return first
+	}
+}
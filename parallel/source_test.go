@@ -0,0 +1,91 @@
+//go:build go1.18
+// +build go1.18
+
+package parallel
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// intSource yields numbers up to n.
+type intSource struct {
+	i, n int
+}
+
+func (s *intSource) Next() (*int, error) {
+	if s.i == s.n {
+		return nil, io.EOF
+	}
+	s.i++
+	v := s.i
+	return &v, nil
+}
+
+func TestSourceProcessor(t *testing.T) {
+	var (
+		buf, failed bytes.Buffer
+		mu          sync.Mutex
+	)
+	p := NewSourceProcessor[*int](&intSource{n: 5}, &buf, func(_ int64, v *int) ([]byte, error) {
+		if *v%2 == 0 {
+			return nil, errFake1
+		}
+		return []byte(fmt.Sprintf("%d\n", *v)), nil
+	})
+	p.SetErrorHandler(func(_ int64, v *int, err error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(&failed, "%d\n", *v)
+		return nil
+	})
+	p.PreserveOrder = true
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "1\n3\n5\n" {
+		t.Errorf("Run: got %q", buf.String())
+	}
+	if failed.String() != "2\n4\n" {
+		t.Errorf("Run: failed: got %q", failed.String())
+	}
+}
+
+// valueSource yields n numbers, starting at 1001, as values.
+type valueSource struct {
+	i, n int
+}
+
+func (s *valueSource) Next() (int, error) {
+	if s.i == s.n {
+		return 0, io.EOF
+	}
+	s.i++
+	return s.i + 1000, nil
+}
+
+func TestSourceProcessorNoBoxing(t *testing.T) {
+	const n = 10000
+	var sum int64
+	allocs := testing.AllocsPerRun(1, func() {
+		atomic.StoreInt64(&sum, 0)
+		p := NewSourceProcessor[int](&valueSource{n: n}, io.Discard, func(_ int64, v int) ([]byte, error) {
+			atomic.AddInt64(&sum, int64(v))
+			return nil, nil
+		})
+		p.NumWorkers = 2
+		if err := p.Run(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > n/10 {
+		t.Errorf("got %v allocations for %d items, items are boxed", allocs, n)
+	}
+	if want := int64(n*(n+1)/2 + n*1000); sum != want {
+		t.Errorf("got sum %d, want %d", sum, want)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/miku/span"
+	"github.com/miku/span/doi"
 	"github.com/miku/span/formats/finc"
 )
 
@@ -47,6 +48,8 @@ var (
 	ErrLongAuthorName              = errors.New("long author name")
 	ErrPageZero                    = errors.New("page is zero")
 	ErrTitleTooLong                = errors.New("title too long")
+	ErrInvalidDOI                  = errors.New("invalid DOI")
+	ErrUnresolvedDOI               = errors.New("DOI does not resolve")
 
 	// currencyPattern is a rather narrow pattern:
 	// http://rubular.com/r/WjcnjhckZq, used by NoCurrencyInTitle
@@ -77,6 +80,7 @@ var TestSuite = []Tester{
 	TesterFunc(TestPublisher),
 	TesterFunc(TestFeasibleAuthor),
 	TesterFunc(TestRepeatedSlashInDOI),
+	TesterFunc(TestDOI),
 	TesterFunc(TestHasURL),
 	TesterFunc(TestCanonicalISSN),
 	TesterFunc(TestTitleTooLong),
@@ -143,7 +147,7 @@ func TestFincStageTwo(is finc.IntermediateSchema) error {
 		return err
 	}
 	if len(is.Authors) == 0 || is.Volume == "" || is.Issue == "" ||
-		is.Pages == "" || is.StartPage == "" || is.EndPage == "" {
+		is.Pages.Raw == "" || is.Pages.Start == "" || is.Pages.End == "" {
 		return Issue{Err: fmt.Errorf("stage two fail"), Record: is}
 	}
 	return nil
@@ -204,15 +208,15 @@ func TestPageCount(is finc.IntermediateSchema) error {
 		maxPageDigits = 6
 		maxPageCount  = 20000
 	)
-	if len(is.StartPage) > maxPageDigits {
+	if len(is.Pages.Start) > maxPageDigits {
 		return Issue{Err: ErrInvalidStartPage, Record: is}
 	}
-	if len(is.EndPage) > maxPageDigits {
+	if len(is.Pages.End) > maxPageDigits {
 		return Issue{Err: ErrInvalidEndPage, Record: is}
 	}
-	if is.StartPage != "" && is.EndPage != "" {
-		if s, err := strconv.Atoi(is.StartPage); err == nil {
-			if e, err := strconv.Atoi(is.EndPage); err == nil {
+	if is.Pages.Start != "" && is.Pages.End != "" {
+		if s, err := strconv.Atoi(is.Pages.Start); err == nil {
+			if e, err := strconv.Atoi(is.Pages.End); err == nil {
 				if e < s {
 					return Issue{Err: ErrEndPageBeforeStartPage, Record: is}
 				}
@@ -325,6 +329,42 @@ func TestRepeatedSlashInDOI(is finc.IntermediateSchema) error {
 	return nil
 }
 
+// TestDOI checks the syntax of a DOI, if there is one.
+func TestDOI(is finc.IntermediateSchema) error {
+	if is.DOI == "" {
+		return nil
+	}
+	if _, err := doi.Normalize(is.DOI); err != nil {
+		return Issue{Err: ErrInvalidDOI, Record: is}
+	}
+	return nil
+}
+
+// DOIResolves checks, whether the DOI of a record is registered. This
+// requires a request per record.
+type DOIResolves struct {
+	Resolver *doi.Resolver
+}
+
+// TestRecord fails for records with a DOI, that does not resolve. Network
+// errors are reported as is.
+func (t DOIResolves) TestRecord(is finc.IntermediateSchema) error {
+	if is.DOI == "" {
+		return nil
+	}
+	ok, err := t.Resolver.Resolves(is.DOI)
+	if err == doi.ErrInvalid {
+		return Issue{Err: ErrInvalidDOI, Record: is}
+	}
+	if err != nil {
+		return Issue{Err: err, Record: is}
+	}
+	if !ok {
+		return Issue{Err: ErrUnresolvedDOI, Record: is}
+	}
+	return nil
+}
+
 // TestHasURL checks for a value in URL. This is no URL validation.
 func TestHasURL(is finc.IntermediateSchema) error {
 	if len(is.URL) == 0 {
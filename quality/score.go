@@ -0,0 +1,182 @@
+package quality
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/miku/span/formats/finc"
+)
+
+var (
+	// MaxAuthors is the number of authors, above which an author list is
+	// suspicious, e.g. a list of all conference participants.
+	MaxAuthors = 100
+	// FutureSlack allows publication dates slightly in the future, as issues
+	// are often dated ahead.
+	FutureSlack = 180 * 24 * time.Hour
+
+	ErrAllCapsTitle         = errors.New("all caps title")
+	ErrPlaceholderTitle     = errors.New("placeholder title")
+	ErrTooManyAuthors       = errors.New("too many authors")
+	ErrFutureDate           = errors.New("publication date in the future")
+	ErrAbstractRepeatsTitle = errors.New("abstract repeats title")
+
+	// placeholderTitles are normalized titles, that carry no information.
+	placeholderTitles = map[string]bool{
+		"untitled": true, "no title": true, "title": true, "notitle": true,
+		"na": true, "n a": true, "none": true, "null": true, "nil": true,
+		"test": true, "tbd": true, "tba": true, "unknown": true,
+		"ohne titel": true, "o t": true, "kein titel": true, "sans titre": true,
+	}
+	nonWord = regexp.MustCompile(`[^\pL\pN]+`)
+
+	// now is used for future dates, replaceable in tests.
+	now = time.Now
+)
+
+// Heuristic flags a suspicious record. The weight is subtracted from the
+// score of a flagged record.
+type Heuristic struct {
+	Name   string
+	Weight float64
+	Tester Tester
+}
+
+// Heuristics are checks for records, that are valid, but probably not useful.
+var Heuristics = []Heuristic{
+	{"all-caps-title", 0.2, TesterFunc(TestAllCapsTitle)},
+	{"placeholder-title", 0.5, TesterFunc(TestPlaceholderTitle)},
+	{"too-many-authors", 0.2, TesterFunc(TestAuthorCount)},
+	{"future-date", 0.3, TesterFunc(TestFutureDate)},
+	{"abstract-repeats-title", 0.2, TesterFunc(TestAbstractRepeatsTitle)},
+}
+
+// normalize lowercases and collapses everything but letters and digits.
+func normalize(s string) string {
+	return strings.TrimSpace(nonWord.ReplaceAllString(strings.ToLower(s), " "))
+}
+
+// TestAllCapsTitle flags titles without lowercase letters. Short titles,
+// which are often acronyms, are ignored.
+func TestAllCapsTitle(is finc.IntermediateSchema) error {
+	var letters int
+	for _, r := range is.ArticleTitle {
+		if unicode.IsLower(r) {
+			return nil
+		}
+		if unicode.IsUpper(r) {
+			letters++
+		}
+	}
+	if letters < 10 {
+		return nil
+	}
+	return Issue{Err: ErrAllCapsTitle, Record: is}
+}
+
+// TestPlaceholderTitle flags titles like "Untitled" or "[n/a]".
+func TestPlaceholderTitle(is finc.IntermediateSchema) error {
+	if is.ArticleTitle == "" {
+		return nil
+	}
+	if t := normalize(is.ArticleTitle); t == "" || placeholderTitles[t] {
+		return Issue{Err: ErrPlaceholderTitle, Record: is}
+	}
+	return nil
+}
+
+// TestAuthorCount flags author lists longer than MaxAuthors.
+func TestAuthorCount(is finc.IntermediateSchema) error {
+	if len(is.Authors) > MaxAuthors {
+		return Issue{Err: ErrTooManyAuthors, Record: is}
+	}
+	return nil
+}
+
+// TestFutureDate flags publication dates in the future, with some slack.
+func TestFutureDate(is finc.IntermediateSchema) error {
+	if is.Date.After(now().Add(FutureSlack)) {
+		return Issue{Err: ErrFutureDate, Record: is}
+	}
+	return nil
+}
+
+// TestAbstractRepeatsTitle flags abstracts, that are just the title, maybe
+// with subtitle or an "Abstract" label.
+func TestAbstractRepeatsTitle(is finc.IntermediateSchema) error {
+	abstract := strings.TrimPrefix(normalize(is.Abstract), "abstract ")
+	if abstract == "" || is.ArticleTitle == "" {
+		return nil
+	}
+	title := normalize(is.ArticleTitle)
+	if abstract == title || abstract == normalize(is.ArticleTitle+" "+is.ArticleSubtitle) {
+		return Issue{Err: ErrAbstractRepeatsTitle, Record: is}
+	}
+	return nil
+}
+
+// Assessment is the quality score of a record, between 0 and 1, along with
+// the names of the heuristics, that flagged it.
+type Assessment struct {
+	ID       string   `json:"id"`
+	SourceID string   `json:"sid"`
+	Score    float64  `json:"score"`
+	Flags    []string `json:"flags,omitempty"`
+}
+
+// Assess runs all heuristics on a record.
+func Assess(is finc.IntermediateSchema) Assessment {
+	a := Assessment{ID: is.ID, SourceID: is.SourceID, Score: 1}
+	for _, h := range Heuristics {
+		if err := h.Tester.TestRecord(is); err != nil {
+			a.Flags = append(a.Flags, h.Name)
+			a.Score -= h.Weight
+		}
+	}
+	if a.Score < 0 {
+		a.Score = 0
+	}
+	return a
+}
+
+// SourceScore summarizes assessments of a source.
+type SourceScore struct {
+	Records      int64            `json:"records"`
+	Flagged      int64            `json:"flagged"`
+	AverageScore float64          `json:"avg_score"`
+	Flags        map[string]int64 `json:"flags,omitempty"`
+}
+
+// ScoreSummary summarizes assessments per source, safe for concurrent use.
+type ScoreSummary struct {
+	mu      sync.Mutex
+	Sources map[string]*SourceScore `json:"sources"`
+}
+
+// NewScoreSummary creates an empty summary.
+func NewScoreSummary() *ScoreSummary {
+	return &ScoreSummary{Sources: make(map[string]*SourceScore)}
+}
+
+// Add adds an assessment to the summary.
+func (s *ScoreSummary) Add(a Assessment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ss := s.Sources[a.SourceID]
+	if ss == nil {
+		ss = &SourceScore{Flags: make(map[string]int64)}
+		s.Sources[a.SourceID] = ss
+	}
+	ss.AverageScore = (ss.AverageScore*float64(ss.Records) + a.Score) / float64(ss.Records+1)
+	ss.Records++
+	if len(a.Flags) > 0 {
+		ss.Flagged++
+	}
+	for _, f := range a.Flags {
+		ss.Flags[f]++
+	}
+}
@@ -0,0 +1,64 @@
+package quality
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miku/span/formats/finc"
+)
+
+func TestAssess(t *testing.T) {
+	now = func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	var cases = []struct {
+		about string
+		is    finc.IntermediateSchema
+		flags []string
+		score float64
+	}{
+		{"ok", finc.IntermediateSchema{ArticleTitle: "On Growth and Form",
+			Abstract: "A book about form.", Date: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)}, nil, 1},
+		{"acronym", finc.IntermediateSchema{ArticleTitle: "DNA"}, nil, 1},
+		{"all caps", finc.IntermediateSchema{ArticleTitle: "ON GROWTH AND FORM"}, []string{"all-caps-title"}, 0.8},
+		{"placeholder", finc.IntermediateSchema{ArticleTitle: "[Untitled]"}, []string{"placeholder-title"}, 0.5},
+		{"n/a", finc.IntermediateSchema{ArticleTitle: "n/a"}, []string{"placeholder-title"}, 0.5},
+		{"authors", finc.IntermediateSchema{ArticleTitle: "Atlas",
+			Authors: make([]finc.Author, 500)}, []string{"too-many-authors"}, 0.8},
+		{"future", finc.IntermediateSchema{ArticleTitle: "Later",
+			Date: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}, []string{"future-date"}, 0.7},
+		{"ahead of print", finc.IntermediateSchema{ArticleTitle: "Soon",
+			Date: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)}, nil, 1},
+		{"abstract", finc.IntermediateSchema{ArticleTitle: "On Growth and Form",
+			Abstract: "Abstract: On growth and form."}, []string{"abstract-repeats-title"}, 0.8},
+		{"everything", finc.IntermediateSchema{ArticleTitle: "UNTITLED DOCUMENT", Abstract: "untitled document",
+			Authors: make([]finc.Author, 101), Date: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+			[]string{"all-caps-title", "too-many-authors", "future-date", "abstract-repeats-title"}, 0.1},
+	}
+	for _, c := range cases {
+		a := Assess(c.is)
+		if !reflect.DeepEqual(a.Flags, c.flags) {
+			t.Errorf("%s: got %v, want %v", c.about, a.Flags, c.flags)
+		}
+		if d := a.Score - c.score; d > 1e-9 || d < -1e-9 {
+			t.Errorf("%s: got score %v, want %v", c.about, a.Score, c.score)
+		}
+	}
+	if a := Assess(finc.IntermediateSchema{ArticleTitle: strings.Repeat("X", 20),
+		Abstract: strings.Repeat("x", 20), Authors: make([]finc.Author, 101),
+		Date: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}); a.Score < 0 || a.Score > 0.1+1e-9 {
+		t.Errorf("got score %v, want between 0 and 0.1", a.Score)
+	}
+}
+
+func TestScoreSummary(t *testing.T) {
+	s := NewScoreSummary()
+	s.Add(Assessment{SourceID: "49", Score: 1})
+	s.Add(Assessment{SourceID: "49", Score: 0.5, Flags: []string{"placeholder-title"}})
+	ss := s.Sources["49"]
+	if ss.Records != 2 || ss.Flagged != 1 || ss.AverageScore != 0.75 || ss.Flags["placeholder-title"] != 1 {
+		t.Errorf("got %+v", ss)
+	}
+}
@@ -0,0 +1,121 @@
+package span
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// FormatKind decides, how input of a format is split into records.
+type FormatKind int
+
+const (
+	// JSONFormat records are newline delimited JSON.
+	JSONFormat FormatKind = iota
+	// XMLFormat records are XML elements, read as a stream.
+	XMLFormat
+	// TextFormat input is a single record, read with UnmarshalText.
+	TextFormat
+)
+
+func (k FormatKind) String() string {
+	switch k {
+	case XMLFormat:
+		return "xml"
+	case TextFormat:
+		return "text"
+	default:
+		return "json"
+	}
+}
+
+// Format is a registered input format.
+type Format struct {
+	Name string
+	Kind FormatKind
+	// New returns a new record, which can be converted to an intermediate
+	// schema.
+	New func() interface{}
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = make(map[string]Format)
+)
+
+// RegisterFormat makes an input format available by name, e.g. for
+// span-import -i. Formats maintained elsewhere register in an init function
+// and are compiled in with a blank import. The factory must return a pointer
+// to a new record with a ToIntermediateSchema method. The kind of format is
+// derived from the record: TextFormat, if it implements
+// encoding.TextUnmarshaler, XMLFormat, if it has an XMLName field or xml, but
+// no json struct tags, JSONFormat otherwise. Panics, if a name is registered
+// twice or the record cannot be converted, like database/sql.Register.
+func RegisterFormat(name string, factory func() interface{}) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	if factory == nil {
+		panic("span: RegisterFormat factory is nil")
+	}
+	if _, dup := formats[name]; dup {
+		panic("span: RegisterFormat called twice for format " + name)
+	}
+	v := factory()
+	if !reflect.ValueOf(v).MethodByName("ToIntermediateSchema").IsValid() {
+		panic(fmt.Sprintf("span: format %s: %T has no ToIntermediateSchema method", name, v))
+	}
+	formats[name] = Format{Name: name, Kind: formatKind(v), New: factory}
+}
+
+// LookupFormat returns a registered format by name.
+func LookupFormat(name string) (Format, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	f, ok := formats[name]
+	return f, ok
+}
+
+// FormatNames returns the names of all registered formats, sorted.
+func FormatNames() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	var names []string
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// formatKind derives the kind of format from a record.
+func formatKind(v interface{}) FormatKind {
+	if _, ok := v.(encoding.TextUnmarshaler); ok {
+		return TextFormat
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return JSONFormat
+	}
+	if _, ok := t.FieldByName("XMLName"); ok {
+		return XMLFormat
+	}
+	var hasXML, hasJSON bool
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag
+		if _, ok := tag.Lookup("xml"); ok {
+			hasXML = true
+		}
+		if _, ok := tag.Lookup("json"); ok {
+			hasJSON = true
+		}
+	}
+	if hasXML && !hasJSON {
+		return XMLFormat
+	}
+	return JSONFormat
+}
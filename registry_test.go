@@ -0,0 +1,80 @@
+package span
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+type jsonRecord struct {
+	Title string `json:"title"`
+}
+
+func (r *jsonRecord) ToIntermediateSchema() (interface{}, error) { return nil, nil }
+
+type xmlRecord struct {
+	XMLName xml.Name `xml:"record"`
+}
+
+func (r *xmlRecord) ToIntermediateSchema() (interface{}, error) { return nil, nil }
+
+type taggedRecord struct {
+	Title string `xml:"Title"`
+}
+
+func (r *taggedRecord) ToIntermediateSchema() (interface{}, error) { return nil, nil }
+
+type textRecord struct{}
+
+func (r *textRecord) UnmarshalText(b []byte) error               { return nil }
+func (r *textRecord) ToIntermediateSchema() (interface{}, error) { return nil, nil }
+
+func TestRegisterFormat(t *testing.T) {
+	var cases = []struct {
+		name    string
+		factory func() interface{}
+		kind    FormatKind
+	}{
+		{"test-json", func() interface{} { return new(jsonRecord) }, JSONFormat},
+		{"test-xml", func() interface{} { return new(xmlRecord) }, XMLFormat},
+		{"test-xml-tags", func() interface{} { return new(taggedRecord) }, XMLFormat},
+		{"test-text", func() interface{} { return new(textRecord) }, TextFormat},
+	}
+	for _, c := range cases {
+		RegisterFormat(c.name, c.factory)
+		f, ok := LookupFormat(c.name)
+		if !ok {
+			t.Fatalf("%s: not registered", c.name)
+		}
+		if f.Kind != c.kind {
+			t.Errorf("%s: got %v, want %v", c.name, f.Kind, c.kind)
+		}
+	}
+	if _, ok := LookupFormat("test-unknown"); ok {
+		t.Errorf("unknown format found")
+	}
+	names := FormatNames()
+	want := []string{"test-json", "test-text", "test-xml", "test-xml-tags"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("FormatNames: got %v, want %v", names, want)
+	}
+
+	for _, c := range []struct {
+		about   string
+		name    string
+		factory func() interface{}
+	}{
+		{"duplicate", "test-json", func() interface{} { return new(jsonRecord) }},
+		{"not convertible", "test-struct", func() interface{} { return new(struct{}) }},
+		{"nil factory", "test-nil", nil},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", c.about)
+				}
+			}()
+			RegisterFormat(c.name, c.factory)
+		}()
+	}
+}
@@ -6,12 +6,13 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 
-	"github.com/miku/span"
 	"github.com/sethgrid/pester"
+	log "github.com/sirupsen/logrus"
 	yaml "gopkg.in/yaml.v2"
+
+	"github.com/miku/span"
 )
 
 // ReviewConfig contains various index review cases and general configuration.
@@ -0,0 +1,385 @@
+package span
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultS3PartSize is the size of parts in multipart uploads. S3 requires
+// at least 5MB for all but the last part.
+const DefaultS3PartSize = 16 << 20
+
+// S3Config holds endpoint and credentials for S3 compatible object storage,
+// e.g. MinIO. Buckets are addressed path-style.
+type S3Config struct {
+	Endpoint  string // e.g. https://minio.example.com
+	Region    string
+	AccessKey string
+	SecretKey string
+	Client    *http.Client
+}
+
+// S3ConfigFromEnv reads configuration from S3_ENDPOINT, AWS_REGION,
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+func S3ConfigFromEnv() S3Config {
+	c := S3Config{
+		Endpoint:  os.Getenv("S3_ENDPOINT"),
+		Region:    os.Getenv("AWS_REGION"),
+		AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = "https://s3.amazonaws.com"
+	}
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+	return c
+}
+
+// ParseS3URL splits a s3://bucket/key URL into bucket and key.
+func ParseS3URL(s string) (bucket, key string, err error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Scheme != "s3" || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return "", "", fmt.Errorf("invalid s3 url, want s3://bucket/key: %s", s)
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+// hmacSHA256 returns the HMAC of data with a given key.
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sha256Hex returns the hex encoded SHA256 of b.
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// signingKey derives a signature version 4 signing key.
+func signingKey(secret, date, region, service string) []byte {
+	k := hmacSHA256([]byte("AWS4"+secret), date)
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	return hmacSHA256(k, "aws4_request")
+}
+
+// uriEncode encodes a string as required by signature version 4.
+func uriEncode(s string, encodeSlash bool) string {
+	var buf bytes.Buffer
+	for _, b := range []byte(s) {
+		switch {
+		case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9',
+			b == '-', b == '_', b == '.', b == '~':
+			buf.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			buf.WriteByte(b)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", b)
+		}
+	}
+	return buf.String()
+}
+
+// newRequest creates a signed request for a bucket and key.
+func (c S3Config) newRequest(method, bucket, key string, query url.Values, body []byte) (*http.Request, error) {
+	path := "/" + uriEncode(bucket, true) + "/" + uriEncode(key, false)
+	var keys []string
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, k := range keys {
+		params = append(params, uriEncode(k, true)+"="+uriEncode(query.Get(k), true))
+	}
+	canonicalQuery := strings.Join(params, "&")
+	link := strings.TrimSuffix(c.Endpoint, "/") + path
+	if canonicalQuery != "" {
+		link += "?" + canonicalQuery
+	}
+	req, err := http.NewRequest(method, link, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	amzDate, date := now.Format("20060102T150405Z"), now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		canonicalQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{date, c.Region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+	signature := hex.EncodeToString(hmacSHA256(signingKey(c.SecretKey, date, c.Region, "s3"), stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKey, scope, signedHeaders, signature))
+	return req, nil
+}
+
+// do performs a signed request and returns the response, if successful.
+func (c S3Config) do(method, bucket, key string, query url.Values, body []byte) (*http.Response, error) {
+	req, err := c.newRequest(method, bucket, key, query, body)
+	if err != nil {
+		return nil, err
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("s3: %s s3://%s/%s failed with: %s %s", method, bucket, key, resp.Status, b)
+	}
+	return resp, nil
+}
+
+// Open returns the content of an object, given as s3://bucket/key.
+func (c S3Config) Open(link string) (io.ReadCloser, error) {
+	bucket, key, err := ParseS3URL(link)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do("GET", bucket, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Create returns a writer for an object, given as s3://bucket/key. The object
+// is only visible after a successful Close.
+func (c S3Config) Create(link string) (*S3Writer, error) {
+	bucket, key, err := ParseS3URL(link)
+	if err != nil {
+		return nil, err
+	}
+	return &S3Writer{Config: c, Bucket: bucket, Key: key, PartSize: DefaultS3PartSize}, nil
+}
+
+// S3Writer uploads to an object. Small objects are uploaded with a single
+// request, larger ones with a multipart upload.
+type S3Writer struct {
+	Config   S3Config
+	Bucket   string
+	Key      string
+	PartSize int
+
+	buf      bytes.Buffer
+	uploadID string
+	etags    []string
+	err      error
+}
+
+// Write buffers data and uploads a part, whenever enough data is collected.
+func (w *S3Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, _ := w.buf.Write(p)
+	for w.buf.Len() >= w.PartSize {
+		if w.err = w.uploadPart(w.buf.Next(w.PartSize)); w.err != nil {
+			w.abort()
+			return n, w.err
+		}
+	}
+	return n, nil
+}
+
+// uploadPart uploads a single part, starting a multipart upload, if necessary.
+func (w *S3Writer) uploadPart(b []byte) error {
+	if w.uploadID == "" {
+		resp, err := w.Config.do("POST", w.Bucket, w.Key, url.Values{"uploads": {""}}, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		var result struct {
+			UploadID string `xml:"UploadId"`
+		}
+		if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return err
+		}
+		w.uploadID = result.UploadID
+	}
+	query := url.Values{
+		"partNumber": {fmt.Sprintf("%d", len(w.etags)+1)},
+		"uploadId":   {w.uploadID},
+	}
+	resp, err := w.Config.do("PUT", w.Bucket, w.Key, query, b)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	w.etags = append(w.etags, resp.Header.Get("ETag"))
+	return nil
+}
+
+// abort cancels a multipart upload, errors are ignored.
+func (w *S3Writer) abort() {
+	if w.uploadID == "" {
+		return
+	}
+	if resp, err := w.Config.do("DELETE", w.Bucket, w.Key, url.Values{"uploadId": {w.uploadID}}, nil); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// Close uploads remaining data and completes the upload.
+func (w *S3Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.uploadID == "" {
+		resp, err := w.Config.do("PUT", w.Bucket, w.Key, nil, w.buf.Bytes())
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+	if w.buf.Len() > 0 {
+		if err := w.uploadPart(w.buf.Bytes()); err != nil {
+			w.abort()
+			return err
+		}
+	}
+	var complete bytes.Buffer
+	complete.WriteString("<CompleteMultipartUpload>")
+	for i, etag := range w.etags {
+		fmt.Fprintf(&complete, "<Part><PartNumber>%d</PartNumber><ETag>", i+1)
+		xml.EscapeText(&complete, []byte(etag))
+		complete.WriteString("</ETag></Part>")
+	}
+	complete.WriteString("</CompleteMultipartUpload>")
+	resp, err := w.Config.do("POST", w.Bucket, w.Key, url.Values{"uploadId": {w.uploadID}}, complete.Bytes())
+	if err != nil {
+		w.abort()
+		return err
+	}
+	defer resp.Body.Close()
+	// Complete may fail with status 200 and an error document.
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(b, []byte("<Error>")) {
+		return fmt.Errorf("s3: completing upload of s3://%s/%s failed: %s", w.Bucket, w.Key, b)
+	}
+	return nil
+}
+
+// isObjectLink returns true for links, that are not fetched via HTTP.
+func isObjectLink(link string) bool {
+	for _, prefix := range []string{"s3://", "ftp://", "sftp://"} {
+		if strings.HasPrefix(link, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Open opens a file, a http(s), ftp or sftp URL, a s3://bucket/key object or
+// a kafka://broker/topic for reading. Compressed content is decompressed,
+// except for kafka topics, which are read message by message. S3
+// configuration and credentials are read from the environment, see
+// S3ConfigFromEnv, OpenFTP, OpenSFTP and OpenKafka.
+func Open(name string) (io.ReadCloser, error) {
+	switch {
+	case strings.HasPrefix(name, "kafka://"):
+		return OpenKafka(name)
+	case strings.HasPrefix(name, "http://"), strings.HasPrefix(name, "https://"):
+		return nopCloser{&LinkReader{Link: name}}, nil
+	}
+	raw, err := openRaw(name)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := NewDecompressReader(raw)
+	if err != nil {
+		raw.Close()
+		return nil, err
+	}
+	return &decompressCloser{ReadCloser: rc, raw: raw}, nil
+}
+
+// openRaw opens a file, a ftp or sftp URL or a s3://bucket/key object and
+// returns its content as is.
+func openRaw(name string) (io.ReadCloser, error) {
+	switch {
+	case strings.HasPrefix(name, "s3://"):
+		return S3ConfigFromEnv().Open(name)
+	case strings.HasPrefix(name, "ftp://"):
+		return OpenFTP(name)
+	case strings.HasPrefix(name, "sftp://"):
+		return OpenSFTP(name)
+	default:
+		return os.Open(name)
+	}
+}
+
+// decompressCloser closes both the decompressor and the underlying reader.
+type decompressCloser struct {
+	io.ReadCloser
+	raw io.Closer
+}
+
+// Close closes the decompressor first, then the underlying reader.
+func (c *decompressCloser) Close() error {
+	err := c.ReadCloser.Close()
+	if cerr := c.raw.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Create creates a file, a s3://bucket/key object or a kafka://broker/topic
+// producer for writing. S3 configuration is read from the environment.
+func Create(name string) (io.WriteCloser, error) {
+	switch {
+	case strings.HasPrefix(name, "s3://"):
+		return S3ConfigFromEnv().Create(name)
+	case strings.HasPrefix(name, "kafka://"):
+		return CreateKafka(name)
+	default:
+		return os.Create(name)
+	}
+}
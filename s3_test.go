@@ -0,0 +1,196 @@
+package span
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSigningKey(t *testing.T) {
+	// Example from the AWS signature version 4 documentation.
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("signingKey: got %s, want %s", got, want)
+	}
+}
+
+func TestParseS3URL(t *testing.T) {
+	var tests = []struct {
+		in          string
+		bucket, key string
+		err         bool
+	}{
+		{"s3://b/k", "b", "k", false},
+		{"s3://b/a/b/c.ldj.gz", "b", "a/b/c.ldj.gz", false},
+		{"s3://b", "", "", true},
+		{"http://b/k", "", "", true},
+	}
+	for _, tt := range tests {
+		bucket, key, err := ParseS3URL(tt.in)
+		if (err != nil) != tt.err || bucket != tt.bucket || key != tt.key {
+			t.Errorf("ParseS3URL(%s): got %s %s %v", tt.in, bucket, key, err)
+		}
+	}
+}
+
+// fakeS3 stores objects and multipart uploads in memory.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]string
+	parts   map[string]string
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+		http.Error(w, "unsigned", http.StatusForbidden)
+		return
+	}
+	b, _ := ioutil.ReadAll(r.Body)
+	if r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(b) {
+		http.Error(w, "payload hash mismatch", http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	switch {
+	case r.Method == "GET":
+		v, ok := s.objects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, v)
+	case r.Method == "POST" && q.Get("uploadId") != "":
+		var keys []string
+		for k := range s.parts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var content []string
+		for _, k := range keys {
+			content = append(content, s.parts[k])
+		}
+		s.objects[r.URL.Path] = strings.Join(content, "")
+		fmt.Fprint(w, "<CompleteMultipartUploadResult/>")
+	case r.Method == "POST":
+		fmt.Fprint(w, "<InitiateMultipartUploadResult><UploadId>1</UploadId></InitiateMultipartUploadResult>")
+	case r.Method == "PUT" && q.Get("partNumber") != "":
+		s.parts[q.Get("partNumber")] = string(b)
+		w.Header().Set("ETag", `"`+q.Get("partNumber")+`"`)
+	case r.Method == "PUT":
+		s.objects[r.URL.Path] = string(b)
+	}
+}
+
+func TestS3(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string]string), parts: make(map[string]string)}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+	c := S3Config{Endpoint: ts.URL, Region: "us-east-1", AccessKey: "key", SecretKey: "secret"}
+
+	var tests = []struct {
+		link     string
+		content  string
+		partSize int
+	}{
+		{"s3://b/small.ldj", "hello\n", 16},
+		{"s3://b/large file.ldj", strings.Repeat("0123456789", 10), 16},
+	}
+	for _, tt := range tests {
+		w, err := c.Create(tt.link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.PartSize = tt.partSize
+		if _, err := w.Write([]byte(tt.content)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		rc, err := c.Open(tt.link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.content {
+			t.Errorf("%s: got %q, want %q", tt.link, string(b), tt.content)
+		}
+	}
+	if len(fake.parts) != 7 {
+		t.Errorf("multipart upload: got %d parts, want 7", len(fake.parts))
+	}
+	if _, err := c.Open("s3://b/missing"); err == nil {
+		t.Errorf("Open: want error for missing object")
+	}
+}
+
+func TestOpenDecompresses(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("hello\n"))
+	zw.Close()
+
+	dir, err := ioutil.TempDir("", "span-open-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string][]byte{
+		"plain.ldj":     []byte("hello\n"),
+		"packed.ldj.gz": gz.Bytes(),
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fake := &fakeS3{objects: map[string]string{"/b/packed.ldj.gz": gz.String()}}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+	for k, v := range map[string]string{
+		"S3_ENDPOINT":           ts.URL,
+		"AWS_ACCESS_KEY_ID":     "key",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+	} {
+		defer func(k, s string) { os.Setenv(k, s) }(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	for _, name := range []string{
+		filepath.Join(dir, "plain.ldj"),
+		filepath.Join(dir, "packed.ldj.gz"),
+		"s3://b/packed.ldj.gz",
+	} {
+		rc, err := Open(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		b, err := ioutil.ReadAll(rc)
+		if cerr := rc.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if string(b) != "hello\n" {
+			t.Errorf("%s: got %q, want %q", name, b, "hello\n")
+		}
+	}
+}
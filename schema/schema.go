@@ -0,0 +1,309 @@
+// Package schema validates intermediate schema records against the JSON
+// schema in this directory, e.g. is-1.0.json. Only the parts of JSON schema
+// (draft 4) used by the intermediate schema are implemented: type, required,
+// properties, additionalProperties, items, enum, pattern, format (date,
+// date-time, uri), minLength, maxLength and uniqueItems.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxSampleIDs is the number of offending record ids kept per violation.
+const MaxSampleIDs = 10
+
+// Schema is a JSON schema or subschema.
+type Schema struct {
+	Type                 string             `json:"type"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	Pattern              string             `json:"pattern"`
+	Format               string             `json:"format"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	UniqueItems          bool               `json:"uniqueItems"`
+
+	pattern *regexp.Regexp
+	enum    map[string]bool
+}
+
+// Violation is a single schema violation. Path is the property, e.g.
+// rft.issn, Rule the failing keyword, e.g. pattern.
+type Violation struct {
+	Path    string
+	Rule    string
+	Message string
+}
+
+// Key groups violations for counting, e.g. "rft.issn: pattern".
+func (v Violation) Key() string {
+	if v.Path == "" {
+		return v.Rule
+	}
+	return fmt.Sprintf("%s: %s", v.Path, v.Rule)
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("%s: %s", v.Key(), v.Message)
+}
+
+// Load reads a JSON schema and compiles patterns.
+func Load(r io.Reader) (*Schema, error) {
+	var s Schema
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// LoadFile reads a JSON schema from a file.
+func LoadFile(filename string) (*Schema, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return s, nil
+}
+
+// compile prepares patterns and enums of a schema and all subschemas.
+func (s *Schema) compile() (err error) {
+	if s.Pattern != "" {
+		if s.pattern, err = regexp.Compile(s.Pattern); err != nil {
+			return err
+		}
+	}
+	if len(s.Enum) > 0 {
+		s.enum = make(map[string]bool, len(s.Enum))
+		for _, v := range s.Enum {
+			s.enum[fmt.Sprintf("%v", v)] = true
+		}
+	}
+	for _, p := range s.Properties {
+		if err := p.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+	return nil
+}
+
+// ValidateBytes validates a single JSON document.
+func (s *Schema) ValidateBytes(b []byte) ([]Violation, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return s.Validate(doc), nil
+}
+
+// Validate validates a decoded JSON value, decoded with UseNumber.
+func (s *Schema) Validate(doc interface{}) []Violation {
+	return s.validate("", doc, nil)
+}
+
+// typeOf returns the JSON schema type of a decoded value.
+func typeOf(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if strings.ContainsAny(t.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "unknown"
+	}
+}
+
+// validFormat checks the formats used by the intermediate schema.
+func validFormat(format, s string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "")
+	default:
+		return true
+	}
+}
+
+func (s *Schema) validate(path string, v interface{}, vs []Violation) []Violation {
+	add := func(rule, format string, args ...interface{}) {
+		vs = append(vs, Violation{Path: path, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	t := typeOf(v)
+	if s.Type != "" && s.Type != t && !(s.Type == "number" && t == "integer") {
+		add("type", "want %s, got %s", s.Type, t)
+		return vs
+	}
+	if s.enum != nil && !s.enum[fmt.Sprintf("%v", v)] {
+		add("enum", "value not allowed: %v", v)
+	}
+	switch t {
+	case "string":
+		str := v.(string)
+		n := utf8.RuneCountInString(str)
+		if s.MinLength != nil && n < *s.MinLength {
+			add("minLength", "length %d, want at least %d", n, *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			add("maxLength", "length %d, want at most %d", n, *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(str) {
+			add("pattern", "%q does not match %s", str, s.Pattern)
+		}
+		if s.Format != "" && !validFormat(s.Format, str) {
+			add("format", "%q is not a valid %s", str, s.Format)
+		}
+	case "array":
+		items := v.([]interface{})
+		if s.UniqueItems {
+			seen := make(map[string]bool)
+			for _, item := range items {
+				k := fmt.Sprintf("%v", item)
+				if seen[k] {
+					add("uniqueItems", "duplicate item: %v", item)
+					break
+				}
+				seen[k] = true
+			}
+		}
+		if s.Items != nil {
+			for _, item := range items {
+				vs = s.Items.validate(path, item, vs)
+			}
+		}
+	case "object":
+		obj := v.(map[string]interface{})
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				vs = append(vs, Violation{Path: join(path, name), Rule: "required", Message: "missing"})
+			}
+		}
+		var names []string
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					vs = append(vs, Violation{Path: join(path, name), Rule: "additionalProperties", Message: "not allowed"})
+				}
+				continue
+			}
+			vs = p.validate(join(path, name), obj[name], vs)
+		}
+	}
+	return vs
+}
+
+// join appends a property name to a path.
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// Entry counts a violation and keeps a few offending record ids.
+type Entry struct {
+	Count int64    `json:"count"`
+	IDs   []string `json:"ids"`
+}
+
+// Report counts violations by key over many records, safe for concurrent use.
+type Report struct {
+	mu      sync.Mutex
+	Records int64             `json:"records"`
+	Invalid int64             `json:"invalid"`
+	Entries map[string]*Entry `json:"violations"`
+}
+
+// NewReport creates an empty report.
+func NewReport() *Report {
+	return &Report{Entries: make(map[string]*Entry)}
+}
+
+// Add records the violations of a record with a given id. Each violation key
+// is counted once per record.
+func (r *Report) Add(id string, vs []Violation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Records++
+	if len(vs) == 0 {
+		return
+	}
+	r.Invalid++
+	seen := make(map[string]bool)
+	for _, v := range vs {
+		key := v.Key()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		e := r.Entries[key]
+		if e == nil {
+			e = &Entry{}
+			r.Entries[key] = e
+		}
+		e.Count++
+		if len(e.IDs) < MaxSampleIDs {
+			e.IDs = append(e.IDs, id)
+		}
+	}
+}
+
+// MarshalJSON locks the report while encoding.
+func (r *Report) MarshalJSON() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return json.Marshal(struct {
+		Records int64             `json:"records"`
+		Invalid int64             `json:"invalid"`
+		Entries map[string]*Entry `json:"violations"`
+	}{r.Records, r.Invalid, r.Entries})
+}
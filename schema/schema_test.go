@@ -0,0 +1,85 @@
+package schema
+
+import (
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFixtures(t *testing.T) {
+	s, err := LoadFile("is-1.0.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, filename := range []string{"fixtures/1.0/crossref.is", "fixtures/1.0/jats.is"} {
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vs, err := s.ValidateBytes(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(vs) > 0 {
+			t.Errorf("%s: got %v, want no violations", filename, vs)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s, err := LoadFile("is-1.0.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases = []struct {
+		about string
+		doc   string
+		keys  []string
+	}{
+		{
+			"minimal",
+			`{"finc.mega_collection": ["A"], "finc.record_id": "1", "finc.source_id": "1",
+			  "languages": ["eng"], "rft.atitle": "T", "rft.genre": "article", "ris.type": "EJOUR"}`,
+			nil,
+		},
+		{
+			"broken",
+			`{"finc.mega_collection": "A", "finc.source_id": 1, "finc.id": "` + strings.Repeat("x", 251) + `",
+			  "languages": ["eng"], "rft.atitle": "T", "rft.genre": "paper", "ris.type": "EJOUR",
+			  "rft.date": "2019-13-01", "rft.issn": ["1234-567"], "url": ["www.example.com"], "extra": 1}`,
+			[]string{"extra: additionalProperties", "finc.id: maxLength", "finc.mega_collection: type",
+				"finc.record_id: required", "finc.source_id: type", "rft.date: format", "rft.genre: enum",
+				"rft.issn: pattern", "url: format"},
+		},
+	}
+	for _, c := range cases {
+		vs, err := s.ValidateBytes([]byte(c.doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for _, v := range vs {
+			keys = append(keys, v.Key())
+		}
+		sort.Strings(keys)
+		if !reflect.DeepEqual(keys, c.keys) {
+			t.Errorf("%s: got %v, want %v", c.about, keys, c.keys)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	r := NewReport()
+	r.Add("a", nil)
+	r.Add("b", []Violation{{Path: "url", Rule: "format"}, {Path: "url", Rule: "format"}})
+	r.Add("c", []Violation{{Path: "url", Rule: "format"}})
+	if r.Records != 3 || r.Invalid != 2 {
+		t.Errorf("got %d records, %d invalid, want 3, 2", r.Records, r.Invalid)
+	}
+	e := r.Entries["url: format"]
+	if e == nil || e.Count != 2 || !reflect.DeepEqual(e.IDs, []string{"b", "c"}) {
+		t.Errorf("got %+v", e)
+	}
+}
@@ -0,0 +1,46 @@
+package span
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// InterruptContext returns a context, that is canceled on SIGINT or SIGTERM,
+// so long running commands can stop reading input, flush partial output and
+// clean up. A second signal terminates the process immediately, until the
+// returned cancel function is called.
+func InterruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	// done is closed by the cancel function, so the goroutine does not wait
+	// for a second signal forever.
+	var (
+		done = make(chan struct{})
+		once sync.Once
+	)
+	go func() {
+		select {
+		case <-c:
+			cancel()
+		case <-ctx.Done():
+			signal.Stop(c)
+			return
+		}
+		select {
+		case <-c:
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+	return ctx, func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+		cancel()
+	}
+}
@@ -240,6 +240,12 @@ func (ix Index) SourceIdentifiers() (result []string, err error) {
 	return ix.FacetKeys("*:*", "source_id")
 }
 
+// SourceCounts returns the number of records per source identifier for a
+// query, e.g. institution:"DE-15".
+func (ix Index) SourceCounts(query string) (FacetMap, error) {
+	return ix.facets(query, "source_id")
+}
+
 // SourceCollections returns the collections for a given source identifier.
 func (ix Index) SourceCollections(sid string) (result []string, err error) {
 	return ix.FacetKeysFunc("source_id:"+sid, "mega_collection",
@@ -0,0 +1,235 @@
+package solrutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Updater defaults.
+const (
+	DefaultBatchSize      = 1000
+	DefaultNumConnections = 4
+	DefaultMaxRetries     = 5
+	DefaultRetryWait      = time.Second
+)
+
+// Updater posts documents to the JSON update handler of SOLR in batches, over
+// a number of parallel connections. It accepts newline delimited JSON
+// documents, as written by span-export, so it can replace a file and a
+// separate indexing step. Updater is not safe for concurrent use.
+type Updater struct {
+	// Server including core, e.g. http://localhost:8983/solr/biblio.
+	Server    string
+	BatchSize int
+	// CommitWithin, if set, asks SOLR to commit documents within this time.
+	CommitWithin   time.Duration
+	NumConnections int
+	// MaxRetries for batches rejected with HTTP 503 or 429 or failed with a
+	// network error; the wait between retries starts at RetryWait and doubles.
+	MaxRetries int
+	RetryWait  time.Duration
+	// Commit sends a hard commit on Close.
+	Commit bool
+	Client *http.Client
+
+	once    sync.Once
+	batches chan batch
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	err     error
+	line    []byte
+	buf     bytes.Buffer
+	n       int
+	indexed int64
+}
+
+// batch is a JSON array of documents.
+type batch struct {
+	body []byte
+	size int
+}
+
+// NewUpdater creates an updater with default settings for a SOLR server.
+func NewUpdater(server string) *Updater {
+	return &Updater{
+		Server:         PrependHTTP(server),
+		BatchSize:      DefaultBatchSize,
+		NumConnections: DefaultNumConnections,
+		MaxRetries:     DefaultMaxRetries,
+		RetryWait:      DefaultRetryWait,
+		Client:         http.DefaultClient,
+	}
+}
+
+// start launches the workers.
+func (u *Updater) start() {
+	if u.BatchSize < 1 {
+		u.BatchSize = DefaultBatchSize
+	}
+	if u.NumConnections < 1 {
+		u.NumConnections = 1
+	}
+	if u.Client == nil {
+		u.Client = http.DefaultClient
+	}
+	u.batches = make(chan batch)
+	for i := 0; i < u.NumConnections; i++ {
+		u.wg.Add(1)
+		go func() {
+			defer u.wg.Done()
+			for b := range u.batches {
+				if u.Err() != nil {
+					continue
+				}
+				if err := u.post(u.updateLink(), b.body); err != nil {
+					u.setErr(err)
+					continue
+				}
+				atomic.AddInt64(&u.indexed, int64(b.size))
+			}
+		}()
+	}
+}
+
+// Err returns the first error, that occurred while sending batches.
+func (u *Updater) Err() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.err
+}
+
+func (u *Updater) setErr(err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.err == nil {
+		u.err = err
+	}
+}
+
+// Indexed returns the number of documents accepted by SOLR.
+func (u *Updater) Indexed() int64 {
+	return atomic.LoadInt64(&u.indexed)
+}
+
+// Write buffers documents, one per line, and sends full batches.
+func (u *Updater) Write(p []byte) (int, error) {
+	u.once.Do(u.start)
+	if err := u.Err(); err != nil {
+		return 0, err
+	}
+	u.line = append(u.line, p...)
+	for {
+		i := bytes.IndexByte(u.line, '\n')
+		if i < 0 {
+			break
+		}
+		u.add(u.line[:i])
+		u.line = u.line[i+1:]
+	}
+	return len(p), nil
+}
+
+// add adds a document to the current batch.
+func (u *Updater) add(doc []byte) {
+	doc = bytes.TrimSpace(doc)
+	if len(doc) == 0 {
+		return
+	}
+	if u.n == 0 {
+		u.buf.WriteByte('[')
+	} else {
+		u.buf.WriteByte(',')
+	}
+	u.buf.Write(doc)
+	u.n++
+	if u.n >= u.BatchSize {
+		u.flush()
+	}
+}
+
+// flush sends the current batch to a worker.
+func (u *Updater) flush() {
+	if u.n == 0 {
+		return
+	}
+	u.buf.WriteByte(']')
+	b := batch{body: make([]byte, u.buf.Len()), size: u.n}
+	copy(b.body, u.buf.Bytes())
+	u.buf.Reset()
+	u.n = 0
+	u.batches <- b
+}
+
+// Close sends remaining documents, waits for all batches and commits, if
+// requested.
+func (u *Updater) Close() error {
+	u.once.Do(u.start)
+	u.add(u.line)
+	u.line = nil
+	u.flush()
+	close(u.batches)
+	u.wg.Wait()
+	if err := u.Err(); err != nil {
+		return err
+	}
+	if u.Commit {
+		return u.post(u.Server+"/update?commit=true&wt=json", []byte(`{"commit": {}}`))
+	}
+	return nil
+}
+
+// updateLink returns the update handler link.
+func (u *Updater) updateLink() string {
+	vals := url.Values{}
+	vals.Add("wt", "json")
+	if u.CommitWithin > 0 {
+		vals.Add("commitWithin", fmt.Sprintf("%d", u.CommitWithin/time.Millisecond))
+	}
+	return fmt.Sprintf("%s/update?%s", u.Server, vals.Encode())
+}
+
+// post sends a request body, retrying on temporary failures.
+func (u *Updater) post(link string, body []byte) error {
+	wait := u.RetryWait
+	for attempt := 0; ; attempt++ {
+		err := u.postOnce(link, body)
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(permanentError); ok || attempt >= u.MaxRetries {
+			return err
+		}
+		log.Warnf("%v, retrying in %s", err, wait)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// permanentError is an update failure, that is not retried.
+type permanentError struct{ error }
+
+func (u *Updater) postOnce(link string, body []byte) error {
+	resp, err := u.Client.Post(link, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("solr update failed with HTTP %d", resp.StatusCode)
+	default:
+		return permanentError{fmt.Errorf("solr update failed with HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(msg))}
+	}
+}
@@ -0,0 +1,93 @@
+package solrutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdater(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+		docs     int
+		commits  int
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Query().Get("commit") == "true" {
+			commits++
+			return
+		}
+		if v := r.URL.Query().Get("commitWithin"); v != "10000" {
+			t.Errorf("commitWithin: got %q, want 10000", v)
+		}
+		var batch []map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("invalid batch: %v", err)
+		}
+		docs += len(batch)
+	}))
+	defer ts.Close()
+
+	u := NewUpdater(ts.URL)
+	u.BatchSize = 3
+	u.NumConnections = 2
+	u.CommitWithin = 10 * time.Second
+	u.RetryWait = time.Millisecond
+	u.Commit = true
+
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf(`{"id": "%d"}`, i))
+	}
+	// Write in chunks, that do not align with lines.
+	s := strings.Join(lines, "\n")
+	for len(s) > 0 {
+		n := 7
+		if n > len(s) {
+			n = len(s)
+		}
+		if _, err := u.Write([]byte(s[:n])); err != nil {
+			t.Fatal(err)
+		}
+		s = s[n:]
+	}
+	if err := u.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if docs != 10 || u.Indexed() != 10 {
+		t.Errorf("got %d docs, %d indexed, want 10", docs, u.Indexed())
+	}
+	if commits != 1 {
+		t.Errorf("got %d commits, want 1", commits)
+	}
+}
+
+func TestUpdaterError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad document", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	u := NewUpdater(ts.URL)
+	u.RetryWait = time.Millisecond
+	if _, err := u.Write([]byte(`{"id": "1"}` + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	err := u.Close()
+	if err == nil || !strings.Contains(err.Error(), "HTTP 400") {
+		t.Errorf("got %v, want HTTP 400 error", err)
+	}
+}
@@ -0,0 +1,163 @@
+// Package sourceconf loads per source settings from a YAML file, overriding
+// values compiled into the format packages, so operational changes do not
+// require a new release. Settings are keyed by input format name, as given to
+// span-import -i:
+//
+//	crossref:
+//	  source_id: "49"
+//	  collections: ["Crossref"]
+//	  languages: ["eng", "deu"]
+//	  batch_size: 20000
+//	  cleanup: true
+//	genios:
+//	  id_prefix: "ai"
+//	  format: "ElectronicArticle"
+//
+// Unset values keep the builtin behaviour.
+package sourceconf
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/miku/span"
+	"github.com/miku/span/container"
+	"github.com/miku/span/formats/finc"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Source contains overrides for a single input format.
+type Source struct {
+	// SourceID replaces the source id, in the record id as well.
+	SourceID string `yaml:"source_id"`
+	// IDPrefix replaces the ai prefix of record ids.
+	IDPrefix string `yaml:"id_prefix"`
+	// Collections replace all collections of a record.
+	Collections []string `yaml:"collections"`
+	Format      string   `yaml:"format"`
+	Genre       string   `yaml:"genre"`
+	RefType     string   `yaml:"reftype"`
+	// Languages are the accepted languages as ISO 639-3 codes, others are
+	// removed from records.
+	Languages []string `yaml:"languages"`
+	// BatchSize is the number of records converted per batch.
+	BatchSize int `yaml:"batch_size"`
+	// BatchMB limits the input size of a batch in megabytes, for formats
+	// with huge records.
+	BatchMB int `yaml:"batch_mb"`
+	// Cleanup enables or disables the text cleanup, refs. package cleanup.
+	Cleanup *bool `yaml:"cleanup"`
+}
+
+// Config maps input format names to overrides.
+type Config map[string]Source
+
+// Load reads a configuration. Unknown keys are errors, so typos do not go
+// unnoticed.
+func Load(r io.Reader) (Config, error) {
+	var c Config
+	dec := yaml.NewDecoder(r)
+	dec.SetStrict(true)
+	if err := dec.Decode(&c); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("source config: %v", err)
+	}
+	for name, s := range c {
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("source config: %s: %v", name, err)
+		}
+	}
+	return c, nil
+}
+
+// LoadFile reads a configuration from a file.
+func LoadFile(filename string) (Config, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+func (s Source) validate() error {
+	if strings.ContainsAny(s.SourceID, "- ") {
+		return fmt.Errorf("invalid source_id: %q", s.SourceID)
+	}
+	if strings.ContainsAny(s.IDPrefix, "- ") {
+		return fmt.Errorf("invalid id_prefix: %q", s.IDPrefix)
+	}
+	for _, lang := range s.Languages {
+		if len(lang) != 3 {
+			return fmt.Errorf("language must be ISO 639-3: %q", lang)
+		}
+	}
+	if s.BatchSize < 0 {
+		return fmt.Errorf("invalid batch_size: %d", s.BatchSize)
+	}
+	if s.BatchMB < 0 {
+		return fmt.Errorf("invalid batch_mb: %d", s.BatchMB)
+	}
+	return nil
+}
+
+// RewriteID returns a record id of the form ai-<sid>-<local part> with
+// prefix and source id replaced, as configured. An error is returned, if the
+// new id would exceed the key length limit.
+func (s Source) RewriteID(sid, id string) (string, error) {
+	if s.SourceID == "" && s.IDPrefix == "" {
+		return id, nil
+	}
+	prefix := fmt.Sprintf("ai-%s-", sid)
+	if !strings.HasPrefix(id, prefix) {
+		return "", fmt.Errorf("cannot rewrite id: %s", id)
+	}
+	p := "ai"
+	if s.IDPrefix != "" {
+		p = s.IDPrefix
+	}
+	if s.SourceID != "" {
+		sid = s.SourceID
+	}
+	result := fmt.Sprintf("%s-%s-%s", p, sid, strings.TrimPrefix(id, prefix))
+	if len(result) > span.KeyLengthLimit {
+		return "", fmt.Errorf("id too long: %s", result)
+	}
+	return result, nil
+}
+
+// Apply overrides values of a converted record.
+func (s Source) Apply(is *finc.IntermediateSchema) error {
+	id, err := s.RewriteID(is.SourceID, is.ID)
+	if err != nil {
+		return err
+	}
+	is.ID = id
+	if s.SourceID != "" {
+		is.SourceID = s.SourceID
+	}
+	if len(s.Collections) > 0 {
+		is.MegaCollections = s.Collections
+	}
+	if s.Format != "" {
+		is.Format = s.Format
+	}
+	if s.Genre != "" {
+		is.Genre = s.Genre
+	}
+	if s.RefType != "" {
+		is.RefType = s.RefType
+	}
+	if len(s.Languages) > 0 {
+		accepted := container.NewStringSet(s.Languages...)
+		var languages []string
+		for _, lang := range is.Languages {
+			if accepted.Contains(lang) {
+				languages = append(languages, lang)
+			}
+		}
+		is.Languages = languages
+	}
+	return nil
+}
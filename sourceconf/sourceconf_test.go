@@ -0,0 +1,69 @@
+package sourceconf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/miku/span/formats/finc"
+)
+
+func TestLoad(t *testing.T) {
+	var cases = []struct {
+		about string
+		yaml  string
+		c     Config
+		err   bool
+	}{
+		{"empty", "", nil, false},
+		{"source", "crossref:\n  source_id: \"149\"\n  batch_size: 100\n  languages: [eng]\n",
+			Config{"crossref": {SourceID: "149", BatchSize: 100, Languages: []string{"eng"}}}, false},
+		{"unknown key", "crossref:\n  sourceid: \"149\"\n", nil, true},
+		{"invalid source id", "crossref:\n  source_id: \"1-2\"\n", nil, true},
+		{"invalid language", "crossref:\n  languages: [en]\n", nil, true},
+		{"batch mb", "genios-zip:\n  batch_mb: 16\n", Config{"genios-zip": {BatchMB: 16}}, false},
+		{"invalid batch mb", "genios-zip:\n  batch_mb: -1\n", nil, true},
+	}
+	for _, c := range cases {
+		result, err := Load(strings.NewReader(c.yaml))
+		if (err != nil) != c.err {
+			t.Errorf("%s: got error %v, want error %v", c.about, err, c.err)
+		}
+		if err == nil && !reflect.DeepEqual(result, c.c) {
+			t.Errorf("%s: got %v, want %v", c.about, result, c.c)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	record := func() *finc.IntermediateSchema {
+		return &finc.IntermediateSchema{ID: "ai-49-abc", SourceID: "49", Format: "ElectronicArticle",
+			MegaCollections: []string{"A"}, Languages: []string{"eng", "fra"}}
+	}
+	var cases = []struct {
+		about  string
+		source Source
+		result *finc.IntermediateSchema
+		err    bool
+	}{
+		{"no overrides", Source{}, record(), false},
+		{"source id", Source{SourceID: "149"},
+			&finc.IntermediateSchema{ID: "ai-149-abc", SourceID: "149", Format: "ElectronicArticle",
+				MegaCollections: []string{"A"}, Languages: []string{"eng", "fra"}}, false},
+		{"prefix and values", Source{IDPrefix: "x", Collections: []string{"B"}, Format: "Book",
+			Languages: []string{"deu", "fra"}},
+			&finc.IntermediateSchema{ID: "x-49-abc", SourceID: "49", Format: "Book",
+				MegaCollections: []string{"B"}, Languages: []string{"fra"}}, false},
+		{"too long", Source{IDPrefix: strings.Repeat("x", 250)}, nil, true},
+	}
+	for _, c := range cases {
+		is := record()
+		err := c.source.Apply(is)
+		if (err != nil) != c.err {
+			t.Errorf("%s: got error %v, want error %v", c.about, err, c.err)
+		}
+		if err == nil && !reflect.DeepEqual(is, c.result) {
+			t.Errorf("%s: got %+v, want %+v", c.about, is, c.result)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package span
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LabelWriter writes records to one writer per label, e.g. per ISIL, so
+// institutions can receive only their part of the data. A record with
+// multiple labels is written to each of their writers. Writers are created on
+// first use. LabelWriter is safe for concurrent use.
+type LabelWriter struct {
+	// Create returns a new writer for a label.
+	Create func(label string) (io.WriteCloser, error)
+
+	mu      sync.Mutex
+	writers map[string]*labelWriter
+}
+
+// labelWriter serializes writes to a single writer and counts records.
+type labelWriter struct {
+	sync.Mutex
+	w     io.WriteCloser
+	count int64
+}
+
+// NewLabelFileWriter writes records into one file per label in a directory,
+// named after the label with a suffix, e.g. DE-15.ldj.
+func NewLabelFileWriter(dir, suffix string) *LabelWriter {
+	return &LabelWriter{Create: func(label string) (io.WriteCloser, error) {
+		f, err := os.Create(filepath.Join(dir, LabelFilename(label)+suffix))
+		if err != nil {
+			return nil, err
+		}
+		return &bufferedFile{Writer: bufio.NewWriter(f), f: f}, nil
+	}}
+}
+
+// LabelFilename replaces characters, that must not appear in file names.
+func LabelFilename(label string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', 0:
+			return '_'
+		}
+		return r
+	}, label)
+}
+
+// bufferedFile flushes a buffered writer before closing the file.
+type bufferedFile struct {
+	*bufio.Writer
+	f *os.File
+}
+
+func (b *bufferedFile) Close() error {
+	if err := b.Flush(); err != nil {
+		b.f.Close()
+		return err
+	}
+	return b.f.Close()
+}
+
+// writer returns the writer for a label, creating it, if necessary.
+func (w *LabelWriter) writer(label string) (*labelWriter, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.writers == nil {
+		w.writers = make(map[string]*labelWriter)
+	}
+	if lw, ok := w.writers[label]; ok {
+		return lw, nil
+	}
+	wc, err := w.Create(label)
+	if err != nil {
+		return nil, fmt.Errorf("label %s: %v", label, err)
+	}
+	lw := &labelWriter{w: wc}
+	w.writers[label] = lw
+	return lw, nil
+}
+
+// Write writes a record to the writers of all labels.
+func (w *LabelWriter) Write(labels []string, p []byte) error {
+	for _, label := range labels {
+		lw, err := w.writer(label)
+		if err != nil {
+			return err
+		}
+		lw.Lock()
+		_, err = lw.w.Write(p)
+		lw.count++
+		lw.Unlock()
+		if err != nil {
+			return fmt.Errorf("label %s: %v", label, err)
+		}
+	}
+	return nil
+}
+
+// Labels returns the labels seen so far, sorted.
+func (w *LabelWriter) Labels() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sortedLabels()
+}
+
+// Count returns the number of records written for a label.
+func (w *LabelWriter) Count(label string) int64 {
+	w.mu.Lock()
+	lw, ok := w.writers[label]
+	w.mu.Unlock()
+	if !ok {
+		return 0
+	}
+	lw.Lock()
+	defer lw.Unlock()
+	return lw.count
+}
+
+// Writer returns the underlying writer of a label, if any, e.g. to query
+// indexing statistics.
+func (w *LabelWriter) Writer(label string) (io.WriteCloser, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	lw, ok := w.writers[label]
+	if !ok {
+		return nil, false
+	}
+	return lw.w, true
+}
+
+// Close closes all writers and returns the first error.
+func (w *LabelWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var err error
+	for _, label := range w.sortedLabels() {
+		if cerr := w.writers[label].w.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("label %s: %v", label, cerr)
+		}
+	}
+	return err
+}
+
+func (w *LabelWriter) sortedLabels() []string {
+	var labels []string
+	for label := range w.writers {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
@@ -0,0 +1,55 @@
+package span
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLabelFileWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "span-split-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w := NewLabelFileWriter(dir, ".ldj")
+	records := []struct {
+		labels []string
+		b      string
+	}{
+		{[]string{"DE-15", "DE-14"}, "a\n"},
+		{[]string{"DE-15"}, "b\n"},
+		{nil, "c\n"},
+		{[]string{"DE-Ch1/x"}, "d\n"},
+	}
+	for _, r := range records {
+		if err := w.Write(r.labels, []byte(r.b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if labels := w.Labels(); !reflect.DeepEqual(labels, []string{"DE-14", "DE-15", "DE-Ch1/x"}) {
+		t.Errorf("Labels: got %v", labels)
+	}
+	if n := w.Count("DE-15"); n != 2 {
+		t.Errorf("Count: got %d, want 2", n)
+	}
+	want := map[string]string{
+		"DE-14.ldj":    "a\n",
+		"DE-15.ldj":    "a\nb\n",
+		"DE-Ch1_x.ldj": "d\n",
+	}
+	for name, content := range want {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("%s: got %q, want %q", name, b, content)
+		}
+	}
+}
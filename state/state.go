@@ -0,0 +1,224 @@
+// Package state keeps state between runs of harvesters and the dedup stage in
+// an embedded bolt database: "last seen" timestamps, e.g. the end of the last
+// harvest of an endpoint, and sets of processed ids.
+//
+// State can be exported to newline delimited JSON and imported again, e.g. to
+// move it between machines or to inspect it:
+//
+//	{"bucket": "last_seen", "key": "oai|https://...", "value": "2019-01-31T00:00:00Z"}
+//	{"bucket": "set.dedup", "key": "ai-49-...", "value": ""}
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// lastSeenBucket holds timestamps by name.
+	lastSeenBucket = "last_seen"
+	// setPrefix is prepended to set names to get their bucket.
+	setPrefix = "set."
+)
+
+// ErrInvalidName is returned for empty names and keys.
+var ErrInvalidName = errors.New("state: invalid name")
+
+// Store is an embedded state database, safe for concurrent use. A database
+// file can only be opened by one process at a time.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens or creates a state database. It waits at most timeout for other
+// processes to release the file, zero means no limit.
+func Open(filename string, timeout time.Duration) (*Store, error) {
+	db, err := bolt.Open(filename, 0644, &bolt.Options{Timeout: timeout})
+	if err != nil {
+		return nil, fmt.Errorf("state: %s: %v", filename, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// LastSeen returns the timestamp stored under a name and false, if there is
+// none.
+func (s *Store) LastSeen(name string) (t time.Time, ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(lastSeenBucket))
+		if b == nil {
+			return nil
+		}
+		v := b.Get([]byte(name))
+		if v == nil {
+			return nil
+		}
+		ok = true
+		return t.UnmarshalText(v)
+	})
+	return t, ok, err
+}
+
+// SetLastSeen stores a timestamp under a name.
+func (s *Store) SetLastSeen(name string, t time.Time) error {
+	if name == "" {
+		return ErrInvalidName
+	}
+	v, err := t.UTC().MarshalText()
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(lastSeenBucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(name), v)
+	})
+}
+
+// Add adds ids to a set in a single transaction.
+func (s *Store) Add(set string, ids ...string) error {
+	if set == "" {
+		return ErrInvalidName
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(setPrefix + set))
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if id == "" {
+				return ErrInvalidName
+			}
+			if err := b.Put([]byte(id), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Has returns true, if an id is in a set.
+func (s *Store) Has(set, id string) (ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(setPrefix + set)); b != nil {
+			ok = b.Get([]byte(id)) != nil
+		}
+		return nil
+	})
+	return ok, err
+}
+
+// Missing returns the ids, that are not in a set, in the given order.
+func (s *Store) Missing(set string, ids []string) (result []string, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(setPrefix + set))
+		for _, id := range ids {
+			if b == nil || b.Get([]byte(id)) == nil {
+				result = append(result, id)
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Len returns the number of ids in a set.
+func (s *Store) Len(set string) (n int, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(setPrefix + set)); b != nil {
+			n = b.Stats().KeyN
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Sets returns the names of all sets, sorted.
+func (s *Store) Sets() (names []string, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if strings.HasPrefix(string(name), setPrefix) {
+				names = append(names, strings.TrimPrefix(string(name), setPrefix))
+			}
+			return nil
+		})
+	})
+	return names, err
+}
+
+// DeleteSet removes a set and all its ids.
+func (s *Store) DeleteSet(set string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket([]byte(setPrefix + set))
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+// Entry is a single key of an exported store.
+type Entry struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+}
+
+// Export writes all entries as newline delimited JSON, ordered by bucket and
+// key.
+func (s *Store) Export(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			return b.ForEach(func(k, v []byte) error {
+				return enc.Encode(Entry{Bucket: string(name), Key: string(k), Value: string(v)})
+			})
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Import adds exported entries to the store, existing keys are overwritten.
+// All entries are imported in a single transaction.
+func (s *Store) Import(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for {
+			var e Entry
+			err := dec.Decode(&e)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if e.Bucket == "" || e.Key == "" {
+				return ErrInvalidName
+			}
+			b, err := tx.CreateBucketIfNotExists([]byte(e.Bucket))
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(e.Key), []byte(e.Value)); err != nil {
+				return err
+			}
+		}
+	})
+}
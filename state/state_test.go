@@ -0,0 +1,125 @@
+package state
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// open opens a store in a temporary directory, removed with cleanup.
+func open(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "span-state-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(filepath.Join(dir, "state.db"), time.Second)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return s, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestLastSeen(t *testing.T) {
+	s, cleanup := open(t)
+	defer cleanup()
+
+	if _, ok, err := s.LastSeen("oai"); ok || err != nil {
+		t.Fatalf("LastSeen: got %v, %v, want false, nil", ok, err)
+	}
+	want := time.Date(2019, 1, 31, 0, 0, 0, 0, time.UTC)
+	if err := s.SetLastSeen("oai", want); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := s.LastSeen("oai")
+	if err != nil || !ok || !got.Equal(want) {
+		t.Errorf("LastSeen: got %v, %v, %v, want %v", got, ok, err, want)
+	}
+	if err := s.SetLastSeen("", want); err != ErrInvalidName {
+		t.Errorf("SetLastSeen: got %v, want %v", err, ErrInvalidName)
+	}
+}
+
+func TestSet(t *testing.T) {
+	s, cleanup := open(t)
+	defer cleanup()
+
+	if err := s.Add("dedup", "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	var cases = []struct {
+		set, id string
+		want    bool
+	}{
+		{"dedup", "a", true},
+		{"dedup", "c", false},
+		{"other", "a", false},
+	}
+	for _, c := range cases {
+		if got, err := s.Has(c.set, c.id); err != nil || got != c.want {
+			t.Errorf("Has(%q, %q): got %v, %v, want %v", c.set, c.id, got, err, c.want)
+		}
+	}
+	missing, err := s.Missing("dedup", []string{"c", "a", "d"})
+	if err != nil || !reflect.DeepEqual(missing, []string{"c", "d"}) {
+		t.Errorf("Missing: got %v, %v", missing, err)
+	}
+	if n, err := s.Len("dedup"); err != nil || n != 2 {
+		t.Errorf("Len: got %d, %v, want 2", n, err)
+	}
+	if err := s.DeleteSet("dedup"); err != nil {
+		t.Fatal(err)
+	}
+	if names, err := s.Sets(); err != nil || len(names) != 0 {
+		t.Errorf("Sets: got %v, %v, want none", names, err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	s, cleanup := open(t)
+	defer cleanup()
+
+	if err := s.SetLastSeen("crossref", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("dedup", "b", "a"); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := s.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"bucket":"last_seen","key":"crossref","value":"2019-01-01T00:00:00Z"}
+{"bucket":"set.dedup","key":"a","value":""}
+{"bucket":"set.dedup","key":"b","value":""}
+`
+	if buf.String() != want {
+		t.Fatalf("Export: got %s, want %s", buf.String(), want)
+	}
+
+	r, cleanupR := open(t)
+	defer cleanupR()
+	if err := r.Import(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := r.Has("dedup", "a"); err != nil || !ok {
+		t.Errorf("Has after import: got %v, %v", ok, err)
+	}
+	var again bytes.Buffer
+	if err := r.Export(&again); err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != want {
+		t.Errorf("Export after import: got %s, want %s", again.String(), want)
+	}
+	if err := r.Import(bytes.NewReader([]byte(`{"bucket":"", "key":"x"}`))); err != ErrInvalidName {
+		t.Errorf("Import: got %v, want %v", err, ErrInvalidName)
+	}
+}
@@ -0,0 +1,211 @@
+package stats
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/miku/span/formats/finc"
+)
+
+// unknown is the key for records without a value, e.g. without date.
+const unknown = "unknown"
+
+// fieldChecks are the fields counted for completeness, by intermediate
+// schema key.
+var fieldChecks = map[string]func(is *finc.IntermediateSchema) bool{
+	"rft.atitle":     func(is *finc.IntermediateSchema) bool { return is.ArticleTitle != "" },
+	"rft.jtitle":     func(is *finc.IntermediateSchema) bool { return is.JournalTitle != "" },
+	"rft.btitle":     func(is *finc.IntermediateSchema) bool { return is.BookTitle != "" },
+	"rft.authors":    func(is *finc.IntermediateSchema) bool { return len(is.Authors) > 0 },
+	"rft.issn":       func(is *finc.IntermediateSchema) bool { return len(is.ISSN)+len(is.EISSN) > 0 },
+	"rft.isbn":       func(is *finc.IntermediateSchema) bool { return len(is.ISBN)+len(is.EISBN) > 0 },
+	"rft.pub":        func(is *finc.IntermediateSchema) bool { return len(is.Publishers) > 0 },
+	"rft.volume":     func(is *finc.IntermediateSchema) bool { return is.Volume != "" },
+	"rft.issue":      func(is *finc.IntermediateSchema) bool { return is.Issue != "" },
+	"rft.spage":      func(is *finc.IntermediateSchema) bool { return is.Pages.Start != "" },
+	"rft.date":       func(is *finc.IntermediateSchema) bool { return !is.Date.IsZero() },
+	"abstract":       func(is *finc.IntermediateSchema) bool { return is.Abstract != "" },
+	"doi":            func(is *finc.IntermediateSchema) bool { return is.DOI != "" },
+	"languages":      func(is *finc.IntermediateSchema) bool { return len(is.Languages) > 0 },
+	"url":            func(is *finc.IntermediateSchema) bool { return len(is.URL) > 0 },
+	"x.subjects":     func(is *finc.IntermediateSchema) bool { return len(is.Subjects) > 0 },
+	"x.fulltext":     func(is *finc.IntermediateSchema) bool { return is.Fulltext != "" },
+	"finc.format":    func(is *finc.IntermediateSchema) bool { return is.Format != "" },
+	"finc.record_id": func(is *finc.IntermediateSchema) bool { return is.RecordID != "" },
+}
+
+// CorpusCounts are the numbers for a source or for all records.
+type CorpusCounts struct {
+	Records     int64            `json:"records"`
+	DOI         int64            `json:"doi"`
+	DOICoverage float64          `json:"doi_coverage"`
+	Collections map[string]int64 `json:"collections"`
+	Years       map[string]int64 `json:"years"`
+	Languages   map[string]int64 `json:"languages"`
+	// Fields counts records with a value in a field.
+	Fields map[string]int64 `json:"fields"`
+	// Completeness is the share of records with a value in a field.
+	Completeness map[string]float64 `json:"completeness"`
+}
+
+// newCorpusCounts returns empty counts.
+func newCorpusCounts() *CorpusCounts {
+	c := &CorpusCounts{
+		Collections:  make(map[string]int64),
+		Years:        make(map[string]int64),
+		Languages:    make(map[string]int64),
+		Fields:       make(map[string]int64),
+		Completeness: make(map[string]float64),
+	}
+	for name := range fieldChecks {
+		c.Fields[name] = 0
+	}
+	return c
+}
+
+// add counts a record.
+func (c *CorpusCounts) add(is *finc.IntermediateSchema) {
+	c.Records++
+	if is.DOI != "" {
+		c.DOI++
+	}
+	if len(is.MegaCollections) == 0 {
+		c.Collections[unknown]++
+	}
+	for _, name := range is.MegaCollections {
+		c.Collections[name]++
+	}
+	if is.Date.IsZero() {
+		c.Years[unknown]++
+	} else {
+		c.Years[strconv.Itoa(is.Date.Year())]++
+	}
+	if len(is.Languages) == 0 {
+		c.Languages[unknown]++
+	}
+	for _, lang := range is.Languages {
+		c.Languages[lang]++
+	}
+	for name, f := range fieldChecks {
+		if f(is) {
+			c.Fields[name]++
+		}
+	}
+}
+
+// updateShares computes DOI coverage and completeness.
+func (c *CorpusCounts) updateShares() {
+	if c.Records == 0 {
+		return
+	}
+	c.DOICoverage = float64(c.DOI) / float64(c.Records)
+	for name, n := range c.Fields {
+		c.Completeness[name] = float64(n) / float64(c.Records)
+	}
+}
+
+// Corpus collects statistics over intermediate schema records, like counts
+// per source, collection and year, languages, DOI coverage and field
+// completeness, safe for concurrent use.
+type Corpus struct {
+	mu      sync.Mutex
+	Total   *CorpusCounts            `json:"total"`
+	Sources map[string]*CorpusCounts `json:"sources"`
+}
+
+// NewCorpus creates empty corpus statistics.
+func NewCorpus() *Corpus {
+	return &Corpus{Total: newCorpusCounts(), Sources: make(map[string]*CorpusCounts)}
+}
+
+// Add counts a record.
+func (c *Corpus) Add(is *finc.IntermediateSchema) {
+	sid := is.SourceID
+	if sid == "" {
+		sid = unknown
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Sources[sid] == nil {
+		c.Sources[sid] = newCorpusCounts()
+	}
+	c.Sources[sid].add(is)
+	c.Total.add(is)
+}
+
+// WriteJSON writes the statistics as JSON.
+func (c *Corpus) WriteJSON(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Total.updateShares()
+	for _, counts := range c.Sources {
+		counts.updateShares()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(c)
+}
+
+// WriteCSV writes the statistics as CSV in long format, one row per source,
+// kind and key, e.g. "49,year,2018,1200,0.1". The source of the totals is
+// "all". The share is relative to the number of records of the source.
+func (c *Corpus) WriteCSV(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"source", "kind", "key", "count", "share"}); err != nil {
+		return err
+	}
+	var sids []string
+	for sid := range c.Sources {
+		sids = append(sids, sid)
+	}
+	sort.Strings(sids)
+	for _, sid := range append([]string{"all"}, sids...) {
+		counts := c.Total
+		if sid != "all" {
+			counts = c.Sources[sid]
+		}
+		rows := [][]string{
+			corpusRow(sid, "records", "", counts.Records, counts.Records),
+			corpusRow(sid, "doi", "", counts.DOI, counts.Records),
+		}
+		for _, m := range []struct {
+			kind   string
+			counts map[string]int64
+		}{
+			{"collection", counts.Collections},
+			{"year", counts.Years},
+			{"language", counts.Languages},
+			{"field", counts.Fields},
+		} {
+			var keys []string
+			for k := range m.counts {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				rows = append(rows, corpusRow(sid, m.kind, k, m.counts[k], counts.Records))
+			}
+		}
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// corpusRow formats a CSV row.
+func corpusRow(sid, kind, key string, n, total int64) []string {
+	var share float64
+	if total > 0 {
+		share = float64(n) / float64(total)
+	}
+	return []string{sid, kind, key, strconv.FormatInt(n, 10), fmt.Sprintf("%0.4f", share)}
+}
@@ -0,0 +1,242 @@
+// Package stats collects run level statistics of conversions, like the
+// number of converted, skipped and failed records per source and package.
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/miku/span/formats/finc"
+)
+
+// maxReasonLength limits the length of skip reasons used as keys.
+const maxReasonLength = 80
+
+// Counts are the numbers for a source or package.
+type Counts struct {
+	Converted int64            `json:"converted"`
+	Skipped   int64            `json:"skipped"`
+	Errors    int64            `json:"errors"`
+	Reasons   map[string]int64 `json:"skip_reasons,omitempty"`
+	Counters  map[string]int64 `json:"counters,omitempty"`
+}
+
+// Report is the machine readable summary of a run.
+type Report struct {
+	Started  time.Time          `json:"started"`
+	Finished time.Time          `json:"finished"`
+	Elapsed  float64            `json:"elapsed_s"`
+	Total    Counts             `json:"total"`
+	Sources  map[string]*Counts `json:"sources"`
+	Packages map[string]*Counts `json:"packages,omitempty"`
+}
+
+// Collector counts records, safe for concurrent use. Records without a source
+// id, e.g. records that cannot be parsed, are counted under Default.
+type Collector struct {
+	Default string
+
+	mu     sync.Mutex
+	report Report
+}
+
+// New creates a collector, which counts unknown sources under a default name,
+// e.g. the input format.
+func New(name string) *Collector {
+	return &Collector{
+		Default: name,
+		report: Report{
+			Started:  time.Now(),
+			Sources:  make(map[string]*Counts),
+			Packages: make(map[string]*Counts),
+		},
+	}
+}
+
+// ReasonKey shortens a skip reason for aggregation: record specific parts,
+// like an id after a colon or after an uppercase code, are dropped.
+func ReasonKey(reason string) string {
+	reason = strings.TrimSpace(reason)
+	if i := strings.Index(reason, ":"); i > 0 {
+		reason = strings.TrimSpace(reason[:i])
+	} else if fields := strings.Fields(reason); len(fields) > 1 && isCode(fields[0]) {
+		reason = fields[0]
+	}
+	if len(reason) > maxReasonLength {
+		reason = reason[:maxReasonLength]
+	}
+	if reason == "" {
+		return "unknown"
+	}
+	return reason
+}
+
+// isCode returns true for reasons like NO_ATITLE.
+func isCode(s string) bool {
+	for _, r := range s {
+		if !unicode.IsUpper(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// counts returns the counters for the source and package of a record, nil
+// if the record has no package.
+func (c *Collector) counts(is *finc.IntermediateSchema) (source, pkg *Counts) {
+	sid := c.Default
+	if is != nil && is.SourceID != "" {
+		sid = is.SourceID
+	}
+	if source = c.report.Sources[sid]; source == nil {
+		source = &Counts{}
+		c.report.Sources[sid] = source
+	}
+	if is == nil || is.MegaCollection() == "" {
+		return source, nil
+	}
+	name := is.MegaCollection()
+	if pkg = c.report.Packages[name]; pkg == nil {
+		pkg = &Counts{}
+		c.report.Packages[name] = pkg
+	}
+	return source, pkg
+}
+
+// update applies f to the total, source and package counts of a record.
+func (c *Collector) update(is *finc.IntermediateSchema, f func(*Counts)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	source, pkg := c.counts(is)
+	for _, counts := range []*Counts{&c.report.Total, source, pkg} {
+		if counts != nil {
+			f(counts)
+		}
+	}
+}
+
+// Converted counts a converted record.
+func (c *Collector) Converted(is *finc.IntermediateSchema) {
+	c.update(is, func(counts *Counts) { counts.Converted++ })
+}
+
+// Skipped counts a skipped record, the record may be nil.
+func (c *Collector) Skipped(is *finc.IntermediateSchema, reason string) {
+	key := ReasonKey(reason)
+	c.update(is, func(counts *Counts) {
+		counts.Skipped++
+		if counts.Reasons == nil {
+			counts.Reasons = make(map[string]int64)
+		}
+		counts.Reasons[key]++
+	})
+}
+
+// Error counts a record, that could not be parsed or converted, the record
+// may be nil.
+func (c *Collector) Error(is *finc.IntermediateSchema) {
+	c.update(is, func(counts *Counts) { counts.Errors++ })
+}
+
+// Inc increments a named counter for a record, e.g. invalid identifiers.
+func (c *Collector) Inc(is *finc.IntermediateSchema, name string) {
+	c.update(is, func(counts *Counts) {
+		if counts.Counters == nil {
+			counts.Counters = make(map[string]int64)
+		}
+		counts.Counters[name]++
+	})
+}
+
+// Report returns a copy of the current numbers.
+func (c *Collector) Report() Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := c.report
+	r.Finished = time.Now()
+	r.Elapsed = r.Finished.Sub(r.Started).Seconds()
+	r.Total = copyCounts(&c.report.Total)
+	r.Sources = make(map[string]*Counts)
+	for k, v := range c.report.Sources {
+		counts := copyCounts(v)
+		r.Sources[k] = &counts
+	}
+	r.Packages = make(map[string]*Counts)
+	for k, v := range c.report.Packages {
+		counts := copyCounts(v)
+		r.Packages[k] = &counts
+	}
+	return r
+}
+
+// copyCounts returns a deep copy.
+func copyCounts(c *Counts) Counts {
+	result := *c
+	result.Reasons = copyMap(c.Reasons)
+	result.Counters = copyMap(c.Counters)
+	return result
+}
+
+// copyMap returns a copy of a map, nil stays nil.
+func copyMap(m map[string]int64) map[string]int64 {
+	if m == nil {
+		return nil
+	}
+	result := make(map[string]int64, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+	return result
+}
+
+// WriteJSON writes the report as JSON.
+func (c *Collector) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(c.Report())
+}
+
+// WriteSummary writes a short human readable summary, one line per source.
+func (c *Collector) WriteSummary(w io.Writer) error {
+	r := c.Report()
+	var sids []string
+	for sid := range r.Sources {
+		sids = append(sids, sid)
+	}
+	sort.Strings(sids)
+	if _, err := fmt.Fprintf(w, "%d converted, %d skipped, %d errors in %0.1fs\n",
+		r.Total.Converted, r.Total.Skipped, r.Total.Errors, r.Elapsed); err != nil {
+		return err
+	}
+	for _, sid := range sids {
+		counts := r.Sources[sid]
+		line := fmt.Sprintf("source %s: %d converted, %d skipped, %d errors",
+			sid, counts.Converted, counts.Skipped, counts.Errors)
+		var keys []string
+		for k := range counts.Reasons {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			line += fmt.Sprintf(", skipped %s: %d", k, counts.Reasons[k])
+		}
+		keys = keys[:0]
+		for k := range counts.Counters {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			line += fmt.Sprintf(", %s: %d", k, counts.Counters[k])
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/miku/span/formats/finc"
+)
+
+func TestReasonKey(t *testing.T) {
+	var cases = []struct {
+		reason string
+		result string
+	}{
+		{"", "unknown"},
+		{"empty date", "empty date"},
+		{"Cannot parse date: 20xx", "Cannot parse date"},
+		{"NO_ATITLE ai-49-abc", "NO_ATITLE"},
+		{"SKIP ID_TOO_LONG", "SKIP"},
+		{"short date", "short date"},
+	}
+	for _, c := range cases {
+		if v := ReasonKey(c.reason); v != c.result {
+			t.Errorf("ReasonKey(%q): got %q, want %q", c.reason, v, c.result)
+		}
+	}
+}
+
+func TestCollector(t *testing.T) {
+	c := New("crossref")
+	a := &finc.IntermediateSchema{SourceID: "49", MegaCollections: []string{"A"}}
+	b := &finc.IntermediateSchema{SourceID: "49"}
+	c.Converted(a)
+	c.Converted(b)
+	c.Skipped(a, "NO_ATITLE ai-49-1")
+	c.Skipped(nil, "empty date")
+	c.Error(nil)
+	c.Inc(a, "invalid_issn")
+
+	r := c.Report()
+	want := Counts{Converted: 2, Skipped: 2, Errors: 1,
+		Reasons:  map[string]int64{"NO_ATITLE": 1, "empty date": 1},
+		Counters: map[string]int64{"invalid_issn": 1}}
+	if !reflect.DeepEqual(r.Total, want) {
+		t.Errorf("total: got %+v, want %+v", r.Total, want)
+	}
+	want = Counts{Converted: 2, Skipped: 1,
+		Reasons:  map[string]int64{"NO_ATITLE": 1},
+		Counters: map[string]int64{"invalid_issn": 1}}
+	if !reflect.DeepEqual(*r.Sources["49"], want) {
+		t.Errorf("source: got %+v, want %+v", *r.Sources["49"], want)
+	}
+	want = Counts{Skipped: 1, Errors: 1, Reasons: map[string]int64{"empty date": 1}}
+	if !reflect.DeepEqual(*r.Sources["crossref"], want) {
+		t.Errorf("default: got %+v, want %+v", *r.Sources["crossref"], want)
+	}
+	if r.Packages["A"].Converted != 1 || r.Packages["A"].Skipped != 1 {
+		t.Errorf("package: got %+v", *r.Packages["A"])
+	}
+
+	var buf bytes.Buffer
+	if err := c.WriteJSON(&buf); err != nil {
+		t.Error(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Error(err)
+	}
+	if decoded.Total.Converted != 2 {
+		t.Errorf("json: got %d converted, want 2", decoded.Total.Converted)
+	}
+}
+
+func TestCorpus(t *testing.T) {
+	c := NewCorpus()
+	c.Add(&finc.IntermediateSchema{SourceID: "49", DOI: "10.1000/1", MegaCollections: []string{"A"},
+		Languages: []string{"eng"}, Date: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)})
+	c.Add(&finc.IntermediateSchema{SourceID: "49", MegaCollections: []string{"A"}, ArticleTitle: "T"})
+	c.Add(&finc.IntermediateSchema{SourceID: "48", DOI: "10.1000/2"})
+
+	var buf bytes.Buffer
+	if err := c.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Total   CorpusCounts            `json:"total"`
+		Sources map[string]CorpusCounts `json:"sources"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Total.Records != 3 || result.Total.DOI != 2 {
+		t.Errorf("got %d records, %d doi, want 3, 2", result.Total.Records, result.Total.DOI)
+	}
+	s := result.Sources["49"]
+	if s.DOICoverage != 0.5 || s.Completeness["rft.atitle"] != 0.5 || s.Completeness["rft.jtitle"] != 0 {
+		t.Errorf("got coverage %v, completeness %v", s.DOICoverage, s.Completeness)
+	}
+	want := map[string]int64{"2018": 1, "unknown": 1}
+	if !reflect.DeepEqual(s.Years, want) {
+		t.Errorf("got years %v, want %v", s.Years, want)
+	}
+
+	buf.Reset()
+	if err := c.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []string{
+		"source,kind,key,count,share\n",
+		"all,records,,3,1.0000\n",
+		"49,collection,A,2,1.0000\n",
+		"48,collection,unknown,1,1.0000\n",
+		"49,language,eng,1,0.5000\n",
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(row)) {
+			t.Errorf("missing CSV row %q", row)
+		}
+	}
+}
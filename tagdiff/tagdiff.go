@@ -0,0 +1,197 @@
+// Package tagdiff compares two tagging runs, e.g. yesterday's tagged corpus or
+// a dump of the index with today's span-tag output, and counts the ISILs
+// attached to or detached from records, per source and collection.
+//
+// Records may be in intermediate schema (finc.id, x.labels) or in the SOLR
+// format (id, institution), one JSON document per line.
+package tagdiff
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// MaxSampleIDs is the number of record ids kept per change, for review.
+const MaxSampleIDs = 5
+
+// ErrMissingID is returned for documents without id.
+var ErrMissingID = errors.New("missing id")
+
+// Doc is the part of a tagged record relevant for licensing.
+type Doc struct {
+	ID          string
+	SourceID    string
+	Collections []string
+	ISILs       []string
+}
+
+// ParseDoc parses an intermediate schema or SOLR document.
+func ParseDoc(b []byte) (Doc, error) {
+	var v struct {
+		FincID              string   `json:"finc.id"`
+		FincSourceID        string   `json:"finc.source_id"`
+		FincMegaCollections []string `json:"finc.mega_collection"`
+		Labels              []string `json:"x.labels"`
+		ID                  string   `json:"id"`
+		SourceID            string   `json:"source_id"`
+		MegaCollections     []string `json:"mega_collection"`
+		Institutions        []string `json:"institution"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return Doc{}, err
+	}
+	doc := Doc{
+		ID:          v.FincID,
+		SourceID:    v.FincSourceID,
+		Collections: v.FincMegaCollections,
+		ISILs:       v.Labels,
+	}
+	if doc.ID == "" {
+		doc = Doc{
+			ID:          v.ID,
+			SourceID:    v.SourceID,
+			Collections: v.MegaCollections,
+			ISILs:       v.Institutions,
+		}
+	}
+	if doc.ID == "" {
+		return doc, ErrMissingID
+	}
+	return doc, nil
+}
+
+// Key groups changes.
+type Key struct {
+	ISIL       string `json:"isil"`
+	SourceID   string `json:"sid"`
+	Collection string `json:"collection"`
+}
+
+// Change counts ISIL changes for records of a source and collection.
+// Attached and Detached count records present in both runs, Added and Removed
+// count records only present in the new or old run.
+type Change struct {
+	Key
+	Attached int64    `json:"attached"`
+	Detached int64    `json:"detached"`
+	Added    int64    `json:"added"`
+	Removed  int64    `json:"removed"`
+	IDs      []string `json:"ids"`
+}
+
+// Differ compares two runs, safe for concurrent use. All documents of the old
+// run must be added before the documents of the new run. Only the old run is
+// kept in memory.
+type Differ struct {
+	mu      sync.Mutex
+	old     map[string]Doc
+	changes map[Key]*Change
+}
+
+// New creates a new differ.
+func New() *Differ {
+	return &Differ{old: make(map[string]Doc), changes: make(map[Key]*Change)}
+}
+
+// AddOld adds a document of the old run.
+func (d *Differ) AddOld(doc Doc) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.old[doc.ID] = Doc{SourceID: doc.SourceID, Collections: doc.Collections, ISILs: doc.ISILs}
+}
+
+// AddNew adds a document of the new run and compares it to the old version.
+func (d *Differ) AddNew(doc Doc) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	prev, ok := d.old[doc.ID]
+	if !ok {
+		d.count(doc, difference(doc.ISILs, nil), func(c *Change) { c.Added++ })
+		return
+	}
+	delete(d.old, doc.ID)
+	d.count(doc, difference(doc.ISILs, prev.ISILs), func(c *Change) { c.Attached++ })
+	// Detached ISILs are reported under the new collections, which are
+	// usually unchanged.
+	d.count(doc, difference(prev.ISILs, doc.ISILs), func(c *Change) { c.Detached++ })
+}
+
+// count applies f to the changes of each ISIL and collection of a document.
+func (d *Differ) count(doc Doc, isils []string, f func(c *Change)) {
+	collections := doc.Collections
+	if len(collections) == 0 {
+		collections = []string{""}
+	}
+	for _, isil := range isils {
+		for _, collection := range collections {
+			k := Key{ISIL: isil, SourceID: doc.SourceID, Collection: collection}
+			c := d.changes[k]
+			if c == nil {
+				c = &Change{Key: k}
+				d.changes[k] = c
+			}
+			f(c)
+			if len(c.IDs) < MaxSampleIDs {
+				c.IDs = append(c.IDs, doc.ID)
+			}
+		}
+	}
+}
+
+// Changes returns all changes, sorted by ISIL, source id and collection. Old
+// documents not seen in the new run are counted as removed.
+func (d *Differ) Changes() []Change {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var ids []string
+	for id := range d.old {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		doc := d.old[id]
+		doc.ID = id
+		d.count(doc, difference(doc.ISILs, nil), func(c *Change) { c.Removed++ })
+		delete(d.old, id)
+	}
+	var result []Change
+	for _, c := range d.changes {
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].Key, result[j].Key
+		if a.ISIL != b.ISIL {
+			return a.ISIL < b.ISIL
+		}
+		if a.SourceID != b.SourceID {
+			return a.SourceID < b.SourceID
+		}
+		return a.Collection < b.Collection
+	})
+	return result
+}
+
+// difference returns the values in a, that are not in b.
+func difference(a, b []string) []string {
+	var result []string
+	seen := make(map[string]bool, len(a))
+	for _, v := range a {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		var found bool
+		for _, w := range b {
+			if v == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,49 @@
+package tagdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDoc(t *testing.T) {
+	var cases = []struct {
+		b   string
+		doc Doc
+		err error
+	}{
+		{`{"finc.id": "ai-49-a", "finc.source_id": "49", "finc.mega_collection": ["C"], "x.labels": ["DE-14"]}`,
+			Doc{ID: "ai-49-a", SourceID: "49", Collections: []string{"C"}, ISILs: []string{"DE-14"}}, nil},
+		{`{"id": "ai-49-a", "source_id": "49", "mega_collection": ["C"], "institution": ["DE-14"]}`,
+			Doc{ID: "ai-49-a", SourceID: "49", Collections: []string{"C"}, ISILs: []string{"DE-14"}}, nil},
+		{`{"source_id": "49"}`, Doc{SourceID: "49"}, ErrMissingID},
+	}
+	for _, c := range cases {
+		doc, err := ParseDoc([]byte(c.b))
+		if err != c.err {
+			t.Errorf("ParseDoc(%s): got %v, want %v", c.b, err, c.err)
+		}
+		if !reflect.DeepEqual(doc, c.doc) {
+			t.Errorf("ParseDoc(%s): got %+v, want %+v", c.b, doc, c.doc)
+		}
+	}
+}
+
+func TestChanges(t *testing.T) {
+	d := New()
+	d.AddOld(Doc{ID: "1", SourceID: "49", Collections: []string{"C"}, ISILs: []string{"DE-14", "DE-15"}})
+	d.AddOld(Doc{ID: "2", SourceID: "49", Collections: []string{"C"}, ISILs: []string{"DE-14"}})
+	d.AddOld(Doc{ID: "3", SourceID: "55", ISILs: []string{"DE-15"}})
+	d.AddNew(Doc{ID: "1", SourceID: "49", Collections: []string{"C"}, ISILs: []string{"DE-14", "DE-Ch1"}})
+	d.AddNew(Doc{ID: "2", SourceID: "49", Collections: []string{"C"}, ISILs: []string{"DE-14"}})
+	d.AddNew(Doc{ID: "4", SourceID: "49", Collections: []string{"C"}, ISILs: []string{"DE-14", "DE-14"}})
+
+	want := []Change{
+		{Key: Key{"DE-14", "49", "C"}, Added: 1, IDs: []string{"4"}},
+		{Key: Key{"DE-15", "49", "C"}, Detached: 1, IDs: []string{"1"}},
+		{Key: Key{"DE-15", "55", ""}, Removed: 1, IDs: []string{"3"}},
+		{Key: Key{"DE-Ch1", "49", "C"}, Attached: 1, IDs: []string{"1"}},
+	}
+	if got := d.Changes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}